@@ -94,6 +94,12 @@ func (c *Client) SetEngineRPC(engine *rpc.Client) {
 	c.engineRPC = engine
 }
 
+// SubscribeChainHeadEvent allows callers to subscribe a provided channel to new
+// authenticated execution chain heads.
+func (c *Client) SubscribeChainHeadEvent(ch chan<- types.ChainHeadEvent) event.Subscription {
+	return c.blockSync.SubscribeChainHead(ch)
+}
+
 func (c *Client) Start() error {
 	headCh := make(chan types.ChainHeadEvent, 16)
 	c.chainHeadSub = c.blockSync.SubscribeChainHead(headCh)
@@ -17,11 +17,13 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/p2p/enode"
 )
@@ -38,8 +40,15 @@ type crawler struct {
 	// settings
 	revalidateInterval time.Duration
 	mu                 sync.RWMutex
+
+	// history database, optional
+	db      *crawlDB
+	rlpxKey *ecdsa.PrivateKey // if set, responding nodes are dialed over RLPx
 }
 
+// rlpxHelloTimeout is the time limit for the RLPx handshake with crawled nodes.
+const rlpxHelloTimeout = 10 * time.Second
+
 const (
 	nodeRemoved = iota
 	nodeSkipRecent
@@ -191,7 +200,8 @@ func (c *crawler) updateNode(n *enode.Node) int {
 	// Request the node record.
 	status := nodeUpdated
 	node.LastCheck = truncNow()
-	if nn, err := c.disc.RequestENR(n); err != nil {
+	nn, err := c.disc.RequestENR(n)
+	if err != nil {
 		if node.Score == 0 {
 			// Node doesn't implement EIP-868.
 			log.Debug("Skipping node", "id", n.ID())
@@ -208,6 +218,9 @@ func (c *crawler) updateNode(n *enode.Node) int {
 		}
 		node.LastResponse = node.LastCheck
 	}
+	if c.db != nil {
+		c.recordHistory(nn, n.ID(), node.LastCheck)
+	}
 	// Store/update node in output set.
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -221,6 +234,31 @@ func (c *crawler) updateNode(n *enode.Node) int {
 	return status
 }
 
+// recordHistory stores the result of a node check in the crawl database. The
+// node record is nil if the ENR request failed.
+func (c *crawler) recordHistory(n *enode.Node, id enode.ID, now time.Time) {
+	var (
+		hello    *ethtest.Hello
+		helloErr error
+	)
+	if n != nil && c.rlpxKey != nil {
+		hello, helloErr = rlpxHello(n, c.rlpxKey, rlpxHelloTimeout)
+	}
+	err := c.db.update(id, func(r *crawlRecord) {
+		r.LastCheck = now
+		if n == nil {
+			return
+		}
+		r.addResponse(n, now)
+		if c.rlpxKey != nil {
+			r.addHello(hello, helloErr, now)
+		}
+	})
+	if err != nil {
+		log.Warn("Failed to store node history", "id", id, "err", err)
+	}
+}
+
 func truncNow() time.Time {
 	return time.Now().UTC().Truncate(1 * time.Second)
 }
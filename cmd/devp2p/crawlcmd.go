@@ -0,0 +1,382 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	crawlCommand = &cli.Command{
+		Name:  "crawl",
+		Usage: "Crawl database tools",
+		Subcommands: []*cli.Command{
+			crawlStatsCommand,
+			crawlExportCommand,
+		},
+	}
+	crawlStatsCommand = &cli.Command{
+		Name:      "stats",
+		Usage:     "Shows client distribution and fork readiness of crawled nodes",
+		Action:    crawlStats,
+		ArgsUsage: "<crawl database>",
+		Flags: []cli.Flag{
+			crawlMaxAgeFlag,
+			crawlNetworkFlag,
+			crawlStatsFormatFlag,
+		},
+	}
+	crawlExportCommand = &cli.Command{
+		Name:      "export",
+		Usage:     "Writes the crawled nodes to standard output",
+		Action:    crawlExport,
+		ArgsUsage: "<crawl database>",
+		Flags: []cli.Flag{
+			crawlMaxAgeFlag,
+			crawlExportFormatFlag,
+		},
+	}
+)
+
+var (
+	crawlDBFlag = &cli.StringFlag{
+		Name:  "db",
+		Usage: "Crawl database location. If set, the history of crawled nodes is recorded.",
+	}
+	crawlRLPxFlag = &cli.BoolFlag{
+		Name:  "rlpx",
+		Usage: "Performs the RLPx handshake with responding nodes to record their client identity (requires --db)",
+	}
+	crawlMaxAgeFlag = &cli.DurationFlag{
+		Name:  "maxage",
+		Usage: "Only includes nodes seen within this duration (zero includes all nodes)",
+		Value: 24 * time.Hour,
+	}
+	crawlNetworkFlag = &cli.StringFlag{
+		Name:  "network",
+		Usage: "Network for the fork readiness report (mainnet, goerli, sepolia, holesky)",
+		Value: "mainnet",
+	}
+	crawlStatsFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format (text, json, csv)",
+		Value: "text",
+	}
+	crawlExportFormatFlag = &cli.StringFlag{
+		Name:  "format",
+		Usage: "Output format (json, csv)",
+		Value: "json",
+	}
+)
+
+// setupCrawlDB configures the crawler to record node history if the --db flag is set.
+func setupCrawlDB(ctx *cli.Context, c *crawler) error {
+	if !ctx.IsSet(crawlDBFlag.Name) {
+		if ctx.Bool(crawlRLPxFlag.Name) {
+			return fmt.Errorf("-%s requires -%s", crawlRLPxFlag.Name, crawlDBFlag.Name)
+		}
+		return nil
+	}
+	db, err := openCrawlDB(ctx.String(crawlDBFlag.Name))
+	if err != nil {
+		return err
+	}
+	c.db = db
+	if ctx.Bool(crawlRLPxFlag.Name) {
+		c.rlpxKey, _ = crypto.GenerateKey()
+	}
+	return nil
+}
+
+// loadCrawlRecords reads the records of all nodes seen within maxage.
+func loadCrawlRecords(ctx *cli.Context) ([]*crawlRecord, error) {
+	if ctx.NArg() < 1 {
+		return nil, errors.New("need crawl database as argument")
+	}
+	db, err := openCrawlDB(ctx.Args().First())
+	if err != nil {
+		return nil, err
+	}
+	defer db.close()
+
+	records, err := db.records()
+	if err != nil {
+		return nil, err
+	}
+	maxage := ctx.Duration(crawlMaxAgeFlag.Name)
+	if maxage == 0 {
+		return records, nil
+	}
+	var (
+		cutoff = time.Now().Add(-maxage)
+		result = records[:0]
+	)
+	for _, r := range records {
+		if r.LastSeen.After(cutoff) {
+			result = append(result, r)
+		}
+	}
+	return result, nil
+}
+
+func crawlStats(ctx *cli.Context) error {
+	records, err := loadCrawlRecords(ctx)
+	if err != nil {
+		return err
+	}
+	var fc *forkCheck
+	if network := ctx.String(crawlNetworkFlag.Name); network != "" {
+		if fc, err = newForkCheck(network, time.Now()); err != nil {
+			return err
+		}
+	}
+	stats := computeCrawlStats(records, fc)
+	return stats.write(os.Stdout, ctx.String(crawlStatsFormatFlag.Name))
+}
+
+func crawlExport(ctx *cli.Context) error {
+	records, err := loadCrawlRecords(ctx)
+	if err != nil {
+		return err
+	}
+	switch format := ctx.String(crawlExportFormatFlag.Name); format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv":
+		return writeCrawlRecordsCSV(os.Stdout, records)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// forkCheck classifies the fork IDs announced by nodes.
+type forkCheck struct {
+	network string
+	current forkid.ID
+	filter  forkid.Filter
+}
+
+func newForkCheck(network string, now time.Time) (*forkCheck, error) {
+	config, genesis, err := ethNetwork(network)
+	if err != nil {
+		return nil, err
+	}
+	// All supported networks have passed their block-based forks, so the fork
+	// ID is determined by the current time.
+	return &forkCheck{
+		network: network,
+		current: forkid.NewID(config, genesis, math.MaxUint64, uint64(now.Unix())),
+		filter:  forkid.NewStaticFilter(config, genesis),
+	}, nil
+}
+
+// crawlStatsReport is the output of the stats command.
+type crawlStatsReport struct {
+	Nodes     int            `json:"nodes"`
+	Dialed    int            `json:"dialed"`
+	Reachable int            `json:"reachable"`
+	Clients   []clientCount  `json:"clients"`
+	Forks     *forkReadiness `json:"forks,omitempty"`
+}
+
+type clientCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// forkReadiness counts nodes by their support of the upcoming fork. Nodes are
+// ready if they announce the current fork ID, including the next scheduled fork.
+type forkReadiness struct {
+	Network      string        `json:"network"`
+	ForkHash     hexutil.Bytes `json:"forkHash"`
+	NextFork     uint64        `json:"nextFork"`
+	Ready        int           `json:"ready"`
+	NotReady     int           `json:"notReady"`
+	Incompatible int           `json:"incompatible"`
+	Unknown      int           `json:"unknown"`
+}
+
+func computeCrawlStats(records []*crawlRecord, fc *forkCheck) *crawlStatsReport {
+	var (
+		stats   = &crawlStatsReport{Nodes: len(records), Clients: []clientCount{}}
+		clients = make(map[string]int)
+	)
+	if fc != nil {
+		stats.Forks = &forkReadiness{
+			Network:  fc.network,
+			ForkHash: fc.current.Hash[:],
+			NextFork: fc.current.Next,
+		}
+	}
+	for _, r := range records {
+		if !r.LastDial.IsZero() {
+			stats.Dialed++
+		}
+		if r.Reachable {
+			stats.Reachable++
+		}
+		if name := r.clientName(); name != "" {
+			clients[name]++
+		}
+		if fc == nil {
+			continue
+		}
+		id, ok := r.forkID()
+		switch {
+		case !ok:
+			stats.Forks.Unknown++
+		case fc.filter(id) != nil:
+			stats.Forks.Incompatible++
+		case id == fc.current:
+			stats.Forks.Ready++
+		default:
+			stats.Forks.NotReady++
+		}
+	}
+	for name, count := range clients {
+		stats.Clients = append(stats.Clients, clientCount{name, count})
+	}
+	sort.Slice(stats.Clients, func(i, j int) bool {
+		a, b := stats.Clients[i], stats.Clients[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Name < b.Name
+	})
+	return stats
+}
+
+func (s *crawlStatsReport) write(w io.Writer, format string) error {
+	switch format {
+	case "text":
+		s.writeText(w)
+		return nil
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(s)
+	case "csv":
+		return s.writeCSV(w)
+	default:
+		return fmt.Errorf("unknown stats format %q", format)
+	}
+}
+
+func (s *crawlStatsReport) writeText(w io.Writer) {
+	fmt.Fprintf(w, "Nodes: %d\n", s.Nodes)
+	fmt.Fprintf(w, "Reachable over RLPx: %d of %d dialed\n", s.Reachable, s.Dialed)
+
+	var identified int
+	for _, c := range s.Clients {
+		identified += c.Count
+	}
+	fmt.Fprintf(w, "Client distribution (%d identified):\n", identified)
+	for _, c := range s.Clients {
+		fmt.Fprintf(w, "  %s: %d (%.1f%%)\n", c.Name, c.Count, percent(c.Count, identified))
+	}
+	if f := s.Forks; f != nil {
+		fmt.Fprintf(w, "Fork readiness (%s, fork hash %v, next fork %d):\n", f.Network, f.ForkHash, f.NextFork)
+		known := s.Nodes - f.Unknown
+		fmt.Fprintf(w, "  ready: %d (%.1f%%)\n", f.Ready, percent(f.Ready, known))
+		fmt.Fprintf(w, "  not ready: %d (%.1f%%)\n", f.NotReady, percent(f.NotReady, known))
+		fmt.Fprintf(w, "  incompatible: %d (%.1f%%)\n", f.Incompatible, percent(f.Incompatible, known))
+		fmt.Fprintf(w, "  no fork ID: %d\n", f.Unknown)
+	}
+}
+
+func (s *crawlStatsReport) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"category", "name", "count"})
+	cw.Write([]string{"nodes", "total", strconv.Itoa(s.Nodes)})
+	cw.Write([]string{"nodes", "dialed", strconv.Itoa(s.Dialed)})
+	cw.Write([]string{"nodes", "reachable", strconv.Itoa(s.Reachable)})
+	for _, c := range s.Clients {
+		cw.Write([]string{"client", c.Name, strconv.Itoa(c.Count)})
+	}
+	if f := s.Forks; f != nil {
+		cw.Write([]string{"fork", "ready", strconv.Itoa(f.Ready)})
+		cw.Write([]string{"fork", "notready", strconv.Itoa(f.NotReady)})
+		cw.Write([]string{"fork", "incompatible", strconv.Itoa(f.Incompatible)})
+		cw.Write([]string{"fork", "unknown", strconv.Itoa(f.Unknown)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeCrawlRecordsCSV writes one row per node, containing the latest state of the node.
+func writeCrawlRecordsCSV(w io.Writer, records []*crawlRecord) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{
+		"id", "firstSeen", "lastSeen", "seq", "enrUpdates", "ip", "tcp", "udp",
+		"forkHash", "forkNext", "reachable", "client", "caps",
+	})
+	for _, r := range records {
+		var (
+			seq, ip, tcp, udp  string
+			forkHash, forkNext string
+		)
+		if n := r.latest(); n != nil {
+			seq = strconv.FormatUint(n.Seq(), 10)
+			if n.IPAddr().IsValid() {
+				ip = n.IPAddr().String()
+			}
+			tcp, udp = strconv.Itoa(n.TCP()), strconv.Itoa(n.UDP())
+		}
+		if id, ok := r.forkID(); ok {
+			forkHash = hexutil.Encode(id.Hash[:])
+			forkNext = strconv.FormatUint(id.Next, 10)
+		}
+		cw.Write([]string{
+			r.ID.String(),
+			r.FirstSeen.Format(time.RFC3339),
+			r.LastSeen.Format(time.RFC3339),
+			seq,
+			strconv.Itoa(len(r.ENRs)),
+			ip, tcp, udp,
+			forkHash, forkNext,
+			strconv.FormatBool(r.Reachable),
+			r.Client,
+			strings.Join(r.Caps, " "),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
@@ -0,0 +1,202 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// maxENRHistory is the number of node records kept per node.
+const maxENRHistory = 32
+
+var crawlRecordPrefix = []byte("crawl-node-") // crawlRecordPrefix + id -> JSON record
+
+// crawlRecord is the history of a node, as stored in the crawl database.
+type crawlRecord struct {
+	ID        enode.ID   `json:"id"`
+	FirstSeen time.Time  `json:"firstSeen"`
+	LastSeen  time.Time  `json:"lastSeen"`
+	LastCheck time.Time  `json:"lastCheck"`
+	ENRs      []enrEntry `json:"enrs"`
+
+	// Fork ID from the "eth" entry of the latest record.
+	ForkHash hexutil.Bytes `json:"forkHash,omitempty"`
+	ForkNext uint64        `json:"forkNext,omitempty"`
+
+	// Results of the last RLPx handshake.
+	LastDial  time.Time `json:"lastDial"`
+	Reachable bool      `json:"reachable"`
+	Client    string    `json:"client,omitempty"`
+	Caps      []string  `json:"caps,omitempty"`
+}
+
+// enrEntry is a node record in the history of a node.
+type enrEntry struct {
+	Seq       uint64      `json:"seq"`
+	FirstSeen time.Time   `json:"firstSeen"`
+	N         *enode.Node `json:"record"`
+}
+
+// latest returns the most recent node record, or nil if none is known.
+func (r *crawlRecord) latest() *enode.Node {
+	if len(r.ENRs) == 0 {
+		return nil
+	}
+	return r.ENRs[len(r.ENRs)-1].N
+}
+
+// forkID returns the fork ID announced by the node.
+func (r *crawlRecord) forkID() (forkid.ID, bool) {
+	if len(r.ForkHash) != 4 {
+		return forkid.ID{}, false
+	}
+	var id forkid.ID
+	copy(id.Hash[:], r.ForkHash)
+	id.Next = r.ForkNext
+	return id, true
+}
+
+// clientName returns the lowercased implementation name of the node's client
+// identifier, e.g. "geth" for "Geth/v1.13.0-stable/linux-amd64/go1.21".
+func (r *crawlRecord) clientName() string {
+	if r.Client == "" {
+		return ""
+	}
+	name, _, _ := strings.Cut(r.Client, "/")
+	return strings.ToLower(name)
+}
+
+// addResponse records a successful ENR request.
+func (r *crawlRecord) addResponse(n *enode.Node, now time.Time) {
+	if r.FirstSeen.IsZero() {
+		r.FirstSeen = now
+	}
+	r.LastSeen = now
+	if latest := r.latest(); latest == nil || n.Seq() > latest.Seq() {
+		r.ENRs = append(r.ENRs, enrEntry{Seq: n.Seq(), FirstSeen: now, N: n})
+		if len(r.ENRs) > maxENRHistory {
+			r.ENRs = append(r.ENRs[:0], r.ENRs[len(r.ENRs)-maxENRHistory:]...)
+		}
+	}
+	if id, ok := ethForkID(n); ok {
+		r.ForkHash = id.Hash[:]
+		r.ForkNext = id.Next
+	} else {
+		r.ForkHash, r.ForkNext = nil, 0
+	}
+}
+
+// addHello records the result of an RLPx handshake.
+func (r *crawlRecord) addHello(h *ethtest.Hello, err error, now time.Time) {
+	r.LastDial = now
+	r.Reachable = err == nil
+	if err != nil {
+		return
+	}
+	r.Client = h.Name
+	r.Caps = r.Caps[:0]
+	for _, cap := range h.Caps {
+		r.Caps = append(r.Caps, cap.String())
+	}
+}
+
+// crawlDB is a persistent store of crawled nodes.
+type crawlDB struct {
+	db ethdb.KeyValueStore
+	mu sync.Mutex // serializes record updates
+}
+
+// openCrawlDB opens the crawl database at the given path.
+func openCrawlDB(path string) (*crawlDB, error) {
+	db, err := leveldb.New(path, 16, 16, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return newCrawlDB(db), nil
+}
+
+func newCrawlDB(db ethdb.KeyValueStore) *crawlDB {
+	return &crawlDB{db: db}
+}
+
+func crawlRecordKey(id enode.ID) []byte {
+	return append(append([]byte{}, crawlRecordPrefix...), id[:]...)
+}
+
+// get returns the record of a node. The returned record is empty if the node is unknown.
+func (db *crawlDB) get(id enode.ID) (*crawlRecord, error) {
+	r := &crawlRecord{ID: id}
+	key := crawlRecordKey(id)
+	if has, err := db.db.Has(key); err != nil || !has {
+		return r, err
+	}
+	blob, err := db.db.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(blob, r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// update applies fn to the record of a node and stores the result.
+func (db *crawlDB) update(id enode.ID, fn func(*crawlRecord)) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	r, err := db.get(id)
+	if err != nil {
+		return err
+	}
+	fn(r)
+	blob, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return db.db.Put(crawlRecordKey(id), blob)
+}
+
+// records returns all stored records, ordered by node ID.
+func (db *crawlDB) records() ([]*crawlRecord, error) {
+	it := db.db.NewIterator(crawlRecordPrefix, nil)
+	defer it.Release()
+
+	var list []*crawlRecord
+	for it.Next() {
+		r := new(crawlRecord)
+		if err := json.Unmarshal(it.Value(), r); err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, it.Error()
+}
+
+func (db *crawlDB) close() error {
+	return db.db.Close()
+}
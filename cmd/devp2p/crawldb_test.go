@@ -0,0 +1,180 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func newCrawlTestNode(t *testing.T, key *ecdsa.PrivateKey, seq uint64, fid *forkid.ID) *enode.Node {
+	var r enr.Record
+	r.SetSeq(seq)
+	if fid != nil {
+		r.Set(enr.WithEntry("eth", struct {
+			ForkID forkid.ID
+			Tail   []rlp.RawValue `rlp:"tail"`
+		}{ForkID: *fid}))
+	}
+	if err := enode.SignV4(&r, key); err != nil {
+		t.Fatal(err)
+	}
+	n, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+// This test checks that node history is recorded in the crawl database.
+func TestCrawlDBHistory(t *testing.T) {
+	var (
+		db    = newCrawlDB(memorydb.New())
+		key   = newTestKey(t)
+		fid   = forkid.ID{Hash: [4]byte{1, 2, 3, 4}, Next: 100}
+		now   = time.Unix(1700000000, 0).UTC()
+		hello = &ethtest.Hello{Name: "Geth/v1.13.0-stable/linux-amd64/go1.21", Caps: []p2p.Cap{{Name: "eth", Version: 68}}}
+	)
+	n1 := newCrawlTestNode(t, key, 1, nil)
+	n2 := newCrawlTestNode(t, key, 2, &fid)
+	steps := []func(*crawlRecord){
+		func(r *crawlRecord) { r.addResponse(n1, now) },
+		func(r *crawlRecord) { r.addResponse(n1, now.Add(time.Minute)) },
+		func(r *crawlRecord) {
+			r.addResponse(n2, now.Add(2*time.Minute))
+			r.addHello(hello, nil, now.Add(2*time.Minute))
+		},
+	}
+	for _, fn := range steps {
+		if err := db.update(n1.ID(), fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err := db.get(n1.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.FirstSeen.Equal(now) || !r.LastSeen.Equal(now.Add(2*time.Minute)) {
+		t.Errorf("wrong first/last seen: %v, %v", r.FirstSeen, r.LastSeen)
+	}
+	if len(r.ENRs) != 2 || r.ENRs[0].Seq != 1 || r.ENRs[1].Seq != 2 {
+		t.Fatalf("wrong ENR history: %+v", r.ENRs)
+	}
+	if !r.ENRs[1].FirstSeen.Equal(now.Add(2 * time.Minute)) {
+		t.Errorf("wrong first seen time of ENR update: %v", r.ENRs[1].FirstSeen)
+	}
+	if id, ok := r.forkID(); !ok || id != fid {
+		t.Errorf("wrong fork ID: %v", id)
+	}
+	if !r.Reachable || r.clientName() != "geth" || !reflect.DeepEqual(r.Caps, []string{"eth/68"}) {
+		t.Errorf("wrong client info: reachable %t, client %q, caps %v", r.Reachable, r.Client, r.Caps)
+	}
+
+	// A failed dial keeps the client identity, but marks the node unreachable.
+	db.update(n1.ID(), func(r *crawlRecord) { r.addHello(nil, errors.New("dial failed"), now.Add(time.Hour)) })
+	if r, _ = db.get(n1.ID()); r.Reachable || r.Client == "" {
+		t.Errorf("wrong client info after failed dial: reachable %t, client %q", r.Reachable, r.Client)
+	}
+	if records, err := db.records(); err != nil || len(records) != 1 {
+		t.Fatalf("wrong records: %d, err %v", len(records), err)
+	}
+}
+
+// This test checks client distribution and fork readiness statistics.
+func TestCrawlStats(t *testing.T) {
+	// Just before the Cancun fork on mainnet.
+	cancun := *params.MainnetChainConfig.CancunTime
+	fc, err := newForkCheck("mainnet", time.Unix(int64(cancun)-1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fc.current.Next != cancun {
+		t.Fatalf("wrong next fork: %d", fc.current.Next)
+	}
+	var (
+		notReady     = forkid.ID{Hash: fc.current.Hash}
+		incompatible = forkid.ID{Hash: [4]byte{0xff, 0xff, 0xff, 0xff}}
+		records      []*crawlRecord
+	)
+	for i, spec := range []struct {
+		client string
+		fid    *forkid.ID
+	}{
+		{"Geth/v1.13.14-stable/linux-amd64/go1.21.7", &fc.current},
+		{"Geth/v1.13.5-stable/linux-amd64/go1.21.4", &notReady},
+		{"Nethermind/v1.25.4+20b10b35/linux-x64/dotnet8.0.2", &fc.current},
+		{"", &incompatible},
+		{"", nil},
+	} {
+		r := &crawlRecord{}
+		r.addResponse(newCrawlTestNode(t, newTestKey(t), uint64(i), spec.fid), time.Now())
+		if spec.client != "" {
+			r.addHello(&ethtest.Hello{Name: spec.client}, nil, time.Now())
+		}
+		records = append(records, r)
+	}
+
+	stats := computeCrawlStats(records, fc)
+	wantClients := []clientCount{{"geth", 2}, {"nethermind", 1}}
+	if !reflect.DeepEqual(stats.Clients, wantClients) {
+		t.Errorf("wrong client distribution: %v", stats.Clients)
+	}
+	if stats.Dialed != 3 || stats.Reachable != 3 {
+		t.Errorf("wrong reachability: dialed %d, reachable %d", stats.Dialed, stats.Reachable)
+	}
+	f := stats.Forks
+	if f.Ready != 2 || f.NotReady != 1 || f.Incompatible != 1 || f.Unknown != 1 {
+		t.Errorf("wrong fork readiness: %+v", f)
+	}
+
+	// Check the CSV export.
+	var buf bytes.Buffer
+	if err := writeCrawlRecordsCSV(&buf, records); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(records)+1 {
+		t.Fatalf("wrong number of CSV rows: %d", len(rows))
+	}
+}
+
+func newTestKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
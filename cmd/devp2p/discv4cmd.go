@@ -91,7 +91,7 @@ var (
 		Name:   "crawl",
 		Usage:  "Updates a nodes.json file with random nodes found in the DHT",
 		Action: discv4Crawl,
-		Flags:  flags.Merge(discoveryNodeFlags, []cli.Flag{crawlTimeoutFlag, crawlParallelismFlag}),
+		Flags:  flags.Merge(discoveryNodeFlags, []cli.Flag{crawlTimeoutFlag, crawlParallelismFlag, crawlDBFlag, crawlRLPxFlag}),
 	}
 	discv4TestCommand = &cli.Command{
 		Name:   "test",
@@ -264,6 +264,12 @@ func discv4Crawl(ctx *cli.Context) error {
 		return err
 	}
 	c.revalidateInterval = 10 * time.Minute
+	if err := setupCrawlDB(ctx, c); err != nil {
+		return err
+	}
+	if c.db != nil {
+		defer c.db.close()
+	}
 	output := c.run(ctx.Duration(crawlTimeoutFlag.Name), ctx.Int(crawlParallelismFlag.Name))
 	writeNodesJSON(nodesFile, output)
 	return nil
@@ -58,6 +58,8 @@ var (
 		Action: discv5Crawl,
 		Flags: flags.Merge(discoveryNodeFlags, []cli.Flag{
 			crawlTimeoutFlag,
+			crawlDBFlag,
+			crawlRLPxFlag,
 		}),
 	}
 	discv5TestCommand = &cli.Command{
@@ -115,6 +117,12 @@ func discv5Crawl(ctx *cli.Context) error {
 		return err
 	}
 	c.revalidateInterval = 10 * time.Minute
+	if err := setupCrawlDB(ctx, c); err != nil {
+		return err
+	}
+	if c.db != nil {
+		defer c.db.close()
+	}
 	output := c.run(ctx.Duration(crawlTimeoutFlag.Name), ctx.Int(crawlParallelismFlag.Name))
 	writeNodesJSON(nodesFile, output)
 	return nil
@@ -25,6 +25,8 @@ import (
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/forkid"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
@@ -73,6 +75,17 @@ func (s *Suite) dialAs(key *ecdsa.PrivateKey) (*Conn, error) {
 	return &conn, nil
 }
 
+// dial69 creates a connection only supporting eth/69.
+func (s *Suite) dial69() (*Conn, error) {
+	conn, err := s.dial()
+	if err != nil {
+		return nil, fmt.Errorf("dial failed: %v", err)
+	}
+	conn.caps = []p2p.Cap{{Name: "eth", Version: 69}}
+	conn.ourHighestProtoVersion = 69
+	return conn, nil
+}
+
 // dialSnap creates a connection with snap/1 capability.
 func (s *Suite) dialSnap() (*Conn, error) {
 	conn, err := s.dial()
@@ -113,7 +126,7 @@ func (c *Conn) ReadMsg(proto Proto, code uint64, msg any) error {
 		if err != nil {
 			return err
 		}
-		if protoOffset(proto)+code == got {
+		if c.protoOffset(proto)+code == got {
 			return rlp.DecodeBytes(data, msg)
 		}
 	}
@@ -126,7 +139,7 @@ func (c *Conn) Write(proto Proto, code uint64, msg any) error {
 	if err != nil {
 		return err
 	}
-	_, err = c.Conn.Write(protoOffset(proto)+code, payload)
+	_, err = c.Conn.Write(c.protoOffset(proto)+code, payload)
 	return err
 }
 
@@ -142,7 +155,7 @@ func (c *Conn) ReadEth() (any, error) {
 			c.Write(baseProto, pongMsg, []byte{})
 			continue
 		}
-		if getProto(code) != ethProto {
+		if c.getProto(code) != ethProto {
 			// Read until eth message.
 			continue
 		}
@@ -151,7 +164,11 @@ func (c *Conn) ReadEth() (any, error) {
 		var msg any
 		switch int(code) {
 		case eth.StatusMsg:
-			msg = new(eth.StatusPacket)
+			if c.negotiatedProtoVersion >= eth.ETH69 {
+				msg = new(eth.StatusPacket69)
+			} else {
+				msg = new(eth.StatusPacket)
+			}
 		case eth.GetBlockHeadersMsg:
 			msg = new(eth.GetBlockHeadersPacket)
 		case eth.BlockHeadersMsg:
@@ -172,6 +189,14 @@ func (c *Conn) ReadEth() (any, error) {
 			msg = new(eth.GetPooledTransactionsPacket)
 		case eth.PooledTransactionsMsg:
 			msg = new(eth.PooledTransactionsPacket)
+		case eth.ReceiptsMsg:
+			if c.negotiatedProtoVersion >= eth.ETH69 {
+				msg = new(eth.ReceiptsPacket69)
+			} else {
+				msg = new(eth.ReceiptsPacket)
+			}
+		case eth.BlockRangeUpdateMsg:
+			msg = new(eth.BlockRangeUpdatePacket)
 		default:
 			panic(fmt.Sprintf("unhandled eth msg code %d", code))
 		}
@@ -190,11 +215,11 @@ func (c *Conn) ReadSnap() (any, error) {
 		if err != nil {
 			return nil, err
 		}
-		if getProto(code) != snapProto {
+		if c.getProto(code) != snapProto {
 			// Read until snap message.
 			continue
 		}
-		code -= baseProtoLen + ethProtoLen
+		code -= baseProtoLen + c.ethProtoLen()
 
 		var msg any
 		switch int(code) {
@@ -225,8 +250,10 @@ func (c *Conn) ReadSnap() (any, error) {
 }
 
 // peer performs both the protocol handshake and the status message
-// exchange with the node in order to peer with it.
-func (c *Conn) peer(chain *Chain, status *eth.StatusPacket) error {
+// exchange with the node in order to peer with it. The status may be either an
+// eth.StatusPacket or an eth.StatusPacket69 depending on the negotiated version,
+// or nil to send the default one.
+func (c *Conn) peer(chain *Chain, status any) error {
 	if err := c.handshake(); err != nil {
 		return fmt.Errorf("handshake failed: %v", err)
 	}
@@ -299,7 +326,7 @@ func (c *Conn) negotiateEthProtocol(caps []p2p.Cap) {
 }
 
 // statusExchange performs a `Status` message exchange with the given node.
-func (c *Conn) statusExchange(chain *Chain, status *eth.StatusPacket) error {
+func (c *Conn) statusExchange(chain *Chain, status any) error {
 loop:
 	for {
 		code, data, err := c.Read()
@@ -307,23 +334,9 @@ loop:
 			return fmt.Errorf("failed to read from connection: %w", err)
 		}
 		switch code {
-		case eth.StatusMsg + protoOffset(ethProto):
-			msg := new(eth.StatusPacket)
-			if err := rlp.DecodeBytes(data, &msg); err != nil {
-				return fmt.Errorf("error decoding status packet: %w", err)
-			}
-			if have, want := msg.Head, chain.blocks[chain.Len()-1].Hash(); have != want {
-				return fmt.Errorf("wrong head block in status, want:  %#x (block %d) have %#x",
-					want, chain.blocks[chain.Len()-1].NumberU64(), have)
-			}
-			if have, want := msg.TD.Cmp(chain.TD()), 0; have != want {
-				return fmt.Errorf("wrong TD in status: have %v want %v", have, want)
-			}
-			if have, want := msg.ForkID, chain.ForkID(); !reflect.DeepEqual(have, want) {
-				return fmt.Errorf("wrong fork ID in status: have %v, want %v", have, want)
-			}
-			if have, want := msg.ProtocolVersion, c.ourHighestProtoVersion; have != uint32(want) {
-				return fmt.Errorf("wrong protocol version: have %v, want %v", have, want)
+		case eth.StatusMsg + c.protoOffset(ethProto):
+			if err := c.checkStatus(chain, data); err != nil {
+				return err
 			}
 			break loop
 		case discMsg:
@@ -346,13 +359,25 @@ loop:
 	}
 	if status == nil {
 		// default status message
-		status = &eth.StatusPacket{
-			ProtocolVersion: uint32(c.negotiatedProtoVersion),
-			NetworkID:       chain.config.ChainID.Uint64(),
-			TD:              chain.TD(),
-			Head:            chain.blocks[chain.Len()-1].Hash(),
-			Genesis:         chain.blocks[0].Hash(),
-			ForkID:          chain.ForkID(),
+		if c.negotiatedProtoVersion >= eth.ETH69 {
+			status = &eth.StatusPacket69{
+				ProtocolVersion: uint32(c.negotiatedProtoVersion),
+				NetworkID:       chain.config.ChainID.Uint64(),
+				Genesis:         chain.blocks[0].Hash(),
+				ForkID:          chain.ForkID(),
+				EarliestBlock:   0,
+				LatestBlock:     chain.Head().NumberU64(),
+				LatestBlockHash: chain.Head().Hash(),
+			}
+		} else {
+			status = &eth.StatusPacket{
+				ProtocolVersion: uint32(c.negotiatedProtoVersion),
+				NetworkID:       chain.config.ChainID.Uint64(),
+				TD:              chain.TD(),
+				Head:            chain.blocks[chain.Len()-1].Hash(),
+				Genesis:         chain.blocks[0].Hash(),
+				ForkID:          chain.ForkID(),
+			}
 		}
 	}
 	if err := c.Write(ethProto, eth.StatusMsg, status); err != nil {
@@ -360,3 +385,46 @@ loop:
 	}
 	return nil
 }
+
+// checkStatus decodes the remote status message according to the negotiated
+// protocol version and verifies it against the expected chain.
+func (c *Conn) checkStatus(chain *Chain, data []byte) error {
+	var (
+		version uint32
+		head    common.Hash
+		forkID  forkid.ID
+	)
+	if c.negotiatedProtoVersion >= eth.ETH69 {
+		msg := new(eth.StatusPacket69)
+		if err := rlp.DecodeBytes(data, &msg); err != nil {
+			return fmt.Errorf("error decoding status packet: %w", err)
+		}
+		if msg.EarliestBlock > msg.LatestBlock {
+			return fmt.Errorf("invalid block range in status: earliest %d > latest %d", msg.EarliestBlock, msg.LatestBlock)
+		}
+		if have, want := msg.LatestBlock, chain.Head().NumberU64(); have != want {
+			return fmt.Errorf("wrong latest block number in status: have %d, want %d", have, want)
+		}
+		version, head, forkID = msg.ProtocolVersion, msg.LatestBlockHash, msg.ForkID
+	} else {
+		msg := new(eth.StatusPacket)
+		if err := rlp.DecodeBytes(data, &msg); err != nil {
+			return fmt.Errorf("error decoding status packet: %w", err)
+		}
+		if have, want := msg.TD.Cmp(chain.TD()), 0; have != want {
+			return fmt.Errorf("wrong TD in status: have %v want %v", have, want)
+		}
+		version, head, forkID = msg.ProtocolVersion, msg.Head, msg.ForkID
+	}
+	if have, want := head, chain.blocks[chain.Len()-1].Hash(); have != want {
+		return fmt.Errorf("wrong head block in status, want:  %#x (block %d) have %#x",
+			want, chain.blocks[chain.Len()-1].NumberU64(), have)
+	}
+	if have, want := forkID, chain.ForkID(); !reflect.DeepEqual(have, want) {
+		return fmt.Errorf("wrong fork ID in status: have %v, want %v", have, want)
+	}
+	if have, want := version, c.ourHighestProtoVersion; have != uint32(want) {
+		return fmt.Errorf("wrong protocol version: have %v, want %v", have, want)
+	}
+	return nil
+}
@@ -16,6 +16,7 @@
 package ethtest
 
 import (
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/rlp"
 )
@@ -30,9 +31,10 @@ const (
 
 // Unexported devp2p protocol lengths from p2p package.
 const (
-	baseProtoLen = 16
-	ethProtoLen  = 17
-	snapProtoLen = 8
+	baseProtoLen  = 16
+	ethProtoLen   = 17
+	eth69ProtoLen = 18
+	snapProtoLen  = 8
 )
 
 // Unexported handshake structure from p2p/peer.go.
@@ -56,15 +58,24 @@ const (
 	snapProto
 )
 
+// ethProtoLen returns the number of message codes reserved by the negotiated
+// eth protocol version.
+func (c *Conn) ethProtoLen() uint64 {
+	if c.negotiatedProtoVersion >= eth.ETH69 {
+		return eth69ProtoLen
+	}
+	return ethProtoLen
+}
+
 // getProto returns the protocol a certain message code is associated with
 // (assuming the negotiated capabilities are exactly {eth,snap})
-func getProto(code uint64) Proto {
+func (c *Conn) getProto(code uint64) Proto {
 	switch {
 	case code < baseProtoLen:
 		return baseProto
-	case code < baseProtoLen+ethProtoLen:
+	case code < baseProtoLen+c.ethProtoLen():
 		return ethProto
-	case code < baseProtoLen+ethProtoLen+snapProtoLen:
+	case code < baseProtoLen+c.ethProtoLen()+snapProtoLen:
 		return snapProto
 	default:
 		panic("unhandled msg code beyond last protocol")
@@ -73,14 +84,14 @@ func getProto(code uint64) Proto {
 
 // protoOffset will return the offset at which the specified protocol's messages
 // begin.
-func protoOffset(proto Proto) uint64 {
+func (c *Conn) protoOffset(proto Proto) uint64 {
 	switch proto {
 	case baseProto:
 		return 0
 	case ethProto:
 		return baseProtoLen
 	case snapProto:
-		return baseProtoLen + ethProtoLen
+		return baseProtoLen + c.ethProtoLen()
 	default:
 		panic("unhandled protocol")
 	}
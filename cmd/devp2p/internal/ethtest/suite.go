@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/internal/utesting"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/trie"
 	"github.com/holiman/uint256"
 )
 
@@ -81,6 +82,11 @@ func (s *Suite) EthTests() []utesting.Test {
 		{Name: "InvalidTxs", Fn: s.TestInvalidTxs},
 		{Name: "NewPooledTxs", Fn: s.TestNewPooledTxs},
 		{Name: "BlobViolations", Fn: s.TestBlobViolations},
+		// eth/69
+		{Name: "Status69", Fn: s.TestStatus69},
+		{Name: "GetReceipts69", Fn: s.TestGetReceipts69},
+		{Name: "BlockRangeUpdate69", Fn: s.TestBlockRangeUpdate69},
+		{Name: "InvalidBlockRangeUpdate69", Fn: s.TestInvalidBlockRangeUpdate69},
 	}
 }
 
@@ -371,6 +377,139 @@ func (s *Suite) TestGetBlockBodies(t *utesting.T) {
 	}
 }
 
+func (s *Suite) TestStatus69(t *utesting.T) {
+	t.Log(`This test performs an eth/69 protocol handshake, checking the advertised block range.`)
+
+	conn, err := s.dial69()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+}
+
+func (s *Suite) TestGetReceipts69(t *utesting.T) {
+	t.Log(`This test sends GetReceipts requests over eth/69 for known blocks in the test chain,
+and verifies the bloom-less receipts against the header receipt roots.`)
+
+	conn, err := s.dial69()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	// Create block receipts request.
+	blocks := []*types.Block{s.chain.blocks[54], s.chain.blocks[75], s.chain.Head()}
+	req := &eth.GetReceiptsPacket{RequestId: 66}
+	for _, block := range blocks {
+		req.GetReceiptsRequest = append(req.GetReceiptsRequest, block.Hash())
+	}
+	if err := conn.Write(ethProto, eth.GetReceiptsMsg, req); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	// Wait for response.
+	resp := new(eth.ReceiptsPacket69)
+	if err := conn.ReadMsg(ethProto, eth.ReceiptsMsg, &resp); err != nil {
+		t.Fatalf("error reading receipts msg: %v", err)
+	}
+	if got, want := resp.RequestId, req.RequestId; got != want {
+		t.Fatalf("unexpected request id in respond: got %d, want %d", got, want)
+	}
+	if len(resp.ReceiptsResponse69) != len(blocks) {
+		t.Fatalf("wrong receipts in response: expected %d lists, got %d", len(blocks), len(resp.ReceiptsResponse69))
+	}
+	receipts, err := resp.ReceiptsResponse69.Unpack()
+	if err != nil {
+		t.Fatalf("invalid receipts in response: %v", err)
+	}
+	for i, block := range blocks {
+		if have, want := types.DeriveSha(types.Receipts(receipts[i]), trie.NewStackTrie(nil)), block.ReceiptHash(); have != want {
+			t.Fatalf("receipt root mismatch for block %d: have %x, want %x", block.NumberU64(), have, want)
+		}
+	}
+}
+
+func (s *Suite) TestBlockRangeUpdate69(t *utesting.T) {
+	t.Log(`This test sends a valid eth/69 BlockRangeUpdate to the node and expects the
+connection to stay alive.`)
+
+	conn, err := s.dial69()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	update := &eth.BlockRangeUpdatePacket{
+		EarliestBlock:   10,
+		LatestBlock:     s.chain.Head().NumberU64(),
+		LatestBlockHash: s.chain.Head().Hash(),
+	}
+	if err := conn.Write(ethProto, eth.BlockRangeUpdateMsg, update); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	// Check the node still serves requests after the announcement.
+	req := &eth.GetBlockHeadersPacket{
+		RequestId: 77,
+		GetBlockHeadersRequest: &eth.GetBlockHeadersRequest{
+			Origin: eth.HashOrNumber{Hash: s.chain.Head().Hash()},
+			Amount: 1,
+		},
+	}
+	if err := conn.Write(ethProto, eth.GetBlockHeadersMsg, req); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	resp := new(eth.BlockHeadersPacket)
+	if err := conn.ReadMsg(ethProto, eth.BlockHeadersMsg, &resp); err != nil {
+		t.Fatalf("error reading block headers msg: %v", err)
+	}
+	if got, want := resp.RequestId, req.RequestId; got != want {
+		t.Fatalf("unexpected request id in respond: got %d, want %d", got, want)
+	}
+}
+
+func (s *Suite) TestInvalidBlockRangeUpdate69(t *utesting.T) {
+	t.Log(`This test sends an eth/69 BlockRangeUpdate with the earliest block past the latest
+one and expects a disconnect.`)
+
+	conn, err := s.dial69()
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+	if err := conn.peer(s.chain, nil); err != nil {
+		t.Fatalf("peering failed: %v", err)
+	}
+	update := &eth.BlockRangeUpdatePacket{
+		EarliestBlock:   s.chain.Head().NumberU64() + 1,
+		LatestBlock:     s.chain.Head().NumberU64(),
+		LatestBlockHash: s.chain.Head().Hash(),
+	}
+	if err := conn.Write(ethProto, eth.BlockRangeUpdateMsg, update); err != nil {
+		t.Fatalf("could not write to connection: %v", err)
+	}
+	// Wait for disconnect.
+	for {
+		code, _, err := conn.Read()
+		if err != nil {
+			t.Fatalf("error reading from connection: %v", err)
+		}
+		switch code {
+		case discMsg:
+			return
+		case pingMsg:
+			conn.Write(baseProto, pongMsg, []byte{})
+		default:
+			t.Fatalf("expected disconnect, got: %d", code)
+		}
+	}
+}
+
 // randBuf makes a random buffer size kilobytes large.
 func randBuf(size int) []byte {
 	buf := make([]byte, size*1024)
@@ -27,6 +27,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -225,30 +227,47 @@ func minAgeFilter(args []string) (nodeFilter, error) {
 	return f, nil
 }
 
-func ethFilter(args []string) (nodeFilter, error) {
-	var filter forkid.Filter
-	switch args[0] {
+// ethNetwork returns the chain config and genesis block of a known network.
+func ethNetwork(name string) (*params.ChainConfig, *types.Block, error) {
+	switch name {
 	case "mainnet":
-		filter = forkid.NewStaticFilter(params.MainnetChainConfig, core.DefaultGenesisBlock().ToBlock())
+		return params.MainnetChainConfig, core.DefaultGenesisBlock().ToBlock(), nil
 	case "goerli":
-		filter = forkid.NewStaticFilter(params.GoerliChainConfig, core.DefaultGoerliGenesisBlock().ToBlock())
+		return params.GoerliChainConfig, core.DefaultGoerliGenesisBlock().ToBlock(), nil
 	case "sepolia":
-		filter = forkid.NewStaticFilter(params.SepoliaChainConfig, core.DefaultSepoliaGenesisBlock().ToBlock())
+		return params.SepoliaChainConfig, core.DefaultSepoliaGenesisBlock().ToBlock(), nil
 	case "holesky":
-		filter = forkid.NewStaticFilter(params.HoleskyChainConfig, core.DefaultHoleskyGenesisBlock().ToBlock())
+		return params.HoleskyChainConfig, core.DefaultHoleskyGenesisBlock().ToBlock(), nil
 	default:
-		return nil, fmt.Errorf("unknown network %q", args[0])
+		return nil, nil, fmt.Errorf("unknown network %q", name)
 	}
+}
+
+// ethForkID returns the fork ID in the "eth" entry of a node record.
+func ethForkID(n *enode.Node) (forkid.ID, bool) {
+	var eth struct {
+		ForkID forkid.ID
+		Tail   []rlp.RawValue `rlp:"tail"`
+	}
+	if n.Load(enr.WithEntry("eth", &eth)) != nil {
+		return forkid.ID{}, false
+	}
+	return eth.ForkID, true
+}
+
+func ethFilter(args []string) (nodeFilter, error) {
+	config, genesis, err := ethNetwork(args[0])
+	if err != nil {
+		return nil, err
+	}
+	filter := forkid.NewStaticFilter(config, genesis)
 
 	f := func(n nodeJSON) bool {
-		var eth struct {
-			ForkID forkid.ID
-			Tail   []rlp.RawValue `rlp:"tail"`
-		}
-		if n.N.Load(enr.WithEntry("eth", &eth)) != nil {
+		id, ok := ethForkID(n.N)
+		if !ok {
 			return false
 		}
-		return filter(eth.ForkID) == nil
+		return filter(id) == nil
 	}
 	return f, nil
 }
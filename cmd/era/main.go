@@ -80,7 +80,7 @@ var (
 	verifyCommand = &cli.Command{
 		Name:      "verify",
 		ArgsUsage: "<expected>",
-		Usage:     "verifies each era1 against expected accumulator root",
+		Usage:     "verifies each era1 and post-merge era against expected accumulator root",
 		Action:    verify,
 	}
 )
@@ -146,13 +146,15 @@ func info(ctx *cli.Context) error {
 	if err != nil {
 		return fmt.Errorf("error reading accumulator: %w", err)
 	}
-	td, err := e.InitialTD()
-	if err != nil {
-		return fmt.Errorf("error reading total difficulty: %w", err)
+	var td *big.Int
+	if !e.PostMerge() {
+		if td, err = e.InitialTD(); err != nil {
+			return fmt.Errorf("error reading total difficulty: %w", err)
+		}
 	}
 	info := struct {
 		Accumulator     common.Hash `json:"accumulator"`
-		TotalDifficulty *big.Int    `json:"totalDifficulty"`
+		TotalDifficulty *big.Int    `json:"totalDifficulty,omitempty"`
 		StartBlock      uint64      `json:"startBlock"`
 		Count           uint64      `json:"count"`
 	}{
@@ -198,13 +200,13 @@ func verify(ctx *cli.Context) error {
 		reported = time.Now()
 	)
 
-	entries, err := era.ReadDir(dir, network)
+	entries, err := era.ReadDirAll(dir, network)
 	if err != nil {
 		return fmt.Errorf("error reading %s: %w", dir, err)
 	}
 
 	if len(entries) != len(roots) {
-		return errors.New("number of era1 files should match the number of accumulator hashes")
+		return errors.New("number of era files should match the number of accumulator hashes")
 	}
 
 	// Verify each epoch matches the expected root.
@@ -214,7 +216,7 @@ func verify(ctx *cli.Context) error {
 			name := entries[i]
 			e, err := era.Open(filepath.Join(dir, name))
 			if err != nil {
-				return fmt.Errorf("error opening era1 file %s: %w", name, err)
+				return fmt.Errorf("error opening era file %s: %w", name, err)
 			}
 			defer e.Close()
 			// Read accumulator and check against expected.
@@ -225,11 +227,11 @@ func verify(ctx *cli.Context) error {
 			}
 			// Recompute accumulator.
 			if err := checkAccumulator(e); err != nil {
-				return fmt.Errorf("error verify era1 file %s: %w", name, err)
+				return fmt.Errorf("error verify era file %s: %w", name, err)
 			}
 			// Give the user some feedback that something is happening.
 			if time.Since(reported) >= 8*time.Second {
-				fmt.Printf("Verifying Era1 files \t\t verified=%d,\t elapsed=%s\n", i, common.PrettyDuration(time.Since(start)))
+				fmt.Printf("Verifying Era files \t\t verified=%d,\t elapsed=%s\n", i, common.PrettyDuration(time.Since(start)))
 				reported = time.Now()
 			}
 			return nil
@@ -254,8 +256,10 @@ func checkAccumulator(e *era.Era) error {
 	if want, err = e.Accumulator(); err != nil {
 		return fmt.Errorf("error reading accumulator: %w", err)
 	}
-	if td, err = e.InitialTD(); err != nil {
-		return fmt.Errorf("error reading total difficulty: %w", err)
+	if !e.PostMerge() {
+		if td, err = e.InitialTD(); err != nil {
+			return fmt.Errorf("error reading total difficulty: %w", err)
+		}
 	}
 	it, err := era.NewIterator(e)
 	if err != nil {
@@ -270,7 +274,9 @@ func checkAccumulator(e *era.Era) error {
 	//      the blocks are all correct (via hash)
 	//
 	// The attributes 1), 2), and 3) are checked for each block. 4) and 5) require
-	// accumulation across the entire set and are verified at the end.
+	// accumulation across the entire set and are verified at the end. Post-merge
+	// eras have no total difficulty, their blocks must instead link up and have
+	// no difficulty.
 	for it.Next() {
 		// 1) next() walks the block index, so we're able to implicitly verify it.
 		if it.Error() != nil {
@@ -290,12 +296,26 @@ func checkAccumulator(e *era.Era) error {
 		if rr != block.ReceiptHash() {
 			return fmt.Errorf("receipt root in block %d mismatch: want %s, got %s", block.NumberU64(), block.ReceiptHash(), rr)
 		}
+		if e.PostMerge() {
+			if block.Difficulty().Sign() != 0 {
+				return fmt.Errorf("block %d in post-merge era has difficulty %v", block.NumberU64(), block.Difficulty())
+			}
+			if len(hashes) > 0 && block.ParentHash() != hashes[len(hashes)-1] {
+				return fmt.Errorf("block %d parent hash mismatch: want %s, got %s", block.NumberU64(), hashes[len(hashes)-1], block.ParentHash())
+			}
+		} else {
+			td.Add(td, block.Difficulty())
+			tds = append(tds, new(big.Int).Set(td))
+		}
 		hashes = append(hashes, block.Hash())
-		td.Add(td, block.Difficulty())
-		tds = append(tds, new(big.Int).Set(td))
 	}
 	// 4+5) Verify accumulator and total difficulty.
-	got, err := era.ComputeAccumulator(hashes, tds)
+	var got common.Hash
+	if e.PostMerge() {
+		got, err = era.ComputeBlockHashes(hashes)
+	} else {
+		got, err = era.ComputeAccumulator(hashes, tds)
+	}
 	if err != nil {
 		return fmt.Errorf("error computing accumulator: %w", err)
 	}
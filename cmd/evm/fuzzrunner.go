@@ -0,0 +1,172 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/statefuzz"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	FuzzSeedFlag = &cli.Int64Flag{
+		Name:     "seed",
+		Usage:    "seed of the first generated test, following tests use consecutive seeds",
+		Value:    time.Now().UnixNano(),
+		Category: flags.VMCategory,
+	}
+	FuzzIterationsFlag = &cli.Uint64Flag{
+		Name:     "iterations",
+		Usage:    "number of tests to generate (0 = unlimited)",
+		Category: flags.VMCategory,
+	}
+	FuzzForksFlag = &cli.StringFlag{
+		Name:     "forks",
+		Usage:    "comma separated list of forks to generate tests for",
+		Value:    strings.Join(statefuzz.DefaultForks, ","),
+		Category: flags.VMCategory,
+	}
+	FuzzContractsFlag = &cli.IntFlag{
+		Name:     "contracts",
+		Usage:    "maximum number of contracts in the generated prestate",
+		Value:    4,
+		Category: flags.VMCategory,
+	}
+	FuzzSnippetsFlag = &cli.IntFlag{
+		Name:     "snippets",
+		Usage:    "maximum number of code snippets per generated contract",
+		Value:    32,
+		Category: flags.VMCategory,
+	}
+	FuzzT8nFlag = &cli.StringFlag{
+		Name:     "t8n",
+		Usage:    "command line of an external t8n tool to compare against, e.g. \"/path/to/evm t8n\"",
+		Category: flags.VMCategory,
+	}
+	FuzzOutdirFlag = &cli.StringFlag{
+		Name:     "outdir",
+		Usage:    "directory where fixtures of mismatching tests are written",
+		Value:    ".",
+		Category: flags.VMCategory,
+	}
+	FuzzNoMinimizeFlag = &cli.BoolFlag{
+		Name:     "nominimize",
+		Usage:    "write mismatching tests as generated, without minimizing them",
+		Category: flags.VMCategory,
+	}
+)
+
+var fuzzCommand = &cli.Command{
+	Action: fuzzCmd,
+	Name:   "fuzz",
+	Usage:  "Differentially fuzzes state transitions with generated state tests",
+	Description: `
+The fuzz command generates random state tests and executes each of them with the
+hash and path state schemes, with snapshot-backed state reads and, optionally,
+an external t8n tool. Whenever the resulting post-state roots or logs disagree,
+the test is minimized and written out as a state test fixture, which can be
+replayed with 'evm statetest'.`,
+	Flags: []cli.Flag{
+		FuzzSeedFlag,
+		FuzzIterationsFlag,
+		FuzzForksFlag,
+		FuzzContractsFlag,
+		FuzzSnippetsFlag,
+		FuzzT8nFlag,
+		FuzzOutdirFlag,
+		FuzzNoMinimizeFlag,
+	},
+}
+
+func fuzzCmd(ctx *cli.Context) error {
+	var (
+		gen = &statefuzz.Generator{
+			Forks:     strings.Split(ctx.String(FuzzForksFlag.Name), ","),
+			Contracts: ctx.Int(FuzzContractsFlag.Name),
+			Snippets:  ctx.Int(FuzzSnippetsFlag.Name),
+		}
+		differ = &statefuzz.Differ{Executors: statefuzz.DefaultExecutors()}
+		outdir = ctx.String(FuzzOutdirFlag.Name)
+		seed   = ctx.Int64(FuzzSeedFlag.Name)
+		limit  = ctx.Uint64(FuzzIterationsFlag.Name)
+	)
+	if command := ctx.String(FuzzT8nFlag.Name); command != "" {
+		differ.Executors = append(differ.Executors, statefuzz.NewT8nExecutor(command, ""))
+	}
+	if err := os.MkdirAll(outdir, 0755); err != nil {
+		return err
+	}
+	var (
+		start    = time.Now()
+		logged   = time.Now()
+		failures int
+	)
+	for i := uint64(0); limit == 0 || i < limit; i, seed = i+1, seed+1 {
+		f, err := gen.Generate(seed)
+		if err != nil {
+			return err
+		}
+		outcomes, mismatch := differ.Diff(f)
+		if mismatch {
+			failures++
+			for j, e := range differ.Executors {
+				log.Warn("State transition mismatch", "seed", seed, "fork", f.Fork, "executor", e.Name(), "outcome", outcomes[j])
+			}
+			path, err := writeFuzzFixture(ctx, differ, f, seed, outdir)
+			if err != nil {
+				return err
+			}
+			log.Warn("Wrote mismatching test", "seed", seed, "file", path)
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Fuzzing state transitions", "tests", i+1, "mismatches", failures, "seed", seed, "elapsed", time.Since(start))
+			logged = time.Now()
+		}
+	}
+	if failures > 0 {
+		return fmt.Errorf("found %d mismatching state transitions", failures)
+	}
+	return nil
+}
+
+// writeFuzzFixture minimizes the mismatching test, seals it with the outcome
+// of the reference executor and writes it to the output directory.
+func writeFuzzFixture(ctx *cli.Context, differ *statefuzz.Differ, f *statefuzz.Fixture, seed int64, outdir string) (string, error) {
+	if !ctx.Bool(FuzzNoMinimizeFlag.Name) {
+		f = statefuzz.Minimize(f, func(f *statefuzz.Fixture) bool {
+			_, mismatch := differ.Diff(f)
+			return mismatch
+		})
+	}
+	f.Seal(differ.Executors[0].Execute(f))
+
+	name := statefuzz.Name(seed)
+	out, err := json.MarshalIndent(map[string]*statefuzz.Fixture{name: f}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(outdir, name+".json")
+	return path, os.WriteFile(path, out, 0644)
+}
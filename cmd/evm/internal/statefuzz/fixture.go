@@ -0,0 +1,162 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package statefuzz
+
+import (
+	"encoding/json"
+	"maps"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// Env is the block environment of a generated state test. The json encoding
+// is understood by both tests.StateTest and the t8n tool.
+type Env struct {
+	Coinbase      common.Address        `json:"currentCoinbase"`
+	Difficulty    *math.HexOrDecimal256 `json:"currentDifficulty,omitempty"`
+	Random        *math.HexOrDecimal256 `json:"currentRandom,omitempty"`
+	GasLimit      math.HexOrDecimal64   `json:"currentGasLimit"`
+	Number        math.HexOrDecimal64   `json:"currentNumber"`
+	Timestamp     math.HexOrDecimal64   `json:"currentTimestamp"`
+	BaseFee       *math.HexOrDecimal256 `json:"currentBaseFee,omitempty"`
+	ExcessBlobGas *math.HexOrDecimal64  `json:"currentExcessBlobGas,omitempty"`
+}
+
+// Transaction is the single transaction of a generated state test, in the
+// 'general state test' format with exactly one data, gas and value entry.
+type Transaction struct {
+	GasPrice             *math.HexOrDecimal256 `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *math.HexOrDecimal256 `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *math.HexOrDecimal256 `json:"maxPriorityFeePerGas,omitempty"`
+	Nonce                math.HexOrDecimal64   `json:"nonce"`
+	To                   *common.Address       `json:"-"`
+	Data                 hexutil.Bytes         `json:"-"`
+	AccessList           types.AccessList      `json:"-"`
+	GasLimit             math.HexOrDecimal64   `json:"-"`
+	Value                *math.HexOrDecimal256 `json:"-"`
+	SecretKey            hexutil.Bytes         `json:"secretKey"`
+}
+
+// PostState is the expected outcome of a state test on a given fork.
+type PostState struct {
+	Root            common.Hash `json:"hash"`
+	Logs            common.Hash `json:"logs"`
+	ExpectException string      `json:"expectException,omitempty"`
+	Indexes         struct {
+		Data  int `json:"data"`
+		Gas   int `json:"gas"`
+		Value int `json:"value"`
+	} `json:"indexes"`
+}
+
+// Fixture is a single generated state test. Its json encoding is compatible
+// with tests.StateTest, so a fixture can be executed by 'evm statetest'.
+type Fixture struct {
+	Fork string // Fork the test is executed on
+	Env  Env
+	Pre  types.GenesisAlloc
+	Tx   Transaction
+	Post map[string][]PostState // Expected outcome, set by Seal
+}
+
+// MarshalJSON encodes the fixture in the general state test format.
+func (f *Fixture) MarshalJSON() ([]byte, error) {
+	type transaction struct {
+		Transaction
+		To          string                `json:"to"`
+		Data        []hexutil.Bytes       `json:"data"`
+		AccessLists []*types.AccessList   `json:"accessLists,omitempty"`
+		GasLimit    []math.HexOrDecimal64 `json:"gasLimit"`
+		Value       []string              `json:"value"`
+	}
+	tx := transaction{
+		Transaction: f.Tx,
+		Data:        []hexutil.Bytes{f.Tx.Data},
+		GasLimit:    []math.HexOrDecimal64{f.Tx.GasLimit},
+		Value:       []string{"0x0"},
+	}
+	if f.Tx.To != nil {
+		tx.To = f.Tx.To.Hex()
+	}
+	if f.Tx.AccessList != nil {
+		tx.AccessLists = []*types.AccessList{&f.Tx.AccessList}
+	}
+	if f.Tx.Value != nil {
+		tx.Value[0] = hexutil.EncodeBig((*big.Int)(f.Tx.Value))
+	}
+	return json.Marshal(&struct {
+		Env  Env                    `json:"env"`
+		Pre  types.GenesisAlloc     `json:"pre"`
+		Tx   transaction            `json:"transaction"`
+		Post map[string][]PostState `json:"post"`
+	}{f.Env, f.Pre, tx, f.post()})
+}
+
+// post returns the post section of the fixture, with an empty placeholder
+// entry for the fixture fork if none has been recorded yet.
+func (f *Fixture) post() map[string][]PostState {
+	if len(f.Post[f.Fork]) > 0 {
+		return f.Post
+	}
+	post := maps.Clone(f.Post)
+	if post == nil {
+		post = make(map[string][]PostState)
+	}
+	post[f.Fork] = []PostState{{}}
+	return post
+}
+
+// StateTest converts the fixture into a runnable state test.
+func (f *Fixture) StateTest() (*tests.StateTest, error) {
+	blob, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	st := new(tests.StateTest)
+	if err := json.Unmarshal(blob, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Copy returns a deep copy of the fixture, which the minimizer can modify
+// without affecting the original.
+func (f *Fixture) Copy() *Fixture {
+	cpy := &Fixture{
+		Fork: f.Fork,
+		Env:  f.Env,
+		Pre:  make(types.GenesisAlloc, len(f.Pre)),
+		Tx:   f.Tx,
+		Post: make(map[string][]PostState, len(f.Post)),
+	}
+	for addr, acc := range f.Pre {
+		acc.Code = common.CopyBytes(acc.Code)
+		acc.Storage = maps.Clone(acc.Storage)
+		cpy.Pre[addr] = acc
+	}
+	cpy.Tx.Data = common.CopyBytes(f.Tx.Data)
+	cpy.Tx.AccessList = append(types.AccessList(nil), f.Tx.AccessList...)
+	for fork, post := range f.Post {
+		cpy.Post[fork] = append([]PostState(nil), post...)
+	}
+	return cpy
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package statefuzz
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// DefaultForks are the forks the fuzzer picks from if none are configured.
+var DefaultForks = []string{"Berlin", "London", "Merge", "Shanghai", "Cancun"}
+
+// senderKey is the key of the transaction sender in all generated tests. It is
+// the well-known key used throughout the ethereum/tests fixtures.
+var senderKey = common.FromHex("45a915e4d060149eb4365960e6a7a45f334393093061116b197e3240065ff2d8")
+
+// Generator produces random state tests. The contracts in the prestate are
+// built by an opcode-aware grammar, so that most of the generated code gets
+// past the trivial failure modes (stack underflow, invalid jumps) and
+// exercises the interpreter, the precompiles and the state database.
+type Generator struct {
+	Forks     []string // Forks to generate tests for, chosen at random
+	Contracts int      // Maximum number of contracts in the prestate
+	Snippets  int      // Maximum number of code snippets per contract
+}
+
+// Generate creates the state test for the given seed. The same seed always
+// yields the same test.
+func (g *Generator) Generate(seed int64) (*Fixture, error) {
+	var (
+		rnd   = rand.New(rand.NewSource(seed))
+		forks = g.Forks
+	)
+	if len(forks) == 0 {
+		forks = DefaultForks
+	}
+	fork := forks[rnd.Intn(len(forks))]
+	config, _, err := tests.GetChainConfig(fork)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		isMerge = config.TerminalTotalDifficulty != nil && config.TerminalTotalDifficulty.BitLen() == 0
+		number  = uint64(1)
+		time    = uint64(1000)
+		rules   = config.Rules(new(big.Int).SetUint64(number), isMerge, time)
+	)
+	f := &Fixture{
+		Fork: fork,
+		Env: Env{
+			Coinbase:  randomAddress(rnd),
+			GasLimit:  math.HexOrDecimal64(30_000_000),
+			Number:    math.HexOrDecimal64(number),
+			Timestamp: math.HexOrDecimal64(time),
+		},
+		Pre: make(types.GenesisAlloc),
+	}
+	if isMerge {
+		f.Env.Random = (*math.HexOrDecimal256)(new(big.Int).SetBytes(randomBytes(rnd, 32)))
+	} else {
+		f.Env.Difficulty = (*math.HexOrDecimal256)(big.NewInt(0x20000))
+	}
+	if rules.IsLondon {
+		f.Env.BaseFee = (*math.HexOrDecimal256)(big.NewInt(10))
+	}
+	if rules.IsCancun {
+		f.Env.ExcessBlobGas = new(math.HexOrDecimal64)
+	}
+	// Create the sender and a handful of contracts calling into each other.
+	key, err := crypto.ToECDSA(senderKey)
+	if err != nil {
+		return nil, err
+	}
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	f.Pre[sender] = types.Account{
+		Balance: new(big.Int).Lsh(big.NewInt(1), 80),
+		Nonce:   uint64(rnd.Intn(3)),
+	}
+	contracts := make([]common.Address, 1+rnd.Intn(max(g.Contracts, 1)))
+	for i := range contracts {
+		contracts[i] = randomAddress(rnd)
+	}
+	targets := append([]common.Address{sender, f.Env.Coinbase}, contracts...)
+	for _, addr := range contracts {
+		acc := types.Account{
+			Code:    newProgram(rnd, rules, targets).generate(1 + rnd.Intn(max(g.Snippets, 1))),
+			Balance: big.NewInt(int64(rnd.Intn(1_000_000))),
+			Nonce:   uint64(rnd.Intn(2)),
+		}
+		if n := rnd.Intn(4); n > 0 {
+			acc.Storage = make(map[common.Hash]common.Hash, n)
+			for i := 0; i < n; i++ {
+				acc.Storage[common.BigToHash(big.NewInt(int64(rnd.Intn(8))))] = common.BigToHash(big.NewInt(1 + rnd.Int63()))
+			}
+		}
+		f.Pre[addr] = acc
+	}
+	// Assemble the transaction, which is a call into one of the contracts or,
+	// occasionally, a contract creation running generated code as initcode.
+	f.Tx = Transaction{
+		Nonce:     math.HexOrDecimal64(f.Pre[sender].Nonce),
+		Data:      randomBytes(rnd, rnd.Intn(100)),
+		GasLimit:  math.HexOrDecimal64(21_000 + rnd.Intn(2_000_000)),
+		Value:     (*math.HexOrDecimal256)(big.NewInt(int64(rnd.Intn(1000)))),
+		SecretKey: senderKey,
+	}
+	if rnd.Intn(8) == 0 {
+		f.Tx.Data = newProgram(rnd, rules, targets).generate(1 + rnd.Intn(max(g.Snippets, 1)))
+	} else {
+		f.Tx.To = &contracts[rnd.Intn(len(contracts))]
+	}
+	if rules.IsBerlin && rnd.Intn(4) == 0 {
+		f.Tx.AccessList = types.AccessList{{
+			Address:     contracts[rnd.Intn(len(contracts))],
+			StorageKeys: []common.Hash{common.BigToHash(big.NewInt(int64(rnd.Intn(8))))},
+		}}
+	}
+	if rules.IsLondon && rnd.Intn(2) == 0 {
+		f.Tx.MaxFeePerGas = (*math.HexOrDecimal256)(big.NewInt(int64(10 + rnd.Intn(100))))
+		f.Tx.MaxPriorityFeePerGas = (*math.HexOrDecimal256)(big.NewInt(int64(rnd.Intn(10))))
+	} else {
+		f.Tx.GasPrice = (*math.HexOrDecimal256)(big.NewInt(int64(10 + rnd.Intn(100))))
+	}
+	return f, nil
+}
+
+// Name returns the name of the test generated for the given seed.
+func Name(seed int64) string {
+	return fmt.Sprintf("fuzz-%d", seed)
+}
+
+func randomAddress(rnd *rand.Rand) common.Address {
+	return common.BytesToAddress(randomBytes(rnd, common.AddressLength))
+}
+
+func randomBytes(rnd *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	rnd.Read(b)
+	return b
+}
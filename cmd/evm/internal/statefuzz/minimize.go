@@ -0,0 +1,206 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package statefuzz
+
+import (
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Minimize shrinks a fixture while the predicate keeps holding for it, and
+// returns the smallest fixture found. The predicate is typically "the
+// executors still disagree". The input fixture is not modified.
+//
+// The reduction is greedy: it drops prestate accounts and storage slots,
+// removes ever smaller chunks of instructions from contract code, and
+// truncates the calldata, retrying until no step makes progress.
+func Minimize(f *Fixture, interesting func(*Fixture) bool) *Fixture {
+	best := f.Copy()
+	for progress := true; progress; {
+		progress = false
+		for _, step := range []func(*Fixture, func(*Fixture) bool) (*Fixture, bool){
+			dropAccounts, dropStorage, shrinkCode, shrinkCalldata, clearTx,
+		} {
+			if smaller, ok := step(best, interesting); ok {
+				best, progress = smaller, true
+			}
+		}
+	}
+	return best
+}
+
+// dropAccounts removes prestate accounts, except for the transaction sender.
+func dropAccounts(f *Fixture, interesting func(*Fixture) bool) (*Fixture, bool) {
+	var (
+		sender = senderAddress(f)
+		found  bool
+	)
+	for _, addr := range sortedAddresses(f) {
+		if addr == sender {
+			continue
+		}
+		cand := f.Copy()
+		delete(cand.Pre, addr)
+		if interesting(cand) {
+			f, found = cand, true
+		}
+	}
+	return f, found
+}
+
+// dropStorage removes storage slots from prestate accounts.
+func dropStorage(f *Fixture, interesting func(*Fixture) bool) (*Fixture, bool) {
+	var found bool
+	for _, addr := range sortedAddresses(f) {
+		var slots []common.Hash
+		for slot := range f.Pre[addr].Storage {
+			slots = append(slots, slot)
+		}
+		slices.SortFunc(slots, func(a, b common.Hash) int { return a.Cmp(b) })
+
+		for _, slot := range slots {
+			cand := f.Copy()
+			delete(cand.Pre[addr].Storage, slot)
+			if interesting(cand) {
+				f, found = cand, true
+			}
+		}
+	}
+	return f, found
+}
+
+// shrinkCode removes chunks of whole instructions from the contract code in
+// the prestate and from the initcode of a creation transaction. Chunks start
+// at half of the instructions and get halved until single instructions are
+// tried.
+func shrinkCode(f *Fixture, interesting func(*Fixture) bool) (*Fixture, bool) {
+	var found bool
+	for _, addr := range sortedAddresses(f) {
+		get := func(f *Fixture) []byte { return f.Pre[addr].Code }
+		set := func(f *Fixture, code []byte) {
+			acc := f.Pre[addr]
+			acc.Code = code
+			f.Pre[addr] = acc
+		}
+		if smaller, ok := shrinkInstructions(f, get, set, interesting); ok {
+			f, found = smaller, true
+		}
+	}
+	if f.Tx.To == nil {
+		get := func(f *Fixture) []byte { return f.Tx.Data }
+		set := func(f *Fixture, code []byte) { f.Tx.Data = code }
+		if smaller, ok := shrinkInstructions(f, get, set, interesting); ok {
+			f, found = smaller, true
+		}
+	}
+	return f, found
+}
+
+func shrinkInstructions(f *Fixture, get func(*Fixture) []byte, set func(*Fixture, []byte), interesting func(*Fixture) bool) (*Fixture, bool) {
+	var found bool
+	for chunk := len(instructions(get(f))) / 2; chunk > 0; chunk /= 2 {
+		for start := 0; ; {
+			offsets := instructions(get(f))
+			if start >= len(offsets) {
+				break
+			}
+			end := len(get(f))
+			if start+chunk < len(offsets) {
+				end = offsets[start+chunk]
+			}
+			code := get(f)
+			cand := f.Copy()
+			set(cand, append(slices.Clone(code[:offsets[start]]), code[end:]...))
+			if interesting(cand) {
+				f, found = cand, true
+				continue // retry the same position on the shortened code
+			}
+			start += chunk
+		}
+	}
+	return f, found
+}
+
+// instructions returns the offsets of the instructions in code.
+func instructions(code []byte) []int {
+	var offsets []int
+	for pc := 0; pc < len(code); pc++ {
+		offsets = append(offsets, pc)
+		if op := vm.OpCode(code[pc]); op.IsPush() {
+			pc += int(op - vm.PUSH0)
+		}
+	}
+	return offsets
+}
+
+// shrinkCalldata truncates the calldata of a message call.
+func shrinkCalldata(f *Fixture, interesting func(*Fixture) bool) (*Fixture, bool) {
+	if f.Tx.To == nil {
+		return f, false
+	}
+	var found bool
+	for len(f.Tx.Data) > 0 {
+		cand := f.Copy()
+		cand.Tx.Data = cand.Tx.Data[:len(cand.Tx.Data)/2]
+		if !interesting(cand) {
+			break
+		}
+		f, found = cand, true
+	}
+	return f, found
+}
+
+// clearTx removes the optional parts of the transaction: value and access list.
+func clearTx(f *Fixture, interesting func(*Fixture) bool) (*Fixture, bool) {
+	var found bool
+	if f.Tx.Value != nil && (*big.Int)(f.Tx.Value).Sign() != 0 {
+		cand := f.Copy()
+		cand.Tx.Value = nil
+		if interesting(cand) {
+			f, found = cand, true
+		}
+	}
+	if f.Tx.AccessList != nil {
+		cand := f.Copy()
+		cand.Tx.AccessList = nil
+		if interesting(cand) {
+			f, found = cand, true
+		}
+	}
+	return f, found
+}
+
+func senderAddress(f *Fixture) common.Address {
+	key, err := crypto.ToECDSA(f.Tx.SecretKey)
+	if err != nil {
+		return common.Address{}
+	}
+	return crypto.PubkeyToAddress(key.PublicKey)
+}
+
+func sortedAddresses(f *Fixture) []common.Address {
+	addrs := make([]common.Address, 0, len(f.Pre))
+	for addr := range f.Pre {
+		addrs = append(addrs, addr)
+	}
+	slices.SortFunc(addrs, func(a, b common.Address) int { return a.Cmp(b) })
+	return addrs
+}
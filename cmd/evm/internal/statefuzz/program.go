@@ -0,0 +1,446 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package statefuzz
+
+import (
+	"math/big"
+	"math/rand"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// interestingWords are stack values which tend to hit edge cases in the
+// arithmetic and comparison instructions.
+var interestingWords = []*uint256.Int{
+	uint256.NewInt(0),
+	uint256.NewInt(1),
+	uint256.NewInt(2),
+	uint256.NewInt(31),
+	uint256.NewInt(32),
+	uint256.NewInt(255),
+	uint256.NewInt(256),
+	new(uint256.Int).Lsh(uint256.NewInt(1), 255),
+	new(uint256.Int).Sub(new(uint256.Int).Lsh(uint256.NewInt(1), 255), uint256.NewInt(1)),
+	new(uint256.Int).SetAllOne(),
+	new(uint256.Int).Sub(new(uint256.Int).SetAllOne(), uint256.NewInt(1)),
+}
+
+// program builds EVM bytecode out of self-contained snippets. Every snippet
+// leaves the stack as it found it, and results of instructions are written
+// to storage so that they affect the post-state root.
+type program struct {
+	rnd     *rand.Rand
+	jt      vm.JumpTable
+	rules   params.Rules
+	targets []common.Address // addresses used for calls and account queries
+	code    []byte
+	slot    uint64 // next storage slot used to record a result
+}
+
+func newProgram(rnd *rand.Rand, rules params.Rules, targets []common.Address) *program {
+	jt, _ := vm.LookupInstructionSet(rules)
+	return &program{rnd: rnd, jt: jt, rules: rules, targets: targets}
+}
+
+// has reports whether the instruction is defined in the active fork.
+func (p *program) has(op vm.OpCode) bool {
+	return op == vm.STOP || p.jt[op].HasCost()
+}
+
+// op appends plain instructions to the program.
+func (p *program) op(ops ...vm.OpCode) {
+	for _, op := range ops {
+		p.code = append(p.code, byte(op))
+	}
+}
+
+// push appends the shortest PUSH instruction for the given value.
+func (p *program) push(v *uint256.Int) {
+	if v.IsZero() && p.has(vm.PUSH0) {
+		p.op(vm.PUSH0)
+		return
+	}
+	n := max(v.ByteLen(), 1)
+	p.code = append(p.code, byte(vm.PUSH1)+byte(n-1))
+	b := v.Bytes32()
+	p.code = append(p.code, b[32-n:]...)
+}
+
+func (p *program) pushU64(v uint64) {
+	p.push(uint256.NewInt(v))
+}
+
+func (p *program) pushAddr(addr common.Address) {
+	p.push(new(uint256.Int).SetBytes(addr.Bytes()))
+}
+
+// pushWord pushes a value which is either interesting, small or random.
+func (p *program) pushWord() {
+	switch p.rnd.Intn(4) {
+	case 0, 1:
+		p.push(interestingWords[p.rnd.Intn(len(interestingWords))])
+	case 2:
+		p.pushU64(uint64(p.rnd.Intn(1024)))
+	default:
+		var b [32]byte
+		p.rnd.Read(b[:p.rnd.Intn(32)+1])
+		p.push(new(uint256.Int).SetBytes(b[:]))
+	}
+}
+
+// pushSmall pushes a value usable as a memory offset or size, which keeps
+// memory expansion cheap.
+func (p *program) pushSmall() {
+	p.pushU64(uint64(p.rnd.Intn(160)))
+}
+
+// pushTarget pushes an address that is likely to be interesting to the
+// transaction: a prestate account, a precompile or a random address.
+func (p *program) pushTarget() {
+	switch n := p.rnd.Intn(8); {
+	case n < 4 && len(p.targets) > 0:
+		p.pushAddr(p.targets[p.rnd.Intn(len(p.targets))])
+	case n < 7:
+		precompiles := vm.ActivePrecompiles(p.rules)
+		p.pushAddr(precompiles[p.rnd.Intn(len(precompiles))])
+	default:
+		var addr common.Address
+		p.rnd.Read(addr[:])
+		p.pushAddr(addr)
+	}
+}
+
+// record stores the value on top of the stack into the next result slot.
+func (p *program) record() {
+	p.pushU64(p.slot)
+	p.op(vm.SSTORE)
+	p.slot++
+}
+
+// generate appends n random snippets followed by a terminating instruction.
+func (p *program) generate(n int) []byte {
+	snippets := []func(){
+		p.arithmetic, p.arithmetic, p.arithmetic,
+		p.context, p.context,
+		p.memory, p.memory,
+		p.storage,
+		p.transient,
+		p.call, p.call,
+		p.precompile, p.precompile,
+		p.log,
+		p.branch,
+		p.create,
+	}
+	for i := 0; i < n; i++ {
+		snippets[p.rnd.Intn(len(snippets))]()
+	}
+	p.terminate()
+	return p.code
+}
+
+// arithmetic emits an arithmetic, comparison or bitwise instruction with
+// random operands.
+func (p *program) arithmetic() {
+	var ops []vm.OpCode
+	for op := vm.ADD; op <= vm.SAR; op++ {
+		if p.has(op) {
+			ops = append(ops, op)
+		}
+	}
+	p.generic(ops[p.rnd.Intn(len(ops))], p.pushWord)
+}
+
+// context emits an instruction that inspects the execution environment.
+func (p *program) context() {
+	ops := []vm.OpCode{
+		vm.ADDRESS, vm.ORIGIN, vm.CALLER, vm.CALLVALUE, vm.CALLDATALOAD,
+		vm.CALLDATASIZE, vm.CODESIZE, vm.GASPRICE, vm.RETURNDATASIZE,
+		vm.COINBASE, vm.TIMESTAMP, vm.NUMBER, vm.DIFFICULTY, vm.GASLIMIT,
+		vm.CHAINID, vm.SELFBALANCE, vm.BASEFEE, vm.BLOBHASH, vm.BLOBBASEFEE,
+		vm.BLOCKHASH, vm.MSIZE, vm.PC,
+	}
+	accountOps := []vm.OpCode{vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODEHASH}
+	if p.rnd.Intn(3) == 0 {
+		op := accountOps[p.rnd.Intn(len(accountOps))]
+		if p.has(op) {
+			p.pushTarget()
+			p.op(op)
+			p.record()
+		}
+		return
+	}
+	if op := ops[p.rnd.Intn(len(ops))]; p.has(op) {
+		p.generic(op, p.pushSmall)
+	}
+}
+
+// memory emits memory writes, reads and copies.
+func (p *program) memory() {
+	switch p.rnd.Intn(6) {
+	case 0, 1:
+		p.pushWord()
+		p.pushSmall()
+		p.op([]vm.OpCode{vm.MSTORE, vm.MSTORE8}[p.rnd.Intn(2)])
+	case 2:
+		p.generic(vm.MLOAD, p.pushSmall)
+	case 3:
+		p.generic(vm.KECCAK256, p.pushSmall)
+	case 4:
+		ops := []vm.OpCode{vm.CALLDATACOPY, vm.CODECOPY, vm.RETURNDATACOPY, vm.MCOPY}
+		if op := ops[p.rnd.Intn(len(ops))]; p.has(op) {
+			p.generic(op, p.pushSmall)
+		}
+	default:
+		p.pushSmall()
+		p.pushSmall()
+		p.pushSmall()
+		p.pushTarget()
+		p.op(vm.EXTCODECOPY)
+	}
+}
+
+// storage emits persistent storage accesses to a small set of slots, so that
+// reads and writes are likely to alias.
+func (p *program) storage() {
+	if p.rnd.Intn(2) == 0 {
+		p.pushU64(uint64(p.rnd.Intn(8)))
+		p.op(vm.SLOAD)
+		p.record()
+		return
+	}
+	p.pushWord()
+	p.pushU64(uint64(p.rnd.Intn(8)))
+	p.op(vm.SSTORE)
+}
+
+// transient emits transient storage accesses, if available.
+func (p *program) transient() {
+	if !p.has(vm.TSTORE) {
+		p.storage()
+		return
+	}
+	p.pushWord()
+	p.pushU64(uint64(p.rnd.Intn(4)))
+	p.op(vm.TSTORE)
+	p.pushU64(uint64(p.rnd.Intn(4)))
+	p.op(vm.TLOAD)
+	p.record()
+}
+
+// call emits one of the call instructions against a random target, and
+// records its success flag.
+func (p *program) call() {
+	ops := []vm.OpCode{vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL}
+	op := ops[p.rnd.Intn(len(ops))]
+	if !p.has(op) {
+		op = vm.CALL
+	}
+	p.emitCall(op, p.pushTarget)
+}
+
+// emitCall emits a call instruction with small memory arguments, where the
+// callee address is pushed by target.
+func (p *program) emitCall(op vm.OpCode, target func()) {
+	p.pushSmall() // retSize
+	p.pushSmall() // retOffset
+	p.pushSmall() // argsSize
+	p.pushSmall() // argsOffset
+	if op == vm.CALL || op == vm.CALLCODE {
+		p.pushU64(uint64(p.rnd.Intn(3))) // value
+	}
+	target()
+	if p.rnd.Intn(2) == 0 {
+		p.op(vm.GAS)
+	} else {
+		p.pushU64(uint64(p.rnd.Intn(100000)))
+	}
+	p.op(op)
+	p.record()
+}
+
+// precompile writes a well-formed input for a random precompile to memory
+// and calls it, recording the result and the returned data.
+func (p *program) precompile() {
+	precompiles := vm.ActivePrecompiles(p.rules)
+	addr := precompiles[p.rnd.Intn(len(precompiles))]
+
+	input := precompileInput(p.rnd, addr)
+	for i := 0; i < len(input); i += 32 {
+		var word [32]byte
+		copy(word[:], input[i:])
+		p.push(new(uint256.Int).SetBytes(word[:]))
+		p.pushU64(uint64(i))
+		p.op(vm.MSTORE)
+	}
+	p.pushU64(64)                 // retSize
+	p.pushU64(0)                  // retOffset
+	p.pushU64(uint64(len(input))) // argsSize
+	p.pushU64(0)                  // argsOffset
+	p.pushAddr(addr)
+	p.op(vm.GAS)
+	p.op(vm.STATICCALL)
+	p.record()
+	p.pushU64(0)
+	p.op(vm.MLOAD)
+	p.record()
+}
+
+// precompileInput returns an input for the precompile at the given address,
+// which is mostly well-formed so that execution gets past input validation.
+func precompileInput(rnd *rand.Rand, addr common.Address) []byte {
+	random := func(n int) []byte {
+		b := make([]byte, n)
+		rnd.Read(b)
+		return b
+	}
+	switch addr {
+	case common.BytesToAddress([]byte{0x01}): // ecrecover
+		input := random(128)
+		copy(input[32:64], common.LeftPadBytes([]byte{byte(27 + rnd.Intn(2))}, 32))
+		return input
+	case common.BytesToAddress([]byte{0x05}): // modexp
+		var (
+			blen = rnd.Intn(33)
+			elen = rnd.Intn(33)
+			mlen = rnd.Intn(33)
+		)
+		input := append(common.LeftPadBytes(big.NewInt(int64(blen)).Bytes(), 32), common.LeftPadBytes(big.NewInt(int64(elen)).Bytes(), 32)...)
+		input = append(input, common.LeftPadBytes(big.NewInt(int64(mlen)).Bytes(), 32)...)
+		return append(input, random(blen+elen+mlen)...)
+	case common.BytesToAddress([]byte{0x06}): // bn256 add
+		return curvePoints(rnd, 2)
+	case common.BytesToAddress([]byte{0x07}): // bn256 scalar mul
+		return append(curvePoints(rnd, 1), random(32)...)
+	case common.BytesToAddress([]byte{0x08}): // bn256 pairing
+		return random(192 * rnd.Intn(2))
+	case common.BytesToAddress([]byte{0x09}): // blake2f
+		input := append(common.LeftPadBytes(big.NewInt(int64(rnd.Intn(64))).Bytes(), 4), random(208)...)
+		return append(input, byte(rnd.Intn(2)))
+	case common.BytesToAddress([]byte{0x0a}): // kzg point evaluation
+		return random(192)
+	default:
+		return random(rnd.Intn(100))
+	}
+}
+
+// curvePoints returns n encoded bn256 points which are either the generator
+// or the point at infinity, so that the curve checks pass.
+func curvePoints(rnd *rand.Rand, n int) []byte {
+	var out []byte
+	for i := 0; i < n; i++ {
+		if rnd.Intn(2) == 0 {
+			out = append(out, make([]byte, 64)...)
+			continue
+		}
+		out = append(out, common.LeftPadBytes([]byte{1}, 32)...)
+		out = append(out, common.LeftPadBytes([]byte{2}, 32)...)
+	}
+	return out
+}
+
+// log emits a LOGn instruction with random topics over a small memory range.
+func (p *program) log() {
+	n := p.rnd.Intn(5)
+	for i := 0; i < n; i++ {
+		p.pushWord()
+	}
+	p.pushSmall()
+	p.pushSmall()
+	p.op(vm.LOG0 + vm.OpCode(n))
+}
+
+// branch emits a conditional forward jump over a nested snippet.
+func (p *program) branch() {
+	p.pushU64(uint64(p.rnd.Intn(2)))
+	p.op(vm.PUSH2)
+	patch := len(p.code)
+	p.code = append(p.code, 0, 0)
+	p.op(vm.JUMPI)
+
+	switch p.rnd.Intn(3) {
+	case 0:
+		p.arithmetic()
+	case 1:
+		p.storage()
+	default:
+		p.call()
+	}
+	dest := len(p.code)
+	p.code[patch], p.code[patch+1] = byte(dest>>8), byte(dest)
+	p.op(vm.JUMPDEST)
+}
+
+// create deploys a small contract whose initcode returns random runtime
+// code, recording the resulting address.
+func (p *program) create() {
+	op := vm.CREATE
+	if p.has(vm.CREATE2) && p.rnd.Intn(2) == 0 {
+		op = vm.CREATE2
+	}
+	// Initcode: return the 'size' first bytes of memory filled with a word.
+	var initcode []byte
+	size := byte(p.rnd.Intn(33))
+	initcode = append(initcode, byte(vm.PUSH1), byte(p.rnd.Intn(256)), byte(vm.PUSH1), 0, byte(vm.MSTORE))
+	initcode = append(initcode, byte(vm.PUSH1), size, byte(vm.PUSH1), 0, byte(vm.RETURN))
+
+	p.push(new(uint256.Int).SetBytes(common.RightPadBytes(initcode, 32)))
+	p.pushU64(0)
+	p.op(vm.MSTORE)
+	if op == vm.CREATE2 {
+		p.pushU64(uint64(p.rnd.Intn(4))) // salt
+	}
+	p.pushU64(uint64(len(initcode)))
+	p.pushU64(0)
+	p.pushU64(uint64(p.rnd.Intn(2))) // value
+	p.op(op)
+	p.record()
+}
+
+// generic emits an instruction which consumes its operands from the stack
+// and records its result, if it produces one. The operands are pushed by arg.
+func (p *program) generic(op vm.OpCode, arg func()) {
+	minStack, maxStack := p.jt[op].Stack()
+	for i := 0; i < minStack; i++ {
+		arg()
+	}
+	p.op(op)
+	if pushes := int(params.StackLimit) + minStack - maxStack; pushes > 0 {
+		p.record()
+	}
+}
+
+// terminate ends the program with a halting instruction.
+func (p *program) terminate() {
+	switch p.rnd.Intn(8) {
+	case 0:
+		p.pushSmall()
+		p.pushSmall()
+		p.op(vm.REVERT)
+	case 1:
+		p.pushSmall()
+		p.pushSmall()
+		p.op(vm.RETURN)
+	case 2:
+		p.pushTarget()
+		p.op(vm.SELFDESTRUCT)
+	default:
+		p.op(vm.STOP)
+	}
+}
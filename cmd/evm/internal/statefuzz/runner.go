@@ -0,0 +1,319 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package statefuzz
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// Outcome is the result of executing a fixture on one of the executors.
+type Outcome struct {
+	Root      common.Hash `json:"stateRoot"`
+	Logs      common.Hash `json:"logsHash"`
+	Err       string      `json:"error,omitempty"` // failure of the executor itself
+	Exception string      `json:"-"`               // validation error of a rejected transaction
+}
+
+func (o Outcome) String() string {
+	if o.Err != "" {
+		return fmt.Sprintf("error(%s)", o.Err)
+	}
+	if o.Exception != "" {
+		return fmt.Sprintf("root %x logs %x rejected(%s)", o.Root, o.Logs, o.Exception)
+	}
+	return fmt.Sprintf("root %x logs %x", o.Root, o.Logs)
+}
+
+// agrees reports whether two outcomes are equivalent. The error messages of
+// rejected transactions differ between implementations, so only the fact that
+// the transaction was rejected is compared.
+func (o Outcome) agrees(other Outcome) bool {
+	return o.Root == other.Root && o.Logs == other.Logs && o.Err == other.Err &&
+		(o.Exception == "") == (other.Exception == "")
+}
+
+// Executor runs a fixture and reports the resulting post-state.
+type Executor interface {
+	Name() string
+	Execute(f *Fixture) Outcome
+}
+
+// LocalExecutor runs fixtures in-process through tests.StateTest, using the
+// given state scheme and optionally the snapshotter.
+type LocalExecutor struct {
+	Scheme   string
+	Snapshot bool
+}
+
+// Name implements Executor.
+func (e *LocalExecutor) Name() string {
+	if e.Snapshot {
+		return e.Scheme + "+snap"
+	}
+	return e.Scheme
+}
+
+// Execute implements Executor.
+func (e *LocalExecutor) Execute(f *Fixture) (out Outcome) {
+	test, err := f.StateTest()
+	if err != nil {
+		return Outcome{Err: err.Error()}
+	}
+	// Bugs in the EVM are what we are looking for, so make sure a panic is
+	// reported like any other mismatch instead of aborting the fuzzer.
+	defer func() {
+		if r := recover(); r != nil {
+			out = Outcome{Err: fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+	// Transaction validation errors are not failures of the executor: the root
+	// after a rejected transaction is still a valid outcome to compare.
+	st, root, err := test.RunNoVerify(tests.StateSubtest{Fork: f.Fork}, vm.Config{}, e.Snapshot, e.Scheme)
+	defer st.Close()
+
+	out.Root = root
+	if err != nil {
+		out.Exception = err.Error()
+	}
+	if st.StateDB != nil {
+		out.Logs = rlpHash(st.StateDB.Logs())
+	}
+	return out
+}
+
+// T8nExecutor runs fixtures through an external t8n tool, which may be any
+// implementation of the 'evm t8n' command line interface.
+type T8nExecutor struct {
+	Command []string // binary and leading arguments, e.g. [evm t8n]
+	Workdir string   // directory for temporary input and output files
+}
+
+// NewT8nExecutor creates an executor from a command line, which is split on
+// whitespace.
+func NewT8nExecutor(command, workdir string) *T8nExecutor {
+	return &T8nExecutor{Command: strings.Fields(command), Workdir: workdir}
+}
+
+// Name implements Executor.
+func (e *T8nExecutor) Name() string {
+	return "t8n(" + strings.Join(e.Command, " ") + ")"
+}
+
+// Execute implements Executor.
+func (e *T8nExecutor) Execute(f *Fixture) Outcome {
+	dir, err := os.MkdirTemp(e.Workdir, "t8n-")
+	if err != nil {
+		return Outcome{Err: err.Error()}
+	}
+	defer os.RemoveAll(dir)
+
+	if err := writeT8nInput(dir, f); err != nil {
+		return Outcome{Err: err.Error()}
+	}
+	args := append(append([]string{}, e.Command[1:]...),
+		"--input.alloc", "alloc.json",
+		"--input.env", "env.json",
+		"--input.txs", "txs.json",
+		"--output.basedir", dir,
+		"--output.result", "result.json",
+		"--output.alloc", "post.json",
+		"--state.fork", f.Fork,
+		"--state.chainid", "1",
+		"--state.reward", "-1",
+	)
+	cmd := exec.Command(e.Command[0], args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return Outcome{Err: fmt.Sprintf("%v: %s", err, lastLine(stderr.String()))}
+	}
+	blob, err := os.ReadFile(filepath.Join(dir, "result.json"))
+	if err != nil {
+		return Outcome{Err: err.Error()}
+	}
+	var result struct {
+		Outcome
+		Rejected []struct {
+			Error string `json:"error"`
+		} `json:"rejected"`
+	}
+	if err := json.Unmarshal(blob, &result); err != nil {
+		return Outcome{Err: fmt.Sprintf("invalid t8n result: %v", err)}
+	}
+	if len(result.Rejected) > 0 {
+		result.Outcome.Exception = result.Rejected[0].Error
+	}
+	return result.Outcome
+}
+
+// writeT8nInput writes the fixture as t8n input files into dir. The
+// transaction is handed over unsigned, together with the key to sign it.
+func writeT8nInput(dir string, f *Fixture) error {
+	env := struct {
+		Env
+		ParentBeaconBlockRoot common.Hash         `json:"parentBeaconBlockRoot"`
+		Withdrawals           []*types.Withdrawal `json:"withdrawals"`
+	}{Env: f.Env, Withdrawals: []*types.Withdrawal{}}
+
+	tx, err := f.transaction()
+	if err != nil {
+		return err
+	}
+	blob, err := tx.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	var txs []map[string]interface{}
+	txs = append(txs, make(map[string]interface{}))
+	if err := json.Unmarshal(blob, &txs[0]); err != nil {
+		return err
+	}
+	txs[0]["secretKey"] = f.Tx.SecretKey
+	txs[0]["protected"] = true
+
+	for name, obj := range map[string]interface{}{"alloc.json": f.Pre, "env.json": env, "txs.json": txs} {
+		blob, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), blob, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transaction assembles the unsigned transaction of the fixture, picking the
+// transaction type from the fields that are set.
+func (f *Fixture) transaction() (*types.Transaction, error) {
+	var (
+		tx    = f.Tx
+		value = new(big.Int)
+		chain = big.NewInt(1)
+	)
+	if tx.Value != nil {
+		value = (*big.Int)(tx.Value)
+	}
+	if _, err := crypto.ToECDSA(tx.SecretKey); err != nil {
+		return nil, fmt.Errorf("invalid secret key: %v", err)
+	}
+	switch {
+	case tx.MaxFeePerGas != nil:
+		var tip *big.Int
+		if tx.MaxPriorityFeePerGas != nil {
+			tip = (*big.Int)(tx.MaxPriorityFeePerGas)
+		} else {
+			tip = (*big.Int)(tx.MaxFeePerGas)
+		}
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    chain,
+			Nonce:      uint64(tx.Nonce),
+			GasTipCap:  tip,
+			GasFeeCap:  (*big.Int)(tx.MaxFeePerGas),
+			Gas:        uint64(tx.GasLimit),
+			To:         tx.To,
+			Value:      value,
+			Data:       tx.Data,
+			AccessList: tx.AccessList,
+		}), nil
+	case tx.GasPrice == nil:
+		return nil, fmt.Errorf("no gas price provided")
+	case tx.AccessList != nil:
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    chain,
+			Nonce:      uint64(tx.Nonce),
+			GasPrice:   (*big.Int)(tx.GasPrice),
+			Gas:        uint64(tx.GasLimit),
+			To:         tx.To,
+			Value:      value,
+			Data:       tx.Data,
+			AccessList: tx.AccessList,
+		}), nil
+	default:
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    uint64(tx.Nonce),
+			GasPrice: (*big.Int)(tx.GasPrice),
+			Gas:      uint64(tx.GasLimit),
+			To:       tx.To,
+			Value:    value,
+			Data:     tx.Data,
+		}), nil
+	}
+}
+
+// Differ executes fixtures on a set of executors and compares the outcomes.
+type Differ struct {
+	Executors []Executor
+}
+
+// Diff runs the fixture on all executors. It returns the outcome of each of
+// them and whether any two disagreed.
+func (d *Differ) Diff(f *Fixture) ([]Outcome, bool) {
+	outcomes := make([]Outcome, len(d.Executors))
+	mismatch := false
+	for i, e := range d.Executors {
+		outcomes[i] = e.Execute(f)
+		if !outcomes[i].agrees(outcomes[0]) {
+			mismatch = true
+		}
+	}
+	return outcomes, mismatch
+}
+
+// Seal records the outcome of the first executor as the expected post-state
+// of the fixture, including the rejection of an invalid transaction, turning it into a regular state test which passes on that
+// executor and fails on the ones disagreeing with it.
+func (f *Fixture) Seal(out Outcome) {
+	post := PostState{Root: out.Root, Logs: out.Logs, ExpectException: out.Exception}
+	f.Post = map[string][]PostState{f.Fork: {post}}
+}
+
+func rlpHash(x interface{}) common.Hash {
+	blob, _ := rlp.EncodeToBytes(x)
+	return crypto.Keccak256Hash(blob)
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return lines[len(lines)-1]
+}
+
+// DefaultExecutors returns the in-process executors, which cover the hash
+// and path state schemes as well as snapshot-backed reads.
+func DefaultExecutors() []Executor {
+	return []Executor{
+		&LocalExecutor{Scheme: rawdb.HashScheme},
+		&LocalExecutor{Scheme: rawdb.PathScheme},
+		&LocalExecutor{Scheme: rawdb.HashScheme, Snapshot: true},
+	}
+}
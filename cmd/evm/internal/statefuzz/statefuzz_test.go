@@ -0,0 +1,103 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package statefuzz
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+func TestGenerateDeterministic(t *testing.T) {
+	gen := &Generator{Contracts: 3, Snippets: 16}
+	for seed := int64(0); seed < 10; seed++ {
+		a, err := gen.Generate(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := gen.Generate(seed)
+		if !reflect.DeepEqual(a, b) {
+			t.Fatalf("seed %d: generated tests differ", seed)
+		}
+	}
+}
+
+// Tests that the in-process executors agree on generated tests, and that the
+// sealed fixtures are valid state tests passing on the reference executor.
+func TestLocalExecutorsAgree(t *testing.T) {
+	var (
+		gen    = &Generator{Contracts: 3, Snippets: 16}
+		differ = &Differ{Executors: DefaultExecutors()}
+	)
+	for seed := int64(0); seed < 50; seed++ {
+		f, err := gen.Generate(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outcomes, mismatch := differ.Diff(f)
+		if mismatch {
+			t.Fatalf("seed %d: executors disagree: %v", seed, outcomes)
+		}
+		f.Seal(outcomes[0])
+
+		blob, err := json.Marshal(map[string]*Fixture{Name(seed): f})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fixtures map[string]tests.StateTest
+		if err := json.Unmarshal(blob, &fixtures); err != nil {
+			t.Fatal(err)
+		}
+		test := fixtures[Name(seed)]
+		for _, st := range test.Subtests() {
+			if err := test.Run(st, vm.Config{}, false, rawdb.HashScheme, func(error, *tests.StateTestState) {}); err != nil {
+				t.Errorf("seed %d: sealed fixture fails: %v", seed, err)
+			}
+		}
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	f, err := (&Generator{Contracts: 4, Snippets: 32}).Generate(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pick the called contract and minimize towards "calls it with non-empty code".
+	if f.Tx.To == nil {
+		t.Skip("seed generates a creation transaction")
+	}
+	target := *f.Tx.To
+	min := Minimize(f, func(f *Fixture) bool {
+		return len(f.Pre[target].Code) > 0
+	})
+	if len(min.Pre) != 2 {
+		t.Errorf("prestate not minimized: have %d accounts, want 2", len(min.Pre))
+	}
+	if have := len(instructions(min.Pre[target].Code)); have != 1 {
+		t.Errorf("code not minimized: have %d instructions, want 1", have)
+	}
+	if len(min.Tx.Data) != 0 {
+		t.Errorf("calldata not minimized: have %d bytes", len(min.Tx.Data))
+	}
+	if len(f.Pre[target].Code) == len(min.Pre[target].Code) {
+		t.Error("input fixture was modified")
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"sort"
 	"strconv"
 	"sync/atomic"
 	"time"
@@ -150,7 +151,25 @@ from Era archives.
 		Flags:     flags.Merge(utils.DatabaseFlags),
 		Description: `
 The export-history command will export blocks and their corresponding receipts
-into Era archives. Eras are typically packaged in steps of 8192 blocks.
+into Era archives. Eras are typically packaged in steps of 8192 blocks. Blocks
+before the merge are exported into era1 files, later ones into post-merge era
+files, splitting the epoch of the merge between the two. Post-merge era files
+commit to the execution block hashes only, as the beacon block roots aren't part
+of the execution chain, so they can't be verified against the beacon chain's
+historical summaries.
+`,
+	}
+	pruneHistoryCommand = &cli.Command{
+		Action:    pruneHistory,
+		Name:      "prune-history",
+		Usage:     "Prune blockchain history below a block",
+		ArgsUsage: "[<cutoff>]",
+		Flags:     flags.Merge(utils.DatabaseFlags),
+		Description: `
+The prune-history command deletes the bodies and receipts of all the frozen
+blocks below the cutoff, which defaults to the merge block. The headers are
+retained, and the pruned history can be served from era1 archives given with
+--history.era1.
 `,
 	}
 	importPreimagesCommand = &cli.Command{
@@ -492,6 +511,51 @@ func exportHistory(ctx *cli.Context) error {
 	return nil
 }
 
+// pruneHistory deletes the chain history below the given block, or below the
+// merge block if none is given.
+func pruneHistory(ctx *cli.Context) error {
+	if ctx.Args().Len() > 1 {
+		utils.Fatalf("usage: %s", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, false)
+	defer db.Close()
+
+	var cutoff uint64
+	if ctx.Args().Len() == 1 {
+		number, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+		if err != nil {
+			utils.Fatalf("Invalid cutoff block number: %v", err)
+		}
+		cutoff = number
+	} else {
+		head := rawdb.ReadHeadHeaderHash(db)
+		number := rawdb.ReadHeaderNumber(db, head)
+		if number == nil {
+			utils.Fatalf("Failed to find the head header")
+		}
+		// The merge block is the first one without difficulty.
+		cutoff = uint64(sort.Search(int(*number)+1, func(n int) bool {
+			header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, uint64(n)), uint64(n))
+			return header != nil && header.Difficulty.Sign() == 0
+		}))
+		if cutoff > *number {
+			utils.Fatalf("The chain has not transitioned to proof-of-stake yet")
+		}
+	}
+	if cutoff == 0 {
+		utils.Fatalf("No history to prune below block 0")
+	}
+	start := time.Now()
+	if err := rawdb.PruneHistory(db, cutoff); err != nil {
+		utils.Fatalf("Failed to prune history: %v", err)
+	}
+	fmt.Printf("Pruned history below block %d in %v\n", cutoff, time.Since(start))
+	return nil
+}
+
 // importPreimages imports preimage data from the specified file.
 // it is deprecated, and the export function has been removed, but
 // the import function is kept around for the time being so that
@@ -37,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/eth/catalyst"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/headersync"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/internal/version"
 	"github.com/ethereum/go-ethereum/log"
@@ -179,6 +180,11 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		cfg.Eth.OverrideVerkle = &v
 	}
 
+	// Header-only mode runs without an execution backend, short circuit
+	if ctx.Bool(utils.HeaderOnlyFlag.Name) {
+		makeHeaderOnlyNode(ctx, stack, &cfg)
+		return stack
+	}
 	backend, eth := utils.RegisterEthService(stack, &cfg.Eth)
 
 	// Create gauge with geth system and build information
@@ -240,6 +246,26 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 	return stack
 }
 
+// makeHeaderOnlyNode registers the header-only sync mode on the node, following
+// the chain via the embedded beacon light client without any execution state.
+func makeHeaderOnlyNode(ctx *cli.Context, stack *node.Node, cfg *gethConfig) {
+	config := utils.MakeHeaderSyncConfig(ctx)
+
+	chainConfig := params.MainnetChainConfig
+	if cfg.Eth.Genesis != nil {
+		chainConfig = cfg.Eth.Genesis.Config
+	}
+	blsyncer := blsync.NewClient(ctx)
+	db := utils.MakeHeaderChainDatabase(ctx, stack)
+	syncer, err := headersync.New(db, chainConfig, blsyncer, config)
+	if err != nil {
+		utils.Fatalf("Failed to create header-only sync service: %v", err)
+	}
+	stack.RegisterAPIs(syncer.APIs())
+	stack.RegisterLifecycle(syncer)
+	stack.RegisterLifecycle(blsyncer)
+}
+
 // dumpConfig is the dumpconfig command.
 func dumpConfig(ctx *cli.Context) error {
 	_, cfg := makeConfigNode(ctx)
@@ -18,6 +18,7 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/signal"
@@ -31,11 +32,13 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/console/prompt"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -54,6 +57,18 @@ var (
 		Name:  "remove.chain",
 		Usage: "If set, selects the state data for removal",
 	}
+	inspectStatsFlag = &cli.BoolFlag{
+		Name:  "stats",
+		Usage: "Print the incrementally maintained statistics instead of iterating the database",
+	}
+	inspectBaselineFlag = &cli.StringFlag{
+		Name:  "baseline",
+		Usage: "File of previously saved statistics to report the growth against (implies --stats)",
+	}
+	inspectSaveBaselineFlag = &cli.StringFlag{
+		Name:  "save-baseline",
+		Usage: "File to save the current statistics to, as the baseline of later reports (implies --stats)",
+	}
 
 	removedbCommand = &cli.Command{
 		Action:    removeDB,
@@ -78,6 +93,8 @@ Remove blockchain and state databases`,
 			dbPutCmd,
 			dbGetSlotsCmd,
 			dbDumpFreezerIndex,
+			dbMigrateFreezerCmd,
+			dbRestoreCmd,
 			dbImportCmd,
 			dbExportCmd,
 			dbMetadataCmd,
@@ -91,9 +108,16 @@ Remove blockchain and state databases`,
 		ArgsUsage: "<prefix> <start>",
 		Flags: flags.Merge([]cli.Flag{
 			utils.SyncModeFlag,
+			inspectStatsFlag,
+			inspectBaselineFlag,
+			inspectSaveBaselineFlag,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
-		Usage:       "Inspect the storage size for each type of data in the database",
-		Description: `This commands iterates the entire database. If the optional 'prefix' and 'start' arguments are provided, then the iteration is limited to the given subset of data.`,
+		Usage: "Inspect the storage size for each type of data in the database",
+		Description: `This commands iterates the entire database. If the optional 'prefix' and 'start' arguments are provided, then the iteration is limited to the given subset of data.
+
+With --stats, the statistics maintained incrementally by a node running with --db.stats
+are printed instead, which is instant. They can be saved with --save-baseline, and the
+growth of every category since then reported with --baseline.`,
 	}
 	dbCheckStateContentCmd = &cli.Command{
 		Action:    checkStateContent,
@@ -178,6 +202,38 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabaseFlags),
 		Description: "This command displays information about the freezer index.",
 	}
+	dbMigrateFreezerCmd = &cli.Command{
+		Action:    freezerMigrate,
+		Name:      "freezer-migrate",
+		Usage:     "Rewrite a freezer table with a different compression codec",
+		ArgsUsage: "<freezer-type> <table-type>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			&cli.StringFlag{
+				Name:  "codec",
+				Usage: "compression codec of the migrated table (zstd, or the table default: snappy or none)",
+				Value: "zstd",
+			},
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command rewrites all items of a freezer table using the given codec.
+For zstd, a compression dictionary is trained from a sample of the table first,
+which is most effective for the receipts. The node must be stopped while the
+migration runs. If the migration is interrupted, it continues where it stopped
+when the command is run again.`,
+	}
+	dbRestoreCmd = &cli.Command{
+		Action:    restoreBackup,
+		Name:      "restore",
+		Usage:     "Restore the database from a backup",
+		ArgsUsage: "<backup-dir>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+		}, utils.NetworkFlags, utils.DatabaseFlags),
+		Description: `This command validates a backup created with admin.backup and copies it
+into the data directory. The backup must contain the head block and state listed
+in its manifest. The node must be stopped, and the data directory must not
+contain a database yet.`,
+	}
 	dbImportCmd = &cli.Command{
 		Action:    importLDBdata,
 		Name:      "import",
@@ -323,6 +379,9 @@ func confirmAndRemoveDB(paths []string, kind string, ctx *cli.Context, removeFla
 }
 
 func inspect(ctx *cli.Context) error {
+	if ctx.Bool(inspectStatsFlag.Name) || ctx.IsSet(inspectBaselineFlag.Name) || ctx.IsSet(inspectSaveBaselineFlag.Name) {
+		return inspectStats(ctx)
+	}
 	var (
 		prefix []byte
 		start  []byte
@@ -353,6 +412,50 @@ func inspect(ctx *cli.Context) error {
 	return rawdb.InspectDatabase(db, prefix, start)
 }
 
+// inspectStats prints the incrementally maintained database statistics, along
+// with their growth since a saved baseline.
+func inspectStats(ctx *cli.Context) error {
+	if ctx.NArg() > 0 {
+		return errors.New("prefix and start arguments are not supported with --stats")
+	}
+	var baseline *rawdb.DatabaseStats
+	if path := ctx.String(inspectBaselineFlag.Name); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		baseline, err = rawdb.LoadDatabaseStats(f)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to load baseline: %v", err)
+		}
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack, true)
+	defer db.Close()
+
+	stats, err := rawdb.ReadDatabaseStats(db)
+	if err != nil {
+		return err
+	}
+	rawdb.PrintDatabaseStats(os.Stdout, stats, baseline)
+
+	if path := ctx.String(inspectSaveBaselineFlag.Name); path != "" {
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := rawdb.SaveDatabaseStats(f, stats); err != nil {
+			return err
+		}
+		log.Info("Saved statistics baseline", "file", path)
+	}
+	return nil
+}
+
 func checkStateContent(ctx *cli.Context) error {
 	var (
 		prefix []byte
@@ -630,6 +733,101 @@ func freezerInspect(ctx *cli.Context) error {
 	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end)
 }
 
+func freezerMigrate(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	var (
+		freezer = ctx.Args().Get(0)
+		table   = ctx.Args().Get(1)
+		codec   = ctx.String("codec")
+	)
+	stack, _ := makeConfigNode(ctx)
+	ancient := stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+
+	// The items of an encrypted freezer must be migrated with the key of the
+	// database. Opening the key-value store fails if it's encrypted, but the
+	// key is missing or wrong.
+	db, err := stack.OpenDatabase("chaindata", 0, 0, "", true)
+	if err != nil {
+		stack.Close()
+		return err
+	}
+	db.Close()
+	var cipher *encryptdb.FileCipher
+	if source := stack.Config().DBEncryption; source != "" {
+		key, err := encryptdb.LoadKey(source, "chaindata")
+		if err != nil {
+			stack.Close()
+			return err
+		}
+		cipher = key.FileCipher()
+	}
+	stack.Close()
+
+	start := time.Now()
+	log.Info("Migrating freezer table", "freezer", freezer, "table", table, "codec", codec, "encrypted", cipher != nil)
+	before, after, err := rawdb.MigrateFreezerTable(ancient, freezer, table, codec, cipher)
+	if err != nil {
+		return err
+	}
+	var saved float64
+	if before > 0 {
+		saved = 100 * (float64(before) - float64(after)) / float64(before)
+	}
+	log.Info("Migrated freezer table", "table", table, "codec", codec, "before", common.StorageSize(before),
+		"after", common.StorageSize(after), "saved", fmt.Sprintf("%.2f%%", saved), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+func restoreBackup(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	var (
+		start   = time.Now()
+		chain   = stack.ResolvePath("chaindata")
+		ancient = stack.ResolveAncient("chaindata", ctx.String(utils.AncientFlag.Name))
+	)
+	if _, err := restoreBackupDir(ctx.Args().Get(0), chain, ancient); err != nil {
+		return err
+	}
+	log.Info("Restored backup", "chaindata", chain, "ancient", ancient, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// restoreBackupDir validates the backup in the given directory against its
+// manifest and copies it into the chain and ancient database directories.
+func restoreBackupDir(dir string, chain string, ancient string) (*core.BackupManifest, error) {
+	manifest, err := core.ReadBackupManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	src := filepath.Join(dir, core.BackupChaindataDir)
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Directory:         src,
+		AncientsDirectory: filepath.Join(src, rawdb.CheckpointAncientDir),
+		ReadOnly:          true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = core.ValidateBackup(db, manifest)
+	db.Close()
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+	log.Info("Validated backup", "number", manifest.Number, "hash", manifest.Hash, "root", manifest.Root, "created", manifest.Time)
+
+	if err := rawdb.RestoreCheckpoint(src, chain, ancient); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
 func importLDBdata(ctx *cli.Context) error {
 	start := 0
 	switch ctx.NArg() {
@@ -0,0 +1,95 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a backup of a database opened by the node can be restored.
+func TestBackupRestore(t *testing.T) {
+	stack, err := node.New(&node.Config{DataDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to create node: %v", err)
+	}
+	defer stack.Close()
+
+	db, err := stack.OpenDatabaseWithFreezer("chaindata", 0, 0, "", "", false)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	var (
+		gspec = &core.Genesis{
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Config:  params.AllEthashProtocolChanges,
+			Alloc:   types.GenesisAlloc{common.Address{0xaa}: {Balance: big.NewInt(params.Ether)}},
+		}
+		engine = ethash.NewFaker()
+	)
+	chain, err := core.NewBlockChain(db, core.DefaultCacheConfigWithScheme(rawdb.PathScheme), gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, engine, 8, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{byte(i + 1)})
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("Failed to insert chain: %v", err)
+	}
+	backup := filepath.Join(t.TempDir(), "backup")
+	if _, err := chain.Backup(backup); err != nil {
+		t.Fatalf("Failed to back up database: %v", err)
+	}
+	var (
+		datadir = t.TempDir()
+		dir     = filepath.Join(datadir, "chaindata")
+		ancient = filepath.Join(dir, "ancient")
+	)
+	manifest, err := restoreBackupDir(backup, dir, ancient)
+	if err != nil {
+		t.Fatalf("Failed to restore backup: %v", err)
+	}
+	head := blocks[len(blocks)-1]
+	if manifest.Hash != head.Hash() {
+		t.Fatalf("Manifest head mismatch: have %x, want %x", manifest.Hash, head.Hash())
+	}
+	restored, err := rawdb.Open(rawdb.OpenOptions{Directory: dir, AncientsDirectory: ancient, ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to open restored database: %v", err)
+	}
+	defer restored.Close()
+
+	if hash := rawdb.ReadHeadBlockHash(restored); hash != head.Hash() {
+		t.Fatalf("Restored head mismatch: have %x, want %x", hash, head.Hash())
+	}
+	if _, err := restoreBackupDir(backup, dir, ancient); err == nil {
+		t.Fatal("Restore overwrote an existing database")
+	}
+}
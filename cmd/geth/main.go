@@ -88,6 +88,12 @@ var (
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag, // deprecated
 		utils.TransactionHistoryFlag,
+		utils.AccessSetHistoryFlag,
+		utils.HistoryEra1Flag,
+		utils.AncientCacheItemsFlag,
+		utils.AncientCacheMemoryFlag,
+		utils.AncientCacheDirFlag,
+		utils.AncientCacheDiskFlag,
 		utils.StateHistoryFlag,
 		utils.LightServeFlag,    // deprecated
 		utils.LightIngressFlag,  // deprecated
@@ -113,6 +119,7 @@ var (
 		utils.CryptoKZGFlag,
 		utils.ListenPortFlag,
 		utils.DiscoveryPortFlag,
+		utils.QUICPortFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
 		utils.MiningEnabledFlag, // deprecated
@@ -138,6 +145,8 @@ var (
 		utils.VMEnableDebugFlag,
 		utils.VMTraceFlag,
 		utils.VMTraceJsonConfigFlag,
+		utils.VMParallelWorkersFlag,
+		utils.VMOptimizedFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
 		utils.NoCompactionFlag,
@@ -156,6 +165,10 @@ var (
 		utils.BeaconGenesisRootFlag,
 		utils.BeaconGenesisTimeFlag,
 		utils.BeaconCheckpointFlag,
+		utils.HeaderOnlyFlag,
+		utils.HeaderOnlyUpstreamFlag,
+		utils.HeaderOnlyHistoryFlag,
+		utils.HeaderOnlyLogLimitFlag,
 		utils.CollectWitnessFlag,
 	}, utils.NetworkFlags, utils.DatabaseFlags)
 
@@ -186,6 +199,11 @@ var (
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCRevertABIDirFlag,
+		utils.RPCRevertSignaturesFlag,
+		utils.RPCRevertErrorDataFlag,
+		utils.RPCDatabaseFlag,
+		utils.RPCDatabaseWriteFlag,
 		utils.AllowUnprotectedTxs,
 		utils.BatchRequestLimit,
 		utils.BatchResponseMaxSize,
@@ -221,6 +239,7 @@ func init() {
 		exportCommand,
 		importHistoryCommand,
 		exportHistoryCommand,
+		pruneHistoryCommand,
 		importPreimagesCommand,
 		removedbCommand,
 		dumpCommand,
@@ -40,6 +40,10 @@ import (
 )
 
 var (
+	pruneRebuildFlag = &cli.BoolFlag{
+		Name:  "rebuild",
+		Usage: "Regenerate all trie nodes from the snapshot before pruning (path scheme only)",
+	}
 	snapshotCommand = &cli.Command{
 		Name:        "snapshot",
 		Usage:       "A set of commands based on the snapshot",
@@ -52,6 +56,7 @@ var (
 				Action:    pruneState,
 				Flags: flags.Merge([]cli.Flag{
 					utils.BloomFilterSizeFlag,
+					pruneRebuildFlag,
 				}, utils.NetworkFlags, utils.DatabaseFlags),
 				Description: `
 geth snapshot prune-state <state-root>
@@ -62,7 +67,12 @@ two version states are available: genesis and the specific one.
 
 The default pruning target is the HEAD-127 state.
 
-WARNING: it's only supported in hash mode(--state.scheme=hash)".
+In path mode (--state.scheme=path), the state layers are flushed first and
+the trie nodes which are not part of the persistent state are deleted, e.g.
+ones left behind by past bugs or aborted syncs, including the storage tries
+of absent accounts. With --rebuild, all trie nodes are regenerated from the
+snapshot before, repairing missing or corrupted ones. The resulting state is
+verified against the state root. No state root argument is accepted.
 `,
 			},
 			{
@@ -174,8 +184,22 @@ func pruneState(ctx *cli.Context) error {
 	chaindb := utils.MakeChainDatabase(ctx, stack, false)
 	defer chaindb.Close()
 
-	if rawdb.ReadStateScheme(chaindb) != rawdb.HashScheme {
-		log.Crit("Offline pruning is not required for path scheme")
+	if rawdb.ReadStateScheme(chaindb) == rawdb.PathScheme {
+		if ctx.NArg() > 0 {
+			return errors.New("state root argument is not supported in path scheme")
+		}
+		pruner, err := pruner.NewPathPruner(chaindb, pruner.PathConfig{Rebuild: ctx.Bool(pruneRebuildFlag.Name)})
+		if err != nil {
+			return err
+		}
+		if err := pruner.Prune(); err != nil {
+			log.Error("Failed to prune state", "err", err)
+			return err
+		}
+		return nil
+	}
+	if ctx.Bool(pruneRebuildFlag.Name) {
+		return errors.New("--rebuild is only supported in path scheme")
 	}
 	prunerconfig := pruner.Config{
 		Datadir:   stack.ResolvePath(""),
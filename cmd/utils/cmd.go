@@ -29,6 +29,7 @@ import (
 	"os/signal"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -241,13 +242,13 @@ func readList(filename string) ([]string, error) {
 	return strings.Split(string(b), "\n"), nil
 }
 
-// ImportHistory imports Era1 files containing historical block information,
-// starting from genesis.
+// ImportHistory imports Era1 and post-merge Era files containing historical
+// block information, starting from genesis.
 func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, network string) error {
 	if chain.CurrentSnapBlock().Number.BitLen() != 0 {
 		return errors.New("history import only supported when starting from genesis")
 	}
-	entries, err := era.ReadDir(dir, network)
+	entries, err := era.ReadDirAll(dir, network)
 	if err != nil {
 		return fmt.Errorf("error reading %s: %w", dir, err)
 	}
@@ -284,7 +285,7 @@ func ImportHistory(chain *core.BlockChain, db ethdb.Database, dir string, networ
 			h.Reset()
 			buf.Reset()
 
-			// Import all block data from Era1.
+			// Import all block data from the Era.
 			e, err := era.From(f)
 			if err != nil {
 				return fmt.Errorf("error opening era: %w", err)
@@ -419,61 +420,27 @@ func ExportHistory(bc *core.BlockChain, dir string, first, last, step uint64) er
 	var (
 		start     = time.Now()
 		reported  = time.Now()
-		h         = sha256.New()
-		buf       = bytes.NewBuffer(nil)
 		checksums []string
 	)
 	for i := first; i <= last; i += step {
-		err := func() error {
-			filename := filepath.Join(dir, era.Filename(network, int(i/step), common.Hash{}))
-			f, err := os.Create(filename)
-			if err != nil {
-				return fmt.Errorf("could not create era file: %w", err)
-			}
-			defer f.Close()
+		end := min(i+step-1, last)
 
-			w := era.NewBuilder(f)
-			for j := uint64(0); j < step && j <= last-i; j++ {
-				var (
-					n     = i + j
-					block = bc.GetBlockByNumber(n)
-				)
-				if block == nil {
-					return fmt.Errorf("export failed on #%d: not found", n)
-				}
-				receipts := bc.GetReceiptsByHash(block.Hash())
-				if receipts == nil {
-					return fmt.Errorf("export failed on #%d: receipts not found", n)
-				}
-				td := bc.GetTd(block.Hash(), block.NumberU64())
-				if td == nil {
-					return fmt.Errorf("export failed on #%d: total difficulty not found", n)
-				}
-				if err := w.Add(block, receipts, td); err != nil {
-					return err
-				}
-			}
-			root, err := w.Finalize()
+		// Pre- and post-merge blocks are archived in different formats, so
+		// split the epoch at the merge if it happened within.
+		split := mergeBlock(bc, i, end)
+		if split > i {
+			checksum, err := exportEra(bc, dir, network, int(i/step), i, split-1)
 			if err != nil {
-				return fmt.Errorf("export failed to finalize %d: %w", step/i, err)
-			}
-			// Set correct filename with root.
-			os.Rename(filename, filepath.Join(dir, era.Filename(network, int(i/step), root)))
-
-			// Compute checksum of entire Era1.
-			if _, err := f.Seek(0, io.SeekStart); err != nil {
 				return err
 			}
-			if _, err := io.Copy(h, f); err != nil {
-				return fmt.Errorf("unable to calculate checksum: %w", err)
+			checksums = append(checksums, checksum)
+		}
+		if split <= end {
+			checksum, err := exportEra(bc, dir, network, int(i/step), split, end)
+			if err != nil {
+				return err
 			}
-			checksums = append(checksums, common.BytesToHash(h.Sum(buf.Bytes()[:])).Hex())
-			h.Reset()
-			buf.Reset()
-			return nil
-		}()
-		if err != nil {
-			return err
+			checksums = append(checksums, checksum)
 		}
 		if time.Since(reported) >= 8*time.Second {
 			log.Info("Exporting blocks", "exported", i, "elapsed", common.PrettyDuration(time.Since(start)))
@@ -488,6 +455,68 @@ func ExportHistory(bc *core.BlockChain, dir string, first, last, step uint64) er
 	return nil
 }
 
+// mergeBlock returns the first block without difficulty in the given range, or
+// the block following it if all have difficulty.
+func mergeBlock(bc *core.BlockChain, first, last uint64) uint64 {
+	postMerge := func(n uint64) bool {
+		header := bc.GetHeaderByNumber(n)
+		return header != nil && header.Difficulty.Sign() == 0
+	}
+	return first + uint64(sort.Search(int(last-first+1), func(i int) bool {
+		return postMerge(first + uint64(i))
+	}))
+}
+
+// exportEra writes the blocks of the given range into an Era file, an era1 one
+// for pre-merge blocks or a post-merge one otherwise, returning its checksum.
+func exportEra(bc *core.BlockChain, dir, network string, epoch int, first, last uint64) (string, error) {
+	filename := filepath.Join(dir, era.Filename(network, epoch, common.Hash{}))
+	f, err := os.Create(filename)
+	if err != nil {
+		return "", fmt.Errorf("could not create era file: %w", err)
+	}
+	defer f.Close()
+
+	w := era.NewBuilder(f)
+	for n := first; n <= last; n++ {
+		block := bc.GetBlockByNumber(n)
+		if block == nil {
+			return "", fmt.Errorf("export failed on #%d: not found", n)
+		}
+		receipts := bc.GetReceiptsByHash(block.Hash())
+		if receipts == nil {
+			return "", fmt.Errorf("export failed on #%d: receipts not found", n)
+		}
+		td := bc.GetTd(block.Hash(), block.NumberU64())
+		if td == nil {
+			return "", fmt.Errorf("export failed on #%d: total difficulty not found", n)
+		}
+		if err := w.Add(block, receipts, td); err != nil {
+			return "", err
+		}
+	}
+	root, err := w.Finalize()
+	if err != nil {
+		return "", fmt.Errorf("export failed to finalize %d: %w", epoch, err)
+	}
+	// Set correct filename with root.
+	name := era.Filename(network, epoch, root)
+	if w.PostMerge() {
+		name = era.FilenamePostMerge(network, epoch, root)
+	}
+	os.Rename(filename, filepath.Join(dir, name))
+
+	// Compute checksum of entire Era.
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("unable to calculate checksum: %w", err)
+	}
+	return common.BytesToHash(h.Sum(nil)).Hex(), nil
+}
+
 // ImportPreimages imports a batch of exported hash preimages into the database.
 // It's a part of the deprecated functionality, should be removed in the future.
 func ImportPreimages(db ethdb.Database, fn string) error {
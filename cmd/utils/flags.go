@@ -52,11 +52,13 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
+	"github.com/ethereum/go-ethereum/eth/headersync"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/ethdb/remotedb"
 	"github.com/ethereum/go-ethereum/ethstats"
 	"github.com/ethereum/go-ethereum/graphql"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
@@ -96,7 +98,7 @@ var (
 	}
 	RemoteDBFlag = &cli.StringFlag{
 		Name:     "remotedb",
-		Usage:    "URL for remote database",
+		Usage:    "URL of a node to operate on the database of, e.g. its IPC endpoint (requires --rpc.db on the node)",
 		Category: flags.LoggingCategory,
 	}
 	DBEngineFlag = &cli.StringFlag{
@@ -105,11 +107,53 @@ var (
 		Value:    node.DefaultConfig.DBEngine,
 		Category: flags.EthCategory,
 	}
+	DBEncryptionFlag = &cli.StringFlag{
+		Name:     "db.encryption",
+		Usage:    "Encrypt the databases with the key from this file (hex-encoded) or unix socket of a key server",
+		Category: flags.EthCategory,
+	}
+	DBEncryptKeysFlag = &cli.BoolFlag{
+		Name:     "db.encryption.keys",
+		Usage:    "Encrypt the database keys besides the values (order-preserving, doubles the key size)",
+		Category: flags.EthCategory,
+	}
+	DBStatsFlag = &cli.BoolFlag{
+		Name:     "db.stats",
+		Usage:    "Maintain per-category database statistics incrementally (debug_dbStats, geth db inspect --stats), at the cost of a database read per written key",
+		Category: flags.EthCategory,
+	}
 	AncientFlag = &flags.DirectoryFlag{
 		Name:     "datadir.ancient",
 		Usage:    "Root directory for ancient data (default = inside chaindata)",
 		Category: flags.EthCategory,
 	}
+	AncientEra1Flag = &flags.DirectoryFlag{
+		Name:     "datadir.era1",
+		Usage:    "Directory of era1 files to serve the ancient data from, continued by the freezer in --datadir.ancient",
+		Category: flags.EthCategory,
+	}
+	AncientCacheItemsFlag = &cli.Uint64Flag{
+		Name:     "datadir.ancient.cache.items",
+		Usage:    "Number of most recently frozen items per ancient table to cache (0 = disabled)",
+		Category: flags.EthCategory,
+	}
+	AncientCacheMemoryFlag = &cli.IntFlag{
+		Name:     "datadir.ancient.cache.memory",
+		Usage:    "Memory budget of the ancient cache in MB",
+		Value:    ethconfig.Defaults.AncientCacheMemory,
+		Category: flags.EthCategory,
+	}
+	AncientCacheDirFlag = &flags.DirectoryFlag{
+		Name:     "datadir.ancient.cache.dir",
+		Usage:    "Directory on fast local storage for the on-disk ancient cache (default = disabled)",
+		Category: flags.EthCategory,
+	}
+	AncientCacheDiskFlag = &cli.IntFlag{
+		Name:     "datadir.ancient.cache.disk",
+		Usage:    "Disk budget of the on-disk ancient cache in MB",
+		Value:    ethconfig.Defaults.AncientCacheDisk,
+		Category: flags.EthCategory,
+	}
 	MinFreeDiskSpaceFlag = &flags.DirectoryFlag{
 		Name:     "datadir.minfreedisk",
 		Usage:    "Minimum free disk space in MB, once reached triggers auto shut down (default = --cache.gc converted to MB, 0 = disabled)",
@@ -283,6 +327,16 @@ var (
 		Value:    ethconfig.Defaults.TransactionHistory,
 		Category: flags.StateCategory,
 	}
+	AccessSetHistoryFlag = &cli.Uint64Flag{
+		Name:     "history.accesssets",
+		Usage:    "Number of recent blocks to retain state access sets for, used to prefetch state on re-execution (0 = none)",
+		Category: flags.StateCategory,
+	}
+	HistoryEra1Flag = &flags.DirectoryFlag{
+		Name:     "history.era1",
+		Usage:    "Directory of era1 files to serve the pruned chain history from",
+		Category: flags.StateCategory,
+	}
 	// Beacon client light sync settings
 	BeaconApiFlag = &cli.StringSliceFlag{
 		Name:     "beacon.api",
@@ -335,6 +389,28 @@ var (
 		Usage:    "Path to a JWT secret to use for target engine API endpoint",
 		Category: flags.BeaconCategory,
 	}
+	HeaderOnlyFlag = &cli.BoolFlag{
+		Name:     "headeronly",
+		Usage:    "Follow the chain via the beacon light client, storing execution headers only (requires --beacon.api)",
+		Category: flags.BeaconCategory,
+	}
+	HeaderOnlyUpstreamFlag = &cli.StringSliceFlag{
+		Name:     "headeronly.upstream",
+		Usage:    "Untrusted execution RPC endpoint to retrieve headers, bodies and receipts from in header-only mode, which doesn't use the eth protocol. This flag can be given multiple times.",
+		Category: flags.BeaconCategory,
+	}
+	HeaderOnlyHistoryFlag = &cli.Uint64Flag{
+		Name:     "headeronly.history",
+		Usage:    "Number of recent execution headers to retrieve in header-only mode",
+		Value:    headersync.DefaultConfig.History,
+		Category: flags.BeaconCategory,
+	}
+	HeaderOnlyLogLimitFlag = &cli.Uint64Flag{
+		Name:     "headeronly.loglimit",
+		Usage:    "Maximum number of blocks a log query may span in header-only mode (0 = no limit)",
+		Value:    headersync.DefaultConfig.LogQueryLimit,
+		Category: flags.BeaconCategory,
+	}
 	// Transaction pool settings
 	TxPoolLocalsFlag = &cli.StringFlag{
 		Name:     "txpool.locals",
@@ -549,6 +625,16 @@ var (
 		Usage:    "Tracer configuration (JSON)",
 		Category: flags.VMCategory,
 	}
+	VMParallelWorkersFlag = &cli.IntFlag{
+		Name:     "vm.parallel",
+		Usage:    "Number of workers speculatively executing block transactions in parallel (0 = sequential)",
+		Category: flags.VMCategory,
+	}
+	VMOptimizedFlag = &cli.BoolFlag{
+		Name:     "vm.optimized",
+		Usage:    "Execute contract code by analysed basic blocks with fused instructions (not used when tracing)",
+		Category: flags.VMCategory,
+	}
 	// API options.
 	RPCGlobalGasCapFlag = &cli.Uint64Flag{
 		Name:     "rpc.gascap",
@@ -568,6 +654,31 @@ var (
 		Value:    ethconfig.Defaults.RPCTxFeeCap,
 		Category: flags.APICategory,
 	}
+	RPCRevertABIDirFlag = &flags.DirectoryFlag{
+		Name:     "rpc.revertabis",
+		Usage:    "Directory of contract ABIs used to decode custom errors of reverted calls",
+		Category: flags.APICategory,
+	}
+	RPCRevertSignaturesFlag = &cli.StringFlag{
+		Name:     "rpc.revertsigs",
+		Usage:    "4byte database file (as used by clef) used to decode custom errors of reverted calls",
+		Category: flags.APICategory,
+	}
+	RPCRevertErrorDataFlag = &cli.BoolFlag{
+		Name:     "rpc.reverterrordata",
+		Usage:    "Return the decoded revert reason next to the raw revert data in eth_call and eth_estimateGas errors",
+		Category: flags.APICategory,
+	}
+	RPCDatabaseFlag = &cli.BoolFlag{
+		Name:     "rpc.db",
+		Usage:    "Expose the chain database read-only in the db API, for 'geth db --remotedb' (only enable on trusted endpoints)",
+		Category: flags.APICategory,
+	}
+	RPCDatabaseWriteFlag = &cli.BoolFlag{
+		Name:     "rpc.db.write",
+		Usage:    "Allow modifying the chain database through the db API (requires --rpc.db)",
+		Category: flags.APICategory,
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = &cli.StringFlag{
 		Name:     "authrpc.addr",
@@ -830,6 +941,11 @@ var (
 		Value:    30303,
 		Category: flags.NetworkingCategory,
 	}
+	QUICPortFlag = &cli.IntFlag{
+		Name:     "quic.port",
+		Usage:    "Enables the experimental QUIC transport on the given UDP port (must differ from the discovery port)",
+		Category: flags.NetworkingCategory,
+	}
 
 	// Console
 	JSpathFlag = &flags.DirectoryFlag{
@@ -975,8 +1091,12 @@ var (
 	DatabaseFlags = []cli.Flag{
 		DataDirFlag,
 		AncientFlag,
+		AncientEra1Flag,
 		RemoteDBFlag,
 		DBEngineFlag,
+		DBEncryptionFlag,
+		DBEncryptKeysFlag,
+		DBStatsFlag,
 		StateSchemeFlag,
 		HttpHeaderFlag,
 	}
@@ -1111,6 +1231,9 @@ func setListenAddress(ctx *cli.Context, cfg *p2p.Config) {
 	if ctx.IsSet(DiscoveryPortFlag.Name) {
 		cfg.DiscAddr = fmt.Sprintf(":%d", ctx.Int(DiscoveryPortFlag.Name))
 	}
+	if ctx.IsSet(QUICPortFlag.Name) {
+		cfg.QUICListenAddr = fmt.Sprintf(":%d", ctx.Int(QUICPortFlag.Name))
+	}
 }
 
 // setNAT creates a port mapper from command line flags.
@@ -1448,6 +1571,15 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		log.Info(fmt.Sprintf("Using %s as db engine", dbEngine))
 		cfg.DBEngine = dbEngine
 	}
+	if ctx.IsSet(DBEncryptionFlag.Name) {
+		cfg.DBEncryption = ctx.String(DBEncryptionFlag.Name)
+	}
+	if ctx.IsSet(DBEncryptKeysFlag.Name) {
+		cfg.DBEncryptKeys = ctx.Bool(DBEncryptKeysFlag.Name)
+	}
+	if ctx.IsSet(DBStatsFlag.Name) {
+		cfg.DBStats = ctx.Bool(DBStatsFlag.Name)
+	}
 	// deprecation notice for log debug flags (TODO: find a more appropriate place to put these?)
 	if ctx.IsSet(LogBacktraceAtFlag.Name) {
 		log.Warn("log.backtrace flag is deprecated")
@@ -1690,6 +1822,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(AncientFlag.Name) {
 		cfg.DatabaseFreezer = ctx.String(AncientFlag.Name)
 	}
+	if ctx.IsSet(AncientEra1Flag.Name) {
+		cfg.DatabaseEra1 = ctx.String(AncientEra1Flag.Name)
+	}
+	if ctx.IsSet(AncientCacheItemsFlag.Name) {
+		cfg.AncientCacheItems = ctx.Uint64(AncientCacheItemsFlag.Name)
+	}
+	if ctx.IsSet(AncientCacheMemoryFlag.Name) {
+		cfg.AncientCacheMemory = ctx.Int(AncientCacheMemoryFlag.Name)
+	}
+	if ctx.IsSet(AncientCacheDirFlag.Name) {
+		cfg.AncientCacheDir = ctx.String(AncientCacheDirFlag.Name)
+	}
+	if ctx.IsSet(AncientCacheDiskFlag.Name) {
+		cfg.AncientCacheDisk = ctx.Int(AncientCacheDiskFlag.Name)
+	}
 
 	if gcmode := ctx.String(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1712,6 +1859,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(StateSchemeFlag.Name) {
 		cfg.StateScheme = ctx.String(StateSchemeFlag.Name)
 	}
+	if ctx.IsSet(AccessSetHistoryFlag.Name) {
+		cfg.AccessSetHistory = ctx.Uint64(AccessSetHistoryFlag.Name)
+	}
+	if ctx.IsSet(HistoryEra1Flag.Name) {
+		cfg.HistoryEra1Dir = ctx.String(HistoryEra1Flag.Name)
+	}
 	// Parse transaction history flag, if user is still using legacy config
 	// file with 'TxLookupLimit' configured, copy the value to 'TransactionHistory'.
 	if cfg.TransactionHistory == ethconfig.Defaults.TransactionHistory && cfg.TxLookupLimit != ethconfig.Defaults.TxLookupLimit {
@@ -1768,6 +1921,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(CollectWitnessFlag.Name) {
 		cfg.EnableWitnessCollection = ctx.Bool(CollectWitnessFlag.Name)
 	}
+	if ctx.IsSet(VMParallelWorkersFlag.Name) {
+		cfg.ParallelWorkers = ctx.Int(VMParallelWorkersFlag.Name)
+	}
+	if ctx.IsSet(VMOptimizedFlag.Name) {
+		cfg.OptimizedInterpreter = ctx.Bool(VMOptimizedFlag.Name)
+	}
 
 	if ctx.IsSet(RPCGlobalGasCapFlag.Name) {
 		cfg.RPCGasCap = ctx.Uint64(RPCGlobalGasCapFlag.Name)
@@ -1783,6 +1942,21 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.Float64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.IsSet(RPCRevertABIDirFlag.Name) {
+		cfg.RPCRevertABIDir = ctx.String(RPCRevertABIDirFlag.Name)
+	}
+	if ctx.IsSet(RPCRevertSignaturesFlag.Name) {
+		cfg.RPCRevertSignatures = ctx.String(RPCRevertSignaturesFlag.Name)
+	}
+	if ctx.IsSet(RPCRevertErrorDataFlag.Name) {
+		cfg.RPCRevertErrorData = ctx.Bool(RPCRevertErrorDataFlag.Name)
+	}
+	if ctx.IsSet(RPCDatabaseFlag.Name) {
+		cfg.RPCDatabase = ctx.Bool(RPCDatabaseFlag.Name)
+	}
+	if ctx.IsSet(RPCDatabaseWriteFlag.Name) {
+		cfg.RPCDatabaseWrite = ctx.Bool(RPCDatabaseWriteFlag.Name)
+	}
 	if ctx.IsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.IsSet(DNSDiscoveryFlag.Name) {
@@ -1974,6 +2148,42 @@ func RegisterFilterAPI(stack *node.Node, backend ethapi.Backend, ethcfg *ethconf
 	return filterSystem
 }
 
+// MakeHeaderSyncConfig creates the header-only sync configuration from the
+// command line flags.
+func MakeHeaderSyncConfig(ctx *cli.Context) headersync.Config {
+	if !ctx.IsSet(BeaconApiFlag.Name) {
+		Fatalf("Header-only mode requires the beacon light client API (--%s)", BeaconApiFlag.Name)
+	}
+	config := headersync.DefaultConfig
+	config.Upstreams = ctx.StringSlice(HeaderOnlyUpstreamFlag.Name)
+	if ctx.IsSet(HeaderOnlyHistoryFlag.Name) {
+		config.History = ctx.Uint64(HeaderOnlyHistoryFlag.Name)
+	}
+	if ctx.IsSet(HeaderOnlyLogLimitFlag.Name) {
+		config.LogQueryLimit = ctx.Uint64(HeaderOnlyLogLimitFlag.Name)
+	}
+	if ctx.IsSet(RPCGlobalGasCapFlag.Name) {
+		config.RPCGasCap = ctx.Uint64(RPCGlobalGasCapFlag.Name)
+	}
+	if ctx.IsSet(RPCGlobalEVMTimeoutFlag.Name) {
+		config.RPCEVMTimeout = ctx.Duration(RPCGlobalEVMTimeoutFlag.Name)
+	}
+	return config
+}
+
+// MakeHeaderChainDatabase opens the database used by the header-only mode.
+func MakeHeaderChainDatabase(ctx *cli.Context, stack *node.Node) ethdb.Database {
+	var (
+		cache   = ctx.Int(CacheFlag.Name) * ctx.Int(CacheDatabaseFlag.Name) / 100
+		handles = MakeDatabaseHandles(ctx.Int(FDLimitFlag.Name))
+	)
+	db, err := stack.OpenDatabase("headerchaindata", cache, handles, "eth/db/headerchaindata/", false)
+	if err != nil {
+		Fatalf("Could not open database: %v", err)
+	}
+	return db
+}
+
 // RegisterFullSyncTester adds the full-sync tester service into node.
 func RegisterFullSyncTester(stack *node.Node, eth *eth.Ethereum, target common.Hash) {
 	catalyst.RegisterFullSyncTester(stack, eth, target)
@@ -2069,13 +2279,19 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node, readonly bool) ethdb.
 	switch {
 	case ctx.IsSet(RemoteDBFlag.Name):
 		log.Info("Using remote db", "url", ctx.String(RemoteDBFlag.Name), "headers", len(ctx.StringSlice(HttpHeaderFlag.Name)))
-		client, err := DialRPCWithHeaders(ctx.String(RemoteDBFlag.Name), ctx.StringSlice(HttpHeaderFlag.Name))
-		if err != nil {
+		var client *rpc.Client
+		if client, err = DialRPCWithHeaders(ctx.String(RemoteDBFlag.Name), ctx.StringSlice(HttpHeaderFlag.Name)); err != nil {
 			break
 		}
-		chainDb = remotedb.New(client)
+		chainDb = remotedb.New(client, readonly)
 	case ctx.String(SyncModeFlag.Name) == "light":
 		chainDb, err = stack.OpenDatabase("lightchaindata", cache, handles, "", readonly)
+	case ctx.IsSet(AncientEra1Flag.Name):
+		var ancients *era.AncientReader
+		if ancients, err = era.NewAncientReader(stack.ResolvePath(ctx.String(AncientEra1Flag.Name))); err != nil {
+			break
+		}
+		chainDb, err = stack.OpenDatabaseWithAncients("chaindata", cache, handles, ctx.String(AncientFlag.Name), ancients, "", readonly)
 	default:
 		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", cache, handles, ctx.String(AncientFlag.Name), "", readonly)
 	}
@@ -2179,6 +2395,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 		Preimages:           ctx.Bool(CachePreimagesFlag.Name),
 		StateScheme:         scheme,
 		StateHistory:        ctx.Uint64(StateHistoryFlag.Name),
+		ParallelWorkers:     ctx.Int(VMParallelWorkersFlag.Name),
 	}
 	if cache.TrieDirtyDisabled && !cache.Preimages {
 		cache.Preimages = true
@@ -2201,6 +2418,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	vmcfg := vm.Config{
 		EnablePreimageRecording: ctx.Bool(VMEnableDebugFlag.Name),
 		EnableWitnessCollection: ctx.Bool(CollectWitnessFlag.Name),
+		OptimizedInterpreter:    ctx.Bool(VMOptimizedFlag.Name),
 	}
 	if ctx.IsSet(VMTraceFlag.Name) {
 		if name := ctx.String(VMTraceFlag.Name); name != "" {
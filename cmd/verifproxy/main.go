@@ -0,0 +1,163 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// verifproxy is a local RPC proxy in front of untrusted execution endpoints. It
+// follows the chain head via the beacon light client and answers state queries
+// by verifying Merkle proofs retrieved from the upstream against the trusted
+// state roots.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ethereum/go-ethereum/beacon/blsync"
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/eth/headersync"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/mattn/go-colorable"
+	"github.com/mattn/go-isatty"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	upstreamFlag = &cli.StringSliceFlag{
+		Name:     "upstream",
+		Usage:    "Untrusted execution RPC endpoint to retrieve headers and proofs from. This flag can be given multiple times.",
+		Required: true,
+	}
+	historyFlag = &cli.Uint64Flag{
+		Name:  "history",
+		Usage: "Number of recent execution headers to retrieve and serve state queries for",
+		Value: 256,
+	}
+	verbosityFlag = &cli.IntFlag{
+		Name:     "verbosity",
+		Usage:    "Logging verbosity: 0=silent, 1=error, 2=warn, 3=info, 4=debug, 5=detail",
+		Value:    3,
+		Category: flags.LoggingCategory,
+	}
+)
+
+func main() {
+	app := flags.NewApp("verifying RPC proxy")
+	app.Flags = []cli.Flag{
+		upstreamFlag,
+		historyFlag,
+		utils.BeaconApiFlag,
+		utils.BeaconApiHeaderFlag,
+		utils.BeaconThresholdFlag,
+		utils.BeaconNoFilterFlag,
+		utils.BeaconConfigFlag,
+		utils.BeaconGenesisRootFlag,
+		utils.BeaconGenesisTimeFlag,
+		utils.BeaconCheckpointFlag,
+		utils.MainnetFlag,
+		utils.SepoliaFlag,
+		utils.GoerliFlag,
+		utils.HTTPListenAddrFlag,
+		utils.HTTPPortFlag,
+		utils.HTTPCORSDomainFlag,
+		utils.HTTPVirtualHostsFlag,
+		utils.RPCGlobalGasCapFlag,
+		utils.RPCGlobalEVMTimeoutFlag,
+		verbosityFlag,
+	}
+	app.Action = proxy
+
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func proxy(ctx *cli.Context) error {
+	usecolor := (isatty.IsTerminal(os.Stderr.Fd()) || isatty.IsCygwinTerminal(os.Stderr.Fd())) && os.Getenv("TERM") != "dumb"
+	output := io.Writer(os.Stderr)
+	if usecolor {
+		output = colorable.NewColorable(os.Stderr)
+	}
+	verbosity := log.FromLegacyLevel(ctx.Int(verbosityFlag.Name))
+	log.SetDefault(log.NewLogger(log.NewTerminalHandlerWithLevel(output, verbosity, usecolor)))
+
+	// Assemble the proxy configuration
+	config := headersync.DefaultConfig
+	config.Upstreams = ctx.StringSlice(upstreamFlag.Name)
+	config.History = ctx.Uint64(historyFlag.Name)
+	if ctx.IsSet(utils.RPCGlobalGasCapFlag.Name) {
+		config.RPCGasCap = ctx.Uint64(utils.RPCGlobalGasCapFlag.Name)
+	}
+	if ctx.IsSet(utils.RPCGlobalEVMTimeoutFlag.Name) {
+		config.RPCEVMTimeout = ctx.Duration(utils.RPCGlobalEVMTimeoutFlag.Name)
+	}
+	nodeConfig := &node.Config{
+		Name:             "verifproxy",
+		HTTPHost:         ctx.String(utils.HTTPListenAddrFlag.Name),
+		HTTPPort:         ctx.Int(utils.HTTPPortFlag.Name),
+		HTTPCors:         utils.SplitAndTrim(ctx.String(utils.HTTPCORSDomainFlag.Name)),
+		HTTPVirtualHosts: utils.SplitAndTrim(ctx.String(utils.HTTPVirtualHostsFlag.Name)),
+		HTTPModules:      []string{"eth"},
+	}
+	// Set up the beacon light client and the proxy on top
+	client := blsync.NewClient(ctx)
+	stack, err := makeProxy(nodeConfig, chainConfig(ctx), client, config)
+	if err != nil {
+		utils.Fatalf("Failed to create proxy: %v", err)
+	}
+	stack.RegisterLifecycle(client)
+
+	utils.StartNode(ctx, stack, false)
+	stack.Wait()
+	return nil
+}
+
+// chainConfig returns the execution chain configuration of the selected network.
+func chainConfig(ctx *cli.Context) *params.ChainConfig {
+	if genesis := utils.MakeGenesis(ctx); genesis != nil {
+		return genesis.Config
+	}
+	if ctx.IsSet(utils.BeaconConfigFlag.Name) {
+		utils.Fatalf("Custom beacon chain configs are not supported, the execution chain config is unknown")
+	}
+	return params.MainnetChainConfig
+}
+
+// makeProxy creates a node serving the verified eth namespace subset over the
+// configured endpoints, following the chain heads announced by the given source.
+// No networking is started besides the RPC endpoints.
+func makeProxy(nodeConfig *node.Config, chainConfig *params.ChainConfig, source headersync.HeadSource, config headersync.Config) (*node.Node, error) {
+	nodeConfig.P2P.NoDiscovery = true
+	nodeConfig.P2P.ListenAddr = ""
+	nodeConfig.P2P.MaxPeers = 0
+
+	stack, err := node.New(nodeConfig)
+	if err != nil {
+		return nil, err
+	}
+	service, err := headersync.New(rawdb.NewMemoryDatabase(), chainConfig, source, config)
+	if err != nil {
+		stack.Close()
+		return nil, err
+	}
+	stack.RegisterAPIs(service.APIs())
+	stack.RegisterLifecycle(service)
+	return stack, nil
+}
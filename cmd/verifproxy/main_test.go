@@ -0,0 +1,177 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	btypes "github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/headersync"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr   = crypto.PubkeyToAddress(testKey.PublicKey)
+	testDest   = common.HexToAddress("0xdead")
+
+	// testContract returns the value of its first storage slot.
+	// PUSH1 0x00 SLOAD PUSH1 0x00 MSTORE PUSH1 0x20 PUSH1 0x00 RETURN
+	testContract = common.HexToAddress("0xc0de")
+	testCode     = common.FromHex("60005460005260206000f3")
+)
+
+// testHeadSource is a fake beacon light client announcing chain heads.
+type testHeadSource struct {
+	feed event.Feed
+}
+
+func (s *testHeadSource) SubscribeChainHeadEvent(ch chan<- btypes.ChainHeadEvent) event.Subscription {
+	return s.feed.Subscribe(ch)
+}
+
+// newUpstream starts an in-process geth node serving a generated chain over
+// HTTP, acting as the untrusted upstream of the proxy.
+func newUpstream(t *testing.T) (*node.Node, []*types.Block) {
+	t.Helper()
+
+	genesis := &core.Genesis{
+		Config: params.AllEthashProtocolChanges,
+		Alloc: types.GenesisAlloc{
+			testAddr: {Balance: big.NewInt(params.Ether)},
+			testContract: {
+				Code:    testCode,
+				Storage: map[common.Hash]common.Hash{{}: common.HexToHash("0x2a")},
+			},
+		},
+		ExtraData: []byte("test genesis"),
+		Timestamp: 9000,
+		BaseFee:   big.NewInt(params.InitialBaseFee),
+	}
+	signer := types.LatestSigner(genesis.Config)
+	_, blocks, _ := core.GenerateChainWithGenesis(genesis, ethash.NewFaker(), 10, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{
+			Nonce:    gen.TxNonce(testAddr),
+			To:       &testDest,
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: gen.BaseFee(),
+		}), signer, testKey)
+		gen.AddTx(tx)
+	})
+	stack, err := node.New(&node.Config{
+		HTTPHost:    "127.0.0.1",
+		HTTPModules: []string{"eth"},
+	})
+	if err != nil {
+		t.Fatalf("can't create upstream node: %v", err)
+	}
+	t.Cleanup(func() { stack.Close() })
+
+	ethservice, err := eth.New(stack, &ethconfig.Config{Genesis: genesis})
+	if err != nil {
+		t.Fatalf("can't create upstream ethereum service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("can't start upstream node: %v", err)
+	}
+	if _, err := ethservice.BlockChain().InsertChain(blocks); err != nil {
+		t.Fatalf("can't import test blocks: %v", err)
+	}
+	return stack, blocks
+}
+
+// Tests that the state queries answered by the proxy from verified proofs match
+// the answers of the upstream node.
+func TestProxyState(t *testing.T) {
+	upstream, blocks := newUpstream(t)
+
+	source := new(testHeadSource)
+	config := headersync.DefaultConfig
+	config.Upstreams = []string{upstream.HTTPEndpoint()}
+	config.History = 4
+
+	stack, err := makeProxy(&node.Config{}, params.AllEthashProtocolChanges, source, config)
+	if err != nil {
+		t.Fatalf("failed to create proxy: %v", err)
+	}
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+	// Announce the head and wait for the proxy to pick it up
+	var (
+		ctx   = context.Background()
+		head  = blocks[len(blocks)-1]
+		proxy = ethclient.NewClient(stack.Attach())
+		geth  = ethclient.NewClient(upstream.Attach())
+	)
+	source.feed.Send(btypes.ChainHeadEvent{Block: head, Finalized: blocks[len(blocks)-3].Hash()})
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if number, err := proxy.BlockNumber(ctx); err == nil && number == head.NumberU64() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("proxy did not reach the announced head")
+		}
+	}
+	for _, number := range []*big.Int{nil, big.NewInt(int64(head.NumberU64() - 2))} {
+		for _, addr := range []common.Address{testAddr, testDest, testContract, common.HexToAddress("0x1234")} {
+			want, _ := geth.BalanceAt(ctx, addr, number)
+			have, err := proxy.BalanceAt(ctx, addr, number)
+			if err != nil || have.Cmp(want) != 0 {
+				t.Errorf("balance %x at %v: have %v (err %v), want %v", addr, number, have, err, want)
+			}
+			wantNonce, _ := geth.NonceAt(ctx, addr, number)
+			haveNonce, err := proxy.NonceAt(ctx, addr, number)
+			if err != nil || haveNonce != wantNonce {
+				t.Errorf("nonce %x at %v: have %d (err %v), want %d", addr, number, haveNonce, err, wantNonce)
+			}
+		}
+		code, err := proxy.CodeAt(ctx, testContract, number)
+		if err != nil || !bytes.Equal(code, testCode) {
+			t.Errorf("code at %v: have %x (err %v), want %x", number, code, err, testCode)
+		}
+		slot, err := proxy.StorageAt(ctx, testContract, common.Hash{}, number)
+		if err != nil || common.BytesToHash(slot) != common.HexToHash("0x2a") {
+			t.Errorf("storage at %v: have %x (err %v), want 0x2a", number, slot, err)
+		}
+		ret, err := proxy.CallContract(ctx, ethereum.CallMsg{From: testAddr, To: &testContract}, number)
+		if err != nil || common.BytesToHash(ret) != common.HexToHash("0x2a") {
+			t.Errorf("call at %v: have %x (err %v), want 0x2a", number, ret, err)
+		}
+	}
+	// Blocks beyond the retained history must be rejected rather than served
+	if _, err := proxy.BalanceAt(ctx, testAddr, big.NewInt(1)); err == nil {
+		t.Error("expected error for block outside of the header history")
+	}
+}
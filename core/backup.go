@@ -0,0 +1,169 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// BackupChaindataDir is the directory of the database within a backup.
+	BackupChaindataDir = "chaindata"
+
+	// backupManifestFile is the file describing the content of a backup.
+	backupManifestFile = "backup.json"
+)
+
+// BackupManifest describes the chain head and state stored in a backup.
+type BackupManifest struct {
+	Genesis common.Hash `json:"genesis"`
+	Number  uint64      `json:"number"`
+	Hash    common.Hash `json:"hash"`
+	Root    common.Hash `json:"root"`
+	Scheme  string      `json:"scheme"`
+	Time    time.Time   `json:"time"`
+}
+
+// Backup writes a consistent, restorable copy of the chain database into the
+// given directory, which must not exist yet. The chain insertion is paused and
+// the in-memory state is flushed to the database before the checkpoint is
+// taken, so the backup contains the state of the current head block.
+func (bc *BlockChain) Backup(dir string) (*BackupManifest, error) {
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return nil, fmt.Errorf("backup directory %s already exists", dir)
+	}
+	cp, ok := bc.db.(ethdb.Checkpointer)
+	if !ok {
+		return nil, errors.New("database doesn't support checkpoints")
+	}
+	if !bc.chainmu.TryLock() {
+		return nil, errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	var (
+		start = time.Now()
+		head  = bc.CurrentBlock()
+	)
+	// Flush the dirty trie nodes and the snapshot diff layers, as they'd
+	// otherwise be missing from the backup.
+	if err := bc.triedb.Commit(head.Root, false); err != nil {
+		return nil, fmt.Errorf("failed to flush state: %w", err)
+	}
+	if bc.snaps != nil {
+		if err := bc.snaps.Checkpoint(head.Root); err != nil {
+			return nil, fmt.Errorf("failed to journal snapshot: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := cp.Checkpoint(filepath.Join(dir, BackupChaindataDir)); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint database: %w", err)
+	}
+	manifest := &BackupManifest{
+		Genesis: bc.genesisBlock.Hash(),
+		Number:  head.Number.Uint64(),
+		Hash:    head.Hash(),
+		Root:    head.Root,
+		Scheme:  bc.triedb.Scheme(),
+		Time:    time.Now().UTC(),
+	}
+	blob, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, backupManifestFile), blob, 0644); err != nil {
+		return nil, err
+	}
+	log.Info("Created database backup", "dir", dir, "number", manifest.Number, "hash", manifest.Hash, "elapsed", common.PrettyDuration(time.Since(start)))
+	return manifest, nil
+}
+
+// ReadBackupManifest reads the manifest of the backup in the given directory.
+func ReadBackupManifest(dir string) (*BackupManifest, error) {
+	blob, err := os.ReadFile(filepath.Join(dir, backupManifestFile))
+	if err != nil {
+		return nil, err
+	}
+	manifest := new(BackupManifest)
+	if err := json.Unmarshal(blob, manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// ValidateBackup checks that the database of a backup contains the head block
+// and state described by its manifest, and that the ancient store is continuous
+// with the key-value store.
+func ValidateBackup(db ethdb.Database, manifest *BackupManifest) error {
+	if genesis := rawdb.ReadCanonicalHash(db, 0); genesis != manifest.Genesis {
+		return fmt.Errorf("genesis mismatch: have %x, want %x", genesis, manifest.Genesis)
+	}
+	if head := rawdb.ReadHeadBlockHash(db); head != manifest.Hash {
+		return fmt.Errorf("head block mismatch: have %x, want %x", head, manifest.Hash)
+	}
+	block := rawdb.ReadBlock(db, manifest.Hash, manifest.Number)
+	if block == nil {
+		return fmt.Errorf("head block #%d [%x] missing", manifest.Number, manifest.Hash)
+	}
+	if block.Root() != manifest.Root {
+		return fmt.Errorf("head state root mismatch: have %x, want %x", block.Root(), manifest.Root)
+	}
+	if scheme := rawdb.ReadStateScheme(db); scheme != manifest.Scheme {
+		return fmt.Errorf("state scheme mismatch: have %q, want %q", scheme, manifest.Scheme)
+	}
+	switch manifest.Scheme {
+	case rawdb.PathScheme:
+		blob := rawdb.ReadAccountTrieNode(db, nil)
+		if root := crypto.Keccak256Hash(blob); len(blob) == 0 || root != manifest.Root {
+			return fmt.Errorf("persisted state root mismatch: have %x, want %x", root, manifest.Root)
+		}
+	case rawdb.HashScheme:
+		if !rawdb.HasLegacyTrieNode(db, manifest.Root) {
+			return fmt.Errorf("head state %x missing", manifest.Root)
+		}
+	}
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	if frozen > 0 && frozen <= manifest.Number {
+		// The block following the ancient store must be stored in the key-value
+		// store, and be the child of the last frozen block.
+		parent := rawdb.ReadCanonicalHash(db, frozen-1)
+		header := rawdb.ReadHeader(db, rawdb.ReadCanonicalHash(db, frozen), frozen)
+		if header == nil {
+			return fmt.Errorf("block #%d following the ancient store missing", frozen)
+		}
+		if header.ParentHash != parent {
+			return fmt.Errorf("ancient store discontinuous at #%d: parent %x, want %x", frozen, header.ParentHash, parent)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestBackup(t *testing.T) {
+	testBackup(t, rawdb.HashScheme)
+	testBackup(t, rawdb.PathScheme)
+}
+
+func testBackup(t *testing.T, scheme string) {
+	datadir := t.TempDir()
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Directory:         datadir,
+		AncientsDirectory: filepath.Join(datadir, "ancient"),
+		Ephemeral:         true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create persistent database: %v", err)
+	}
+	defer db.Close()
+
+	var (
+		gspec = &Genesis{
+			BaseFee: big.NewInt(params.InitialBaseFee),
+			Config:  params.AllEthashProtocolChanges,
+			Alloc:   types.GenesisAlloc{common.Address{0xaa}: {Balance: big.NewInt(params.Ether)}},
+		}
+		engine = ethash.NewFaker()
+		config = DefaultCacheConfigWithScheme(scheme)
+	)
+	config.SnapshotWait = true
+	chain, err := NewBlockChain(db, config, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 8, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{byte(i + 1)})
+	})
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("Failed to insert chain: %v", err)
+	}
+	// Back up the live database and check the copy against the manifest.
+	backup := filepath.Join(t.TempDir(), "backup")
+	manifest, err := chain.Backup(backup)
+	if err != nil {
+		t.Fatalf("Failed to back up database: %v", err)
+	}
+	if head := blocks[len(blocks)-1]; manifest.Hash != head.Hash() || manifest.Root != head.Root() {
+		t.Fatalf("Manifest mismatch: have #%d [%x], want #%d [%x]", manifest.Number, manifest.Hash, head.NumberU64(), head.Hash())
+	}
+	if _, err := chain.Backup(backup); err == nil {
+		t.Fatal("Backup overwrote an existing directory")
+	}
+	stored, err := ReadBackupManifest(backup)
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	// Restore the backup into a fresh directory and validate the result.
+	var (
+		src     = filepath.Join(backup, BackupChaindataDir)
+		restore = filepath.Join(t.TempDir(), "chaindata")
+		ancient = filepath.Join(restore, "ancient")
+	)
+	if err := rawdb.RestoreCheckpoint(src, restore, ancient); err != nil {
+		t.Fatalf("Failed to restore backup: %v", err)
+	}
+	if err := rawdb.RestoreCheckpoint(src, restore, ancient); err == nil {
+		t.Fatal("Restore overwrote an existing database")
+	}
+	restored, err := rawdb.Open(rawdb.OpenOptions{
+		Directory:         restore,
+		AncientsDirectory: ancient,
+		ReadOnly:          true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open restored database: %v", err)
+	}
+	defer restored.Close()
+
+	if err := ValidateBackup(restored, stored); err != nil {
+		t.Fatalf("Restored backup invalid: %v", err)
+	}
+	// Corrupt the manifest and ensure the validation notices.
+	stored.Root = common.Hash{0x1}
+	if err := ValidateBackup(restored, stored); err == nil {
+		t.Fatal("Validation passed with a mismatching state root")
+	}
+}
@@ -17,8 +17,10 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"math/big"
+	"runtime"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -69,6 +71,24 @@ func BenchmarkInsertChain_ring1000_diskdb(b *testing.B) {
 	benchInsertChain(b, true, genTxRing(1000))
 }
 
+// The parallel benchmarks compare sequential and speculative parallel block
+// processing, for blocks of conflicting and of independent transactions.
+func BenchmarkInsertChain_ring1000_memdb_parallel(b *testing.B) {
+	benchInsertChainWithConfig(b, false, genTxRing(1000), nil, runtime.NumCPU())
+}
+func BenchmarkInsertChain_independent_memdb(b *testing.B) {
+	benchInsertChainWithConfig(b, false, genIndependentCalls, benchRingAlloc(), 0)
+}
+func BenchmarkInsertChain_independent_memdb_parallel(b *testing.B) {
+	benchInsertChainWithConfig(b, false, genIndependentCalls, benchRingAlloc(), runtime.NumCPU())
+}
+func BenchmarkInsertChain_independent_diskdb(b *testing.B) {
+	benchInsertChainWithConfig(b, true, genIndependentCalls, benchRingAlloc(), 0)
+}
+func BenchmarkInsertChain_independent_diskdb_parallel(b *testing.B) {
+	benchInsertChainWithConfig(b, true, genIndependentCalls, benchRingAlloc(), runtime.NumCPU())
+}
+
 var (
 	// This is the content of the genesis block used by the benchmarks.
 	benchRootKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
@@ -158,6 +178,47 @@ func genTxRing(naccounts int) func(int, *BlockGen) {
 	}
 }
 
+var (
+	// benchHasherAddr is a contract hashing its memory 200 times, and storing
+	// the result in the slot keyed by the caller.
+	benchHasherAddr = common.HexToAddress("0xbeef")
+	benchHasherCode = append(bytes.Repeat(common.FromHex("0x60405f205f52"), 200), common.FromHex("0x5f51335500")...)
+)
+
+// benchRingAlloc returns a genesis allocation funding the ring accounts and
+// deploying the hasher contract.
+func benchRingAlloc() types.GenesisAlloc {
+	alloc := types.GenesisAlloc{benchHasherAddr: {Code: benchHasherCode}}
+	for _, addr := range ringAddrs {
+		alloc[addr] = types.Account{Balance: benchRootFunds}
+	}
+	return alloc
+}
+
+// genIndependentCalls is a block generator filling the blocks with calls to
+// the hasher contract, each from a different ring account. The transactions of
+// a block don't depend on each other.
+func genIndependentCalls(i int, gen *BlockGen) {
+	var (
+		gas    = gen.PrevBlock(i - 1).GasLimit()
+		signer = gen.Signer()
+	)
+	for j := 0; gas >= 100_000 && j < len(ringKeys); j++ {
+		gas -= 100_000
+		from := (i*len(ringKeys)/8 + j) % len(ringKeys)
+		tx, err := types.SignNewTx(ringKeys[from], signer, &types.LegacyTx{
+			Nonce:    gen.TxNonce(ringAddrs[from]),
+			To:       &benchHasherAddr,
+			Gas:      100_000,
+			GasPrice: gen.header.BaseFee,
+		})
+		if err != nil {
+			panic(err)
+		}
+		gen.AddTx(tx)
+	}
+}
+
 // genUncles generates blocks with two uncle headers.
 func genUncles(i int, gen *BlockGen) {
 	if i >= 7 {
@@ -171,6 +232,10 @@ func genUncles(i int, gen *BlockGen) {
 }
 
 func benchInsertChain(b *testing.B, disk bool, gen func(int, *BlockGen)) {
+	benchInsertChainWithConfig(b, disk, gen, nil, 0)
+}
+
+func benchInsertChainWithConfig(b *testing.B, disk bool, gen func(int, *BlockGen), alloc types.GenesisAlloc, workers int) {
 	// Create the database in memory or in a temporary directory.
 	var db ethdb.Database
 	var err error
@@ -191,11 +256,16 @@ func benchInsertChain(b *testing.B, disk bool, gen func(int, *BlockGen)) {
 		Config: params.TestChainConfig,
 		Alloc:  types.GenesisAlloc{benchRootAddr: {Balance: benchRootFunds}},
 	}
+	for addr, account := range alloc {
+		gspec.Alloc[addr] = account
+	}
 	_, chain, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), b.N, gen)
 
 	// Time the insertion of the new chain.
 	// State and blocks are stored in the same DB.
-	chainman, _ := NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.ParallelWorkers = workers
+	chainman, _ := NewBlockChain(db, &cacheConfig, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
 	defer chainman.Stop()
 	b.ReportAllocs()
 	b.ResetTimer()
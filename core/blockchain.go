@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"runtime"
 	"strings"
@@ -88,6 +89,7 @@ var (
 
 	blockPrefetchExecuteTimer   = metrics.NewRegisteredTimer("chain/prefetch/executes", nil)
 	blockPrefetchInterruptMeter = metrics.NewRegisteredMeter("chain/prefetch/interrupts", nil)
+	blockPrefetchAccessSetTimer = metrics.NewRegisteredTimer("chain/prefetch/accesssets", nil)
 
 	errInsertionInterrupted = errors.New("insertion is interrupted")
 	errChainStopped         = errors.New("blockchain is stopped")
@@ -96,10 +98,11 @@ var (
 )
 
 const (
-	bodyCacheLimit     = 256
-	blockCacheLimit    = 256
-	receiptsCacheLimit = 32
-	txLookupCacheLimit = 1024
+	bodyCacheLimit      = 256
+	blockCacheLimit     = 256
+	receiptsCacheLimit  = 32
+	accessSetCacheLimit = 64
+	txLookupCacheLimit  = 1024
 
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	//
@@ -139,6 +142,8 @@ type CacheConfig struct {
 	Preimages           bool          // Whether to store preimage of trie key to the disk
 	StateHistory        uint64        // Number of blocks from head whose state histories are reserved.
 	StateScheme         string        // Scheme used to store ethereum states and merkle tree nodes on top
+	AccessSetHistory    uint64        // Number of recent blocks to retain recorded state access sets for (0 = none)
+	ParallelWorkers     int           // Number of workers speculatively executing block transactions (0 = sequential)
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
@@ -218,6 +223,7 @@ type BlockChain struct {
 	triedb        *triedb.Database                 // The database handler for maintaining trie nodes.
 	stateCache    state.Database                   // State database to reuse between imports (contains state cache)
 	txIndexer     *txIndexer                       // Transaction indexer, might be nil if not enabled
+	historyCutoff uint64                           // First block with retained body and receipts, older ones are pruned
 
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
@@ -238,10 +244,11 @@ type BlockChain struct {
 	currentFinalBlock atomic.Pointer[types.Header] // Latest (consensus) finalized block
 	currentSafeBlock  atomic.Pointer[types.Header] // Latest (consensus) safe block
 
-	bodyCache     *lru.Cache[common.Hash, *types.Body]
-	bodyRLPCache  *lru.Cache[common.Hash, rlp.RawValue]
-	receiptsCache *lru.Cache[common.Hash, []*types.Receipt]
-	blockCache    *lru.Cache[common.Hash, *types.Block]
+	bodyCache      *lru.Cache[common.Hash, *types.Body]
+	bodyRLPCache   *lru.Cache[common.Hash, rlp.RawValue]
+	receiptsCache  *lru.Cache[common.Hash, []*types.Receipt]
+	accessSetCache *lru.Cache[common.Hash, *types.BlockAccessSet]
+	blockCache     *lru.Cache[common.Hash, *types.Block]
 
 	txLookupLock  sync.RWMutex
 	txLookupCache *lru.Cache[common.Hash, txLookup]
@@ -286,21 +293,22 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	log.Info("")
 
 	bc := &BlockChain{
-		chainConfig:   chainConfig,
-		cacheConfig:   cacheConfig,
-		db:            db,
-		triedb:        triedb,
-		triegc:        prque.New[int64, common.Hash](nil),
-		quit:          make(chan struct{}),
-		chainmu:       syncx.NewClosableMutex(),
-		bodyCache:     lru.NewCache[common.Hash, *types.Body](bodyCacheLimit),
-		bodyRLPCache:  lru.NewCache[common.Hash, rlp.RawValue](bodyCacheLimit),
-		receiptsCache: lru.NewCache[common.Hash, []*types.Receipt](receiptsCacheLimit),
-		blockCache:    lru.NewCache[common.Hash, *types.Block](blockCacheLimit),
-		txLookupCache: lru.NewCache[common.Hash, txLookup](txLookupCacheLimit),
-		engine:        engine,
-		vmConfig:      vmConfig,
-		logger:        vmConfig.Tracer,
+		chainConfig:    chainConfig,
+		cacheConfig:    cacheConfig,
+		db:             db,
+		triedb:         triedb,
+		triegc:         prque.New[int64, common.Hash](nil),
+		quit:           make(chan struct{}),
+		chainmu:        syncx.NewClosableMutex(),
+		bodyCache:      lru.NewCache[common.Hash, *types.Body](bodyCacheLimit),
+		bodyRLPCache:   lru.NewCache[common.Hash, rlp.RawValue](bodyCacheLimit),
+		receiptsCache:  lru.NewCache[common.Hash, []*types.Receipt](receiptsCacheLimit),
+		accessSetCache: lru.NewCache[common.Hash, *types.BlockAccessSet](accessSetCacheLimit),
+		blockCache:     lru.NewCache[common.Hash, *types.Block](blockCacheLimit),
+		txLookupCache:  lru.NewCache[common.Hash, txLookup](txLookupCacheLimit),
+		engine:         engine,
+		vmConfig:       vmConfig,
+		logger:         vmConfig.Tracer,
 	}
 	bc.flushInterval.Store(int64(cacheConfig.TrieTimeLimit))
 	bc.forker = NewForkChoice(bc, shouldPreserve)
@@ -314,7 +322,19 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	if err != nil {
 		return nil, err
 	}
+	bc.historyCutoff = rawdb.ReadHistoryCutoff(db)
 	bc.genesisBlock = bc.GetBlockByNumber(0)
+	if bc.genesisBlock == nil && bc.historyCutoff > 0 {
+		// The genesis body was pruned along with the chain history, but it
+		// is always empty, so reassemble the block from its header.
+		if header := bc.GetHeaderByNumber(0); header != nil {
+			body := new(types.Body)
+			if header.WithdrawalsHash != nil {
+				body.Withdrawals = make([]*types.Withdrawal, 0)
+			}
+			bc.genesisBlock = types.NewBlockWithHeader(header).WithBody(*body)
+		}
+	}
 	if bc.genesisBlock == nil {
 		return nil, ErrNoGenesis
 	}
@@ -459,6 +479,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		rawdb.WriteChainConfig(db, genesisHash, chainConfig)
 	}
 
+	// Drop the state access sets retained earlier if retention was disabled.
+	bc.pruneAccessSets()
+
 	// Start tx indexer if it's enabled.
 	if txLookupLimit != nil {
 		bc.txIndexer = newTxIndexer(*txLookupLimit, bc)
@@ -1451,7 +1474,7 @@ func (bc *BlockChain) writeKnownBlock(block *types.Block) error {
 
 // writeBlockWithState writes block, metadata and corresponding state data to the
 // database.
-func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, statedb *state.StateDB) error {
+func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.Receipt, accessSet *types.BlockAccessSet, statedb *state.StateDB) error {
 	// Calculate the total difficulty of the block
 	ptd := bc.GetTd(block.ParentHash(), block.NumberU64()-1)
 	if ptd == nil {
@@ -1469,9 +1492,15 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	rawdb.WriteBlock(blockBatch, block)
 	rawdb.WriteReceipts(blockBatch, block.Hash(), block.NumberU64(), receipts)
 	rawdb.WritePreimages(blockBatch, statedb.Preimages())
+	if accessSet != nil {
+		bc.writeAccessSet(blockBatch, block, accessSet)
+	}
 	if err := blockBatch.Write(); err != nil {
 		log.Crit("Failed to write block into disk", "err", err)
 	}
+	if accessSet != nil {
+		bc.accessSetCache.Add(block.Hash(), accessSet)
+	}
 	// Commit all cached state changes into underlying memory database.
 	root, err := statedb.Commit(block.NumberU64(), bc.chainConfig.IsEIP158(block.Number()))
 	if err != nil {
@@ -1539,8 +1568,8 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 
 // writeBlockAndSetHead is the internal implementation of WriteBlockAndSetHead.
 // This function expects the chain mutex to be held.
-func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
-	if err := bc.writeBlockWithState(block, receipts, state); err != nil {
+func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types.Receipt, logs []*types.Log, accessSet *types.BlockAccessSet, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
+	if err := bc.writeBlockWithState(block, receipts, accessSet, state); err != nil {
 		return NonStatTy, err
 	}
 	currentBlock := bc.CurrentBlock()
@@ -1813,30 +1842,41 @@ func (bc *BlockChain) insertChain(chain types.Blocks, setHead bool) (int, error)
 		}
 		activeState = statedb
 
+		// If the state accessed by the block is known from an earlier execution or
+		// was supplied externally, load it in parallel to the block execution.
+		var accessSetInterrupt atomic.Bool
+		if set := bc.GetAccessSet(block.Hash(), block.NumberU64()); set != nil {
+			go bc.prefetchAccessSet(set, parent.Root, &accessSetInterrupt)
+		}
 		// If we have a followup block, run that against the current state to pre-cache
 		// transactions and probabilistically some of the account/storage trie nodes.
 		var followupInterrupt atomic.Bool
 		if !bc.cacheConfig.TrieCleanNoPrefetch {
 			if followup, err := it.peek(); followup != nil && err == nil {
-				throwaway, _ := state.New(parent.Root, bc.stateCache, bc.snaps)
-
-				go func(start time.Time, followup *types.Block, throwaway *state.StateDB) {
-					// Disable tracing for prefetcher executions.
-					vmCfg := bc.vmConfig
-					vmCfg.Tracer = nil
-					bc.prefetcher.Prefetch(followup, throwaway, vmCfg, &followupInterrupt)
-
-					blockPrefetchExecuteTimer.Update(time.Since(start))
-					if followupInterrupt.Load() {
-						blockPrefetchInterruptMeter.Mark(1)
-					}
-				}(time.Now(), followup, throwaway)
+				if set := bc.GetAccessSet(followup.Hash(), followup.NumberU64()); set != nil {
+					go bc.prefetchAccessSet(set, parent.Root, &followupInterrupt)
+				} else {
+					throwaway, _ := state.New(parent.Root, bc.stateCache, bc.snaps)
+
+					go func(start time.Time, followup *types.Block, throwaway *state.StateDB) {
+						// Disable tracing for prefetcher executions.
+						vmCfg := bc.vmConfig
+						vmCfg.Tracer = nil
+						bc.prefetcher.Prefetch(followup, throwaway, vmCfg, &followupInterrupt)
+
+						blockPrefetchExecuteTimer.Update(time.Since(start))
+						if followupInterrupt.Load() {
+							blockPrefetchInterruptMeter.Mark(1)
+						}
+					}(time.Now(), followup, throwaway)
+				}
 			}
 		}
 
 		// The traced section of block import.
 		res, err := bc.processBlock(block, statedb, start, setHead)
 		followupInterrupt.Store(true)
+		accessSetInterrupt.Store(true)
 		if err != nil {
 			return it.index, err
 		}
@@ -1923,6 +1963,9 @@ func (bc *BlockChain) processBlock(block *types.Block, statedb *state.StateDB, s
 	}
 	ptime := time.Since(pstart)
 
+	// Record the state accessed by the block, before committing drops it
+	accessSet := statedb.AccessSet()
+
 	vstart := time.Now()
 	if err := bc.validator.ValidateState(block, statedb, receipts, usedGas); err != nil {
 		bc.reportBlock(block, receipts, err)
@@ -1953,13 +1996,14 @@ func (bc *BlockChain) processBlock(block *types.Block, statedb *state.StateDB, s
 	)
 	if !setHead {
 		// Don't set the head, only insert the block
-		err = bc.writeBlockWithState(block, receipts, statedb)
+		err = bc.writeBlockWithState(block, receipts, accessSet, statedb)
 	} else {
-		status, err = bc.writeBlockAndSetHead(block, receipts, logs, statedb, false)
+		status, err = bc.writeBlockAndSetHead(block, receipts, logs, accessSet, statedb, false)
 	}
 	if err != nil {
 		return nil, err
 	}
+
 	// Update the metrics touched during block commit
 	accountCommitTimer.Update(statedb.AccountCommits)   // Account commits are complete, we can mark them
 	storageCommitTimer.Update(statedb.StorageCommits)   // Storage commits are complete, we can mark them
@@ -1972,6 +2016,71 @@ func (bc *BlockChain) processBlock(block *types.Block, statedb *state.StateDB, s
 	return &blockProcessingResult{usedGas: usedGas, procTime: proctime, status: status}, nil
 }
 
+// AddAccessSet makes a state access set of a block, e.g. as supplied by a peer
+// or the consensus layer, available for prefetching the state of the block when
+// it gets executed.
+func (bc *BlockChain) AddAccessSet(hash common.Hash, set *types.BlockAccessSet) {
+	bc.accessSetCache.Add(hash, set)
+}
+
+// writeAccessSet persists the state access set recorded for the executed block
+// into the given batch, if access sets of recent blocks are retained. Access sets
+// of all blocks older than the retention limit are deleted along with it.
+func (bc *BlockChain) writeAccessSet(batch ethdb.KeyValueWriter, block *types.Block, set *types.BlockAccessSet) {
+	limit := bc.cacheConfig.AccessSetHistory
+	if limit == 0 {
+		return
+	}
+	rawdb.WriteAccessSet(batch, block.Hash(), block.NumberU64(), set)
+
+	var (
+		number = block.NumberU64()
+		stored = rawdb.ReadAccessSetTail(bc.db)
+		tail   uint64
+	)
+	if number >= limit {
+		tail = number - limit + 1
+	}
+	switch {
+	case stored == nil:
+		rawdb.DeleteAccessSetRange(bc.db, batch, 0, tail)
+		rawdb.WriteAccessSetTail(batch, tail)
+	case number < *stored:
+		// The block is below the tail after a rewind, move the tail back to
+		// cover its access set.
+		rawdb.WriteAccessSetTail(batch, number)
+	case *stored < tail:
+		rawdb.DeleteAccessSetRange(bc.db, batch, *stored, tail)
+		rawdb.WriteAccessSetTail(batch, tail)
+	}
+}
+
+// pruneAccessSets deletes all retained state access sets if their retention has
+// been disabled since they were written.
+func (bc *BlockChain) pruneAccessSets() {
+	if bc.cacheConfig.AccessSetHistory != 0 {
+		return
+	}
+	tail := rawdb.ReadAccessSetTail(bc.db)
+	if tail == nil {
+		return
+	}
+	batch := bc.db.NewBatch()
+	rawdb.DeleteAccessSetRange(bc.db, batch, *tail, math.MaxUint64)
+	rawdb.DeleteAccessSetTail(batch)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to delete block access sets", "err", err)
+	}
+	log.Info("Deleted retained block access sets", "tail", *tail)
+}
+
+// prefetchAccessSet loads the state in the access set on top of the given root.
+func (bc *BlockChain) prefetchAccessSet(set *types.BlockAccessSet, root common.Hash, interrupt *atomic.Bool) {
+	start := time.Now()
+	PrefetchAccessSet(set, root, bc.stateCache, bc.snaps, interrupt)
+	blockPrefetchAccessSetTimer.Update(time.Since(start))
+}
+
 // insertSideChain is called when an import batch hits upon a pruned ancestor
 // error, which happens when a sidechain with a sufficiently old fork-block is
 // found.
@@ -230,6 +230,23 @@ func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
 	return receipts
 }
 
+// GetAccessSet retrieves the state access set of a block, either recorded when
+// the block was executed or supplied externally. Nil is returned if the access
+// set is not known.
+func (bc *BlockChain) GetAccessSet(hash common.Hash, number uint64) *types.BlockAccessSet {
+	if set, ok := bc.accessSetCache.Get(hash); ok {
+		return set
+	}
+	if bc.cacheConfig.AccessSetHistory == 0 {
+		return nil
+	}
+	set := rawdb.ReadAccessSet(bc.db, hash, number)
+	if set != nil {
+		bc.accessSetCache.Add(hash, set)
+	}
+	return set
+}
+
 // GetUnclesInChain retrieves all the uncles from a given block backwards until
 // a specific distance is reached.
 func (bc *BlockChain) GetUnclesInChain(block *types.Block, length int) []*types.Header {
@@ -282,7 +299,7 @@ func (bc *BlockChain) GetTransactionLookup(hash common.Hash) (*rawdb.LegacyTxLoo
 		// The transaction indexing is not finished yet, returning an
 		// error to explicitly indicate it.
 		if !progress.Done() {
-			return nil, nil, errors.New("transaction indexing still in progress")
+			return nil, nil, ErrTxIndexing
 		}
 		// The transaction is already indexed, the transaction is either
 		// not existent or not in the range of index, returning null.
@@ -395,6 +412,12 @@ func (bc *BlockChain) Genesis() *types.Block {
 	return bc.genesisBlock
 }
 
+// HistoryCutoff returns the number of the first block whose body and receipts
+// are retained in the database, the history below it having been pruned.
+func (bc *BlockChain) HistoryCutoff() uint64 {
+	return bc.historyCutoff
+}
+
 // GetVMConfig returns the block chain VM config.
 func (bc *BlockChain) GetVMConfig() *vm.Config {
 	return &bc.vmConfig
@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -4221,3 +4222,91 @@ func TestEIP3651(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// Tests that the state access sets of executed blocks are recorded, retained
+// for the configured number of recent blocks and usable for prefetching.
+func TestAccessSetHistory(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(1000000000000000)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  types.GenesisAlloc{address: {Balance: funds}},
+		}
+	)
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 4, func(i int, gen *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(gen.TxNonce(address), common.Address{byte(i + 1)}, big.NewInt(1), params.TxGas, gen.header.BaseFee, nil), types.HomesteadSigner{}, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gen.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	cacheConfig := DefaultCacheConfigWithScheme(rawdb.HashScheme)
+	cacheConfig.AccessSetHistory = 2
+	cacheConfig.SnapshotWait = true
+
+	chain, err := NewBlockChain(db, cacheConfig, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Leave a stale access set of an unknown block behind, which must be pruned
+	// along with the retired canonical ones.
+	stale := common.Hash{0xff}
+	rawdb.WriteAccessSet(db, stale, 1, new(types.BlockAccessSet))
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	if rawdb.ReadAccessSet(db, stale, 1) != nil {
+		t.Error("stale access set not pruned")
+	}
+	if tail := rawdb.ReadAccessSetTail(db); tail == nil || *tail != 3 {
+		t.Errorf("access set tail mismatch: have %v, want 3", tail)
+	}
+	for _, block := range blocks {
+		set := rawdb.ReadAccessSet(db, block.Hash(), block.NumberU64())
+		if block.NumberU64() <= 2 {
+			if set != nil {
+				t.Errorf("block %d: access set not pruned", block.NumberU64())
+			}
+			continue
+		}
+		if set == nil {
+			t.Fatalf("block %d: access set missing", block.NumberU64())
+		}
+		// The sender, the recipient and the coinbase must be in the set
+		want := []common.Address{address, {byte(block.NumberU64())}, block.Coinbase()}
+		for _, addr := range want {
+			if !slices.ContainsFunc(set.Accounts, func(acc types.AccountAccess) bool { return acc.Address == addr }) {
+				t.Errorf("block %d: account %x missing from access set", block.NumberU64(), addr)
+			}
+		}
+		parent := chain.GetHeaderByHash(block.ParentHash())
+		PrefetchAccessSet(set, parent.Root, chain.stateCache, chain.snaps, nil)
+	}
+	// Externally supplied access sets are served for prefetching.
+	supplied := &types.BlockAccessSet{Accounts: []types.AccountAccess{{Address: address}}}
+	chain.AddAccessSet(stale, supplied)
+	if set := chain.GetAccessSet(stale, 5); set != supplied {
+		t.Errorf("supplied access set not returned")
+	}
+	// Disabling the retention drops all retained access sets.
+	chain.Stop()
+	cacheConfig.AccessSetHistory = 0
+	chain, err = NewBlockChain(db, cacheConfig, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	for _, block := range blocks {
+		if rawdb.ReadAccessSet(db, block.Hash(), block.NumberU64()) != nil {
+			t.Errorf("block %d: access set retained after disabling retention", block.NumberU64())
+		}
+	}
+	if tail := rawdb.ReadAccessSetTail(db); tail != nil {
+		t.Errorf("access set tail retained after disabling retention: %d", *tail)
+	}
+}
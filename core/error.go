@@ -29,6 +29,10 @@ var (
 	// ErrNoGenesis is returned when there is no Genesis Block.
 	ErrNoGenesis = errors.New("genesis not found in chain")
 
+	// ErrTxIndexing is returned when a transaction lookup fails because the
+	// transaction indexer has not finished yet.
+	ErrTxIndexing = errors.New("transaction indexing still in progress")
+
 	errSideChainReceipts = errors.New("side blocks can't be accepted as ancient chain data")
 )
 
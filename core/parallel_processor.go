@@ -0,0 +1,454 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/holiman/uint256"
+)
+
+var (
+	parallelSpeculatedMeter  = metrics.NewRegisteredMeter("chain/parallel/speculated", nil)
+	parallelReexecutedMeter  = metrics.NewRegisteredMeter("chain/parallel/reexecuted", nil)
+	parallelConflictingMeter = metrics.NewRegisteredMeter("chain/parallel/conflicting", nil)
+)
+
+// The parallel processor executes the transactions of a block optimistically,
+// in the style of block-STM:
+//
+//   - Worker goroutines speculatively execute every transaction on their own
+//     copy of the state as it was before the first transaction, recording the
+//     state the transaction read and the mutations it made.
+//   - The transactions are then committed strictly in block order. If nothing
+//     a transaction read was written by a transaction committed before it, the
+//     speculative execution is valid and its recorded mutations are replayed
+//     on the canonical state. Otherwise the transaction is re-executed on the
+//     canonical state.
+//
+// Since a valid speculation observed exactly the state sequential execution
+// would have, and replaying its mutations goes through the very same journal
+// as executing it would, the resulting state, receipts and logs are identical
+// to the sequential processor by construction.
+//
+// Balance increases and decreases are not treated as reads of the balance, so
+// that all transactions paying fees to the coinbase don't conflict with each
+// other. They are however writes, and conflict with any later transaction
+// reading the balance.
+
+// parallel reports whether the transactions of the block should be executed
+// by the parallel processor.
+func (p *StateProcessor) parallel(block *types.Block, cfg vm.Config) bool {
+	// Tracers and witnesses expect to observe execution on the canonical state
+	if p.workers <= 0 || cfg.Tracer != nil || cfg.EnableWitnessCollection {
+		return false
+	}
+	// Pre-byzantium receipts contain the intermediate state root of every
+	// transaction, parallelism would not buy anything.
+	if !p.config.IsByzantium(block.Number()) {
+		return false
+	}
+	return len(block.Transactions()) > 1
+}
+
+// speculation is the outcome of speculatively executing a single transaction.
+type speculation struct {
+	result *ExecutionResult
+	err    error
+	state  *recordingState // Read set, write set and mutations of the execution
+	done   chan struct{}   // Closed when the speculation is finished
+}
+
+// processParallel executes the transactions of the block speculatively in
+// parallel and commits them in order onto the state. It returns the same
+// receipts, logs and errors as sequential execution.
+func (p *StateProcessor) processParallel(block *types.Block, statedb *state.StateDB, cfg vm.Config, gp *GasPool, usedGas *uint64, vmenv *vm.EVM, signer types.Signer) (types.Receipts, []*types.Log, error) {
+	var (
+		header = block.Header()
+		txs    = block.Transactions()
+		msgs   = make([]*Message, len(txs))
+		specs  = make([]*speculation, len(txs))
+	)
+	for i, tx := range txs {
+		msg, err := TransactionToMessage(tx, signer, header.BaseFee)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+		}
+		msgs[i] = msg
+		specs[i] = &speculation{done: make(chan struct{})}
+	}
+	// Start the speculative workers, each operating on its own copy of the state
+	workers := p.workers
+	if workers > len(txs) {
+		workers = len(txs)
+	}
+	var (
+		next  atomic.Int64
+		abort atomic.Bool
+		wg    sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(base *state.StateDB) {
+			defer wg.Done()
+
+			evm := vm.NewEVM(NewEVMBlockContext(header, p.bc, nil), vm.TxContext{}, base, p.config, cfg)
+			for {
+				index := int(next.Add(1) - 1)
+				if index >= len(txs) {
+					return
+				}
+				spec := specs[index]
+				if !abort.Load() {
+					p.speculate(evm, base, txs[index], index, msgs[index], header.GasLimit, spec)
+				}
+				close(spec.done)
+			}
+		}(statedb.Copy())
+	}
+	defer wg.Wait()
+	defer abort.Store(true)
+
+	// Commit the transactions in order, validating their speculations against
+	// the writes of all transactions committed before them
+	var (
+		receipts = make(types.Receipts, 0, len(txs))
+		allLogs  []*types.Log
+		written  = newAccessSet()
+	)
+	for i, tx := range txs {
+		spec := specs[i]
+		<-spec.done
+
+		statedb.SetTxContext(tx.Hash(), i)
+
+		var (
+			result *ExecutionResult
+			writes *accessSet
+		)
+		switch {
+		case spec.err == nil && !spec.state.reads.conflicts(written) && gp.Gas() >= msgs[i].GasLimit:
+			// The speculation is valid, replay its mutations and charge its gas
+			spec.state.replay(statedb)
+			if err := gp.SubGas(spec.result.UsedGas); err != nil {
+				return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			result, writes = spec.result, spec.state.writes
+			parallelSpeculatedMeter.Mark(1)
+
+		default:
+			// The speculation failed or read stale state, execute the transaction
+			// on the canonical state, recording its writes for later validations
+			if spec.err == nil {
+				parallelConflictingMeter.Mark(1)
+			}
+			recorder := newRecordingState(statedb, false)
+			vmenv.Reset(NewEVMTxContext(msgs[i]), recorder)
+
+			var err error
+			if result, err = ApplyMessage(vmenv, msgs[i], gp); err != nil {
+				return nil, nil, fmt.Errorf("could not apply tx %d [%v]: %w", i, tx.Hash().Hex(), err)
+			}
+			writes = recorder.writes
+			parallelReexecutedMeter.Mark(1)
+		}
+		statedb.Finalise(true)
+		written.merge(writes)
+
+		*usedGas += result.UsedGas
+		vmenv.Reset(NewEVMTxContext(msgs[i]), statedb)
+		receipt := MakeReceipt(vmenv, result, statedb, block.Number(), block.Hash(), tx, *usedGas, nil)
+
+		receipts = append(receipts, receipt)
+		allLogs = append(allLogs, receipt.Logs...)
+	}
+	return receipts, allLogs, nil
+}
+
+// speculate executes a transaction on the given state, recording its reads,
+// writes and mutations, and rolls the state back afterwards.
+func (p *StateProcessor) speculate(evm *vm.EVM, base *state.StateDB, tx *types.Transaction, index int, msg *Message, gasLimit uint64, spec *speculation) {
+	base.SetTxContext(tx.Hash(), index)
+	snapshot := base.Snapshot()
+	defer base.RevertToSnapshot(snapshot)
+
+	spec.state = newRecordingState(base, true)
+	evm.Reset(NewEVMTxContext(msg), spec.state)
+	spec.result, spec.err = ApplyMessage(evm, msg, new(GasPool).AddGas(gasLimit))
+}
+
+// storageKey identifies a storage slot of an account.
+type storageKey struct {
+	addr common.Address
+	slot common.Hash
+}
+
+// accessSet is a set of accessed accounts and storage slots.
+type accessSet struct {
+	accounts map[common.Address]struct{} // Accounts whose fields were accessed
+	resets   map[common.Address]struct{} // Accounts whose storage was wiped (written only)
+	slots    map[storageKey]struct{}     // Storage slots accessed
+}
+
+func newAccessSet() *accessSet {
+	return &accessSet{
+		accounts: make(map[common.Address]struct{}),
+		resets:   make(map[common.Address]struct{}),
+		slots:    make(map[storageKey]struct{}),
+	}
+}
+
+// conflicts reports whether any read in the set is invalidated by the writes.
+func (s *accessSet) conflicts(writes *accessSet) bool {
+	for addr := range s.accounts {
+		if _, ok := writes.accounts[addr]; ok {
+			return true
+		}
+	}
+	for key := range s.slots {
+		if _, ok := writes.slots[key]; ok {
+			return true
+		}
+		if _, ok := writes.resets[key.addr]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// merge adds all accesses of the other set.
+func (s *accessSet) merge(other *accessSet) {
+	for addr := range other.accounts {
+		s.accounts[addr] = struct{}{}
+	}
+	for addr := range other.resets {
+		s.resets[addr] = struct{}{}
+	}
+	for key := range other.slots {
+		s.slots[key] = struct{}{}
+	}
+}
+
+// stateOp is a recorded state mutation. Revisions maps the snapshot ids of
+// the recording state to the ones of the replaying state.
+type stateOp func(statedb *state.StateDB, revisions map[int]int)
+
+// recordingState wraps a state, tracking the accounts and storage slots read
+// and written through it, and optionally recording the mutations so they can
+// be replayed on another state.
+//
+// Accesses to the access list, transient storage and refund counter are not
+// tracked, as they are reset at the start of every transaction.
+type recordingState struct {
+	*state.StateDB
+
+	reads  *accessSet
+	writes *accessSet
+	ops    []stateOp // Recorded mutations, nil if not recording
+	record bool
+}
+
+var _ vm.StateDB = (*recordingState)(nil)
+
+func newRecordingState(statedb *state.StateDB, record bool) *recordingState {
+	return &recordingState{
+		StateDB: statedb,
+		reads:   newAccessSet(),
+		writes:  newAccessSet(),
+		record:  record,
+	}
+}
+
+// replay applies the recorded mutations to the state.
+func (s *recordingState) replay(statedb *state.StateDB) {
+	revisions := make(map[int]int)
+	for _, op := range s.ops {
+		op(statedb, revisions)
+	}
+}
+
+func (s *recordingState) push(op stateOp) {
+	if s.record {
+		s.ops = append(s.ops, op)
+	}
+}
+
+func (s *recordingState) readAccount(addr common.Address) {
+	s.reads.accounts[addr] = struct{}{}
+}
+
+func (s *recordingState) writeAccount(addr common.Address) {
+	s.writes.accounts[addr] = struct{}{}
+}
+
+func (s *recordingState) resetAccount(addr common.Address) {
+	s.writes.accounts[addr] = struct{}{}
+	s.writes.resets[addr] = struct{}{}
+}
+
+func (s *recordingState) CreateAccount(addr common.Address) {
+	s.resetAccount(addr)
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.CreateAccount(addr) })
+	s.StateDB.CreateAccount(addr)
+}
+
+func (s *recordingState) CreateContract(addr common.Address) {
+	s.resetAccount(addr)
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.CreateContract(addr) })
+	s.StateDB.CreateContract(addr)
+}
+
+func (s *recordingState) SubBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
+	s.writeAccount(addr)
+	amount = new(uint256.Int).Set(amount)
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.SubBalance(addr, amount, reason) })
+	s.StateDB.SubBalance(addr, amount, reason)
+}
+
+func (s *recordingState) AddBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) {
+	s.writeAccount(addr)
+	amount = new(uint256.Int).Set(amount)
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.AddBalance(addr, amount, reason) })
+	s.StateDB.AddBalance(addr, amount, reason)
+}
+
+func (s *recordingState) GetBalance(addr common.Address) *uint256.Int {
+	s.readAccount(addr)
+	return s.StateDB.GetBalance(addr)
+}
+
+func (s *recordingState) GetNonce(addr common.Address) uint64 {
+	s.readAccount(addr)
+	return s.StateDB.GetNonce(addr)
+}
+
+func (s *recordingState) SetNonce(addr common.Address, nonce uint64) {
+	s.writeAccount(addr)
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.SetNonce(addr, nonce) })
+	s.StateDB.SetNonce(addr, nonce)
+}
+
+func (s *recordingState) GetCodeHash(addr common.Address) common.Hash {
+	s.readAccount(addr)
+	return s.StateDB.GetCodeHash(addr)
+}
+
+func (s *recordingState) GetCode(addr common.Address) []byte {
+	s.readAccount(addr)
+	return s.StateDB.GetCode(addr)
+}
+
+func (s *recordingState) SetCode(addr common.Address, code []byte) {
+	s.writeAccount(addr)
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.SetCode(addr, code) })
+	s.StateDB.SetCode(addr, code)
+}
+
+func (s *recordingState) GetCodeSize(addr common.Address) int {
+	s.readAccount(addr)
+	return s.StateDB.GetCodeSize(addr)
+}
+
+func (s *recordingState) GetCommittedState(addr common.Address, slot common.Hash) common.Hash {
+	s.reads.slots[storageKey{addr, slot}] = struct{}{}
+	return s.StateDB.GetCommittedState(addr, slot)
+}
+
+func (s *recordingState) GetState(addr common.Address, slot common.Hash) common.Hash {
+	s.reads.slots[storageKey{addr, slot}] = struct{}{}
+	return s.StateDB.GetState(addr, slot)
+}
+
+func (s *recordingState) SetState(addr common.Address, slot common.Hash, value common.Hash) {
+	// Setting the storage of a missing account implicitly creates it
+	if !s.StateDB.Exist(addr) {
+		s.writeAccount(addr)
+	}
+	s.writes.slots[storageKey{addr, slot}] = struct{}{}
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.SetState(addr, slot, value) })
+	s.StateDB.SetState(addr, slot, value)
+}
+
+func (s *recordingState) GetStorageRoot(addr common.Address) common.Hash {
+	s.readAccount(addr)
+	return s.StateDB.GetStorageRoot(addr)
+}
+
+func (s *recordingState) SelfDestruct(addr common.Address) {
+	s.resetAccount(addr)
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.SelfDestruct(addr) })
+	s.StateDB.SelfDestruct(addr)
+}
+
+func (s *recordingState) HasSelfDestructed(addr common.Address) bool {
+	s.readAccount(addr)
+	return s.StateDB.HasSelfDestructed(addr)
+}
+
+func (s *recordingState) Selfdestruct6780(addr common.Address) {
+	s.resetAccount(addr)
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.Selfdestruct6780(addr) })
+	s.StateDB.Selfdestruct6780(addr)
+}
+
+func (s *recordingState) Exist(addr common.Address) bool {
+	s.readAccount(addr)
+	return s.StateDB.Exist(addr)
+}
+
+func (s *recordingState) Empty(addr common.Address) bool {
+	s.readAccount(addr)
+	return s.StateDB.Empty(addr)
+}
+
+func (s *recordingState) Snapshot() int {
+	id := s.StateDB.Snapshot()
+	s.push(func(statedb *state.StateDB, revisions map[int]int) { revisions[id] = statedb.Snapshot() })
+	return id
+}
+
+func (s *recordingState) RevertToSnapshot(id int) {
+	s.push(func(statedb *state.StateDB, revisions map[int]int) { statedb.RevertToSnapshot(revisions[id]) })
+	s.StateDB.RevertToSnapshot(id)
+}
+
+func (s *recordingState) AddLog(log *types.Log) {
+	// The state assigns the transaction and position fields of the log, so the
+	// replayed log must be a fresh one.
+	s.push(func(statedb *state.StateDB, _ map[int]int) {
+		statedb.AddLog(&types.Log{
+			Address:     log.Address,
+			Topics:      log.Topics,
+			Data:        log.Data,
+			BlockNumber: log.BlockNumber,
+		})
+	})
+	s.StateDB.AddLog(log)
+}
+
+func (s *recordingState) AddPreimage(hash common.Hash, preimage []byte) {
+	s.push(func(statedb *state.StateDB, _ map[int]int) { statedb.AddPreimage(hash, preimage) })
+	s.StateDB.AddPreimage(hash, preimage)
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the parallel processor produces the same state and receipts as
+// sequential execution, for blocks mixing independent and conflicting
+// transactions.
+func TestParallelProcessor(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 16)
+		addrs = make([]common.Address, len(keys))
+		funds = new(big.Int).Mul(big.NewInt(params.Ether), big.NewInt(1000))
+
+		counter    = common.HexToAddress("0xc0") // increments slot 0 and logs the new value
+		writer     = common.HexToAddress("0xc1") // stores the caller in the slot keyed by the caller
+		destructor = common.HexToAddress("0xc2") // self-destructs, sending its balance to the caller
+		observer   = common.HexToAddress("0xc3") // stores the coinbase balance in slot 0
+	)
+	alloc := types.GenesisAlloc{
+		counter:    {Code: common.FromHex("0x5f54600101805f555f5260205fa000")},
+		writer:     {Code: common.FromHex("0x33335500")},
+		destructor: {Code: common.FromHex("0x33ff"), Balance: big.NewInt(1000)},
+		observer:   {Code: common.FromHex("0x41315f5500")},
+
+		params.BeaconRootsAddress: {Code: params.BeaconRootsCode},
+	}
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+		alloc[addrs[i]] = types.Account{Balance: funds}
+	}
+	gspec := &Genesis{
+		Config:     params.MergedTestChainConfig,
+		GasLimit:   30_000_000,
+		Difficulty: common.Big1,
+		Alloc:      alloc,
+	}
+	engine := beacon.NewFaker()
+	_, blocks, _ := GenerateChainWithGenesis(gspec, engine, 4, func(n int, gen *BlockGen) {
+		gen.SetParentBeaconRoot(common.Hash{byte(n + 1)})
+
+		signer := gen.Signer()
+		send := func(key *ecdsa.PrivateKey, to *common.Address, value int64, data []byte) {
+			tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+				ChainID:   gspec.Config.ChainID,
+				Nonce:     gen.TxNonce(crypto.PubkeyToAddress(key.PublicKey)),
+				To:        to,
+				Value:     big.NewInt(value),
+				Gas:       200_000,
+				GasFeeCap: new(big.Int).Mul(gen.BaseFee(), big.NewInt(2)),
+				GasTipCap: big.NewInt(int64(n + 1)),
+				Data:      data,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			gen.AddTx(tx)
+		}
+		for i, key := range keys {
+			// Independent value transfer to a fresh account
+			recipient := common.BigToAddress(big.NewInt(int64(0x1000 + n*len(keys) + i)))
+			send(key, &recipient, 1, nil)
+
+			switch i % 4 {
+			case 0:
+				send(key, &counter, 0, nil)
+			case 1:
+				send(key, &writer, 0, nil)
+			case 2:
+				send(key, &addrs[(i+1)%len(keys)], 100, nil)
+			case 3:
+				send(key, nil, 0, common.FromHex("0x60006000a000")) // log and deploy empty code
+			}
+		}
+		send(keys[0], &destructor, 0, nil)
+		send(keys[1], &observer, 0, nil)
+	})
+	// Import the chain sequentially, and re-process every block in parallel on
+	// top of the imported parent state
+	chain, err := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer chain.Stop()
+
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	process := func(block *types.Block, workers int) (types.Receipts, []*types.Log, uint64, common.Hash) {
+		parent := chain.GetHeaderByHash(block.ParentHash())
+		statedb, err := chain.StateAt(parent.Root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		processor := NewStateProcessor(chain.Config(), chain, chain.Engine())
+		processor.workers = workers
+		receipts, logs, used, err := processor.Process(block, statedb, vm.Config{})
+		if err != nil {
+			t.Fatalf("workers %d, block %d: failed to process: %v", workers, block.NumberU64(), err)
+		}
+		return receipts, logs, used, statedb.IntermediateRoot(true)
+	}
+	for _, block := range blocks {
+		wantReceipts, wantLogs, _, _ := process(block, 0)
+
+		for _, workers := range []int{1, 4, 64} {
+			receipts, logs, used, root := process(block, workers)
+			if used != block.GasUsed() {
+				t.Errorf("workers %d, block %d: gas used mismatch: have %d, want %d", workers, block.NumberU64(), used, block.GasUsed())
+			}
+			if root != block.Root() {
+				t.Errorf("workers %d, block %d: state root mismatch: have %x, want %x", workers, block.NumberU64(), root, block.Root())
+			}
+			if have, want := mustJSON(t, receipts), mustJSON(t, wantReceipts); have != want {
+				t.Errorf("workers %d, block %d: receipts mismatch:\nhave %s\nwant %s", workers, block.NumberU64(), have, want)
+			}
+			if have, want := mustJSON(t, logs), mustJSON(t, wantLogs); have != want {
+				t.Errorf("workers %d, block %d: logs mismatch:\nhave %s\nwant %s", workers, block.NumberU64(), have, want)
+			}
+		}
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) string {
+	blob, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(blob)
+}
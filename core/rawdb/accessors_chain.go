@@ -277,6 +277,24 @@ func WriteTxIndexTail(db ethdb.KeyValueWriter, number uint64) {
 	}
 }
 
+// ReadHistoryCutoff retrieves the number of the oldest block whose body and
+// receipts are stored, zero if the chain history has not been pruned.
+func ReadHistoryCutoff(db ethdb.KeyValueReader) uint64 {
+	data, _ := db.Get(historyCutoffKey)
+	if len(data) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(data)
+}
+
+// WriteHistoryCutoff stores the number of the oldest block whose body and
+// receipts are stored into database.
+func WriteHistoryCutoff(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(historyCutoffKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the history cutoff", "err", err)
+	}
+}
+
 // ReadHeaderRange returns the rlp-encoded headers, starting at 'number', and going
 // backwards towards genesis. This method assumes that the caller already has
 // placed a cap on count, to prevent DoS issues.
@@ -668,6 +686,85 @@ func DeleteReceipts(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	}
 }
 
+// ReadAccessSet retrieves the state access set recorded for a block.
+func ReadAccessSet(db ethdb.KeyValueReader, hash common.Hash, number uint64) *types.BlockAccessSet {
+	data, _ := db.Get(blockAccessSetKey(number, hash))
+	if len(data) == 0 {
+		return nil
+	}
+	set := new(types.BlockAccessSet)
+	if err := rlp.DecodeBytes(data, set); err != nil {
+		log.Error("Invalid block access set RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return set
+}
+
+// WriteAccessSet stores the state access set recorded for a block.
+func WriteAccessSet(db ethdb.KeyValueWriter, hash common.Hash, number uint64, set *types.BlockAccessSet) {
+	data, err := rlp.EncodeToBytes(set)
+	if err != nil {
+		log.Crit("Failed to encode block access set", "err", err)
+	}
+	if err := db.Put(blockAccessSetKey(number, hash), data); err != nil {
+		log.Crit("Failed to store block access set", "err", err)
+	}
+}
+
+// DeleteAccessSet removes the state access set of a block.
+func DeleteAccessSet(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockAccessSetKey(number, hash)); err != nil {
+		log.Crit("Failed to delete block access set", "err", err)
+	}
+}
+
+// DeleteAccessSetRange removes the state access sets of all blocks in the range
+// [from, to), including the ones of side chains.
+func DeleteAccessSetRange(db ethdb.Iteratee, w ethdb.KeyValueWriter, from uint64, to uint64) {
+	it := db.NewIterator(blockAccessSetPrefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(blockAccessSetPrefix)+8+common.HashLength {
+			continue
+		}
+		if binary.BigEndian.Uint64(key[len(blockAccessSetPrefix):]) >= to {
+			break
+		}
+		if err := w.Delete(key); err != nil {
+			log.Crit("Failed to delete block access set", "err", err)
+		}
+	}
+}
+
+// ReadAccessSetTail retrieves the number of the oldest block whose state access
+// set is retained.
+func ReadAccessSetTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(accessSetTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteAccessSetTail stores the number of the oldest block whose state access
+// set is retained.
+func WriteAccessSetTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(accessSetTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the access set tail", "err", err)
+	}
+}
+
+// DeleteAccessSetTail removes the number of the oldest block whose state access
+// set is retained.
+func DeleteAccessSetTail(db ethdb.KeyValueWriter) {
+	if err := db.Delete(accessSetTailKey); err != nil {
+		log.Crit("Failed to delete the access set tail", "err", err)
+	}
+}
+
 // storedReceiptRLP is the storage encoding of a receipt.
 // Re-definition in core/types/receipt.go.
 // TODO: Re-use the existing definition.
@@ -929,3 +929,50 @@ func TestHeadersRLPStorage(t *testing.T) {
 	checkSequence(1, 1)    // Only block 1
 	checkSequence(1, 2)    // Genesis + block 1
 }
+
+func TestAccessSetStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	set := &types.BlockAccessSet{Accounts: []types.AccountAccess{
+		{Address: common.HexToAddress("0x1"), Code: true, Slots: []common.Hash{{0x1}, {0x2}}},
+		{Address: common.HexToAddress("0x2"), Slots: []common.Hash{}},
+	}}
+	hash1, hash2 := common.Hash{0x1}, common.Hash{0x2}
+	if entry := ReadAccessSet(db, hash1, 1); entry != nil {
+		t.Fatalf("Non existent access set returned: %v", entry)
+	}
+	WriteAccessSet(db, hash1, 1, set)
+	WriteAccessSet(db, hash2, 1, set)
+	WriteAccessSet(db, hash1, 2, set)
+
+	if entry := ReadAccessSet(db, hash1, 1); entry == nil {
+		t.Fatalf("Stored access set not found")
+	} else if !reflect.DeepEqual(entry, set) {
+		t.Fatalf("Retrieved access set mismatch: have %v, want %v", entry, set)
+	}
+	DeleteAccessSet(db, hash1, 2)
+	if entry := ReadAccessSet(db, hash1, 2); entry != nil {
+		t.Fatalf("Deleted access set returned: %v", entry)
+	}
+	// Deleting a range of access sets drops the side chain ones too
+	WriteAccessSet(db, hash1, 2, set)
+	WriteAccessSet(db, hash1, 3, set)
+	DeleteAccessSetRange(db, db, 0, 3)
+	if ReadAccessSet(db, hash1, 1) != nil || ReadAccessSet(db, hash2, 1) != nil || ReadAccessSet(db, hash1, 2) != nil {
+		t.Fatalf("Deleted access sets returned")
+	}
+	if ReadAccessSet(db, hash1, 3) == nil {
+		t.Fatalf("Access set beyond the range deleted")
+	}
+	if tail := ReadAccessSetTail(db); tail != nil {
+		t.Fatalf("Non existent access set tail returned: %d", *tail)
+	}
+	WriteAccessSetTail(db, 3)
+	if tail := ReadAccessSetTail(db); tail == nil || *tail != 3 {
+		t.Fatalf("Access set tail mismatch: have %v, want 3", tail)
+	}
+	DeleteAccessSetTail(db)
+	if tail := ReadAccessSetTail(db); tail != nil {
+		t.Fatalf("Deleted access set tail returned: %d", *tail)
+	}
+}
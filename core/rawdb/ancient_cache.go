@@ -0,0 +1,434 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// AncientCacheConfig contains the settings of the ancient store cache.
+type AncientCacheConfig struct {
+	Items     uint64 // Number of most recent items per table which are cached
+	Memory    uint64 // Size of the in-memory cache in bytes
+	Directory string // Directory of the on-disk cache, disabled if empty
+	Disk      uint64 // Size of the on-disk cache in bytes
+}
+
+// ancientCacheKey identifies a cached ancient item.
+type ancientCacheKey struct {
+	kind   string
+	number uint64
+}
+
+// ancientCacheMeters tracks the cache efficiency of an ancient table.
+type ancientCacheMeters struct {
+	memoryHit metrics.Meter
+	diskHit   metrics.Meter
+	miss      metrics.Meter
+}
+
+// cachedAncientStore is an ancient store wrapper, caching the most recent
+// items of every table in memory and optionally on a local disk. It allows the
+// ancient store to be placed on slow storage without affecting the access to
+// recently frozen data.
+//
+// Items are only cached on reads. The caches are dropped whenever the content
+// of the ancient store changes other than by appending items.
+type cachedAncientStore struct {
+	ethdb.AncientStore
+	config    AncientCacheConfig
+	namespace string
+
+	memory  *lru.SizeConstrainedCache[ancientCacheKey, []byte]
+	disk    *leveldb.Database                     // Disk tier, nil if disabled
+	recency lru.BasicLRU[ancientCacheKey, uint64] // Sizes of the disk tier items, by last access across all tables
+	size    uint64                                // Size of the items in the disk tier
+	gen     uint64                                // Cache generation, bumped when the caches are dropped
+	lock    sync.Mutex                            // Lock protecting the caches
+
+	meters     map[string]*ancientCacheMeters
+	metersLock sync.Mutex
+}
+
+// newCachedAncientStore wraps the ancient store with the configured caches.
+// The on-disk cache is cleared when opened, as the ancient store might have
+// been modified while the cache was not attached.
+func newCachedAncientStore(store ethdb.AncientStore, config AncientCacheConfig, namespace string) (*cachedAncientStore, error) {
+	c := &cachedAncientStore{
+		AncientStore: store,
+		config:       config,
+		namespace:    namespace,
+		memory:       lru.NewSizeConstrainedCache[ancientCacheKey, []byte](config.Memory),
+		recency:      lru.NewBasicLRU[ancientCacheKey, uint64](math.MaxInt),
+		meters:       make(map[string]*ancientCacheMeters),
+	}
+	if config.Directory != "" && config.Disk > 0 {
+		disk, err := leveldb.New(config.Directory, 16, 16, namespace+"ancient/cache/disk/", false)
+		if err != nil {
+			return nil, err
+		}
+		c.disk = disk
+		c.drop()
+	}
+	log.Info("Enabled ancient store cache", "items", config.Items, "memory", config.Memory, "disk", config.Disk, "directory", config.Directory)
+	return c, nil
+}
+
+// diskKey returns the key of an item in the disk tier. The table name is length
+// prefixed to keep the key spaces of the tables separate.
+func diskKey(kind string, number uint64) []byte {
+	key := make([]byte, 0, 1+len(kind)+8)
+	key = append(key, byte(len(kind)))
+	key = append(key, kind...)
+	return binary.BigEndian.AppendUint64(key, number)
+}
+
+// tableMeters returns the cache meters of the given table.
+func (c *cachedAncientStore) tableMeters(kind string) *ancientCacheMeters {
+	c.metersLock.Lock()
+	defer c.metersLock.Unlock()
+
+	m, ok := c.meters[kind]
+	if !ok {
+		prefix := c.namespace + "ancient/cache/" + kind + "/"
+		m = &ancientCacheMeters{
+			memoryHit: metrics.GetOrRegisterMeter(prefix+"hit/memory", nil),
+			diskHit:   metrics.GetOrRegisterMeter(prefix+"hit/disk", nil),
+			miss:      metrics.GetOrRegisterMeter(prefix+"miss", nil),
+		}
+		c.meters[kind] = m
+	}
+	return m
+}
+
+// cacheable reports whether the given item is among the most recent ones of
+// the ancient store.
+func (c *cachedAncientStore) cacheable(reader ethdb.AncientReaderOp, number uint64) bool {
+	frozen, err := reader.Ancients()
+	if err != nil {
+		return false
+	}
+	return number < frozen && number+c.config.Items >= frozen
+}
+
+// lookup retrieves an item from the caches, also returning the generation of
+// the caches for inserting the item after a miss.
+func (c *cachedAncientStore) lookup(kind string, number uint64) ([]byte, uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	meters := c.tableMeters(kind)
+	key := ancientCacheKey{kind, number}
+
+	// Any access counts as a use of the item in the disk tier.
+	c.recency.Get(key)
+	if blob, ok := c.memory.Get(key); ok {
+		meters.memoryHit.Mark(1)
+		return blob, c.gen
+	}
+	if c.disk != nil {
+		if blob, err := c.disk.Get(diskKey(kind, number)); err == nil {
+			meters.diskHit.Mark(1)
+			c.memory.Add(key, blob)
+			return blob, c.gen
+		}
+	}
+	meters.miss.Mark(1)
+	return nil, c.gen
+}
+
+// insert adds an item retrieved from the ancient store to the caches, unless
+// the caches were dropped since the retrieval started.
+func (c *cachedAncientStore) insert(kind string, number uint64, blob []byte, gen uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if gen != c.gen {
+		return
+	}
+	c.memory.Add(ancientCacheKey{kind, number}, blob)
+	if c.disk == nil || uint64(len(blob)) > c.config.Disk {
+		return
+	}
+	if err := c.disk.Put(diskKey(kind, number), blob); err != nil {
+		log.Warn("Failed to write ancient cache", "err", err)
+		return
+	}
+	key := ancientCacheKey{kind, number}
+	if size, ok := c.recency.Peek(key); ok {
+		c.size -= size
+	}
+	c.recency.Add(key, uint64(len(blob)))
+	c.size += uint64(len(blob))
+	if c.size > c.config.Disk {
+		c.evict()
+	}
+}
+
+// evict removes items from the disk tier until it fits its budget, starting
+// with the least recently used ones across all tables. The caller must hold
+// the lock.
+func (c *cachedAncientStore) evict() {
+	batch := c.disk.NewBatch()
+	for c.size > c.config.Disk {
+		key, size, ok := c.recency.RemoveOldest()
+		if !ok {
+			break
+		}
+		if err := batch.Delete(diskKey(key.kind, key.number)); err != nil {
+			break
+		}
+		c.size -= size
+	}
+	if err := batch.Write(); err != nil {
+		log.Warn("Failed to evict ancient cache items", "err", err)
+	}
+}
+
+// drop discards all cached items.
+func (c *cachedAncientStore) drop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.gen++
+	c.memory = lru.NewSizeConstrainedCache[ancientCacheKey, []byte](c.config.Memory)
+	c.recency.Purge()
+	if c.disk == nil {
+		return
+	}
+	it := c.disk.NewIterator(nil, nil)
+	defer it.Release()
+
+	batch := c.disk.NewBatch()
+	for it.Next() {
+		batch.Delete(it.Key())
+	}
+	if err := batch.Write(); err != nil {
+		log.Warn("Failed to drop ancient cache", "err", err)
+	}
+	c.size = 0
+}
+
+// ancient retrieves an item through the caches.
+func (c *cachedAncientStore) ancient(reader ethdb.AncientReaderOp, kind string, number uint64) ([]byte, error) {
+	if !c.cacheable(reader, number) {
+		return reader.Ancient(kind, number)
+	}
+	blob, gen := c.lookup(kind, number)
+	if blob != nil {
+		return blob, nil
+	}
+	blob, err := reader.Ancient(kind, number)
+	if err != nil {
+		return nil, err
+	}
+	c.insert(kind, number, blob, gen)
+	return blob, nil
+}
+
+// ancientRange retrieves a sequence of items through the caches. The cached
+// prefix of the range is served from the caches, the rest is retrieved from
+// the ancient store in one go.
+func (c *cachedAncientStore) ancientRange(reader ethdb.AncientReaderOp, kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	if !c.cacheable(reader, start) {
+		return reader.AncientRange(kind, start, count, maxBytes)
+	}
+	var (
+		items [][]byte
+		size  uint64
+		gen   uint64
+	)
+	for number := start; number < start+count; number++ {
+		var blob []byte
+		if blob, gen = c.lookup(kind, number); blob == nil {
+			break
+		}
+		if maxBytes != 0 && len(items) > 0 && size+uint64(len(blob)) > maxBytes {
+			return items, nil
+		}
+		items = append(items, blob)
+		size += uint64(len(blob))
+	}
+	next := start + uint64(len(items))
+	if next == start+count {
+		return items, nil
+	}
+	var limit uint64
+	if maxBytes != 0 {
+		if size >= maxBytes {
+			return items, nil
+		}
+		limit = maxBytes - size
+	}
+	blobs, err := reader.AncientRange(kind, next, start+count-next, limit)
+	if err != nil {
+		if len(items) > 0 {
+			return items, nil
+		}
+		return nil, err
+	}
+	for i, blob := range blobs {
+		// The ancient store returns at least one item, even if exceeding the
+		// limit, which only applies to the first item of the whole range.
+		if maxBytes != 0 && len(items) > 0 && size+uint64(len(blob)) > maxBytes {
+			break
+		}
+		c.insert(kind, next+uint64(i), blob, gen)
+		items = append(items, blob)
+		size += uint64(len(blob))
+	}
+	return items, nil
+}
+
+// Ancient retrieves an ancient binary blob, from the caches if possible.
+func (c *cachedAncientStore) Ancient(kind string, number uint64) ([]byte, error) {
+	return c.ancient(c.AncientStore, kind, number)
+}
+
+// AncientRange retrieves multiple items in sequence, from the caches if possible.
+func (c *cachedAncientStore) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	return c.ancientRange(c.AncientStore, kind, start, count, maxBytes)
+}
+
+// ReadAncients runs the given read operation, serving the reads from the
+// caches if possible.
+func (c *cachedAncientStore) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	return c.AncientStore.ReadAncients(func(reader ethdb.AncientReaderOp) error {
+		return fn(&cachedAncientReader{AncientReaderOp: reader, cache: c})
+	})
+}
+
+// TruncateHead discards all but the first n ancient items, dropping the caches.
+func (c *cachedAncientStore) TruncateHead(n uint64) (uint64, error) {
+	defer c.drop()
+	return c.AncientStore.TruncateHead(n)
+}
+
+// TruncateTail discards the first n ancient items, dropping the caches.
+func (c *cachedAncientStore) TruncateTail(n uint64) (uint64, error) {
+	defer c.drop()
+	return c.AncientStore.TruncateTail(n)
+}
+
+// MigrateTable converts the entries of a table, dropping the caches.
+func (c *cachedAncientStore) MigrateTable(kind string, convert convertLegacyFn) error {
+	defer c.drop()
+	return c.AncientStore.MigrateTable(kind, convert)
+}
+
+// Close closes the ancient store and the on-disk cache.
+func (c *cachedAncientStore) Close() error {
+	if c.disk != nil {
+		if err := c.disk.Close(); err != nil {
+			log.Warn("Failed to close ancient cache", "err", err)
+		}
+	}
+	return c.AncientStore.Close()
+}
+
+// cachedAncientReader serves the reads of a ReadAncients operation through
+// the caches.
+type cachedAncientReader struct {
+	ethdb.AncientReaderOp
+	cache *cachedAncientStore
+}
+
+// Ancient retrieves an ancient binary blob, from the caches if possible.
+func (r *cachedAncientReader) Ancient(kind string, number uint64) ([]byte, error) {
+	return r.cache.ancient(r.AncientReaderOp, kind, number)
+}
+
+// AncientRange retrieves multiple items in sequence, from the caches if possible.
+func (r *cachedAncientReader) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	return r.cache.ancientRange(r.AncientReaderOp, kind, start, count, maxBytes)
+}
+
+// cachedAncientDB is a database wrapper caching the recent ancient items.
+type cachedAncientDB struct {
+	ethdb.Database
+	ancients *cachedAncientStore
+}
+
+// NewDatabaseWithAncientCache wraps the database, caching the most recent items
+// of its ancient store according to the given config.
+func NewDatabaseWithAncientCache(db ethdb.Database, config AncientCacheConfig, namespace string) (ethdb.Database, error) {
+	ancients, err := newCachedAncientStore(db, config, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &cachedAncientDB{Database: db, ancients: ancients}, nil
+}
+
+// Ancient retrieves an ancient binary blob, from the caches if possible.
+func (db *cachedAncientDB) Ancient(kind string, number uint64) ([]byte, error) {
+	return db.ancients.Ancient(kind, number)
+}
+
+// AncientRange retrieves multiple items in sequence, from the caches if possible.
+func (db *cachedAncientDB) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	return db.ancients.AncientRange(kind, start, count, maxBytes)
+}
+
+// ReadAncients runs the given read operation, serving the reads from the
+// caches if possible.
+func (db *cachedAncientDB) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	return db.ancients.ReadAncients(fn)
+}
+
+// TruncateHead discards all but the first n ancient items, dropping the caches.
+func (db *cachedAncientDB) TruncateHead(n uint64) (uint64, error) {
+	return db.ancients.TruncateHead(n)
+}
+
+// TruncateTail discards the first n ancient items, dropping the caches.
+func (db *cachedAncientDB) TruncateTail(n uint64) (uint64, error) {
+	return db.ancients.TruncateTail(n)
+}
+
+// MigrateTable converts the entries of a table, dropping the caches.
+func (db *cachedAncientDB) MigrateTable(kind string, convert convertLegacyFn) error {
+	return db.ancients.MigrateTable(kind, convert)
+}
+
+// AncientCipher returns the cipher encrypting the freezer items of the database.
+func (db *cachedAncientDB) AncientCipher() *encryptdb.FileCipher {
+	return AncientCipher(db.Database)
+}
+
+// Close closes the on-disk cache and the database.
+func (db *cachedAncientDB) Close() error {
+	return db.ancients.Close()
+}
+
+// Checkpoint creates a consistent copy of the database, if it supports them.
+func (db *cachedAncientDB) Checkpoint(dir string) error {
+	cp, ok := db.Database.(ethdb.Checkpointer)
+	if !ok {
+		return fmt.Errorf("%w: checkpoints of %T", errNotSupported, db.Database)
+	}
+	return cp.Checkpoint(dir)
+}
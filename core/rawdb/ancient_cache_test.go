@@ -0,0 +1,198 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb/ancienttest"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+func TestCachedAncientStore(t *testing.T) {
+	ancienttest.TestAncientSuite(t, func(kinds []string) ethdb.AncientStore {
+		tables := make(map[string]freezerTableConfig)
+		for _, kind := range kinds {
+			tables[kind] = freezerTableConfig{noSnappy: true, prunable: true}
+		}
+		store, err := newCachedAncientStore(NewMemoryFreezer(false, tables), AncientCacheConfig{
+			Items:     50,
+			Memory:    1024,
+			Directory: t.TempDir(),
+			Disk:      4096,
+		}, "")
+		if err != nil {
+			t.Fatalf("Failed to create cache: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	})
+}
+
+func TestCachedAncientStoreTiers(t *testing.T) {
+	// Enable the metrics to track the cache hits.
+	enabled := metrics.Enabled
+	metrics.Enabled = true
+	defer func() { metrics.Enabled = enabled }()
+
+	tables := map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}}
+	store, err := newCachedAncientStore(NewMemoryFreezer(false, tables), AncientCacheConfig{
+		Items:     5,
+		Memory:    64,
+		Directory: t.TempDir(),
+		Disk:      1024,
+	}, "test/")
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer store.Close()
+
+	write := func(from, to uint64, fill byte) {
+		_, err := store.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				if err := op.AppendRaw("a", i, bytes.Repeat([]byte{fill + byte(i)}, 16)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to write items: %v", err)
+		}
+	}
+	check := func(number uint64, fill byte) {
+		t.Helper()
+		blob, err := store.Ancient("a", number)
+		if err != nil {
+			t.Fatalf("Failed to read item %d: %v", number, err)
+		}
+		if want := bytes.Repeat([]byte{fill + byte(number)}, 16); !bytes.Equal(blob, want) {
+			t.Fatalf("Item %d mismatch: have %x, want %x", number, blob, want)
+		}
+	}
+	write(0, 10, 0)
+	meters := store.tableMeters("a")
+
+	// Items outside of the recent window bypass the cache.
+	check(0, 0)
+	if n := meters.miss.Snapshot().Count(); n != 0 {
+		t.Fatalf("Old item went through the cache: %d misses", n)
+	}
+	// Reading the recent items fills both tiers, with the memory tier only
+	// holding the last four.
+	for i := uint64(5); i < 10; i++ {
+		check(i, 0)
+	}
+	for i := uint64(5); i < 10; i++ {
+		check(i, 0)
+	}
+	if n := meters.miss.Snapshot().Count(); n != 5 {
+		t.Fatalf("Miss count mismatch: have %d, want 5", n)
+	}
+	if n := meters.memoryHit.Snapshot().Count() + meters.diskHit.Snapshot().Count(); n != 5 {
+		t.Fatalf("Hit count mismatch: have %d, want 5", n)
+	}
+	if meters.diskHit.Snapshot().Count() == 0 {
+		t.Fatal("Disk tier not used")
+	}
+	// Range reads are served from the cache, up to the size limit.
+	items, err := store.AncientRange("a", 7, 3, 40)
+	if err != nil {
+		t.Fatalf("Failed to read range: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("Range length mismatch: have %d, want 2", len(items))
+	}
+	// Truncating drops the caches, rewritten items are read correctly.
+	if _, err := store.TruncateHead(7); err != nil {
+		t.Fatalf("Failed to truncate: %v", err)
+	}
+	write(7, 10, 0x80)
+	for i := uint64(7); i < 10; i++ {
+		check(i, 0x80)
+	}
+	check(6, 0)
+}
+
+// Tests that the disk tier evicts the least recently used items across all
+// tables, not the ones of the table being inserted into.
+func TestCachedAncientStoreEviction(t *testing.T) {
+	tables := map[string]freezerTableConfig{
+		"a": {noSnappy: true, prunable: true},
+		"b": {noSnappy: true, prunable: true},
+	}
+	store, err := newCachedAncientStore(NewMemoryFreezer(false, tables), AncientCacheConfig{
+		Items:     10,
+		Memory:    64,
+		Directory: t.TempDir(),
+		Disk:      6 * 16,
+	}, "")
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer store.Close()
+
+	_, err = store.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			if err := op.AppendRaw("a", i, bytes.Repeat([]byte{byte(i)}, 16)); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, bytes.Repeat([]byte{0x80 + byte(i)}, 16)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to write items: %v", err)
+	}
+	read := func(kind string, number uint64) {
+		t.Helper()
+		if _, err := store.Ancient(kind, number); err != nil {
+			t.Fatalf("Failed to read item %s/%d: %v", kind, number, err)
+		}
+	}
+	// Fill the disk tier with items of both tables, then use the first item of
+	// table a again.
+	for i := uint64(0); i < 4; i++ {
+		read("a", i)
+	}
+	read("b", 0)
+	read("b", 1)
+	read("a", 0)
+
+	// Caching another item of table b evicts the least recently used item,
+	// which belongs to table a.
+	read("b", 2)
+	for _, test := range []struct {
+		kind   string
+		number uint64
+		cached bool
+	}{
+		{"a", 0, true}, {"a", 1, false}, {"a", 2, true}, {"a", 3, true},
+		{"b", 0, true}, {"b", 1, true}, {"b", 2, true},
+	} {
+		if has, _ := store.disk.Has(diskKey(test.kind, test.number)); has != test.cached {
+			t.Errorf("Item %s/%d: disk tier presence mismatch: have %v, want %v", test.kind, test.number, has, test.cached)
+		}
+	}
+	if store.size != 6*16 {
+		t.Errorf("Disk tier size mismatch: have %d, want %d", store.size, 6*16)
+	}
+}
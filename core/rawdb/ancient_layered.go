@@ -0,0 +1,238 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// layeredAncientReader serves the ancient items below the offset from the base
+// ancient store, and the ones above it from the tip ancient store. The items of
+// the tip are numbered starting from zero at the offset.
+type layeredAncientReader struct {
+	base   ethdb.AncientReaderOp
+	tip    ethdb.AncientReaderOp
+	offset uint64
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists.
+func (r *layeredAncientReader) HasAncient(kind string, number uint64) (bool, error) {
+	if number < r.offset {
+		return r.base.HasAncient(kind, number)
+	}
+	return r.tip.HasAncient(kind, number-r.offset)
+}
+
+// Ancient retrieves an ancient binary blob.
+func (r *layeredAncientReader) Ancient(kind string, number uint64) ([]byte, error) {
+	if number < r.offset {
+		return r.base.Ancient(kind, number)
+	}
+	return r.tip.Ancient(kind, number-r.offset)
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index
+// 'start', continuing in the tip store if the range crosses the offset.
+func (r *layeredAncientReader) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	if start >= r.offset {
+		return r.tip.AncientRange(kind, start-r.offset, count, maxBytes)
+	}
+	n := min(count, r.offset-start)
+	items, err := r.base.AncientRange(kind, start, n, maxBytes)
+	if err != nil || uint64(len(items)) < n || n == count {
+		return items, err
+	}
+	var size uint64
+	for _, item := range items {
+		size += uint64(len(item))
+	}
+	if maxBytes != 0 && size >= maxBytes {
+		return items, nil
+	}
+	avail, err := r.tip.Ancients()
+	if err != nil || avail == 0 {
+		return items, err
+	}
+	more, err := r.tip.AncientRange(kind, 0, min(count-n, avail), 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range more {
+		if maxBytes != 0 && size+uint64(len(item)) > maxBytes {
+			break
+		}
+		items = append(items, item)
+		size += uint64(len(item))
+	}
+	return items, nil
+}
+
+// Ancients returns the number of items in both stores.
+func (r *layeredAncientReader) Ancients() (uint64, error) {
+	n, err := r.tip.Ancients()
+	if err != nil {
+		return 0, err
+	}
+	return r.offset + n, nil
+}
+
+// Tail returns the number of the first item stored in the base store.
+func (r *layeredAncientReader) Tail() (uint64, error) {
+	return r.base.Tail()
+}
+
+// AncientSize returns the size of the specified table in both stores.
+func (r *layeredAncientReader) AncientSize(kind string) (uint64, error) {
+	base, err := r.base.AncientSize(kind)
+	if err != nil {
+		return 0, err
+	}
+	tip, err := r.tip.AncientSize(kind)
+	if err != nil {
+		return 0, err
+	}
+	return base + tip, nil
+}
+
+// layeredAncientStore is an ancient store extending an external, read-only one,
+// e.g. an archive of era1 files, with a regular ancient store holding the chain
+// segments frozen after the ones of the external store. Only the items in the
+// tip store can be modified.
+type layeredAncientStore struct {
+	layeredAncientReader
+	store ethdb.AncientStore
+}
+
+// newLayeredAncientStore creates an ancient store continuing the base store with
+// the given one. The items of the latter are checked to follow the ones of the
+// base store.
+func newLayeredAncientStore(base ethdb.AncientReader, store ethdb.AncientStore) (*layeredAncientStore, error) {
+	offset, err := base.Ancients()
+	if err != nil {
+		return nil, err
+	}
+	if items, _ := store.Ancients(); items > 0 {
+		blob, err := store.Ancient(ChainFreezerHeaderTable, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve first frozen header: %v", err)
+		}
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(blob, header); err != nil {
+			return nil, fmt.Errorf("invalid first frozen header: %v", err)
+		}
+		if header.Number.Uint64() != offset {
+			return nil, fmt.Errorf("ancient store starting at block #%d doesn't follow %d external ancient blocks", header.Number, offset)
+		}
+	}
+	return &layeredAncientStore{
+		layeredAncientReader: layeredAncientReader{base: base, tip: store, offset: offset},
+		store:                store,
+	}, nil
+}
+
+// ReadAncients runs the given read operation while ensuring that no writes take
+// place on the tip store. The base store is immutable.
+func (s *layeredAncientStore) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	return s.store.ReadAncients(func(op ethdb.AncientReaderOp) error {
+		return fn(&layeredAncientReader{base: s.base, tip: op, offset: s.offset})
+	})
+}
+
+// ModifyAncients runs a write operation on the tip store.
+func (s *layeredAncientStore) ModifyAncients(fn func(ethdb.AncientWriteOp) error) (int64, error) {
+	return s.store.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		return fn(&layeredAncientWriteOp{op: op, offset: s.offset})
+	})
+}
+
+// TruncateHead discards all but the first n ancient items. The items of the
+// base store can't be discarded.
+func (s *layeredAncientStore) TruncateHead(n uint64) (uint64, error) {
+	if n < s.offset {
+		return 0, fmt.Errorf("%w: can't truncate external ancient store to %d items", errReadOnly, n)
+	}
+	old, err := s.store.TruncateHead(n - s.offset)
+	if err != nil {
+		return 0, err
+	}
+	return s.offset + old, nil
+}
+
+// TruncateTail returns an error, as the base store is read-only.
+func (s *layeredAncientStore) TruncateTail(n uint64) (uint64, error) {
+	tail, err := s.base.Tail()
+	if err != nil {
+		return 0, err
+	}
+	if n <= tail {
+		return tail, nil
+	}
+	return 0, errReadOnly
+}
+
+// Sync flushes the tip store to disk.
+func (s *layeredAncientStore) Sync() error {
+	return s.store.Sync()
+}
+
+// MigrateTable migrates the items of the given table in the tip store.
+func (s *layeredAncientStore) MigrateTable(kind string, convert convertLegacyFn) error {
+	return s.store.MigrateTable(kind, convert)
+}
+
+// Close closes both stores, the base one if it needs closing.
+func (s *layeredAncientStore) Close() error {
+	var errs []error
+	if closer, ok := s.base.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := s.store.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// layeredAncientWriteOp appends items to the tip store, numbering them from the
+// offset.
+type layeredAncientWriteOp struct {
+	op     ethdb.AncientWriteOp
+	offset uint64
+}
+
+// Append adds an RLP-encoded item.
+func (w *layeredAncientWriteOp) Append(kind string, number uint64, item interface{}) error {
+	if number < w.offset {
+		return errReadOnly
+	}
+	return w.op.Append(kind, number-w.offset, item)
+}
+
+// AppendRaw adds an item without RLP-encoding it.
+func (w *layeredAncientWriteOp) AppendRaw(kind string, number uint64, item []byte) error {
+	if number < w.offset {
+		return errReadOnly
+	}
+	return w.op.AppendRaw(kind, number-w.offset, item)
+}
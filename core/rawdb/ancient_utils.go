@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
 )
 
 type tableSize struct {
@@ -51,7 +52,7 @@ func (info *freezerInfo) size() common.StorageSize {
 	return total
 }
 
-func inspect(name string, order map[string]bool, reader ethdb.AncientReader) (freezerInfo, error) {
+func inspect(name string, order map[string]freezerTableConfig, reader ethdb.AncientReader) (freezerInfo, error) {
 	info := freezerInfo{name: name}
 	for t := range order {
 		size, err := reader.AncientSize(t)
@@ -82,7 +83,7 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 	for _, freezer := range freezers {
 		switch freezer {
 		case ChainFreezerName:
-			info, err := inspect(ChainFreezerName, chainFreezerNoSnappy, db)
+			info, err := inspect(ChainFreezerName, chainFreezerTableConfigs, db)
 			if err != nil {
 				return nil, err
 			}
@@ -91,15 +92,15 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 		case StateFreezerName:
 			datadir, err := db.AncientDatadir()
 			if err != nil {
-				return nil, err
+				continue // the ancient store might not be local, e.g. a remote database
 			}
-			f, err := NewStateFreezer(datadir, true)
+			f, err := NewStateFreezer(datadir, AncientCipher(db), true)
 			if err != nil {
 				continue // might be possible the state freezer is not existent
 			}
 			defer f.Close()
 
-			info, err := inspect(freezer, stateFreezerNoSnappy, f)
+			info, err := inspect(freezer, stateFreezerTableConfigs, f)
 			if err != nil {
 				return nil, err
 			}
@@ -119,17 +120,17 @@ func inspectFreezers(db ethdb.Database) ([]freezerInfo, error) {
 func InspectFreezerTable(ancient string, freezerName string, tableName string, start, end int64) error {
 	var (
 		path   string
-		tables map[string]bool
+		tables map[string]freezerTableConfig
 	)
 	switch freezerName {
 	case ChainFreezerName:
-		path, tables = resolveChainFreezerDir(ancient), chainFreezerNoSnappy
+		path, tables = resolveChainFreezerDir(ancient), chainFreezerTableConfigs
 	case StateFreezerName:
-		path, tables = filepath.Join(ancient, freezerName), stateFreezerNoSnappy
+		path, tables = filepath.Join(ancient, freezerName), stateFreezerTableConfigs
 	default:
 		return fmt.Errorf("unknown freezer, supported ones: %v", freezers)
 	}
-	noSnappy, exist := tables[tableName]
+	config, exist := tables[tableName]
 	if !exist {
 		var names []string
 		for name := range tables {
@@ -137,10 +138,76 @@ func InspectFreezerTable(ancient string, freezerName string, tableName string, s
 		}
 		return fmt.Errorf("unknown table, supported ones: %v", names)
 	}
-	table, err := newFreezerTable(path, tableName, noSnappy, true)
+	table, err := newFreezerTable(path, tableName, config.noSnappy, true)
 	if err != nil {
 		return err
 	}
 	table.dumpIndexStdout(start, end)
 	return nil
 }
+
+// MigrateFreezerTable rewrites a freezer table using the given codec, which is
+// either zstd or the codec the table is configured with. The passed ancient
+// indicates the path of root ancient directory where the freezers can be found.
+// The sizes of the table before and after the migration are returned.
+//
+// The freezer is locked for the duration of the migration, so it can't be run
+// while the node is using the database. An interrupted migration is resumed on
+// the next invocation.
+//
+// The items of an encrypted freezer are decrypted and encrypted again with the
+// given cipher, which must be the one of the database. Migrating an encrypted
+// table without it corrupts the table.
+func MigrateFreezerTable(ancient string, freezerName string, tableName string, codecName string, cipher *encryptdb.FileCipher) (uint64, uint64, error) {
+	var (
+		path   string
+		tables map[string]freezerTableConfig
+	)
+	switch freezerName {
+	case ChainFreezerName:
+		path, tables = resolveChainFreezerDir(ancient), chainFreezerTableConfigs
+	case StateFreezerName:
+		path, tables = filepath.Join(ancient, freezerName), stateFreezerTableConfigs
+	default:
+		return 0, 0, fmt.Errorf("unknown freezer, supported ones: %v", freezers)
+	}
+	config, exist := tables[tableName]
+	if !exist {
+		var names []string
+		for name := range tables {
+			names = append(names, name)
+		}
+		return 0, 0, fmt.Errorf("unknown table, supported ones: %v", names)
+	}
+	codec, err := parseFreezerCodec(codecName)
+	if err != nil {
+		return 0, 0, err
+	}
+	// Tables can't be opened with any other codec than the configured one,
+	// unless they were migrated to zstd.
+	def := codecSnappy
+	if config.noSnappy {
+		def = codecNone
+	}
+	if codec != codecZstd && codec != def {
+		return 0, 0, fmt.Errorf("table %s can only be stored with zstd or %v", tableName, def)
+	}
+	f, err := newFreezer(path, "", false, freezerTableSize, tables, cipher)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	before, err := f.AncientSize(tableName)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := f.migrateTableCodec(tableName, codec); err != nil {
+		return 0, 0, err
+	}
+	after, err := f.AncientSize(tableName)
+	if err != nil {
+		return 0, 0, err
+	}
+	return before, after, nil
+}
@@ -17,6 +17,7 @@
 package rawdb
 
 import (
+	"fmt"
 	"runtime"
 	"sync/atomic"
 	"time"
@@ -83,6 +84,32 @@ func InitDatabaseFromFreezer(db ethdb.Database) {
 	log.Info("Initialized database from freezer", "blocks", frozen, "elapsed", common.PrettyDuration(time.Since(start)))
 }
 
+// PruneHistory deletes the bodies and receipts of all the frozen blocks below
+// the given cutoff. The headers and transaction lookup entries are retained, so
+// the chain can still be verified and lookups can report the pruned history.
+func PruneHistory(db ethdb.Database, cutoff uint64) error {
+	frozen, err := db.Ancients()
+	if err != nil {
+		return err
+	}
+	if cutoff > frozen {
+		return fmt.Errorf("history cutoff %d above frozen blocks %d", cutoff, frozen)
+	}
+	if prev := ReadHistoryCutoff(db); cutoff <= prev {
+		return fmt.Errorf("history already pruned up to block %d", prev)
+	}
+	start := time.Now()
+	if _, err := db.TruncateTail(cutoff); err != nil {
+		return err
+	}
+	if err := db.Sync(); err != nil {
+		return err
+	}
+	WriteHistoryCutoff(db, cutoff)
+	log.Info("Pruned chain history", "cutoff", cutoff, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
 type blockTxHashes struct {
 	number uint64
 	hashes []common.Hash
@@ -108,6 +135,7 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 	var (
 		rlpCh    = make(chan *numberRlp, threads*2)     // we send raw rlp over this channel
 		hashesCh = make(chan *blockTxHashes, threads*2) // send hashes over hashesCh
+		cutoff   = ReadHistoryCutoff(db)                // blocks below have no bodies
 	)
 	// lookup runs in one instance
 	lookup := func() {
@@ -142,14 +170,18 @@ func iterateTransactions(db ethdb.Database, from uint64, to uint64, reverse bool
 			}
 		}()
 		for data := range rlpCh {
-			var body types.Body
-			if err := rlp.DecodeBytes(data.rlp, &body); err != nil {
-				log.Warn("Failed to decode block body", "block", data.number, "error", err)
-				return
-			}
 			var hashes []common.Hash
-			for _, tx := range body.Transactions {
-				hashes = append(hashes, tx.Hash())
+			// Bodies below the history cutoff are pruned, deliver them without
+			// any transactions so the (un)indexing can progress past them.
+			if len(data.rlp) != 0 || data.number >= cutoff {
+				var body types.Body
+				if err := rlp.DecodeBytes(data.rlp, &body); err != nil {
+					log.Warn("Failed to decode block body", "block", data.number, "error", err)
+					return
+				}
+				for _, tx := range body.Transactions {
+					hashes = append(hashes, tx.Hash())
+				}
 			}
 			result := &blockTxHashes{
 				hashes: hashes,
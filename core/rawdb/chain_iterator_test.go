@@ -206,3 +206,67 @@ func TestIndexTransactions(t *testing.T) {
 	verify(8, 11, true, 8)
 	verify(0, 8, false, 8)
 }
+
+func TestPruneHistory(t *testing.T) {
+	db, err := NewDatabaseWithFreezer(NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var (
+		blocks   []*types.Block
+		receipts []types.Receipts
+		to       = common.BytesToAddress([]byte{0x11})
+	)
+	for i := uint64(0); i < 10; i++ {
+		tx := types.NewTx(&types.LegacyTx{Nonce: i, GasPrice: big.NewInt(1), Gas: 21000, To: &to})
+		block := types.NewBlock(&types.Header{Number: new(big.Int).SetUint64(i)}, &types.Body{Transactions: types.Transactions{tx}}, nil, newTestHasher())
+		blocks = append(blocks, block)
+		receipts = append(receipts, types.Receipts{{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}}})
+	}
+	if _, err := WriteAncientBlocks(db, blocks, receipts, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	for _, block := range blocks {
+		WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		WriteHeaderNumber(db, block.Hash(), block.NumberU64())
+	}
+	WriteTxLookupEntriesByBlock(db, blocks[3])
+
+	if err := PruneHistory(db, 11); err == nil {
+		t.Fatal("pruned above the frozen blocks")
+	}
+	if err := PruneHistory(db, 5); err != nil {
+		t.Fatal(err)
+	}
+	if cutoff := ReadHistoryCutoff(db); cutoff != 5 {
+		t.Fatalf("wrong history cutoff: have %d, want 5", cutoff)
+	}
+	if err := PruneHistory(db, 5); err == nil {
+		t.Fatal("pruned the same history twice")
+	}
+	for _, block := range blocks {
+		number, hash := block.NumberU64(), block.Hash()
+		if ReadHeader(db, hash, number) == nil {
+			t.Fatalf("header %d missing", number)
+		}
+		body, rs := ReadBody(db, hash, number), ReadRawReceipts(db, hash, number)
+		if number < 5 && (body != nil || rs != nil) {
+			t.Fatalf("block %d not pruned", number)
+		}
+		if number >= 5 && (body == nil || rs == nil) {
+			t.Fatalf("block %d pruned", number)
+		}
+	}
+	if ReadTxLookupEntry(db, blocks[3].Transactions()[0].Hash()) == nil {
+		t.Fatal("transaction lookup entry of pruned block missing")
+	}
+	// Pruned blocks should not stall the transaction indexer.
+	IndexTransactions(db, 0, 10, nil, false)
+	for _, block := range blocks[5:] {
+		if ReadTxLookupEntry(db, block.Transactions()[0].Hash()) == nil {
+			t.Fatalf("transaction of block %d not indexed", block.NumberU64())
+		}
+	}
+}
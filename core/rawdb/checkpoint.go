@@ -0,0 +1,199 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// CheckpointAncientDir is the directory of the ancient store within a database
+// checkpoint.
+const CheckpointAncientDir = "ancient"
+
+// checkpointKeyValueStore creates a checkpoint of the key-value store, if it
+// supports them.
+func checkpointKeyValueStore(db ethdb.KeyValueStore, dir string) error {
+	cp, ok := db.(ethdb.Checkpointer)
+	if !ok {
+		return fmt.Errorf("%w: checkpoints of %T", errNotSupported, db)
+	}
+	return cp.Checkpoint(dir)
+}
+
+// Checkpoint creates a consistent copy of the database in the given directory,
+// with the ancient store placed in its ancient subdirectory. Writes to the
+// chain freezer are paused for the duration of the checkpoint.
+//
+// The state freezer is copied along, but it is not paused, the caller must make
+// sure the state isn't modified while the checkpoint is taken.
+func (frdb *freezerdb) Checkpoint(dir string) error {
+	if frdb.ancientRoot == "" {
+		return errors.New("in-memory ancient store can't be checkpointed")
+	}
+	return frdb.chainFreezer.ReadAncients(func(ethdb.AncientReaderOp) error {
+		if err := frdb.chainFreezer.Sync(); err != nil {
+			return err
+		}
+		if err := checkpointKeyValueStore(frdb.KeyValueStore, dir); err != nil {
+			return err
+		}
+		return checkpointFreezerDir(frdb.ancientRoot, filepath.Join(dir, CheckpointAncientDir))
+	})
+}
+
+// Checkpoint creates a consistent copy of the key-value store in the given
+// directory.
+func (db *nofreezedb) Checkpoint(dir string) error {
+	return checkpointKeyValueStore(db.KeyValueStore, dir)
+}
+
+// checkpointFreezerDir copies the freezer files from the src directory and its
+// subdirectories into dst. The data files are immutable once the next one is
+// started, so all but the head files are hard-linked if possible. The index,
+// metadata and head files are copied, as they are still appended to.
+func checkpointFreezerDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	// Find the head data file of every table.
+	heads := make(map[string]uint64)
+	for _, entry := range entries {
+		if table, num, ok := parseDataFile(entry.Name()); ok && num >= heads[table] {
+			heads[table] = num
+		}
+	}
+	for _, entry := range entries {
+		var (
+			name = entry.Name()
+			from = filepath.Join(src, name)
+			to   = filepath.Join(dst, name)
+		)
+		switch {
+		case entry.IsDir():
+			// Skip the leftovers of interrupted table migrations.
+			if name == "migration" {
+				continue
+			}
+			if err := checkpointFreezerDir(from, to); err != nil {
+				return err
+			}
+		case name == "FLOCK":
+			continue
+		default:
+			if table, num, ok := parseDataFile(name); ok && num < heads[table] {
+				if err := os.Link(from, to); err == nil {
+					continue
+				}
+			}
+			if err := copyFile(from, to); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseDataFile splits the name of a freezer data file into the table name,
+// including the codec suffix, and the file number.
+func parseDataFile(name string) (string, uint64, bool) {
+	parts := strings.Split(name, ".")
+	if len(parts) != 3 || !strings.HasSuffix(parts[2], "dat") {
+		return "", 0, false
+	}
+	num, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	return parts[0] + "." + parts[2], num, true
+}
+
+// copyFile copies the content of the file src into the new file dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// RestoreCheckpoint copies the database checkpoint in src into the key-value
+// store directory dir and the ancient directory ancient. Neither of them may
+// exist yet, to avoid mixing the checkpoint with an existing database.
+func RestoreCheckpoint(src, dir, ancient string) error {
+	for _, path := range []string{dir, ancient} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			return fmt.Errorf("database directory %s already exists", path)
+		}
+	}
+	if err := copyDir(src, dir, CheckpointAncientDir); err != nil {
+		return err
+	}
+	return copyDir(filepath.Join(src, CheckpointAncientDir), ancient, "")
+}
+
+// copyDir recursively copies the content of the directory src into dst, except
+// for the top-level entry named skip.
+func copyDir(src, dst string, skip string) error {
+	entries, err := os.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == skip {
+			continue
+		}
+		from, to := filepath.Join(src, entry.Name()), filepath.Join(dst, entry.Name())
+		if entry.IsDir() {
+			err = copyDir(from, to, "")
+		} else {
+			err = copyFile(from, to)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
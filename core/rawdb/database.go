@@ -27,6 +27,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
 	"github.com/ethereum/go-ethereum/ethdb/leveldb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/ethdb/pebble"
@@ -41,6 +42,29 @@ type freezerdb struct {
 
 	readOnly    bool
 	ancientRoot string
+	cipher      *encryptdb.FileCipher // Cipher of the freezer items, nil if not encrypted
+}
+
+// ancientCipherer is implemented by the databases whose freezer items are
+// encrypted, as well as the wrappers around them.
+type ancientCipherer interface {
+	AncientCipher() *encryptdb.FileCipher
+}
+
+// AncientCipher returns the cipher encrypting the freezer items of the database,
+// or nil if they aren't encrypted. Freezers opened separately from the database,
+// like the state history freezer, must be encrypted with it as well.
+func AncientCipher(db ethdb.Database) *encryptdb.FileCipher {
+	if c, ok := db.(ancientCipherer); ok {
+		return c.AncientCipher()
+	}
+	return nil
+}
+
+// AncientCipher returns the cipher encrypting the freezer items, nil if they
+// aren't encrypted.
+func (frdb *freezerdb) AncientCipher() *encryptdb.FileCipher {
+	return frdb.cipher
 }
 
 // AncientDatadir returns the path of root ancient directory.
@@ -166,6 +190,79 @@ func NewDatabase(db ethdb.KeyValueStore) ethdb.Database {
 	return &nofreezedb{KeyValueStore: db}
 }
 
+// ancientHashIndex is implemented by ancient stores able to resolve block hashes
+// to numbers themselves, so the key-value store doesn't need the mappings.
+type ancientHashIndex interface {
+	HeaderNumber(hash common.Hash) (uint64, bool)
+}
+
+// ancientdb is a database wrapper serving the ancient chain segment from an
+// external, read-only ancient store, followed by the chain segments frozen into
+// a regular freezer afterwards.
+type ancientdb struct {
+	*freezerdb
+
+	index ancientHashIndex // Optional hash index of the external ancient store
+}
+
+// Get retrieves the given key if it's present in the key-value store, falling
+// back to the hash index of the ancient store for block number lookups.
+func (db *ancientdb) Get(key []byte) ([]byte, error) {
+	blob, err := db.KeyValueStore.Get(key)
+	if err != nil {
+		if number, ok := db.lookupNumber(key); ok {
+			return encodeBlockNumber(number), nil
+		}
+	}
+	return blob, err
+}
+
+// Has retrieves if a key is present in the key-value store, falling back to
+// the hash index of the ancient store for block number lookups.
+func (db *ancientdb) Has(key []byte) (bool, error) {
+	has, err := db.KeyValueStore.Has(key)
+	if err == nil && !has {
+		_, has = db.lookupNumber(key)
+	}
+	return has, err
+}
+
+// lookupNumber resolves a header number key through the ancient hash index.
+func (db *ancientdb) lookupNumber(key []byte) (uint64, bool) {
+	if db.index == nil || len(key) != len(headerNumberPrefix)+common.HashLength || !bytes.HasPrefix(key, headerNumberPrefix) {
+		return 0, false
+	}
+	return db.index.HeaderNumber(common.BytesToHash(key[len(headerNumberPrefix):]))
+}
+
+// Checkpoint returns an error, as the external ancient store can't be copied
+// along with the database.
+func (db *ancientdb) Checkpoint(dir string) error {
+	return fmt.Errorf("%w: checkpoints of databases with an external ancient store", errNotSupported)
+}
+
+// NewDatabaseWithAncients creates a high level database on top of a given key-
+// value data store, serving the ancient chain segment from an external read-only
+// ancient store, such as an archive of era1 files. The chain segments following
+// it are moved into a freezer in the given ancient directory, or an in-memory
+// one if the directory is empty. The external ancient store is closed together
+// with the database.
+func NewDatabaseWithAncients(db ethdb.KeyValueStore, ancients ethdb.AncientReader, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	return newDatabaseWithAncients(db, ancients, ancient, namespace, readonly, nil)
+}
+
+// newDatabaseWithAncients creates a high level database serving the ancient chain
+// segment from an external ancient store, with the items of the freezer holding
+// the following chain segments encrypted with the given cipher if it's non-nil.
+func newDatabaseWithAncients(db ethdb.KeyValueStore, ancients ethdb.AncientReader, ancient string, namespace string, readonly bool, cipher *encryptdb.FileCipher) (ethdb.Database, error) {
+	frdb, err := newDatabaseWithFreezer(db, ancients, ancient, namespace, readonly, cipher)
+	if err != nil {
+		return nil, err
+	}
+	index, _ := ancients.(ancientHashIndex)
+	return &ancientdb{freezerdb: frdb, index: index}, nil
+}
+
 // resolveChainFreezerDir is a helper function which resolves the absolute path
 // of chain freezer by considering backward compatibility.
 func resolveChainFreezerDir(ancient string) string {
@@ -194,6 +291,17 @@ func resolveChainFreezerDir(ancient string) string {
 // storage. The passed ancient indicates the path of root ancient directory
 // where the chain freezer can be opened.
 func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace string, readonly bool) (ethdb.Database, error) {
+	frdb, err := newDatabaseWithFreezer(db, nil, ancient, namespace, readonly, nil)
+	if err != nil {
+		return nil, err
+	}
+	return frdb, nil
+}
+
+// newDatabaseWithFreezer creates a high level database with a freezer, whose items
+// are encrypted with the given cipher if it's non-nil. If a base ancient store is
+// given, the freezer holds the chain segments frozen after the ones in it.
+func newDatabaseWithFreezer(db ethdb.KeyValueStore, base ethdb.AncientReader, ancient string, namespace string, readonly bool, cipher *encryptdb.FileCipher) (*freezerdb, error) {
 	// Create the idle freezer instance. If the given ancient directory is empty,
 	// in-memory chain freezer is used (e.g. dev mode); otherwise the regular
 	// file-based freezer is created.
@@ -201,11 +309,19 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace st
 	if chainFreezerDir != "" {
 		chainFreezerDir = resolveChainFreezerDir(chainFreezerDir)
 	}
-	frdb, err := newChainFreezer(chainFreezerDir, namespace, readonly)
+	frdb, err := newChainFreezer(chainFreezerDir, namespace, readonly, cipher)
 	if err != nil {
 		printChainMetadata(db)
 		return nil, err
 	}
+	if base != nil {
+		store, err := newLayeredAncientStore(base, frdb.AncientStore)
+		if err != nil {
+			frdb.Close()
+			return nil, err
+		}
+		frdb.AncientStore = store
+	}
 	// Since the freezer can be stored separately from the user's key-value database,
 	// there's a fairly high probability that the user requests invalid combinations
 	// of the freezer and database. Ensure that we don't shoot ourselves in the foot
@@ -296,6 +412,7 @@ func NewDatabaseWithFreezer(db ethdb.KeyValueStore, ancient string, namespace st
 		ancientRoot:   ancient,
 		KeyValueStore: db,
 		chainFreezer:  frdb,
+		cipher:        cipher,
 	}, nil
 }
 
@@ -357,16 +474,30 @@ func PreexistingDatabase(path string) string {
 // OpenOptions contains the options to apply when opening a database.
 // OBS: If AncientsDirectory is empty, it indicates that no freezer is to be used.
 type OpenOptions struct {
-	Type              string // "leveldb" | "pebble"
-	Directory         string // the datadir
-	AncientsDirectory string // the ancients-dir
-	Namespace         string // the namespace for database relevant metrics
-	Cache             int    // the capacity(in megabytes) of the data caching
-	Handles           int    // number of files to be open simultaneously
+	Type              string              // "leveldb" | "pebble"
+	Directory         string              // the datadir
+	AncientsDirectory string              // the ancients-dir
+	Ancients          ethdb.AncientReader // external read-only ancient store, continued by the freezer in the ancients-dir
+	Namespace         string              // the namespace for database relevant metrics
+	Cache             int                 // the capacity(in megabytes) of the data caching
+	Handles           int                 // number of files to be open simultaneously
 	ReadOnly          bool
 	// Ephemeral means that filesystem sync operations should be avoided: data integrity in the face of
 	// a crash is not important. This option should typically be used in tests.
 	Ephemeral bool
+	// Encryption is the key to encrypt the key-value store and the freezers with, nil if the
+	// database isn't encrypted. The state history freezer of the path scheme is opened
+	// separately, using the cipher returned by AncientCipher. EncryptKeys enables the
+	// encryption of the keys besides the values.
+	Encryption  *encryptdb.Key
+	EncryptKeys bool
+	// Stats enables the incremental maintenance of the key-value store statistics. Opening
+	// the database for writing without it marks any previously maintained ones outdated.
+	//
+	// To account overwrites and deletions exactly, every written key is read back from the
+	// store before the write, once per key in a batch. This is significant for write heavy
+	// workloads such as sync and block import.
+	Stats bool
 }
 
 // openKeyValueDatabase opens a disk-based key-value database, e.g. leveldb or pebble.
@@ -409,10 +540,43 @@ func Open(o OpenOptions) (ethdb.Database, error) {
 	if err != nil {
 		return nil, err
 	}
+	var cipher *encryptdb.FileCipher
+	if o.Encryption != nil {
+		edb, err := encryptdb.New(kvdb, o.Encryption, o.EncryptKeys)
+		if err != nil {
+			kvdb.Close()
+			return nil, err
+		}
+		kvdb, cipher = NewDatabase(edb), o.Encryption.FileCipher()
+	} else if encryptdb.IsEncrypted(kvdb) {
+		kvdb.Close()
+		return nil, errors.New("database is encrypted, but no encryption key was given")
+	}
+	if !o.ReadOnly {
+		if o.Stats {
+			sdb, err := newStatsStore(kvdb, o.Namespace)
+			if err != nil {
+				kvdb.Close()
+				return nil, err
+			}
+			kvdb = NewDatabase(sdb)
+		} else if err := markDatabaseStatsDirty(kvdb); err != nil {
+			kvdb.Close()
+			return nil, err
+		}
+	}
+	if o.Ancients != nil {
+		db, err := newDatabaseWithAncients(kvdb, o.Ancients, o.AncientsDirectory, o.Namespace, o.ReadOnly, cipher)
+		if err != nil {
+			kvdb.Close()
+			return nil, err
+		}
+		return db, nil
+	}
 	if len(o.AncientsDirectory) == 0 {
 		return kvdb, nil
 	}
-	frdb, err := NewDatabaseWithFreezer(kvdb, o.AncientsDirectory, o.Namespace, o.ReadOnly)
+	frdb, err := newDatabaseWithFreezer(kvdb, nil, o.AncientsDirectory, o.Namespace, o.ReadOnly, cipher)
 	if err != nil {
 		kvdb.Close()
 		return nil, err
@@ -461,33 +625,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		start  = time.Now()
 		logged = time.Now()
 
-		// Key-value store statistics
-		headers         stat
-		bodies          stat
-		receipts        stat
-		tds             stat
-		numHashPairings stat
-		hashNumPairings stat
-		legacyTries     stat
-		stateLookups    stat
-		accountTries    stat
-		storageTries    stat
-		codes           stat
-		txLookups       stat
-		accountSnaps    stat
-		storageSnaps    stat
-		preimages       stat
-		bloomBits       stat
-		beaconHeaders   stat
-		cliqueSnaps     stat
-
-		// Les statistic
-		chtTrieNodes   stat
-		bloomTrieNodes stat
-
-		// Meta- and unaccounted data
-		metadata    stat
-		unaccounted stat
+		// Key-value store statistics by category
+		categories = make(map[string]*stat)
 
 		// Totals
 		total common.StorageSize
@@ -499,76 +638,13 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			size = common.StorageSize(len(key) + len(it.Value()))
 		)
 		total += size
-		switch {
-		case bytes.HasPrefix(key, headerPrefix) && len(key) == (len(headerPrefix)+8+common.HashLength):
-			headers.Add(size)
-		case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == (len(blockBodyPrefix)+8+common.HashLength):
-			bodies.Add(size)
-		case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
-			receipts.Add(size)
-		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
-			tds.Add(size)
-		case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
-			numHashPairings.Add(size)
-		case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == (len(headerNumberPrefix)+common.HashLength):
-			hashNumPairings.Add(size)
-		case IsLegacyTrieNode(key, it.Value()):
-			legacyTries.Add(size)
-		case bytes.HasPrefix(key, stateIDPrefix) && len(key) == len(stateIDPrefix)+common.HashLength:
-			stateLookups.Add(size)
-		case IsAccountTrieNode(key):
-			accountTries.Add(size)
-		case IsStorageTrieNode(key):
-			storageTries.Add(size)
-		case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
-			codes.Add(size)
-		case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
-			txLookups.Add(size)
-		case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
-			accountSnaps.Add(size)
-		case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
-			storageSnaps.Add(size)
-		case bytes.HasPrefix(key, PreimagePrefix) && len(key) == (len(PreimagePrefix)+common.HashLength):
-			preimages.Add(size)
-		case bytes.HasPrefix(key, configPrefix) && len(key) == (len(configPrefix)+common.HashLength):
-			metadata.Add(size)
-		case bytes.HasPrefix(key, genesisPrefix) && len(key) == (len(genesisPrefix)+common.HashLength):
-			metadata.Add(size)
-		case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
-			bloomBits.Add(size)
-		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
-			bloomBits.Add(size)
-		case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
-			beaconHeaders.Add(size)
-		case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
-			cliqueSnaps.Add(size)
-		case bytes.HasPrefix(key, ChtTablePrefix) ||
-			bytes.HasPrefix(key, ChtIndexTablePrefix) ||
-			bytes.HasPrefix(key, ChtPrefix): // Canonical hash trie
-			chtTrieNodes.Add(size)
-		case bytes.HasPrefix(key, BloomTrieTablePrefix) ||
-			bytes.HasPrefix(key, BloomTrieIndexPrefix) ||
-			bytes.HasPrefix(key, BloomTriePrefix): // Bloomtrie sub
-			bloomTrieNodes.Add(size)
-		default:
-			var accounted bool
-			for _, meta := range [][]byte{
-				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, headFinalizedBlockKey,
-				lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
-				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
-				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
-				persistentStateIDKey, trieJournalKey, snapshotSyncStatusKey, snapSyncStatusFlagKey,
-			} {
-				if bytes.Equal(key, meta) {
-					metadata.Add(size)
-					accounted = true
-					break
-				}
-			}
-			if !accounted {
-				unaccounted.Add(size)
-			}
+
+		category := categorize(key, it.Value())
+		if categories[category] == nil {
+			categories[category] = new(stat)
 		}
+		categories[category].Add(size)
+
 		count++
 		if count%1000 == 0 && time.Since(logged) > 8*time.Second {
 			log.Info("Inspecting database", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
@@ -576,28 +652,13 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		}
 	}
 	// Display the database statistic of key-value store.
-	stats := [][]string{
-		{"Key-Value store", "Headers", headers.Size(), headers.Count()},
-		{"Key-Value store", "Bodies", bodies.Size(), bodies.Count()},
-		{"Key-Value store", "Receipt lists", receipts.Size(), receipts.Count()},
-		{"Key-Value store", "Difficulties", tds.Size(), tds.Count()},
-		{"Key-Value store", "Block number->hash", numHashPairings.Size(), numHashPairings.Count()},
-		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
-		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
-		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
-		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
-		{"Key-Value store", "Hash trie nodes", legacyTries.Size(), legacyTries.Count()},
-		{"Key-Value store", "Path trie state lookups", stateLookups.Size(), stateLookups.Count()},
-		{"Key-Value store", "Path trie account nodes", accountTries.Size(), accountTries.Count()},
-		{"Key-Value store", "Path trie storage nodes", storageTries.Size(), storageTries.Count()},
-		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
-		{"Key-Value store", "Account snapshot", accountSnaps.Size(), accountSnaps.Count()},
-		{"Key-Value store", "Storage snapshot", storageSnaps.Size(), storageSnaps.Count()},
-		{"Key-Value store", "Beacon sync headers", beaconHeaders.Size(), beaconHeaders.Count()},
-		{"Key-Value store", "Clique snapshots", cliqueSnaps.Size(), cliqueSnaps.Count()},
-		{"Key-Value store", "Singleton metadata", metadata.Size(), metadata.Count()},
-		{"Light client", "CHT trie nodes", chtTrieNodes.Size(), chtTrieNodes.Count()},
-		{"Light client", "Bloom trie nodes", bloomTrieNodes.Size(), bloomTrieNodes.Count()},
+	var stats [][]string
+	for _, c := range dbCategories {
+		s := categories[c.id]
+		if s == nil {
+			s = new(stat)
+		}
+		stats = append(stats, []string{c.database, c.name, s.Size(), s.Count()})
 	}
 	// Inspect all registered append-only file store then.
 	ancients, err := inspectFreezers(db)
@@ -621,7 +682,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 	table.AppendBulk(stats)
 	table.Render()
 
-	if unaccounted.size > 0 {
+	if unaccounted := categories[unaccountedCategory]; unaccounted != nil {
 		log.Error("Database contains unaccounted data", "size", unaccounted.size, "count", unaccounted.count)
 	}
 	return nil
@@ -658,6 +719,7 @@ func ReadChainMetadata(db ethdb.KeyValueStore) [][]string {
 		{"snapshotRecoveryNumber", pp(ReadSnapshotRecoveryNumber(db))},
 		{"snapshotRoot", fmt.Sprintf("%v", ReadSnapshotRoot(db))},
 		{"txIndexTail", pp(ReadTxIndexTail(db))},
+		{"accessSetTail", pp(ReadAccessSetTail(db))},
 	}
 	if b := ReadSkeletonSyncStatus(db); b != nil {
 		data = append(data, []string{"SkeletonSyncStatus", string(b)})
@@ -0,0 +1,631 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/olekukonko/tablewriter"
+)
+
+const (
+	// statsPersistInterval is the interval at which the incrementally maintained
+	// database statistics are persisted.
+	statsPersistInterval = time.Minute
+
+	// statsScanChunk is the number of entries scanned by the initial scan of the
+	// statistics before its progress is recorded and the iterator is reopened,
+	// so it doesn't pin an old version of the database.
+	statsScanChunk = 100_000
+)
+
+// errNoDatabaseStats is returned if the database has no persisted statistics.
+var errNoDatabaseStats = errors.New("no database statistics available")
+
+// dbCategory is a category of the data in the key-value store.
+type dbCategory struct {
+	id       string // Identifier in the persisted statistics and metrics
+	database string // Database column of the inspection report
+	name     string // Category column of the inspection report
+}
+
+// dbCategories are the categories of the key-value store, in report order.
+var dbCategories = []dbCategory{
+	{"headers", "Key-Value store", "Headers"},
+	{"bodies", "Key-Value store", "Bodies"},
+	{"receipts", "Key-Value store", "Receipt lists"},
+	{"accessSets", "Key-Value store", "Block access sets"},
+	{"difficulties", "Key-Value store", "Difficulties"},
+	{"numberToHash", "Key-Value store", "Block number->hash"},
+	{"hashToNumber", "Key-Value store", "Block hash->number"},
+	{"txLookups", "Key-Value store", "Transaction index"},
+	{"bloomBits", "Key-Value store", "Bloombit index"},
+	{"codes", "Key-Value store", "Contract codes"},
+	{"hashTrieNodes", "Key-Value store", "Hash trie nodes"},
+	{"stateLookups", "Key-Value store", "Path trie state lookups"},
+	{"accountTrieNodes", "Key-Value store", "Path trie account nodes"},
+	{"storageTrieNodes", "Key-Value store", "Path trie storage nodes"},
+	{"preimages", "Key-Value store", "Trie preimages"},
+	{"accountSnapshot", "Key-Value store", "Account snapshot"},
+	{"storageSnapshot", "Key-Value store", "Storage snapshot"},
+	{"beaconHeaders", "Key-Value store", "Beacon sync headers"},
+	{"cliqueSnapshots", "Key-Value store", "Clique snapshots"},
+	{"metadata", "Key-Value store", "Singleton metadata"},
+	{"chtTrieNodes", "Light client", "CHT trie nodes"},
+	{"bloomTrieNodes", "Light client", "Bloom trie nodes"},
+}
+
+// unaccountedCategory is the category of the entries not belonging to any of
+// the known ones.
+const unaccountedCategory = "unaccounted"
+
+// metadataKeys are the singleton keys accounted as metadata.
+var metadataKeys = [][]byte{
+	databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, headFinalizedBlockKey,
+	lastPivotKey, fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
+	snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, historyCutoffKey, fastTxLookupLimitKey,
+	uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
+	persistentStateIDKey, trieJournalKey, snapshotSyncStatusKey, snapSyncStatusFlagKey,
+	databaseStatsKey, accessSetTailKey,
+}
+
+// categorize returns the category of a key-value store entry.
+func categorize(key, value []byte) string {
+	switch {
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == (len(headerPrefix)+8+common.HashLength):
+		return "headers"
+	case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == (len(blockBodyPrefix)+8+common.HashLength):
+		return "bodies"
+	case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == (len(blockReceiptsPrefix)+8+common.HashLength):
+		return "receipts"
+	case bytes.HasPrefix(key, blockAccessSetPrefix) && len(key) == (len(blockAccessSetPrefix)+8+common.HashLength):
+		return "accessSets"
+	case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerTDSuffix):
+		return "difficulties"
+	case bytes.HasPrefix(key, headerPrefix) && bytes.HasSuffix(key, headerHashSuffix):
+		return "numberToHash"
+	case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == (len(headerNumberPrefix)+common.HashLength):
+		return "hashToNumber"
+	case IsLegacyTrieNode(key, value):
+		return "hashTrieNodes"
+	case bytes.HasPrefix(key, stateIDPrefix) && len(key) == len(stateIDPrefix)+common.HashLength:
+		return "stateLookups"
+	case IsAccountTrieNode(key):
+		return "accountTrieNodes"
+	case IsStorageTrieNode(key):
+		return "storageTrieNodes"
+	case bytes.HasPrefix(key, CodePrefix) && len(key) == len(CodePrefix)+common.HashLength:
+		return "codes"
+	case bytes.HasPrefix(key, txLookupPrefix) && len(key) == (len(txLookupPrefix)+common.HashLength):
+		return "txLookups"
+	case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == (len(SnapshotAccountPrefix)+common.HashLength):
+		return "accountSnapshot"
+	case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == (len(SnapshotStoragePrefix)+2*common.HashLength):
+		return "storageSnapshot"
+	case bytes.HasPrefix(key, PreimagePrefix) && len(key) == (len(PreimagePrefix)+common.HashLength):
+		return "preimages"
+	case bytes.HasPrefix(key, configPrefix) && len(key) == (len(configPrefix)+common.HashLength):
+		return "metadata"
+	case bytes.HasPrefix(key, genesisPrefix) && len(key) == (len(genesisPrefix)+common.HashLength):
+		return "metadata"
+	case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == (len(bloomBitsPrefix)+10+common.HashLength):
+		return "bloomBits"
+	case bytes.HasPrefix(key, BloomBitsIndexPrefix):
+		return "bloomBits"
+	case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
+		return "beaconHeaders"
+	case bytes.HasPrefix(key, CliqueSnapshotPrefix) && len(key) == 7+common.HashLength:
+		return "cliqueSnapshots"
+	case bytes.HasPrefix(key, ChtTablePrefix) ||
+		bytes.HasPrefix(key, ChtIndexTablePrefix) ||
+		bytes.HasPrefix(key, ChtPrefix): // Canonical hash trie
+		return "chtTrieNodes"
+	case bytes.HasPrefix(key, BloomTrieTablePrefix) ||
+		bytes.HasPrefix(key, BloomTrieIndexPrefix) ||
+		bytes.HasPrefix(key, BloomTriePrefix): // Bloomtrie sub
+		return "bloomTrieNodes"
+	}
+	for _, meta := range metadataKeys {
+		if bytes.Equal(key, meta) {
+			return "metadata"
+		}
+	}
+	return unaccountedCategory
+}
+
+// CategoryStats is the number and total size of the entries of a category.
+type CategoryStats struct {
+	Count int64 `json:"count"`
+	Size  int64 `json:"size"`
+}
+
+// DatabaseStats are the statistics of the key-value store categories, which
+// are maintained incrementally by a node running with database statistics
+// enabled, and the statistics of the chain freezer tables.
+type DatabaseStats struct {
+	Updated    time.Time                 `json:"updated"`          // Time the statistics were persisted
+	Complete   bool                      `json:"complete"`         // Whether the initial scan finished
+	Marker     hexutil.Bytes             `json:"marker,omitempty"` // Progress of the initial scan
+	Dirty      bool                      `json:"dirty,omitempty"`  // Whether the database was modified without tracking
+	Categories map[string]*CategoryStats `json:"categories"`       // Key-value store statistics by category
+	Ancients   map[string]*CategoryStats `json:"ancients,omitempty"`
+}
+
+// ReadDatabaseStats retrieves the persisted statistics of the key-value store
+// and adds the current statistics of the chain freezer, if there is one.
+func ReadDatabaseStats(db ethdb.Database) (*DatabaseStats, error) {
+	blob, err := db.Get(databaseStatsKey)
+	if err != nil {
+		return nil, errNoDatabaseStats
+	}
+	stats := new(DatabaseStats)
+	if err := json.Unmarshal(blob, stats); err != nil {
+		return nil, err
+	}
+	if info, err := inspect(ChainFreezerName, chainFreezerTableConfigs, db); err == nil {
+		stats.Ancients = make(map[string]*CategoryStats)
+		for _, table := range info.sizes {
+			stats.Ancients[ChainFreezerName+"/"+table.name] = &CategoryStats{
+				Count: int64(info.count()),
+				Size:  int64(table.size),
+			}
+		}
+	}
+	return stats, nil
+}
+
+// LoadDatabaseStats reads statistics saved with SaveDatabaseStats.
+func LoadDatabaseStats(r io.Reader) (*DatabaseStats, error) {
+	stats := new(DatabaseStats)
+	if err := json.NewDecoder(r).Decode(stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// SaveDatabaseStats writes the statistics, e.g. to be used as the baseline of a
+// later report.
+func SaveDatabaseStats(w io.Writer, stats *DatabaseStats) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(stats)
+}
+
+// PrintDatabaseStats prints the statistics, along with the growth of every
+// category since the baseline if one is given.
+func PrintDatabaseStats(w io.Writer, stats *DatabaseStats, baseline *DatabaseStats) {
+	fmt.Fprintf(w, "Statistics updated %v\n", stats.Updated.Format(time.RFC1123))
+	if baseline != nil {
+		fmt.Fprintf(w, "Growth since %v (%v)\n", baseline.Updated.Format(time.RFC1123), common.PrettyDuration(stats.Updated.Sub(baseline.Updated)))
+	}
+	if stats.Dirty {
+		fmt.Fprintln(w, "WARNING: the database was modified while the statistics were not maintained, they are outdated")
+	} else if !stats.Complete {
+		fmt.Fprintf(w, "WARNING: the initial scan is in progress at key %x, the statistics are incomplete\n", []byte(stats.Marker))
+	}
+	var (
+		rows  [][]string
+		total CategoryStats
+		grown CategoryStats
+	)
+	row := func(database, name string, have, base map[string]*CategoryStats, id string) {
+		cur := have[id]
+		if cur == nil {
+			cur = new(CategoryStats)
+		}
+		total.Count += cur.Count
+		total.Size += cur.Size
+		r := []string{database, name, common.StorageSize(cur.Size).String(), fmt.Sprintf("%d", cur.Count)}
+		if baseline != nil {
+			old := base[id]
+			if old == nil {
+				old = new(CategoryStats)
+			}
+			grown.Count += cur.Count - old.Count
+			grown.Size += cur.Size - old.Size
+			r = append(r, formatSizeDelta(cur.Size-old.Size), fmt.Sprintf("%+d", cur.Count-old.Count))
+		}
+		rows = append(rows, r)
+	}
+	var baseCategories, baseAncients map[string]*CategoryStats
+	if baseline != nil {
+		baseCategories, baseAncients = baseline.Categories, baseline.Ancients
+	}
+	for _, c := range dbCategories {
+		row(c.database, c.name, stats.Categories, baseCategories, c.id)
+	}
+	row("Key-Value store", "Unaccounted", stats.Categories, baseCategories, unaccountedCategory)
+	for _, name := range chainFreezerTableNames() {
+		id := ChainFreezerName + "/" + name
+		if _, ok := stats.Ancients[id]; ok {
+			row(fmt.Sprintf("Ancient store (%s)", strings.Title(ChainFreezerName)), strings.Title(name), stats.Ancients, baseAncients, id)
+		}
+	}
+	table := tablewriter.NewWriter(w)
+	header := []string{"Database", "Category", "Size", "Items"}
+	footer := []string{"", "Total", common.StorageSize(total.Size).String(), " "}
+	if baseline != nil {
+		header = append(header, "Size growth", "Items growth")
+		footer = append(footer, formatSizeDelta(grown.Size), " ")
+	}
+	table.SetHeader(header)
+	table.SetFooter(footer)
+	table.AppendBulk(rows)
+	table.Render()
+}
+
+// formatSizeDelta formats a signed storage size difference.
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + common.StorageSize(-delta).String()
+	}
+	return "+" + common.StorageSize(delta).String()
+}
+
+// chainFreezerTableNames returns the names of the chain freezer tables in a
+// stable order.
+func chainFreezerTableNames() []string {
+	return []string{ChainFreezerHeaderTable, ChainFreezerHashTable, ChainFreezerBodiesTable, ChainFreezerReceiptTable, ChainFreezerDifficultyTable}
+}
+
+// markDatabaseStatsDirty flags the persisted statistics as outdated, as the
+// database is opened for writing without maintaining them.
+func markDatabaseStatsDirty(db ethdb.KeyValueStore) error {
+	blob, err := db.Get(databaseStatsKey)
+	if err != nil {
+		return nil
+	}
+	stats := new(DatabaseStats)
+	if err := json.Unmarshal(blob, stats); err != nil || stats.Dirty {
+		return nil
+	}
+	stats.Dirty = true
+	if blob, err = json.Marshal(stats); err != nil {
+		return err
+	}
+	return db.Put(databaseStatsKey, blob)
+}
+
+// statsGauges are the metrics of a key-value store category.
+type statsGauges struct {
+	count metrics.Gauge
+	size  metrics.Gauge
+}
+
+// statsChange is a write to an entry of the key-value store, resolved against
+// the previous value of the entry.
+type statsChange struct {
+	key     []byte
+	oldCat  string // Category of the previous value, empty if there's none
+	oldSize int
+	newCat  string // Category of the new value, empty for deletions
+	newSize int
+}
+
+// statsStore is a key-value store wrapper maintaining the statistics of the
+// stored categories. Every write resolves the previous value of the written
+// entries, so overwrites and deletions are accounted exactly.
+//
+// A scan of the database in key order seeds the statistics initially. Writes
+// to keys the scan hasn't reached yet are left to it. Writes racing with the
+// progress of the scan might be miscounted slightly.
+type statsStore struct {
+	ethdb.KeyValueStore
+
+	stats  *DatabaseStats
+	gauges map[string]*statsGauges
+	lock   sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newStatsStore wraps the key-value store, resuming the persisted statistics if
+// they are up to date and starting the initial scan otherwise.
+func newStatsStore(db ethdb.KeyValueStore, namespace string) (*statsStore, error) {
+	s := &statsStore{
+		KeyValueStore: db,
+		gauges:        make(map[string]*statsGauges),
+		quit:          make(chan struct{}),
+	}
+	if blob, err := db.Get(databaseStatsKey); err == nil {
+		stats := new(DatabaseStats)
+		if err := json.Unmarshal(blob, stats); err == nil && !stats.Dirty {
+			s.stats = stats
+		}
+	}
+	if s.stats == nil {
+		log.Info("Starting database statistics scan")
+		s.stats = &DatabaseStats{Categories: make(map[string]*CategoryStats)}
+	}
+	s.stats.Ancients = nil
+	for _, c := range append(dbCategories, dbCategory{id: unaccountedCategory}) {
+		if s.stats.Categories[c.id] == nil {
+			s.stats.Categories[c.id] = new(CategoryStats)
+		}
+		prefix := namespace + "stats/" + strings.ToLower(c.id) + "/"
+		s.gauges[c.id] = &statsGauges{
+			count: metrics.GetOrRegisterGauge(prefix+"count", nil),
+			size:  metrics.GetOrRegisterGauge(prefix+"size", nil),
+		}
+		s.gauges[c.id].count.Update(s.stats.Categories[c.id].Count)
+		s.gauges[c.id].size.Update(s.stats.Categories[c.id].Size)
+	}
+	// Flag the statistics dirty until they are persisted on a clean shutdown.
+	if err := s.persist(true); err != nil {
+		return nil, err
+	}
+	if !s.stats.Complete {
+		s.wg.Add(1)
+		go s.scan()
+	}
+	s.wg.Add(1)
+	go s.loop()
+	return s, nil
+}
+
+// persist writes the statistics to the wrapped store.
+func (s *statsStore) persist(dirty bool) error {
+	s.lock.Lock()
+	s.stats.Updated = time.Now()
+	s.stats.Dirty = dirty
+	blob, err := json.Marshal(s.stats)
+	s.lock.Unlock()
+
+	if err != nil {
+		return err
+	}
+	return s.KeyValueStore.Put(databaseStatsKey, blob)
+}
+
+// loop persists the statistics periodically.
+func (s *statsStore) loop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(statsPersistInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.persist(true); err != nil {
+				log.Warn("Failed to persist database statistics", "err", err)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// scan iterates over the database from the progress marker, adding the entries
+// to the statistics in chunks.
+func (s *statsStore) scan() {
+	defer s.wg.Done()
+
+	var (
+		start  = time.Now()
+		logged = time.Now()
+	)
+	for {
+		s.lock.Lock()
+		marker := common.CopyBytes(s.stats.Marker)
+		s.lock.Unlock()
+
+		var (
+			it    = s.KeyValueStore.NewIterator(nil, marker)
+			chunk = make(map[string]*CategoryStats)
+			count int
+			last  []byte
+		)
+		for count < statsScanChunk && it.Next() {
+			key := it.Key()
+			if !bytes.Equal(key, databaseStatsKey) {
+				cat := categorize(key, it.Value())
+				if chunk[cat] == nil {
+					chunk[cat] = new(CategoryStats)
+				}
+				chunk[cat].Count++
+				chunk[cat].Size += int64(len(key) + len(it.Value()))
+			}
+			last = key
+			count++
+
+			if count%1000 == 0 {
+				select {
+				case <-s.quit:
+					it.Release()
+					return
+				default:
+				}
+			}
+		}
+		err := it.Error()
+		it.Release()
+		if err != nil {
+			log.Error("Database statistics scan failed", "err", err)
+			return
+		}
+		s.lock.Lock()
+		for cat, delta := range chunk {
+			s.add(cat, delta.Count, delta.Size)
+		}
+		if count < statsScanChunk {
+			s.stats.Complete, s.stats.Marker = true, nil
+		} else {
+			s.stats.Marker = append(common.CopyBytes(last), 0) // First key after the last one
+		}
+		done := s.stats.Complete
+		s.lock.Unlock()
+
+		if done {
+			log.Info("Finished database statistics scan", "elapsed", common.PrettyDuration(time.Since(start)))
+			return
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Scanning database for statistics", "at", hexutil.Bytes(last), "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+}
+
+// add adjusts the statistics of a category. The lock must be held.
+func (s *statsStore) add(cat string, count, size int64) {
+	stat := s.stats.Categories[cat]
+	stat.Count += count
+	stat.Size += size
+	s.gauges[cat].count.Update(stat.Count)
+	s.gauges[cat].size.Update(stat.Size)
+}
+
+// resolve fills in the previous value of the changed entry. This is a read of
+// the wrapped store for every written key, the main cost of the statistics.
+func (s *statsStore) resolve(change *statsChange) {
+	if old, err := s.KeyValueStore.Get(change.key); err == nil {
+		change.oldCat, change.oldSize = categorize(change.key, old), len(change.key)+len(old)
+	}
+}
+
+// apply adds the written changes to the statistics.
+func (s *statsStore) apply(changes []*statsChange) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, change := range changes {
+		if !s.stats.Complete && bytes.Compare(change.key, s.stats.Marker) >= 0 {
+			continue // Not scanned yet, the scan will account for it
+		}
+		if change.oldCat != "" {
+			s.add(change.oldCat, -1, -int64(change.oldSize))
+		}
+		if change.newCat != "" {
+			s.add(change.newCat, 1, int64(change.newSize))
+		}
+	}
+}
+
+// Put inserts the given value into the key-value store.
+func (s *statsStore) Put(key []byte, value []byte) error {
+	change := &statsChange{key: common.CopyBytes(key), newCat: categorize(key, value), newSize: len(key) + len(value)}
+	s.resolve(change)
+	if err := s.KeyValueStore.Put(key, value); err != nil {
+		return err
+	}
+	s.apply([]*statsChange{change})
+	return nil
+}
+
+// Delete removes the key from the key-value store.
+func (s *statsStore) Delete(key []byte) error {
+	change := &statsChange{key: common.CopyBytes(key)}
+	s.resolve(change)
+	if err := s.KeyValueStore.Delete(key); err != nil {
+		return err
+	}
+	s.apply([]*statsChange{change})
+	return nil
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (s *statsStore) NewBatch() ethdb.Batch {
+	return &statsBatch{Batch: s.KeyValueStore.NewBatch(), store: s, changes: make(map[string]*statsChange)}
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+func (s *statsStore) NewBatchWithSize(size int) ethdb.Batch {
+	return &statsBatch{Batch: s.KeyValueStore.NewBatchWithSize(size), store: s, changes: make(map[string]*statsChange)}
+}
+
+// Checkpoint creates a consistent copy of the wrapped store in the given
+// directory.
+func (s *statsStore) Checkpoint(dir string) error {
+	cp, ok := s.KeyValueStore.(ethdb.Checkpointer)
+	if !ok {
+		return fmt.Errorf("checkpoints of %T not supported", s.KeyValueStore)
+	}
+	return cp.Checkpoint(dir)
+}
+
+// Close stops the maintenance of the statistics, persists them and closes the
+// wrapped store.
+func (s *statsStore) Close() error {
+	close(s.quit)
+	s.wg.Wait()
+
+	if err := s.persist(false); err != nil {
+		log.Warn("Failed to persist database statistics", "err", err)
+	}
+	return s.KeyValueStore.Close()
+}
+
+// statsBatch is a batch recording the last change of every written key, which
+// is accounted in the statistics when the batch is written.
+type statsBatch struct {
+	ethdb.Batch
+	store   *statsStore
+	changes map[string]*statsChange
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *statsBatch) Put(key, value []byte) error {
+	if err := b.Batch.Put(key, value); err != nil {
+		return err
+	}
+	b.changes[string(key)] = &statsChange{key: common.CopyBytes(key), newCat: categorize(key, value), newSize: len(key) + len(value)}
+	return nil
+}
+
+// Delete inserts the key removal into the batch for later committing.
+func (b *statsBatch) Delete(key []byte) error {
+	if err := b.Batch.Delete(key); err != nil {
+		return err
+	}
+	b.changes[string(key)] = &statsChange{key: common.CopyBytes(key)}
+	return nil
+}
+
+// Write flushes any accumulated data to disk and updates the statistics.
+func (b *statsBatch) Write() error {
+	changes := make([]*statsChange, 0, len(b.changes))
+	for _, change := range b.changes {
+		b.store.resolve(change)
+		changes = append(changes, change)
+	}
+	if err := b.Batch.Write(); err != nil {
+		return err
+	}
+	b.store.apply(changes)
+	return nil
+}
+
+// Reset resets the batch for reuse.
+func (b *statsBatch) Reset() {
+	b.Batch.Reset()
+	clear(b.changes)
+}
@@ -15,3 +15,188 @@
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
 package rawdb
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
+)
+
+// Tests that encrypted databases can only be reopened with their key.
+func TestOpenEncrypted(t *testing.T) {
+	key, err := encryptdb.NewKey(bytes.Repeat([]byte{0x1}, encryptdb.KeyLength))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		dir     = t.TempDir()
+		options = OpenOptions{
+			Directory:         dir,
+			AncientsDirectory: filepath.Join(dir, "ancient"),
+			Encryption:        key,
+			EncryptKeys:       true,
+			Ephemeral:         true,
+		}
+	)
+	db, err := Open(options)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	WriteHeadHeaderHash(db, common.Hash{0x1})
+	db.Close()
+
+	plain := options
+	plain.Encryption = nil
+	if db, err := Open(plain); err == nil {
+		db.Close()
+		t.Fatal("Opened encrypted database without key")
+	}
+	db, err = Open(options)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer db.Close()
+	if hash := ReadHeadHeaderHash(db); hash != (common.Hash{0x1}) {
+		t.Fatalf("Head header hash mismatch: have %x", hash)
+	}
+	// The state freezer is opened separately with the cipher of the database.
+	if AncientCipher(db) != key.FileCipher() {
+		t.Fatal("Freezer cipher not exposed")
+	}
+}
+
+// statsStoreOf returns the statistics maintaining store of an opened database.
+func statsStoreOf(t *testing.T, db ethdb.Database) *statsStore {
+	t.Helper()
+	nofreeze, ok := db.(*nofreezedb)
+	if !ok {
+		t.Fatalf("Unexpected database type %T", db)
+	}
+	s, ok := nofreeze.KeyValueStore.(*statsStore)
+	if !ok {
+		t.Fatalf("Statistics not maintained by %T", nofreeze.KeyValueStore)
+	}
+	return s
+}
+
+// checkStats waits for the initial scan of the statistics to finish and checks
+// them against a full iteration of the database.
+func checkStats(t *testing.T, s *statsStore) {
+	t.Helper()
+	for i := 0; ; i++ {
+		s.lock.Lock()
+		complete := s.stats.Complete
+		s.lock.Unlock()
+		if complete {
+			break
+		}
+		if i == 500 {
+			t.Fatal("Statistics scan didn't finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	want := make(map[string]CategoryStats)
+	it := s.KeyValueStore.NewIterator(nil, nil)
+	for it.Next() {
+		if bytes.Equal(it.Key(), databaseStatsKey) {
+			continue
+		}
+		stat := want[categorize(it.Key(), it.Value())]
+		stat.Count++
+		stat.Size += int64(len(it.Key()) + len(it.Value()))
+		want[categorize(it.Key(), it.Value())] = stat
+	}
+	it.Release()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for cat, have := range s.stats.Categories {
+		if *have != want[cat] {
+			t.Errorf("Category %s mismatch: have %+v, want %+v", cat, *have, want[cat])
+		}
+	}
+}
+
+// Tests that the incrementally maintained statistics match the database content
+// across writes, and that they are resumed if the database was closed cleanly
+// and rescanned if it was modified without maintaining them.
+func TestDatabaseStats(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		options = OpenOptions{Directory: dir, Stats: true}
+	)
+	writeCode := func(db ethdb.KeyValueWriter, i int) {
+		WriteCode(db, common.Hash{byte(i)}, bytes.Repeat([]byte{byte(i)}, i+1))
+	}
+	// Populate the database before the statistics are enabled.
+	db, err := Open(OpenOptions{Directory: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		writeCode(db, i)
+	}
+	WriteHeadHeaderHash(db, common.Hash{0x1})
+	db.Close()
+
+	db, err = Open(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := statsStoreOf(t, db)
+	checkStats(t, s)
+
+	// Overwrite, delete and add entries, also repeatedly within a batch.
+	WriteCode(db, common.Hash{1}, []byte{0x1, 0x2, 0x3, 0x4, 0x5})
+	DeleteCode(db, common.Hash{2})
+	DeleteCode(db, common.Hash{0xff})
+
+	batch := db.NewBatch()
+	WriteCode(batch, common.Hash{3}, []byte{0x3})
+	DeleteCode(batch, common.Hash{3})
+	WriteCode(batch, common.Hash{0xfe}, []byte{0x1})
+	DeleteCode(batch, common.Hash{0xfe})
+	WriteCode(batch, common.Hash{0xfd}, []byte{0x1})
+	WriteCode(batch, common.Hash{0xfd}, []byte{0x1, 0x2})
+	WriteTxLookupEntries(batch, 1, []common.Hash{{0x1}, {0x2}})
+	if err := batch.Write(); err != nil {
+		t.Fatal(err)
+	}
+	checkStats(t, s)
+	db.Close()
+
+	// Reopening resumes the persisted statistics.
+	db, err = Open(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s = statsStoreOf(t, db)
+	if !s.stats.Complete {
+		t.Fatal("Statistics not resumed")
+	}
+	checkStats(t, s)
+	db.Close()
+
+	// Modifications without maintaining the statistics outdate them.
+	db, err = Open(OpenOptions{Directory: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeCode(db, 200)
+	if stats, err := ReadDatabaseStats(db); err != nil || !stats.Dirty {
+		t.Fatalf("Statistics not flagged outdated: %v", err)
+	}
+	db.Close()
+
+	db, err = Open(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	checkStats(t, statsStoreOf(t, db))
+}
@@ -22,12 +22,14 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/gofrs/flock"
@@ -63,7 +65,7 @@ const freezerTableSize = 2 * 1000 * 1000 * 1000
 //     of Geth, and thus also GC overhead.
 type Freezer struct {
 	frozen atomic.Uint64 // Number of items already frozen
-	tail   atomic.Uint64 // Number of the first stored item in the freezer
+	tail   atomic.Uint64 // Number of the first stored item in the prunable tables
 
 	// This lock synchronizes writers and the truncate operation, as well as
 	// the "atomic" (batched) read operations.
@@ -72,6 +74,7 @@ type Freezer struct {
 
 	readonly     bool
 	tables       map[string]*freezerTable // Data tables for storing everything
+	prunable     map[string]bool          // Tables which are truncated by TruncateTail
 	instanceLock *flock.Flock             // File-system lock to prevent double opens
 	closeOnce    sync.Once
 }
@@ -79,9 +82,14 @@ type Freezer struct {
 // NewFreezer creates a freezer instance for maintaining immutable ordered
 // data according to the given parameters.
 //
-// The 'tables' argument defines the data tables. If the value of a map
-// entry is true, snappy compression is disabled for the table.
-func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+// The 'tables' argument defines the data tables and their settings.
+func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]freezerTableConfig) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, maxTableSize, tables, nil)
+}
+
+// newFreezer creates a freezer instance, encrypting the items of all tables with
+// the given cipher if it's non-nil.
+func newFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]freezerTableConfig, cipher *encryptdb.FileCipher) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -115,12 +123,28 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 	freezer := &Freezer{
 		readonly:     readonly,
 		tables:       make(map[string]*freezerTable),
+		prunable:     make(map[string]bool),
 		instanceLock: lock,
 	}
 
 	// Create the tables.
-	for name, disableSnappy := range tables {
-		table, err := newTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, disableSnappy, readonly)
+	for name, config := range tables {
+		// Complete a table migration interrupted while switching the tables.
+		if common.FileExist(filepath.Join(migrationDir(datadir, name), migrationMarkerFile(name))) {
+			err := fmt.Errorf("unfinished migration of table %s", name)
+			if !readonly {
+				err = finishTableMigration(datadir, name)
+			}
+			if err != nil {
+				for _, table := range freezer.tables {
+					table.Close()
+				}
+				lock.Unlock()
+				return nil, err
+			}
+		}
+		codec := detectCodec(datadir, name, config.noSnappy)
+		table, err := openTable(datadir, name, readMeter, writeMeter, sizeGauge, maxTableSize, codec, cipher, readonly)
 		if err != nil {
 			for _, table := range freezer.tables {
 				table.Close()
@@ -129,6 +153,7 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 			return nil, err
 		}
 		freezer.tables[name] = table
+		freezer.prunable[name] = config.prunable
 	}
 	var err error
 	if freezer.readonly {
@@ -211,7 +236,8 @@ func (f *Freezer) Ancients() (uint64, error) {
 	return f.frozen.Load(), nil
 }
 
-// Tail returns the number of first stored item in the freezer.
+// Tail returns the number of first stored item in the freezer. The tables which
+// are not prunable retain all their items from zero.
 func (f *Freezer) Tail() (uint64, error) {
 	return f.tail.Load(), nil
 }
@@ -294,7 +320,8 @@ func (f *Freezer) TruncateHead(items uint64) (uint64, error) {
 	return oitems, nil
 }
 
-// TruncateTail discards any recent data below the provided threshold number.
+// TruncateTail discards any recent data below the provided threshold number
+// from the prunable tables.
 func (f *Freezer) TruncateTail(tail uint64) (uint64, error) {
 	if f.readonly {
 		return 0, errReadOnly
@@ -306,7 +333,10 @@ func (f *Freezer) TruncateTail(tail uint64) (uint64, error) {
 	if old >= tail {
 		return old, nil
 	}
-	for _, table := range f.tables {
+	for name, table := range f.tables {
+		if !f.prunable[name] {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return 0, err
 		}
@@ -336,24 +366,27 @@ func (f *Freezer) validate() error {
 		return nil
 	}
 	var (
-		head uint64
-		tail uint64
-		name string
+		head     uint64
+		tail     uint64
+		name     string
+		tailName string
 	)
 	// Hack to get boundary of any table
 	for kind, table := range f.tables {
 		head = table.items.Load()
-		tail = table.itemHidden.Load()
 		name = kind
-		break
+		if f.prunable[kind] {
+			tail = table.itemHidden.Load()
+			tailName = kind
+		}
 	}
 	// Now check every table against those boundaries.
 	for kind, table := range f.tables {
 		if head != table.items.Load() {
 			return fmt.Errorf("freezer tables %s and %s have differing head: %d != %d", kind, name, table.items.Load(), head)
 		}
-		if tail != table.itemHidden.Load() {
-			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, name, table.itemHidden.Load(), tail)
+		if f.prunable[kind] && tail != table.itemHidden.Load() {
+			return fmt.Errorf("freezer tables %s and %s have differing tail: %d != %d", kind, tailName, table.itemHidden.Load(), tail)
 		}
 	}
 	f.frozen.Store(head)
@@ -367,20 +400,26 @@ func (f *Freezer) repair() error {
 		head = uint64(math.MaxUint64)
 		tail = uint64(0)
 	)
-	for _, table := range f.tables {
+	for name, table := range f.tables {
 		items := table.items.Load()
 		if head > items {
 			head = items
 		}
+		if !f.prunable[name] {
+			continue
+		}
 		hidden := table.itemHidden.Load()
 		if hidden > tail {
 			tail = hidden
 		}
 	}
-	for _, table := range f.tables {
+	for name, table := range f.tables {
 		if err := table.truncateHead(head); err != nil {
 			return err
 		}
+		if !f.prunable[name] {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return err
 		}
@@ -407,6 +446,41 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 	if !ok {
 		return errUnknownTable
 	}
+	return f.migrateTable(kind, table.codec, convert)
+}
+
+// migrateTableCodec rewrites the items of a table using the given codec. For
+// zstd, a dictionary is trained from a sample of the items first.
+//
+// The migrated table is built next to the original one and the progress is
+// committed to disk regularly, so an interrupted migration resumes where it
+// stopped. The tables are switched by moving the new files in place, the index
+// file last, after which the files of the old codec are deleted. The files are
+// recorded beforehand, so an interrupted switch is completed when the freezer
+// is opened again.
+func (f *Freezer) migrateTableCodec(kind string, codec freezerCodec) error {
+	if f.readonly {
+		return errReadOnly
+	}
+	f.writeLock.Lock()
+	defer f.writeLock.Unlock()
+
+	table, ok := f.tables[kind]
+	if !ok {
+		return errUnknownTable
+	}
+	if table.codec == codec {
+		// Clean up after a migration interrupted right after switching tables.
+		return removeCodecFiles(table.path, kind, codec)
+	}
+	return f.migrateTable(kind, codec, func(blob []byte) ([]byte, error) { return blob, nil })
+}
+
+// migrateTable rewrites the entries of a table using the given codec, passing
+// each one through the conversion function. The caller must hold the write lock.
+func (f *Freezer) migrateTable(kind string, codec freezerCodec, convert convertLegacyFn) error {
+	table := f.tables[kind]
+
 	// forEach iterates every entry in the table serially and in order, calling `fn`
 	// with the item as argument. If `fn` returns an error the iteration stops
 	// and that error will be returned.
@@ -433,16 +507,16 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 		}
 		return nil
 	}
-	// TODO(s1na): This is a sanity-check since as of now no process does tail-deletion. But the migration
-	// process assumes no deletion at tail and needs to be modified to account for that.
-	if table.itemOffset.Load() > 0 || table.itemHidden.Load() > 0 {
-		return errors.New("migration not supported for tail-deleted freezers")
-	}
 	ancientsPath := filepath.Dir(table.index.Name())
 	// Set up new dir for the migrated table, the content of which
 	// we'll at the end move over to the ancients dir.
-	migrationPath := filepath.Join(ancientsPath, "migration")
-	newTable, err := newFreezerTable(migrationPath, kind, table.noCompression, false)
+	migrationPath := migrationDir(ancientsPath, kind)
+	if codec == codecZstd && !common.FileExist(filepath.Join(migrationPath, dictFile(kind))) {
+		if err := trainTableDictionary(table, migrationPath); err != nil {
+			return err
+		}
+	}
+	newTable, err := openMigrationTable(table, migrationPath, codec)
 	if err != nil {
 		return err
 	}
@@ -453,8 +527,8 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 		logged = time.Now()
 		offset = newTable.items.Load()
 	)
-	if offset > 0 {
-		log.Info("found previous migration attempt", "migrated", offset)
+	if offset > newTable.itemHidden.Load() {
+		log.Info("found previous migration attempt", "migrated", offset-newTable.itemHidden.Load())
 	}
 	// Iterate through entries and transform them
 	if err := forEach(table, offset, func(i uint64, blob []byte) error {
@@ -471,33 +545,266 @@ func (f *Freezer) MigrateTable(kind string, convert convertLegacyFn) error {
 		}
 		return nil
 	}); err != nil {
+		newTable.Close()
 		return err
 	}
 	if err := batch.commit(); err != nil {
+		newTable.Close()
 		return err
 	}
 	log.Info("Replacing old table files with migrated ones", "elapsed", common.PrettyDuration(time.Since(start)))
-	// Release and delete old table files. Note this won't
-	// delete the index file.
-	table.releaseFilesAfter(0, true)
-
 	if err := newTable.Close(); err != nil {
 		return err
 	}
+	size, err := table.size()
+	if err != nil {
+		return err
+	}
+	if err := table.Close(); err != nil {
+		return err
+	}
+	table.sizeGauge.Dec(int64(size))
+
+	// Record the files of the migrated table before moving any of them, so an
+	// interrupted switch is completed when the freezer is opened again.
+	if err := writeMigrationMarker(migrationPath, kind, codec); err != nil {
+		return err
+	}
+	if err := finishTableMigration(ancientsPath, kind); err != nil {
+		return err
+	}
+	// Reopen the migrated table.
+	table, err = openTable(ancientsPath, kind, table.readMeter, table.writeMeter, table.sizeGauge, table.maxFileSize, codec, table.cipher, false)
+	if err != nil {
+		return err
+	}
+	f.tables[kind] = table
+	f.writeBatch.tables[kind] = table.newBatch()
+	return nil
+}
+
+// openMigrationTable opens the table a migration is written to. The new table
+// starts at the tail of the migrated one, items deleted from the tail are not
+// carried over.
+func openMigrationTable(table *freezerTable, dir string, codec freezerCodec) (*freezerTable, error) {
+	tail := table.itemHidden.Load()
+	open := func() (*freezerTable, error) {
+		index := filepath.Join(dir, codec.indexFile(table.name))
+		if tail > 0 && !common.FileExist(index) {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return nil, err
+			}
+			// Initialize the index with the number of deleted items, the same
+			// way as truncateTail does.
+			entry := indexEntry{filenum: 0, offset: uint32(tail)}
+			if err := os.WriteFile(index+".tmp", entry.append(nil), 0644); err != nil {
+				return nil, err
+			}
+			if err := os.Rename(index+".tmp", index); err != nil {
+				return nil, err
+			}
+		}
+		return openTable(dir, table.name, metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, table.maxFileSize, codec, table.cipher, false)
+	}
+	newTable, err := open()
+	if err != nil {
+		return nil, err
+	}
+	if newTable.items.Load() >= tail {
+		// The tail might have moved since a previous attempt, drop the items
+		// deleted meanwhile.
+		if err := newTable.truncateTail(tail); err != nil {
+			newTable.Close()
+			return nil, err
+		}
+		return newTable, nil
+	}
+	// The items migrated by a previous attempt have all been deleted meanwhile,
+	// start over. The dictionary is kept.
+	log.Info("Discarding previous migration attempt", "table", table.name, "migrated", newTable.items.Load(), "tail", tail)
+	if err := newTable.Close(); err != nil {
+		return nil, err
+	}
+	files, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%s.*", table.name)))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if filepath.Base(file) == dictFile(table.name) {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return nil, err
+		}
+	}
+	return open()
+}
+
+// migrationDir returns the directory a table is migrated in. Each table has its
+// own, so the migrations of several tables can be interrupted and resumed
+// independently.
+func migrationDir(ancientsPath string, kind string) string {
+	return filepath.Join(ancientsPath, "migration", kind)
+}
+
+// migrationMarkerFile returns the name of the file listing the files of a
+// completely migrated table, which are yet to be moved to the ancients dir.
+func migrationMarkerFile(table string) string {
+	return fmt.Sprintf("%s.switch", table)
+}
+
+// writeMigrationMarker records the files of a completely migrated table in
+// the migration dir. The index file is listed last, as moving it marks the
+// migrated table as complete.
+func writeMigrationMarker(migrationPath string, kind string, codec freezerCodec) error {
 	files, err := os.ReadDir(migrationPath)
 	if err != nil {
 		return err
 	}
-	// Move migrated files to ancients dir.
+	var names []string
 	for _, f := range files {
-		// This will replace the old index file as a side-effect.
-		if err := os.Rename(filepath.Join(migrationPath, f.Name()), filepath.Join(ancientsPath, f.Name())); err != nil {
+		name := f.Name()
+		if strings.HasPrefix(name, kind+".") && !strings.HasSuffix(name, ".tmp") && name != codec.indexFile(kind) {
+			names = append(names, name)
+		}
+	}
+	names = append(names, codec.indexFile(kind))
+
+	marker := filepath.Join(migrationPath, migrationMarkerFile(kind))
+	if err := os.WriteFile(marker+".tmp", []byte(strings.Join(names, "\n")), 0644); err != nil {
+		return err
+	}
+	return os.Rename(marker+".tmp", marker)
+}
+
+// finishTableMigration moves the files of a completely migrated table to the
+// ancients dir, replacing the old table. It does nothing if no migration of
+// the table is pending, and completes a switch interrupted halfway.
+func finishTableMigration(ancientsPath string, kind string) error {
+	migrationPath := migrationDir(ancientsPath, kind)
+	blob, err := os.ReadFile(filepath.Join(migrationPath, migrationMarkerFile(kind)))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var (
+		names = strings.Split(string(blob), "\n")
+		index = names[len(names)-1]
+		codec freezerCodec
+		found bool
+		moved = make(map[string]bool)
+	)
+	for _, c := range []freezerCodec{codecNone, codecSnappy, codecZstd} {
+		if c.indexFile(kind) == index {
+			codec, found = c, true
+		}
+	}
+	if !found {
+		return fmt.Errorf("invalid migration of table %s: unknown index file %q", kind, index)
+	}
+	for _, name := range names {
+		moved[name] = true
+
+		// Files moved before an interruption are gone from the migration dir.
+		src := filepath.Join(migrationPath, name)
+		if !common.FileExist(src) {
+			continue
+		}
+		if err := os.Rename(src, filepath.Join(ancientsPath, name)); err != nil {
 			return err
 		}
 	}
-	// Delete by now empty dir.
-	if err := os.Remove(migrationPath); err != nil {
+	// Delete the data files of the old table which weren't replaced, as well
+	// as the files of the old codec.
+	ext := filepath.Ext(codec.dataFile(kind, 0))
+	stale, err := filepath.Glob(filepath.Join(ancientsPath, fmt.Sprintf("%s.*%s", kind, ext)))
+	if err != nil {
 		return err
 	}
+	for _, file := range stale {
+		if moved[filepath.Base(file)] {
+			continue
+		}
+		if err := os.Remove(file); err != nil {
+			return err
+		}
+	}
+	if err := removeCodecFiles(ancientsPath, kind, codec); err != nil {
+		return err
+	}
+	// Delete by now obsolete dir, and the parent one too if no other table is
+	// being migrated.
+	if err := os.RemoveAll(migrationPath); err != nil {
+		return err
+	}
+	parent := filepath.Dir(migrationPath)
+	if entries, err := os.ReadDir(parent); err != nil || len(entries) > 0 {
+		return nil
+	}
+	return os.Remove(parent)
+}
+
+// trainTableDictionary trains a zstd dictionary from items sampled evenly
+// across the table, and stores it in the given directory.
+func trainTableDictionary(table *freezerTable, dir string) error {
+	tail, items := table.itemHidden.Load(), table.items.Load()
+	if items == tail {
+		return errors.New("no items to train dictionary")
+	}
+	step := (items - tail) / freezerDictSamples
+	if step == 0 {
+		step = 1
+	}
+	var samples [][]byte
+	for i := tail; i < items; i += step {
+		blob, err := table.Retrieve(i)
+		if err != nil {
+			return err
+		}
+		samples = append(samples, blob)
+	}
+	start := time.Now()
+	dict := trainDictionary(samples, freezerDictSize)
+	log.Info("Trained compression dictionary", "table", table.name, "samples", len(samples), "size", len(dict), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// Write the dictionary atomically, it is used to decode the migrated items.
+	// It is derived from the items, so it's encrypted along with them.
+	if table.cipher != nil {
+		dict = table.cipher.Seal(nil, dict, []byte(dictFile(table.name)))
+	}
+	tmp := filepath.Join(dir, dictFile(table.name)+".tmp")
+	if err := os.WriteFile(tmp, dict, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, dictFile(table.name)))
+}
+
+// removeCodecFiles deletes the index and data files of a table stored with
+// any codec but the given one.
+func removeCodecFiles(dir string, kind string, keep freezerCodec) error {
+	for _, codec := range []freezerCodec{codecNone, codecSnappy, codecZstd} {
+		if codec == keep {
+			continue
+		}
+		ext := filepath.Ext(codec.dataFile(kind, 0))
+		files, err := filepath.Glob(filepath.Join(dir, fmt.Sprintf("%s.*%s", kind, ext)))
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.Join(dir, codec.indexFile(kind)))
+		if codec == codecZstd {
+			files = append(files, filepath.Join(dir, dictFile(kind)))
+		}
+		for _, file := range files {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
 	return nil
 }
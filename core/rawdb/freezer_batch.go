@@ -85,6 +85,8 @@ type freezerTableBatch struct {
 	t *freezerTable
 
 	sb          *snappyBuffer
+	zb          []byte // zstd output buffer, reused across items
+	cb          []byte // encryption output buffer, reused across items
 	encBuffer   writeBuffer
 	dataBuffer  []byte
 	indexBuffer []byte
@@ -95,7 +97,7 @@ type freezerTableBatch struct {
 // newBatch creates a new batch for the freezer table.
 func (t *freezerTable) newBatch() *freezerTableBatch {
 	batch := &freezerTableBatch{t: t}
-	if !t.noCompression {
+	if t.codec == codecSnappy {
 		batch.sb = new(snappyBuffer)
 	}
 	batch.reset()
@@ -123,11 +125,7 @@ func (batch *freezerTableBatch) Append(item uint64, data interface{}) error {
 	if err := rlp.Encode(&batch.encBuffer, data); err != nil {
 		return err
 	}
-	encItem := batch.encBuffer.data
-	if batch.sb != nil {
-		encItem = batch.sb.compress(encItem)
-	}
-	return batch.appendItem(encItem)
+	return batch.appendItem(batch.encrypt(batch.compress(batch.encBuffer.data)))
 }
 
 // AppendRaw injects a binary blob at the end of the freezer table. The item number is a
@@ -138,11 +136,28 @@ func (batch *freezerTableBatch) AppendRaw(item uint64, blob []byte) error {
 		return fmt.Errorf("%w: have %d want %d", errOutOrderInsertion, item, batch.curItem)
 	}
 
-	encItem := blob
-	if batch.sb != nil {
-		encItem = batch.sb.compress(blob)
+	return batch.appendItem(batch.encrypt(batch.compress(blob)))
+}
+
+// compress encodes an item using the codec of the table.
+func (batch *freezerTableBatch) compress(data []byte) []byte {
+	switch batch.t.codec {
+	case codecSnappy:
+		return batch.sb.compress(data)
+	case codecZstd:
+		batch.zb = batch.t.encoder.EncodeAll(data, batch.zb[:0])
+		return batch.zb
+	}
+	return data
+}
+
+// encrypt seals the next item if the table is encrypted.
+func (batch *freezerTableBatch) encrypt(data []byte) []byte {
+	if batch.t.cipher == nil {
+		return data
 	}
-	return batch.appendItem(encItem)
+	batch.cb = batch.t.cipher.Seal(batch.cb[:0], data, batch.t.itemPosition(batch.curItem))
+	return batch.cb
 }
 
 func (batch *freezerTableBatch) appendItem(data []byte) error {
@@ -0,0 +1,252 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// freezerCodec is the compression scheme used for the items of a freezer table.
+// The codec of a table is encoded in the suffix of its index and data files, so
+// a table can be opened without knowing how it was written.
+type freezerCodec uint8
+
+const (
+	codecNone   freezerCodec = iota // raw items, files suffixed with .ridx and .rdat
+	codecSnappy                     // snappy block format, files suffixed with .cidx and .cdat
+	codecZstd                       // zstd with a trained dictionary, files suffixed with .zidx and .zdat
+)
+
+const (
+	// freezerDictID is the identifier of the raw zstd dictionaries, embedded in
+	// the header of every compressed item.
+	freezerDictID = 1
+
+	// freezerDictSize is the target size of the trained zstd dictionaries.
+	freezerDictSize = 64 * 1024
+
+	// freezerDictSamples is the number of items sampled from a table to train
+	// its dictionary.
+	freezerDictSamples = 8192
+)
+
+// parseFreezerCodec resolves a codec from its name.
+func parseFreezerCodec(name string) (freezerCodec, error) {
+	switch name {
+	case "none":
+		return codecNone, nil
+	case "snappy":
+		return codecSnappy, nil
+	case "zstd":
+		return codecZstd, nil
+	}
+	return 0, fmt.Errorf("unknown freezer codec %q, supported ones: none, snappy, zstd", name)
+}
+
+// String implements fmt.Stringer.
+func (c freezerCodec) String() string {
+	switch c {
+	case codecNone:
+		return "none"
+	case codecSnappy:
+		return "snappy"
+	case codecZstd:
+		return "zstd"
+	}
+	return fmt.Sprintf("codec(%d)", uint8(c))
+}
+
+// indexFile returns the name of the index file of a table using the codec.
+func (c freezerCodec) indexFile(table string) string {
+	switch c {
+	case codecNone:
+		return fmt.Sprintf("%s.ridx", table) // raw index file
+	case codecZstd:
+		return fmt.Sprintf("%s.zidx", table) // zstd index file
+	default:
+		return fmt.Sprintf("%s.cidx", table) // compressed index file
+	}
+}
+
+// dataFile returns the name of a data file of a table using the codec.
+func (c freezerCodec) dataFile(table string, num uint32) string {
+	switch c {
+	case codecNone:
+		return fmt.Sprintf("%s.%04d.rdat", table, num)
+	case codecZstd:
+		return fmt.Sprintf("%s.%04d.zdat", table, num)
+	default:
+		return fmt.Sprintf("%s.%04d.cdat", table, num)
+	}
+}
+
+// decodedLen returns the size of an item once decoded, without decoding it. If
+// the size of a zstd item isn't recorded in its frame header, the encoded size
+// is returned.
+func (c freezerCodec) decodedLen(item []byte) int {
+	switch c {
+	case codecSnappy:
+		size, _ := snappy.DecodedLen(item)
+		return size
+	case codecZstd:
+		var header zstd.Header
+		if err := header.Decode(item); err == nil && header.HasFCS {
+			return int(header.FrameContentSize)
+		}
+	}
+	return len(item)
+}
+
+// dictFile returns the name of the file holding the zstd dictionary of a table.
+func dictFile(table string) string {
+	return fmt.Sprintf("%s.dict", table)
+}
+
+// detectCodec returns the codec of the table stored in the given directory.
+// Tables migrated to zstd are recognised by their index file, all others use
+// the codec they were configured with.
+func detectCodec(path string, table string, noCompression bool) freezerCodec {
+	if common.FileExist(filepath.Join(path, codecZstd.indexFile(table))) {
+		return codecZstd
+	}
+	if noCompression {
+		return codecNone
+	}
+	return codecSnappy
+}
+
+// loadDictionary reads the zstd dictionary of a table, decrypting it with the
+// cipher if set, and sets up the encoder and decoder using it.
+func loadDictionary(path string, table string, cipher *encryptdb.FileCipher) (*zstd.Encoder, *zstd.Decoder, error) {
+	dict, err := os.ReadFile(filepath.Join(path, dictFile(table)))
+	if err != nil {
+		return nil, nil, err
+	}
+	if cipher != nil {
+		if dict, err = cipher.Open(nil, dict, []byte(dictFile(table))); err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt dictionary of table %s: %w", table, err)
+		}
+	}
+	// Single segment frames record the size of the item in their header, which
+	// lets reads check size limits before decompressing.
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithSingleSegment(true), zstd.WithEncoderDictRaw(freezerDictID, dict))
+	if err != nil {
+		return nil, nil, err
+	}
+	dec, err := zstd.NewReader(nil, zstd.WithDecoderDictRaw(freezerDictID, dict))
+	if err != nil {
+		enc.Close()
+		return nil, nil, err
+	}
+	return enc, dec, nil
+}
+
+// trainDictionary builds a raw zstd dictionary of at most the given size from
+// the sample items. It is a simplified version of the cover algorithm: the
+// samples are cut into overlapping segments, which are scored by how often the
+// short substrings contained in them occur across all samples, and the best
+// ones are picked until the dictionary is full. Segments whose substrings are
+// already covered by the picked ones are skipped, to avoid wasting space on
+// repetitions.
+func trainDictionary(samples [][]byte, size int) []byte {
+	const (
+		kmer    = 8  // Length of the substrings counted
+		segment = 64 // Length of the segments picked
+		stride  = 16 // Distance between the starts of the candidate segments
+	)
+	// Count the occurrences of every substring. Substrings occurring once can't
+	// be referenced by any other item and don't contribute to the score.
+	freqs := make(map[uint64]uint32)
+	for _, sample := range samples {
+		for i := 0; i+kmer <= len(sample); i++ {
+			freqs[binary.LittleEndian.Uint64(sample[i:])]++
+		}
+	}
+	score := func(seg []byte, covered map[uint64]struct{}) uint64 {
+		var total uint64
+		for i := 0; i+kmer <= len(seg); i++ {
+			key := binary.LittleEndian.Uint64(seg[i:])
+			if _, ok := covered[key]; ok {
+				continue
+			}
+			if freq := freqs[key]; freq > 1 {
+				total += uint64(freq)
+			}
+		}
+		return total
+	}
+	type candidate struct {
+		data  []byte
+		score uint64
+	}
+	var candidates []candidate
+	for _, sample := range samples {
+		for i := 0; i+segment <= len(sample); i += stride {
+			seg := sample[i : i+segment]
+			if s := score(seg, nil); s > 0 {
+				candidates = append(candidates, candidate{seg, s})
+			}
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+	// Pick the segments in the order of their scores, rescoring them against
+	// the substrings already covered.
+	var (
+		picked  [][]byte
+		total   int
+		covered = make(map[uint64]struct{})
+	)
+	for _, c := range candidates {
+		if total+len(c.data) > size {
+			break
+		}
+		if score(c.data, covered) < c.score/2 {
+			continue
+		}
+		for i := 0; i+kmer <= len(c.data); i++ {
+			covered[binary.LittleEndian.Uint64(c.data[i:])] = struct{}{}
+		}
+		picked = append(picked, c.data)
+		total += len(c.data)
+	}
+	// Matches closer to the end of the dictionary are cheaper to encode, so put
+	// the best segments last.
+	dict := make([]byte, 0, total)
+	for i := len(picked) - 1; i >= 0; i-- {
+		dict = append(dict, picked[i]...)
+	}
+	// Fall back to the raw samples if nothing repeats, the dictionary can't be
+	// empty.
+	for i := len(samples) - 1; i >= 0 && len(dict) == 0; i-- {
+		dict = append(dict, samples[i][:min(len(samples[i]), size)]...)
+	}
+	if len(dict) == 0 {
+		dict = append(dict, 0)
+	}
+	return dict
+}
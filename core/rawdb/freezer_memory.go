@@ -210,23 +210,29 @@ func (b *memoryBatch) commit(freezer *MemoryFreezer) (items uint64, writeSize in
 // interface and can be used along with ephemeral key-value store.
 type MemoryFreezer struct {
 	items      uint64                  // Number of items stored
-	tail       uint64                  // Number of the first stored item in the freezer
+	tail       uint64                  // Number of the first stored item in the prunable tables
 	readonly   bool                    // Flag if the freezer is only for reading
 	lock       sync.RWMutex            // Lock to protect fields
 	tables     map[string]*memoryTable // Tables for storing everything
+	prunable   map[string]bool         // Tables which are truncated by TruncateTail
 	writeBatch *memoryBatch            // Pre-allocated write batch
 }
 
 // NewMemoryFreezer initializes an in-memory freezer instance.
-func NewMemoryFreezer(readonly bool, tableName map[string]bool) *MemoryFreezer {
-	tables := make(map[string]*memoryTable)
-	for name := range tableName {
+func NewMemoryFreezer(readonly bool, tableName map[string]freezerTableConfig) *MemoryFreezer {
+	var (
+		tables   = make(map[string]*memoryTable)
+		prunable = make(map[string]bool)
+	)
+	for name, config := range tableName {
 		tables[name] = newMemoryTable(name)
+		prunable[name] = config.prunable
 	}
 	return &MemoryFreezer{
 		writeBatch: newMemoryBatch(),
 		readonly:   readonly,
 		tables:     tables,
+		prunable:   prunable,
 	}
 }
 
@@ -368,7 +374,8 @@ func (f *MemoryFreezer) TruncateHead(items uint64) (uint64, error) {
 	return old, nil
 }
 
-// TruncateTail discards any recent data below the provided threshold number.
+// TruncateTail discards any recent data below the provided threshold number
+// from the prunable tables.
 func (f *MemoryFreezer) TruncateTail(tail uint64) (uint64, error) {
 	f.lock.Lock()
 	defer f.lock.Unlock()
@@ -380,7 +387,10 @@ func (f *MemoryFreezer) TruncateTail(tail uint64) (uint64, error) {
 	if old >= tail {
 		return old, nil
 	}
-	for _, table := range f.tables {
+	for name, table := range f.tables {
+		if !f.prunable[name] {
+			continue
+		}
 		if err := table.truncateTail(tail); err != nil {
 			return 0, err
 		}
@@ -28,9 +28,11 @@ import (
 	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
 )
 
 var (
@@ -98,11 +100,14 @@ type freezerTable struct {
 	// should never be lower than itemOffset.
 	itemHidden atomic.Uint64
 
-	noCompression bool // if true, disables snappy compression. Note: does not work retroactively
-	readonly      bool
-	maxFileSize   uint32 // Max file size for data-files
-	name          string
-	path          string
+	codec       freezerCodec          // Compression scheme of the items
+	encoder     *zstd.Encoder         // Dictionary encoder if the table uses zstd
+	decoder     *zstd.Decoder         // Dictionary decoder if the table uses zstd
+	cipher      *encryptdb.FileCipher // Cipher of the items, nil if not encrypted
+	readonly    bool
+	maxFileSize uint32 // Max file size for data-files
+	name        string
+	path        string
 
 	head   *os.File            // File descriptor for the data head of the table
 	index  *os.File            // File descriptor for the indexEntry file of the table
@@ -128,22 +133,32 @@ func newFreezerTable(path, name string, disableSnappy, readonly bool) (*freezerT
 // newTable opens a freezer table, creating the data and index files if they are
 // non-existent. Both files are truncated to the shortest common length to ensure
 // they don't go out of sync.
+// If the table has been migrated to a different codec, that one is used instead
+// of the configured one.
 func newTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, noCompression, readonly bool) (*freezerTable, error) {
+	return openTable(path, name, readMeter, writeMeter, sizeGauge, maxFilesize, detectCodec(path, name, noCompression), nil, readonly)
+}
+
+// openTable opens a freezer table using the given codec. If the cipher is set,
+// the items of the table are encrypted with it.
+func openTable(path string, name string, readMeter metrics.Meter, writeMeter metrics.Meter, sizeGauge metrics.Gauge, maxFilesize uint32, codec freezerCodec, cipher *encryptdb.FileCipher, readonly bool) (*freezerTable, error) {
 	// Ensure the containing directory exists and open the indexEntry file
 	if err := os.MkdirAll(path, 0755); err != nil {
 		return nil, err
 	}
-	var idxName string
-	if noCompression {
-		idxName = fmt.Sprintf("%s.ridx", name) // raw index file
-	} else {
-		idxName = fmt.Sprintf("%s.cidx", name) // compressed index file
-	}
 	var (
-		err   error
-		index *os.File
-		meta  *os.File
+		idxName = codec.indexFile(name)
+		err     error
+		index   *os.File
+		meta    *os.File
+		encoder *zstd.Encoder
+		decoder *zstd.Decoder
 	)
+	if codec == codecZstd {
+		if encoder, decoder, err = loadDictionary(path, name, cipher); err != nil {
+			return nil, err
+		}
+	}
 	if readonly {
 		// Will fail if table index file or meta file is not existent
 		index, err = openFreezerFileForReadOnly(filepath.Join(path, idxName))
@@ -166,18 +181,21 @@ func newTable(path string, name string, readMeter metrics.Meter, writeMeter metr
 	}
 	// Create the table and repair any past inconsistency
 	tab := &freezerTable{
-		index:         index,
-		meta:          meta,
-		files:         make(map[uint32]*os.File),
-		readMeter:     readMeter,
-		writeMeter:    writeMeter,
-		sizeGauge:     sizeGauge,
-		name:          name,
-		path:          path,
-		logger:        log.New("database", path, "table", name),
-		noCompression: noCompression,
-		readonly:      readonly,
-		maxFileSize:   maxFilesize,
+		index:       index,
+		meta:        meta,
+		files:       make(map[uint32]*os.File),
+		readMeter:   readMeter,
+		writeMeter:  writeMeter,
+		sizeGauge:   sizeGauge,
+		name:        name,
+		path:        path,
+		logger:      log.New("database", path, "table", name),
+		codec:       codec,
+		encoder:     encoder,
+		decoder:     decoder,
+		cipher:      cipher,
+		readonly:    readonly,
+		maxFileSize: maxFilesize,
 	}
 	if err := tab.repair(); err != nil {
 		tab.Close()
@@ -625,6 +643,10 @@ func (t *freezerTable) Close() error {
 	t.meta = nil
 	t.head = nil
 
+	if t.codec == codecZstd {
+		t.encoder.Close()
+		t.decoder.Close()
+	}
 	if errs != nil {
 		return fmt.Errorf("%v", errs)
 	}
@@ -635,13 +657,7 @@ func (t *freezerTable) Close() error {
 func (t *freezerTable) openFile(num uint32, opener func(string) (*os.File, error)) (f *os.File, err error) {
 	var exist bool
 	if f, exist = t.files[num]; !exist {
-		var name string
-		if t.noCompression {
-			name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
-		} else {
-			name = fmt.Sprintf("%s.%04d.cdat", t.name, num)
-		}
-		f, err = opener(filepath.Join(t.path, name))
+		f, err = opener(filepath.Join(t.path, t.codec.dataFile(t.name, num)))
 		if err != nil {
 			return nil, err
 		}
@@ -751,22 +767,32 @@ func (t *freezerTable) RetrieveItems(start, count, maxBytes uint64) ([][]byte, e
 	for i, diskSize := range sizes {
 		item := diskData[offset : offset+diskSize]
 		offset += diskSize
-		decompressedSize := diskSize
-		if !t.noCompression {
-			decompressedSize, _ = snappy.DecodedLen(item)
+		if t.cipher != nil {
+			data, err := t.cipher.Open(nil, item, t.itemPosition(start+uint64(i)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt item %d: %w", start+uint64(i), err)
+			}
+			item = data
 		}
+		decompressedSize := t.codec.decodedLen(item)
 		if i > 0 && maxBytes != 0 && uint64(outputSize+decompressedSize) > maxBytes {
 			break
 		}
-		if !t.noCompression {
+		switch t.codec {
+		case codecSnappy:
 			data, err := snappy.Decode(nil, item)
 			if err != nil {
 				return nil, err
 			}
-			output = append(output, data)
-		} else {
-			output = append(output, item)
+			item = data
+		case codecZstd:
+			data, err := t.decoder.DecodeAll(item, nil)
+			if err != nil {
+				return nil, err
+			}
+			item = data
 		}
+		output = append(output, item)
 		outputSize += decompressedSize
 	}
 	return output, nil
@@ -810,7 +836,8 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 		if !exist {
 			return fmt.Errorf("missing data file %d", fileId)
 		}
-		if _, err := dataFile.ReadAt(output[len(output)-length:], int64(start)); err != nil {
+		data := output[len(output)-length:]
+		if _, err := dataFile.ReadAt(data, int64(start)); err != nil {
 			return fmt.Errorf("%w, fileid: %d, start: %d, length: %d", err, fileId, start, length)
 		}
 		return nil
@@ -871,6 +898,11 @@ func (t *freezerTable) retrieveItems(start, count, maxBytes uint64) ([]byte, []i
 	return output, sizes, nil
 }
 
+// itemPosition returns the position an encrypted item is bound to.
+func (t *freezerTable) itemPosition(item uint64) []byte {
+	return binary.BigEndian.AppendUint64([]byte(t.name), item)
+}
+
 // has returns an indicator whether the specified number data is still accessible
 // in the freezer table.
 func (t *freezerTable) has(number uint64) bool {
@@ -27,13 +27,16 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/rawdb/ancienttest"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
+	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/stretchr/testify/require"
 )
 
-var freezerTestTableDef = map[string]bool{"test": true}
+var freezerTestTableDef = map[string]freezerTableConfig{"test": {noSnappy: true, prunable: true}}
 
 func TestFreezerModify(t *testing.T) {
 	t.Parallel()
@@ -49,7 +52,7 @@ func TestFreezerModify(t *testing.T) {
 		valuesRLP = append(valuesRLP, iv)
 	}
 
-	tables := map[string]bool{"raw": true, "rlp": false}
+	tables := map[string]freezerTableConfig{"raw": {noSnappy: true, prunable: true}, "rlp": {noSnappy: false, prunable: true}}
 	f, _ := newFreezerForTesting(t, tables)
 	defer f.Close()
 
@@ -113,7 +116,7 @@ func TestFreezerModifyRollback(t *testing.T) {
 	f.Close()
 
 	// Reopen and check that the rolled-back data doesn't reappear.
-	tables := map[string]bool{"test": true}
+	tables := map[string]freezerTableConfig{"test": {noSnappy: true, prunable: true}}
 	f2, err := NewFreezer(dir, "", false, 2049, tables)
 	if err != nil {
 		t.Fatalf("can't reopen freezer after failed ModifyAncients: %v", err)
@@ -251,7 +254,7 @@ func TestFreezerConcurrentModifyTruncate(t *testing.T) {
 }
 
 func TestFreezerReadonlyValidate(t *testing.T) {
-	tables := map[string]bool{"a": true, "b": true}
+	tables := map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}, "b": {noSnappy: true, prunable: true}}
 	dir := t.TempDir()
 	// Open non-readonly freezer and fill individual tables
 	// with different amount of data.
@@ -287,7 +290,7 @@ func TestFreezerReadonlyValidate(t *testing.T) {
 func TestFreezerConcurrentReadonly(t *testing.T) {
 	t.Parallel()
 
-	tables := map[string]bool{"a": true}
+	tables := map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}}
 	dir := t.TempDir()
 
 	f, err := NewFreezer(dir, "", false, 2049, tables)
@@ -335,7 +338,7 @@ func TestFreezerConcurrentReadonly(t *testing.T) {
 	}
 }
 
-func newFreezerForTesting(t *testing.T, tables map[string]bool) (*Freezer, string) {
+func newFreezerForTesting(t *testing.T, tables map[string]freezerTableConfig) (*Freezer, string) {
 	t.Helper()
 
 	dir := t.TempDir()
@@ -462,7 +465,7 @@ func TestRenameWindows(t *testing.T) {
 
 func TestFreezerCloseSync(t *testing.T) {
 	t.Parallel()
-	f, _ := newFreezerForTesting(t, map[string]bool{"a": true, "b": true})
+	f, _ := newFreezerForTesting(t, map[string]freezerTableConfig{"a": {noSnappy: true, prunable: true}, "b": {noSnappy: true, prunable: true}})
 	defer f.Close()
 
 	// Now, close and sync. This mimics the behaviour if the node is shut down,
@@ -482,21 +485,317 @@ func TestFreezerCloseSync(t *testing.T) {
 	}
 }
 
+func TestFreezerTruncateTailNonPrunable(t *testing.T) {
+	t.Parallel()
+	tables := map[string]freezerTableConfig{"a": {noSnappy: true}, "b": {noSnappy: true, prunable: true}}
+	f, dir := newFreezerForTesting(t, tables)
+
+	_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 10; i++ {
+			if err := op.AppendRaw("a", i, []byte{byte(i)}); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, []byte{byte(i)}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	old, err := f.TruncateTail(5)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), old)
+
+	check := func(f *Freezer) {
+		t.Helper()
+		tail, err := f.Tail()
+		require.NoError(t, err)
+		require.Equal(t, uint64(5), tail)
+
+		if _, err := f.Ancient("a", 0); err != nil {
+			t.Fatalf("non-prunable table lost item 0: %v", err)
+		}
+		if _, err := f.Ancient("b", 0); err == nil {
+			t.Fatal("prunable table retained item 0")
+		}
+		checkAncientCount(t, f, "a", 10)
+		checkAncientCount(t, f, "b", 10)
+	}
+	check(f)
+
+	// The tail should only be validated against the prunable tables on reopen.
+	require.NoError(t, f.Close())
+	f, err = NewFreezer(dir, "", false, 2049, tables)
+	require.NoError(t, err)
+	defer f.Close()
+	check(f)
+}
+
+func TestFreezerMigrateCodec(t *testing.T) {
+	t.Parallel()
+	tables := map[string]freezerTableConfig{"a": {noSnappy: false}}
+	f, dir := newFreezerForTesting(t, tables)
+
+	item := func(i uint64) []byte {
+		return bytes.Repeat([]byte(fmt.Sprintf("receipt %d, log topic 0xddf252ad;", i%7)), 4)
+	}
+	appendItems := func(f *Freezer, from, to uint64) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				if err := op.AppendRaw("a", i, item(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	checkItems := func(f *Freezer, n uint64) {
+		t.Helper()
+		checkAncientCount(t, f, "a", n)
+		for i := uint64(0); i < n; i++ {
+			blob, err := f.Ancient("a", i)
+			require.NoError(t, err)
+			require.Equal(t, item(i), blob, "item %d", i)
+		}
+	}
+	appendItems(f, 0, 100)
+
+	// Simulate an interrupted migration by building part of the new table.
+	migration := migrationDir(dir, "a")
+	require.NoError(t, trainTableDictionary(f.tables["a"], migration))
+	partial, err := openTable(migration, "a", metrics.NilMeter{}, metrics.NilMeter{}, metrics.NilGauge{}, 2049, codecZstd, nil, false)
+	require.NoError(t, err)
+	batch := partial.newBatch()
+	for i := uint64(0); i < 40; i++ {
+		require.NoError(t, batch.AppendRaw(i, item(i)))
+	}
+	require.NoError(t, batch.commit())
+	require.NoError(t, partial.Close())
+
+	require.NoError(t, f.migrateTableCodec("a", codecZstd))
+	require.Equal(t, codecZstd, f.tables["a"].codec)
+	checkItems(f, 100)
+
+	for _, pattern := range []string{"a.cidx", "a.*.cdat", "migration"} {
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		require.NoError(t, err)
+		require.Empty(t, files, "stale files %v", pattern)
+	}
+	// New items are appended with the migrated codec, also after reopening.
+	appendItems(f, 100, 120)
+	require.NoError(t, f.Close())
+	f, err = NewFreezer(dir, "", false, 2049, tables)
+	require.NoError(t, err)
+	require.Equal(t, codecZstd, f.tables["a"].codec)
+	checkItems(f, 120)
+
+	// Migrate back to the configured codec.
+	require.NoError(t, f.migrateTableCodec("a", codecSnappy))
+	require.Equal(t, codecSnappy, f.tables["a"].codec)
+	checkItems(f, 120)
+	require.NoError(t, f.Close())
+	require.False(t, common.FileExist(filepath.Join(dir, "a.dict")))
+}
+
+// Tests migrating a tail-deleted table, and completing a migration interrupted
+// while switching the tables.
+func TestFreezerMigrateTail(t *testing.T) {
+	t.Parallel()
+	tables := map[string]freezerTableConfig{"a": {noSnappy: false, prunable: true}, "b": {noSnappy: false, prunable: true}}
+	f, dir := newFreezerForTesting(t, tables)
+
+	item := func(i uint64) []byte {
+		return bytes.Repeat([]byte(fmt.Sprintf("block body %d;", i%5)), 8)
+	}
+	_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 100; i++ {
+			if err := op.AppendRaw("a", i, item(i)); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, item(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	_, err = f.TruncateTail(30)
+	require.NoError(t, err)
+
+	checkItems := func(f *Freezer) {
+		t.Helper()
+		tail, err := f.Tail()
+		require.NoError(t, err)
+		require.Equal(t, uint64(30), tail)
+		checkAncientCount(t, f, "a", 100)
+		if _, err := f.Ancient("a", 29); err == nil {
+			t.Fatal("deleted item 29 is retrievable")
+		}
+		for i := uint64(30); i < 100; i++ {
+			blob, err := f.Ancient("a", i)
+			require.NoError(t, err)
+			require.Equal(t, item(i), blob, "item %d", i)
+		}
+	}
+	require.NoError(t, f.migrateTableCodec("a", codecZstd))
+	require.Equal(t, codecZstd, f.tables["a"].codec)
+	checkItems(f)
+
+	// The size limit is checked against the decompressed items.
+	size := uint64(len(item(0)))
+	items, err := f.tables["a"].RetrieveItems(30, 10, 2*size+size/2)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+
+	// Leave a partial migration of another table behind.
+	other, err := openMigrationTable(f.tables["b"], migrationDir(dir, "b"), codecNone)
+	require.NoError(t, err)
+	batch := other.newBatch()
+	for i := uint64(30); i < 50; i++ {
+		require.NoError(t, batch.AppendRaw(i, item(i)))
+	}
+	require.NoError(t, batch.commit())
+	require.NoError(t, other.Close())
+
+	// Build the migrated table and interrupt the switch after moving a data
+	// file, but before the index file.
+	migration := migrationDir(dir, "a")
+	table, err := openMigrationTable(f.tables["a"], migration, codecSnappy)
+	require.NoError(t, err)
+	batch = table.newBatch()
+	for i := uint64(30); i < 100; i++ {
+		require.NoError(t, batch.AppendRaw(i, item(i)))
+	}
+	require.NoError(t, batch.commit())
+	require.NoError(t, table.Close())
+	require.NoError(t, writeMigrationMarker(migration, "a", codecSnappy))
+	require.NoError(t, f.Close())
+
+	data := codecSnappy.dataFile("a", 0)
+	require.NoError(t, os.Rename(filepath.Join(migration, data), filepath.Join(dir, data)))
+
+	// The switch is completed when the freezer is opened.
+	f, err = NewFreezer(dir, "", false, 2049, tables)
+	require.NoError(t, err)
+	defer f.Close()
+	require.Equal(t, codecSnappy, f.tables["a"].codec)
+	checkItems(f)
+	for _, pattern := range []string{"a.zidx", "a.*.zdat", "a.dict", "migration/a"} {
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		require.NoError(t, err)
+		require.Empty(t, files, "stale files %v", pattern)
+	}
+	// The partial migration of the other table is kept, and resumed.
+	other, err = openMigrationTable(f.tables["b"], migrationDir(dir, "b"), codecNone)
+	require.NoError(t, err)
+	require.Equal(t, uint64(50), other.items.Load())
+	require.NoError(t, other.Close())
+
+	require.NoError(t, f.migrateTableCodec("b", codecNone))
+	require.Equal(t, codecNone, f.tables["b"].codec)
+	for i := uint64(30); i < 100; i++ {
+		blob, err := f.Ancient("b", i)
+		require.NoError(t, err)
+		require.Equal(t, item(i), blob, "item %d", i)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "migration"))
+	require.NoError(t, err)
+	require.Empty(t, files, "stale migration dir")
+}
+
+// Tests that the data files of an encrypted freezer don't contain the items in
+// plain, also after truncating and migrating tables.
+func TestFreezerEncryption(t *testing.T) {
+	t.Parallel()
+	key, err := encryptdb.NewKey(bytes.Repeat([]byte{0x1}, encryptdb.KeyLength))
+	require.NoError(t, err)
+
+	var (
+		dir    = t.TempDir()
+		tables = map[string]freezerTableConfig{"a": {noSnappy: true}}
+		item   = func(i uint64) []byte { return []byte(fmt.Sprintf("plain item %d", i)) }
+	)
+	f, err := newFreezer(dir, "", false, 2049, tables, key.FileCipher())
+	require.NoError(t, err)
+
+	appendItems := func(from, to uint64) {
+		t.Helper()
+		_, err := f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+			for i := from; i < to; i++ {
+				if err := op.AppendRaw("a", i, item(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	checkItems := func(n uint64) {
+		t.Helper()
+		checkAncientCount(t, f, "a", n)
+		for i := uint64(0); i < n; i++ {
+			blob, err := f.Ancient("a", i)
+			require.NoError(t, err)
+			require.Equal(t, item(i), blob, "item %d", i)
+		}
+		files, err := filepath.Glob(filepath.Join(dir, "a.*"))
+		require.NoError(t, err)
+		for _, file := range files {
+			blob, err := os.ReadFile(file)
+			require.NoError(t, err)
+			require.NotContains(t, string(blob), "plain item", "plain data in %s", file)
+		}
+	}
+	appendItems(0, 300)
+	checkItems(300)
+
+	// Rewrite the truncated items differently, spanning multiple files.
+	_, err = f.TruncateHead(150)
+	require.NoError(t, err)
+	appendItems(150, 300)
+	checkItems(300)
+
+	require.NoError(t, f.migrateTableCodec("a", codecZstd))
+	checkItems(300)
+	require.NoError(t, f.Close())
+
+	f, err = newFreezer(dir, "", false, 2049, tables, key.FileCipher())
+	require.NoError(t, err)
+	checkItems(300)
+	require.NoError(t, f.Close())
+
+	// Tampered items are detected.
+	file := filepath.Join(dir, codecZstd.dataFile("a", 0))
+	blob, err := os.ReadFile(file)
+	require.NoError(t, err)
+	blob[len(blob)/2] ^= 0x1
+	require.NoError(t, os.WriteFile(file, blob, 0644))
+
+	f, err = newFreezer(dir, "", false, 2049, tables, key.FileCipher())
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = f.AncientRange("a", 0, 300, 0)
+	require.ErrorContains(t, err, "failed to decrypt item")
+}
+
 func TestFreezerSuite(t *testing.T) {
 	ancienttest.TestAncientSuite(t, func(kinds []string) ethdb.AncientStore {
-		tables := make(map[string]bool)
+		tables := make(map[string]freezerTableConfig)
 		for _, kind := range kinds {
-			tables[kind] = true
+			tables[kind] = freezerTableConfig{noSnappy: true, prunable: true}
 		}
 		f, _ := newFreezerForTesting(t, tables)
 		return f
 	})
 	ancienttest.TestResettableAncientSuite(t, func(kinds []string) ethdb.ResettableAncientStore {
-		tables := make(map[string]bool)
+		tables := make(map[string]freezerTableConfig)
 		for _, kind := range kinds {
-			tables[kind] = true
+			tables[kind] = freezerTableConfig{noSnappy: true, prunable: true}
 		}
-		f, _ := newResettableFreezer(t.TempDir(), "", false, 2048, tables)
+		f, _ := newResettableFreezer(t.TempDir(), "", false, 2048, tables, nil)
 		return f
 	})
 }
@@ -0,0 +1,58 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package state
+
+import (
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// AccessSet returns the accounts, storage slots and contract codes the state
+// has accessed since it was opened, sorted by address and slot. Accounts that
+// were deleted since are included, as they were accessed before.
+//
+// The set is derived from the cached state objects, so it must be retrieved
+// before the state is committed.
+func (s *StateDB) AccessSet() *types.BlockAccessSet {
+	addrs := make(map[common.Address]struct{}, len(s.stateObjects)+len(s.stateObjectsDestruct))
+	for addr := range s.stateObjects {
+		addrs[addr] = struct{}{}
+	}
+	for addr := range s.stateObjectsDestruct {
+		addrs[addr] = struct{}{}
+	}
+	for addr := range s.mutations {
+		addrs[addr] = struct{}{}
+	}
+	set := &types.BlockAccessSet{Accounts: make([]types.AccountAccess, 0, len(addrs))}
+	for addr := range addrs {
+		access := types.AccountAccess{Address: addr}
+		if obj := s.stateObjects[addr]; obj != nil {
+			// Code deployed within the block is not in the database yet
+			access.Code = obj.code != nil && !obj.dirtyCode
+			for slot := range obj.originStorage {
+				access.Slots = append(access.Slots, slot)
+			}
+			slices.SortFunc(access.Slots, func(a, b common.Hash) int { return a.Cmp(b) })
+		}
+		set.Accounts = append(set.Accounts, access)
+	}
+	slices.SortFunc(set.Accounts, func(a, b types.AccountAccess) int { return a.Address.Cmp(b.Address) })
+	return set
+}
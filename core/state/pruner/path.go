@@ -0,0 +1,487 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/database"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+// PathConfig includes the configurations for pruning the path scheme state.
+type PathConfig struct {
+	Rebuild bool // Whether to regenerate the trie nodes from the snapshot first
+}
+
+// PathPruner is an offline tool to remove the stale state of the path scheme.
+// The path scheme deletes obsolete trie nodes by itself, but nodes can still
+// be left behind by past bugs or aborted syncs. The workflow is:
+//
+//   - flush the state layers of pathdb, so the persistent state is the head one
+//   - optionally regenerate all trie nodes from the snapshot, repairing the
+//     missing or corrupted ones
+//   - iterate the persistent trie alongside the stored trie nodes, which are
+//     both ordered by path, deleting the nodes which are not part of the trie
+//     as well as the storage tries of absent accounts
+//   - verify the whole state against the state root
+type PathPruner struct {
+	config PathConfig
+	head   *types.Header
+	db     ethdb.Database
+}
+
+// NewPathPruner creates the pruner for the path scheme state of the database.
+func NewPathPruner(db ethdb.Database, config PathConfig) (*PathPruner, error) {
+	if scheme := rawdb.ReadStateScheme(db); scheme != rawdb.PathScheme {
+		return nil, fmt.Errorf("state scheme is %q, not %q", scheme, rawdb.PathScheme)
+	}
+	headBlock := rawdb.ReadHeadBlock(db)
+	if headBlock == nil {
+		return nil, errors.New("failed to load head block")
+	}
+	return &PathPruner{
+		config: config,
+		head:   headBlock.Header(),
+		db:     db,
+	}, nil
+}
+
+// Prune removes the stale trie nodes and orphaned storage tries.
+func (p *PathPruner) Prune() error {
+	start := time.Now()
+
+	// Flatten the state layers onto the disk layer, so that the persistent
+	// state matches the head block. The stale nodes are determined against
+	// the persistent state, so pruning must not proceed if it fails.
+	tdb := triedb.NewDatabase(p.db, &triedb.Config{PathDB: pathdb.Defaults})
+	defer tdb.Close()
+
+	root := p.persistentRoot()
+	if root != p.head.Root {
+		if err := tdb.Commit(p.head.Root, false); err != nil {
+			return fmt.Errorf("failed to flush state layers of head state %x: %w", p.head.Root, err)
+		}
+		if err := tdb.Journal(p.head.Root); err != nil {
+			return err
+		}
+		if root = p.persistentRoot(); root != p.head.Root {
+			return fmt.Errorf("persistent state %x doesn't match head state %x", root, p.head.Root)
+		}
+	}
+	if p.config.Rebuild {
+		if err := p.rebuild(tdb, root); err != nil {
+			return err
+		}
+	}
+	stats, err := p.walk(root, true)
+	if err != nil {
+		return err
+	}
+	log.Info("Pruned stale state", "nodes", stats.nodes, "stale", stats.stale, "orphans", stats.orphans, "size", stats.size, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Compact the trie node ranges if enough data was deleted.
+	if stats.stale+stats.orphans >= rangeCompactionThreshold {
+		for _, prefix := range [][]byte{rawdb.TrieNodeAccountPrefix, rawdb.TrieNodeStoragePrefix} {
+			cstart := time.Now()
+			limit := []byte{prefix[0] + 1}
+			log.Info("Compacting database", "range", fmt.Sprintf("%#x-%#x", prefix, limit), "elapsed", common.PrettyDuration(time.Since(start)))
+			if err := p.db.Compact(prefix, limit); err != nil {
+				log.Error("Database compaction failed", "error", err)
+				return err
+			}
+			log.Info("Compacted database range", "elapsed", common.PrettyDuration(time.Since(cstart)))
+		}
+	}
+	// Verify that the whole state is intact.
+	vstart := time.Now()
+	if stats, err = p.walk(root, false); err != nil {
+		return fmt.Errorf("state verification failed: %w", err)
+	}
+	log.Info("Verified state", "root", root, "nodes", stats.nodes, "elapsed", common.PrettyDuration(time.Since(vstart)))
+	return nil
+}
+
+// persistentRoot returns the root of the persistent state.
+func (p *PathPruner) persistentRoot() common.Hash {
+	if blob := rawdb.ReadAccountTrieNode(p.db, nil); len(blob) > 0 {
+		return crypto.Keccak256Hash(blob)
+	}
+	return types.EmptyRootHash
+}
+
+// rebuild regenerates all trie nodes of the state from the snapshot. The
+// snapshot is verified to reproduce the state root first, so the trie is
+// never overwritten with mismatching nodes.
+func (p *PathPruner) rebuild(tdb *triedb.Database, root common.Hash) error {
+	snapconfig := snapshot.Config{
+		CacheSize:  256,
+		Recovery:   false,
+		NoBuild:    true,
+		AsyncBuild: false,
+	}
+	snaptree, err := snapshot.New(snapconfig, p.db, tdb, p.head.Root)
+	if err != nil {
+		return err // The relevant snapshot(s) might not exist
+	}
+	defer snaptree.Release()
+
+	log.Info("Verifying snapshot against state root", "root", root)
+	if err := snapshot.GenerateTrie(snaptree, root, p.db, discardWriter{}); err != nil {
+		return err
+	}
+	log.Info("Regenerating trie nodes from snapshot", "root", root)
+	batch := &lockedBatch{db: p.db, batch: p.db.NewBatch()}
+	if err := snapshot.GenerateTrie(snaptree, root, p.db, batch); err != nil {
+		return err
+	}
+	return batch.flush()
+}
+
+// pathPruneStats is a collection of statistics gathered while walking the state.
+type pathPruneStats struct {
+	nodes   int                // Number of trie nodes of the state
+	stale   int                // Number of deleted nodes of existing tries
+	orphans int                // Number of deleted nodes of absent storage tries
+	size    common.StorageSize // Size of the deleted nodes
+}
+
+// walk iterates over all trie nodes of the state. If prune is set, the stored
+// nodes are iterated alongside, and the ones not visited are deleted.
+func (p *PathPruner) walk(root common.Hash, prune bool) (*pathPruneStats, error) {
+	var (
+		stats   = new(pathPruneStats)
+		reader  = &diskDatabase{db: p.db}
+		batch   = p.db.NewBatch()
+		start   = time.Now()
+		logged  = time.Now()
+		account *sweeper
+		storage *sweeper
+	)
+	if prune {
+		account = newSweeper(p.db, rawdb.TrieNodeAccountPrefix, batch)
+		defer account.release()
+		storage = newSweeper(p.db, rawdb.TrieNodeStoragePrefix, batch)
+		defer storage.release()
+	}
+	if root == types.EmptyRootHash {
+		if !prune {
+			return stats, nil
+		}
+		if err := account.drop(nil); err != nil {
+			return nil, err
+		}
+		if err := storage.drop(nil); err != nil {
+			return nil, err
+		}
+		stats.stale, stats.orphans = account.deleted, storage.deleted
+		stats.size = account.size + storage.size
+		return stats, batch.Write()
+	}
+	t, err := trie.New(trie.StateTrieID(root), reader)
+	if err != nil {
+		return nil, err
+	}
+	accIter, err := t.NodeIterator(nil)
+	if err != nil {
+		return nil, err
+	}
+	for accIter.Next(true) {
+		if accIter.Hash() != (common.Hash{}) {
+			stats.nodes++
+			if prune {
+				if err := account.keep(accountNodeKey(accIter.Path())); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if !accIter.Leaf() {
+			continue
+		}
+		var acc types.StateAccount
+		if err := rlp.DecodeBytes(accIter.LeafBlob(), &acc); err != nil {
+			return nil, fmt.Errorf("invalid account %x: %w", accIter.LeafKey(), err)
+		}
+		if acc.Root == types.EmptyRootHash {
+			continue
+		}
+		owner := common.BytesToHash(accIter.LeafKey())
+		if prune {
+			// Storage tries of the accounts preceding this one are orphaned.
+			orphans := storage.deleted
+			if err := storage.drop(storageNodeKey(owner, nil)); err != nil {
+				return nil, err
+			}
+			stats.orphans += storage.deleted - orphans
+		}
+		st, err := trie.New(trie.StorageTrieID(root, owner, acc.Root), reader)
+		if err != nil {
+			return nil, err
+		}
+		storageIter, err := st.NodeIterator(nil)
+		if err != nil {
+			return nil, err
+		}
+		for storageIter.Next(true) {
+			if storageIter.Hash() == (common.Hash{}) {
+				continue
+			}
+			stats.nodes++
+			if prune {
+				if err := storage.keep(storageNodeKey(owner, storageIter.Path())); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := storageIter.Error(); err != nil {
+			return nil, fmt.Errorf("storage trie of %x: %w", owner, err)
+		}
+		if prune {
+			// Nodes of the storage trie beyond the last visited one are stale.
+			stale := storage.deleted
+			if err := storage.dropPrefix(storageNodeKey(owner, nil)); err != nil {
+				return nil, err
+			}
+			stats.stale += storage.deleted - stale
+		}
+		if time.Since(logged) > 8*time.Second {
+			var deleted int
+			if prune {
+				deleted = account.deleted + storage.deleted
+			}
+			log.Info("Iterating state", "at", owner, "nodes", stats.nodes, "deleted", deleted, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := accIter.Error(); err != nil {
+		return nil, err
+	}
+	if !prune {
+		return stats, nil
+	}
+	orphans := storage.deleted
+	if err := storage.drop(nil); err != nil {
+		return nil, err
+	}
+	stats.orphans += storage.deleted - orphans
+	if err := account.drop(nil); err != nil {
+		return nil, err
+	}
+	stats.stale += account.deleted
+	stats.size = account.size + storage.size
+	return stats, batch.Write()
+}
+
+// accountNodeKey returns the database key of an account trie node.
+func accountNodeKey(path []byte) []byte {
+	key := make([]byte, 0, len(rawdb.TrieNodeAccountPrefix)+len(path))
+	return append(append(key, rawdb.TrieNodeAccountPrefix...), path...)
+}
+
+// storageNodeKey returns the database key of a storage trie node.
+func storageNodeKey(owner common.Hash, path []byte) []byte {
+	key := make([]byte, 0, len(rawdb.TrieNodeStoragePrefix)+common.HashLength+len(path))
+	return append(append(append(key, rawdb.TrieNodeStoragePrefix...), owner.Bytes()...), path...)
+}
+
+// sweeper iterates over a range of stored trie nodes alongside the trie nodes
+// visited in path order, deleting the stored ones which are skipped.
+type sweeper struct {
+	it    ethdb.Iterator
+	valid bool // Whether the iterator is positioned at an entry
+	batch ethdb.Batch
+
+	deleted int
+	size    common.StorageSize
+}
+
+// newSweeper creates a sweeper over the stored entries with the given prefix.
+func newSweeper(db ethdb.Database, prefix []byte, batch ethdb.Batch) *sweeper {
+	s := &sweeper{it: db.NewIterator(prefix, nil), batch: batch}
+	s.valid = s.it.Next()
+	return s
+}
+
+// delete removes the current entry and advances the iterator.
+func (s *sweeper) delete() error {
+	s.deleted++
+	s.size += common.StorageSize(len(s.it.Key()) + len(s.it.Value()))
+	if err := s.batch.Delete(s.it.Key()); err != nil {
+		return err
+	}
+	if s.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := s.batch.Write(); err != nil {
+			return err
+		}
+		s.batch.Reset()
+	}
+	s.valid = s.it.Next()
+	return nil
+}
+
+// keep deletes the stored entries preceding the given key, and skips the given
+// key itself if it's stored.
+func (s *sweeper) keep(key []byte) error {
+	for s.valid {
+		switch cmp := bytes.Compare(s.it.Key(), key); {
+		case cmp < 0:
+			if err := s.delete(); err != nil {
+				return err
+			}
+		case cmp == 0:
+			s.valid = s.it.Next()
+			return nil
+		default:
+			return nil
+		}
+	}
+	return s.it.Error()
+}
+
+// drop deletes the stored entries preceding the given key, or all remaining
+// ones if it's nil.
+func (s *sweeper) drop(limit []byte) error {
+	for s.valid && (limit == nil || bytes.Compare(s.it.Key(), limit) < 0) {
+		if err := s.delete(); err != nil {
+			return err
+		}
+	}
+	return s.it.Error()
+}
+
+// dropPrefix deletes the following stored entries with the given prefix.
+func (s *sweeper) dropPrefix(prefix []byte) error {
+	for s.valid && bytes.HasPrefix(s.it.Key(), prefix) {
+		if err := s.delete(); err != nil {
+			return err
+		}
+	}
+	return s.it.Error()
+}
+
+// release releases the iterator.
+func (s *sweeper) release() {
+	s.it.Release()
+}
+
+// diskDatabase is a trie database serving the persistent trie nodes of the
+// path scheme, verifying them against the expected hashes.
+type diskDatabase struct {
+	db ethdb.KeyValueReader
+}
+
+// Reader returns the node reader of the persistent state, regardless of the
+// given root.
+func (d *diskDatabase) Reader(root common.Hash) (database.Reader, error) {
+	return d, nil
+}
+
+// Node retrieves the trie node with the given owner, path and hash.
+func (d *diskDatabase) Node(owner common.Hash, path []byte, hash common.Hash) ([]byte, error) {
+	var blob []byte
+	if owner == (common.Hash{}) {
+		blob = rawdb.ReadAccountTrieNode(d.db, path)
+	} else {
+		blob = rawdb.ReadStorageTrieNode(d.db, owner, path)
+	}
+	if len(blob) == 0 {
+		return nil, nil
+	}
+	if have := crypto.Keccak256Hash(blob); have != hash {
+		return nil, fmt.Errorf("unexpected trie node (owner %x, path %x): have %x, want %x", owner, path, have, hash)
+	}
+	return blob, nil
+}
+
+// Preimage is a noop, preimages aren't needed for iterating the state.
+func (d *diskDatabase) Preimage(hash common.Hash) []byte {
+	return nil
+}
+
+// InsertPreimage is a noop, the state is only read.
+func (d *diskDatabase) InsertPreimage(preimages map[common.Hash][]byte) {}
+
+// discardWriter is a key-value writer dropping all writes.
+type discardWriter struct{}
+
+func (discardWriter) Put(key []byte, value []byte) error { return nil }
+func (discardWriter) Delete(key []byte) error            { return nil }
+
+// lockedBatch is a key-value writer safe for concurrent use, which writes the
+// accumulated changes to the database whenever they reach the ideal batch size.
+type lockedBatch struct {
+	db    ethdb.Database
+	batch ethdb.Batch
+	lock  sync.Mutex
+}
+
+// Put inserts the given value into the batch.
+func (b *lockedBatch) Put(key []byte, value []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err := b.batch.Put(key, value); err != nil {
+		return err
+	}
+	return b.maybeWrite()
+}
+
+// Delete inserts the key removal into the batch.
+func (b *lockedBatch) Delete(key []byte) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if err := b.batch.Delete(key); err != nil {
+		return err
+	}
+	return b.maybeWrite()
+}
+
+// maybeWrite writes the batch if it reached the ideal size. The lock must be
+// held.
+func (b *lockedBatch) maybeWrite() error {
+	if b.batch.ValueSize() < ethdb.IdealBatchSize {
+		return nil
+	}
+	if err := b.batch.Write(); err != nil {
+		return err
+	}
+	b.batch.Reset()
+	return nil
+}
+
+// flush writes the remaining changes.
+func (b *lockedBatch) flush() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.batch.Write()
+}
@@ -0,0 +1,163 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
+)
+
+// newPathTestDatabase creates a path scheme database with some accounts and
+// storage slots. The state of the head block is journaled in the state layers,
+// on top of the persistent state of its parent.
+func newPathTestDatabase(t *testing.T) (ethdb.Database, []common.Address, common.Hash) {
+	var (
+		db    = rawdb.NewMemoryDatabase()
+		tdb   = triedb.NewDatabase(db, &triedb.Config{PathDB: pathdb.Defaults})
+		root  = types.EmptyRootHash
+		addrs []common.Address
+	)
+	for number := uint64(0); number < 2; number++ {
+		statedb, err := state.New(root, state.NewDatabaseWithNodeDB(db, tdb), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := len(addrs); i < int(number+1)*10; i++ {
+			addr := common.BytesToAddress([]byte{byte(i), 0x1})
+			statedb.SetBalance(addr, uint256.NewInt(uint64(i+1)), tracing.BalanceChangeUnspecified)
+			for j := 0; j < i%4*10; j++ {
+				statedb.SetState(addr, common.BigToHash(big.NewInt(int64(j))), common.BigToHash(big.NewInt(int64(i+j+1))))
+			}
+			addrs = append(addrs, addr)
+		}
+		if root, err = statedb.Commit(number, true); err != nil {
+			t.Fatal(err)
+		}
+		if number == 0 {
+			if err := tdb.Commit(root, false); err != nil {
+				t.Fatal(err)
+			}
+		}
+		block := types.NewBlockWithHeader(&types.Header{Number: new(big.Int).SetUint64(number), Root: root})
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), number)
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+	}
+	if err := tdb.Journal(root); err != nil {
+		t.Fatal(err)
+	}
+	tdb.Close()
+	return db, addrs, root
+}
+
+// Tests that pruning removes stale trie nodes and orphaned storage tries, but
+// keeps the head state intact.
+func TestPathPrune(t *testing.T) {
+	db, addrs, root := newPathTestDatabase(t)
+
+	// Seed stale nodes of the account trie and of an existing storage trie, as
+	// well as the storage trie of an absent account.
+	var (
+		junk    = []byte{0xde, 0xad}
+		owner   = crypto.Keccak256Hash(addrs[3].Bytes())
+		orphan  = common.Hash{0xff, 0xff}
+		stale   = []byte{0xf, 0xf, 0xf, 0xf, 0xf, 0xf}
+		orphans = [][]byte{nil, {0x1}, {0x1, 0x2}}
+	)
+	rawdb.WriteAccountTrieNode(db, stale, junk)
+	rawdb.WriteStorageTrieNode(db, owner, stale, junk)
+	for _, path := range orphans {
+		rawdb.WriteStorageTrieNode(db, orphan, path, junk)
+	}
+	pruner, err := NewPathPruner(db, PathConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(); err != nil {
+		t.Fatalf("Failed to prune: %v", err)
+	}
+	if rawdb.HasAccountTrieNode(db, stale) {
+		t.Error("Stale account trie node retained")
+	}
+	if rawdb.HasStorageTrieNode(db, owner, stale) {
+		t.Error("Stale storage trie node retained")
+	}
+	for _, path := range orphans {
+		if rawdb.HasStorageTrieNode(db, orphan, path) {
+			t.Errorf("Orphaned storage trie node %x retained", path)
+		}
+	}
+	// The head state was flushed to disk and is fully readable.
+	if have := pruner.persistentRoot(); have != root {
+		t.Fatalf("Persistent state mismatch: have %x, want %x", have, root)
+	}
+	if _, err := pruner.walk(root, false); err != nil {
+		t.Fatalf("State verification failed: %v", err)
+	}
+	tdb := triedb.NewDatabase(db, &triedb.Config{PathDB: pathdb.Defaults})
+	defer tdb.Close()
+	statedb, err := state.New(root, state.NewDatabaseWithNodeDB(db, tdb), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, addr := range addrs {
+		if balance := statedb.GetBalance(addr); balance.Uint64() != uint64(i+1) {
+			t.Errorf("Account %d balance mismatch: have %v", i, balance)
+		}
+		for j := 0; j < i%4*10; j++ {
+			want := common.BigToHash(big.NewInt(int64(i + j + 1)))
+			if have := statedb.GetState(addr, common.BigToHash(big.NewInt(int64(j)))); have != want {
+				t.Errorf("Account %d slot %d mismatch: have %x, want %x", i, j, have, want)
+			}
+		}
+	}
+}
+
+// Tests that pruning is aborted if the head state can't be flushed to disk.
+func TestPathPruneMissingHeadState(t *testing.T) {
+	db, _, _ := newPathTestDatabase(t)
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2), Root: common.Hash{0x1}})
+	rawdb.WriteBlock(db, block)
+	rawdb.WriteCanonicalHash(db, block.Hash(), 2)
+	rawdb.WriteHeadBlockHash(db, block.Hash())
+
+	stale := []byte{0xf, 0xf, 0xf, 0xf, 0xf, 0xf}
+	rawdb.WriteAccountTrieNode(db, stale, []byte{0xde, 0xad})
+
+	pruner, err := NewPathPruner(db, PathConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(); err == nil {
+		t.Fatal("Pruned without head state")
+	}
+	if !rawdb.HasAccountTrieNode(db, stale) {
+		t.Fatal("Nodes deleted by aborted pruning")
+	}
+}
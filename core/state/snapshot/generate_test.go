@@ -85,6 +85,38 @@ func testGeneration(t *testing.T, scheme string) {
 	<-stop
 }
 
+// Tests that checkpointing the snapshot during generation doesn't stop it.
+func TestGenerationCheckpoint(t *testing.T) {
+	var helper = newHelper(rawdb.HashScheme)
+	for i := 0; i < 1000; i++ {
+		helper.addTrieAccount(fmt.Sprintf("acc-%d", i), &types.StateAccount{Balance: uint256.NewInt(uint64(i)), Root: types.EmptyRootHash, CodeHash: types.EmptyCodeHash.Bytes()})
+	}
+	root, snap := helper.CommitAndGenerate()
+	snaps := &Tree{
+		diskdb: helper.diskdb,
+		triedb: helper.triedb,
+		layers: map[common.Hash]snapshot{root: snap},
+	}
+	if err := snaps.Checkpoint(root); err != nil {
+		t.Fatalf("Failed to checkpoint snapshot: %v", err)
+	}
+	if len(rawdb.ReadSnapshotJournal(helper.diskdb)) == 0 {
+		t.Fatal("Snapshot journal missing")
+	}
+	select {
+	case <-snap.genPending:
+		// Snapshot generation succeeded
+	case <-time.After(3 * time.Second):
+		t.Fatal("Snapshot generation failed")
+	}
+	checkSnapRoot(t, snap, root)
+
+	// Journalling for shutdown must still be possible afterwards.
+	if _, err := snaps.Journal(root); err != nil {
+		t.Fatalf("Failed to journal snapshot: %v", err)
+	}
+}
+
 // Tests that snapshot generation with existent flat state.
 func TestGenerateExistentState(t *testing.T) {
 	testGenerateExistentState(t, rawdb.HashScheme)
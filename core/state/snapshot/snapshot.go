@@ -714,6 +714,46 @@ func (t *Tree) Journal(root common.Hash) (common.Hash, error) {
 	return base, nil
 }
 
+// Checkpoint commits the diff hierarchy into the journal, like Journal, but keeps
+// the snapshot operational afterwards by resuming a pending generation. This is
+// meant to be used to back up the database of a running node.
+func (t *Tree) Checkpoint(root common.Hash) error {
+	// Suspend the generation, it would otherwise be stopped for good by the
+	// journalling.
+	t.lock.Lock()
+	var (
+		dl    = t.disklayer()
+		stats *generatorStats
+	)
+	if dl != nil && dl.genAbort != nil {
+		abort := make(chan *generatorStats)
+		dl.genAbort <- abort
+		stats = <-abort
+		dl.genAbort = nil
+	}
+	t.lock.Unlock()
+
+	_, err := t.Journal(root)
+
+	// Resume the generation where it left off, regardless of the journalling
+	// result.
+	if dl != nil && stats != nil {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+
+		dl.lock.RLock()
+		marker := dl.genMarker
+		dl.lock.RUnlock()
+
+		journalProgress(dl.diskdb, marker, stats)
+		if marker != nil && !dl.Stale() {
+			dl.genAbort = make(chan chan *generatorStats)
+			go dl.generate(stats)
+		}
+	}
+	return err
+}
+
 // Rebuild wipes all available snapshot data from the persistent database and
 // discard all caches and diff layers. Afterwards, it starts a new snapshot
 // generator with the given root hash.
@@ -1373,3 +1373,39 @@ func TestStorageDirtiness(t *testing.T) {
 	state.RevertToSnapshot(snap)
 	checkDirty(common.Hash{0x1}, common.Hash{0x1}, true)
 }
+
+func TestAccessSet(t *testing.T) {
+	var (
+		db       = NewDatabase(rawdb.NewMemoryDatabase())
+		state, _ = New(types.EmptyRootHash, db, nil)
+		a, b, c  = common.HexToAddress("0xa"), common.HexToAddress("0xb"), common.HexToAddress("0xc")
+		d, e     = common.HexToAddress("0xd"), common.HexToAddress("0xe")
+	)
+	state.SetBalance(a, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	state.SetCode(b, []byte{0x1})
+	state.SetState(b, common.Hash{0x1}, common.Hash{0x1})
+	state.SetState(b, common.Hash{0x2}, common.Hash{0x2})
+	state.SetBalance(c, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	root, _ := state.Commit(0, false)
+
+	// Access the state in all kinds of ways and check the recorded set
+	state, _ = New(root, db, nil)
+	state.GetBalance(a)
+	state.GetCode(b)
+	state.GetState(b, common.Hash{0x2})
+	state.SelfDestruct(c)
+	state.Finalise(true)
+	state.AddBalance(d, uint256.NewInt(1), tracing.BalanceChangeUnspecified)
+	state.SetCode(e, []byte{0x2})
+
+	want := &types.BlockAccessSet{Accounts: []types.AccountAccess{
+		{Address: a},
+		{Address: b, Code: true, Slots: []common.Hash{{0x2}}},
+		{Address: c},
+		{Address: d},
+		{Address: e},
+	}}
+	if have := state.AccessSet(); !reflect.DeepEqual(have, want) {
+		t.Fatalf("access set mismatch:\nhave %+v\nwant %+v", have, want)
+	}
+}
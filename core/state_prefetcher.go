@@ -17,10 +17,14 @@
 package core
 
 import (
+	"runtime"
+	"sync"
 	"sync/atomic"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/params"
@@ -92,3 +96,55 @@ func precacheTransaction(msg *Message, config *params.ChainConfig, gaspool *GasP
 	_, err := ApplyMessage(evm, msg, gaspool)
 	return err
 }
+
+// PrefetchAccessSet loads the state in a recorded block access set on top of
+// the given state root, with the goal of pulling the snapshot entries, trie
+// nodes and contract codes the block needs into the caches before the block is
+// executed. Contrary to Prefetch, no transactions are executed, so the accounts
+// and slots are loaded in parallel.
+func PrefetchAccessSet(set *types.BlockAccessSet, root common.Hash, db state.Database, snaps *snapshot.Tree, interrupt *atomic.Bool) {
+	var (
+		next atomic.Int64
+		wg   sync.WaitGroup
+	)
+	for i := 0; i < min(runtime.NumCPU(), len(set.Accounts)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// A snapshot backed state only warms the snapshot, load the state
+			// through the tries too to pull in the nodes needed for hashing.
+			var states []*state.StateDB
+			if snaps != nil {
+				if statedb, err := state.New(root, db, snaps); err == nil {
+					states = append(states, statedb)
+				}
+			}
+			if statedb, err := state.New(root, db, nil); err == nil {
+				states = append(states, statedb)
+			}
+			for {
+				if interrupt != nil && interrupt.Load() {
+					return
+				}
+				index := int(next.Add(1) - 1)
+				if index >= len(set.Accounts) {
+					return
+				}
+				access := set.Accounts[index]
+				for j, statedb := range states {
+					if !statedb.Exist(access.Address) {
+						break
+					}
+					if access.Code && j == 0 {
+						statedb.GetCode(access.Address)
+					}
+					for _, slot := range access.Slots {
+						statedb.GetCommittedState(access.Address, slot)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
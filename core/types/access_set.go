@@ -0,0 +1,44 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// BlockAccessSet is the set of state loaded from the database while executing
+// a block. It is recorded during block processing and used to prefetch the
+// state of the block, when the block is executed again or validated with the
+// set supplied by a peer or the consensus layer.
+type BlockAccessSet struct {
+	Accounts []AccountAccess `json:"accounts"`
+}
+
+// AccountAccess is the state of a single account accessed by a block.
+type AccountAccess struct {
+	Address common.Address `json:"address"`
+	Code    bool           `json:"code"`            // Whether the contract code was loaded
+	Slots   []common.Hash  `json:"slots,omitempty"` // Storage slots loaded
+}
+
+// Size returns the number of accounts and storage slots in the set.
+func (s *BlockAccessSet) Size() (accounts int, slots int) {
+	for _, acc := range s.Accounts {
+		slots += len(acc.Slots)
+	}
+	return len(s.Accounts), slots
+}
@@ -34,6 +34,7 @@ type Config struct {
 	EnablePreimageRecording bool  // Enables recording of SHA3/keccak preimages
 	ExtraEips               []int // Additional EIPS that are to be enabled
 	EnableWitnessCollection bool  // true if witness collection is enabled
+	OptimizedInterpreter    bool  // Executes analysed basic blocks with fused instructions, unless tracing
 }
 
 // ScopeContext contains the things that are per-call, such as stack and memory,
@@ -93,6 +94,8 @@ type EVMInterpreter struct {
 
 	readOnly   bool   // Whether to throw on stateful modifications
 	returnData []byte // Last CALL's return data for subsequent reuse
+
+	optimized bool // Whether code is executed by basic blocks
 }
 
 // NewEVMInterpreter returns a new instance of the Interpreter.
@@ -128,10 +131,17 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 	default:
 		table = &frontierInstructionSet
 	}
-	var extraEips []int
+	var (
+		extraEips []int
+		optimized = evm.Config.OptimizedInterpreter && !evm.chainRules.IsEIP4762 // verkle charges code chunks per opcode
+	)
 	if len(evm.Config.ExtraEips) > 0 {
 		// Deep-copy jumptable to prevent modification of opcodes in other tables
 		table = copyJumpTable(table)
+
+		// The block analysis is cached per instruction set, so it can only be
+		// used with the static ones
+		optimized = false
 	}
 	for _, eip := range evm.Config.ExtraEips {
 		if err := EnableEIP(eip, table); err != nil {
@@ -142,7 +152,7 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 		}
 	}
 	evm.Config.ExtraEips = extraEips
-	return &EVMInterpreter{evm: evm, table: table}
+	return &EVMInterpreter{evm: evm, table: table, optimized: optimized}
 }
 
 // Run loops and evaluates the contract's code with the given input data and returns
@@ -201,6 +211,13 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	}()
 	contract.Input = input
 
+	if in.optimized && !debug {
+		res, err = in.runProgram(in.program(contract), callContext)
+		if err == errStopToken {
+			err = nil // clear stop token error
+		}
+		return res, err
+	}
 	if debug {
 		defer func() { // this deferred method handles exit-with-error
 			if err == nil {
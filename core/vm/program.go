@@ -0,0 +1,419 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// programCacheLimit is the number of analysed contract codes to keep around.
+const programCacheLimit = 1024
+
+// programKey identifies an analysed code. The analysis depends on the gas and
+// stack requirements of the instruction set, so the jump table is part of the
+// key. Only the static instruction sets are used, which are never released.
+type programKey struct {
+	codeHash common.Hash
+	table    *JumpTable
+}
+
+var programCache = lru.NewCache[programKey, *program](programCacheLimit)
+
+// Kinds of instructions in an analysed program.
+const (
+	kindOp         uint8 = iota // plain opcode, dispatched through the jump table
+	kindPush                    // PUSH1-PUSH32 with the value decoded upfront
+	kindPushJump                // PUSH to a valid JUMPDEST followed by JUMP
+	kindPushJumpi               // PUSH to a valid JUMPDEST followed by JUMPI
+	kindPushMstore              // PUSH of the offset followed by MSTORE
+	kindDupSwap                 // DUP followed by SWAP
+)
+
+// instruction is a single step of an analysed program, which is either a plain
+// opcode or a superinstruction fusing two opcodes.
+type instruction struct {
+	pc   uint64 // Position of the (last) opcode in the code
+	rest uint64 // Constant gas of the block's instructions after this one
+	left uint32 // Number of opcodes in the block after this one
+	arg  uint32 // Index of the pushed value in the program constants
+	op   OpCode // The (last) opcode
+	kind uint8  // Kind of instruction
+	n, m uint8  // Operands of a DUP/SWAP pair
+}
+
+// basicBlock is a run of instructions which is always entered at its start and
+// always executed in full, unless execution fails. Blocks start at the beginning
+// of the code, at every JUMPDEST and after every instruction ending a block.
+type basicBlock struct {
+	start    uint64 // Position of the first opcode
+	end      uint64 // Position after the last opcode
+	ops      int    // Number of opcodes in the block
+	gas      uint64 // Constant gas of all opcodes in the block
+	minStack int    // Minimum stack height on entry for no opcode to underflow
+	maxStack int    // Maximum stack height on entry for no opcode to overflow
+	code     []instruction
+}
+
+// program is the basic block analysis of a contract code.
+type program struct {
+	blockAt []uint32 // Block index plus one by start position, zero inside blocks
+	blocks  []basicBlock
+	consts  []uint256.Int // Values pushed by the code
+}
+
+// endsBlock reports whether the opcode terminates a basic block. Apart from the
+// control flow and halting opcodes, blocks are cut after every opcode whose
+// behaviour depends on the gas left, which must not include the constant gas
+// charged in advance for the instructions after it.
+func endsBlock(op OpCode, operation *operation) bool {
+	switch op {
+	case JUMP, JUMPI, STOP, RETURN, REVERT, SELFDESTRUCT, INVALID:
+		return true
+	case GAS, SSTORE, CALL, CALLCODE, DELEGATECALL, STATICCALL, CREATE, CREATE2:
+		return true
+	}
+	// Undefined opcodes have no cost
+	return !operation.HasCost()
+}
+
+// analyse splits the code into basic blocks and fuses common opcode sequences.
+func analyse(code []byte, table *JumpTable) *program {
+	var (
+		prog  = &program{blockAt: make([]uint32, len(code))}
+		bits  = codeBitmap(code)
+		block *basicBlock
+		delta int // Stack height change since the start of the block
+	)
+	validJumpdest := func(dest *uint256.Int) bool {
+		udest, overflow := dest.Uint64WithOverflow()
+		if overflow || udest >= uint64(len(code)) {
+			return false
+		}
+		return OpCode(code[udest]) == JUMPDEST && bits.codeSegment(udest)
+	}
+	closeBlock := func(end uint64) {
+		var (
+			gas  uint64
+			left uint32
+		)
+		for i := len(block.code) - 1; i >= 0; i-- {
+			block.code[i].rest, block.code[i].left = gas, left
+			gas += block.code[i].gasOf(table)
+			left += block.code[i].opsOf()
+		}
+		block.end = end
+		prog.blocks = append(prog.blocks, *block)
+		block = nil
+	}
+	var pc uint64
+	for pc < uint64(len(code)) {
+		op := OpCode(code[pc])
+		if op == JUMPDEST && block != nil {
+			closeBlock(pc)
+		}
+		if block == nil {
+			prog.blockAt[pc] = uint32(len(prog.blocks) + 1)
+			block = &basicBlock{start: pc, maxStack: int(params.StackLimit)}
+			delta = 0
+		}
+		operation := table[op]
+
+		// Account the gas and stack requirements of the opcode
+		block.gas += operation.constantGas
+		block.minStack = max(block.minStack, operation.minStack-delta)
+		block.maxStack = min(block.maxStack, operation.maxStack-delta)
+		delta += int(params.StackLimit) - operation.maxStack // pushed - popped
+
+		ins := instruction{pc: pc, op: op, kind: kindOp}
+		size := uint64(1)
+		if op >= PUSH1 && op <= PUSH32 {
+			n := int(op - PUSH0)
+			start := min(len(code), int(pc+1))
+			end := min(len(code), start+n)
+			value := new(uint256.Int).SetBytes(common.RightPadBytes(code[start:end], n))
+
+			ins.kind, ins.arg = kindPush, uint32(len(prog.consts))
+			prog.consts = append(prog.consts, *value)
+			size += uint64(n)
+		}
+		// Fuse the opcode with the previous one if possible
+		fused := false
+		if last := len(block.code) - 1; last >= 0 {
+			prev := &block.code[last]
+			switch {
+			case prev.kind == kindPush && op == JUMP && validJumpdest(&prog.consts[prev.arg]):
+				prev.kind, fused = kindPushJump, true
+			case prev.kind == kindPush && op == JUMPI && validJumpdest(&prog.consts[prev.arg]):
+				prev.kind, fused = kindPushJumpi, true
+			case prev.kind == kindPush && op == MSTORE:
+				prev.kind, fused = kindPushMstore, true
+			case prev.kind == kindOp && prev.op >= DUP1 && prev.op <= DUP16 && op >= SWAP1 && op <= SWAP16:
+				prev.kind, prev.n, prev.m, fused = kindDupSwap, uint8(prev.op-DUP1+1), uint8(op-SWAP1+2), true
+			}
+			if fused {
+				prev.pc, prev.op = pc, op
+			}
+		}
+		if !fused {
+			block.code = append(block.code, ins)
+		}
+		block.ops++
+
+		pc += size
+		if endsBlock(op, operation) {
+			closeBlock(pc)
+		}
+	}
+	if block != nil {
+		closeBlock(pc)
+	}
+	return prog
+}
+
+// gasOf returns the constant gas of the opcodes making up the instruction.
+func (ins *instruction) gasOf(table *JumpTable) uint64 {
+	gas := table[ins.op].constantGas
+	switch ins.kind {
+	case kindPushJump, kindPushJumpi, kindPushMstore:
+		gas += table[PUSH1].constantGas
+	case kindDupSwap:
+		gas += table[DUP1].constantGas
+	}
+	return gas
+}
+
+// opsOf returns the number of opcodes making up the instruction.
+func (ins *instruction) opsOf() uint32 {
+	if ins.kind == kindOp || ins.kind == kindPush {
+		return 1
+	}
+	return 2
+}
+
+// program returns the basic block analysis of the contract code, caching it
+// for contracts with a known code hash.
+func (in *EVMInterpreter) program(contract *Contract) *program {
+	if contract.CodeHash == (common.Hash{}) {
+		return analyse(contract.Code, in.table)
+	}
+	key := programKey{codeHash: contract.CodeHash, table: in.table}
+	if prog, ok := programCache.Get(key); ok {
+		return prog
+	}
+	prog := analyse(contract.Code, in.table)
+	programCache.Add(key, prog)
+	return prog
+}
+
+// runProgram executes the analysed contract code block by block. The constant
+// gas and the stack bounds of a whole block are checked on entry, and blocks
+// failing the check are stepped through opcode by opcode as in the standard
+// loop, so that execution fails at the same opcode with the same error.
+func (in *EVMInterpreter) runProgram(prog *program, scope *ScopeContext) ([]byte, error) {
+	var (
+		contract = scope.Contract
+		stack    = scope.Stack
+		mem      = scope.Memory
+		codeLen  = uint64(len(contract.Code))
+		pc       uint64
+		res      []byte
+		err      error
+	)
+blocks:
+	for pc < codeLen {
+		block := &prog.blocks[prog.blockAt[pc]-1]
+		if sLen := stack.len(); sLen < block.minStack || sLen > block.maxStack || contract.Gas < block.gas {
+			if pc, res, err = in.step(block.start, block.ops, scope); err != nil {
+				return res, err
+			}
+			continue
+		}
+		contract.Gas -= block.gas
+
+		next := block.end
+		for i := range block.code {
+			ins := &block.code[i]
+			switch ins.kind {
+			case kindPush:
+				stack.push(&prog.consts[ins.arg])
+
+			case kindDupSwap:
+				stack.dup(int(ins.n))
+				stack.swap(int(ins.m))
+
+			case kindPushJump:
+				if in.evm.abort.Load() {
+					return nil, errStopToken
+				}
+				next = prog.consts[ins.arg].Uint64()
+
+			case kindPushJumpi:
+				if in.evm.abort.Load() {
+					return nil, errStopToken
+				}
+				if cond := stack.pop(); !cond.IsZero() {
+					next = prog.consts[ins.arg].Uint64()
+				}
+
+			case kindPushMstore:
+				offset := &prog.consts[ins.arg]
+				memSize, overflow := calcMemSize64WithUint(offset, 32)
+				if overflow {
+					return nil, ErrGasUintOverflow
+				}
+				memorySize, overflow := math.SafeMul(toWordSize(memSize), 32)
+				if overflow {
+					return nil, ErrGasUintOverflow
+				}
+				if deopt, err := in.useDynamicGas(in.table[MSTORE], scope, memorySize, ins.rest); err != nil {
+					return nil, err
+				} else if deopt {
+					// Undo the fused push, and step through the rest of the block
+					stack.push(offset)
+					if pc, res, err = in.resume(ins, scope); err != nil {
+						return res, err
+					}
+					continue blocks
+				}
+				val := stack.pop()
+				mem.Set32(offset.Uint64(), &val)
+
+			default:
+				operation := in.table[ins.op]
+				if operation.dynamicGas != nil {
+					memorySize, err := memoryRequirement(operation, stack)
+					if err != nil {
+						return nil, err
+					}
+					if deopt, err := in.useDynamicGas(operation, scope, memorySize, ins.rest); err != nil {
+						return nil, err
+					} else if deopt {
+						if pc, res, err = in.resume(ins, scope); err != nil {
+							return res, err
+						}
+						continue blocks
+					}
+				}
+				pc = ins.pc
+				if res, err = operation.execute(&pc, in, scope); err != nil {
+					return res, err
+				}
+				if ins.op == JUMP || ins.op == JUMPI {
+					next = pc + 1
+				}
+			}
+		}
+		pc = next
+	}
+	return nil, nil
+}
+
+// resume executes the opcode of the instruction, whose dynamic gas has been
+// charged already, and steps through the remaining opcodes of its block. It's
+// used when the block runs out of gas only because of the constant gas charged
+// upfront for the opcodes not executed yet.
+func (in *EVMInterpreter) resume(ins *instruction, scope *ScopeContext) (uint64, []byte, error) {
+	pc := ins.pc
+	res, err := in.table[ins.op].execute(&pc, in, scope)
+	if err != nil {
+		return pc, res, err
+	}
+	return in.step(pc+1, int(ins.left), scope)
+}
+
+// step executes n opcodes starting at pc the same way as the standard loop,
+// returning the position of the next opcode.
+func (in *EVMInterpreter) step(pc uint64, n int, scope *ScopeContext) (uint64, []byte, error) {
+	var (
+		contract = scope.Contract
+		stack    = scope.Stack
+	)
+	for ; n > 0; n-- {
+		op := contract.GetOp(pc)
+		operation := in.table[op]
+		if sLen := stack.len(); sLen < operation.minStack {
+			return pc, nil, &ErrStackUnderflow{stackLen: sLen, required: operation.minStack}
+		} else if sLen > operation.maxStack {
+			return pc, nil, &ErrStackOverflow{stackLen: sLen, limit: operation.maxStack}
+		}
+		if !contract.UseGas(operation.constantGas, nil, tracing.GasChangeIgnored) {
+			return pc, nil, ErrOutOfGas
+		}
+		if operation.dynamicGas != nil {
+			memorySize, err := memoryRequirement(operation, stack)
+			if err != nil {
+				return pc, nil, err
+			}
+			if _, err := in.useDynamicGas(operation, scope, memorySize, 0); err != nil {
+				return pc, nil, err
+			}
+		}
+		res, err := operation.execute(&pc, in, scope)
+		if err != nil {
+			return pc, res, err
+		}
+		pc++
+	}
+	return pc, nil, nil
+}
+
+// memoryRequirement returns the memory size in words needed by the operation.
+func memoryRequirement(operation *operation, stack *Stack) (uint64, error) {
+	if operation.memorySize == nil {
+		return 0, nil
+	}
+	memSize, overflow := operation.memorySize(stack)
+	if overflow {
+		return 0, ErrGasUintOverflow
+	}
+	memorySize, overflow := math.SafeMul(toWordSize(memSize), 32)
+	if overflow {
+		return 0, ErrGasUintOverflow
+	}
+	return memorySize, nil
+}
+
+// useDynamicGas charges the dynamic gas of the operation and expands the memory.
+// If the gas left is insufficient only because of the given amount of gas paid
+// in advance, that amount is refunded, the dynamic gas charged and deopt set.
+func (in *EVMInterpreter) useDynamicGas(operation *operation, scope *ScopeContext, memorySize uint64, prepaid uint64) (deopt bool, err error) {
+	contract := scope.Contract
+
+	cost, err := operation.dynamicGas(in.evm, contract, scope.Stack, scope.Memory, memorySize)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrOutOfGas, err)
+	}
+	if !contract.UseGas(cost, nil, tracing.GasChangeIgnored) {
+		if prepaid == 0 || cost > contract.Gas+prepaid {
+			return false, ErrOutOfGas
+		}
+		contract.Gas += prepaid
+		contract.Gas -= cost
+		deopt = true
+	}
+	if memorySize > 0 {
+		scope.Memory.Resize(memorySize)
+	}
+	return deopt, nil
+}
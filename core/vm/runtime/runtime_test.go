@@ -19,6 +19,7 @@ package runtime
 import (
 	"fmt"
 	"math/big"
+	"math/rand"
 	"os"
 	"strings"
 	"testing"
@@ -913,3 +914,104 @@ func BenchmarkTracerStepVsCallFrame(b *testing.B) {
 	benchmarkNonModifyingCode(10000000, code, "tracer-step-10M", stepTracer, b)
 	benchmarkNonModifyingCode(10000000, code, "tracer-call-frame-10M", callFrameTracer, b)
 }
+
+// Tests that the optimized interpreter behaves exactly like the standard one on
+// randomly generated code, including running out of gas and stack within basic
+// blocks.
+func TestOptimizedInterpreter(t *testing.T) {
+	var (
+		rng     = rand.New(rand.NewSource(1))
+		address = common.HexToAddress("0xaa")
+		ops     = []vm.OpCode{
+			vm.ADD, vm.MUL, vm.SUB, vm.EXP, vm.LT, vm.ISZERO, vm.POP, vm.PC, vm.MSIZE,
+			vm.DUP1, vm.DUP2, vm.DUP4, vm.SWAP1, vm.SWAP2, vm.SWAP3, vm.PUSH0,
+			vm.MLOAD, vm.MSTORE, vm.MSTORE8, vm.SLOAD, vm.SSTORE, vm.TLOAD, vm.TSTORE,
+			vm.KECCAK256, vm.CALLDATALOAD, vm.GAS, vm.LOG0, vm.CALL, vm.STATICCALL,
+			vm.JUMPDEST, vm.JUMP, vm.JUMPI, vm.RETURN, vm.REVERT, vm.STOP, vm.INVALID, 0x0c,
+		}
+	)
+	generate := func() []byte {
+		var (
+			code  []byte
+			jumps []int // positions of the PUSH2 values of jump destinations
+			dests []int
+		)
+		for n := rng.Intn(200); n > 0; n-- {
+			switch r := rng.Intn(10); {
+			case r < 3:
+				code = append(code, byte(vm.PUSH1), byte(rng.Intn(64)))
+			case r < 4:
+				code = append(code, byte(vm.PUSH2), 0, 0)
+				jumps = append(jumps, len(code)-2)
+				code = append(code, byte([]vm.OpCode{vm.JUMP, vm.JUMPI, vm.MSTORE}[rng.Intn(3)]))
+			default:
+				op := ops[rng.Intn(len(ops))]
+				if op == vm.JUMPDEST {
+					dests = append(dests, len(code))
+				}
+				code = append(code, byte(op))
+			}
+		}
+		for _, pos := range jumps {
+			dest := rng.Intn(len(code) + 1)
+			if len(dests) > 0 && rng.Intn(4) > 0 {
+				dest = dests[rng.Intn(len(dests))]
+			}
+			code[pos], code[pos+1] = byte(dest>>8), byte(dest)
+		}
+		// Sometimes cut the last push short
+		if len(code) > 0 && rng.Intn(8) == 0 {
+			code = append(code, byte(vm.PUSH4), 0xff)
+		}
+		return code
+	}
+	run := func(code []byte, gas uint64, optimized bool) string {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetCode(address, code)
+		ret, left, err := Call(address, []byte{0x01, 0x02}, &Config{
+			State:     statedb,
+			GasLimit:  gas,
+			EVMConfig: vm.Config{OptimizedInterpreter: optimized},
+		})
+		return fmt.Sprintf("ret %x, gas %d, err %v, root %x", ret, left, err, statedb.IntermediateRoot(true))
+	}
+	for i := 0; i < 2000; i++ {
+		code := generate()
+		for _, gas := range []uint64{uint64(rng.Intn(500)), uint64(rng.Intn(50000)), 1_000_000} {
+			if want, have := run(code, gas, false), run(code, gas, true); want != have {
+				t.Fatalf("code %x, gas %d: mismatch\nhave %s\nwant %s", code, gas, have, want)
+			}
+		}
+	}
+}
+
+// BenchmarkOptimizedInterpreter compares the standard and the optimized
+// interpreter on a loop writing to memory, which runs until out of gas.
+func BenchmarkOptimizedInterpreter(b *testing.B) {
+	code := []byte{
+		byte(vm.PUSH1), 0, // [ i ]
+		byte(vm.JUMPDEST), // [ i ]
+		byte(vm.PUSH1), 1,
+		byte(vm.ADD),         // [ i+1 ]
+		byte(vm.DUP1),        // [ i+1, i+1 ]
+		byte(vm.DUP1),        // [ i+1, i+1, i+1 ]
+		byte(vm.SWAP1),       // [ i+1, i+1, i+1 ]
+		byte(vm.PUSH1), 0x40, // [ i+1, i+1, i+1, 0x40 ]
+		byte(vm.MSTORE),   // [ i+1, i+1 ]
+		byte(vm.PUSH1), 2, // [ i+1, i+1, 2 ]
+		byte(vm.JUMPI), // [ i+1 ]
+	}
+	for _, optimized := range []bool{false, true} {
+		b.Run(fmt.Sprintf("optimized=%v", optimized), func(b *testing.B) {
+			statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+			address := common.HexToAddress("0xaa")
+			statedb.SetCode(address, code)
+
+			cfg := &Config{State: statedb, GasLimit: 10_000_000, EVMConfig: vm.Config{OptimizedInterpreter: optimized}}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Call(address, nil, cfg)
+			}
+		})
+	}
+}
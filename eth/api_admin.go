@@ -141,3 +141,10 @@ func (api *AdminAPI) ImportChain(file string) (bool, error) {
 	}
 	return true, nil
 }
+
+// Backup writes a consistent copy of the chain database into the given
+// directory, which must not exist yet. Block processing is paused while the
+// backup is taken.
+func (api *AdminAPI) Backup(dir string) (*core.BackupManifest, error) {
+	return api.eth.BlockChain().Backup(dir)
+}
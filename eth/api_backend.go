@@ -19,6 +19,7 @@ package eth
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 	"time"
 
@@ -38,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -48,6 +50,7 @@ type EthAPIBackend struct {
 	allowUnprotectedTxs bool
 	eth                 *Ethereum
 	gpo                 *gasprice.Oracle
+	revertDecoder       *ethapi.RevertDecoder
 }
 
 // ChainConfig returns the active chain configuration.
@@ -143,10 +146,20 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumbe
 		}
 		return b.eth.blockchain.GetBlock(header.Hash(), header.Number.Uint64()), nil
 	}
+	if uint64(number) < b.eth.blockchain.HistoryCutoff() {
+		hash := b.eth.blockchain.GetCanonicalHash(uint64(number))
+		if hash == (common.Hash{}) {
+			return nil, nil
+		}
+		return b.prunedBlock(hash, uint64(number))
+	}
 	return b.eth.blockchain.GetBlockByNumber(uint64(number)), nil
 }
 
 func (b *EthAPIBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	if number := rawdb.ReadHeaderNumber(b.eth.chainDb, hash); number != nil && *number < b.eth.blockchain.HistoryCutoff() {
+		return b.prunedBlock(hash, *number)
+	}
 	return b.eth.blockchain.GetBlockByHash(hash), nil
 }
 
@@ -158,6 +171,13 @@ func (b *EthAPIBackend) GetBody(ctx context.Context, hash common.Hash, number rp
 	if body := b.eth.blockchain.GetBody(hash); body != nil {
 		return body, nil
 	}
+	if uint64(number) < b.eth.blockchain.HistoryCutoff() {
+		block, err := b.prunedBlock(hash, uint64(number))
+		if err != nil {
+			return nil, err
+		}
+		return block.Body(), nil
+	}
 	return nil, errors.New("block body not found")
 }
 
@@ -173,6 +193,9 @@ func (b *EthAPIBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash r
 		if blockNrOrHash.RequireCanonical && b.eth.blockchain.GetCanonicalHash(header.Number.Uint64()) != hash {
 			return nil, errors.New("hash is not currently canonical")
 		}
+		if header.Number.Uint64() < b.eth.blockchain.HistoryCutoff() {
+			return b.prunedBlock(hash, header.Number.Uint64())
+		}
 		block := b.eth.blockchain.GetBlock(hash, header.Number.Uint64())
 		if block == nil {
 			return nil, errors.New("header found, but block body is missing")
@@ -235,13 +258,64 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 }
 
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	if number := rawdb.ReadHeaderNumber(b.eth.chainDb, hash); number != nil && *number < b.eth.blockchain.HistoryCutoff() {
+		return b.prunedReceipts(hash, *number)
+	}
 	return b.eth.blockchain.GetReceiptsByHash(hash), nil
 }
 
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
+	if number < b.eth.blockchain.HistoryCutoff() {
+		receipts, err := b.prunedReceipts(hash, number)
+		if err != nil {
+			return nil, err
+		}
+		logs := make([][]*types.Log, len(receipts))
+		for i, receipt := range receipts {
+			logs[i] = receipt.Logs
+		}
+		return logs, nil
+	}
 	return rawdb.ReadLogs(b.eth.chainDb, hash, number), nil
 }
 
+// prunedBlock retrieves a block below the history cutoff from the era1 store,
+// verifying that it belongs to the canonical chain.
+func (b *EthAPIBackend) prunedBlock(hash common.Hash, number uint64) (*types.Block, error) {
+	if b.eth.eraStore == nil {
+		return nil, ethapi.NewPrunedHistoryError()
+	}
+	block, err := b.eth.eraStore.GetBlockByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	if block.Hash() != hash {
+		return nil, fmt.Errorf("era1 block %d hash mismatch: have %x, want %x", number, block.Hash(), hash)
+	}
+	return block, nil
+}
+
+// prunedReceipts retrieves the receipts of a block below the history cutoff
+// from the era1 store, deriving the fields not stored in the archive.
+func (b *EthAPIBackend) prunedReceipts(hash common.Hash, number uint64) (types.Receipts, error) {
+	block, err := b.prunedBlock(hash, number)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := b.eth.eraStore.GetReceiptsByNumber(number)
+	if err != nil {
+		return nil, err
+	}
+	var blobGasPrice *big.Int
+	if excess := block.ExcessBlobGas(); excess != nil {
+		blobGasPrice = eip4844.CalcBlobFee(*excess)
+	}
+	if err := receipts.DeriveFields(b.ChainConfig(), hash, number, block.Time(), block.BaseFee(), blobGasPrice, block.Transactions()); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
 func (b *EthAPIBackend) GetTd(ctx context.Context, hash common.Hash) *big.Int {
 	if header := b.eth.blockchain.GetHeaderByHash(hash); header != nil {
 		return b.eth.blockchain.GetTd(hash, header.Number.Uint64())
@@ -315,6 +389,21 @@ func (b *EthAPIBackend) GetPoolTransaction(hash common.Hash) *types.Transaction
 // indexing is already finished. The transaction is not existent from the perspective
 // of node.
 func (b *EthAPIBackend) GetTransaction(ctx context.Context, txHash common.Hash) (bool, *types.Transaction, common.Hash, uint64, uint64, error) {
+	// Transactions of pruned blocks are still indexed, but have to be looked
+	// up in the era1 store.
+	if number := rawdb.ReadTxLookupEntry(b.eth.chainDb, txHash); number != nil && *number < b.eth.blockchain.HistoryCutoff() {
+		hash := b.eth.blockchain.GetCanonicalHash(*number)
+		block, err := b.prunedBlock(hash, *number)
+		if err != nil {
+			return false, nil, common.Hash{}, 0, 0, err
+		}
+		for i, tx := range block.Transactions() {
+			if tx.Hash() == txHash {
+				return true, tx, hash, *number, uint64(i), nil
+			}
+		}
+		return false, nil, common.Hash{}, 0, 0, nil
+	}
 	lookup, tx, err := b.eth.blockchain.GetTransactionLookup(txHash)
 	if err != nil {
 		return false, nil, common.Hash{}, 0, 0, err
@@ -405,6 +494,14 @@ func (b *EthAPIBackend) RPCTxFeeCap() float64 {
 	return b.eth.config.RPCTxFeeCap
 }
 
+func (b *EthAPIBackend) RevertDecoder() *ethapi.RevertDecoder {
+	return b.revertDecoder
+}
+
+func (b *EthAPIBackend) RPCRevertErrorData() bool {
+	return b.eth.config.RPCRevertErrorData
+}
+
 func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	sections, _, _ := b.eth.bloomIndexer.Sections()
 	return params.BloomBitsBlocks, sections
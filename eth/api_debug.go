@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -420,6 +421,12 @@ func (api *DebugAPI) GetAccessibleState(from, to rpc.BlockNumber) (uint64, error
 	return 0, errors.New("no state found")
 }
 
+// SnapSyncStatus returns a detailed report of the snap sync progress, including
+// the estimated remaining healing work and the per-peer throughput.
+func (api *DebugAPI) SnapSyncStatus() *snap.SyncStatus {
+	return api.eth.Downloader().SnapSyncer.Status()
+}
+
 // SetTrieFlushInterval configures how often in-memory tries are persisted
 // to disk. The value is in terms of block processing time, not wall clock.
 // If the value is shorter than the block generation time, or even 0 or negative,
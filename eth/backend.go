@@ -44,7 +44,9 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/remotedb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/era"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/internal/shutdowncheck"
 	"github.com/ethereum/go-ethereum/log"
@@ -75,7 +77,9 @@ type Ethereum struct {
 	snapDialCandidates enode.Iterator
 
 	// DB interfaces
-	chainDb ethdb.Database // Block chain database
+	chainDb  ethdb.Database   // Block chain database
+	eraStore *era.Store       // Era1 archive of the pruned chain history, nil if not configured
+	dbServer *remotedb.Server // Server of the chain database over RPC, nil if not enabled
 
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
@@ -123,9 +127,40 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	log.Info("Allocated trie memory caches", "clean", common.StorageSize(config.TrieCleanCache)*1024*1024, "dirty", common.StorageSize(config.TrieDirtyCache)*1024*1024)
 
 	// Assemble the Ethereum object
-	chainDb, err := stack.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", false)
-	if err != nil {
-		return nil, err
+	var (
+		chainDb ethdb.Database
+		err     error
+	)
+	if config.DatabaseEra1 != "" {
+		ancients, err := era.NewAncientReader(stack.ResolvePath(config.DatabaseEra1))
+		if err != nil {
+			return nil, err
+		}
+		chainDb, err = stack.OpenDatabaseWithAncients("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, ancients, "eth/db/chaindata/", false)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		chainDb, err = stack.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "eth/db/chaindata/", false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if config.AncientCacheItems > 0 {
+		cacheConfig := rawdb.AncientCacheConfig{
+			Items:  config.AncientCacheItems,
+			Memory: uint64(config.AncientCacheMemory) * 1024 * 1024,
+		}
+		if config.AncientCacheDir != "" && stack.Config().DBEncryption != "" {
+			// The disk tier stores the items in plain, don't leak them.
+			log.Warn("Ancient disk cache disabled for encrypted database")
+		} else if config.AncientCacheDir != "" {
+			cacheConfig.Directory = stack.ResolvePath(config.AncientCacheDir)
+			cacheConfig.Disk = uint64(config.AncientCacheDisk) * 1024 * 1024
+		}
+		if chainDb, err = rawdb.NewDatabaseWithAncientCache(chainDb, cacheConfig, "eth/db/chaindata/"); err != nil {
+			return nil, err
+		}
 	}
 	scheme, err := rawdb.ParseStateScheme(config.StateScheme, chainDb)
 	if err != nil {
@@ -185,6 +220,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		vmConfig = vm.Config{
 			EnablePreimageRecording: config.EnablePreimageRecording,
 			EnableWitnessCollection: config.EnableWitnessCollection,
+			OptimizedInterpreter:    config.OptimizedInterpreter,
 		}
 		cacheConfig = &core.CacheConfig{
 			TrieCleanLimit:      config.TrieCleanCache,
@@ -195,6 +231,8 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
 			StateHistory:        config.StateHistory,
+			AccessSetHistory:    config.AccessSetHistory,
+			ParallelWorkers:     config.ParallelWorkers,
 			StateScheme:         scheme,
 		}
 	)
@@ -227,6 +265,23 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
 
+	if config.HistoryEra1Dir != "" {
+		network, ok := params.NetworkNames[eth.blockchain.Config().ChainID.String()]
+		if !ok {
+			network = "unknown"
+		}
+		if eth.eraStore, err = era.NewStore(stack.ResolvePath(config.HistoryEra1Dir), network); err != nil {
+			return nil, err
+		}
+		log.Info("Serving pruned history from era1 files", "dir", config.HistoryEra1Dir, "blocks", eth.eraStore.Blocks())
+	}
+	if config.RPCDatabase {
+		eth.dbServer = remotedb.NewServer(chainDb, config.RPCDatabaseWrite)
+		log.Warn("Exposing the chain database over RPC", "namespace", remotedb.Namespace, "writable", config.RPCDatabaseWrite)
+	} else if config.RPCDatabaseWrite {
+		log.Warn("Ignoring database write access without the database API enabled")
+	}
+
 	if config.BlobPool.Datadir != "" {
 		config.BlobPool.Datadir = stack.ResolvePath(config.BlobPool.Datadir)
 	}
@@ -260,10 +315,13 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	eth.miner = miner.New(eth, config.Miner, eth.engine)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
-	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil}
+	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil, nil}
 	if eth.APIBackend.allowUnprotectedTxs {
 		log.Info("Unprotected transactions allowed")
 	}
+	if eth.APIBackend.revertDecoder, err = makeRevertDecoder(config); err != nil {
+		return nil, err
+	}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.Miner.GasPrice
@@ -312,6 +370,26 @@ func makeExtraData(extra []byte) []byte {
 	return extra
 }
 
+// makeRevertDecoder creates the decoder for the revert data returned by failed
+// calls, loaded with the custom errors configured by the user.
+func makeRevertDecoder(config *ethconfig.Config) (*ethapi.RevertDecoder, error) {
+	decoder := ethapi.NewRevertDecoder()
+	if config.RPCRevertABIDir != "" {
+		if err := decoder.LoadABIDir(config.RPCRevertABIDir); err != nil {
+			return nil, fmt.Errorf("failed to load revert ABIs: %v", err)
+		}
+	}
+	if config.RPCRevertSignatures != "" {
+		if err := decoder.LoadSignatures(config.RPCRevertSignatures); err != nil {
+			return nil, fmt.Errorf("failed to load revert signatures: %v", err)
+		}
+	}
+	if n := decoder.Size(); n > 0 {
+		log.Info("Loaded custom error definitions", "errors", n)
+	}
+	return decoder, nil
+}
+
 // APIs return the collection of RPC services the ethereum package offers.
 // NOTE, some of these services probably need to be moved to somewhere else.
 func (s *Ethereum) APIs() []rpc.API {
@@ -320,8 +398,8 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
-	// Append all the local APIs and return
-	return append(apis, []rpc.API{
+	// Append all the local APIs
+	apis = append(apis, []rpc.API{
 		{
 			Namespace: "miner",
 			Service:   NewMinerAPI(s),
@@ -339,6 +417,10 @@ func (s *Ethereum) APIs() []rpc.API {
 			Service:   s.netRPCService,
 		},
 	}...)
+	if s.dbServer != nil {
+		apis = append(apis, s.dbServer.APIs()...)
+	}
+	return apis
 }
 
 func (s *Ethereum) ResetWithGenesisBlock(gb *types.Block) {
@@ -364,7 +446,9 @@ func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
 	protos := eth.MakeProtocols((*ethHandler)(s.handler), s.networkID, s.ethDialCandidates)
-	if s.config.SnapshotCache > 0 {
+	// The snap protocol can be served either from the snapshot or, in the path
+	// scheme, directly from the trie database.
+	if s.config.SnapshotCache > 0 || s.blockchain.TrieDB().Scheme() == rawdb.PathScheme {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
 	return protos
@@ -408,6 +492,12 @@ func (s *Ethereum) Stop() error {
 	s.txPool.Close()
 	s.blockchain.Stop()
 	s.engine.Close()
+	if s.eraStore != nil {
+		s.eraStore.Close()
+	}
+	if s.dbServer != nil {
+		s.dbServer.Close()
+	}
 
 	// Clean shutdown marker as the last thing before closing db
 	s.shutdownTracker.Stop()
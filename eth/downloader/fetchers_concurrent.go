@@ -25,6 +25,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/prque"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 )
 
 // timeoutGracePeriod is the amount of time to allow for a peer to deliver a
@@ -295,6 +296,7 @@ func (d *Downloader) concurrentFetch(queue typedQueue) error {
 				log.Error("Delivery timeout from unknown peer", "peer", req.Peer)
 				continue
 			}
+			d.reportPeer(peer.id, p2p.BehaviourSlowResponse)
 			if fails > 2 {
 				queue.updateCapacity(peer, 0, 0)
 			} else {
@@ -336,6 +338,12 @@ func (d *Downloader) concurrentFetch(queue typedQueue) error {
 				if errors.Is(err, errInvalidChain) {
 					return err
 				}
+				switch {
+				case err == nil && accepted > 0:
+					d.reportPeer(peer.id, p2p.BehaviourUsefulResponse)
+				case errors.Is(err, errInvalidBody), errors.Is(err, errInvalidReceipt):
+					d.reportPeer(peer.id, p2p.BehaviourInvalidResponse)
+				}
 				// Unless a peer delivered something completely else than requested (usually
 				// caused by a timed out request which came through in the end), set it to
 				// idle. If the delivery's stale, the peer should have already been idled.
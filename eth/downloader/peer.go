@@ -65,6 +65,12 @@ type Peer interface {
 	RequestReceipts([]common.Hash, chan *eth.Response) (*eth.Request, error)
 }
 
+// blockRangePeer is implemented by peers advertising the range of blocks they
+// are able to serve (eth/69 and newer).
+type blockRangePeer interface {
+	BlockRange() *eth.BlockRangeUpdatePacket
+}
+
 // newPeerConnection creates a new downloader peer.
 func newPeerConnection(id string, version uint, peer Peer, logger log.Logger) *peerConnection {
 	return &peerConnection{
@@ -148,6 +154,21 @@ func (p *peerConnection) MarkLacking(hash common.Hash) {
 	p.lacking[hash] = struct{}{}
 }
 
+// Serves retrieves whether the peer advertised to still retain the body and
+// receipts of the block with the given number. Peers not announcing their block
+// range are assumed to serve all blocks.
+//
+// Only the lower bound is checked, since the advertised latest block lags behind
+// the peer's actual head between announcements.
+func (p *peerConnection) Serves(number uint64) bool {
+	peer, ok := p.peer.(blockRangePeer)
+	if !ok {
+		return true
+	}
+	blockRange := peer.BlockRange()
+	return blockRange == nil || number >= blockRange.EarliestBlock
+}
+
 // Lacks retrieves whether the hash of a blockchain item is on the peers lacking
 // list (i.e. whether we know that the peer does not have it).
 func (p *peerConnection) Lacks(hash common.Hash) bool {
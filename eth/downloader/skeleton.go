@@ -30,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 )
 
 // scratchHeaders is the number of headers to store in a scratch space to allow
@@ -202,9 +203,10 @@ type skeleton struct {
 	db     ethdb.Database // Database backing the skeleton
 	filler backfiller     // Chain syncer suspended/resumed by head events
 
-	peers *peerSet                   // Set of peers we can sync from
-	idles map[string]*peerConnection // Set of idle peers in the current sync cycle
-	drop  peerDropFn                 // Drops a peer for misbehaving
+	peers  *peerSet                   // Set of peers we can sync from
+	idles  map[string]*peerConnection // Set of idle peers in the current sync cycle
+	drop   peerDropFn                 // Drops a peer for misbehaving
+	report peerReportFn               // Reports the behaviour of a peer for scoring
 
 	progress *skeletonProgress // Sync progress tracker for resumption and metrics
 	started  time.Time         // Timestamp when the skeleton syncer was created
@@ -227,12 +229,13 @@ type skeleton struct {
 
 // newSkeleton creates a new sync skeleton that tracks a potentially dangling
 // header chain until it's linked into an existing set of blocks.
-func newSkeleton(db ethdb.Database, peers *peerSet, drop peerDropFn, filler backfiller) *skeleton {
+func newSkeleton(db ethdb.Database, peers *peerSet, drop peerDropFn, report peerReportFn, filler backfiller) *skeleton {
 	sk := &skeleton{
 		db:         db,
 		filler:     filler,
 		peers:      peers,
 		drop:       drop,
+		report:     report,
 		requests:   make(map[uint64]*headerRequest),
 		headEvents: make(chan *headUpdate),
 		terminate:  make(chan chan error),
@@ -951,10 +954,13 @@ func (s *skeleton) processResponse(res *headerResponse) (linked bool, merged boo
 
 			// The peer delivered junk, or at least not the subchain we are
 			// syncing to. Free up the scratch space and assignment, reassign
-			// and drop the original peer.
+			// and drop the original peer, penalizing it for the invalid chain.
 			for i := 0; i < requestHeaders; i++ {
 				s.scratchSpace[i] = nil
 			}
+			if s.report != nil {
+				s.report(s.scratchOwners[0], p2p.BehaviourInvalidBlock)
+			}
 			s.drop(s.scratchOwners[0])
 			s.scratchOwners[0] = ""
 			break
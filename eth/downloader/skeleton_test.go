@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
 )
 
 // hookedBackfiller is a tester backfiller with all interface methods mocked and
@@ -85,6 +86,7 @@ type skeletonTestPeer struct {
 
 	served  atomic.Uint64 // Number of headers served by this peer
 	dropped atomic.Uint64 // Flag whether the peer was dropped (stop responding)
+	invalid atomic.Uint64 // Number of times the peer was reported for invalid headers
 }
 
 // newSkeletonTestPeer creates a new mock peer to test the skeleton sync with.
@@ -369,7 +371,7 @@ func TestSkeletonSyncInit(t *testing.T) {
 		// Create a skeleton sync and run a cycle
 		wait := make(chan struct{})
 
-		skeleton := newSkeleton(db, newPeerSet(), nil, newHookedBackfiller())
+		skeleton := newSkeleton(db, newPeerSet(), nil, nil, newHookedBackfiller())
 		skeleton.syncStarting = func() { close(wait) }
 		skeleton.Sync(tt.head, nil, true)
 
@@ -472,7 +474,7 @@ func TestSkeletonSyncExtend(t *testing.T) {
 		// Create a skeleton sync and run a cycle
 		wait := make(chan struct{})
 
-		skeleton := newSkeleton(db, newPeerSet(), nil, newHookedBackfiller())
+		skeleton := newSkeleton(db, newPeerSet(), nil, nil, newHookedBackfiller())
 		skeleton.syncStarting = func() { close(wait) }
 		skeleton.Sync(tt.head, nil, true)
 
@@ -494,6 +496,7 @@ type skeletonExpect struct {
 	state []*subchain // Expected sync state after the post-init event
 	serve uint64      // Expected number of header retrievals after initial cycle
 	drop  uint64      // Expected number of peers dropped after initial cycle
+	bad   uint64      // Expected number of peers reported for invalid headers
 }
 
 type skeletonTest struct {
@@ -713,6 +716,7 @@ func TestSkeletonSyncRetrievals(t *testing.T) {
 				state: []*subchain{{Head: requestHeaders + 100, Tail: 100}},
 				serve: requestHeaders + 101 - 2, // len - head - genesis
 				drop:  1,                        // different set of headers, drop // TODO(karalabe): maybe just diff sync?
+				bad:   1,                        // different set of headers, penalize
 			},
 
 			newPeer: newSkeletonTestPeer("good-peer", chain),
@@ -720,6 +724,7 @@ func TestSkeletonSyncRetrievals(t *testing.T) {
 				state: []*subchain{{Head: requestHeaders + 100, Tail: 1}},
 				serve: (requestHeaders + 101 - 2) + (100 - 1), // midserve + lenrest - genesis
 				drop:  1,                                      // no new drops
+				bad:   1,                                      // no new reports
 			},
 		},
 		// This test checks if a peer tries to inject a different header - *off*
@@ -849,6 +854,11 @@ func TestSkeletonSyncRetrievals(t *testing.T) {
 			peerset.Unregister(peer)
 			dropped[peer]++
 		}
+		report := func(peer string, behaviour p2p.Behaviour) {
+			if p := peerset.Peer(peer); p != nil && behaviour == p2p.BehaviourInvalidBlock {
+				p.peer.(*skeletonTestPeer).invalid.Add(1)
+			}
+		}
 		// Create a backfiller if we need to run more advanced tests
 		filler := newHookedBackfiller()
 		if tt.fill {
@@ -885,7 +895,7 @@ func TestSkeletonSyncRetrievals(t *testing.T) {
 			}
 		}
 		// Create a skeleton sync and run a cycle
-		skeleton := newSkeleton(db, peerset, drop, filler)
+		skeleton := newSkeleton(db, peerset, drop, report, filler)
 		skeleton.Sync(tt.head, nil, true)
 
 		// Wait a bit (bleah) for the initial sync loop to go to idle. This might
@@ -965,6 +975,13 @@ func checkSkeletonProgress(db ethdb.KeyValueReader, unpredictable bool, peers []
 		if drops != expected.drop {
 			return fmt.Errorf("dropped peers mismatch: have %d, want %d", drops, expected.drop)
 		}
+		var bad uint64
+		for _, peer := range peers {
+			bad += peer.invalid.Load()
+		}
+		if bad != expected.bad {
+			return fmt.Errorf("invalid peers mismatch: have %d, want %d", bad, expected.bad)
+		}
 	}
 	return nil
 }
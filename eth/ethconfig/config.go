@@ -55,6 +55,8 @@ var Defaults = Config{
 	StateHistory:       params.FullImmutabilityThreshold,
 	LightPeers:         100,
 	DatabaseCache:      512,
+	AncientCacheMemory: 128,
+	AncientCacheDisk:   2048,
 	TrieCleanCache:     154,
 	TrieDirtyCache:     256,
 	TrieTimeout:        60 * time.Minute,
@@ -94,6 +96,8 @@ type Config struct {
 	TxLookupLimit      uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	TransactionHistory uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 	StateHistory       uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state histories are reserved.
+	AccessSetHistory   uint64 `toml:",omitempty"` // The maximum number of blocks from head whose state access sets are reserved.
+	HistoryEra1Dir     string `toml:",omitempty"` // Directory of era1 files serving the pruned chain history.
 
 	// State scheme represents the scheme used to store ethereum states and trie
 	// nodes on top. It can be 'hash', 'path', or none which means use the scheme
@@ -118,6 +122,12 @@ type Config struct {
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 	DatabaseFreezer    string
+	DatabaseEra1       string `toml:",omitempty"` // Directory of era1 files serving the ancient data instead of the freezer
+
+	AncientCacheItems  uint64 `toml:",omitempty"` // Number of most recent items per ancient table to cache, 0 to disable
+	AncientCacheMemory int    // Memory budget of the ancient cache in MB
+	AncientCacheDir    string `toml:",omitempty"` // Directory of the on-disk ancient cache, disabled if empty
+	AncientCacheDisk   int    // Disk budget of the ancient cache in MB
 
 	TrieCleanCache int
 	TrieDirtyCache int
@@ -144,6 +154,12 @@ type Config struct {
 	// Enables prefetching trie nodes for read operations too
 	EnableWitnessCollection bool `toml:"-"`
 
+	// Number of workers speculatively executing block transactions in parallel
+	ParallelWorkers int `toml:",omitempty"`
+
+	// Enables the basic block interpreter with fused instructions
+	OptimizedInterpreter bool `toml:",omitempty"`
+
 	// Enables VM tracing
 	VMTrace           string
 	VMTraceJsonConfig string
@@ -161,6 +177,26 @@ type Config struct {
 	// send-transaction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// RPCRevertABIDir is a directory of contract ABIs whose custom errors are
+	// used to decode the revert data of failed calls.
+	RPCRevertABIDir string `toml:",omitempty"`
+
+	// RPCRevertSignatures is a 4byte database file whose signatures are used
+	// to decode custom errors of failed calls.
+	RPCRevertSignatures string `toml:",omitempty"`
+
+	// RPCRevertErrorData makes eth_call and eth_estimateGas return the decoded
+	// revert reason next to the raw revert data in the error data.
+	RPCRevertErrorData bool `toml:",omitempty"`
+
+	// RPCDatabase exposes the chain database in the db RPC namespace, for the
+	// remote database mode of the db commands.
+	RPCDatabase bool `toml:",omitempty"`
+
+	// RPCDatabaseWrite allows modifying the chain database through the db RPC
+	// namespace, otherwise it's read-only.
+	RPCDatabaseWrite bool `toml:",omitempty"`
+
 	// OverrideCancun (TODO: remove after the fork)
 	OverrideCancun *uint64 `toml:",omitempty"`
 
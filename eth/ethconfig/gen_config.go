@@ -27,6 +27,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		TxLookupLimit           uint64                 `toml:",omitempty"`
 		TransactionHistory      uint64                 `toml:",omitempty"`
 		StateHistory            uint64                 `toml:",omitempty"`
+		AccessSetHistory        uint64                 `toml:",omitempty"`
+		HistoryEra1Dir          string                 `toml:",omitempty"`
 		StateScheme             string                 `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               int                    `toml:",omitempty"`
@@ -39,6 +41,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DatabaseHandles         int                    `toml:"-"`
 		DatabaseCache           int
 		DatabaseFreezer         string
+		DatabaseEra1            string `toml:",omitempty"`
+		AncientCacheItems       uint64 `toml:",omitempty"`
+		AncientCacheMemory      int
+		AncientCacheDir         string `toml:",omitempty"`
+		AncientCacheDisk        int
 		TrieCleanCache          int
 		TrieDirtyCache          int
 		TrieTimeout             time.Duration
@@ -51,12 +58,19 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
 		EnableWitnessCollection bool `toml:"-"`
+		ParallelWorkers         int  `toml:",omitempty"`
+		OptimizedInterpreter    bool `toml:",omitempty"`
 		VMTrace                 string
 		VMTraceJsonConfig       string
 		DocRoot                 string `toml:"-"`
 		RPCGasCap               uint64
 		RPCEVMTimeout           time.Duration
 		RPCTxFeeCap             float64
+		RPCRevertABIDir         string  `toml:",omitempty"`
+		RPCRevertSignatures     string  `toml:",omitempty"`
+		RPCRevertErrorData      bool    `toml:",omitempty"`
+		RPCDatabase             bool    `toml:",omitempty"`
+		RPCDatabaseWrite        bool    `toml:",omitempty"`
 		OverrideCancun          *uint64 `toml:",omitempty"`
 		OverrideVerkle          *uint64 `toml:",omitempty"`
 	}
@@ -71,6 +85,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.TxLookupLimit = c.TxLookupLimit
 	enc.TransactionHistory = c.TransactionHistory
 	enc.StateHistory = c.StateHistory
+	enc.AccessSetHistory = c.AccessSetHistory
+	enc.HistoryEra1Dir = c.HistoryEra1Dir
 	enc.StateScheme = c.StateScheme
 	enc.RequiredBlocks = c.RequiredBlocks
 	enc.LightServ = c.LightServ
@@ -83,6 +99,11 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
 	enc.DatabaseFreezer = c.DatabaseFreezer
+	enc.DatabaseEra1 = c.DatabaseEra1
+	enc.AncientCacheItems = c.AncientCacheItems
+	enc.AncientCacheMemory = c.AncientCacheMemory
+	enc.AncientCacheDir = c.AncientCacheDir
+	enc.AncientCacheDisk = c.AncientCacheDisk
 	enc.TrieCleanCache = c.TrieCleanCache
 	enc.TrieDirtyCache = c.TrieDirtyCache
 	enc.TrieTimeout = c.TrieTimeout
@@ -95,12 +116,19 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.EnableWitnessCollection = c.EnableWitnessCollection
+	enc.ParallelWorkers = c.ParallelWorkers
+	enc.OptimizedInterpreter = c.OptimizedInterpreter
 	enc.VMTrace = c.VMTrace
 	enc.VMTraceJsonConfig = c.VMTraceJsonConfig
 	enc.DocRoot = c.DocRoot
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.RPCRevertABIDir = c.RPCRevertABIDir
+	enc.RPCRevertSignatures = c.RPCRevertSignatures
+	enc.RPCRevertErrorData = c.RPCRevertErrorData
+	enc.RPCDatabase = c.RPCDatabase
+	enc.RPCDatabaseWrite = c.RPCDatabaseWrite
 	enc.OverrideCancun = c.OverrideCancun
 	enc.OverrideVerkle = c.OverrideVerkle
 	return &enc, nil
@@ -119,6 +147,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		TxLookupLimit           *uint64                `toml:",omitempty"`
 		TransactionHistory      *uint64                `toml:",omitempty"`
 		StateHistory            *uint64                `toml:",omitempty"`
+		AccessSetHistory        *uint64                `toml:",omitempty"`
+		HistoryEra1Dir          *string                `toml:",omitempty"`
 		StateScheme             *string                `toml:",omitempty"`
 		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
 		LightServ               *int                   `toml:",omitempty"`
@@ -131,6 +161,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DatabaseHandles         *int                   `toml:"-"`
 		DatabaseCache           *int
 		DatabaseFreezer         *string
+		DatabaseEra1            *string `toml:",omitempty"`
+		AncientCacheItems       *uint64 `toml:",omitempty"`
+		AncientCacheMemory      *int
+		AncientCacheDir         *string `toml:",omitempty"`
+		AncientCacheDisk        *int
 		TrieCleanCache          *int
 		TrieDirtyCache          *int
 		TrieTimeout             *time.Duration
@@ -143,12 +178,19 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
 		EnableWitnessCollection *bool `toml:"-"`
+		ParallelWorkers         *int  `toml:",omitempty"`
+		OptimizedInterpreter    *bool `toml:",omitempty"`
 		VMTrace                 *string
 		VMTraceJsonConfig       *string
 		DocRoot                 *string `toml:"-"`
 		RPCGasCap               *uint64
 		RPCEVMTimeout           *time.Duration
 		RPCTxFeeCap             *float64
+		RPCRevertABIDir         *string `toml:",omitempty"`
+		RPCRevertSignatures     *string `toml:",omitempty"`
+		RPCRevertErrorData      *bool   `toml:",omitempty"`
+		RPCDatabase             *bool   `toml:",omitempty"`
+		RPCDatabaseWrite        *bool   `toml:",omitempty"`
 		OverrideCancun          *uint64 `toml:",omitempty"`
 		OverrideVerkle          *uint64 `toml:",omitempty"`
 	}
@@ -186,6 +228,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.StateHistory != nil {
 		c.StateHistory = *dec.StateHistory
 	}
+	if dec.AccessSetHistory != nil {
+		c.AccessSetHistory = *dec.AccessSetHistory
+	}
+	if dec.HistoryEra1Dir != nil {
+		c.HistoryEra1Dir = *dec.HistoryEra1Dir
+	}
 	if dec.StateScheme != nil {
 		c.StateScheme = *dec.StateScheme
 	}
@@ -222,6 +270,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseFreezer != nil {
 		c.DatabaseFreezer = *dec.DatabaseFreezer
 	}
+	if dec.DatabaseEra1 != nil {
+		c.DatabaseEra1 = *dec.DatabaseEra1
+	}
+	if dec.AncientCacheItems != nil {
+		c.AncientCacheItems = *dec.AncientCacheItems
+	}
+	if dec.AncientCacheMemory != nil {
+		c.AncientCacheMemory = *dec.AncientCacheMemory
+	}
+	if dec.AncientCacheDir != nil {
+		c.AncientCacheDir = *dec.AncientCacheDir
+	}
+	if dec.AncientCacheDisk != nil {
+		c.AncientCacheDisk = *dec.AncientCacheDisk
+	}
 	if dec.TrieCleanCache != nil {
 		c.TrieCleanCache = *dec.TrieCleanCache
 	}
@@ -258,6 +321,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnableWitnessCollection != nil {
 		c.EnableWitnessCollection = *dec.EnableWitnessCollection
 	}
+	if dec.ParallelWorkers != nil {
+		c.ParallelWorkers = *dec.ParallelWorkers
+	}
+	if dec.OptimizedInterpreter != nil {
+		c.OptimizedInterpreter = *dec.OptimizedInterpreter
+	}
 	if dec.VMTrace != nil {
 		c.VMTrace = *dec.VMTrace
 	}
@@ -276,6 +345,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.RPCRevertABIDir != nil {
+		c.RPCRevertABIDir = *dec.RPCRevertABIDir
+	}
+	if dec.RPCRevertSignatures != nil {
+		c.RPCRevertSignatures = *dec.RPCRevertSignatures
+	}
+	if dec.RPCRevertErrorData != nil {
+		c.RPCRevertErrorData = *dec.RPCRevertErrorData
+	}
+	if dec.RPCDatabase != nil {
+		c.RPCDatabase = *dec.RPCDatabase
+	}
+	if dec.RPCDatabaseWrite != nil {
+		c.RPCDatabaseWrite = *dec.RPCDatabaseWrite
+	}
 	if dec.OverrideCancun != nil {
 		c.OverrideCancun = dec.OverrideCancun
 	}
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p"
 )
 
 const (
@@ -190,10 +191,11 @@ type TxFetcher struct {
 	alternates map[common.Hash]map[string]struct{} // In-flight transaction alternate origins if retrieval fails
 
 	// Callbacks
-	hasTx    func(common.Hash) bool             // Retrieves a tx from the local txpool
-	addTxs   func([]*types.Transaction) []error // Insert a batch of transactions into local txpool
-	fetchTxs func(string, []common.Hash) error  // Retrieves a set of txs from a remote peer
-	dropPeer func(string)                       // Drops a peer in case of announcement violation
+	hasTx      func(common.Hash) bool             // Retrieves a tx from the local txpool
+	addTxs     func([]*types.Transaction) []error // Insert a batch of transactions into local txpool
+	fetchTxs   func(string, []common.Hash) error  // Retrieves a set of txs from a remote peer
+	dropPeer   func(string)                       // Drops a peer in case of announcement violation
+	reportPeer func(string, p2p.Behaviour)        // Reports the behaviour of a peer for scoring
 
 	step  chan struct{} // Notification channel when the fetcher loop iterates
 	clock mclock.Clock  // Time wrapper to simulate in tests
@@ -202,15 +204,15 @@ type TxFetcher struct {
 
 // NewTxFetcher creates a transaction fetcher to retrieve transaction
 // based on hash announcements.
-func NewTxFetcher(hasTx func(common.Hash) bool, addTxs func([]*types.Transaction) []error, fetchTxs func(string, []common.Hash) error, dropPeer func(string)) *TxFetcher {
-	return NewTxFetcherForTests(hasTx, addTxs, fetchTxs, dropPeer, mclock.System{}, nil)
+func NewTxFetcher(hasTx func(common.Hash) bool, addTxs func([]*types.Transaction) []error, fetchTxs func(string, []common.Hash) error, dropPeer func(string), reportPeer func(string, p2p.Behaviour)) *TxFetcher {
+	return NewTxFetcherForTests(hasTx, addTxs, fetchTxs, dropPeer, reportPeer, mclock.System{}, nil)
 }
 
 // NewTxFetcherForTests is a testing method to mock out the realtime clock with
 // a simulated version and the internal randomness with a deterministic one.
 func NewTxFetcherForTests(
 	hasTx func(common.Hash) bool, addTxs func([]*types.Transaction) []error, fetchTxs func(string, []common.Hash) error, dropPeer func(string),
-	clock mclock.Clock, rand *mrand.Rand) *TxFetcher {
+	reportPeer func(string, p2p.Behaviour), clock mclock.Clock, rand *mrand.Rand) *TxFetcher {
 	return &TxFetcher{
 		notify:      make(chan *txAnnounce),
 		cleanup:     make(chan *txDelivery),
@@ -229,6 +231,7 @@ func NewTxFetcherForTests(
 		addTxs:      addTxs,
 		fetchTxs:    fetchTxs,
 		dropPeer:    dropPeer,
+		reportPeer:  reportPeer,
 		clock:       clock,
 		rand:        rand,
 	}
@@ -366,6 +369,7 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 		if otherreject > 128/4 {
 			time.Sleep(200 * time.Millisecond)
 			log.Debug("Peer delivering stale transactions", "peer", peer, "rejected", otherreject)
+			f.report(peer, p2p.BehaviourInvalidResponse)
 		}
 	}
 	select {
@@ -376,6 +380,13 @@ func (f *TxFetcher) Enqueue(peer string, txs []*types.Transaction, direct bool)
 	}
 }
 
+// report forwards a behaviour of a peer to the scoring callback, if set.
+func (f *TxFetcher) report(peer string, behaviour p2p.Behaviour) {
+	if f.reportPeer != nil {
+		f.reportPeer(peer, behaviour)
+	}
+}
+
 // Drop should be called when a peer disconnects. It cleans up all the internal
 // data structures of the given node.
 func (f *TxFetcher) Drop(peer string) error {
@@ -545,6 +556,7 @@ func (f *TxFetcher) loop() {
 			for peer, req := range f.requests {
 				if time.Duration(f.clock.Now()-req.time)+txGatherSlack > txFetchTimeout {
 					txRequestTimeoutMeter.Mark(int64(len(req.hashes)))
+					f.report(peer, p2p.BehaviourSlowResponse)
 
 					// Reschedule all the not-yet-delivered fetches to alternate peers
 					for _, hash := range req.hashes {
@@ -676,6 +688,11 @@ func (f *TxFetcher) loop() {
 					delivered[hash] = struct{}{}
 				}
 				cutoff := len(req.hashes) // If nothing is delivered, assume everything is missing, don't retry!!!
+				if len(delivered) == 0 {
+					f.report(delivery.origin, p2p.BehaviourUselessAnnouncement)
+				} else {
+					f.report(delivery.origin, p2p.BehaviourUsefulResponse)
+				}
 				for i, hash := range req.hashes {
 					if _, ok := delivered[hash]; ok {
 						cutoff = i
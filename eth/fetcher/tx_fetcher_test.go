@@ -20,7 +20,9 @@ import (
 	"errors"
 	"math/big"
 	"math/rand"
+	"reflect"
 	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -28,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -102,6 +105,7 @@ func TestTransactionFetcherWaiting(t *testing.T) {
 				nil,
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -197,6 +201,7 @@ func TestTransactionFetcherWaitingWithMeta(t *testing.T) {
 				nil,
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -399,6 +404,7 @@ func TestTransactionFetcherSkipWaiting(t *testing.T) {
 				nil,
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -463,6 +469,7 @@ func TestTransactionFetcherSingletonRequesting(t *testing.T) {
 				nil,
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -543,6 +550,7 @@ func TestTransactionFetcherFailedRescheduling(t *testing.T) {
 					return errors.New("peer disconnected")
 				},
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -613,6 +621,7 @@ func TestTransactionFetcherCleanup(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -653,6 +662,7 @@ func TestTransactionFetcherCleanupEmpty(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -692,6 +702,7 @@ func TestTransactionFetcherMissingRescheduling(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -739,6 +750,7 @@ func TestTransactionFetcherMissingCleanup(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -778,6 +790,7 @@ func TestTransactionFetcherBroadcasts(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -827,6 +840,7 @@ func TestTransactionFetcherWaitTimerResets(t *testing.T) {
 				nil,
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -873,6 +887,62 @@ func TestTransactionFetcherWaitTimerResets(t *testing.T) {
 	})
 }
 
+// Tests that the behaviour of peers is reported for scoring: timed out requests
+// as slow, empty deliveries as useless announcements and deliveries as useful.
+func TestTransactionFetcherPeerReports(t *testing.T) {
+	var (
+		lock    sync.Mutex
+		reports = make(map[string][]p2p.Behaviour)
+	)
+	testTransactionFetcherParallel(t, txFetcherTest{
+		init: func() *TxFetcher {
+			return NewTxFetcher(
+				func(common.Hash) bool { return false },
+				func(txs []*types.Transaction) []error {
+					return make([]error, len(txs))
+				},
+				func(string, []common.Hash) error { return nil },
+				nil,
+				func(peer string, behaviour p2p.Behaviour) {
+					lock.Lock()
+					defer lock.Unlock()
+					reports[peer] = append(reports[peer], behaviour)
+				},
+			)
+		},
+		steps: []interface{}{
+			// Let a request time out
+			doTxNotify{peer: "A", hashes: []common.Hash{testTxsHashes[0]}},
+			doWait{time: txArriveTimeout, step: true},
+			doWait{time: txFetchTimeout, step: true},
+
+			// Deliver nothing for a request
+			doTxNotify{peer: "B", hashes: []common.Hash{testTxsHashes[1]}},
+			doWait{time: txArriveTimeout, step: true},
+			doTxEnqueue{peer: "B", txs: nil, direct: true},
+
+			// Deliver the requested transaction
+			doTxNotify{peer: "C", hashes: []common.Hash{testTxsHashes[2]}},
+			doWait{time: txArriveTimeout, step: true},
+			doTxEnqueue{peer: "C", txs: []*types.Transaction{testTxs[2]}, direct: true},
+
+			doFunc(func() {
+				lock.Lock()
+				defer lock.Unlock()
+
+				want := map[string][]p2p.Behaviour{
+					"A": {p2p.BehaviourSlowResponse},
+					"B": {p2p.BehaviourUselessAnnouncement},
+					"C": {p2p.BehaviourUsefulResponse},
+				}
+				if !reflect.DeepEqual(reports, want) {
+					t.Errorf("peer reports mismatch: have %v, want %v", reports, want)
+				}
+			}),
+		},
+	})
+}
+
 // Tests that if a transaction request is not replied to, it will time
 // out and be re-scheduled for someone else.
 func TestTransactionFetcherTimeoutRescheduling(t *testing.T) {
@@ -885,6 +955,7 @@ func TestTransactionFetcherTimeoutRescheduling(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -951,6 +1022,7 @@ func TestTransactionFetcherTimeoutTimerResets(t *testing.T) {
 				nil,
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1010,6 +1082,7 @@ func TestTransactionFetcherRateLimiting(t *testing.T) {
 				nil,
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1040,6 +1113,7 @@ func TestTransactionFetcherBandwidthLimiting(t *testing.T) {
 				nil,
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1111,6 +1185,7 @@ func TestTransactionFetcherDoSProtection(t *testing.T) {
 				nil,
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1179,6 +1254,7 @@ func TestTransactionFetcherUnderpricedDedup(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1249,6 +1325,7 @@ func TestTransactionFetcherUnderpricedDoSProtection(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: append(steps, []interface{}{
@@ -1272,6 +1349,7 @@ func TestTransactionFetcherOutOfBoundDeliveries(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1326,6 +1404,7 @@ func TestTransactionFetcherDrop(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1393,6 +1472,7 @@ func TestTransactionFetcherDropRescheduling(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1439,6 +1519,7 @@ func TestInvalidAnnounceMetadata(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				func(peer string) { drop <- peer },
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1507,6 +1588,7 @@ func TestTransactionFetcherFuzzCrash01(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1535,6 +1617,7 @@ func TestTransactionFetcherFuzzCrash02(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1565,6 +1648,7 @@ func TestTransactionFetcherFuzzCrash03(t *testing.T) {
 				},
 				func(string, []common.Hash) error { return nil },
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1602,6 +1686,7 @@ func TestTransactionFetcherFuzzCrash04(t *testing.T) {
 					return errors.New("peer disconnected")
 				},
 				nil,
+				nil,
 			)
 		},
 		steps: []interface{}{
@@ -1998,6 +2083,7 @@ func TestTransactionForgotten(t *testing.T) {
 		},
 		func(string, []common.Hash) error { return nil },
 		func(string) {},
+		nil,
 	)
 	fetcher.Start()
 	defer fetcher.Stop()
@@ -53,6 +53,13 @@ const (
 	// All transactions with a higher size will be announced and need to be fetched
 	// by the peer.
 	txMaxBroadcastSize = 4096
+
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
+
+	// blockRangeUpdateInterval is the number of blocks after which an updated
+	// block range is announced to eth/69 peers.
+	blockRangeUpdateInterval = 32
 )
 
 var syncChallengeTimeout = 15 * time.Second // Time allowance for a node to reply to the sync progress challenge
@@ -116,6 +123,9 @@ type handler struct {
 	txsCh    chan core.NewTxsEvent
 	txsSub   event.Subscription
 
+	chainHeadCh  chan core.ChainHeadEvent
+	chainHeadSub event.Subscription
+
 	requiredBlocks map[uint64]common.Hash
 
 	// channels for fetcher, syncer, txsyncLoop
@@ -180,7 +190,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 		return nil, errors.New("snap sync not supported with snapshots disabled")
 	}
 	// Construct the downloader (long sync)
-	h.downloader = downloader.New(config.Database, h.eventMux, h.chain, h.removePeer, h.enableSyncedFeatures)
+	h.downloader = downloader.New(config.Database, h.eventMux, h.chain, h.removePeer, h.reportPeer, h.enableSyncedFeatures)
 
 	fetchTx := func(peer string, hashes []common.Hash) error {
 		p := h.peers.peer(peer)
@@ -192,7 +202,7 @@ func newHandler(config *handlerConfig) (*handler, error) {
 	addTxs := func(txs []*types.Transaction) []error {
 		return h.txpool.Add(txs, false, false)
 	}
-	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, addTxs, fetchTx, h.removePeer)
+	h.txFetcher = fetcher.NewTxFetcher(h.txpool.Has, addTxs, fetchTx, h.removePeer, h.reportPeer)
 	return h, nil
 }
 
@@ -257,7 +267,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 		td      = h.chain.GetTd(hash, number)
 	)
 	forkID := forkid.NewID(h.chain.Config(), genesis, number, head.Time)
-	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), forkID, h.forkFilter); err != nil {
+	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), forkID, h.forkFilter, h.blockRange()); err != nil {
 		peer.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
@@ -341,6 +351,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 				}
 				if headers[0].Number.Uint64() != number || headers[0].Hash() != hash {
 					peer.Log().Info("Required block mismatch, dropping peer", "number", number, "hash", headers[0].Hash(), "want", hash)
+					peer.Peer.Report(p2p.BehaviourInvalidBlock)
 					res.Done <- errors.New("required block mismatch")
 					return
 				}
@@ -348,6 +359,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 				res.Done <- nil
 			case <-timeout.C:
 				peer.Log().Warn("Required block challenge timed out, dropping", "addr", peer.RemoteAddr(), "type", peer.Name())
+				peer.Peer.Report(p2p.BehaviourSlowResponse)
 				h.removePeer(peer.ID())
 			}
 		}(number, hash, req)
@@ -388,6 +400,14 @@ func (h *handler) removePeer(id string) {
 	}
 }
 
+// reportPeer records a behaviour of a peer in the p2p layer's peer scoring.
+func (h *handler) reportPeer(id string, behaviour p2p.Behaviour) {
+	peer := h.peers.peer(id)
+	if peer != nil {
+		peer.Peer.Report(behaviour)
+	}
+}
+
 // unregisterPeer removes a peer from the downloader, fetchers and main peer set.
 func (h *handler) unregisterPeer(id string) {
 	// Create a custom logger to avoid printing the entire id
@@ -428,6 +448,12 @@ func (h *handler) Start(maxPeers int) {
 	h.txsSub = h.txpool.SubscribeTransactions(h.txsCh, false)
 	go h.txBroadcastLoop()
 
+	// announce block range changes to eth/69 peers
+	h.wg.Add(1)
+	h.chainHeadCh = make(chan core.ChainHeadEvent, chainHeadChanSize)
+	h.chainHeadSub = h.chain.SubscribeChainHeadEvent(h.chainHeadCh)
+	go h.blockRangeLoop()
+
 	// start sync handlers
 	h.txFetcher.Start()
 
@@ -437,7 +463,8 @@ func (h *handler) Start(maxPeers int) {
 }
 
 func (h *handler) Stop() {
-	h.txsSub.Unsubscribe() // quits txBroadcastLoop
+	h.txsSub.Unsubscribe()       // quits txBroadcastLoop
+	h.chainHeadSub.Unsubscribe() // quits blockRangeLoop
 	h.txFetcher.Stop()
 	h.downloader.Terminate()
 
@@ -546,6 +573,48 @@ func (h *handler) txBroadcastLoop() {
 	}
 }
 
+// blockRange returns the range of blocks the local node is able to serve.
+func (h *handler) blockRange() eth.BlockRangeUpdatePacket {
+	head := h.chain.CurrentBlock()
+	return eth.BlockRangeUpdatePacket{
+		EarliestBlock:   h.chain.HistoryCutoff(),
+		LatestBlock:     head.Number.Uint64(),
+		LatestBlockHash: head.Hash(),
+	}
+}
+
+// blockRangeLoop announces changes in the locally available block range to
+// connected eth/69 peers, every blockRangeUpdateInterval blocks or whenever
+// the history cutoff moves.
+func (h *handler) blockRangeLoop() {
+	defer h.wg.Done()
+
+	last := h.blockRange()
+	for {
+		select {
+		case <-h.chainHeadCh:
+			current := h.blockRange()
+			if current.EarliestBlock == last.EarliestBlock &&
+				current.LatestBlock >= last.LatestBlock &&
+				current.LatestBlock < last.LatestBlock+blockRangeUpdateInterval {
+				continue
+			}
+			// Send asynchronously to avoid a slow peer stalling the others
+			for _, peer := range h.peers.all() {
+				go func(peer *ethPeer) {
+					if err := peer.SendBlockRangeUpdate(current); err != nil {
+						peer.Log().Debug("Failed to announce block range", "err", err)
+					}
+				}(peer)
+			}
+			last = current
+
+		case <-h.chainHeadSub.Err():
+			return
+		}
+	}
+}
+
 // enableSyncedFeatures enables the post-sync functionalities when the initial
 // sync is finished.
 func (h *handler) enableSyncedFeatures() {
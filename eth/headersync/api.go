@@ -0,0 +1,447 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package headersync
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	errNoHead          = errors.New("chain head not yet known")
+	errUnknownBlock    = errors.New("unknown block")
+	errInvalidBlockRng = errors.New("invalid block range params")
+)
+
+// API serves the subset of the eth namespace that can be answered from a header
+// chain, retrieving and verifying any additional data on demand.
+type API struct {
+	s *Service
+}
+
+// ChainId returns the chain ID of the followed chain.
+func (api *API) ChainId() *hexutil.Big {
+	return (*hexutil.Big)(api.s.chainConfig.ChainID)
+}
+
+// BlockNumber returns the number of the chain head.
+func (api *API) BlockNumber() (hexutil.Uint64, error) {
+	head := api.s.chain.CurrentHeader()
+	if head == nil {
+		return 0, errNoHead
+	}
+	return hexutil.Uint64(head.Number.Uint64()), nil
+}
+
+// GetHeaderByNumber returns the requested canonical header.
+func (api *API) GetHeaderByNumber(ctx context.Context, number rpc.BlockNumber) map[string]interface{} {
+	if header := api.s.header(number); header != nil {
+		return ethapi.RPCMarshalHeader(header)
+	}
+	return nil
+}
+
+// GetHeaderByHash returns the requested header by hash.
+func (api *API) GetHeaderByHash(ctx context.Context, hash common.Hash) map[string]interface{} {
+	if header := api.s.chain.GetHeaderByHash(hash); header != nil {
+		return ethapi.RPCMarshalHeader(header)
+	}
+	return nil
+}
+
+// GetBlockByNumber returns the requested canonical block. The block body is
+// retrieved from the upstream endpoints and verified against the local header.
+func (api *API) GetBlockByNumber(ctx context.Context, number rpc.BlockNumber, fullTx bool) (map[string]interface{}, error) {
+	header := api.s.header(number)
+	if header == nil {
+		return nil, nil
+	}
+	return api.marshalBlock(ctx, header, fullTx)
+}
+
+// GetBlockByHash returns the requested block. The block body is retrieved from
+// the upstream endpoints and verified against the local header.
+func (api *API) GetBlockByHash(ctx context.Context, hash common.Hash, fullTx bool) (map[string]interface{}, error) {
+	header := api.s.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, nil
+	}
+	return api.marshalBlock(ctx, header, fullTx)
+}
+
+// marshalBlock retrieves the verified body of the given header and converts the
+// assembled block to its RPC representation.
+func (api *API) marshalBlock(ctx context.Context, header *types.Header, fullTx bool) (map[string]interface{}, error) {
+	rctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	body, err := api.s.upstreams.body(rctx, header)
+	if err != nil {
+		return nil, err
+	}
+	block := types.NewBlockWithHeader(header).WithBody(*body)
+	return ethapi.RPCMarshalBlock(block, true, fullTx, api.s.chainConfig), nil
+}
+
+// GetBalance returns the balance of an account at the given block, retrieved
+// with a Merkle proof from the upstream endpoints and verified locally.
+func (api *API) GetBalance(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Big, error) {
+	statedb, err := api.state(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	balance := statedb.GetBalance(address).ToBig()
+	return (*hexutil.Big)(balance), statedb.Error()
+}
+
+// GetTransactionCount returns the nonce of an account at the given block,
+// retrieved with a Merkle proof from the upstream endpoints and verified locally.
+func (api *API) GetTransactionCount(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (*hexutil.Uint64, error) {
+	statedb, err := api.state(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	nonce := statedb.GetNonce(address)
+	return (*hexutil.Uint64)(&nonce), statedb.Error()
+}
+
+// GetCode returns the code of an account at the given block, verified against
+// the code hash in the proven account.
+func (api *API) GetCode(ctx context.Context, address common.Address, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	statedb, err := api.state(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	code := statedb.GetCode(address)
+	return code, statedb.Error()
+}
+
+// GetStorageAt returns a storage slot of an account at the given block,
+// retrieved with a Merkle proof from the upstream endpoints and verified locally.
+func (api *API) GetStorageAt(ctx context.Context, address common.Address, hexKey string, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	key, err := decodeStorageKey(hexKey)
+	if err != nil {
+		return nil, &invalidParamsError{fmt.Sprintf("unable to decode storage key: %s", err)}
+	}
+	statedb, err := api.state(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	value := statedb.GetState(address, key)
+	return value[:], statedb.Error()
+}
+
+// Call executes the given transaction on top of the proof-backed state of the
+// given block. Every account, storage slot and code accessed during execution
+// is retrieved from the upstream endpoints and verified before use.
+func (api *API) Call(ctx context.Context, args ethapi.TransactionArgs, blockNrOrHash *rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	header, err := api.s.headerByNumberOrHash(*blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	var cancel context.CancelFunc
+	if timeout := api.s.config.RPCEVMTimeout; timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	statedb, err := api.s.stateAt(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	blockCtx := core.NewEVMBlockContext(header, &chainContext{api.s.chain}, &header.Coinbase)
+	if err := args.CallDefaults(api.s.config.RPCGasCap, blockCtx.BaseFee, api.s.chainConfig.ChainID); err != nil {
+		return nil, err
+	}
+	msg := args.ToMessage(blockCtx.BaseFee)
+	evm := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, api.s.chainConfig, vm.Config{NoBaseFee: true})
+
+	// Wait for the context to be done and cancel the evm. Even if the
+	// EVM has finished, cancelling may be done (repeatedly)
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	result, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(math.MaxUint64))
+	if err := statedb.Error(); err != nil {
+		return nil, err
+	}
+	if evm.Cancelled() {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", api.s.config.RPCEVMTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("err: %w (supplied gas %d)", err, msg.GasLimit)
+	}
+	if len(result.Revert()) > 0 {
+		return nil, newRevertError(result.Revert())
+	}
+	return result.Return(), result.Err
+}
+
+// state resolves the given block and returns a proof-backed state for it.
+func (api *API) state(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*state.StateDB, error) {
+	header, err := api.s.headerByNumberOrHash(blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	return api.s.stateAt(ctx, header)
+}
+
+// decodeStorageKey parses a hex encoded storage key, allowing it to be shorter
+// than 32 bytes.
+func decodeStorageKey(s string) (common.Hash, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if (len(s) & 1) > 0 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return common.Hash{}, errors.New("hex string invalid")
+	}
+	if len(b) > common.HashLength {
+		return common.Hash{}, errors.New("hex string too long, want at most 32 bytes")
+	}
+	return common.BytesToHash(b), nil
+}
+
+// chainContext adapts the header chain to the EVM's chain context, needed for
+// the BLOCKHASH opcode.
+type chainContext struct {
+	chain *HeaderChain
+}
+
+// Engine returns nil, the block author is always provided explicitly.
+func (c *chainContext) Engine() consensus.Engine {
+	return nil
+}
+
+// GetHeader returns the header with the given hash and number, if available.
+func (c *chainContext) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if header := c.chain.GetHeaderByHash(hash); header != nil && header.Number.Uint64() == number {
+		return header
+	}
+	return nil
+}
+
+// revertError is an API error that encompasses an EVM revert with JSON error
+// code and a binary data blob.
+type revertError struct {
+	error
+	reason string // revert reason hex encoded
+}
+
+// ErrorCode returns the JSON error code for a revert.
+func (e *revertError) ErrorCode() int {
+	return 3
+}
+
+// ErrorData returns the hex encoded revert reason.
+func (e *revertError) ErrorData() interface{} {
+	return e.reason
+}
+
+// newRevertError creates a revertError instance with the provided revert data.
+func newRevertError(revert []byte) *revertError {
+	err := vm.ErrExecutionReverted
+	if reason, errUnpack := abi.UnpackRevert(revert); errUnpack == nil {
+		err = fmt.Errorf("%w: %v", vm.ErrExecutionReverted, reason)
+	}
+	return &revertError{
+		error:  err,
+		reason: hexutil.Encode(revert),
+	}
+}
+
+// invalidParamsError is returned for malformed request parameters.
+type invalidParamsError struct{ message string }
+
+func (e *invalidParamsError) Error() string  { return e.message }
+func (e *invalidParamsError) ErrorCode() int { return -32602 }
+
+// GetLogs returns the logs matching the given filter criteria. Candidate blocks
+// are selected locally using the header blooms, and only their receipts are
+// retrieved from the upstream endpoints and verified against the headers.
+func (api *API) GetLogs(ctx context.Context, crit filters.FilterCriteria) ([]*types.Log, error) {
+	if crit.BlockHash != nil {
+		header := api.s.chain.GetHeaderByHash(*crit.BlockHash)
+		if header == nil {
+			return nil, errUnknownBlock
+		}
+		return api.blockLogs(ctx, header, crit)
+	}
+	head := api.s.chain.CurrentHeader()
+	if head == nil {
+		return nil, errNoHead
+	}
+	from, err := api.resolveNumber(crit.FromBlock, head)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.resolveNumber(crit.ToBlock, head)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, errInvalidBlockRng
+	}
+	if tail := api.s.chain.Tail(); from < tail {
+		return nil, fmt.Errorf("block #%d before available history (tail #%d)", from, tail)
+	}
+	if limit := api.s.config.LogQueryLimit; limit != 0 && to-from+1 > limit {
+		return nil, fmt.Errorf("log query spans %d blocks, limit is %d", to-from+1, limit)
+	}
+	logs := []*types.Log{}
+	for number := from; number <= to; number++ {
+		header := api.s.chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("missing header #%d", number)
+		}
+		matches, err := api.blockLogs(ctx, header, crit)
+		if err != nil {
+			return nil, err
+		}
+		logs = append(logs, matches...)
+	}
+	return logs, nil
+}
+
+// resolveNumber converts a filter block number to a concrete one, defaulting to
+// the chain head if unset.
+func (api *API) resolveNumber(number *big.Int, head *types.Header) (uint64, error) {
+	if number == nil {
+		return head.Number.Uint64(), nil
+	}
+	if !number.IsInt64() {
+		return 0, errInvalidBlockRng
+	}
+	header := api.s.header(rpc.BlockNumber(number.Int64()))
+	if header == nil {
+		return 0, fmt.Errorf("%w: #%d", errUnknownBlock, number)
+	}
+	return header.Number.Uint64(), nil
+}
+
+// blockLogs returns the logs of a single block matching the filter criteria,
+// retrieving the receipts only if the header bloom indicates a possible match.
+func (api *API) blockLogs(ctx context.Context, header *types.Header, crit filters.FilterCriteria) ([]*types.Log, error) {
+	if !bloomFilter(header.Bloom, crit.Addresses, crit.Topics) {
+		return nil, nil
+	}
+	receipts, err := api.s.blockReceipts(ctx, header)
+	if err != nil {
+		return nil, err
+	}
+	var logs []*types.Log
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			if matchLog(log, crit.Addresses, crit.Topics) {
+				logs = append(logs, log)
+			}
+		}
+	}
+	return logs, nil
+}
+
+// bloomFilter checks whether the given bloom may contain logs matching the
+// address and topic criteria.
+func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var included bool
+		for _, addr := range addresses {
+			if types.BloomLookup(bloom, addr) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, sub := range topics {
+		included := len(sub) == 0 // empty rule set == wildcard
+		for _, topic := range sub {
+			if types.BloomLookup(bloom, topic) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	return true
+}
+
+// matchLog checks whether a log matches the address and topic criteria.
+func matchLog(log *types.Log, addresses []common.Address, topics [][]common.Hash) bool {
+	if len(addresses) > 0 {
+		var found bool
+		for _, addr := range addresses {
+			if log.Address == addr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(topics) > len(log.Topics) {
+		return false
+	}
+	for i, sub := range topics {
+		if len(sub) == 0 {
+			continue // empty rule set == wildcard
+		}
+		var found bool
+		for _, topic := range sub {
+			if log.Topics[i] == topic {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,190 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package headersync
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// HeaderChain is a minimal canonical chain of execution headers, without any
+// block bodies, receipts or state. Every header in it is either attested by
+// the beacon light client or linked to such a header through parent hashes.
+type HeaderChain struct {
+	db ethdb.Database
+
+	lock      sync.RWMutex
+	head      *types.Header // Latest header attested by the beacon chain
+	finalized *types.Header // Latest finalized header, nil if not yet known
+	tail      uint64        // Number of the oldest canonical header available
+}
+
+// NewHeaderChain creates a header chain on top of the given database, loading
+// the previously tracked head if there is one.
+func NewHeaderChain(db ethdb.Database) *HeaderChain {
+	hc := &HeaderChain{db: db}
+
+	if hash := rawdb.ReadHeadHeaderHash(db); hash != (common.Hash{}) {
+		if head := hc.readHeader(hash); head != nil {
+			hc.head = head
+			hc.tail = head.Number.Uint64()
+			for hc.tail > 0 && rawdb.ReadCanonicalHash(db, hc.tail-1) != (common.Hash{}) {
+				hc.tail--
+			}
+			log.Info("Loaded header chain", "number", head.Number, "hash", hash, "tail", hc.tail)
+		}
+	}
+	if hash := rawdb.ReadFinalizedBlockHash(db); hash != (common.Hash{}) {
+		hc.finalized = hc.readHeader(hash)
+	}
+	return hc
+}
+
+// readHeader retrieves a header from the database by hash.
+func (hc *HeaderChain) readHeader(hash common.Hash) *types.Header {
+	number := rawdb.ReadHeaderNumber(hc.db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadHeader(hc.db, hash, *number)
+}
+
+// CurrentHeader returns the latest header tracked, or nil if the chain has not
+// yet received any head from the beacon light client.
+func (hc *HeaderChain) CurrentHeader() *types.Header {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.head
+}
+
+// CurrentFinalizedHeader returns the latest finalized header, or nil if unknown.
+func (hc *HeaderChain) CurrentFinalizedHeader() *types.Header {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.finalized
+}
+
+// Tail returns the number of the oldest canonical header available.
+func (hc *HeaderChain) Tail() uint64 {
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+
+	return hc.tail
+}
+
+// GetHeaderByHash retrieves a header by hash, regardless of whether it is part
+// of the canonical chain or not.
+func (hc *HeaderChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return hc.readHeader(hash)
+}
+
+// GetHeaderByNumber retrieves the canonical header with the given number.
+func (hc *HeaderChain) GetHeaderByNumber(number uint64) *types.Header {
+	hash := rawdb.ReadCanonicalHash(hc.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return rawdb.ReadHeader(hc.db, hash, number)
+}
+
+// HasHeader checks if a header is present in the database.
+func (hc *HeaderChain) HasHeader(hash common.Hash) bool {
+	return rawdb.ReadHeaderNumber(hc.db, hash) != nil
+}
+
+// writeHeaders stores a batch of already verified headers, without touching the
+// canonical chain.
+func (hc *HeaderChain) writeHeaders(headers []*types.Header) {
+	batch := hc.db.NewBatch()
+	for _, header := range headers {
+		rawdb.WriteHeader(batch, header)
+	}
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write headers", "err", err)
+	}
+}
+
+// setHead marks the given, already stored header as the new head, rewriting the
+// canonical mappings back to the first common ancestor with the previous chain
+// or until a header is missing. Canonical mappings above the new head that are
+// left over from a longer previous chain are removed.
+func (hc *HeaderChain) setHead(head *types.Header) {
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+
+	batch := hc.db.NewBatch()
+	if hc.head != nil {
+		for n := head.Number.Uint64() + 1; n <= hc.head.Number.Uint64(); n++ {
+			rawdb.DeleteCanonicalHash(batch, n)
+		}
+	}
+	var (
+		header = head
+		tail   = head.Number.Uint64()
+	)
+	for {
+		number := header.Number.Uint64()
+		if rawdb.ReadCanonicalHash(hc.db, number) == header.Hash() {
+			// Reached the old chain, everything below is already canonical
+			tail = hc.tail
+			break
+		}
+		rawdb.WriteCanonicalHash(batch, header.Hash(), number)
+		tail = number
+		if number == 0 {
+			break
+		}
+		parent := rawdb.ReadHeader(hc.db, header.ParentHash, number-1)
+		if parent == nil {
+			// The new chain is not linked to the old one (yet), drop any stale
+			// canonical mappings below to avoid serving a mixed chain.
+			for n := number - 1; n >= hc.tail && rawdb.ReadCanonicalHash(hc.db, n) != (common.Hash{}); n-- {
+				rawdb.DeleteCanonicalHash(batch, n)
+				if n == 0 {
+					break
+				}
+			}
+			break
+		}
+		header = parent
+	}
+	rawdb.WriteHeadHeaderHash(batch, head.Hash())
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to update header chain head", "err", err)
+	}
+	hc.head, hc.tail = head, tail
+}
+
+// setFinalized marks the header with the given hash as finalized if it is known.
+func (hc *HeaderChain) setFinalized(hash common.Hash) {
+	header := hc.readHeader(hash)
+	if header == nil {
+		return
+	}
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+
+	rawdb.WriteFinalizedBlockHash(hc.db, hash)
+	hc.finalized = header
+}
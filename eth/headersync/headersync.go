@@ -0,0 +1,337 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package headersync implements a header-only execution chain follower. It
+// tracks the chain head through the beacon light client, keeps only execution
+// headers locally and retrieves bodies and receipts on demand from untrusted
+// upstream endpoints, verifying them against the trusted headers.
+//
+// Note, the data is retrieved over JSON-RPC rather than the eth protocol. Eth
+// peers are expected to serve the chain they advertise in their handshake, which
+// a node without bodies, receipts and state can't do. As all retrieved data is
+// checked against the headers, an upstream endpoint needs to be trusted no more
+// than an eth peer would.
+package headersync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	btypes "github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// backfillBatch is the number of headers requested from an upstream endpoint
+	// in a single batch while filling gaps behind a new head.
+	backfillBatch = 128
+
+	// requestTimeout is the maximum time allowed for a single upstream retrieval.
+	requestTimeout = 10 * time.Second
+
+	// receiptCacheSize is the number of verified block receipts to keep around
+	// for serving repeated log queries.
+	receiptCacheSize = 256
+)
+
+// Config contains the configuration options of the header-only sync mode.
+type Config struct {
+	// Upstreams is the list of untrusted execution RPC endpoints used to fill
+	// header gaps and to retrieve bodies, receipts and proofs on demand. They
+	// are the only source of chain data, there's no eth protocol retrieval.
+	Upstreams []string
+
+	// History is the number of recent headers to retrieve behind the first
+	// head received, and the maximum gap to fill behind any later head.
+	History uint64
+
+	// LogQueryLimit is the maximum number of blocks a single log query may
+	// span. Zero means no limit.
+	LogQueryLimit uint64
+
+	// RPCGasCap is the global gas cap for eth_call executed on proof-backed
+	// state. Zero means no limit.
+	RPCGasCap uint64
+
+	// RPCEVMTimeout is the global timeout for eth_call executed on proof-backed
+	// state. Zero means no timeout.
+	RPCEVMTimeout time.Duration
+}
+
+// DefaultConfig contains the default settings for the header-only sync mode.
+var DefaultConfig = Config{
+	History:       8192,
+	LogQueryLimit: 10000,
+	RPCGasCap:     50000000,
+	RPCEVMTimeout: 5 * time.Second,
+}
+
+// HeadSource is the provider of beacon light client attested chain heads,
+// implemented by blsync.Client.
+type HeadSource interface {
+	SubscribeChainHeadEvent(ch chan<- btypes.ChainHeadEvent) event.Subscription
+}
+
+// Service follows the execution chain head announced by the beacon light client,
+// storing only the execution headers and serving a subset of the eth namespace
+// on top of them.
+type Service struct {
+	config      Config
+	chainConfig *params.ChainConfig
+	source      HeadSource
+	chain       *HeaderChain
+	upstreams   *upstreams
+	receipts    *lru.Cache[common.Hash, types.Receipts]
+
+	headSub event.Subscription
+	closeCh chan struct{}
+	doneCh  chan struct{}
+}
+
+// New creates a header-only sync service on top of the given database. The
+// service and its APIs need to be registered on the node by the caller.
+func New(db ethdb.Database, chainConfig *params.ChainConfig, source HeadSource, config Config) (*Service, error) {
+	upstreams, err := dialUpstreams(config.Upstreams)
+	if err != nil {
+		return nil, err
+	}
+	if len(config.Upstreams) == 0 {
+		log.Warn("No upstream endpoints configured, only headers announced by the beacon chain will be available")
+	}
+	return &Service{
+		config:      config,
+		chainConfig: chainConfig,
+		source:      source,
+		chain:       NewHeaderChain(db),
+		upstreams:   upstreams,
+		receipts:    lru.NewCache[common.Hash, types.Receipts](receiptCacheSize),
+		closeCh:     make(chan struct{}),
+		doneCh:      make(chan struct{}),
+	}, nil
+}
+
+// APIs returns the RPC APIs served by the header-only sync mode.
+func (s *Service) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: "eth",
+		Service:   &API{s},
+	}}
+}
+
+// Chain returns the header chain maintained by the service.
+func (s *Service) Chain() *HeaderChain {
+	return s.chain
+}
+
+// Start implements node.Lifecycle, starting to follow the chain head.
+func (s *Service) Start() error {
+	headCh := make(chan btypes.ChainHeadEvent, 16)
+	s.headSub = s.source.SubscribeChainHeadEvent(headCh)
+	go s.loop(headCh)
+
+	log.Info("Started header-only sync", "upstreams", len(s.upstreams.clients))
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the head following.
+func (s *Service) Stop() error {
+	s.headSub.Unsubscribe()
+	close(s.closeCh)
+	<-s.doneCh
+	s.upstreams.close()
+
+	log.Info("Stopped header-only sync")
+	return nil
+}
+
+// loop processes the chain heads announced by the beacon light client.
+func (s *Service) loop(headCh <-chan btypes.ChainHeadEvent) {
+	defer close(s.doneCh)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-s.closeCh
+		cancel()
+	}()
+	for {
+		select {
+		case ev := <-headCh:
+			if err := s.processHead(ctx, ev.Block.Header(), ev.Finalized); err != nil {
+				log.Warn("Failed to process chain head", "number", ev.Block.Number(), "hash", ev.Block.Hash(), "err", err)
+			}
+		case <-s.headSub.Err():
+			return
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
+// processHead stores a new trusted head header, fills the gap between it and
+// the previously known chain, and updates the canonical chain and finality.
+func (s *Service) processHead(ctx context.Context, head *types.Header, finalized common.Hash) error {
+	s.chain.writeHeaders([]*types.Header{head})
+
+	// Fill the gap behind the new head, as far as allowed by the history limit
+	var limit uint64
+	if number := head.Number.Uint64(); number > s.config.History {
+		limit = number - s.config.History
+	}
+	err := s.backfill(ctx, head, limit)
+	s.chain.setHead(head)
+	s.chain.setFinalized(finalized)
+
+	log.Info("Updated header chain", "number", head.Number, "hash", head.Hash(), "finalized", finalized, "tail", s.chain.Tail())
+	return err
+}
+
+// backfill retrieves the ancestors of the given trusted header from the upstream
+// endpoints until a locally known header or the given number limit is reached.
+// Headers are retrieved in batches by number and verified by linking each one
+// to its already verified child via the parent hash.
+func (s *Service) backfill(ctx context.Context, child *types.Header, limit uint64) error {
+	for child.Number.Uint64() > limit && !s.chain.HasHeader(child.ParentHash) {
+		if len(s.upstreams.clients) == 0 {
+			return errNoUpstream
+		}
+		to := child.Number.Uint64() - 1
+		from := limit
+		if to+1-from > backfillBatch {
+			from = to + 1 - backfillBatch
+		}
+		rctx, cancel := context.WithTimeout(ctx, requestTimeout)
+		headers, err := s.upstreams.headersByNumber(rctx, from, to)
+		cancel()
+		if err != nil {
+			return err
+		}
+		var verified []*types.Header
+		for i := len(headers) - 1; i >= 0; i-- {
+			if headers[i].Hash() != child.ParentHash {
+				break
+			}
+			verified = append(verified, headers[i])
+			child = headers[i]
+			if s.chain.HasHeader(child.ParentHash) {
+				break
+			}
+		}
+		if len(verified) == 0 {
+			// The upstream canonical chain differs from ours (reorg in progress
+			// or a misbehaving endpoint), fall back to retrieving by hash.
+			rctx, cancel := context.WithTimeout(ctx, requestTimeout)
+			header, err := s.upstreams.headerByHash(rctx, child.ParentHash)
+			cancel()
+			if err != nil {
+				return err
+			}
+			verified, child = []*types.Header{header}, header
+		}
+		s.chain.writeHeaders(verified)
+	}
+	return nil
+}
+
+// header resolves a block number to a canonical header, supporting the latest,
+// safe and finalized tags.
+func (s *Service) header(number rpc.BlockNumber) *types.Header {
+	switch number {
+	case rpc.LatestBlockNumber, rpc.PendingBlockNumber:
+		return s.chain.CurrentHeader()
+	case rpc.SafeBlockNumber, rpc.FinalizedBlockNumber:
+		return s.chain.CurrentFinalizedHeader()
+	case rpc.EarliestBlockNumber:
+		return s.chain.GetHeaderByNumber(s.chain.Tail())
+	default:
+		if number < 0 {
+			return nil
+		}
+		return s.chain.GetHeaderByNumber(uint64(number))
+	}
+}
+
+// headerByNumberOrHash resolves a block number or hash to a trusted header.
+func (s *Service) headerByNumberOrHash(blockNrOrHash rpc.BlockNumberOrHash) (*types.Header, error) {
+	if number, ok := blockNrOrHash.Number(); ok {
+		header := s.header(number)
+		if header == nil {
+			return nil, fmt.Errorf("%w: #%d", errUnknownBlock, number)
+		}
+		return header, nil
+	}
+	hash, _ := blockNrOrHash.Hash()
+	header := s.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, fmt.Errorf("%w: %x", errUnknownBlock, hash)
+	}
+	if blockNrOrHash.RequireCanonical {
+		if canonical := s.chain.GetHeaderByNumber(header.Number.Uint64()); canonical == nil || canonical.Hash() != hash {
+			return nil, errors.New("hash is not currently canonical")
+		}
+	}
+	return header, nil
+}
+
+// stateAt returns a state database for the given trusted header, which lazily
+// retrieves and verifies the accessed state items from the upstream endpoints.
+func (s *Service) stateAt(ctx context.Context, header *types.Header) (*state.StateDB, error) {
+	return state.New(header.Root, newProofDatabase(ctx, s.upstreams, header), nil)
+}
+
+// blockReceipts retrieves the verified receipts of the given trusted header,
+// with all the derived fields filled in from the verified block body.
+func (s *Service) blockReceipts(ctx context.Context, header *types.Header) (types.Receipts, error) {
+	hash := header.Hash()
+	if receipts, ok := s.receipts.Get(hash); ok {
+		return receipts, nil
+	}
+	rctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	body, err := s.upstreams.body(rctx, header)
+	if err != nil {
+		return nil, err
+	}
+	receipts, err := s.upstreams.receipts(rctx, header)
+	if err != nil {
+		return nil, err
+	}
+	if len(receipts) != len(body.Transactions) {
+		return nil, errors.New("receipt count mismatch")
+	}
+	var blobGasPrice *big.Int
+	if header.ExcessBlobGas != nil {
+		blobGasPrice = eip4844.CalcBlobFee(*header.ExcessBlobGas)
+	}
+	if err := receipts.DeriveFields(s.chainConfig, hash, header.Number.Uint64(), header.Time, header.BaseFee, blobGasPrice, body.Transactions); err != nil {
+		return nil, err
+	}
+	s.receipts.Add(hash, receipts)
+	return receipts, nil
+}
@@ -0,0 +1,299 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package headersync
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	btypes "github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testEmitter = common.HexToAddress("0xe1")
+	testTopic   = common.HexToHash("0x2a")
+)
+
+// testUpstream is a minimal execution RPC endpoint serving a pre-generated chain,
+// optionally tampering with its responses.
+type testUpstream struct {
+	config   *params.ChainConfig
+	blocks   []*types.Block
+	receipts []types.Receipts
+	byHash   map[common.Hash]int
+	forge    bool // Whether to return forged receipts
+}
+
+func (u *testUpstream) GetBlockByHash(hash common.Hash, fullTx bool) map[string]interface{} {
+	if i, ok := u.byHash[hash]; ok {
+		return ethapi.RPCMarshalBlock(u.blocks[i], true, fullTx, u.config)
+	}
+	return nil
+}
+
+func (u *testUpstream) GetBlockByNumber(number rpc.BlockNumber, fullTx bool) map[string]interface{} {
+	if number < 0 || int(number) >= len(u.blocks) {
+		return nil
+	}
+	return ethapi.RPCMarshalBlock(u.blocks[number], true, fullTx, u.config)
+}
+
+func (u *testUpstream) GetBlockReceipts(blockNrOrHash rpc.BlockNumberOrHash) ([]*types.Receipt, error) {
+	hash, ok := blockNrOrHash.Hash()
+	if !ok {
+		return nil, errors.New("only hash queries supported")
+	}
+	i, ok := u.byHash[hash]
+	if !ok {
+		return nil, nil
+	}
+	receipts := u.receipts[i]
+	if u.forge && len(receipts) > 0 {
+		forged := *receipts[0]
+		forged.Logs = []*types.Log{}
+		receipts = append(types.Receipts{&forged}, receipts[1:]...)
+	}
+	return receipts, nil
+}
+
+// testHeadSource is a fake beacon light client announcing chain heads.
+type testHeadSource struct {
+	feed event.Feed
+}
+
+func (s *testHeadSource) SubscribeChainHeadEvent(ch chan<- btypes.ChainHeadEvent) event.Subscription {
+	return s.feed.Subscribe(ch)
+}
+
+// newTestChain generates a chain where every block contains a transaction
+// calling a contract that emits a single log.
+func newTestChain(t *testing.T, n int) *testUpstream {
+	t.Helper()
+
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			testAddr: {Balance: big.NewInt(params.Ether)},
+			// PUSH1 0x2a PUSH1 0x00 PUSH1 0x00 LOG1 STOP
+			testEmitter: {Balance: common.Big0, Code: common.FromHex("602a60006000a100")},
+		},
+	}
+	signer := types.LatestSigner(gspec.Config)
+	_, blocks, receipts := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), n, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{
+			Nonce:    gen.TxNonce(testAddr),
+			To:       &testEmitter,
+			Gas:      50000,
+			GasPrice: gen.BaseFee(),
+		}), signer, testKey)
+		gen.AddTx(tx)
+	})
+	genesis := gspec.ToBlock()
+	u := &testUpstream{
+		config:   gspec.Config,
+		blocks:   append([]*types.Block{genesis}, blocks...),
+		receipts: append([]types.Receipts{nil}, receipts...),
+		byHash:   make(map[common.Hash]int),
+	}
+	for i, block := range u.blocks {
+		u.byHash[block.Hash()] = i
+	}
+	return u
+}
+
+// newTestService creates a header-only sync service with the given upstream.
+func newTestService(t *testing.T, upstream *testUpstream, config Config) (*Service, *testHeadSource) {
+	t.Helper()
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", upstream); err != nil {
+		t.Fatalf("failed to register upstream: %v", err)
+	}
+	t.Cleanup(srv.Stop)
+
+	s, err := New(rawdb.NewMemoryDatabase(), upstream.config, new(testHeadSource), config)
+	if err != nil {
+		t.Fatalf("failed to create service: %v", err)
+	}
+	s.upstreams.clients = append(s.upstreams.clients, rpc.DialInProc(srv))
+	t.Cleanup(s.upstreams.close)
+	return s, s.source.(*testHeadSource)
+}
+
+// Tests that announcing a head fills the history behind it and that later heads
+// link up with the existing chain.
+func TestHeadBackfill(t *testing.T) {
+	upstream := newTestChain(t, 300)
+	s, _ := newTestService(t, upstream, Config{History: 200})
+
+	head := upstream.blocks[250].Header()
+	if err := s.processHead(context.Background(), head, upstream.blocks[240].Hash()); err != nil {
+		t.Fatalf("failed to process head: %v", err)
+	}
+	if have := s.chain.CurrentHeader().Hash(); have != head.Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", have, head.Hash())
+	}
+	if have, want := s.chain.Tail(), uint64(50); have != want {
+		t.Fatalf("tail mismatch: have %d, want %d", have, want)
+	}
+	if have := s.chain.CurrentFinalizedHeader(); have == nil || have.Hash() != upstream.blocks[240].Hash() {
+		t.Fatalf("finalized header mismatch")
+	}
+	for n := 50; n <= 250; n++ {
+		if have := s.chain.GetHeaderByNumber(uint64(n)); have == nil || have.Hash() != upstream.blocks[n].Hash() {
+			t.Fatalf("canonical header #%d mismatch", n)
+		}
+	}
+	if s.chain.GetHeaderByNumber(49) != nil {
+		t.Fatalf("header beyond history retrieved")
+	}
+	// Announce a later head and ensure the gap is filled
+	head = upstream.blocks[300].Header()
+	if err := s.processHead(context.Background(), head, upstream.blocks[290].Hash()); err != nil {
+		t.Fatalf("failed to process head: %v", err)
+	}
+	if have, want := s.chain.Tail(), uint64(50); have != want {
+		t.Fatalf("tail mismatch: have %d, want %d", have, want)
+	}
+	for n := 251; n <= 300; n++ {
+		if have := s.chain.GetHeaderByNumber(uint64(n)); have == nil || have.Hash() != upstream.blocks[n].Hash() {
+			t.Fatalf("canonical header #%d mismatch", n)
+		}
+	}
+	// Reload the chain from the database and ensure the state is retained
+	chain := NewHeaderChain(s.chain.db)
+	if chain.CurrentHeader().Hash() != head.Hash() || chain.Tail() != 50 {
+		t.Fatalf("reloaded chain mismatch: head #%d, tail %d", chain.CurrentHeader().Number, chain.Tail())
+	}
+}
+
+// Tests that heads announced by the light client are processed by the service
+// loop once started.
+func TestHeadFollowing(t *testing.T) {
+	upstream := newTestChain(t, 16)
+	s, source := newTestService(t, upstream, Config{History: 16})
+
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start service: %v", err)
+	}
+	defer s.Stop()
+
+	for _, n := range []int{10, 16} {
+		source.feed.Send(btypes.ChainHeadEvent{Block: upstream.blocks[n], Finalized: upstream.blocks[n-4].Hash()})
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if head := s.chain.CurrentHeader(); head != nil && head.Number.Uint64() == 16 {
+			break
+		}
+	}
+	if have := s.chain.CurrentHeader(); have == nil || have.Number.Uint64() != 16 {
+		t.Fatalf("head not followed: have %v", have)
+	}
+}
+
+// Tests that logs are served from verified receipts, and that forged upstream
+// responses are rejected.
+func TestGetLogs(t *testing.T) {
+	upstream := newTestChain(t, 32)
+	s, _ := newTestService(t, upstream, Config{History: 32, LogQueryLimit: 16})
+	api := &API{s}
+
+	if err := s.processHead(context.Background(), upstream.blocks[32].Header(), common.Hash{}); err != nil {
+		t.Fatalf("failed to process head: %v", err)
+	}
+	logs, err := api.GetLogs(context.Background(), filters.FilterCriteria{
+		FromBlock: big.NewInt(20),
+		ToBlock:   big.NewInt(29),
+		Addresses: []common.Address{testEmitter},
+		Topics:    [][]common.Hash{{testTopic}},
+	})
+	if err != nil {
+		t.Fatalf("failed to retrieve logs: %v", err)
+	}
+	if len(logs) != 10 {
+		t.Fatalf("log count mismatch: have %d, want %d", len(logs), 10)
+	}
+	for i, log := range logs {
+		block := upstream.blocks[20+i]
+		if log.BlockNumber != block.NumberU64() || log.BlockHash != block.Hash() || log.TxHash != block.Transactions()[0].Hash() {
+			t.Errorf("log %d: metadata mismatch", i)
+		}
+	}
+	// Non-matching topics should be filtered out via the header blooms
+	logs, err = api.GetLogs(context.Background(), filters.FilterCriteria{
+		FromBlock: big.NewInt(20),
+		ToBlock:   big.NewInt(29),
+		Topics:    [][]common.Hash{{common.HexToHash("0xdead")}},
+	})
+	if err != nil || len(logs) != 0 {
+		t.Fatalf("unexpected logs: %d, err %v", len(logs), err)
+	}
+	// Queries over the limit should be rejected
+	if _, err := api.GetLogs(context.Background(), filters.FilterCriteria{FromBlock: big.NewInt(1), ToBlock: big.NewInt(32)}); err == nil {
+		t.Fatalf("expected error for oversized range")
+	}
+	// Forged receipts should be rejected
+	upstream.forge = true
+	hash := upstream.blocks[5].Hash()
+	if _, err := api.GetLogs(context.Background(), filters.FilterCriteria{BlockHash: &hash}); !errors.Is(err, errInvalidResponse) {
+		t.Fatalf("forged receipts not rejected: %v", err)
+	}
+}
+
+// Tests that blocks are assembled from verified bodies.
+func TestGetBlock(t *testing.T) {
+	upstream := newTestChain(t, 8)
+	s, _ := newTestService(t, upstream, Config{History: 8})
+	api := &API{s}
+
+	if err := s.processHead(context.Background(), upstream.blocks[8].Header(), common.Hash{}); err != nil {
+		t.Fatalf("failed to process head: %v", err)
+	}
+	if have, _ := api.BlockNumber(); have != 8 {
+		t.Fatalf("block number mismatch: have %d, want %d", have, 8)
+	}
+	block, err := api.GetBlockByNumber(context.Background(), 4, false)
+	if err != nil {
+		t.Fatalf("failed to retrieve block: %v", err)
+	}
+	if block["hash"] != upstream.blocks[4].Hash() {
+		t.Fatalf("block hash mismatch: have %v, want %x", block["hash"], upstream.blocks[4].Hash())
+	}
+	if txs := block["transactions"].([]interface{}); len(txs) != 1 || txs[0] != upstream.blocks[4].Transactions()[0].Hash() {
+		t.Fatalf("transaction list mismatch: %v", txs)
+	}
+	if header := api.GetHeaderByNumber(context.Background(), rpc.LatestBlockNumber); header["number"].(*hexutil.Big).ToInt().Uint64() != 8 {
+		t.Fatalf("latest header mismatch: %v", header["number"])
+	}
+}
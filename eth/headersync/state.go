@@ -0,0 +1,332 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package headersync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/ethereum/go-ethereum/trie/trienode"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+// errUnsupported is returned for trie operations that cannot be served from a
+// partial, proof-backed state.
+var errUnsupported = errors.New("not supported by proof-backed state")
+
+// proofDatabase is a state database that starts out empty and lazily retrieves
+// the accounts, storage slots and contract codes accessed from the upstream
+// endpoints. Every Merkle proof is verified against the trusted state root and
+// its nodes are stored keyed by their hash, so the regular trie implementation
+// can resolve them afterwards.
+type proofDatabase struct {
+	state.Database // Hash-scheme database over the verified nodes
+
+	ctx       context.Context
+	disk      ethdb.Database
+	upstreams *upstreams
+	header    *types.Header
+}
+
+// newProofDatabase creates an empty proof-backed state database for the state
+// of the given trusted header.
+func newProofDatabase(ctx context.Context, upstreams *upstreams, header *types.Header) *proofDatabase {
+	disk := rawdb.NewMemoryDatabase()
+	return &proofDatabase{
+		Database:  state.NewDatabaseWithConfig(disk, triedb.HashDefaults),
+		ctx:       ctx,
+		disk:      disk,
+		upstreams: upstreams,
+		header:    header,
+	}
+}
+
+// OpenTrie opens the main account trie at a specific root hash.
+func (db *proofDatabase) OpenTrie(root common.Hash) (state.Trie, error) {
+	return &proofTrie{db: db, stateRoot: root, root: root}, nil
+}
+
+// OpenStorageTrie opens the storage trie of an account.
+func (db *proofDatabase) OpenStorageTrie(stateRoot common.Hash, address common.Address, root common.Hash, self state.Trie) (state.Trie, error) {
+	return &proofTrie{db: db, stateRoot: stateRoot, owner: &address, root: root}, nil
+}
+
+// CopyTrie returns an independent copy of the given trie.
+func (db *proofDatabase) CopyTrie(t state.Trie) state.Trie {
+	cpy := *t.(*proofTrie)
+	if cpy.tr != nil {
+		cpy.tr = db.Database.CopyTrie(cpy.tr)
+	}
+	return &cpy
+}
+
+// ContractCode retrieves a particular contract's code, fetching it from the
+// upstream endpoints if not yet available locally.
+func (db *proofDatabase) ContractCode(address common.Address, codeHash common.Hash) ([]byte, error) {
+	if code := rawdb.ReadCode(db.disk, codeHash); len(code) > 0 {
+		return code, nil
+	}
+	ctx, cancel := context.WithTimeout(db.ctx, requestTimeout)
+	defer cancel()
+
+	code, err := db.upstreams.code(ctx, db.header.Hash(), address, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	rawdb.WriteCode(db.disk, codeHash, code)
+	return code, nil
+}
+
+// ContractCodeSize retrieves a particular contracts code's size.
+func (db *proofDatabase) ContractCodeSize(address common.Address, codeHash common.Hash) (int, error) {
+	code, err := db.ContractCode(address, codeHash)
+	return len(code), err
+}
+
+// fetchProof retrieves and verifies the proof of an account and optionally one
+// of its storage slots, storing all the proof nodes locally.
+func (db *proofDatabase) fetchProof(address common.Address, keys []common.Hash) error {
+	ctx, cancel := context.WithTimeout(db.ctx, requestTimeout)
+	defer cancel()
+
+	result, err := db.upstreams.proof(ctx, db.header.Hash(), address, keys)
+	if err != nil {
+		return err
+	}
+	account, nodes, err := verifyAccountProof(db.header.Root, address, result)
+	if err != nil {
+		return err
+	}
+	for i, key := range keys {
+		if account == nil {
+			break // Non-existent account has no storage to prove
+		}
+		storageNodes, err := verifyStorageProof(account.Root, key, result.StorageProof[i])
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, storageNodes...)
+	}
+	batch := db.disk.NewBatch()
+	for _, node := range nodes {
+		rawdb.WriteLegacyTrieNode(batch, crypto.Keccak256Hash(node), node)
+	}
+	return batch.Write()
+}
+
+// decodeProof converts a hex encoded list of proof nodes into a database that
+// can be used for proof verification.
+func decodeProof(proof []string) (*memorydb.Database, [][]byte, error) {
+	var (
+		db    = memorydb.New()
+		nodes = make([][]byte, 0, len(proof))
+	)
+	for _, hex := range proof {
+		node, err := hexutil.Decode(hex)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: invalid proof node: %v", errInvalidResponse, err)
+		}
+		db.Put(crypto.Keccak256(node), node)
+		nodes = append(nodes, node)
+	}
+	return db, nodes, nil
+}
+
+// verifyAccountProof checks the account proof in the given result against the
+// trusted state root, returning the proven account (nil if it does not exist)
+// and the proof nodes.
+func verifyAccountProof(root common.Hash, address common.Address, result *ethapi.AccountResult) (*types.StateAccount, [][]byte, error) {
+	proofDb, nodes, err := decodeProof(result.AccountProof)
+	if err != nil {
+		return nil, nil, err
+	}
+	blob, err := trie.VerifyProof(root, crypto.Keccak256(address.Bytes()), proofDb)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid account proof: %v", errInvalidResponse, err)
+	}
+	if blob == nil {
+		return nil, nodes, nil
+	}
+	account := new(types.StateAccount)
+	if err := rlp.DecodeBytes(blob, account); err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid account: %v", errInvalidResponse, err)
+	}
+	return account, nodes, nil
+}
+
+// verifyStorageProof checks a storage proof against the trusted storage root of
+// the owning account, returning the proof nodes.
+func verifyStorageProof(root common.Hash, key common.Hash, result ethapi.StorageResult) ([][]byte, error) {
+	proofDb, nodes, err := decodeProof(result.Proof)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := trie.VerifyProof(root, crypto.Keccak256(key.Bytes()), proofDb); err != nil {
+		return nil, fmt.Errorf("%w: invalid storage proof: %v", errInvalidResponse, err)
+	}
+	return nodes, nil
+}
+
+// proofTrie is an account or storage trie backed by a proofDatabase, fetching
+// the proofs of the accessed items when they are missing locally.
+type proofTrie struct {
+	db        *proofDatabase
+	stateRoot common.Hash
+	owner     *common.Address // Owning account for storage tries, nil for the account trie
+	root      common.Hash
+	tr        state.Trie // Backing trie, opened lazily as the root might be missing
+}
+
+// open opens the backing trie if it is not yet open.
+func (t *proofTrie) open() error {
+	if t.tr != nil {
+		return nil
+	}
+	var err error
+	if t.owner == nil {
+		t.tr, err = t.db.Database.OpenTrie(t.root)
+	} else {
+		t.tr, err = t.db.Database.OpenStorageTrie(t.stateRoot, *t.owner, t.root, nil)
+	}
+	return err
+}
+
+// resolve runs the given trie access, retrieving the proof via fetch and then
+// retrying once if the access fails due to missing trie nodes.
+func (t *proofTrie) resolve(fetch func() error, access func() error) error {
+	err := t.open()
+	if err == nil {
+		err = access()
+	}
+	var missing *trie.MissingNodeError
+	if !errors.As(err, &missing) {
+		return err
+	}
+	if err := fetch(); err != nil {
+		return err
+	}
+	if err := t.open(); err != nil {
+		return err
+	}
+	return access()
+}
+
+// GetKey returns the sha3 preimage of a hashed key, which is never available.
+func (t *proofTrie) GetKey([]byte) []byte {
+	return nil
+}
+
+// GetAccount retrieves an account, fetching its proof if needed.
+func (t *proofTrie) GetAccount(address common.Address) (*types.StateAccount, error) {
+	var account *types.StateAccount
+	err := t.resolve(func() error {
+		return t.db.fetchProof(address, nil)
+	}, func() (err error) {
+		account, err = t.tr.GetAccount(address)
+		return err
+	})
+	return account, err
+}
+
+// GetStorage retrieves a storage slot, fetching its proof if needed.
+func (t *proofTrie) GetStorage(address common.Address, key []byte) ([]byte, error) {
+	var value []byte
+	err := t.resolve(func() error {
+		return t.db.fetchProof(address, []common.Hash{common.BytesToHash(key)})
+	}, func() (err error) {
+		value, err = t.tr.GetStorage(address, key)
+		return err
+	})
+	return value, err
+}
+
+// UpdateAccount applies an account write to the backing trie.
+func (t *proofTrie) UpdateAccount(address common.Address, account *types.StateAccount) error {
+	if err := t.open(); err != nil {
+		return err
+	}
+	return t.tr.UpdateAccount(address, account)
+}
+
+// UpdateStorage applies a storage write to the backing trie.
+func (t *proofTrie) UpdateStorage(address common.Address, key, value []byte) error {
+	if err := t.open(); err != nil {
+		return err
+	}
+	return t.tr.UpdateStorage(address, key, value)
+}
+
+// DeleteAccount applies an account deletion to the backing trie.
+func (t *proofTrie) DeleteAccount(address common.Address) error {
+	if err := t.open(); err != nil {
+		return err
+	}
+	return t.tr.DeleteAccount(address)
+}
+
+// DeleteStorage applies a storage deletion to the backing trie.
+func (t *proofTrie) DeleteStorage(address common.Address, key []byte) error {
+	if err := t.open(); err != nil {
+		return err
+	}
+	return t.tr.DeleteStorage(address, key)
+}
+
+// UpdateContractCode is a noop, contract codes are not part of the trie.
+func (t *proofTrie) UpdateContractCode(address common.Address, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+// Hash returns the root hash of the trie.
+func (t *proofTrie) Hash() common.Hash {
+	if t.tr == nil {
+		return t.root
+	}
+	return t.tr.Hash()
+}
+
+// Commit is not supported on a proof-backed state, only the root is returned.
+func (t *proofTrie) Commit(collectLeaf bool) (common.Hash, *trienode.NodeSet) {
+	return t.Hash(), nil
+}
+
+// NodeIterator is not supported on a proof-backed state.
+func (t *proofTrie) NodeIterator(startKey []byte) (trie.NodeIterator, error) {
+	return nil, errUnsupported
+}
+
+// Prove is not supported on a proof-backed state.
+func (t *proofTrie) Prove(key []byte, proofDb ethdb.KeyValueWriter) error {
+	return errUnsupported
+}
+
+// IsVerkle returns false, proof-backed states are always Merkle-Patricia tries.
+func (t *proofTrie) IsVerkle() bool {
+	return false
+}
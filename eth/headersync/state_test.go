@@ -0,0 +1,179 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package headersync
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+)
+
+// proofList collects trie proof nodes as hex strings, like eth_getProof does.
+type proofList []string
+
+func (n *proofList) Put(key []byte, value []byte) error {
+	*n = append(*n, hexutil.Encode(value))
+	return nil
+}
+
+func (n *proofList) Delete(key []byte) error {
+	panic("not supported")
+}
+
+// newTestState creates a state with a number of accounts, each having a few
+// storage slots, returning the state root and the committed database.
+func newTestState(t *testing.T) (common.Hash, state.Database) {
+	t.Helper()
+
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(types.EmptyRootHash, db, nil)
+	for i := byte(1); i <= 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		statedb.SetBalance(addr, uint256.NewInt(uint64(i)*1000), tracing.BalanceChangeUnspecified)
+		statedb.SetNonce(addr, uint64(i))
+		for j := byte(1); j <= 8; j++ {
+			statedb.SetState(addr, common.BytesToHash([]byte{j}), common.BytesToHash([]byte{i, j}))
+		}
+	}
+	root, err := statedb.Commit(0, false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	return root, db
+}
+
+// proveAccount assembles an eth_getProof style result for the given account
+// and storage keys.
+func proveAccount(t *testing.T, db state.Database, root common.Hash, addr common.Address, keys []common.Hash) *ethapi.AccountResult {
+	t.Helper()
+
+	tr, err := trie.NewStateTrie(trie.StateTrieID(root), db.TrieDB())
+	if err != nil {
+		t.Fatalf("failed to open account trie: %v", err)
+	}
+	var accountProof proofList
+	if err := tr.Prove(crypto.Keccak256(addr.Bytes()), &accountProof); err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	result := &ethapi.AccountResult{Address: addr, AccountProof: accountProof}
+
+	account, err := tr.GetAccount(addr)
+	if err != nil || account == nil {
+		return result
+	}
+	st, err := trie.NewStateTrie(trie.StorageTrieID(root, crypto.Keccak256Hash(addr.Bytes()), account.Root), db.TrieDB())
+	if err != nil {
+		t.Fatalf("failed to open storage trie: %v", err)
+	}
+	for _, key := range keys {
+		var storageProof proofList
+		if err := st.Prove(crypto.Keccak256(key.Bytes()), &storageProof); err != nil {
+			t.Fatalf("failed to prove storage: %v", err)
+		}
+		result.StorageProof = append(result.StorageProof, ethapi.StorageResult{Key: key.Hex(), Proof: storageProof})
+	}
+	return result
+}
+
+// tamper returns a copy of the proof with one byte flipped in the given node.
+func tamper(proof []string, index int) []string {
+	cpy := append([]string{}, proof...)
+	node := hexutil.MustDecode(cpy[index])
+	node[len(node)-1] ^= 0xff
+	cpy[index] = hexutil.Encode(node)
+	return cpy
+}
+
+// Tests that account proofs are verified against the trusted state root.
+func TestVerifyAccountProof(t *testing.T) {
+	root, db := newTestState(t)
+
+	// Existing accounts are proven with their content
+	addr := common.BytesToAddress([]byte{5})
+	result := proveAccount(t, db, root, addr, nil)
+	account, nodes, err := verifyAccountProof(root, addr, result)
+	if err != nil {
+		t.Fatalf("failed to verify valid proof: %v", err)
+	}
+	if account == nil || account.Nonce != 5 || account.Balance.Uint64() != 5000 {
+		t.Fatalf("wrong account proven: %+v", account)
+	}
+	if len(nodes) != len(result.AccountProof) {
+		t.Fatalf("wrong number of proof nodes: have %d, want %d", len(nodes), len(result.AccountProof))
+	}
+	// Missing accounts are proven as absent
+	missing := common.HexToAddress("0xdead")
+	if account, _, err := verifyAccountProof(root, missing, proveAccount(t, db, root, missing, nil)); err != nil || account != nil {
+		t.Fatalf("absent account: have %+v (err %v), want nil", account, err)
+	}
+	// Tampered proofs, proofs of other accounts and wrong roots are rejected
+	for i := range result.AccountProof {
+		forged := *result
+		forged.AccountProof = tamper(result.AccountProof, i)
+		if _, _, err := verifyAccountProof(root, addr, &forged); !errors.Is(err, errInvalidResponse) {
+			t.Errorf("tampered node %d: have error %v, want %v", i, err, errInvalidResponse)
+		}
+	}
+	other := proveAccount(t, db, root, common.BytesToAddress([]byte{6}), nil)
+	if account, _, err := verifyAccountProof(root, addr, other); err == nil && account != nil {
+		t.Errorf("proof of another account accepted: %+v", account)
+	}
+	if _, _, err := verifyAccountProof(common.Hash{1}, addr, result); !errors.Is(err, errInvalidResponse) {
+		t.Errorf("wrong root: have error %v, want %v", err, errInvalidResponse)
+	}
+}
+
+// Tests that storage proofs are verified against the proven storage root.
+func TestVerifyStorageProof(t *testing.T) {
+	root, db := newTestState(t)
+
+	addr := common.BytesToAddress([]byte{3})
+	keys := []common.Hash{common.BytesToHash([]byte{2}), common.BytesToHash([]byte{42})}
+	result := proveAccount(t, db, root, addr, keys)
+	account, _, err := verifyAccountProof(root, addr, result)
+	if err != nil {
+		t.Fatalf("failed to verify account proof: %v", err)
+	}
+	for i, key := range keys {
+		t.Run(fmt.Sprintf("key-%x", key[len(key)-1]), func(t *testing.T) {
+			if _, err := verifyStorageProof(account.Root, key, result.StorageProof[i]); err != nil {
+				t.Fatalf("failed to verify valid proof: %v", err)
+			}
+			for j := range result.StorageProof[i].Proof {
+				forged := result.StorageProof[i]
+				forged.Proof = tamper(forged.Proof, j)
+				if _, err := verifyStorageProof(account.Root, key, forged); !errors.Is(err, errInvalidResponse) {
+					t.Errorf("tampered node %d: have error %v, want %v", j, err, errInvalidResponse)
+				}
+			}
+			if _, err := verifyStorageProof(root, key, result.StorageProof[i]); !errors.Is(err, errInvalidResponse) {
+				t.Errorf("wrong root: have error %v, want %v", err, errInvalidResponse)
+			}
+		})
+	}
+}
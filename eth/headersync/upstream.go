@@ -0,0 +1,255 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package headersync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+var (
+	// errNoUpstream is returned if data needs to be retrieved but no upstream
+	// endpoints are configured.
+	errNoUpstream = errors.New("no upstream endpoints configured")
+
+	// errInvalidResponse is returned if an upstream response does not match the
+	// commitments in the locally trusted header.
+	errInvalidResponse = errors.New("invalid upstream response")
+)
+
+// upstreams is a set of untrusted execution RPC endpoints used to retrieve chain
+// data on demand, in place of eth protocol peers. All retrieved data is verified
+// against the trusted headers, so a misbehaving endpoint can only withhold data,
+// not forge it.
+type upstreams struct {
+	clients []*rpc.Client
+	next    atomic.Uint32 // Index of the next endpoint to try first
+}
+
+// dialUpstreams connects to all the given endpoints.
+func dialUpstreams(urls []string) (*upstreams, error) {
+	u := new(upstreams)
+	for _, url := range urls {
+		client, err := rpc.Dial(url)
+		if err != nil {
+			u.close()
+			return nil, fmt.Errorf("failed to dial upstream %s: %w", url, err)
+		}
+		u.clients = append(u.clients, client)
+	}
+	return u, nil
+}
+
+// close disconnects from all the upstream endpoints.
+func (u *upstreams) close() {
+	for _, client := range u.clients {
+		client.Close()
+	}
+}
+
+// do runs the given retrieval against the upstream endpoints in a round-robin
+// fashion, moving on to the next endpoint if one fails.
+func (u *upstreams) do(ctx context.Context, fn func(client *rpc.Client) error) error {
+	if len(u.clients) == 0 {
+		return errNoUpstream
+	}
+	var (
+		first = int(u.next.Add(1)) % len(u.clients)
+		err   error
+	)
+	for i := 0; i < len(u.clients); i++ {
+		client := u.clients[(first+i)%len(u.clients)]
+		if err = fn(client); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Debug("Upstream retrieval failed", "err", err)
+	}
+	return err
+}
+
+// headerByHash retrieves the header with the given hash, verifying that the
+// returned header indeed hashes to the requested value.
+func (u *upstreams) headerByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	var header *types.Header
+	err := u.do(ctx, func(client *rpc.Client) error {
+		h, err := ethclient.NewClient(client).HeaderByHash(ctx, hash)
+		if err != nil {
+			return err
+		}
+		if h.Hash() != hash {
+			return fmt.Errorf("%w: header hash mismatch: have %x, want %x", errInvalidResponse, h.Hash(), hash)
+		}
+		header = h
+		return nil
+	})
+	return header, err
+}
+
+// headersByNumber retrieves the canonical headers in the range [from, to] as
+// seen by a single upstream endpoint. The returned headers are NOT verified,
+// it is up to the caller to link them to a trusted header via parent hashes.
+func (u *upstreams) headersByNumber(ctx context.Context, from, to uint64) ([]*types.Header, error) {
+	var headers []*types.Header
+	err := u.do(ctx, func(client *rpc.Client) error {
+		var (
+			batch   = make([]rpc.BatchElem, 0, to-from+1)
+			results = make([]*types.Header, to-from+1)
+		)
+		for i := range results {
+			batch = append(batch, rpc.BatchElem{
+				Method: "eth_getBlockByNumber",
+				Args:   []interface{}{hexutil.EncodeBig(new(big.Int).SetUint64(from + uint64(i))), false},
+				Result: &results[i],
+			})
+		}
+		if err := client.BatchCallContext(ctx, batch); err != nil {
+			return err
+		}
+		for i, elem := range batch {
+			if elem.Error != nil {
+				return elem.Error
+			}
+			if results[i] == nil {
+				return fmt.Errorf("missing header #%d", from+uint64(i))
+			}
+		}
+		headers = results
+		return nil
+	})
+	return headers, err
+}
+
+// body retrieves the body of the given trusted header, verifying the returned
+// transactions, uncles and withdrawals against the header commitments.
+func (u *upstreams) body(ctx context.Context, header *types.Header) (*types.Body, error) {
+	var body *types.Body
+	err := u.do(ctx, func(client *rpc.Client) error {
+		block, err := ethclient.NewClient(client).BlockByHash(ctx, header.Hash())
+		if err != nil {
+			return err
+		}
+		b := block.Body()
+		if err := verifyBody(header, b); err != nil {
+			return err
+		}
+		body = b
+		return nil
+	})
+	return body, err
+}
+
+// verifyBody checks that the given block body matches the commitments in the
+// trusted header.
+func verifyBody(header *types.Header, body *types.Body) error {
+	if hash := types.DeriveSha(types.Transactions(body.Transactions), trie.NewStackTrie(nil)); hash != header.TxHash {
+		return fmt.Errorf("%w: transaction root mismatch: have %x, want %x", errInvalidResponse, hash, header.TxHash)
+	}
+	if hash := types.CalcUncleHash(body.Uncles); hash != header.UncleHash {
+		return fmt.Errorf("%w: uncle root mismatch: have %x, want %x", errInvalidResponse, hash, header.UncleHash)
+	}
+	if header.WithdrawalsHash == nil {
+		if body.Withdrawals != nil {
+			return fmt.Errorf("%w: unexpected withdrawals", errInvalidResponse)
+		}
+		return nil
+	}
+	if body.Withdrawals == nil {
+		return fmt.Errorf("%w: missing withdrawals", errInvalidResponse)
+	}
+	if hash := types.DeriveSha(types.Withdrawals(body.Withdrawals), trie.NewStackTrie(nil)); hash != *header.WithdrawalsHash {
+		return fmt.Errorf("%w: withdrawal root mismatch: have %x, want %x", errInvalidResponse, hash, *header.WithdrawalsHash)
+	}
+	return nil
+}
+
+// receipts retrieves the receipts of the given trusted header, verifying them
+// against the receipt root in the header.
+func (u *upstreams) receipts(ctx context.Context, header *types.Header) (types.Receipts, error) {
+	var receipts types.Receipts
+	err := u.do(ctx, func(client *rpc.Client) error {
+		r, err := ethclient.NewClient(client).BlockReceipts(ctx, rpc.BlockNumberOrHashWithHash(header.Hash(), false))
+		if err != nil {
+			return err
+		}
+		if hash := types.DeriveSha(types.Receipts(r), trie.NewStackTrie(nil)); hash != header.ReceiptHash {
+			return fmt.Errorf("%w: receipt root mismatch: have %x, want %x", errInvalidResponse, hash, header.ReceiptHash)
+		}
+		receipts = r
+		return nil
+	})
+	return receipts, err
+}
+
+// proof retrieves the Merkle proofs of an account and some of its storage slots
+// at the state of the given block. The returned proofs are NOT verified, it is
+// up to the caller to check them against the trusted state root.
+func (u *upstreams) proof(ctx context.Context, block common.Hash, address common.Address, keys []common.Hash) (*ethapi.AccountResult, error) {
+	var result *ethapi.AccountResult
+	err := u.do(ctx, func(client *rpc.Client) error {
+		hexKeys := make([]string, len(keys))
+		for i, key := range keys {
+			hexKeys[i] = key.Hex()
+		}
+		var res *ethapi.AccountResult
+		if err := client.CallContext(ctx, &res, "eth_getProof", address, hexKeys, rpc.BlockNumberOrHashWithHash(block, false)); err != nil {
+			return err
+		}
+		if res == nil {
+			return fmt.Errorf("missing proof for %x", address)
+		}
+		if len(res.StorageProof) != len(keys) {
+			return fmt.Errorf("%w: storage proof count mismatch: have %d, want %d", errInvalidResponse, len(res.StorageProof), len(keys))
+		}
+		result = res
+		return nil
+	})
+	return result, err
+}
+
+// code retrieves the contract code with the given hash, deployed at the given
+// address in the state of the given block, verifying it against the hash.
+func (u *upstreams) code(ctx context.Context, block common.Hash, address common.Address, hash common.Hash) ([]byte, error) {
+	var code []byte
+	err := u.do(ctx, func(client *rpc.Client) error {
+		var res hexutil.Bytes
+		if err := client.CallContext(ctx, &res, "eth_getCode", address, rpc.BlockNumberOrHashWithHash(block, false)); err != nil {
+			return err
+		}
+		if have := crypto.Keccak256Hash(res); have != hash {
+			return fmt.Errorf("%w: code hash mismatch: have %x, want %x", errInvalidResponse, have, hash)
+		}
+		code = res
+		return nil
+	})
+	return code, err
+}
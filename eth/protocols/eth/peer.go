@@ -53,6 +53,8 @@ type Peer struct {
 	head common.Hash // Latest advertised head block hash
 	td   *big.Int    // Latest advertised head block total difficulty
 
+	blockRange *BlockRangeUpdatePacket // Latest advertised block range (eth/69+, nil otherwise)
+
 	txpool      TxPool             // Transaction pool used by the broadcasters for liveness checks
 	knownTxs    *knownCache        // Set of transaction hashes known to be known by this peer
 	txBroadcast chan []common.Hash // Channel used to queue transaction propagation requests
@@ -126,6 +128,38 @@ func (p *Peer) SetHead(hash common.Hash, td *big.Int) {
 	p.td.Set(td)
 }
 
+// BlockRange retrieves the latest range of blocks the peer advertised to be
+// able to serve. It returns nil for peers on protocol versions predating eth/69.
+func (p *Peer) BlockRange() *BlockRangeUpdatePacket {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.blockRange == nil {
+		return nil
+	}
+	blockRange := *p.blockRange
+	return &blockRange
+}
+
+// setBlockRange updates the range of blocks available from the peer, also
+// moving its head to the latest announced block.
+func (p *Peer) setBlockRange(blockRange *BlockRangeUpdatePacket) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.blockRange = blockRange
+	p.head = blockRange.LatestBlockHash
+}
+
+// SendBlockRangeUpdate announces a change in the range of locally available
+// blocks to the peer. It is a noop for peers on versions predating eth/69.
+func (p *Peer) SendBlockRangeUpdate(blockRange BlockRangeUpdatePacket) error {
+	if p.version < ETH69 {
+		return nil
+	}
+	return p2p.Send(p.rw, BlockRangeUpdateMsg, &blockRange)
+}
+
 // KnownTransaction returns whether peer is known to already have a transaction.
 func (p *Peer) KnownTransaction(hash common.Hash) bool {
 	return p.knownTxs.Contains(hash)
@@ -31,6 +31,7 @@ import (
 // Constants to match up protocol versions and messages
 const (
 	ETH68 = 68
+	ETH69 = 69
 )
 
 // ProtocolName is the official short name of the `eth` protocol used during
@@ -39,11 +40,11 @@ const ProtocolName = "eth"
 
 // ProtocolVersions are the supported versions of the `eth` protocol (first
 // is primary).
-var ProtocolVersions = []uint{ETH68}
+var ProtocolVersions = []uint{ETH69, ETH68}
 
 // protocolLengths are the number of implemented message corresponding to
 // different protocol versions.
-var protocolLengths = map[uint]uint64{ETH68: 17}
+var protocolLengths = map[uint]uint64{ETH68: 17, ETH69: 18}
 
 // maxMessageSize is the maximum cap on the size of a protocol message.
 const maxMessageSize = 10 * 1024 * 1024
@@ -62,6 +63,7 @@ const (
 	PooledTransactionsMsg         = 0x0a
 	GetReceiptsMsg                = 0x0f
 	ReceiptsMsg                   = 0x10
+	BlockRangeUpdateMsg           = 0x11
 )
 
 var (
@@ -73,6 +75,7 @@ var (
 	errNetworkIDMismatch       = errors.New("network ID mismatch")
 	errGenesisMismatch         = errors.New("genesis mismatch")
 	errForkIDRejected          = errors.New("fork ID rejected")
+	errInvalidBlockRange       = errors.New("invalid block range")
 )
 
 // Packet represents a p2p message in the `eth` protocol.
@@ -91,6 +94,43 @@ type StatusPacket struct {
 	ForkID          forkid.ID
 }
 
+// StatusPacket69 is the network packet for the status message on eth/69 and
+// newer. Compared to previous versions, the total difficulty is dropped and the
+// range of blocks available for serving is advertised instead.
+type StatusPacket69 struct {
+	ProtocolVersion uint32
+	NetworkID       uint64
+	Genesis         common.Hash
+	ForkID          forkid.ID
+	EarliestBlock   uint64      // Oldest block whose body and receipts are available
+	LatestBlock     uint64      // Number of the latest available block
+	LatestBlockHash common.Hash // Hash of the latest available block
+}
+
+// BlockRangeUpdatePacket is the network packet announcing a change in the range
+// of blocks the sender is able to serve (eth/69 and newer).
+type BlockRangeUpdatePacket struct {
+	EarliestBlock   uint64      // Oldest block whose body and receipts are available
+	LatestBlock     uint64      // Number of the latest available block
+	LatestBlockHash common.Hash // Hash of the latest available block
+}
+
+// Validate checks that the announced block range is well formed.
+func (p *BlockRangeUpdatePacket) Validate() error {
+	if p.EarliestBlock > p.LatestBlock {
+		return fmt.Errorf("%w: earliest %d > latest %d", errInvalidBlockRange, p.EarliestBlock, p.LatestBlock)
+	}
+	if p.LatestBlockHash == (common.Hash{}) {
+		return fmt.Errorf("%w: zero latest block hash", errInvalidBlockRange)
+	}
+	return nil
+}
+
+// Contains reports whether the given block number is within the range.
+func (p *BlockRangeUpdatePacket) Contains(number uint64) bool {
+	return p.EarliestBlock <= number && number <= p.LatestBlock
+}
+
 // NewBlockHashesPacket is the network packet for the block announcements.
 type NewBlockHashesPacket []struct {
 	Hash   common.Hash // Hash of one particular block being announced
@@ -260,6 +300,85 @@ type ReceiptsPacket struct {
 	ReceiptsResponse
 }
 
+// Receipt69 is the eth/69 network representation of a receipt. Compared to
+// the consensus encoding, the logs bloom is omitted (it's recomputed by the
+// receiver) and the transaction type is always a list field, regardless of
+// whether the receipt is legacy or typed.
+type Receipt69 struct {
+	TxType            byte
+	PostStateOrStatus []byte
+	CumulativeGasUsed uint64
+	Logs              []*types.Log
+}
+
+// newReceipt69 converts a consensus receipt into its eth/69 network form.
+func newReceipt69(r *types.Receipt) *Receipt69 {
+	status := r.PostState
+	if len(status) == 0 {
+		if r.Status == types.ReceiptStatusSuccessful {
+			status = []byte{0x01}
+		} else {
+			status = []byte{}
+		}
+	}
+	return &Receipt69{
+		TxType:            r.Type,
+		PostStateOrStatus: status,
+		CumulativeGasUsed: r.CumulativeGasUsed,
+		Logs:              r.Logs,
+	}
+}
+
+// Receipt converts the network receipt into a consensus one, reconstructing
+// the logs bloom locally.
+func (r *Receipt69) Receipt() (*types.Receipt, error) {
+	receipt := &types.Receipt{
+		Type:              r.TxType,
+		CumulativeGasUsed: r.CumulativeGasUsed,
+		Logs:              r.Logs,
+	}
+	switch {
+	case len(r.PostStateOrStatus) == len(common.Hash{}):
+		receipt.PostState = r.PostStateOrStatus
+	case len(r.PostStateOrStatus) == 1 && r.PostStateOrStatus[0] == 0x01:
+		receipt.Status = types.ReceiptStatusSuccessful
+	case len(r.PostStateOrStatus) == 0:
+		receipt.Status = types.ReceiptStatusFailed
+	default:
+		return nil, fmt.Errorf("invalid receipt status %x", r.PostStateOrStatus)
+	}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	return receipt, nil
+}
+
+// ReceiptsResponse69 is the eth/69 network packet for block receipts
+// distribution.
+type ReceiptsResponse69 [][]*Receipt69
+
+// ReceiptsPacket69 is the eth/69 network packet for block receipts distribution
+// with request ID wrapping.
+type ReceiptsPacket69 struct {
+	RequestId uint64
+	ReceiptsResponse69
+}
+
+// Unpack converts the network receipts into consensus ones, reconstructing
+// the logs blooms along the way.
+func (p *ReceiptsResponse69) Unpack() (ReceiptsResponse, error) {
+	res := make(ReceiptsResponse, len(*p))
+	for i, receipts := range *p {
+		res[i] = make([]*types.Receipt, len(receipts))
+		for j, receipt := range receipts {
+			r, err := receipt.Receipt()
+			if err != nil {
+				return nil, err
+			}
+			res[i][j] = r
+		}
+	}
+	return res, nil
+}
+
 // ReceiptsRLPResponse is used for receipts, when we already have it encoded
 type ReceiptsRLPResponse []rlp.RawValue
 
@@ -308,6 +427,9 @@ type PooledTransactionsRLPPacket struct {
 func (*StatusPacket) Name() string { return "Status" }
 func (*StatusPacket) Kind() byte   { return StatusMsg }
 
+func (*StatusPacket69) Name() string { return "Status" }
+func (*StatusPacket69) Kind() byte   { return StatusMsg }
+
 func (*NewBlockHashesPacket) Name() string { return "NewBlockHashes" }
 func (*NewBlockHashesPacket) Kind() byte   { return NewBlockHashesMsg }
 
@@ -343,3 +465,9 @@ func (*GetReceiptsRequest) Kind() byte   { return GetReceiptsMsg }
 
 func (*ReceiptsResponse) Name() string { return "Receipts" }
 func (*ReceiptsResponse) Kind() byte   { return ReceiptsMsg }
+
+func (*ReceiptsResponse69) Name() string { return "Receipts" }
+func (*ReceiptsResponse69) Kind() byte   { return ReceiptsMsg }
+
+func (*BlockRangeUpdatePacket) Name() string { return "BlockRangeUpdate" }
+func (*BlockRangeUpdatePacket) Kind() byte   { return BlockRangeUpdateMsg }
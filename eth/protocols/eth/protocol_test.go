@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Tests that the custom union field encoder and decoder works correctly.
@@ -246,3 +247,55 @@ func TestMessages(t *testing.T) {
 		}
 	}
 }
+
+// Tests that eth/69 receipts survive a network roundtrip, with the blooms being
+// correctly reconstructed on the receiving side.
+func TestReceipts69Roundtrip(t *testing.T) {
+	receipts := []*types.Receipt{
+		{
+			Type:              types.LegacyTxType,
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: 21000,
+			Logs: []*types.Log{{
+				Address: common.Address{0x11},
+				Topics:  []common.Hash{{0x22}, {0x33}},
+				Data:    []byte{0x44},
+			}},
+		},
+		{
+			Type:              types.DynamicFeeTxType,
+			Status:            types.ReceiptStatusFailed,
+			CumulativeGasUsed: 42000,
+			Logs:              []*types.Log{},
+		},
+		{
+			Type:              types.LegacyTxType,
+			PostState:         common.Hash{0x55}.Bytes(),
+			CumulativeGasUsed: 63000,
+			Logs:              []*types.Log{},
+		},
+	}
+	for _, receipt := range receipts {
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	}
+	packet := &ReceiptsPacket69{RequestId: 1, ReceiptsResponse69: ReceiptsResponse69{make([]*Receipt69, len(receipts))}}
+	for i, receipt := range receipts {
+		packet.ReceiptsResponse69[0][i] = newReceipt69(receipt)
+	}
+	blob, err := rlp.EncodeToBytes(packet)
+	if err != nil {
+		t.Fatalf("failed to encode packet: %v", err)
+	}
+	decoded := new(ReceiptsPacket69)
+	if err := rlp.DecodeBytes(blob, decoded); err != nil {
+		t.Fatalf("failed to decode packet: %v", err)
+	}
+	unpacked, err := decoded.ReceiptsResponse69.Unpack()
+	if err != nil {
+		t.Fatalf("failed to unpack receipts: %v", err)
+	}
+	want := types.DeriveSha(types.Receipts(receipts), trie.NewStackTrie(nil))
+	if have := types.DeriveSha(types.Receipts(unpacked[0]), trie.NewStackTrie(nil)); have != want {
+		t.Fatalf("receipt root mismatch: have %x, want %x", have, want)
+	}
+}
@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -54,6 +55,11 @@ const (
 	// If we spend too much time, then it's a fairly high chance of timing out
 	// at the remote side, which means all the work is in vain.
 	maxTrieNodeTimeSpent = 5 * time.Second
+
+	// maxTrieRangeTimeSpent is the maximum time we should spend on iterating a
+	// state range directly from the trie if the snapshot is not available. The
+	// response is cut short and proven up to the last item served beyond that.
+	maxTrieRangeTimeSpent = 2 * time.Second
 )
 
 // Handler is a callback to invoke from an outside runner after the boilerplate
@@ -283,12 +289,14 @@ func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePac
 	if req.Bytes > softResponseLimit {
 		req.Bytes = softResponseLimit
 	}
+	start := time.Now()
+
 	// Retrieve the requested state and bail out if non existent
 	tr, err := trie.New(trie.StateTrieID(req.Root), chain.TrieDB())
 	if err != nil {
 		return nil, nil
 	}
-	it, err := chain.Snapshots().AccountIterator(req.Root, req.Origin)
+	it, fromTrie, err := newAccountRangeIterator(chain, tr, req.Root, req.Origin)
 	if err != nil {
 		return nil, nil
 	}
@@ -317,6 +325,11 @@ func ServiceGetAccountRangeQuery(chain *core.BlockChain, req *GetAccountRangePac
 		if size > req.Bytes {
 			break
 		}
+		// If the trie iteration is taking too long, abort
+		if fromTrie && time.Since(start) > maxTrieRangeTimeSpent {
+			trieRangeTimeoutMeter.Mark(1)
+			break
+		}
 	}
 	it.Release()
 
@@ -355,13 +368,30 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 		slots  [][]*StorageData
 		proofs [][]byte
 		size   uint64
+		start  = time.Now()
+		served bool // Whether any range was served from the trie
+
+		accTrie    *trie.StateTrie
+		accTrieErr error
 	)
+	// openAccTrie lazily opens the account trie, needed both for proving and for
+	// serving from the trie if the snapshot is unavailable.
+	openAccTrie := func() (*trie.StateTrie, error) {
+		if accTrie == nil && accTrieErr == nil {
+			accTrie, accTrieErr = trie.NewStateTrie(trie.StateTrieID(req.Root), chain.TrieDB())
+		}
+		return accTrie, accTrieErr
+	}
 	for _, account := range req.Accounts {
 		// If we've exceeded the requested data limit, abort without opening
 		// a new storage range (that we'd need to prove due to exceeded size)
 		if size >= req.Bytes {
 			break
 		}
+		if served && time.Since(start) > maxTrieRangeTimeSpent {
+			trieRangeTimeoutMeter.Mark(1)
+			break
+		}
 		// The first account might start from a different origin and end sooner
 		var origin common.Hash
 		if len(req.Origin) > 0 {
@@ -372,10 +402,11 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 			limit, req.Limit = common.BytesToHash(req.Limit), nil
 		}
 		// Retrieve the requested state and bail out if non existent
-		it, err := chain.Snapshots().StorageIterator(req.Root, account, origin)
+		it, fromTrie, err := newStorageRangeIterator(chain, openAccTrie, req.Root, account, origin)
 		if err != nil {
 			return nil, nil
 		}
+		served = served || fromTrie
 		// Iterate over the requested range and pile slots up
 		var (
 			storage []*StorageData
@@ -402,6 +433,12 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 			if bytes.Compare(hash[:], limit[:]) >= 0 {
 				break
 			}
+			// If the trie iteration is taking too long, abort and prove
+			if fromTrie && time.Since(start) > maxTrieRangeTimeSpent {
+				trieRangeTimeoutMeter.Mark(1)
+				abort = true
+				break
+			}
 		}
 		if len(storage) > 0 {
 			slots = append(slots, storage)
@@ -414,7 +451,7 @@ func ServiceGetStorageRangesQuery(chain *core.BlockChain, req *GetStorageRangesP
 		if origin != (common.Hash{}) || (abort && len(storage) > 0) {
 			// Request started at a non-zero hash or was capped prematurely, add
 			// the endpoint Merkle proofs
-			accTrie, err := trie.NewStateTrie(trie.StateTrieID(req.Root), chain.TrieDB())
+			accTrie, err := openAccTrie()
 			if err != nil {
 				return nil, nil
 			}
@@ -494,8 +531,12 @@ func ServiceGetTrieNodesQuery(chain *core.BlockChain, req *GetTrieNodesPacket, s
 		// We don't have the requested state available, bail out
 		return nil, nil
 	}
-	// The 'snap' might be nil, in which case we cannot serve storage slots.
-	snap := chain.Snapshots().Snapshot(req.Root)
+	// The 'snap' might be nil, in which case storage roots are resolved from the
+	// account trie instead.
+	var snap snapshot.Snapshot
+	if snaps := chain.Snapshots(); snaps != nil {
+		snap = snaps.Snapshot(req.Root)
+	}
 	// Retrieve trie nodes until the packet size limit is reached
 	var (
 		nodes [][]byte
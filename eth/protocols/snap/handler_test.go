@@ -0,0 +1,152 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"encoding/binary"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newServingChain creates a small chain with a few hundred accounts, every odd
+// one of them having storage, using the given state scheme and optionally the
+// snapshot for serving.
+func newServingChain(t *testing.T, scheme string, snapshots bool) (*core.BlockChain, common.Hash, []common.Hash) {
+	t.Helper()
+
+	var (
+		alloc   = make(types.GenesisAlloc)
+		storage = make(map[common.Hash]common.Hash)
+		owners  []common.Hash
+	)
+	for i := 0; i < 64; i++ {
+		storage[common.BigToHash(big.NewInt(int64(i)))] = common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+	for i := 0; i < 256; i++ {
+		addr := make([]byte, 20)
+		binary.BigEndian.PutUint64(addr[12:], uint64(i+1))
+
+		acc := types.Account{Balance: big.NewInt(int64(i + 1))}
+		if i%2 == 1 {
+			acc.Storage = storage
+			owners = append(owners, crypto.Keccak256Hash(addr))
+		}
+		alloc[common.BytesToAddress(addr)] = acc
+	}
+	gspec := &core.Genesis{Config: params.TestChainConfig, Alloc: alloc}
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, func(i int, gen *core.BlockGen) {})
+
+	config := core.DefaultCacheConfigWithScheme(scheme)
+	config.SnapshotWait = true
+	if !snapshots {
+		config.SnapshotLimit = 0
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), config, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	t.Cleanup(chain.Stop)
+	return chain, blocks[len(blocks)-1].Root(), owners
+}
+
+// Tests that account ranges served directly from the path trie are identical
+// to the ones served from the snapshot, both for complete and capped requests.
+func TestServeAccountRangeFromTrie(t *testing.T) {
+	snapChain, root, _ := newServingChain(t, rawdb.HashScheme, true)
+	trieChain, _, _ := newServingChain(t, rawdb.PathScheme, false)
+
+	if trieChain.Snapshots() != nil {
+		t.Fatalf("snapshot unexpectedly enabled")
+	}
+	for _, req := range []GetAccountRangePacket{
+		{Root: root, Origin: common.Hash{}, Limit: common.MaxHash, Bytes: softResponseLimit},
+		{Root: root, Origin: common.Hash{}, Limit: common.MaxHash, Bytes: 500},
+		{Root: root, Origin: common.HexToHash("0x40"), Limit: common.HexToHash("0x80"), Bytes: softResponseLimit},
+		{Root: root, Origin: common.HexToHash("0x80"), Limit: common.MaxHash, Bytes: 1000},
+	} {
+		snapReq, trieReq := req, req
+		wantAccounts, wantProofs := ServiceGetAccountRangeQuery(snapChain, &snapReq)
+		haveAccounts, haveProofs := ServiceGetAccountRangeQuery(trieChain, &trieReq)
+
+		if len(wantAccounts) == 0 {
+			t.Fatalf("origin %x: no accounts served from snapshot", req.Origin)
+		}
+		if !reflect.DeepEqual(haveAccounts, wantAccounts) {
+			t.Errorf("origin %x: account mismatch: have %d, want %d", req.Origin, len(haveAccounts), len(wantAccounts))
+		}
+		if !reflect.DeepEqual(haveProofs, wantProofs) {
+			t.Errorf("origin %x: proof mismatch: have %d nodes, want %d", req.Origin, len(haveProofs), len(wantProofs))
+		}
+	}
+}
+
+// Tests that storage ranges served directly from the path trie are identical
+// to the ones served from the snapshot, both for complete and capped requests.
+func TestServeStorageRangesFromTrie(t *testing.T) {
+	snapChain, root, owners := newServingChain(t, rawdb.HashScheme, true)
+	trieChain, _, _ := newServingChain(t, rawdb.PathScheme, false)
+
+	for _, req := range []GetStorageRangesPacket{
+		{Root: root, Accounts: owners, Bytes: softResponseLimit},
+		{Root: root, Accounts: owners, Bytes: 1000},
+		{Root: root, Accounts: owners[:1], Origin: common.HexToHash("0x80").Bytes(), Bytes: softResponseLimit},
+		{Root: root, Accounts: owners[:1], Origin: common.HexToHash("0x40").Bytes(), Bytes: 200},
+	} {
+		snapReq, trieReq := req, req
+		wantSlots, wantProofs := ServiceGetStorageRangesQuery(snapChain, &snapReq)
+		haveSlots, haveProofs := ServiceGetStorageRangesQuery(trieChain, &trieReq)
+
+		if len(wantSlots) == 0 {
+			t.Fatalf("bytes %d: no slots served from snapshot", req.Bytes)
+		}
+		if !reflect.DeepEqual(haveSlots, wantSlots) {
+			t.Errorf("bytes %d: slot mismatch: have %d ranges, want %d", req.Bytes, len(haveSlots), len(wantSlots))
+		}
+		if !reflect.DeepEqual(haveProofs, wantProofs) {
+			t.Errorf("bytes %d: proof mismatch: have %d nodes, want %d", req.Bytes, len(haveProofs), len(wantProofs))
+		}
+	}
+}
+
+// Tests that the hash scheme does not fall back to trie iteration if the
+// snapshot is unavailable, since iterating a hash-keyed trie is too expensive
+// to do on the request path.
+func TestServeRangesWithoutSnapshotHashScheme(t *testing.T) {
+	chain, root, owners := newServingChain(t, rawdb.HashScheme, false)
+
+	accounts, proofs := ServiceGetAccountRangeQuery(chain, &GetAccountRangePacket{Root: root, Limit: common.MaxHash, Bytes: softResponseLimit})
+	if len(accounts) != 0 || len(proofs) != 0 {
+		t.Errorf("unexpected account range served: %d accounts, %d proofs", len(accounts), len(proofs))
+	}
+	slots, proofs := ServiceGetStorageRangesQuery(chain, &GetStorageRangesPacket{Root: root, Accounts: owners, Bytes: softResponseLimit})
+	if len(slots) != 0 || len(proofs) != 0 {
+		t.Errorf("unexpected storage ranges served: %d ranges, %d proofs", len(slots), len(proofs))
+	}
+}
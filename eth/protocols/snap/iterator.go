@@ -0,0 +1,191 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state/snapshot"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// errStateUnavailable is returned if neither the snapshot nor the trie database
+// is able to serve a requested state range.
+var errStateUnavailable = errors.New("state range unavailable")
+
+// trieAccountIterator is an account iterator that steps over the leaves of an
+// account trie directly, converting them to the slim format served by the
+// snapshot. It is used as a fallback if the snapshot is missing or not yet
+// generated.
+type trieAccountIterator struct {
+	it   *trie.Iterator
+	hash common.Hash
+	blob []byte
+	fail error
+}
+
+// newTrieAccountIterator creates an account iterator over the given trie,
+// positioned just before the given origin.
+func newTrieAccountIterator(tr *trie.Trie, origin common.Hash) (*trieAccountIterator, error) {
+	nodeIt, err := tr.NodeIterator(origin[:])
+	if err != nil {
+		return nil, err
+	}
+	return &trieAccountIterator{it: trie.NewIterator(nodeIt)}, nil
+}
+
+// Next steps the iterator forward one element, returning false if exhausted.
+func (it *trieAccountIterator) Next() bool {
+	if it.fail != nil || !it.it.Next() {
+		return false
+	}
+	var account types.StateAccount
+	if err := rlp.DecodeBytes(it.it.Value, &account); err != nil {
+		it.fail = err
+		return false
+	}
+	it.hash = common.BytesToHash(it.it.Key)
+	it.blob = types.SlimAccountRLP(account)
+	return true
+}
+
+// Error returns any failure that occurred during iteration.
+func (it *trieAccountIterator) Error() error {
+	if it.fail != nil {
+		return it.fail
+	}
+	return it.it.Err
+}
+
+// Hash returns the hash of the account the iterator is currently at.
+func (it *trieAccountIterator) Hash() common.Hash { return it.hash }
+
+// Account returns the RLP encoded slim account the iterator is currently at.
+func (it *trieAccountIterator) Account() []byte { return it.blob }
+
+// Release is a noop for trie iterators as there are no held resources.
+func (it *trieAccountIterator) Release() {}
+
+// trieStorageIterator is a storage iterator that steps over the leaves of a
+// storage trie directly. It is used as a fallback if the snapshot is missing
+// or not yet generated.
+type trieStorageIterator struct {
+	it *trie.Iterator
+}
+
+// newTrieStorageIterator creates a storage iterator over the given trie,
+// positioned just before the given origin.
+func newTrieStorageIterator(tr *trie.Trie, origin common.Hash) (*trieStorageIterator, error) {
+	nodeIt, err := tr.NodeIterator(origin[:])
+	if err != nil {
+		return nil, err
+	}
+	return &trieStorageIterator{it: trie.NewIterator(nodeIt)}, nil
+}
+
+// Next steps the iterator forward one element, returning false if exhausted.
+func (it *trieStorageIterator) Next() bool { return it.it.Next() }
+
+// Error returns any failure that occurred during iteration.
+func (it *trieStorageIterator) Error() error { return it.it.Err }
+
+// Hash returns the hash of the storage slot the iterator is currently at.
+func (it *trieStorageIterator) Hash() common.Hash { return common.BytesToHash(it.it.Key) }
+
+// Slot returns the RLP encoded storage slot the iterator is currently at.
+func (it *trieStorageIterator) Slot() []byte { return it.it.Value }
+
+// Release is a noop for trie iterators as there are no held resources.
+func (it *trieStorageIterator) Release() {}
+
+// servesFromTrie reports whether state ranges may be served by iterating the
+// trie directly. Only the path scheme stores nodes in key order, making range
+// iteration cheap enough to do on the request path.
+func servesFromTrie(chain *core.BlockChain) bool {
+	return chain.TrieDB().Scheme() == rawdb.PathScheme
+}
+
+// newAccountRangeIterator opens an account iterator for serving a range request,
+// preferring the snapshot and falling back to the path-scheme trie if the
+// snapshot is missing, still generating or does not cover the requested root.
+func newAccountRangeIterator(chain *core.BlockChain, tr *trie.Trie, root common.Hash, origin common.Hash) (snapshot.AccountIterator, bool, error) {
+	if snaps := chain.Snapshots(); snaps != nil {
+		if it, err := snaps.AccountIterator(root, origin); err == nil {
+			snapshotAccountRangeMeter.Mark(1)
+			return it, false, nil
+		}
+	}
+	if !servesFromTrie(chain) {
+		unavailableAccountRangeMeter.Mark(1)
+		return nil, false, errStateUnavailable
+	}
+	it, err := newTrieAccountIterator(tr, origin)
+	if err != nil {
+		unavailableAccountRangeMeter.Mark(1)
+		return nil, false, err
+	}
+	trieAccountRangeMeter.Mark(1)
+	return it, true, nil
+}
+
+// newStorageRangeIterator opens a storage iterator for serving a range request,
+// preferring the snapshot and falling back to the path-scheme trie if the
+// snapshot is missing, still generating or does not cover the requested root.
+// The account trie is only resolved if the fallback is actually needed.
+func newStorageRangeIterator(chain *core.BlockChain, accTrie func() (*trie.StateTrie, error), root common.Hash, account common.Hash, origin common.Hash) (snapshot.StorageIterator, bool, error) {
+	if snaps := chain.Snapshots(); snaps != nil {
+		if it, err := snaps.StorageIterator(root, account, origin); err == nil {
+			snapshotStorageRangeMeter.Mark(1)
+			return it, false, nil
+		}
+	}
+	if !servesFromTrie(chain) {
+		unavailableStorageRangeMeter.Mark(1)
+		return nil, false, errStateUnavailable
+	}
+	tr, err := accTrie()
+	if err != nil {
+		unavailableStorageRangeMeter.Mark(1)
+		return nil, false, err
+	}
+	acc, err := tr.GetAccountByHash(account)
+	if err != nil {
+		unavailableStorageRangeMeter.Mark(1)
+		return nil, false, err
+	}
+	storageRoot := types.EmptyRootHash
+	if acc != nil {
+		storageRoot = acc.Root
+	}
+	stTrie, err := trie.New(trie.StorageTrieID(root, account, storageRoot), chain.TrieDB())
+	if err != nil {
+		unavailableStorageRangeMeter.Mark(1)
+		return nil, false, err
+	}
+	it, err := newTrieStorageIterator(stTrie, origin)
+	if err != nil {
+		unavailableStorageRangeMeter.Mark(1)
+		return nil, false, err
+	}
+	trieStorageRangeMeter.Mark(1)
+	return it, true, nil
+}
@@ -66,4 +66,22 @@ var (
 	// discarded during the snap sync.
 	largeStorageDiscardGauge = metrics.NewRegisteredGauge("eth/protocols/snap/sync/storage/chunk/discard", nil)
 	largeStorageResumedGauge = metrics.NewRegisteredGauge("eth/protocols/snap/sync/storage/chunk/resume", nil)
+
+	// snapshotAccountRangeMeter and trieAccountRangeMeter track how many account
+	// range requests are served from the snapshot and from the trie respectively,
+	// while unavailableAccountRangeMeter tracks the ones that cannot be served.
+	snapshotAccountRangeMeter    = metrics.NewRegisteredMeter("eth/protocols/snap/serve/account/snapshot", nil)
+	trieAccountRangeMeter        = metrics.NewRegisteredMeter("eth/protocols/snap/serve/account/trie", nil)
+	unavailableAccountRangeMeter = metrics.NewRegisteredMeter("eth/protocols/snap/serve/account/unavailable", nil)
+
+	// snapshotStorageRangeMeter and trieStorageRangeMeter track how many storage
+	// ranges are served from the snapshot and from the trie respectively, while
+	// unavailableStorageRangeMeter tracks the ones that cannot be served.
+	snapshotStorageRangeMeter    = metrics.NewRegisteredMeter("eth/protocols/snap/serve/storage/snapshot", nil)
+	trieStorageRangeMeter        = metrics.NewRegisteredMeter("eth/protocols/snap/serve/storage/trie", nil)
+	unavailableStorageRangeMeter = metrics.NewRegisteredMeter("eth/protocols/snap/serve/storage/unavailable", nil)
+
+	// trieRangeTimeoutMeter tracks how many range responses served from the trie
+	// are cut short due to exceeding the allowed time budget.
+	trieRangeTimeoutMeter = metrics.NewRegisteredMeter("eth/protocols/snap/serve/trie/timeout", nil)
 )
@@ -0,0 +1,363 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package snap
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// healEstimateInterval is the minimum time between two recalculations of the
+// remaining trie node estimate, as it requires iterating all pending requests.
+const healEstimateInterval = time.Second
+
+// Sync phases reported in the sync status.
+const (
+	SyncPhaseIdle     = "idle"     // No sync cycle has been started yet
+	SyncPhaseSnapping = "snapping" // Account, storage and bytecode ranges are being downloaded
+	SyncPhaseHealing  = "healing"  // State ranges are complete, the trie is being healed
+	SyncPhaseComplete = "complete" // The state is fully synced
+)
+
+// healEstimator approximates the number of trie nodes left to heal. Healing is
+// open ended, as every retrieved node may reveal new missing children, so the
+// estimate is based on the currently pending frontier: every pending node is
+// assumed to be followed by a path of missing nodes down to the leaf level.
+// The leaf depths are derived from the number of synced accounts and from the
+// deepest nodes observed during healing so far.
+type healEstimator struct {
+	accountDepth int // Deepest account trie node observed during healing
+	storageDepth int // Deepest storage trie node (relative to its root) observed
+
+	remaining uint64    // Estimated number of trie nodes left to heal
+	updated   time.Time // Time instance the estimate was last recalculated
+
+	started time.Time // Time instance healing started in this sync cycle
+	base    uint64    // Number of trie nodes healed before the current cycle
+}
+
+// observe tracks the depth of a healed trie node, given its hex path. Storage
+// trie paths are prefixed with the 64 nibble long account hash.
+func (e *healEstimator) observe(path string) {
+	if len(path) < 2*common.HashLength {
+		if len(path) > e.accountDepth {
+			e.accountDepth = len(path)
+		}
+		return
+	}
+	if depth := len(path) - 2*common.HashLength; depth > e.storageDepth {
+		e.storageDepth = depth
+	}
+}
+
+// estimate recalculates the number of remaining trie nodes to heal, given the
+// number of accounts in the state and an iterator over the pending node paths.
+func (e *healEstimator) estimate(accounts uint64, pending func(func(path []byte))) uint64 {
+	accountDepth := e.accountDepth
+	if accounts > 0 {
+		// A balanced hexary trie with n leaves is log16(n) levels deep
+		if depth := int(math.Ceil(math.Log(float64(accounts)) / math.Log(16))); depth > accountDepth {
+			accountDepth = depth
+		}
+	}
+	storageDepth := e.storageDepth
+	if storageDepth == 0 {
+		storageDepth = 1
+	}
+	var remaining uint64
+	pending(func(path []byte) {
+		depth, leaf := len(path), accountDepth
+		if depth >= 2*common.HashLength {
+			depth, leaf = depth-2*common.HashLength, storageDepth
+		}
+		remaining++
+		if leaf > depth {
+			remaining += uint64(leaf - depth)
+		}
+	})
+	e.remaining, e.updated = remaining, time.Now()
+	return remaining
+}
+
+// eta estimates the time left to heal the remaining trie nodes, based on the
+// average heal rate of the current sync cycle.
+func (e *healEstimator) eta(healed uint64) time.Duration {
+	if e.started.IsZero() || healed <= e.base {
+		return 0
+	}
+	rate := float64(healed-e.base) / time.Since(e.started).Seconds()
+	return time.Duration(float64(e.remaining) / rate * float64(time.Second))
+}
+
+// SyncCheckpoint is a summary of the snap sync state persisted along with the
+// resumable progress, so a restart can report the work that will be redone.
+type SyncCheckpoint struct {
+	Saved time.Time   // Time instance the checkpoint was written
+	Root  common.Hash // State root being synced when the checkpoint was written
+	Phase string      // Sync phase the checkpoint was written in
+
+	AccountRanges    int     // Number of account ranges left to download
+	AccountFilled    float64 // Fraction of the account hash space already downloaded
+	StorageTasks     int     // Number of large contract storage ranges left to download
+	StorageCompleted int     // Number of completed storage tries pending account commit
+
+	TrienodeHealPending   uint64 // Number of trie nodes pending heal, rediscovered on restart
+	TrienodeHealRemaining uint64 // Estimated number of trie nodes left to heal
+	BytecodeHealPending   uint64 // Number of bytecodes pending heal, rediscovered on restart
+}
+
+// report logs what will be redone when resuming from the checkpoint.
+func (c *SyncCheckpoint) report() {
+	log.Info("Resuming snap sync from checkpoint", "phase", c.Phase, "saved", c.Saved, "root", c.Root,
+		"ranges", c.AccountRanges, "filled", fmtPercent(c.AccountFilled), "storage", c.StorageTasks, "completed", c.StorageCompleted,
+		"pending", c.TrienodeHealPending, "remaining", c.TrienodeHealRemaining, "codes", c.BytecodeHealPending)
+
+	switch c.Phase {
+	case SyncPhaseSnapping:
+		log.Info("Partial account and storage ranges will be refetched from their last committed boundaries")
+	case SyncPhaseHealing:
+		log.Info("Pending heal requests will be rediscovered from the state root, already healed nodes are kept")
+	}
+}
+
+// fmtPercent formats a fraction as a percentage for logging.
+func fmtPercent(fraction float64) string {
+	return big.NewFloat(fraction*100).Text('f', 2) + "%"
+}
+
+// SyncStatus is a structured report of the snap sync progress, exposed over RPC.
+type SyncStatus struct {
+	Phase   string         `json:"phase"`
+	Root    common.Hash    `json:"root"`
+	Started *time.Time     `json:"started,omitempty"`
+	Elapsed string         `json:"elapsed"`
+	Filled  float64        `json:"filled"` // Fraction of the account hash space downloaded
+	Ranges  int            `json:"ranges"` // Number of account ranges left to download
+	Synced  SyncStatusData `json:"synced"`
+	Healed  SyncStatusHeal `json:"healed"`
+
+	Checkpoint *SyncCheckpoint    `json:"checkpoint,omitempty"`
+	Peers      []SyncStatusPeer   `json:"peers"`
+	Throughput map[string]float64 `json:"throughput"` // Mean items/s across peers, by request type
+}
+
+// SyncStatusData contains the item and byte counters of the download phase.
+type SyncStatusData struct {
+	Accounts      hexutil.Uint64     `json:"accounts"`
+	AccountBytes  common.StorageSize `json:"accountBytes"`
+	Storage       hexutil.Uint64     `json:"storage"`
+	StorageBytes  common.StorageSize `json:"storageBytes"`
+	Bytecodes     hexutil.Uint64     `json:"bytecodes"`
+	BytecodeBytes common.StorageSize `json:"bytecodeBytes"`
+}
+
+// SyncStatusHeal contains the counters and estimates of the healing phase.
+type SyncStatusHeal struct {
+	Trienodes          hexutil.Uint64     `json:"trienodes"`
+	TrienodeBytes      common.StorageSize `json:"trienodeBytes"`
+	TrienodeDups       hexutil.Uint64     `json:"trienodeDups"`
+	TrienodeNops       hexutil.Uint64     `json:"trienodeNops"`
+	TrienodesPending   hexutil.Uint64     `json:"trienodesPending"`
+	TrienodesRemaining hexutil.Uint64     `json:"trienodesRemaining"`
+	Bytecodes          hexutil.Uint64     `json:"bytecodes"`
+	BytecodeBytes      common.StorageSize `json:"bytecodeBytes"`
+	BytecodesPending   hexutil.Uint64     `json:"bytecodesPending"`
+	Accounts           hexutil.Uint64     `json:"accounts"`
+	AccountBytes       common.StorageSize `json:"accountBytes"`
+	Storage            hexutil.Uint64     `json:"storage"`
+	StorageBytes       common.StorageSize `json:"storageBytes"`
+	ETA                string             `json:"eta"`
+}
+
+// SyncStatusPeer contains the measured throughput of a single peer.
+type SyncStatusPeer struct {
+	ID         string             `json:"id"`
+	RoundTrip  string             `json:"roundTrip"`
+	Throughput map[string]float64 `json:"throughput"` // Items/s by request type
+	Stateless  bool               `json:"stateless"`
+}
+
+// msgNames maps the snap response message codes to names used in status reports.
+var msgNames = map[uint64]string{
+	AccountRangeMsg:  "accounts",
+	StorageRangesMsg: "storage",
+	ByteCodesMsg:     "bytecodes",
+	TrieNodesMsg:     "trienodes",
+}
+
+// namedThroughputs converts a message code keyed throughput map into a name
+// keyed one.
+func namedThroughputs(throughputs map[uint64]float64) map[string]float64 {
+	named := make(map[string]float64, len(throughputs))
+	for code, rate := range throughputs {
+		if name, ok := msgNames[code]; ok {
+			named[name] = rate
+		}
+	}
+	return named
+}
+
+// phase returns the current sync phase. It must be called from the sync loop,
+// which owns the heal scheduler.
+func (s *Syncer) phase() string {
+	switch {
+	case s.healer == nil:
+		return SyncPhaseIdle
+	case len(s.tasks) > 0:
+		return SyncPhaseSnapping
+	case s.healer.scheduler.Pending() > 0 || len(s.healer.trieTasks) > 0 || len(s.healer.codeTasks) > 0:
+		return SyncPhaseHealing
+	default:
+		return SyncPhaseComplete
+	}
+}
+
+// accountFilled returns the fraction of the account hash space downloaded. It
+// must be called from the sync loop, which owns the account tasks.
+func (s *Syncer) accountFilled() float64 {
+	if len(s.tasks) == 0 {
+		return 1
+	}
+	gaps := new(big.Int)
+	for _, task := range s.tasks {
+		gaps.Add(gaps, new(big.Int).Sub(task.Last.Big(), task.Next.Big()))
+	}
+	filled, _ := new(big.Float).Quo(
+		new(big.Float).SetInt(new(big.Int).Sub(hashSpace, gaps)),
+		new(big.Float).SetInt(hashSpace),
+	).Float64()
+	return filled
+}
+
+// checkpoint assembles the summary persisted along with the sync progress. It
+// must be called from the sync loop, which owns the tasks and the scheduler.
+func (s *Syncer) checkpoint() *SyncCheckpoint {
+	cp := &SyncCheckpoint{
+		Saved:         time.Now(),
+		Root:          s.root,
+		Phase:         s.phase(),
+		AccountRanges: len(s.tasks),
+		AccountFilled: s.accountFilled(),
+	}
+	for _, task := range s.tasks {
+		for _, subtasks := range task.SubTasks {
+			cp.StorageTasks += len(subtasks)
+		}
+		cp.StorageCompleted += len(task.stateCompleted)
+	}
+	if s.healer != nil {
+		cp.TrienodeHealPending = uint64(len(s.healer.trieTasks)) + uint64(s.healer.scheduler.Pending())
+		cp.TrienodeHealRemaining = s.healEst.remaining
+		cp.BytecodeHealPending = uint64(len(s.healer.codeTasks))
+	}
+	return cp
+}
+
+// updateStatus periodically snapshots the state owned by the sync loop into the
+// externally accessible status, recalculating the heal estimates along the way.
+// It must be called from the sync loop.
+func (s *Syncer) updateStatus(force bool) {
+	if !force && time.Since(s.healEst.updated) < healEstimateInterval {
+		return
+	}
+	phase := s.phase()
+	if phase == SyncPhaseHealing {
+		if s.healEst.started.IsZero() {
+			s.healEst.started, s.healEst.base = time.Now(), s.trienodeHealSynced
+		}
+		s.healEst.estimate(s.accountSynced, s.healer.scheduler.PendingPaths)
+	} else {
+		s.healEst.updated = time.Now()
+	}
+	status := &SyncStatus{
+		Phase:  phase,
+		Root:   s.root,
+		Filled: s.accountFilled(),
+		Ranges: len(s.tasks),
+		Healed: SyncStatusHeal{
+			TrienodeDups:       hexutil.Uint64(s.trienodeHealDups),
+			TrienodeNops:       hexutil.Uint64(s.trienodeHealNops),
+			TrienodesRemaining: hexutil.Uint64(s.healEst.remaining),
+			Accounts:           hexutil.Uint64(s.accountHealed),
+			AccountBytes:       s.accountHealedBytes,
+			Storage:            hexutil.Uint64(s.storageHealed),
+			StorageBytes:       s.storageHealedBytes,
+		},
+	}
+	if s.healer != nil {
+		status.Healed.TrienodesPending = hexutil.Uint64(s.healer.scheduler.Pending())
+		status.Healed.BytecodesPending = hexutil.Uint64(len(s.healer.codeTasks))
+	}
+	if eta := s.healEst.eta(s.trienodeHealSynced); eta > 0 {
+		status.Healed.ETA = common.PrettyDuration(eta).String()
+	}
+	s.lock.Lock()
+	s.extStatus = status
+	s.lock.Unlock()
+}
+
+// Status returns a structured report of the snap sync progress.
+func (s *Syncer) Status() *SyncStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	status := &SyncStatus{Phase: SyncPhaseIdle}
+	if s.extStatus != nil {
+		*status = *s.extStatus
+	}
+	status.Checkpoint = s.resumed
+	status.Throughput = namedThroughputs(s.rates.MeanCapacities())
+	status.Peers = make([]SyncStatusPeer, 0, len(s.peers))
+
+	if !s.startTime.IsZero() {
+		started := s.startTime
+		status.Started = &started
+		status.Elapsed = common.PrettyDuration(time.Since(s.startTime)).String()
+	}
+	if progress := s.extProgress; progress != nil {
+		status.Synced = SyncStatusData{
+			Accounts:      hexutil.Uint64(progress.AccountSynced),
+			AccountBytes:  progress.AccountBytes,
+			Storage:       hexutil.Uint64(progress.StorageSynced),
+			StorageBytes:  progress.StorageBytes,
+			Bytecodes:     hexutil.Uint64(progress.BytecodeSynced),
+			BytecodeBytes: progress.BytecodeBytes,
+		}
+		status.Healed.Trienodes = hexutil.Uint64(progress.TrienodeHealSynced)
+		status.Healed.TrienodeBytes = progress.TrienodeHealBytes
+		status.Healed.Bytecodes = hexutil.Uint64(progress.BytecodeHealSynced)
+		status.Healed.BytecodeBytes = progress.BytecodeHealBytes
+	}
+	for id := range s.peers {
+		throughputs, rtt := s.rates.Throughputs(id)
+		_, stateless := s.statelessPeers[id]
+		status.Peers = append(status.Peers, SyncStatusPeer{
+			ID:         id,
+			RoundTrip:  common.PrettyDuration(rtt).String(),
+			Throughput: namedThroughputs(throughputs),
+			Stateless:  stateless,
+		})
+	}
+	sort.Slice(status.Peers, func(i, j int) bool { return status.Peers[i].ID < status.Peers[j].ID })
+	return status
+}
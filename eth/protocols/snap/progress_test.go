@@ -17,10 +17,16 @@
 package snap
 
 import (
+	"bytes"
 	"encoding/json"
+	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/log"
 )
 
 // Legacy sync progress definitions
@@ -152,3 +158,151 @@ func TestSyncProgressCompatibility(t *testing.T) {
 		t.Fatal("sync progress is not forward compatible")
 	}
 }
+
+// Tests that the heal estimator extrapolates the pending frontier down to the
+// expected leaf depths of the account and storage tries.
+func TestHealEstimator(t *testing.T) {
+	var est healEstimator
+
+	// Observe a few healed nodes: account trie 3 deep, storage trie 2 deep
+	est.observe(string([]byte{1, 2, 3}))
+	est.observe(string(append(make([]byte, 2*common.HashLength), 4, 5)))
+
+	pending := func(paths ...[]byte) func(func(path []byte)) {
+		return func(fn func(path []byte)) {
+			for _, path := range paths {
+				fn(path)
+			}
+		}
+	}
+	// With no accounts known, the observed depths are used: the account node at
+	// depth 1 is followed by 2 more nodes, the storage root by 2 more nodes.
+	storageRoot := make([]byte, 2*common.HashLength)
+	if have, want := est.estimate(0, pending([]byte{1}, storageRoot)), uint64(3+3); have != want {
+		t.Fatalf("remaining estimate mismatch: have %d, want %d", have, want)
+	}
+	// A large account count deepens the expected account trie: 16^5 accounts
+	// means 5 levels, so a node at depth 1 is followed by 4 more.
+	if have, want := est.estimate(1<<20, pending([]byte{1})), uint64(5); have != want {
+		t.Fatalf("remaining estimate mismatch: have %d, want %d", have, want)
+	}
+	// Nodes deeper than the expected leaf level count only themselves
+	if have, want := est.estimate(0, pending([]byte{1, 2, 3, 4, 5})), uint64(1); have != want {
+		t.Fatalf("remaining estimate mismatch: have %d, want %d", have, want)
+	}
+	// Without healed nodes since the cycle started, no ETA can be derived
+	if eta := est.eta(0); eta != 0 {
+		t.Fatalf("unexpected eta without progress: %v", eta)
+	}
+}
+
+// Tests that the checkpoint summary is persisted along with the sync progress
+// and picked up when the sync is resumed.
+func TestSyncCheckpoint(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+
+	syncer := NewSyncer(db, rawdb.HashScheme)
+	syncer.root = common.Hash{0x01}
+	syncer.healer = &healTask{
+		scheduler: state.NewStateSync(syncer.root, db, nil, rawdb.HashScheme),
+		trieTasks: make(map[string]common.Hash),
+		codeTasks: make(map[common.Hash]struct{}),
+	}
+	syncer.loadSyncStatus()
+	syncer.saveSyncStatus()
+
+	resumed := NewSyncer(db, rawdb.HashScheme)
+	resumed.loadSyncStatus()
+	if resumed.resumed == nil {
+		t.Fatal("checkpoint not restored")
+	}
+	cp := resumed.resumed
+	if cp.Root != syncer.root {
+		t.Errorf("root mismatch: have %x, want %x", cp.Root, syncer.root)
+	}
+	if cp.Phase != SyncPhaseSnapping {
+		t.Errorf("phase mismatch: have %s, want %s", cp.Phase, SyncPhaseSnapping)
+	}
+	if cp.AccountRanges != accountConcurrency {
+		t.Errorf("account ranges mismatch: have %d, want %d", cp.AccountRanges, accountConcurrency)
+	}
+	if cp.AccountFilled > 1e-9 {
+		t.Errorf("filled fraction mismatch: have %g, want 0", cp.AccountFilled)
+	}
+}
+
+// captureCheckpointReport runs fn and returns the fields of the checkpoint
+// report it logged, or nil if there was none.
+func captureCheckpointReport(t *testing.T, fn func()) map[string]interface{} {
+	var buf bytes.Buffer
+	defer log.SetDefault(log.Root())
+	log.SetDefault(log.NewLogger(slog.NewJSONHandler(&buf, nil)))
+	fn()
+
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		var record map[string]interface{}
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("invalid log record %q: %v", line, err)
+		}
+		if record["msg"] == "Resuming snap sync from checkpoint" {
+			return record
+		}
+	}
+	return nil
+}
+
+// Tests that resuming from a checkpoint reports the work to be redone.
+func TestSyncCheckpointReport(t *testing.T) {
+	// The checkpoint of a fresh sync is reported on resume.
+	db := rawdb.NewMemoryDatabase()
+	syncer := NewSyncer(db, rawdb.HashScheme)
+	syncer.root = common.Hash{0x01}
+	syncer.healer = &healTask{
+		scheduler: state.NewStateSync(syncer.root, db, nil, rawdb.HashScheme),
+		trieTasks: make(map[string]common.Hash),
+		codeTasks: make(map[common.Hash]struct{}),
+	}
+	syncer.loadSyncStatus()
+	syncer.saveSyncStatus()
+
+	record := captureCheckpointReport(t, NewSyncer(db, rawdb.HashScheme).loadSyncStatus)
+	if record == nil {
+		t.Fatal("checkpoint not reported on resume")
+	}
+	if record["phase"] != SyncPhaseSnapping || record["ranges"] != float64(accountConcurrency) {
+		t.Errorf("wrong resumed checkpoint report: %v", record)
+	}
+	// All counters are reported.
+	cp := &SyncCheckpoint{
+		Saved:                 time.Now(),
+		Root:                  common.Hash{0x02},
+		Phase:                 SyncPhaseHealing,
+		AccountRanges:         3,
+		AccountFilled:         0.5,
+		StorageTasks:          7,
+		StorageCompleted:      2,
+		TrienodeHealPending:   120,
+		TrienodeHealRemaining: 4500,
+		BytecodeHealPending:   9,
+	}
+	record = captureCheckpointReport(t, cp.report)
+	want := map[string]interface{}{
+		"phase":     SyncPhaseHealing,
+		"root":      cp.Root.Hex(),
+		"ranges":    float64(3),
+		"filled":    "50.00%",
+		"storage":   float64(7),
+		"completed": float64(2),
+		"pending":   float64(120),
+		"remaining": float64(4500),
+		"codes":     float64(9),
+	}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("reported %s mismatch: have %v, want %v", key, record[key], value)
+		}
+	}
+}
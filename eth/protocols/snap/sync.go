@@ -395,6 +395,9 @@ type SyncProgress struct {
 	TrienodeHealBytes  common.StorageSize // Number of state trie bytes persisted to disk
 	BytecodeHealSynced uint64             // Number of bytecodes downloaded
 	BytecodeHealBytes  common.StorageSize // Number of bytecodes persisted to disk
+
+	// Summary of the sync state at the time of suspension
+	Checkpoint *SyncCheckpoint
 }
 
 // SyncPending is analogous to SyncProgress, but it's used to report on pending
@@ -402,6 +405,8 @@ type SyncProgress struct {
 type SyncPending struct {
 	TrienodeHeal uint64 // Number of state trie nodes pending
 	BytecodeHeal uint64 // Number of bytecodes pending
+
+	TrienodeHealRemaining uint64 // Estimated number of state trie nodes left to heal
 }
 
 // SyncPeer abstracts out the methods required for a peer to be synced against
@@ -474,7 +479,9 @@ type Syncer struct {
 	storageSynced  uint64             // Number of storage slots downloaded
 	storageBytes   common.StorageSize // Number of storage trie bytes persisted to disk
 
-	extProgress *SyncProgress // progress that can be exposed to external caller.
+	extProgress *SyncProgress   // progress that can be exposed to external caller.
+	extStatus   *SyncStatus     // detailed status that can be exposed to external caller.
+	resumed     *SyncCheckpoint // checkpoint the sync was resumed from after a restart
 
 	// Request tracking during healing phase
 	trienodeHealIdlers map[string]struct{} // Peers that aren't serving trie node requests
@@ -497,6 +504,8 @@ type Syncer struct {
 	bytecodeHealDups   uint64             // Number of bytecodes already processed
 	bytecodeHealNops   uint64             // Number of bytecodes not requested
 
+	healEst healEstimator // Estimator for the number of trie nodes left to heal
+
 	stateWriter        ethdb.Batch        // Shared batch writer used for persisting raw states
 	accountHealed      uint64             // Number of accounts downloaded during the healing stage
 	accountHealedBytes common.StorageSize // Number of raw account bytes persisted to disk during the healing stage
@@ -619,6 +628,7 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 	if s.startTime == (time.Time{}) {
 		s.startTime = time.Now()
 	}
+	s.healEst.started = time.Time{} // Heal rate is measured per sync cycle
 	// Retrieve the previous sync status from LevelDB and abort if already synced
 	s.loadSyncStatus()
 	if len(s.tasks) == 0 && s.healer.scheduler.Pending() == 0 {
@@ -713,6 +723,8 @@ func (s *Syncer) Sync(root common.Hash, cancel chan struct{}) error {
 			BytecodeHealBytes:  s.bytecodeHealBytes,
 		}
 		s.lock.Unlock()
+		s.updateStatus(false)
+
 		// Wait for something to happen
 		select {
 		case <-s.update:
@@ -812,6 +824,12 @@ func (s *Syncer) loadSyncStatus() {
 
 			s.snapped = len(s.tasks) == 0
 
+			// Report the work to be redone when resuming after a restart
+			if s.resumed == nil && progress.Checkpoint != nil {
+				s.resumed = progress.Checkpoint
+				s.resumed.report()
+			}
+
 			s.accountSynced = progress.AccountSynced
 			s.accountBytes = progress.AccountBytes
 			s.bytecodeSynced = progress.BytecodeSynced
@@ -918,6 +936,7 @@ func (s *Syncer) saveSyncStatus() {
 		TrienodeHealBytes:  s.trienodeHealBytes,
 		BytecodeHealSynced: s.bytecodeHealSynced,
 		BytecodeHealBytes:  s.bytecodeHealBytes,
+		Checkpoint:         s.checkpoint(),
 	}
 	status, err := json.Marshal(progress)
 	if err != nil {
@@ -935,6 +954,9 @@ func (s *Syncer) Progress() (*SyncProgress, *SyncPending) {
 		pending.TrienodeHeal = uint64(len(s.healer.trieTasks))
 		pending.BytecodeHeal = uint64(len(s.healer.codeTasks))
 	}
+	if s.extStatus != nil {
+		pending.TrienodeHealRemaining = uint64(s.extStatus.Healed.TrienodesRemaining)
+	}
 	return s.extProgress, pending
 }
 
@@ -2289,6 +2311,7 @@ func (s *Syncer) processTrienodeHealResponse(res *trienodeHealResponse) {
 			continue
 		}
 		fills++
+		s.healEst.observe(res.paths[i])
 
 		// Push the trie node into the state syncer
 		s.trienodeHealSynced++
@@ -3140,8 +3163,13 @@ func (s *Syncer) reportHealProgress(force bool) {
 		accounts = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.accountHealed), s.accountHealedBytes.TerminalString())
 		storage  = fmt.Sprintf("%v@%v", log.FormatLogfmtUint64(s.storageHealed), s.storageHealedBytes.TerminalString())
 	)
+	var eta time.Duration
+	if s.healEst.remaining > 0 {
+		eta = s.healEst.eta(s.trienodeHealSynced)
+	}
 	log.Info("Syncing: state healing in progress", "accounts", accounts, "slots", storage,
-		"codes", bytecode, "nodes", trienode, "pending", s.healer.scheduler.Pending())
+		"codes", bytecode, "nodes", trienode, "pending", s.healer.scheduler.Pending(),
+		"remaining", log.FormatLogfmtUint64(s.healEst.remaining), "eta", common.PrettyDuration(eta))
 }
 
 // estimateRemainingSlots tries to determine roughly how many slots are left in
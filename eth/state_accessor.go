@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -142,11 +143,20 @@ func (eth *Ethereum) hashState(ctx context.Context, block *types.Block, reexec u
 			logged = time.Now()
 		}
 		// Retrieve the next block to regenerate and process it
-		next := current.NumberU64() + 1
+		var (
+			next       = current.NumberU64() + 1
+			parentRoot = current.Root()
+		)
 		if current = eth.blockchain.GetBlockByNumber(next); current == nil {
 			return nil, nil, fmt.Errorf("block #%d not found", next)
 		}
+		// If the state accessed by the block is known, load it in parallel
+		var interrupt atomic.Bool
+		if set := eth.blockchain.GetAccessSet(current.Hash(), current.NumberU64()); set != nil {
+			go core.PrefetchAccessSet(set, parentRoot, database, nil, &interrupt)
+		}
 		_, _, _, err := eth.blockchain.Processor().Process(current, statedb, vm.Config{})
+		interrupt.Store(true)
 		if err != nil {
 			return nil, nil, fmt.Errorf("processing block %d failed: %v", current.NumberU64(), err)
 		}
@@ -87,6 +87,7 @@ type Backend interface {
 	ChainDb() ethdb.Database
 	StateAtBlock(ctx context.Context, block *types.Block, reexec uint64, base *state.StateDB, readOnly bool, preferDisk bool) (*state.StateDB, StateReleaseFunc, error)
 	StateAtTransaction(ctx context.Context, block *types.Block, txIndex int, reexec uint64) (*types.Transaction, vm.BlockContext, *state.StateDB, StateReleaseFunc, error)
+	RevertDecoder() *ethapi.RevertDecoder
 }
 
 // API is the collection of tracing APIs exposed over the private debugging endpoint.
@@ -849,7 +850,10 @@ func containsTx(block *types.Block, hash common.Hash) bool {
 func (api *API) TraceTransaction(ctx context.Context, hash common.Hash, config *TraceConfig) (interface{}, error) {
 	found, _, blockHash, blockNumber, index, err := api.backend.GetTransaction(ctx, hash)
 	if err != nil {
-		return nil, ethapi.NewTxIndexingError()
+		if errors.Is(err, core.ErrTxIndexing) {
+			return nil, ethapi.NewTxIndexingError()
+		}
+		return nil, err
 	}
 	// Only mined txes are supported
 	if !found {
@@ -980,6 +984,7 @@ func (api *API) traceTx(ctx context.Context, tx *types.Transaction, message *cor
 			Stop:      logger.Stop,
 		}
 	} else {
+		txctx.RevertDecoder = api.backend.RevertDecoder()
 		tracer, err = DefaultDirectory.New(*config.Tracer, txctx, config.TracerConfig)
 		if err != nil {
 			return nil, err
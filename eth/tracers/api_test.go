@@ -92,6 +92,10 @@ func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i i
 	return backend
 }
 
+func (b *testBackend) RevertDecoder() *ethapi.RevertDecoder {
+	return nil
+}
+
 func (b *testBackend) HeaderByHash(ctx context.Context, hash common.Hash) (*types.Header, error) {
 	return b.chain.GetHeaderByHash(hash), nil
 }
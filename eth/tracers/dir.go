@@ -22,6 +22,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 )
 
 // Context contains some contextual infos for a transaction execution that is not
@@ -31,6 +32,8 @@ type Context struct {
 	BlockNumber *big.Int    // Number of the block the tx is contained within (zero if dangling tx or call)
 	TxIndex     int         // Index of the transaction within a block (zero if dangling tx or call)
 	TxHash      common.Hash // Hash of the transaction being traced (zero if dangling call)
+
+	RevertDecoder *ethapi.RevertDecoder // Decoder of the revert data of failed calls (nil if only built-in errors are known)
 }
 
 // The set of methods that must be exposed by a tracer
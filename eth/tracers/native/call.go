@@ -22,13 +22,13 @@ import (
 	"math/big"
 	"sync/atomic"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/tracing"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 )
 
 //go:generate go run github.com/fjl/gencodec -type callFrame -field-override callFrameMarshaling -out gen_callframe_json.go
@@ -47,17 +47,18 @@ type callLog struct {
 }
 
 type callFrame struct {
-	Type         vm.OpCode       `json:"-"`
-	From         common.Address  `json:"from"`
-	Gas          uint64          `json:"gas"`
-	GasUsed      uint64          `json:"gasUsed"`
-	To           *common.Address `json:"to,omitempty" rlp:"optional"`
-	Input        []byte          `json:"input" rlp:"optional"`
-	Output       []byte          `json:"output,omitempty" rlp:"optional"`
-	Error        string          `json:"error,omitempty" rlp:"optional"`
-	RevertReason string          `json:"revertReason,omitempty"`
-	Calls        []callFrame     `json:"calls,omitempty" rlp:"optional"`
-	Logs         []callLog       `json:"logs,omitempty" rlp:"optional"`
+	Type         vm.OpCode             `json:"-"`
+	From         common.Address        `json:"from"`
+	Gas          uint64                `json:"gas"`
+	GasUsed      uint64                `json:"gasUsed"`
+	To           *common.Address       `json:"to,omitempty" rlp:"optional"`
+	Input        []byte                `json:"input" rlp:"optional"`
+	Output       []byte                `json:"output,omitempty" rlp:"optional"`
+	Error        string                `json:"error,omitempty" rlp:"optional"`
+	RevertReason string                `json:"revertReason,omitempty"`
+	RevertError  *ethapi.DecodedRevert `json:"revertError,omitempty" rlp:"-"`
+	Calls        []callFrame           `json:"calls,omitempty" rlp:"optional"`
+	Logs         []callLog             `json:"logs,omitempty" rlp:"optional"`
 	// Placed at end on purpose. The RLP will be decoded to 0 instead of
 	// nil if there are non-empty elements after in the struct.
 	Value            *big.Int `json:"value,omitempty" rlp:"optional"`
@@ -72,7 +73,7 @@ func (f callFrame) failed() bool {
 	return len(f.Error) > 0 && f.revertedSnapshot
 }
 
-func (f *callFrame) processOutput(output []byte, err error, reverted bool) {
+func (f *callFrame) processOutput(output []byte, err error, reverted bool, decoder *ethapi.RevertDecoder) {
 	output = common.CopyBytes(output)
 	// Clear error if tx wasn't reverted. This happened
 	// for pre-homestead contract storage OOG.
@@ -95,8 +96,12 @@ func (f *callFrame) processOutput(output []byte, err error, reverted bool) {
 	if len(output) < 4 {
 		return
 	}
-	if unpacked, err := abi.UnpackRevert(output); err == nil {
-		f.RevertReason = unpacked
+	if decoded := decoder.Decode(output); decoded != nil {
+		f.RevertReason = decoded.String()
+		// Error(string) is fully described by the reason, keep its frame as is.
+		if decoded.Kind != ethapi.RevertKindError {
+			f.RevertError = decoded
+		}
 	}
 }
 
@@ -116,6 +121,7 @@ type callTracer struct {
 	depth     int
 	interrupt atomic.Bool // Atomic flag to signal execution interruption
 	reason    error       // Textual reason for the interruption
+	decoder   *ethapi.RevertDecoder
 }
 
 type callTracerConfig struct {
@@ -152,7 +158,11 @@ func newCallTracerObject(ctx *tracers.Context, cfg json.RawMessage) (*callTracer
 	}
 	// First callframe contains tx context info
 	// and is populated on start and end.
-	return &callTracer{callstack: make([]callFrame, 0, 1), config: config}, nil
+	t := &callTracer{callstack: make([]callFrame, 0, 1), config: config}
+	if ctx != nil {
+		t.decoder = ctx.RevertDecoder
+	}
+	return t, nil
 }
 
 // OnEnter is called when EVM enters a new scope (via call, create or selfdestruct).
@@ -204,7 +214,7 @@ func (t *callTracer) OnExit(depth int, output []byte, gasUsed uint64, err error,
 	size -= 1
 
 	call.GasUsed = gasUsed
-	call.processOutput(output, err, reverted)
+	call.processOutput(output, err, reverted, t.decoder)
 	// Nest call into parent.
 	t.callstack[size-1].Calls = append(t.callstack[size-1].Calls, call)
 }
@@ -213,7 +223,7 @@ func (t *callTracer) captureEnd(output []byte, gasUsed uint64, err error, revert
 	if len(t.callstack) != 1 {
 		return
 	}
-	t.callstack[0].processOutput(output, err, reverted)
+	t.callstack[0].processOutput(output, err, reverted, t.decoder)
 }
 
 func (t *callTracer) OnTxStart(env *tracing.VMContext, tx *types.Transaction, from common.Address) {
@@ -0,0 +1,76 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallRevertError(t *testing.T) {
+	contract, err := abi.JSON(strings.NewReader(`[{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}]`))
+	require.NoError(t, err)
+	decoder := ethapi.NewRevertDecoder()
+	decoder.AddABI(&contract)
+
+	insufficient := contract.Errors["InsufficientBalance"]
+	output, err := insufficient.Inputs.Pack(big.NewInt(1), big.NewInt(2))
+	require.NoError(t, err)
+	output = append(insufficient.ID[:4], output...)
+
+	tracer, err := tracers.DefaultDirectory.New("callTracer", &tracers.Context{RevertDecoder: decoder}, nil)
+	require.NoError(t, err)
+
+	// simulate a reverted transaction
+	tx := types.NewTx(&types.LegacyTx{
+		To:       &common.Address{},
+		Value:    big.NewInt(0),
+		GasPrice: big.NewInt(0),
+	})
+	tracer.OnTxStart(&tracing.VMContext{
+		ChainConfig: params.MainnetChainConfig,
+	}, tx, common.Address{})
+	tracer.OnEnter(0, byte(vm.CALL), common.Address{}, common.Address{}, nil, 0, big.NewInt(0))
+	tracer.OnExit(0, output, 0, vm.ErrExecutionReverted, true)
+	tracer.OnTxEnd(&types.Receipt{GasUsed: 0}, nil)
+
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+
+	var frame struct {
+		RevertReason string                `json:"revertReason"`
+		RevertError  *ethapi.DecodedRevert `json:"revertError"`
+	}
+	require.NoError(t, json.Unmarshal(res, &frame))
+	require.Equal(t, "InsufficientBalance(available=1, required=2)", frame.RevertReason)
+	require.NotNil(t, frame.RevertError)
+	require.Equal(t, ethapi.RevertKindCustom, frame.RevertError.Kind)
+	require.Equal(t, "InsufficientBalance(uint256,uint256)", frame.RevertError.Signature)
+	require.Len(t, frame.RevertError.Args, 2)
+}
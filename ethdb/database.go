@@ -55,6 +55,15 @@ type Compacter interface {
 	Compact(start []byte, limit []byte) error
 }
 
+// Checkpointer wraps the Checkpoint method of a backing data store. It is not
+// part of the database interfaces, as not all data stores support it.
+type Checkpointer interface {
+	// Checkpoint creates a consistent, point-in-time copy of the data store in
+	// the given directory, which must not exist yet. The copy can be opened as
+	// a regular data store of the same kind.
+	Checkpoint(dir string) error
+}
+
 // KeyValueStore contains all the methods required to allow handling different
 // key-value data stores backing the high level database.
 type KeyValueStore interface {
@@ -0,0 +1,401 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package encryptdb implements a key-value store wrapper encrypting the data at
+// rest. Values are encrypted and authenticated with AES-GCM, bound to their key.
+// Keys are optionally encrypted with a deterministic encoding which preserves
+// their order and common prefixes, so iteration keeps working.
+package encryptdb
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+// checkKey is the key of the entry used to verify the encryption key and mode
+// of a database. It is stored in plain, its odd length ensures that it never
+// collides with an encrypted key.
+var checkKey = []byte("EncryptionCheck")
+
+// checkMagic is the content of the check entry, followed by the key mode.
+var checkMagic = []byte("geth-encryptdb")
+
+// nonceSize is the size of the AES-GCM nonces prepended to the values.
+const nonceSize = 12
+
+var (
+	// errNotEncrypted is returned if an existing unencrypted database is opened
+	// with encryption.
+	errNotEncrypted = errors.New("database is not encrypted")
+
+	// errWrongKey is returned if the database was encrypted with a different key.
+	errWrongKey = errors.New("wrong database encryption key")
+
+	// errInvalidValue is returned if a stored value is too short to be decrypted.
+	errInvalidValue = errors.New("invalid encrypted value")
+)
+
+// Database is a key-value store encrypting all the data stored in the wrapped
+// one.
+type Database struct {
+	db          ethdb.KeyValueStore
+	key         *Key
+	encryptKeys bool
+
+	salt [4]byte       // Random prefix of the nonces, unique to this instance
+	seq  atomic.Uint64 // Counter of the nonces, started at a random value
+}
+
+// IsEncrypted reports whether the data store holds an encrypted database.
+func IsEncrypted(db ethdb.KeyValueReader) bool {
+	has, _ := db.Has(checkKey)
+	return has
+}
+
+// New wraps a key-value store, encrypting all data written to it. If the store
+// is empty, it is initialized for encryption with the given key and key mode,
+// otherwise it must have been initialized with the same ones.
+func New(db ethdb.KeyValueStore, key *Key, encryptKeys bool) (*Database, error) {
+	edb := &Database{
+		db:          db,
+		key:         key,
+		encryptKeys: encryptKeys,
+	}
+	var seq [8]byte
+	if _, err := rand.Read(edb.salt[:]); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(seq[:]); err != nil {
+		return nil, err
+	}
+	edb.seq.Store(binary.BigEndian.Uint64(seq[:]))
+
+	if err := edb.check(); err != nil {
+		return nil, err
+	}
+	return edb, nil
+}
+
+// check verifies the key and mode against the check entry of the database, or
+// writes it if the database is empty.
+func (db *Database) check() error {
+	mode := byte(0)
+	if db.encryptKeys {
+		mode = 1
+	}
+	blob, err := db.db.Get(checkKey)
+	if err != nil {
+		// Refuse to encrypt a populated database, the existing data would be
+		// unreadable.
+		it := db.db.NewIterator(nil, nil)
+		populated := it.Next()
+		it.Release()
+		if populated {
+			return errNotEncrypted
+		}
+		return db.db.Put(checkKey, db.seal(checkKey, append(bytes.Clone(checkMagic), mode)))
+	}
+	check, err := db.open(checkKey, blob)
+	if err != nil || !bytes.HasPrefix(check, checkMagic) || len(check) != len(checkMagic)+1 {
+		return errWrongKey
+	}
+	if check[len(checkMagic)] != mode {
+		return fmt.Errorf("database key encryption mismatch: have %t, want %t", !db.encryptKeys, db.encryptKeys)
+	}
+	return nil
+}
+
+// encodeKey returns the key as stored in the wrapped store.
+func (db *Database) encodeKey(key []byte) []byte {
+	if !db.encryptKeys {
+		return key
+	}
+	return encodeKey(db.key.keys, make([]byte, 0, 2*len(key)), key)
+}
+
+// decodeKey returns the key stored in the wrapped store in plain.
+func (db *Database) decodeKey(key []byte) ([]byte, error) {
+	if !db.encryptKeys {
+		return key, nil
+	}
+	return decodeKey(db.key.keys, key)
+}
+
+// seal encrypts the value of the given key.
+func (db *Database) seal(key []byte, value []byte) []byte {
+	nonce := make([]byte, nonceSize, nonceSize+len(value)+db.key.values.Overhead())
+	copy(nonce, db.salt[:])
+	binary.BigEndian.PutUint64(nonce[len(db.salt):], db.seq.Add(1))
+	return db.key.values.Seal(nonce, nonce, value, key)
+}
+
+// open decrypts the value of the given key.
+func (db *Database) open(key []byte, blob []byte) ([]byte, error) {
+	if len(blob) < nonceSize {
+		return nil, errInvalidValue
+	}
+	value, err := db.key.values.Open(nil, blob[:nonceSize], blob[nonceSize:], key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value of %x: %w", key, err)
+	}
+	if value == nil {
+		value = []byte{}
+	}
+	return value, nil
+}
+
+// Has retrieves if a key is present in the key-value store.
+func (db *Database) Has(key []byte) (bool, error) {
+	return db.db.Has(db.encodeKey(key))
+}
+
+// Get retrieves the given key if it's present in the key-value store.
+func (db *Database) Get(key []byte) ([]byte, error) {
+	blob, err := db.db.Get(db.encodeKey(key))
+	if err != nil {
+		return nil, err
+	}
+	return db.open(key, blob)
+}
+
+// Put inserts the given value into the key-value store.
+func (db *Database) Put(key []byte, value []byte) error {
+	return db.db.Put(db.encodeKey(key), db.seal(key, value))
+}
+
+// Delete removes the key from the key-value store.
+func (db *Database) Delete(key []byte) error {
+	return db.db.Delete(db.encodeKey(key))
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{db: db, b: db.db.NewBatch()}
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
+	return &batch{db: db, b: db.db.NewBatchWithSize(size)}
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset
+// of database content with a particular key prefix, starting at a particular
+// initial key (or after, if it does not exist).
+func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	if db.encryptKeys {
+		// The encoding of a key depends on the preceding bytes, so the start
+		// can only be encoded along with the prefix.
+		full := make([]byte, 0, len(prefix)+len(start))
+		full = append(append(full, prefix...), start...)
+
+		enc := db.encodeKey(full)
+		prefix, start = enc[:2*len(prefix)], enc[2*len(prefix):]
+	}
+	return &iterator{db: db, it: db.db.NewIterator(prefix, start)}
+}
+
+// Stat returns the statistic data of the wrapped store.
+func (db *Database) Stat(property string) (string, error) {
+	return db.db.Stat(property)
+}
+
+// Compact flattens the underlying data store for the given key range.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	if start != nil {
+		start = db.encodeKey(start)
+	}
+	if limit != nil {
+		limit = db.encodeKey(limit)
+	}
+	return db.db.Compact(start, limit)
+}
+
+// NewSnapshot creates a database snapshot based on the current state.
+func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
+	snap, err := db.db.NewSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot{db: db, snap: snap}, nil
+}
+
+// Checkpoint creates a consistent copy of the wrapped store in the given
+// directory. The copy stays encrypted with the same key.
+func (db *Database) Checkpoint(dir string) error {
+	cp, ok := db.db.(ethdb.Checkpointer)
+	if !ok {
+		return fmt.Errorf("checkpoints of %T not supported", db.db)
+	}
+	return cp.Checkpoint(dir)
+}
+
+// Close closes the wrapped store.
+func (db *Database) Close() error {
+	return db.db.Close()
+}
+
+// batch is a write-only batch encrypting the changes before buffering them.
+type batch struct {
+	db *Database
+	b  ethdb.Batch
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	return b.b.Put(b.db.encodeKey(key), b.db.seal(key, value))
+}
+
+// Delete inserts the key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	return b.b.Delete(b.db.encodeKey(key))
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.b.ValueSize()
+}
+
+// Write flushes any accumulated data to disk.
+func (b *batch) Write() error {
+	return b.b.Write()
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.b.Reset()
+}
+
+// Replay replays the batch contents in plain.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	return b.b.Replay(&replayer{db: b.db, w: w})
+}
+
+// replayer is a writer decrypting the replayed changes of a batch.
+type replayer struct {
+	db *Database
+	w  ethdb.KeyValueWriter
+}
+
+// Put inserts the decrypted value into the wrapped writer.
+func (r *replayer) Put(key, value []byte) error {
+	key, err := r.db.decodeKey(key)
+	if err != nil {
+		return err
+	}
+	value, err = r.db.open(key, value)
+	if err != nil {
+		return err
+	}
+	return r.w.Put(key, value)
+}
+
+// Delete removes the decrypted key from the wrapped writer.
+func (r *replayer) Delete(key []byte) error {
+	key, err := r.db.decodeKey(key)
+	if err != nil {
+		return err
+	}
+	return r.w.Delete(key)
+}
+
+// iterator decrypts the entries of an iterator over the wrapped store.
+type iterator struct {
+	db    *Database
+	it    ethdb.Iterator
+	key   []byte
+	value []byte
+	err   error
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted, or an entry failed to decrypt.
+func (it *iterator) Next() bool {
+	if it.err != nil || !it.it.Next() {
+		it.key, it.value = nil, nil
+		return false
+	}
+	// Skip the check entry, it isn't part of the stored data.
+	if bytes.Equal(it.it.Key(), checkKey) {
+		return it.Next()
+	}
+	key, err := it.db.decodeKey(it.it.Key())
+	if err != nil {
+		it.err = err
+		return false
+	}
+	value, err := it.db.open(key, it.it.Value())
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.key, it.value = key, value
+	return true
+}
+
+// Error returns any accumulated error.
+func (it *iterator) Error() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.it.Error()
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *iterator) Key() []byte {
+	return it.key
+}
+
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *iterator) Value() []byte {
+	return it.value
+}
+
+// Release releases associated resources.
+func (it *iterator) Release() {
+	it.it.Release()
+}
+
+// snapshot decrypts the entries of a snapshot of the wrapped store.
+type snapshot struct {
+	db   *Database
+	snap ethdb.Snapshot
+}
+
+// Has retrieves if a key is present in the snapshot.
+func (s *snapshot) Has(key []byte) (bool, error) {
+	return s.snap.Has(s.db.encodeKey(key))
+}
+
+// Get retrieves the given key if it's present in the snapshot.
+func (s *snapshot) Get(key []byte) ([]byte, error) {
+	blob, err := s.snap.Get(s.db.encodeKey(key))
+	if err != nil {
+		return nil, err
+	}
+	return s.db.open(key, blob)
+}
+
+// Release releases associated resources.
+func (s *snapshot) Release() {
+	s.snap.Release()
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package encryptdb
+
+import (
+	"bytes"
+	"crypto/rand"
+	mrand "math/rand"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+)
+
+func newTestKey(t testing.TB) *Key {
+	master := make([]byte, KeyLength)
+	rand.Read(master)
+	key, err := NewKey(master)
+	if err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	return key
+}
+
+func TestEncryptDB(t *testing.T) {
+	for _, encryptKeys := range []bool{false, true} {
+		key := newTestKey(t)
+		t.Run("DatabaseSuite", func(t *testing.T) {
+			dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+				db, err := New(memorydb.New(), key, encryptKeys)
+				if err != nil {
+					t.Fatalf("Failed to create database: %v", err)
+				}
+				return db
+			})
+		})
+	}
+}
+
+// Tests that neither the keys nor the values are stored in plain, and that the
+// database can only be reopened with the same key and mode.
+func TestEncryptDBReopen(t *testing.T) {
+	var (
+		mem = memorydb.New()
+		key = newTestKey(t)
+	)
+	db, err := New(mem, key, true)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	db.Put([]byte("secret-key"), []byte("secret-value"))
+
+	it := mem.NewIterator(nil, nil)
+	for it.Next() {
+		if bytes.Contains(it.Key(), []byte("secret")) || bytes.Contains(it.Value(), []byte("secret")) {
+			t.Fatalf("Plain data stored: %q -> %q", it.Key(), it.Value())
+		}
+	}
+	it.Release()
+
+	if !IsEncrypted(mem) {
+		t.Fatal("Database not reported as encrypted")
+	}
+	if _, err := New(mem, newTestKey(t), true); err != errWrongKey {
+		t.Fatalf("Reopened with wrong key: %v", err)
+	}
+	if _, err := New(mem, key, false); err == nil {
+		t.Fatal("Reopened with mismatching key mode")
+	}
+	db, err = New(mem, key, true)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	if value, err := db.Get([]byte("secret-key")); err != nil || string(value) != "secret-value" {
+		t.Fatalf("Value mismatch: have %q, %v", value, err)
+	}
+	// Populated plain databases must not be encrypted.
+	plain := memorydb.New()
+	plain.Put([]byte("key"), []byte("value"))
+	if _, err := New(plain, key, false); err != errNotEncrypted {
+		t.Fatalf("Encrypted populated database: %v", err)
+	}
+}
+
+// Tests that the key encoding preserves the order and prefixes of the keys.
+func TestKeyEncoding(t *testing.T) {
+	key := newTestKey(t)
+	random := func() []byte {
+		b := make([]byte, mrand.Intn(6))
+		for i := range b {
+			b[i] = byte(mrand.Intn(4)) * 85 // Few distinct bytes to get common prefixes
+		}
+		return b
+	}
+	for i := 0; i < 2000; i++ {
+		a, b := random(), random()
+		encA, encB := encodeKey(key.keys, nil, a), encodeKey(key.keys, nil, b)
+		if have, want := bytes.Compare(encA, encB), bytes.Compare(a, b); have != want {
+			t.Fatalf("Order mismatch for %x and %x: have %d, want %d", a, b, have, want)
+		}
+		if bytes.HasPrefix(b, a) != bytes.HasPrefix(encB, encA) {
+			t.Fatalf("Prefix mismatch for %x and %x", a, b)
+		}
+		dec, err := decodeKey(key.keys, encA)
+		if err != nil || !bytes.Equal(dec, a) {
+			t.Fatalf("Decoding mismatch: have %x, %v, want %x", dec, err, a)
+		}
+	}
+}
+
+// Tests that file items are encrypted with unique nonces, and only decrypt at
+// the position they were stored at.
+func TestFileCipher(t *testing.T) {
+	var (
+		c     = newTestKey(t).FileCipher()
+		plain = make([]byte, 1000)
+		pos   = []byte("table 1")
+	)
+	rand.Read(plain)
+	enc := c.Seal(nil, plain, pos)
+	if len(enc) != len(plain)+c.Overhead() || bytes.Contains(enc, plain[:32]) {
+		t.Fatal("Item not encrypted")
+	}
+	dec, err := c.Open(nil, enc, pos)
+	if err != nil || !bytes.Equal(dec, plain) {
+		t.Fatalf("Decryption mismatch: %v", err)
+	}
+	// Rewriting the same item at the same position must not reuse the key stream.
+	if again := c.Seal(nil, plain, pos); bytes.Equal(again[nonceSize:], enc[nonceSize:]) {
+		t.Fatal("Key stream reused")
+	}
+	if _, err := c.Open(nil, enc, []byte("table 2")); err == nil {
+		t.Fatal("Item decrypted at wrong position")
+	}
+	enc[mrand.Intn(len(enc))] ^= 0x1
+	if _, err := c.Open(nil, enc, pos); err == nil {
+		t.Fatal("Tampered item decrypted")
+	}
+	if _, err := c.Open(nil, enc[:c.Overhead()-1], pos); err != errInvalidItem {
+		t.Fatalf("Wrong error for truncated item: %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package encryptdb
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+)
+
+// errInvalidItem is returned if a stored item is too short to be decrypted.
+var errInvalidItem = errors.New("invalid encrypted item")
+
+// FileCipher encrypts the items of append-only files, such as the data files
+// of the freezer. Every item is encrypted and authenticated on its own with
+// AES-GCM under a unique nonce, which is stored in front of it. The items are
+// bound to their position, e.g. the table and item number, so tampered items
+// or items moved to another position fail to decrypt.
+//
+// As the nonces are never reused, items rewritten after truncating a file are
+// encrypted with a different key stream.
+type FileCipher struct {
+	aead cipher.AEAD
+	salt [4]byte       // Random prefix of the nonces, unique to this instance
+	seq  atomic.Uint64 // Counter of the nonces, started at a random value
+}
+
+// newFileCipher creates a file cipher using the given AES cipher.
+func newFileCipher(block cipher.Block) (*FileCipher, error) {
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	c := &FileCipher{aead: aead}
+	var seq [8]byte
+	if _, err := rand.Read(c.salt[:]); err != nil {
+		return nil, err
+	}
+	if _, err := rand.Read(seq[:]); err != nil {
+		return nil, err
+	}
+	c.seq.Store(binary.BigEndian.Uint64(seq[:]))
+	return c, nil
+}
+
+// Overhead returns the number of bytes an item grows by when encrypted.
+func (c *FileCipher) Overhead() int {
+	return nonceSize + c.aead.Overhead()
+}
+
+// Seal encrypts the item stored at the given position, and appends the result
+// to dst.
+func (c *FileCipher) Seal(dst []byte, item []byte, position []byte) []byte {
+	var nonce [nonceSize]byte
+	copy(nonce[:], c.salt[:])
+	binary.BigEndian.PutUint64(nonce[len(c.salt):], c.seq.Add(1))
+
+	dst = append(dst, nonce[:]...)
+	return c.aead.Seal(dst, nonce[:], item, position)
+}
+
+// Open decrypts and authenticates the item stored at the given position, and
+// appends the result to dst.
+func (c *FileCipher) Open(dst []byte, blob []byte, position []byte) ([]byte, error) {
+	if len(blob) < c.Overhead() {
+		return nil, errInvalidItem
+	}
+	return c.aead.Open(dst, blob[:nonceSize], blob[nonceSize:], position)
+}
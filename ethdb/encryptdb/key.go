@@ -0,0 +1,137 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package encryptdb
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeyLength is the length of the master encryption key.
+const KeyLength = 32
+
+// keyRequestTimeout is the maximum time waited for a key server to answer.
+const keyRequestTimeout = 10 * time.Second
+
+// Key is a master encryption key, along with the ciphers derived from it for
+// the different kinds of encrypted data.
+type Key struct {
+	values cipher.AEAD  // AES-GCM cipher for the values of the key-value store
+	keys   cipher.Block // AES cipher for the order-preserving key encoding
+	files  *FileCipher  // AES-GCM cipher for the items of the ancient store files
+}
+
+// NewKey derives the encryption ciphers from a master key.
+func NewKey(master []byte) (*Key, error) {
+	if len(master) != KeyLength {
+		return nil, fmt.Errorf("invalid key length %d, want %d", len(master), KeyLength)
+	}
+	derive := func(info string) (cipher.Block, error) {
+		subkey := make([]byte, KeyLength)
+		if _, err := io.ReadFull(hkdf.New(sha256.New, master, nil, []byte(info)), subkey); err != nil {
+			return nil, err
+		}
+		return aes.NewCipher(subkey)
+	}
+	values, err := derive("geth encryptdb values")
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(values)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := derive("geth encryptdb keys")
+	if err != nil {
+		return nil, err
+	}
+	files, err := derive("geth encryptdb files")
+	if err != nil {
+		return nil, err
+	}
+	fc, err := newFileCipher(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Key{values: gcm, keys: keys, files: fc}, nil
+}
+
+// FileCipher returns the cipher used to encrypt the files of the ancient store.
+func (k *Key) FileCipher() *FileCipher {
+	return k.files
+}
+
+// LoadKey loads the master key of the named database from the given source.
+// The source is either a file containing the hex-encoded key, or the path of
+// a unix socket of a local key server.
+//
+// Key servers are sent the name of the database followed by a newline, and
+// must answer with the hex-encoded key followed by a newline.
+func LoadKey(source string, name string) (*Key, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	var encoded string
+	if info.Mode()&os.ModeSocket != 0 {
+		encoded, err = requestKey(source, name)
+	} else {
+		var blob []byte
+		blob, err = os.ReadFile(source)
+		encoded = string(blob)
+	}
+	if err != nil {
+		return nil, err
+	}
+	master, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(encoded), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return NewKey(master)
+}
+
+// requestKey retrieves the key of the named database from the key server
+// listening on the given unix socket.
+func requestKey(path string, name string) (string, error) {
+	conn, err := net.DialTimeout("unix", path, keyRequestTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(keyRequestTimeout))
+	if _, err := io.WriteString(conn, name+"\n"); err != nil {
+		return "", err
+	}
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+		return "", fmt.Errorf("failed to read key from %s: %w", path, err)
+	}
+	return line, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package encryptdb
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+)
+
+// errInvalidKey is returned if a stored key can't be decoded.
+var errInvalidKey = errors.New("invalid encrypted key")
+
+// The keys of the database are encrypted with a deterministic, prefix- and
+// order-preserving encoding, so that lookups, prefix iteration and ordering
+// keep working on the encrypted store.
+//
+// Every byte of a key is mapped to two bytes by a strictly increasing function,
+// picked pseudo-randomly from a state depending on all the preceding bytes:
+//
+//	f(b) = gap(0) + gap(1) + ... + gap(b), gap(i) in [1, 255]
+//
+// The gaps are taken from the AES-CTR key stream seeded with the state, which
+// is then advanced by encrypting it mixed with the byte. The encoding thus only
+// reveals the order of the keys and their common prefixes, but not the bytes
+// themselves.
+
+// keyState is the state of the key encoding after a prefix of a key.
+type keyState [aes.BlockSize]byte
+
+// initKeyState returns the state of the key encoding before the first byte.
+func initKeyState(block cipher.Block) keyState {
+	var s keyState
+	block.Encrypt(s[:], s[:])
+	return s
+}
+
+// gaps fills the buffer with the gaps of the byte mapping at the given state.
+func (s *keyState) gaps(block cipher.Block, buf []byte) {
+	clear(buf)
+	cipher.NewCTR(block, s[:]).XORKeyStream(buf, buf)
+	for i := range buf {
+		buf[i] = 1 + buf[i]%255
+	}
+}
+
+// next advances the state over the given byte.
+func (s *keyState) next(block cipher.Block, b byte) {
+	s[0] ^= b
+	block.Encrypt(s[:], s[:])
+}
+
+// encodeKey appends the encoding of the key to dst.
+func encodeKey(block cipher.Block, dst []byte, key []byte) []byte {
+	var (
+		state = initKeyState(block)
+		buf   [256]byte
+	)
+	for _, b := range key {
+		gaps := buf[:int(b)+1]
+		state.gaps(block, gaps)
+
+		var v uint16
+		for _, gap := range gaps {
+			v += uint16(gap)
+		}
+		dst = append(dst, byte(v>>8), byte(v))
+		state.next(block, b)
+	}
+	return dst
+}
+
+// decodeKey decodes an encoded key.
+func decodeKey(block cipher.Block, enc []byte) ([]byte, error) {
+	if len(enc)%2 != 0 {
+		return nil, errInvalidKey
+	}
+	var (
+		key   = make([]byte, 0, len(enc)/2)
+		state = initKeyState(block)
+		gaps  [256]byte
+	)
+	for i := 0; i < len(enc); i += 2 {
+		want := uint16(enc[i])<<8 | uint16(enc[i+1])
+		state.gaps(block, gaps[:])
+
+		var (
+			v     uint16
+			found bool
+			b     int
+		)
+		for b = 0; b < len(gaps); b++ {
+			v += uint16(gaps[b])
+			if v >= want {
+				found = v == want
+				break
+			}
+		}
+		if !found {
+			return nil, errInvalidKey
+		}
+		key = append(key, byte(b))
+		state.next(block, byte(b))
+	}
+	return key, nil
+}
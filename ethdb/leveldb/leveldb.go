@@ -22,6 +22,7 @@ package leveldb
 
 import (
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -265,6 +266,49 @@ func (db *Database) Compact(start []byte, limit []byte) error {
 	return db.db.CompactRange(util.Range{Start: start, Limit: limit})
 }
 
+// Checkpoint creates a consistent copy of the database in the given directory.
+// Leveldb has no native support for checkpoints, so the content of a snapshot
+// of the database is written into a new one.
+func (db *Database) Checkpoint(dir string) error {
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		return fmt.Errorf("checkpoint directory %s already exists", dir)
+	}
+	snap, err := db.db.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snap.Release()
+
+	cpdb, err := leveldb.OpenFile(dir, nil)
+	if err != nil {
+		return err
+	}
+	var (
+		it    = snap.NewIterator(nil, nil)
+		batch = new(leveldb.Batch)
+	)
+	defer it.Release()
+	for it.Next() {
+		batch.Put(it.Key(), it.Value())
+		if batch.Len() >= 1024 {
+			if err := cpdb.Write(batch, nil); err != nil {
+				cpdb.Close()
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	if err := it.Error(); err != nil {
+		cpdb.Close()
+		return err
+	}
+	if err := cpdb.Write(batch, &opt.WriteOptions{Sync: true}); err != nil {
+		cpdb.Close()
+		return err
+	}
+	return cpdb.Close()
+}
+
 // Path returns the path to the database directory.
 func (db *Database) Path() string {
 	return db.fn
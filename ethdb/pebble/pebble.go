@@ -445,6 +445,18 @@ func (d *Database) Compact(start []byte, limit []byte) error {
 	return d.db.Compact(start, limit, true) // Parallelization is preferred
 }
 
+// Checkpoint creates a consistent copy of the database in the given directory.
+// The immutable table files are hard-linked if the directory is on the same
+// filesystem, so the checkpoint is cheap in time and space.
+func (d *Database) Checkpoint(dir string) error {
+	d.quitLock.RLock()
+	defer d.quitLock.RUnlock()
+	if d.closed {
+		return pebble.ErrClosed
+	}
+	return d.db.Checkpoint(dir, pebble.WithFlushedWAL())
+}
+
 // Path returns the path to the database directory.
 func (d *Database) Path() string {
 	return d.fn
@@ -0,0 +1,388 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	// Namespace is the RPC namespace the database API is registered under.
+	Namespace = "db"
+
+	// maxPageItems is the maximum number of entries returned in an iterator page.
+	maxPageItems = 4096
+
+	// maxPageBytes is the soft limit of the size of an iterator page, it is
+	// exceeded by at most one entry.
+	maxPageBytes = 4 * 1024 * 1024
+
+	// maxResources is the maximum number of iterators and snapshots open at the
+	// same time.
+	maxResources = 256
+
+	// resourceTimeout is the time after which unused iterators and snapshots are
+	// released, in case the client didn't release them.
+	resourceTimeout = 5 * time.Minute
+)
+
+var (
+	errUnknownResource  = errors.New("unknown or expired iterator or snapshot")
+	errTooManyResources = errors.New("too many open iterators and snapshots")
+	errWritesDisabled   = errors.New("database writes are disabled")
+	errServerClosed     = errors.New("database server closed")
+)
+
+// BatchOp is a single operation of a remote write batch.
+type BatchOp struct {
+	Key    hexutil.Bytes `json:"key"`
+	Value  hexutil.Bytes `json:"value,omitempty"`
+	Delete bool          `json:"delete,omitempty"`
+}
+
+// IteratorPage is a page of the entries of a remote iterator.
+type IteratorPage struct {
+	Keys   []hexutil.Bytes `json:"keys"`
+	Values []hexutil.Bytes `json:"values"`
+	Done   bool            `json:"done"`
+}
+
+// resource is an iterator or snapshot held open for a client.
+type resource struct {
+	lock     sync.Mutex // Iterators can't be used concurrently
+	iterator ethdb.Iterator
+	snapshot ethdb.Snapshot
+	used     time.Time
+}
+
+// release releases the iterator or snapshot.
+func (r *resource) release() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.iterator != nil {
+		r.iterator.Release()
+	}
+	if r.snapshot != nil {
+		r.snapshot.Release()
+	}
+}
+
+// Server serves a database over RPC, so tools can operate on the database of a
+// running node. It holds the iterators and snapshots opened by clients until
+// they are released or the server is closed.
+type Server struct {
+	db       ethdb.Database
+	writable bool
+
+	lock      sync.Mutex
+	resources map[rpc.ID]*resource
+	closed    bool
+}
+
+// NewServer creates a server for the given database. Unless writable is set,
+// all modifications of the database are rejected.
+func NewServer(db ethdb.Database, writable bool) *Server {
+	return &Server{
+		db:        db,
+		writable:  writable,
+		resources: make(map[rpc.ID]*resource),
+	}
+}
+
+// APIs returns the RPC APIs serving the database. Even in read-only mode they
+// expose all data of the node, and must only be made available on trusted
+// endpoints such as IPC.
+func (s *Server) APIs() []rpc.API {
+	return []rpc.API{{
+		Namespace: Namespace,
+		Service:   &API{s},
+	}}
+}
+
+// Close releases all open iterators and snapshots, and rejects opening new ones.
+func (s *Server) Close() {
+	s.lock.Lock()
+	resources := s.resources
+	s.resources = make(map[rpc.ID]*resource)
+	s.closed = true
+	s.lock.Unlock()
+
+	for _, r := range resources {
+		r.release()
+	}
+}
+
+// open registers an iterator or snapshot, releasing the expired ones.
+func (s *Server) open(r *resource) (rpc.ID, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.closed {
+		r.release()
+		return "", errServerClosed
+	}
+	for id, old := range s.resources {
+		if time.Since(old.used) > resourceTimeout {
+			delete(s.resources, id)
+			go old.release() // Might be in use, don't block on it
+		}
+	}
+	if len(s.resources) >= maxResources {
+		r.release()
+		return "", errTooManyResources
+	}
+	id := rpc.NewID()
+	r.used = time.Now()
+	s.resources[id] = r
+	return id, nil
+}
+
+// get retrieves an open iterator or snapshot.
+func (s *Server) get(id rpc.ID) (*resource, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	r, ok := s.resources[id]
+	if !ok {
+		return nil, errUnknownResource
+	}
+	r.used = time.Now()
+	return r, nil
+}
+
+// close releases an open iterator or snapshot.
+func (s *Server) close(id rpc.ID) error {
+	s.lock.Lock()
+	r, ok := s.resources[id]
+	delete(s.resources, id)
+	s.lock.Unlock()
+
+	if !ok {
+		return errUnknownResource
+	}
+	r.release()
+	return nil
+}
+
+// API is the RPC API of a database server.
+type API struct {
+	s *Server
+}
+
+// Has retrieves if a key is present in the key-value store.
+func (api *API) Has(key hexutil.Bytes) (bool, error) {
+	return api.s.db.Has(key)
+}
+
+// Get retrieves the given key if it's present in the key-value store.
+func (api *API) Get(key hexutil.Bytes) (hexutil.Bytes, error) {
+	return api.s.db.Get(key)
+}
+
+// Put inserts the given value into the key-value store.
+func (api *API) Put(key hexutil.Bytes, value hexutil.Bytes) error {
+	if !api.s.writable {
+		return errWritesDisabled
+	}
+	return api.s.db.Put(key, value)
+}
+
+// Delete removes the key from the key-value store.
+func (api *API) Delete(key hexutil.Bytes) error {
+	if !api.s.writable {
+		return errWritesDisabled
+	}
+	return api.s.db.Delete(key)
+}
+
+// WriteBatch atomically applies the operations of a batch.
+func (api *API) WriteBatch(ops []BatchOp) error {
+	if !api.s.writable {
+		return errWritesDisabled
+	}
+	batch := api.s.db.NewBatch()
+	for _, op := range ops {
+		var err error
+		if op.Delete {
+			err = batch.Delete(op.Key)
+		} else {
+			err = batch.Put(op.Key, op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+// NewIterator creates an iterator over the entries with the given key prefix,
+// starting at the given key, and returns its identifier.
+func (api *API) NewIterator(prefix hexutil.Bytes, start hexutil.Bytes) (rpc.ID, error) {
+	return api.s.open(&resource{iterator: api.s.db.NewIterator(prefix, start)})
+}
+
+// IteratorNext returns the next entries of an iterator, at most the given number.
+// Exhausted iterators are released.
+func (api *API) IteratorNext(id rpc.ID, limit int) (*IteratorPage, error) {
+	r, err := api.s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if r.iterator == nil {
+		return nil, errUnknownResource
+	}
+	if limit <= 0 || limit > maxPageItems {
+		limit = maxPageItems
+	}
+	r.lock.Lock()
+	var (
+		page = new(IteratorPage)
+		size int
+	)
+	for len(page.Keys) < limit && size < maxPageBytes {
+		if !r.iterator.Next() {
+			page.Done = true
+			break
+		}
+		key, value := r.iterator.Key(), r.iterator.Value()
+		page.Keys = append(page.Keys, append([]byte{}, key...))
+		page.Values = append(page.Values, append([]byte{}, value...))
+		size += len(key) + len(value)
+	}
+	err = r.iterator.Error()
+	r.lock.Unlock()
+
+	if page.Done || err != nil {
+		api.s.close(id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// ReleaseIterator releases an iterator.
+func (api *API) ReleaseIterator(id rpc.ID) error {
+	return api.s.close(id)
+}
+
+// NewSnapshot creates a snapshot of the current state of the key-value store
+// and returns its identifier.
+func (api *API) NewSnapshot() (rpc.ID, error) {
+	snap, err := api.s.db.NewSnapshot()
+	if err != nil {
+		return "", err
+	}
+	return api.s.open(&resource{snapshot: snap})
+}
+
+// SnapshotHas retrieves if a key is present in a snapshot.
+func (api *API) SnapshotHas(id rpc.ID, key hexutil.Bytes) (bool, error) {
+	r, err := api.s.get(id)
+	if err != nil {
+		return false, err
+	}
+	if r.snapshot == nil {
+		return false, errUnknownResource
+	}
+	return r.snapshot.Has(key)
+}
+
+// SnapshotGet retrieves the given key if it's present in a snapshot.
+func (api *API) SnapshotGet(id rpc.ID, key hexutil.Bytes) (hexutil.Bytes, error) {
+	r, err := api.s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if r.snapshot == nil {
+		return nil, errUnknownResource
+	}
+	return r.snapshot.Get(key)
+}
+
+// ReleaseSnapshot releases a snapshot.
+func (api *API) ReleaseSnapshot(id rpc.ID) error {
+	return api.s.close(id)
+}
+
+// Stat returns a particular internal stat of the database.
+func (api *API) Stat(property string) (string, error) {
+	return api.s.db.Stat(property)
+}
+
+// Compact flattens the key-value store for the given key range. Missing bounds
+// are treated as the start and end of the key-value store.
+func (api *API) Compact(start *hexutil.Bytes, limit *hexutil.Bytes) error {
+	if !api.s.writable {
+		return errWritesDisabled
+	}
+	var from, to []byte
+	if start != nil {
+		from = *start
+	}
+	if limit != nil {
+		to = *limit
+	}
+	return api.s.db.Compact(from, to)
+}
+
+// HasAncient returns an indicator whether the specified data exists in the
+// ancient store.
+func (api *API) HasAncient(kind string, number uint64) (bool, error) {
+	return api.s.db.HasAncient(kind, number)
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (api *API) Ancient(kind string, number uint64) (hexutil.Bytes, error) {
+	return api.s.db.Ancient(kind, number)
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index
+// 'start'.
+func (api *API) AncientRange(kind string, start, count, maxBytes uint64) ([]hexutil.Bytes, error) {
+	items, err := api.s.db.AncientRange(kind, start, count, maxBytes)
+	if err != nil {
+		return nil, err
+	}
+	blobs := make([]hexutil.Bytes, len(items))
+	for i, item := range items {
+		blobs[i] = item
+	}
+	return blobs, nil
+}
+
+// Ancients returns the ancient item numbers in the ancient store.
+func (api *API) Ancients() (uint64, error) {
+	return api.s.db.Ancients()
+}
+
+// Tail returns the number of first stored item in the ancient store.
+func (api *API) Tail() (uint64, error) {
+	return api.s.db.Tail()
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (api *API) AncientSize(kind string) (uint64, error) {
+	return api.s.db.AncientSize(kind)
+}
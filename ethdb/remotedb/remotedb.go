@@ -14,141 +14,372 @@
 // You should have received a copy of the GNU Lesser General Public License
 // along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
 
-// Package remotedb implements the key-value database layer based on a remote geth
-// node. Under the hood, it utilises the `debug_dbGet` method to implement a
-// read-only database.
-// There really are no guarantees in this database, since the local geth does not
-// exclusive access, but it can be used for basic diagnostics of a remote node.
+// Package remotedb implements the database layer based on the database of a
+// remote geth node, accessed through the `db` RPC namespace. It supports the
+// key-value store with iterators, batches and snapshots, and reads from the
+// ancient store, so database tooling can operate on the database of a running
+// node.
+// There are no guarantees beyond the ones of the individual operations, since
+// the node keeps modifying its database concurrently.
 package remotedb
 
 import (
+	"errors"
+
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-// Database is a key-value lookup for a remote database via debug_dbGet.
+var (
+	// errReadOnly is returned if a read-only remote database is written to.
+	errReadOnly = errors.New("remote database is read-only")
+
+	// errNotSupported is returned for the ancient store operations which are
+	// not available remotely.
+	errNotSupported = errors.New("not supported by remote database")
+)
+
+// iteratorPageSize is the number of entries fetched at once by iterators.
+const iteratorPageSize = 1024
+
+// Database is a database client operating on the database of a remote node.
 type Database struct {
-	remote *rpc.Client
+	remote   *rpc.Client
+	readonly bool
 }
 
-func (db *Database) Has(key []byte) (bool, error) {
-	if _, err := db.Get(key); err != nil {
-		return false, nil
+// New creates a client for the database of the remote node. If readonly is set,
+// all writes are rejected locally.
+func New(client *rpc.Client, readonly bool) ethdb.Database {
+	return &Database{
+		remote:   client,
+		readonly: readonly,
 	}
-	return true, nil
 }
 
+// Has retrieves if a key is present in the key-value store.
+func (db *Database) Has(key []byte) (bool, error) {
+	var has bool
+	err := db.remote.Call(&has, "db_has", hexutil.Bytes(key))
+	return has, err
+}
+
+// Get retrieves the given key if it's present in the key-value store.
 func (db *Database) Get(key []byte) ([]byte, error) {
 	var resp hexutil.Bytes
-	err := db.remote.Call(&resp, "debug_dbGet", hexutil.Bytes(key))
-	if err != nil {
+	if err := db.remote.Call(&resp, "db_get", hexutil.Bytes(key)); err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
-func (db *Database) HasAncient(kind string, number uint64) (bool, error) {
-	if _, err := db.Ancient(kind, number); err != nil {
-		return false, nil
+// Put inserts the given value into the key-value store.
+func (db *Database) Put(key []byte, value []byte) error {
+	if db.readonly {
+		return errReadOnly
+	}
+	return db.remote.Call(nil, "db_put", hexutil.Bytes(key), hexutil.Bytes(value))
+}
+
+// Delete removes the key from the key-value store.
+func (db *Database) Delete(key []byte) error {
+	if db.readonly {
+		return errReadOnly
+	}
+	return db.remote.Call(nil, "db_delete", hexutil.Bytes(key))
+}
+
+// NewBatch creates a write-only database that buffers changes until a final
+// write is called, which applies them atomically on the remote node.
+func (db *Database) NewBatch() ethdb.Batch {
+	return &batch{db: db}
+}
+
+// NewBatchWithSize creates a write-only database batch with pre-allocated buffer.
+func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
+	return &batch{db: db}
+}
+
+// NewIterator creates a binary-alphabetical iterator over a subset
+// of database content with a particular key prefix, starting at a particular
+// initial key (or after, if it does not exist).
+func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
+	it := &iterator{db: db}
+	it.err = db.remote.Call(&it.id, "db_newIterator", hexutil.Bytes(prefix), hexutil.Bytes(start))
+	return it
+}
+
+// NewSnapshot creates a database snapshot based on the current state.
+func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
+	snap := &snapshot{db: db}
+	if err := db.remote.Call(&snap.id, "db_newSnapshot"); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Stat returns a particular internal stat of the remote database.
+func (db *Database) Stat(property string) (string, error) {
+	var stat string
+	err := db.remote.Call(&stat, "db_stat", property)
+	return stat, err
+}
+
+// Compact flattens the remote key-value store for the given key range.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	if db.readonly {
+		return errReadOnly
+	}
+	var from, to *hexutil.Bytes
+	if start != nil {
+		from = (*hexutil.Bytes)(&start)
+	}
+	if limit != nil {
+		to = (*hexutil.Bytes)(&limit)
 	}
-	return true, nil
+	return db.remote.Call(nil, "db_compact", from, to)
 }
 
+// HasAncient returns an indicator whether the specified data exists in the
+// ancient store.
+func (db *Database) HasAncient(kind string, number uint64) (bool, error) {
+	var has bool
+	err := db.remote.Call(&has, "db_hasAncient", kind, number)
+	return has, err
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
 func (db *Database) Ancient(kind string, number uint64) ([]byte, error) {
 	var resp hexutil.Bytes
-	err := db.remote.Call(&resp, "debug_dbAncient", kind, number)
-	if err != nil {
+	if err := db.remote.Call(&resp, "db_ancient", kind, number); err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
+// AncientRange retrieves multiple items in sequence, starting from the index
+// 'start'.
 func (db *Database) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
-	panic("not supported")
+	var resp []hexutil.Bytes
+	if err := db.remote.Call(&resp, "db_ancientRange", kind, start, count, maxBytes); err != nil {
+		return nil, err
+	}
+	items := make([][]byte, len(resp))
+	for i, item := range resp {
+		items[i] = item
+	}
+	return items, nil
 }
 
+// Ancients returns the ancient item numbers in the ancient store.
 func (db *Database) Ancients() (uint64, error) {
 	var resp uint64
-	err := db.remote.Call(&resp, "debug_dbAncients")
+	err := db.remote.Call(&resp, "db_ancients")
 	return resp, err
 }
 
+// Tail returns the number of first stored item in the ancient store.
 func (db *Database) Tail() (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, "db_tail")
+	return resp, err
 }
 
+// AncientSize returns the ancient size of the specified category.
 func (db *Database) AncientSize(kind string) (uint64, error) {
-	panic("not supported")
+	var resp uint64
+	err := db.remote.Call(&resp, "db_ancientSize", kind)
+	return resp, err
 }
 
+// ReadAncients runs the given read operation. The reads are not atomic, as
+// the remote ancient store can't be locked.
 func (db *Database) ReadAncients(fn func(op ethdb.AncientReaderOp) error) (err error) {
 	return fn(db)
 }
 
-func (db *Database) Put(key []byte, value []byte) error {
-	panic("not supported")
-}
-
-func (db *Database) Delete(key []byte) error {
-	panic("not supported")
-}
-
+// ModifyAncients returns an error as the remote ancient store is read-only.
 func (db *Database) ModifyAncients(f func(ethdb.AncientWriteOp) error) (int64, error) {
-	panic("not supported")
+	return 0, errNotSupported
 }
 
+// TruncateHead returns an error as the remote ancient store is read-only.
 func (db *Database) TruncateHead(n uint64) (uint64, error) {
-	panic("not supported")
+	return 0, errNotSupported
 }
 
+// TruncateTail returns an error as the remote ancient store is read-only.
 func (db *Database) TruncateTail(n uint64) (uint64, error) {
-	panic("not supported")
+	return 0, errNotSupported
 }
 
+// Sync is a noop as the remote ancient store is read-only.
 func (db *Database) Sync() error {
 	return nil
 }
 
+// MigrateTable returns an error as the remote ancient store is read-only.
 func (db *Database) MigrateTable(s string, f func([]byte) ([]byte, error)) error {
-	panic("not supported")
+	return errNotSupported
 }
 
-func (db *Database) NewBatch() ethdb.Batch {
-	panic("not supported")
+// AncientDatadir returns an error as the remote ancient store has no local
+// directory.
+func (db *Database) AncientDatadir() (string, error) {
+	return "", errNotSupported
 }
 
-func (db *Database) NewBatchWithSize(size int) ethdb.Batch {
-	panic("not supported")
+// Close closes the connection to the remote node.
+func (db *Database) Close() error {
+	db.remote.Close()
+	return nil
 }
 
-func (db *Database) NewIterator(prefix []byte, start []byte) ethdb.Iterator {
-	panic("not supported")
+// batch is a write-only batch buffering the changes locally until they are
+// written to the remote database.
+type batch struct {
+	db   *Database
+	ops  []BatchOp
+	size int
 }
 
-func (db *Database) Stat(property string) (string, error) {
-	panic("not supported")
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	b.ops = append(b.ops, BatchOp{Key: append([]byte{}, key...), Value: append([]byte{}, value...)})
+	b.size += len(key) + len(value)
+	return nil
 }
 
-func (db *Database) AncientDatadir() (string, error) {
-	panic("not supported")
+// Delete inserts the key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	b.ops = append(b.ops, BatchOp{Key: append([]byte{}, key...), Delete: true})
+	b.size += len(key)
+	return nil
 }
 
-func (db *Database) Compact(start []byte, limit []byte) error {
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write applies the buffered changes atomically on the remote database.
+func (b *batch) Write() error {
+	if b.db.readonly {
+		return errReadOnly
+	}
+	return b.db.remote.Call(nil, "db_writeBatch", b.ops)
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.ops = b.ops[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *batch) Replay(w ethdb.KeyValueWriter) error {
+	for _, op := range b.ops {
+		var err error
+		if op.Delete {
+			err = w.Delete(op.Key)
+		} else {
+			err = w.Put(op.Key, op.Value)
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (db *Database) NewSnapshot() (ethdb.Snapshot, error) {
-	panic("not supported")
+// iterator iterates over the entries of the remote key-value store, fetching
+// them in pages.
+type iterator struct {
+	db   *Database
+	id   string
+	page IteratorPage
+	pos  int
+	done bool // Whether the remote iterator is exhausted and released
+	err  error
 }
 
-func (db *Database) Close() error {
-	db.remote.Close()
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	it.pos++
+	for it.pos >= len(it.page.Keys) {
+		if it.done {
+			return false
+		}
+		var page IteratorPage
+		if err := it.db.remote.Call(&page, "db_iteratorNext", it.id, iteratorPageSize); err != nil {
+			it.err, it.done = err, true
+			return false
+		}
+		it.page, it.pos, it.done = page, 0, page.Done
+	}
+	return true
+}
+
+// Error returns any accumulated error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done.
+func (it *iterator) Key() []byte {
+	if it.pos < len(it.page.Keys) {
+		return it.page.Keys[it.pos]
+	}
 	return nil
 }
 
-func New(client *rpc.Client) ethdb.Database {
-	return &Database{
-		remote: client,
+// Value returns the value of the current key/value pair, or nil if done.
+func (it *iterator) Value() []byte {
+	if it.pos < len(it.page.Values) {
+		return it.page.Values[it.pos]
+	}
+	return nil
+}
+
+// Release releases the remote iterator if it's not exhausted yet.
+func (it *iterator) Release() {
+	if !it.done && it.id != "" {
+		it.db.remote.Call(nil, "db_releaseIterator", it.id)
+	}
+	it.done, it.page = true, IteratorPage{}
+}
+
+// snapshot is a snapshot of the remote key-value store.
+type snapshot struct {
+	db *Database
+	id string
+}
+
+// Has retrieves if a key is present in the snapshot.
+func (s *snapshot) Has(key []byte) (bool, error) {
+	var has bool
+	err := s.db.remote.Call(&has, "db_snapshotHas", s.id, hexutil.Bytes(key))
+	return has, err
+}
+
+// Get retrieves the given key if it's present in the snapshot.
+func (s *snapshot) Get(key []byte) ([]byte, error) {
+	var resp hexutil.Bytes
+	if err := s.db.remote.Call(&resp, "db_snapshotGet", s.id, hexutil.Bytes(key)); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Release releases the remote snapshot.
+func (s *snapshot) Release() {
+	if s.id != "" {
+		s.db.remote.Call(nil, "db_releaseSnapshot", s.id)
+		s.id = ""
 	}
 }
@@ -0,0 +1,213 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package remotedb
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/dbtest"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newTestServer serves the given database over an in-process RPC server and
+// returns a client for it.
+func newTestServer(t *testing.T, db ethdb.Database, writable bool) (*Server, *rpc.Client) {
+	var (
+		server = rpc.NewServer()
+		dbsrv  = NewServer(db, writable)
+	)
+	for _, api := range dbsrv.APIs() {
+		if err := server.RegisterName(api.Namespace, api.Service); err != nil {
+			t.Fatalf("Failed to register API: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		server.Stop()
+		dbsrv.Close()
+	})
+	return dbsrv, rpc.DialInProc(server)
+}
+
+// newTestDatabase serves the given writable database over an in-process RPC
+// server and returns a client for it.
+func newTestDatabase(t *testing.T, db ethdb.Database, readonly bool) ethdb.Database {
+	_, client := newTestServer(t, db, true)
+	return New(client, readonly)
+}
+
+func TestRemoteDB(t *testing.T) {
+	t.Run("DatabaseSuite", func(t *testing.T) {
+		dbtest.TestDatabaseSuite(t, func() ethdb.KeyValueStore {
+			return newTestDatabase(t, rawdb.NewDatabase(memorydb.New()), false)
+		})
+	})
+}
+
+// Tests that iterators spanning multiple pages return all entries in order.
+func TestRemoteDBIteratorPages(t *testing.T) {
+	var (
+		local  = rawdb.NewDatabase(memorydb.New())
+		remote = newTestDatabase(t, local, false)
+		n      = 3*iteratorPageSize + 10
+	)
+	for i := 0; i < n; i++ {
+		local.Put([]byte(fmt.Sprintf("key-%05d", i)), []byte{byte(i)})
+	}
+	it := remote.NewIterator([]byte("key-"), []byte("00010"))
+	defer it.Release()
+
+	for i := 10; i < n; i++ {
+		if !it.Next() {
+			t.Fatalf("Iterator exhausted at %d: %v", i, it.Error())
+		}
+		if key := fmt.Sprintf("key-%05d", i); string(it.Key()) != key || !bytes.Equal(it.Value(), []byte{byte(i)}) {
+			t.Fatalf("Entry mismatch: have %s -> %x, want %s", it.Key(), it.Value(), key)
+		}
+	}
+	if it.Next() {
+		t.Fatalf("Iterator not exhausted: %s", it.Key())
+	}
+	if err := it.Error(); err != nil {
+		t.Fatalf("Iterator failed: %v", err)
+	}
+}
+
+// Tests that missing keys are reported without error, ancient items can be read
+// and that writes are rejected in read-only mode.
+func TestRemoteDBReadOnly(t *testing.T) {
+	local, err := rawdb.NewDatabaseWithFreezer(memorydb.New(), "", "", false)
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	genesis := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)})
+	if _, err := rawdb.WriteAncientBlocks(local, []*types.Block{genesis}, []types.Receipts{nil}, big.NewInt(0)); err != nil {
+		t.Fatalf("Failed to write ancient blocks: %v", err)
+	}
+	remote := newTestDatabase(t, local, true)
+
+	if has, err := remote.Has([]byte("missing")); has || err != nil {
+		t.Fatalf("Missing key reported: %v, %v", has, err)
+	}
+	if frozen, err := remote.Ancients(); frozen != 1 || err != nil {
+		t.Fatalf("Ancient count mismatch: have %d, %v", frozen, err)
+	}
+	if hash, err := remote.Ancient(rawdb.ChainFreezerHashTable, 0); err != nil || !bytes.Equal(hash, genesis.Hash().Bytes()) {
+		t.Fatalf("Ancient hash mismatch: have %x, %v", hash, err)
+	}
+	if has, err := remote.HasAncient(rawdb.ChainFreezerHashTable, 0); !has || err != nil {
+		t.Fatalf("Ancient item not reported: %v, %v", has, err)
+	}
+	if has, err := remote.HasAncient(rawdb.ChainFreezerHashTable, 1); has || err != nil {
+		t.Fatalf("Missing ancient item reported: %v, %v", has, err)
+	}
+	if err := remote.Put([]byte("key"), []byte("value")); err != errReadOnly {
+		t.Fatalf("Write accepted in read-only mode: %v", err)
+	}
+	batch := remote.NewBatch()
+	batch.Put([]byte("key"), []byte("value"))
+	if err := batch.Write(); err != errReadOnly {
+		t.Fatalf("Batch accepted in read-only mode: %v", err)
+	}
+}
+
+// Tests that connection failures are reported, rather than taken as missing data.
+func TestRemoteDBConnectionError(t *testing.T) {
+	_, client := newTestServer(t, rawdb.NewMemoryDatabase(), false)
+	remote := New(client, true)
+	client.Close()
+
+	if _, err := remote.HasAncient(rawdb.ChainFreezerHashTable, 0); err == nil {
+		t.Fatal("HasAncient succeeded on closed connection")
+	}
+	if _, err := remote.Has([]byte("key")); err == nil {
+		t.Fatal("Has succeeded on closed connection")
+	}
+}
+
+// Tests that servers reject writes unless enabled, even from writable clients.
+func TestRemoteDBServerReadOnly(t *testing.T) {
+	local := rawdb.NewMemoryDatabase()
+	local.Put([]byte("key"), []byte("value"))
+
+	_, client := newTestServer(t, local, false)
+	remote := New(client, false)
+
+	if value, err := remote.Get([]byte("key")); err != nil || string(value) != "value" {
+		t.Fatalf("Value mismatch: have %q, %v", value, err)
+	}
+	if err := remote.Put([]byte("key"), []byte("other")); err == nil {
+		t.Fatal("Write accepted by read-only server")
+	}
+	if err := remote.Delete([]byte("key")); err == nil {
+		t.Fatal("Delete accepted by read-only server")
+	}
+	batch := remote.NewBatch()
+	batch.Put([]byte("key"), []byte("other"))
+	if err := batch.Write(); err == nil {
+		t.Fatal("Batch accepted by read-only server")
+	}
+	if err := remote.Compact(nil, nil); err == nil {
+		t.Fatal("Compaction accepted by read-only server")
+	}
+	if value, _ := local.Get([]byte("key")); string(value) != "value" {
+		t.Fatalf("Value modified: have %q", value)
+	}
+}
+
+// Tests that closing a server releases the open iterators and snapshots.
+func TestRemoteDBServerClose(t *testing.T) {
+	local := rawdb.NewMemoryDatabase()
+	for i := 0; i < 2*iteratorPageSize; i++ {
+		local.Put([]byte(fmt.Sprintf("key-%05d", i)), []byte{byte(i)})
+	}
+	dbsrv, client := newTestServer(t, local, false)
+	remote := New(client, true)
+
+	it := remote.NewIterator(nil, nil)
+	defer it.Release()
+	if !it.Next() {
+		t.Fatalf("Iterator exhausted: %v", it.Error())
+	}
+	snap, err := remote.NewSnapshot()
+	if err != nil {
+		t.Fatalf("Failed to create snapshot: %v", err)
+	}
+	defer snap.Release()
+
+	dbsrv.Close()
+	if n := len(dbsrv.resources); n != 0 {
+		t.Fatalf("Resources not released: %d", n)
+	}
+	if _, err := snap.Get([]byte("key-00000")); err == nil {
+		t.Fatal("Snapshot usable after close")
+	}
+	for it.Next() {
+	}
+	if it.Error() == nil {
+		t.Fatal("Iterator usable after close")
+	}
+	if _, err := remote.NewSnapshot(); err == nil {
+		t.Fatal("Snapshot created after close")
+	}
+}
@@ -118,8 +118,9 @@ type SyncProgress struct {
 	HealedBytecodes     uint64 // Number of bytecodes downloaded
 	HealedBytecodeBytes uint64 // Number of bytecodes persisted to disk
 
-	HealingTrienodes uint64 // Number of state trie nodes pending
-	HealingBytecode  uint64 // Number of bytecodes pending
+	HealingTrienodes   uint64 // Number of state trie nodes pending
+	HealingBytecode    uint64 // Number of bytecodes pending
+	RemainingTrienodes uint64 // Estimated number of state trie nodes left to heal
 
 	// "transaction indexing" fields
 	TxIndexFinishedBlocks  uint64 // Number of blocks whose transactions are already indexed
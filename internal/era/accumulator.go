@@ -47,6 +47,21 @@ func ComputeAccumulator(hashes []common.Hash, tds []*big.Int) (common.Hash, erro
 	return hh.HashRoot()
 }
 
+// ComputeBlockHashes calculates the SSZ hash tree root of the execution block
+// hashes of a post-merge Era, merkleized as a List[Bytes32, 8192]. This is not
+// the beacon chain's block_summary_root, which is a vector of beacon block roots.
+func ComputeBlockHashes(hashes []common.Hash) (common.Hash, error) {
+	if len(hashes) > MaxEra1Size {
+		return common.Hash{}, fmt.Errorf("too many records: have %d, max %d", len(hashes), MaxEra1Size)
+	}
+	hh := ssz.NewHasher()
+	for i := range hashes {
+		hh.Append(hashes[i][:])
+	}
+	hh.MerkleizeWithMixin(0, uint64(len(hashes)), uint64(MaxEra1Size))
+	return hh.HashRoot()
+}
+
 // headerRecord is an individual record for a historical header.
 //
 // See https://github.com/ethereum/portal-network-specs/blob/master/history-network.md#the-header-accumulator
@@ -0,0 +1,298 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	"golang.org/x/sync/errgroup"
+)
+
+// errUnknownTable is returned if an ancient table not stored in era1 files is
+// requested.
+var errUnknownTable = errors.New("unknown ancient table")
+
+// AncientReader serves the chain freezer tables from a directory of era1 files,
+// allowing the ancient chain segment to be kept on cheap, read-only storage.
+// The block hashes are indexed in memory when opened, so blocks can also be
+// looked up by hash without the database holding the mappings.
+type AncientReader struct {
+	store  *Store
+	size   uint64        // Total size of the era1 files
+	hashes []common.Hash // Block hashes by number
+	byHash []uint32      // Block numbers ordered by hash
+}
+
+// NewAncientReader opens the era1 files in the given directory, which must all
+// belong to the same network, and indexes the block hashes.
+func NewAncientReader(dir string) (*AncientReader, error) {
+	network, err := detectNetwork(dir)
+	if err != nil {
+		return nil, err
+	}
+	store, err := NewStore(dir, network)
+	if err != nil {
+		return nil, err
+	}
+	r := &AncientReader{store: store}
+	if err := r.index(); err != nil {
+		store.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// detectNetwork returns the network name shared by the era1 files in dir.
+func detectNetwork(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	var network string
+	for _, entry := range entries {
+		if path.Ext(entry.Name()) != ".era1" {
+			continue
+		}
+		name, _, _ := strings.Cut(entry.Name(), "-")
+		if network != "" && name != network {
+			return "", fmt.Errorf("era1 files of multiple networks in %s: %s, %s", dir, network, name)
+		}
+		network = name
+	}
+	if network == "" {
+		return "", fmt.Errorf("no era1 files found in %s", dir)
+	}
+	return network, nil
+}
+
+// index computes the hashes of all the blocks in the era1 files, verifying
+// that the files are contiguous.
+func (r *AncientReader) index() error {
+	var (
+		start = time.Now()
+		files = r.store.files
+	)
+	last, err := Open(filepath.Join(r.store.dir, files[len(files)-1]))
+	if err != nil {
+		return err
+	}
+	count := last.Start() + last.Count()
+	last.Close()
+
+	r.hashes = make([]common.Hash, count)
+	sizes := make([]uint64, len(files))
+
+	var group errgroup.Group
+	group.SetLimit(runtime.NumCPU())
+	for i, file := range files {
+		i, file := i, file
+		group.Go(func() error {
+			name := filepath.Join(r.store.dir, file)
+			e, err := Open(name)
+			if err != nil {
+				return err
+			}
+			defer e.Close()
+
+			want := uint64(i * MaxEra1Size)
+			if e.Start() != want {
+				return fmt.Errorf("era1 file %s starts at block %d, want %d", file, e.Start(), want)
+			}
+			if i < len(files)-1 && e.Count() != uint64(MaxEra1Size) {
+				return fmt.Errorf("era1 file %s holds %d blocks, want %d", file, e.Count(), MaxEra1Size)
+			}
+			sizes[i] = uint64(e.m.length)
+			for n := e.Start(); n < e.Start()+e.Count(); n++ {
+				header, err := e.readRecord(n, TypeCompressedHeader)
+				if err != nil {
+					return fmt.Errorf("era1 file %s, block %d: %w", file, n, err)
+				}
+				r.hashes[n] = crypto.Keccak256Hash(header)
+			}
+			return nil
+		})
+	}
+	if err := group.Wait(); err != nil {
+		return err
+	}
+	for _, size := range sizes {
+		r.size += size
+	}
+	r.byHash = make([]uint32, count)
+	for i := range r.byHash {
+		r.byHash[i] = uint32(i)
+	}
+	sort.Slice(r.byHash, func(i, j int) bool {
+		return bytes.Compare(r.hashes[r.byHash[i]][:], r.hashes[r.byHash[j]][:]) < 0
+	})
+	log.Info("Indexed era1 files", "files", len(files), "blocks", count, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// HeaderNumber returns the number of the block with the given hash, if it is
+// stored in the era1 files.
+func (r *AncientReader) HeaderNumber(hash common.Hash) (uint64, bool) {
+	i := sort.Search(len(r.byHash), func(i int) bool {
+		return bytes.Compare(r.hashes[r.byHash[i]][:], hash[:]) >= 0
+	})
+	if i < len(r.byHash) && r.hashes[r.byHash[i]] == hash {
+		return uint64(r.byHash[i]), true
+	}
+	return 0, false
+}
+
+// HasAncient returns an indicator whether the specified data exists.
+func (r *AncientReader) HasAncient(kind string, number uint64) (bool, error) {
+	switch kind {
+	case rawdb.ChainFreezerHeaderTable, rawdb.ChainFreezerHashTable, rawdb.ChainFreezerBodiesTable,
+		rawdb.ChainFreezerReceiptTable, rawdb.ChainFreezerDifficultyTable:
+		return number < uint64(len(r.hashes)), nil
+	}
+	return false, errUnknownTable
+}
+
+// Ancient retrieves an ancient binary blob from the era1 files, encoded the
+// same way as in the chain freezer.
+func (r *AncientReader) Ancient(kind string, number uint64) ([]byte, error) {
+	if number >= uint64(len(r.hashes)) {
+		if _, err := r.HasAncient(kind, number); err != nil {
+			return nil, err
+		}
+		return nil, ErrNotFound
+	}
+	switch kind {
+	case rawdb.ChainFreezerHashTable:
+		return common.CopyBytes(r.hashes[number][:]), nil
+	case rawdb.ChainFreezerHeaderTable:
+		return r.record(number, TypeCompressedHeader)
+	case rawdb.ChainFreezerBodiesTable:
+		return r.record(number, TypeCompressedBody)
+	case rawdb.ChainFreezerReceiptTable:
+		// The era1 files contain the consensus encoding of the receipts,
+		// convert them into the storage encoding used by the freezer.
+		blob, err := r.record(number, TypeCompressedReceipts)
+		if err != nil {
+			return nil, err
+		}
+		var receipts []*types.Receipt
+		if err := rlp.DecodeBytes(blob, &receipts); err != nil {
+			return nil, err
+		}
+		stored := make([]*types.ReceiptForStorage, len(receipts))
+		for i, receipt := range receipts {
+			stored[i] = (*types.ReceiptForStorage)(receipt)
+		}
+		return rlp.EncodeToBytes(stored)
+	case rawdb.ChainFreezerDifficultyTable:
+		blob, err := r.record(number, TypeTotalDifficulty)
+		if err != nil {
+			return nil, err
+		}
+		return rlp.EncodeToBytes(new(big.Int).SetBytes(reverseOrder(blob)))
+	}
+	return nil, errUnknownTable
+}
+
+// record reads a record of a block from the era1 file containing it.
+func (r *AncientReader) record(number uint64, typ uint16) ([]byte, error) {
+	r.store.lock.Lock()
+	defer r.store.lock.Unlock()
+
+	e, err := r.store.era(number)
+	if err != nil {
+		return nil, err
+	}
+	return e.readRecord(number, typ)
+}
+
+// AncientRange retrieves multiple items in sequence, starting from the index
+// 'start'. It returns at most 'count' items, but at least one if maxBytes is
+// exceeded.
+func (r *AncientReader) AncientRange(kind string, start, count, maxBytes uint64) ([][]byte, error) {
+	var (
+		items [][]byte
+		size  uint64
+	)
+	for number := start; number < start+count && number < uint64(len(r.hashes)); number++ {
+		item, err := r.Ancient(kind, number)
+		if err != nil {
+			return nil, err
+		}
+		if maxBytes != 0 && len(items) > 0 && size+uint64(len(item)) > maxBytes {
+			break
+		}
+		items = append(items, item)
+		size += uint64(len(item))
+	}
+	if len(items) == 0 {
+		return nil, ErrNotFound
+	}
+	return items, nil
+}
+
+// Ancients returns the number of blocks in the era1 files.
+func (r *AncientReader) Ancients() (uint64, error) {
+	return uint64(len(r.hashes)), nil
+}
+
+// Tail returns the number of the first block in the era1 files, which is
+// always the genesis.
+func (r *AncientReader) Tail() (uint64, error) {
+	return 0, nil
+}
+
+// AncientSize returns the size of the era1 files. The tables are interleaved
+// in the files, so the total is reported for the header table and nothing for
+// the others.
+func (r *AncientReader) AncientSize(kind string) (uint64, error) {
+	switch kind {
+	case rawdb.ChainFreezerHeaderTable:
+		return r.size, nil
+	case rawdb.ChainFreezerHashTable, rawdb.ChainFreezerBodiesTable,
+		rawdb.ChainFreezerReceiptTable, rawdb.ChainFreezerDifficultyTable:
+		return 0, nil
+	}
+	return 0, errUnknownTable
+}
+
+// ReadAncients runs the given read operation. The era1 files are immutable, so
+// no further synchronisation is needed.
+func (r *AncientReader) ReadAncients(fn func(ethdb.AncientReaderOp) error) error {
+	return fn(r)
+}
+
+// Close closes the opened era1 files.
+func (r *AncientReader) Close() error {
+	return r.store.Close()
+}
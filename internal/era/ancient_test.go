@@ -0,0 +1,181 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+)
+
+func TestAncientReader(t *testing.T) {
+	dir := t.TempDir()
+	blocks := makeTestEra(t, dir)
+
+	ancients, err := NewAncientReader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := rawdb.NewDatabaseWithAncients(rawdb.NewMemoryDatabase(), ancients, "", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if frozen, _ := db.Ancients(); frozen != uint64(len(blocks)) {
+		t.Fatalf("wrong number of ancients: have %d, want %d", frozen, len(blocks))
+	}
+	for i, want := range blocks {
+		number := rawdb.ReadHeaderNumber(db, want.Hash())
+		if number == nil || *number != uint64(i) {
+			t.Fatalf("block %d: wrong number for hash: %v", i, number)
+		}
+		if hash := rawdb.ReadCanonicalHash(db, uint64(i)); hash != want.Hash() {
+			t.Fatalf("block %d: wrong canonical hash: have %x, want %x", i, hash, want.Hash())
+		}
+		block := rawdb.ReadBlock(db, want.Hash(), uint64(i))
+		if block == nil || block.Hash() != want.Hash() || block.Transactions()[0].Hash() != want.Transactions()[0].Hash() {
+			t.Fatalf("block %d: wrong block", i)
+		}
+		receipts := rawdb.ReadReceipts(db, want.Hash(), uint64(i), want.Time(), params.TestChainConfig)
+		if len(receipts) != 1 || receipts[0].CumulativeGasUsed != 21000+uint64(i) || receipts[0].TxHash != want.Transactions()[0].Hash() {
+			t.Fatalf("block %d: wrong receipts", i)
+		}
+		if td := rawdb.ReadTd(db, want.Hash(), uint64(i)); td == nil || td.Uint64() != uint64(i+1) {
+			t.Fatalf("block %d: wrong total difficulty: %v", i, td)
+		}
+	}
+	if number := rawdb.ReadHeaderNumber(db, common.Hash{0x01}); number != nil {
+		t.Fatalf("unknown hash resolved to block %d", *number)
+	}
+	if _, err := db.TruncateHead(1); err == nil {
+		t.Fatal("truncated read-only ancients")
+	}
+}
+
+// Tests that the chain segments following the era1 files are frozen into a
+// regular freezer, which the state history freezer is placed next to.
+func TestAncientReaderFreezer(t *testing.T) {
+	var (
+		eradir  = t.TempDir()
+		datadir = t.TempDir()
+		ancient = filepath.Join(datadir, "ancient")
+		blocks  = makeTestEra(t, eradir)
+		offset  = uint64(len(blocks))
+	)
+	open := func() ethdb.Database {
+		t.Helper()
+		ancients, err := NewAncientReader(eradir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		db, err := rawdb.Open(rawdb.OpenOptions{
+			Directory:         filepath.Join(datadir, "chaindata"),
+			AncientsDirectory: ancient,
+			Ancients:          ancients,
+			Ephemeral:         true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+	db := open()
+	if dir, err := db.AncientDatadir(); err != nil || dir != ancient {
+		t.Fatalf("wrong ancient datadir: have %q (%v), want %q", dir, err, ancient)
+	}
+	// Freeze some blocks following the era1 files.
+	var headers []*types.Header
+	parent := blocks[len(blocks)-1].Hash()
+	for i := offset; i < offset+4; i++ {
+		header := &types.Header{ParentHash: parent, Number: new(big.Int).SetUint64(i), Difficulty: big.NewInt(1)}
+		headers = append(headers, header)
+		parent = header.Hash()
+	}
+	_, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for _, header := range headers {
+			number, hash := header.Number.Uint64(), header.Hash()
+			if err := op.AppendRaw(rawdb.ChainFreezerHashTable, number, hash[:]); err != nil {
+				return err
+			}
+			if err := op.Append(rawdb.ChainFreezerHeaderTable, number, header); err != nil {
+				return err
+			}
+			if err := op.Append(rawdb.ChainFreezerBodiesTable, number, new(types.Body)); err != nil {
+				return err
+			}
+			if err := op.Append(rawdb.ChainFreezerReceiptTable, number, []*types.ReceiptForStorage{}); err != nil {
+				return err
+			}
+			if err := op.Append(rawdb.ChainFreezerDifficultyTable, number, new(big.Int).SetUint64(number+1)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to freeze blocks: %v", err)
+	}
+	if _, err := db.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		return op.AppendRaw(rawdb.ChainFreezerHashTable, 1, make([]byte, 32))
+	}); err == nil {
+		t.Fatal("modified era1 ancients")
+	}
+	// The path database opens its state history freezer in the ancient dir.
+	tdb := triedb.NewDatabase(db, &triedb.Config{PathDB: pathdb.Defaults})
+	if !common.FileExist(filepath.Join(ancient, "state")) {
+		t.Fatal("state history freezer missing")
+	}
+	tdb.Close()
+	db.Close()
+
+	// The frozen blocks are served along the era1 ones after reopening.
+	db = open()
+	defer db.Close()
+
+	if frozen, _ := db.Ancients(); frozen != offset+4 {
+		t.Fatalf("wrong number of ancients: have %d, want %d", frozen, offset+4)
+	}
+	for _, header := range headers {
+		number := header.Number.Uint64()
+		if hash := rawdb.ReadCanonicalHash(db, number); hash != header.Hash() {
+			t.Fatalf("block %d: wrong canonical hash: have %x, want %x", number, hash, header.Hash())
+		}
+	}
+	items, err := db.AncientRange(rawdb.ChainFreezerHeaderTable, offset-2, 4, 0)
+	if err != nil || len(items) != 4 {
+		t.Fatalf("failed to read range across era1 files and freezer: %d items, %v", len(items), err)
+	}
+	for i, blob := range items {
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(blob, header); err != nil {
+			t.Fatal(err)
+		}
+		if have, want := header.Number.Uint64(), offset-2+uint64(i); have != want {
+			t.Fatalf("range item %d: wrong header %d, want %d", i, have, want)
+		}
+	}
+}
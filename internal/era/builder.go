@@ -70,14 +70,38 @@ import (
 //
 // Due to the accumulator size limit of 8192, the maximum number of blocks in
 // an Era1 batch is also 8192.
+//
+// Blocks after the merge are packaged in post-merge Era files instead, which the
+// builder produces if the first block added has no difficulty. As the total
+// difficulty no longer changes, it's omitted from these files, and the
+// accumulator is computed over the block hashes alone:
+//
+//	erae        := Version | block-tuple* | other-entries* | BlockHashes | BlockIndex
+//	block-tuple := CompressedHeader | CompressedBody | CompressedReceipts
+//	BlockHashes = { type: [0x08, 0x00], data: block-hashes-root }
+//
+// The execution block hashes of the file are merkleized as an SSZ list of length
+// at most 8192, mixing in the number of blocks:
+//
+//	block-hashes-root := hash_tree_root(List[Bytes32, 8192])
+//
+// Note this root commits to execution block hashes grouped by block number. It
+// can't be checked against the beacon chain's historical summaries, which are
+// computed over beacon block roots grouped by slot, as those roots aren't part
+// of the execution chain before Cancun. Post-merge Era files are
+// verified against a list of trusted block-hashes-root values instead, like the
+// accumulators of Era1 files.
+//
+// Pre- and post-merge blocks can't be mixed in a single file.
 type Builder struct {
-	w        *e2store.Writer
-	startNum *uint64
-	startTd  *big.Int
-	indexes  []uint64
-	hashes   []common.Hash
-	tds      []*big.Int
-	written  int
+	w         *e2store.Writer
+	startNum  *uint64
+	startTd   *big.Int
+	postMerge bool
+	indexes   []uint64
+	hashes    []common.Hash
+	tds       []*big.Int
+	written   int
 
 	buf    *bytes.Buffer
 	snappy *snappy.Writer
@@ -123,8 +147,12 @@ func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash comm
 		startNum := number
 		b.startNum = &startNum
 		b.startTd = new(big.Int).Sub(td, difficulty)
+		b.postMerge = difficulty.Sign() == 0
 		b.written += n
 	}
+	if postMerge := difficulty.Sign() == 0; postMerge != b.postMerge {
+		return fmt.Errorf("block %d: can't mix pre- and post-merge blocks", number)
+	}
 	if len(b.indexes) >= MaxEra1Size {
 		return fmt.Errorf("exceeds maximum batch size of %d", MaxEra1Size)
 	}
@@ -143,6 +171,9 @@ func (b *Builder) AddRLP(header, body, receipts []byte, number uint64, hash comm
 	if err := b.snappyWrite(TypeCompressedReceipts, receipts); err != nil {
 		return err
 	}
+	if b.postMerge {
+		return nil
+	}
 
 	// Also write total difficulty, but don't snappy encode.
 	btd := bigToBytes32(td)
@@ -162,11 +193,21 @@ func (b *Builder) Finalize() (common.Hash, error) {
 		return common.Hash{}, errors.New("finalize called on empty builder")
 	}
 	// Compute accumulator root and write entry.
-	root, err := ComputeAccumulator(b.hashes, b.tds)
+	var (
+		root common.Hash
+		typ  = TypeAccumulator
+		err  error
+	)
+	if b.postMerge {
+		root, err = ComputeBlockHashes(b.hashes)
+		typ = TypeBlockHashes
+	} else {
+		root, err = ComputeAccumulator(b.hashes, b.tds)
+	}
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("error calculating accumulator root: %w", err)
 	}
-	n, err := b.w.Write(TypeAccumulator, root[:])
+	n, err := b.w.Write(typ, root[:])
 	b.written += n
 	if err != nil {
 		return common.Hash{}, fmt.Errorf("error writing accumulator: %w", err)
@@ -202,6 +243,11 @@ func (b *Builder) Finalize() (common.Hash, error) {
 	return root, nil
 }
 
+// PostMerge returns whether the builder is packaging post-merge blocks.
+func (b *Builder) PostMerge() bool {
+	return b.postMerge
+}
+
 // snappyWrite is a small helper to take care snappy encoding and writing an e2store entry.
 func (b *Builder) snappyWrite(typ uint16, in []byte) error {
 	var (
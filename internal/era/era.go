@@ -35,6 +35,10 @@ import (
 	"github.com/golang/snappy"
 )
 
+// errNoTotalDifficulty is returned when reading total difficulties from a
+// post-merge Era file.
+var errNoTotalDifficulty = errors.New("post-merge era has no total difficulty")
+
 var (
 	TypeVersion            uint16 = 0x3265
 	TypeCompressedHeader   uint16 = 0x03
@@ -42,6 +46,7 @@ var (
 	TypeCompressedReceipts uint16 = 0x05
 	TypeTotalDifficulty    uint16 = 0x06
 	TypeAccumulator        uint16 = 0x07
+	TypeBlockHashes        uint16 = 0x08
 	TypeBlockIndex         uint16 = 0x3266
 
 	MaxEra1Size = 8192
@@ -53,6 +58,12 @@ func Filename(network string, epoch int, root common.Hash) string {
 	return fmt.Sprintf("%s-%05d-%s.era1", network, epoch, root.Hex()[2:10])
 }
 
+// FilenamePostMerge returns a recognizable post-merge Era file name for the
+// specified epoch and network.
+func FilenamePostMerge(network string, epoch int, root common.Hash) string {
+	return fmt.Sprintf("%s-%05d-%s.erae", network, epoch, root.Hex()[2:10])
+}
+
 // ReadDir reads all the era1 files in a directory for a given network.
 // Format: <network>-<epoch>-<hexroot>.era1
 func ReadDir(dir, network string) ([]string, error) {
@@ -84,13 +95,55 @@ func ReadDir(dir, network string) ([]string, error) {
 	return eras, nil
 }
 
+// ReadDirAll reads all the era1 and post-merge Era files in a directory for a
+// given network, ordered by epoch. The epoch of the merge is split between an
+// era1 and a post-merge file, all others are expected exactly once.
+// Format: <network>-<epoch>-<hexroot>.era1 or <network>-<epoch>-<hexroot>.erae
+func ReadDirAll(dir, network string) ([]string, error) {
+	pre, err := ReadDir(dir, network)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error reading directory %s: %w", dir, err)
+	}
+	// The post-merge files continue from the last era1 epoch, which they
+	// share if the merge didn't happen at an epoch boundary.
+	next := uint64(len(pre))
+	if next > 0 {
+		next--
+	}
+	eras := pre
+	for _, entry := range entries {
+		if path.Ext(entry.Name()) != ".erae" {
+			continue
+		}
+		parts := strings.Split(entry.Name(), "-")
+		if len(parts) != 3 || parts[0] != network {
+			// invalid era filename, skip
+			continue
+		}
+		epoch, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed era filename: %s", entry.Name())
+		}
+		if epoch != next && (len(pre) == 0 || len(eras) > len(pre) || epoch != next+1) {
+			return nil, fmt.Errorf("missing epoch %d", next)
+		}
+		next = epoch + 1
+		eras = append(eras, entry.Name())
+	}
+	return eras, nil
+}
+
 type ReadAtSeekCloser interface {
 	io.ReaderAt
 	io.Seeker
 	io.Closer
 }
 
-// Era reads and Era1 file.
+// Era reads an Era1 or a post-merge Era file.
 type Era struct {
 	f   ReadAtSeekCloser // backing era1 file
 	s   *e2store.Reader  // e2store reader over f
@@ -154,18 +207,83 @@ func (e *Era) GetBlockByNumber(num uint64) (*types.Block, error) {
 	return types.NewBlockWithHeader(&header).WithBody(body), nil
 }
 
-// Accumulator reads the accumulator entry in the Era1 file.
+// GetReceiptsByNumber returns the receipts of the block with the given number.
+// Only the consensus fields of the receipts are stored, the others need to be
+// derived from the block.
+func (e *Era) GetReceiptsByNumber(num uint64) (types.Receipts, error) {
+	blob, err := e.readRecord(num, TypeCompressedReceipts)
+	if err != nil {
+		return nil, err
+	}
+	var receipts types.Receipts
+	if err := rlp.DecodeBytes(blob, &receipts); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// readRecord reads the record of the given type belonging to a block, which
+// is decompressed unless it's the total difficulty.
+func (e *Era) readRecord(num uint64, typ uint16) ([]byte, error) {
+	if e.m.start > num || e.m.start+e.m.count <= num {
+		return nil, errors.New("out-of-bounds")
+	}
+	if typ == TypeTotalDifficulty && e.m.postMerge {
+		return nil, errNoTotalDifficulty
+	}
+	off, err := e.readOffset(num)
+	if err != nil {
+		return nil, err
+	}
+	// Skip over the records preceding the requested one. The records of
+	// a block are stored in the order of their types.
+	for t := TypeCompressedHeader; t < typ; t++ {
+		length, err := e.s.LengthAt(off)
+		if err != nil {
+			return nil, err
+		}
+		off += length
+	}
+	if typ == TypeTotalDifficulty {
+		r, _, err := e.s.ReaderAt(typ, off)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(r)
+	}
+	r, _, err := newSnappyReader(e.s, typ, off)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+// Accumulator reads the accumulator entry in the Era1 file, or the block hashes
+// entry in a post-merge Era file.
 func (e *Era) Accumulator() (common.Hash, error) {
-	entry, err := e.s.Find(TypeAccumulator)
+	typ := TypeAccumulator
+	if e.m.postMerge {
+		typ = TypeBlockHashes
+	}
+	entry, err := e.s.Find(typ)
 	if err != nil {
 		return common.Hash{}, err
 	}
 	return common.BytesToHash(entry.Value), nil
 }
 
+// PostMerge returns whether the file is a post-merge Era, which doesn't
+// contain total difficulties.
+func (e *Era) PostMerge() bool {
+	return e.m.postMerge
+}
+
 // InitialTD returns initial total difficulty before the difficulty of the
 // first block of the Era1 is applied.
 func (e *Era) InitialTD() (*big.Int, error) {
+	if e.m.postMerge {
+		return nil, errNoTotalDifficulty
+	}
 	var (
 		r      io.Reader
 		header types.Header
@@ -250,9 +368,10 @@ func newSnappyReader(e *e2store.Reader, expectedType uint16, off int64) (io.Read
 
 // metadata wraps the metadata in the block index.
 type metadata struct {
-	start  uint64
-	count  uint64
-	length int64
+	start     uint64
+	count     uint64
+	length    int64
+	postMerge bool // Whether the file is a post-merge Era without total difficulties
 }
 
 // readMetadata reads the metadata stored in an Era1 file's block index.
@@ -273,5 +392,19 @@ func readMetadata(f ReadAtSeekCloser) (m metadata, err error) {
 		return
 	}
 	m.start = binary.LittleEndian.Uint64(b[8:])
+
+	// The accumulator entry preceding the block index tells the file types
+	// apart. Its header is followed by the 32 byte root.
+	typ, _, err := e2store.NewReader(f).ReadMetadataAt(m.length - 24 - int64(m.count*8) - 8 - 32)
+	if err != nil {
+		return
+	}
+	switch typ {
+	case TypeAccumulator:
+	case TypeBlockHashes:
+		m.postMerge = true
+	default:
+		err = fmt.Errorf("unexpected entry type %#x before block index", typ)
+	}
 	return
 }
@@ -21,9 +21,12 @@ import (
 	"io"
 	"math/big"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 )
 
 type testchain struct {
@@ -140,3 +143,109 @@ func TestEraFilename(t *testing.T) {
 		}
 	}
 }
+
+func TestPostMergeEra(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		parent common.Hash
+		hashes = make(map[string][]common.Hash)
+	)
+	// Write an era1 file with the pre-merge blocks of epoch 0, and post-merge
+	// files with the rest of epoch 0 and epoch 1.
+	write := func(epoch int, first, last uint64) string {
+		f, err := os.Create(filepath.Join(dir, "tmp"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		var blockHashes []common.Hash
+		builder := NewBuilder(f)
+		for n := first; n <= last; n++ {
+			difficulty := big.NewInt(0)
+			if n < 10 {
+				difficulty = big.NewInt(1)
+			}
+			header := &types.Header{ParentHash: parent, Number: new(big.Int).SetUint64(n), Difficulty: difficulty}
+			block := types.NewBlockWithHeader(header)
+			if err := builder.Add(block, nil, big.NewInt(10)); err != nil {
+				t.Fatalf("error adding block %d: %v", n, err)
+			}
+			blockHashes = append(blockHashes, block.Hash())
+			parent = block.Hash()
+		}
+		root, err := builder.Finalize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := Filename("test", epoch, root)
+		if builder.PostMerge() {
+			name = FilenamePostMerge("test", epoch, root)
+		}
+		if err := os.Rename(f.Name(), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+		hashes[name] = blockHashes
+		return name
+	}
+	names := []string{write(0, 0, 9), write(0, 10, 15), write(1, 16, 31)}
+
+	entries, err := ReadDirAll(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(entries, names) {
+		t.Fatalf("wrong era files: have %v, want %v", entries, names)
+	}
+	for i, name := range entries {
+		e, err := Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if postMerge := i > 0; e.PostMerge() != postMerge {
+			t.Fatalf("%s: wrong post-merge flag: have %t, want %t", name, e.PostMerge(), postMerge)
+		}
+		tds := make([]*big.Int, len(hashes[name]))
+		for j := range tds {
+			tds[j] = big.NewInt(10)
+		}
+		want, err := ComputeAccumulator(hashes[name], tds)
+		if e.PostMerge() {
+			want, err = ComputeBlockHashes(hashes[name])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root, err := e.Accumulator(); err != nil || root != want {
+			t.Fatalf("%s: wrong accumulator: have %x, want %x (err %v)", name, root, want, err)
+		}
+		it, err := NewIterator(e)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; it.Next(); j++ {
+			block, err := it.Block()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if block.Hash() != hashes[name][j] {
+				t.Fatalf("%s: block %d hash mismatch", name, block.NumberU64())
+			}
+			if _, err := it.TotalDifficulty(); (err != nil) != e.PostMerge() {
+				t.Fatalf("%s: unexpected total difficulty error: %v", name, err)
+			}
+		}
+		if it.Error() != nil {
+			t.Fatal(it.Error())
+		}
+		e.Close()
+	}
+	// Blocks from before and after the merge can't share a file.
+	builder := NewBuilder(new(bytes.Buffer))
+	if err := builder.Add(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1)}), nil, big.NewInt(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.Add(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(0)}), nil, big.NewInt(1)); err == nil {
+		t.Fatal("mixed pre- and post-merge blocks")
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// storeOpenFiles is the number of era1 files a store keeps open.
+const storeOpenFiles = 16
+
+// ErrNotFound is returned if the requested block is not in the era1 files.
+var ErrNotFound = errors.New("not found in era1 files")
+
+// Store provides random access to the blocks and receipts in a directory of
+// era1 files, such as the chain history pruned from the database.
+type Store struct {
+	dir   string
+	files []string // Era1 file names by epoch
+
+	lock sync.Mutex // Lock serializing the reads, as files may be closed on eviction
+	eras lru.BasicLRU[int, *Era]
+}
+
+// NewStore opens the era1 files of the network in the given directory.
+func NewStore(dir, network string) (*Store, error) {
+	files, err := ReadDir(dir, network)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no %s era1 files found in %s", network, dir)
+	}
+	return &Store{
+		dir:   dir,
+		files: files,
+		eras:  lru.NewBasicLRU[int, *Era](storeOpenFiles),
+	}, nil
+}
+
+// Blocks returns the number of blocks covered by the era1 files.
+func (s *Store) Blocks() uint64 {
+	return uint64(len(s.files)) * uint64(MaxEra1Size)
+}
+
+// era returns the opened era1 file containing the block, opening it if needed.
+// The caller must hold the lock.
+func (s *Store) era(number uint64) (*Era, error) {
+	epoch := number / uint64(MaxEra1Size)
+	if epoch >= uint64(len(s.files)) {
+		return nil, ErrNotFound
+	}
+	if e, ok := s.eras.Get(int(epoch)); ok {
+		return e, nil
+	}
+	e, err := Open(filepath.Join(s.dir, s.files[epoch]))
+	if err != nil {
+		return nil, err
+	}
+	if e.Start() != epoch*uint64(MaxEra1Size) {
+		e.Close()
+		return nil, fmt.Errorf("era1 file %s starts at block %d, want %d", s.files[epoch], e.Start(), epoch*uint64(MaxEra1Size))
+	}
+	if s.eras.Len() >= storeOpenFiles {
+		if _, evicted, ok := s.eras.RemoveOldest(); ok {
+			evicted.Close()
+		}
+	}
+	s.eras.Add(int(epoch), e)
+	return e, nil
+}
+
+// GetBlockByNumber returns the block with the given number.
+func (s *Store) GetBlockByNumber(number uint64) (*types.Block, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, err := s.era(number)
+	if err != nil {
+		return nil, err
+	}
+	if number >= e.Start()+e.Count() {
+		return nil, ErrNotFound
+	}
+	return e.GetBlockByNumber(number)
+}
+
+// GetReceiptsByNumber returns the receipts of the block with the given number,
+// with only their consensus fields set.
+func (s *Store) GetReceiptsByNumber(number uint64) (types.Receipts, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e, err := s.era(number)
+	if err != nil {
+		return nil, err
+	}
+	if number >= e.Start()+e.Count() {
+		return nil, ErrNotFound
+	}
+	return e.GetReceiptsByNumber(number)
+}
+
+// Close closes all the opened era1 files.
+func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var errs []error
+	for _, epoch := range s.eras.Keys() {
+		if e, ok := s.eras.Peek(epoch); ok {
+			if err := e.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	s.eras.Purge()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,99 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package era
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// makeTestEra writes an era1 file of the test network holding a short chain,
+// returning its blocks.
+func makeTestEra(t *testing.T, dir string) []*types.Block {
+	t.Helper()
+
+	f, err := os.Create(filepath.Join(dir, "tmp.era1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		builder = NewBuilder(f)
+		blocks  []*types.Block
+		parent  common.Hash
+		to      = common.Address{0x11}
+	)
+	for i := uint64(0); i < 16; i++ {
+		tx := types.NewTx(&types.LegacyTx{Nonce: i, GasPrice: big.NewInt(1), Gas: 21000, To: &to})
+		header := &types.Header{ParentHash: parent, Number: new(big.Int).SetUint64(i), Difficulty: big.NewInt(1)}
+		block := types.NewBlock(header, &types.Body{Transactions: types.Transactions{tx}}, nil, trie.NewStackTrie(nil))
+		receipts := types.Receipts{{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000 + i, Logs: []*types.Log{}}}
+		if err := builder.Add(block, receipts, new(big.Int).SetUint64(i+1)); err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+		parent = block.Hash()
+	}
+	root, err := builder.Finalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := os.Rename(f.Name(), filepath.Join(dir, Filename("test", 0, root))); err != nil {
+		t.Fatal(err)
+	}
+	return blocks
+}
+
+func TestStore(t *testing.T) {
+	dir := t.TempDir()
+	blocks := makeTestEra(t, dir)
+
+	store, err := NewStore(dir, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	for i, want := range blocks {
+		block, err := store.GetBlockByNumber(uint64(i))
+		if err != nil {
+			t.Fatalf("block %d: %v", i, err)
+		}
+		if block.Hash() != want.Hash() {
+			t.Fatalf("block %d: hash mismatch: have %x, want %x", i, block.Hash(), want.Hash())
+		}
+		receipts, err := store.GetReceiptsByNumber(uint64(i))
+		if err != nil {
+			t.Fatalf("receipts %d: %v", i, err)
+		}
+		if len(receipts) != 1 || receipts[0].CumulativeGasUsed != 21000+uint64(i) {
+			t.Fatalf("receipts %d: unexpected contents %v", i, receipts)
+		}
+	}
+	if _, err := store.GetBlockByNumber(uint64(len(blocks))); err != ErrNotFound {
+		t.Fatalf("block beyond the archive: have %v, want %v", err, ErrNotFound)
+	}
+	if _, err := store.GetBlockByNumber(uint64(MaxEra1Size)); err != ErrNotFound {
+		t.Fatalf("block beyond the era1 files: have %v, want %v", err, ErrNotFound)
+	}
+}
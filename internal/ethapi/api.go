@@ -171,6 +171,7 @@ func (api *EthereumAPI) Syncing() (interface{}, error) {
 		"healedBytecodeBytes":    hexutil.Uint64(progress.HealedBytecodeBytes),
 		"healingTrienodes":       hexutil.Uint64(progress.HealingTrienodes),
 		"healingBytecode":        hexutil.Uint64(progress.HealingBytecode),
+		"remainingTrienodes":     hexutil.Uint64(progress.RemainingTrienodes),
 		"txIndexFinishedBlocks":  hexutil.Uint64(progress.TxIndexFinishedBlocks),
 		"txIndexRemainingBlocks": hexutil.Uint64(progress.TxIndexRemainingBlocks),
 	}, nil
@@ -937,6 +938,10 @@ func (api *BlockChainAPI) GetStorageAt(ctx context.Context, address common.Addre
 func (api *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) ([]map[string]interface{}, error) {
 	block, err := api.b.BlockByNumberOrHash(ctx, blockNrOrHash)
 	if block == nil || err != nil {
+		var pruned *PrunedHistoryError
+		if errors.As(err, &pruned) {
+			return nil, err
+		}
 		// When the block doesn't exist, the RPC method should return JSON null
 		// as per specification.
 		return nil, nil
@@ -1172,7 +1177,7 @@ func (api *BlockChainAPI) Call(ctx context.Context, args TransactionArgs, blockN
 	}
 	// If the result contains a revert reason, try to unpack and return it.
 	if len(result.Revert()) > 0 {
-		return nil, newRevertError(result.Revert())
+		return nil, newRevertError(result.Revert(), api.b.RevertDecoder(), api.b.RPCRevertErrorData())
 	}
 	return result.Return(), result.Err
 }
@@ -1212,7 +1217,7 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 	estimate, revert, err := gasestimator.Estimate(ctx, call, opts, gasCap)
 	if err != nil {
 		if len(revert) > 0 {
-			return 0, newRevertError(revert)
+			return 0, newRevertError(revert, b.RevertDecoder(), b.RPCRevertErrorData())
 		}
 		return 0, err
 	}
@@ -1653,7 +1658,7 @@ func (api *TransactionAPI) GetTransactionByHash(ctx context.Context, hash common
 		if err == nil {
 			return nil, nil
 		}
-		return nil, NewTxIndexingError()
+		return nil, txLookupError(err)
 	}
 	header, err := api.b.HeaderByHash(ctx, blockHash)
 	if err != nil {
@@ -1673,7 +1678,7 @@ func (api *TransactionAPI) GetRawTransactionByHash(ctx context.Context, hash com
 		if err == nil {
 			return nil, nil
 		}
-		return nil, NewTxIndexingError()
+		return nil, txLookupError(err)
 	}
 	return tx.MarshalBinary()
 }
@@ -1682,7 +1687,7 @@ func (api *TransactionAPI) GetRawTransactionByHash(ctx context.Context, hash com
 func (api *TransactionAPI) GetTransactionReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
 	found, tx, blockHash, blockNumber, index, err := api.b.GetTransaction(ctx, hash)
 	if err != nil {
-		return nil, NewTxIndexingError() // transaction is not fully indexed
+		return nil, txLookupError(err) // transaction is not fully indexed or pruned
 	}
 	if !found {
 		return nil, nil // transaction is not existent or reachable
@@ -2093,11 +2098,21 @@ func (api *DebugAPI) GetRawTransaction(ctx context.Context, hash common.Hash) (h
 		if err == nil {
 			return nil, nil
 		}
-		return nil, NewTxIndexingError()
+		return nil, txLookupError(err)
 	}
 	return tx.MarshalBinary()
 }
 
+// DecodeRevert decodes the data returned by a reverted call into a structured
+// error, using the contract ABIs and error signatures known to the node.
+func (api *DebugAPI) DecodeRevert(data hexutil.Bytes) (*DecodedRevert, error) {
+	decoded := api.b.RevertDecoder().Decode(data)
+	if decoded == nil {
+		return nil, errors.New("unknown revert data")
+	}
+	return decoded, nil
+}
+
 // PrintBlock retrieves a block and returns its pretty printed form.
 func (api *DebugAPI) PrintBlock(ctx context.Context, number uint64) (string, error) {
 	block, _ := api.b.BlockByNumber(ctx, rpc.BlockNumber(number))
@@ -480,6 +480,8 @@ func (b testBackend) RPCGasCap() uint64                        { return 10000000
 func (b testBackend) RPCEVMTimeout() time.Duration             { return time.Second }
 func (b testBackend) RPCTxFeeCap() float64                     { return 0 }
 func (b testBackend) UnprotectedAllowed() bool                 { return false }
+func (b testBackend) RevertDecoder() *RevertDecoder            { return nil }
+func (b testBackend) RPCRevertErrorData() bool                 { return false }
 func (b testBackend) SetHead(number uint64)                    {}
 func (b testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
 	if number == rpc.LatestBlockNumber {
@@ -2053,3 +2055,23 @@ func testRPCResponseWithFile(t *testing.T, testid int, result interface{}, rpc s
 	}
 	require.JSONEqf(t, string(want), string(data), "test %d: json not match, want: %s, have: %s", testid, string(want), string(data))
 }
+
+// This test checks that failed transaction lookups are only reported as
+// indexing in progress if the indexer is actually behind.
+func TestTxLookupError(t *testing.T) {
+	corrupt := errors.New("era1 block 1 hash mismatch")
+	tests := []struct {
+		err  error
+		want error
+	}{
+		{NewPrunedHistoryError(), NewPrunedHistoryError()},
+		{fmt.Errorf("lookup failed: %w", NewPrunedHistoryError()), NewPrunedHistoryError()},
+		{core.ErrTxIndexing, NewTxIndexingError()},
+		{corrupt, corrupt},
+	}
+	for i, test := range tests {
+		if have := txLookupError(test.err); !reflect.DeepEqual(have, test.want) {
+			t.Errorf("test %d: wrong error: have %v, want %v", i, have, test.want)
+		}
+	}
+}
@@ -19,6 +19,7 @@ package ethapi
 import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 )
 
 // DbGet returns the raw value of a key stored in the database.
@@ -41,3 +42,10 @@ func (api *DebugAPI) DbAncient(kind string, number uint64) (hexutil.Bytes, error
 func (api *DebugAPI) DbAncients() (uint64, error) {
 	return api.b.ChainDb().Ancients()
 }
+
+// DbStats returns the incrementally maintained statistics of the key-value store
+// categories, as persisted at most a minute ago, and the current statistics of
+// the ancient store tables.
+func (api *DebugAPI) DbStats() (*rawdb.DatabaseStats, error) {
+	return rawdb.ReadDatabaseStats(api.b.ChainDb())
+}
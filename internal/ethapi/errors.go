@@ -17,10 +17,11 @@
 package ethapi
 
 import (
+	"errors"
 	"fmt"
 
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/vm"
 )
 
@@ -28,7 +29,15 @@ import (
 // code and a binary data blob.
 type revertError struct {
 	error
-	reason string // revert reason hex encoded
+	reason  string         // revert reason hex encoded
+	decoded *DecodedRevert // structured revert reason, set if it is returned as error data
+}
+
+// revertErrorData is the error data of a revert whose decoding was requested,
+// carrying the decoded revert reason next to the raw one.
+type revertErrorData struct {
+	Data    string         `json:"data"`
+	Decoded *DecodedRevert `json:"decoded"`
 }
 
 // ErrorCode returns the JSON error code for a revert.
@@ -37,23 +46,34 @@ func (e *revertError) ErrorCode() int {
 	return 3
 }
 
-// ErrorData returns the hex encoded revert reason.
+// ErrorData returns the hex encoded revert reason, along with its decoding if
+// that was requested.
 func (e *revertError) ErrorData() interface{} {
-	return e.reason
+	if e.decoded == nil {
+		return e.reason
+	}
+	return &revertErrorData{Data: e.reason, Decoded: e.decoded}
 }
 
 // newRevertError creates a revertError instance with the provided revert data.
-func newRevertError(revert []byte) *revertError {
+// The decoder is used to turn the revert data into a readable error message. If
+// withDecoded is set, the error data is an object holding both the raw and the
+// decoded revert data, otherwise only the raw data is returned.
+func newRevertError(revert []byte, decoder *RevertDecoder, withDecoded bool) *revertError {
 	err := vm.ErrExecutionReverted
 
-	reason, errUnpack := abi.UnpackRevert(revert)
-	if errUnpack == nil {
-		err = fmt.Errorf("%w: %v", vm.ErrExecutionReverted, reason)
+	decoded := decoder.Decode(revert)
+	if decoded != nil {
+		err = fmt.Errorf("%w: %v", vm.ErrExecutionReverted, decoded)
 	}
-	return &revertError{
+	revertErr := &revertError{
 		error:  err,
 		reason: hexutil.Encode(revert),
 	}
+	if withDecoded {
+		revertErr.decoded = decoded
+	}
+	return revertErr
 }
 
 // TxIndexingError is an API error that indicates the transaction indexing is not
@@ -76,3 +96,35 @@ func (e *TxIndexingError) ErrorCode() int {
 
 // ErrorData returns the hex encoded revert reason.
 func (e *TxIndexingError) ErrorData() interface{} { return "transaction indexing is in progress" }
+
+// PrunedHistoryError is an API error that indicates the requested chain history
+// was pruned from the node and is not available from any other source.
+type PrunedHistoryError struct{}
+
+// NewPrunedHistoryError creates a PrunedHistoryError instance.
+func NewPrunedHistoryError() *PrunedHistoryError { return &PrunedHistoryError{} }
+
+// Error implement error interface, returning the error message.
+func (e *PrunedHistoryError) Error() string {
+	return "pruned history unavailable"
+}
+
+// ErrorCode returns the JSON error code for pruned history.
+func (e *PrunedHistoryError) ErrorCode() int {
+	return 4444
+}
+
+// txLookupError converts a failed transaction lookup into the API error to
+// return. Pruned history and indexing still in progress are reported with their
+// API errors, any other failure (e.g. a corrupt era1 file) is returned as is.
+func txLookupError(err error) error {
+	var pruned *PrunedHistoryError
+	switch {
+	case errors.As(err, &pruned):
+		return pruned
+	case errors.Is(err, core.ErrTxIndexing):
+		return NewTxIndexingError()
+	default:
+		return err
+	}
+}
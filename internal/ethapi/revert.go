@@ -0,0 +1,282 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// errorSelector is the selector of the Solidity Error(string) revert.
+	errorSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+	// panicSelector is the selector of the Solidity Panic(uint256) revert.
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// Kinds of decoded revert data.
+const (
+	RevertKindError  = "error"  // Error(string), as raised by require and revert
+	RevertKindPanic  = "panic"  // Panic(uint256), as raised by assert and checked arithmetic
+	RevertKindCustom = "custom" // a custom error declared in a contract ABI
+)
+
+// DecodedRevert is the human-readable form of the data returned by a reverted
+// call.
+type DecodedRevert struct {
+	Kind      string         `json:"kind"`
+	Name      string         `json:"name"`
+	Signature string         `json:"signature"`
+	Reason    string         `json:"reason,omitempty"` // Error(string) message or panic description
+	Code      *hexutil.Big   `json:"code,omitempty"`   // Panic(uint256) code
+	Args      []DecodedValue `json:"args,omitempty"`   // Custom error arguments
+}
+
+// DecodedValue is a single decoded argument of a custom error.
+type DecodedValue struct {
+	Name  string      `json:"name,omitempty"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// String formats the decoded revert the way it is shown in error messages.
+func (d *DecodedRevert) String() string {
+	switch d.Kind {
+	case RevertKindError, RevertKindPanic:
+		return d.Reason
+	}
+	args := make([]string, len(d.Args))
+	for i, arg := range d.Args {
+		value := fmt.Sprint(arg.Value)
+		if b, ok := arg.Value.([]byte); ok {
+			value = hexutil.Encode(b)
+		}
+		if arg.Name != "" {
+			args[i] = fmt.Sprintf("%s=%s", arg.Name, value)
+		} else {
+			args[i] = value
+		}
+	}
+	return fmt.Sprintf("%s(%s)", d.Name, strings.Join(args, ", "))
+}
+
+// RevertDecoder decodes revert data into structured errors. Error(string) and
+// Panic(uint256) are always understood, custom errors are resolved using the
+// contract ABIs and error signatures known to the decoder.
+type RevertDecoder struct {
+	lock   sync.RWMutex
+	errors map[[4]byte]abi.Error
+	named  map[[4]byte]bool // whether the error arguments carry real names
+}
+
+// NewRevertDecoder creates a decoder which only knows the built-in Solidity
+// errors.
+func NewRevertDecoder() *RevertDecoder {
+	return &RevertDecoder{
+		errors: make(map[[4]byte]abi.Error),
+		named:  make(map[[4]byte]bool),
+	}
+}
+
+// Size returns the number of custom errors known to the decoder.
+func (d *RevertDecoder) Size() int {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return len(d.errors)
+}
+
+// AddABI registers all custom errors declared in the contract ABI.
+func (d *RevertDecoder) AddABI(contract *abi.ABI) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, e := range contract.Errors {
+		id := [4]byte(e.ID[:4])
+		d.errors[id] = e
+		d.named[id] = true
+	}
+}
+
+// AddSignature registers a custom error by its signature, e.g.
+// "InsufficientBalance(uint256,uint256)". Errors registered by signature
+// lack argument names, so an error already known from an ABI is kept.
+func (d *RevertDecoder) AddSignature(signature string) error {
+	selector, err := abi.ParseSelector(signature)
+	if err != nil {
+		return err
+	}
+	selector.Type = "error"
+	blob, err := json.Marshal([]abi.SelectorMarshaling{selector})
+	if err != nil {
+		return err
+	}
+	parsed, err := abi.JSON(bytes.NewReader(blob))
+	if err != nil {
+		return err
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for _, e := range parsed.Errors {
+		id := [4]byte(e.ID[:4])
+		if _, ok := d.errors[id]; !ok {
+			d.errors[id] = e
+		}
+	}
+	return nil
+}
+
+// LoadABIDir registers the custom errors of all contract ABIs in the given
+// directory. Both plain ABI files and compiler artifacts with an "abi" field
+// are accepted. Files that cannot be parsed are skipped.
+func (d *RevertDecoder) LoadABIDir(dir string) error {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || (filepath.Ext(file.Name()) != ".json" && filepath.Ext(file.Name()) != ".abi") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		blob, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if json.Unmarshal(blob, &artifact) == nil && len(artifact.ABI) > 0 {
+			blob = artifact.ABI
+		}
+		contract, err := abi.JSON(bytes.NewReader(blob))
+		if err != nil {
+			log.Warn("Skipping invalid contract ABI", "file", path, "err", err)
+			continue
+		}
+		d.AddABI(&contract)
+	}
+	return nil
+}
+
+// LoadSignatures registers the error signatures of a 4byte database file, as
+// used by clef: a JSON object mapping hex selectors to signatures. As such files
+// mostly hold function signatures, which can't be told apart from errors by
+// their selector, only signatures named in CapWords are taken to be errors, in
+// line with the Solidity naming conventions. Entries whose selector doesn't
+// match their signature are skipped.
+func (d *RevertDecoder) LoadSignatures(path string) error {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var db map[string]string
+	if err := json.Unmarshal(blob, &db); err != nil {
+		return err
+	}
+	for id, signature := range db {
+		selector, err := hex.DecodeString(strings.TrimPrefix(id, "0x"))
+		if err != nil || !bytes.Equal(selector, crypto.Keccak256([]byte(signature))[:4]) {
+			continue
+		}
+		if !isErrorSignature(signature) {
+			continue
+		}
+		if err := d.AddSignature(signature); err != nil {
+			log.Debug("Skipping invalid error signature", "signature", signature, "err", err)
+		}
+	}
+	return nil
+}
+
+// isErrorSignature reports whether the signature is named like a custom error,
+// i.e. in CapWords, rather than like a function. The built-in errors are
+// excluded, as they are always decoded.
+func isErrorSignature(signature string) bool {
+	if signature == "" || !unicode.IsUpper(rune(signature[0])) {
+		return false
+	}
+	return signature != "Error(string)" && signature != "Panic(uint256)"
+}
+
+// Decode interprets the revert data. It returns nil if the data doesn't match
+// any known error. Calling Decode on a nil decoder is allowed, and decodes
+// only the built-in errors.
+func (d *RevertDecoder) Decode(data []byte) *DecodedRevert {
+	if len(data) < 4 {
+		return nil
+	}
+	switch {
+	case bytes.Equal(data[:4], errorSelector):
+		reason, err := abi.UnpackRevert(data)
+		if err != nil {
+			return nil
+		}
+		return &DecodedRevert{Kind: RevertKindError, Name: "Error", Signature: "Error(string)", Reason: reason}
+
+	case bytes.Equal(data[:4], panicSelector):
+		reason, err := abi.UnpackRevert(data)
+		if err != nil {
+			return nil
+		}
+		return &DecodedRevert{
+			Kind:      RevertKindPanic,
+			Name:      "Panic",
+			Signature: "Panic(uint256)",
+			Reason:    reason,
+			Code:      (*hexutil.Big)(new(big.Int).SetBytes(data[4:36])),
+		}
+	}
+	if d == nil {
+		return nil
+	}
+	d.lock.RLock()
+	e, ok := d.errors[[4]byte(data[:4])]
+	named := d.named[[4]byte(data[:4])]
+	d.lock.RUnlock()
+	if !ok {
+		return nil
+	}
+	values, err := e.Unpack(data)
+	if err != nil {
+		return nil
+	}
+	decoded := &DecodedRevert{Kind: RevertKindCustom, Name: e.Name, Signature: e.Sig}
+	for i, value := range values.([]interface{}) {
+		arg := DecodedValue{Type: e.Inputs[i].Type.String(), Value: value}
+		if named {
+			arg.Name = e.Inputs[i].Name
+		}
+		decoded.Args = append(decoded.Args, arg)
+	}
+	return decoded
+}
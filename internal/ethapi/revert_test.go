@@ -0,0 +1,190 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethapi
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const revertTestABI = `[{"type":"error","name":"InsufficientBalance","inputs":[{"name":"available","type":"uint256"},{"name":"required","type":"uint256"}]}]`
+
+func TestRevertDecoder(t *testing.T) {
+	contract, err := abi.JSON(strings.NewReader(revertTestABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	artifact, _ := json.Marshal(map[string]json.RawMessage{"abi": json.RawMessage(revertTestABI)})
+	if err := os.WriteFile(filepath.Join(dir, "Token.json"), artifact, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sigs := map[string]string{
+		hexutil.Encode(crypto.Keccak256([]byte("Unauthorized(address)"))[:4]):     "Unauthorized(address)",
+		hexutil.Encode(crypto.Keccak256([]byte("transfer(address,uint256)"))[:4]): "transfer(address,uint256)",
+		"0xdeadbeef": "Mismatching(uint256)",
+	}
+	blob, _ := json.Marshal(sigs)
+	sigfile := filepath.Join(dir, "4byte.json")
+	if err := os.WriteFile(sigfile, blob, 0644); err != nil {
+		t.Fatal(err)
+	}
+	decoder := NewRevertDecoder()
+	if err := decoder.LoadABIDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := decoder.LoadSignatures(sigfile); err != nil {
+		t.Fatal(err)
+	}
+	if have, want := decoder.Size(), 2; have != want {
+		t.Fatalf("wrong number of errors: have %d, want %d", have, want)
+	}
+
+	var (
+		stringType, _  = abi.NewType("string", "", nil)
+		uintType, _    = abi.NewType("uint256", "", nil)
+		addressType, _ = abi.NewType("address", "", nil)
+		addr           = common.HexToAddress("0x0102030405060708090a0b0c0d0e0f1011121314")
+	)
+	pack := func(sig string, typ abi.Type, value interface{}) []byte {
+		data, err := abi.Arguments{{Type: typ}}.Pack(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(crypto.Keccak256([]byte(sig))[:4], data...)
+	}
+	insufficient := contract.Errors["InsufficientBalance"]
+	custom, err := insufficient.Inputs.Pack(big.NewInt(1), big.NewInt(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom = append(insufficient.ID[:4], custom...)
+
+	tests := []struct {
+		data []byte
+		want string
+		kind string
+	}{
+		{pack("Error(string)", stringType, "not owner"), "not owner", RevertKindError},
+		{pack("Panic(uint256)", uintType, big.NewInt(0x11)), "arithmetic underflow or overflow", RevertKindPanic},
+		{custom, "InsufficientBalance(available=1, required=2)", RevertKindCustom},
+		{pack("Unauthorized(address)", addressType, addr), "Unauthorized(" + addr.Hex() + ")", RevertKindCustom},
+		{pack("Unknown(uint256)", uintType, big.NewInt(1)), "", ""},
+		{pack("transfer(address,uint256)", addressType, addr), "", ""},
+		{[]byte{0xde, 0xad, 0xbe, 0xef}, "", ""},
+		{[]byte{0x01}, "", ""},
+	}
+	for i, test := range tests {
+		decoded := decoder.Decode(test.data)
+		if test.kind == "" {
+			if decoded != nil {
+				t.Errorf("test %d: unexpected decoding %v", i, decoded)
+			}
+			continue
+		}
+		if decoded == nil {
+			t.Errorf("test %d: failed to decode", i)
+			continue
+		}
+		if decoded.Kind != test.kind {
+			t.Errorf("test %d: wrong kind: have %s, want %s", i, decoded.Kind, test.kind)
+		}
+		if have := decoded.String(); have != test.want {
+			t.Errorf("test %d: wrong decoding: have %q, want %q", i, have, test.want)
+		}
+	}
+	// Check that the revert error carries the decoded message, but leaves the
+	// error data as the raw hex for compatibility.
+	revertErr := newRevertError(custom, decoder, false)
+	if have, want := revertErr.Error(), "execution reverted: InsufficientBalance(available=1, required=2)"; have != want {
+		t.Errorf("wrong error message: have %q, want %q", have, want)
+	}
+	if have, want := revertErr.ErrorData(), hexutil.Encode(custom); have != want {
+		t.Errorf("wrong error data: have %v, want %v", have, want)
+	}
+	// A nil decoder still understands the built-in errors.
+	if have := (*RevertDecoder)(nil).Decode(tests[0].data); have == nil || have.Reason != "not owner" {
+		t.Errorf("nil decoder failed to decode Error(string): %v", have)
+	}
+}
+
+// This test checks that revert errors carry the structured decoding of the
+// revert data next to the raw hex data, if requested.
+func TestRevertErrorDecodedData(t *testing.T) {
+	contract, err := abi.JSON(strings.NewReader(revertTestABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := NewRevertDecoder()
+	decoder.AddABI(&contract)
+
+	var (
+		stringType, _ = abi.NewType("string", "", nil)
+		uintType, _   = abi.NewType("uint256", "", nil)
+	)
+	pack := func(sig string, args abi.Arguments, values ...interface{}) []byte {
+		data, err := args.Pack(values...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(crypto.Keccak256([]byte(sig))[:4], data...)
+	}
+	tests := []struct {
+		data []byte
+		want string // JSON encoding of the decoded data
+	}{
+		{
+			pack("Error(string)", abi.Arguments{{Type: stringType}}, "not owner"),
+			`{"kind":"error","name":"Error","signature":"Error(string)","reason":"not owner"}`,
+		},
+		{
+			pack("Panic(uint256)", abi.Arguments{{Type: uintType}}, big.NewInt(0x11)),
+			`{"kind":"panic","name":"Panic","signature":"Panic(uint256)","reason":"arithmetic underflow or overflow","code":"0x11"}`,
+		},
+		{
+			pack("InsufficientBalance(uint256,uint256)", contract.Errors["InsufficientBalance"].Inputs, big.NewInt(1), big.NewInt(2)),
+			`{"kind":"custom","name":"InsufficientBalance","signature":"InsufficientBalance(uint256,uint256)","args":[{"name":"available","type":"uint256","value":1},{"name":"required","type":"uint256","value":2}]}`,
+		},
+	}
+	for i, test := range tests {
+		raw := hexutil.Encode(test.data)
+		if have := newRevertError(test.data, decoder, false).ErrorData(); have != raw {
+			t.Errorf("test %d: wrong error data: have %v, want %v", i, have, raw)
+		}
+		blob, err := json.Marshal(newRevertError(test.data, decoder, true).ErrorData())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `{"data":"` + raw + `","decoded":` + test.want + `}`; string(blob) != want {
+			t.Errorf("test %d: wrong error data:\nhave %s\nwant %s", i, blob, want)
+		}
+	}
+	// Unknown revert data is returned raw.
+	data := []byte{0xde, 0xad, 0xbe, 0xef}
+	if have, want := newRevertError(data, decoder, true).ErrorData(), hexutil.Encode(data); have != want {
+		t.Errorf("wrong error data for unknown revert: have %v, want %v", have, want)
+	}
+}
@@ -19,7 +19,9 @@ package node
 import (
 	"context"
 	"fmt"
+	"net/netip"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -120,6 +122,83 @@ func (api *adminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// BanPeer bans a node or an IP network from connecting for the given number of
+// seconds, or permanently if no duration is given. The target is an enode URL,
+// a hex node ID, an IP address or an IP network in CIDR notation. Matching
+// peers are disconnected.
+func (api *adminAPI) BanPeer(target string, seconds *uint64) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	var duration time.Duration
+	if seconds != nil {
+		duration = time.Duration(*seconds) * time.Second
+	}
+	id, network, err := parseBanTarget(target)
+	if err != nil {
+		return false, err
+	}
+	if network.IsValid() {
+		err = server.BanNetwork(network, duration)
+	} else {
+		err = server.BanNode(id, duration)
+	}
+	return err == nil, err
+}
+
+// UnbanPeer lifts the ban of a node or an IP network, reporting whether it was
+// banned. Networks are only unbanned if they match a ban exactly.
+func (api *adminAPI) UnbanPeer(target string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, network, err := parseBanTarget(target)
+	if err != nil {
+		return false, err
+	}
+	if network.IsValid() {
+		return server.UnbanNetwork(network)
+	}
+	return server.UnbanNode(id)
+}
+
+// ListBans retrieves the active node and IP network bans.
+func (api *adminAPI) ListBans() ([]*p2p.BanInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.Bans(), nil
+}
+
+// parseBanTarget parses the target of a ban, which is either a node given by its
+// URL or ID, or an IP network given in CIDR notation or as a single address.
+func parseBanTarget(target string) (enode.ID, netip.Prefix, error) {
+	if network, err := netip.ParsePrefix(target); err == nil {
+		return enode.ID{}, network.Masked(), nil
+	}
+	if ip, err := netip.ParseAddr(target); err == nil {
+		ip = ip.Unmap()
+		return enode.ID{}, netip.PrefixFrom(ip, ip.BitLen()), nil
+	}
+	if strings.HasPrefix(target, "enode://") || strings.HasPrefix(target, "enr:") {
+		node, err := enode.Parse(enode.ValidSchemes, target)
+		if err != nil {
+			return enode.ID{}, netip.Prefix{}, fmt.Errorf("invalid enode: %v", err)
+		}
+		return node.ID(), netip.Prefix{}, nil
+	}
+	id, err := enode.ParseID(target)
+	if err != nil {
+		return enode.ID{}, netip.Prefix{}, fmt.Errorf("invalid ban target %q: not a node or IP network", target)
+	}
+	return id, netip.Prefix{}, nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *adminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
@@ -307,6 +386,16 @@ func (api *adminAPI) Peers() ([]*p2p.PeerInfo, error) {
 	return server.PeersInfo(), nil
 }
 
+// PeerScores retrieves the reputation scores of the connected and recently seen
+// peers, along with the behaviours reported about them.
+func (api *adminAPI) PeerScores() ([]*p2p.PeerScore, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.PeerScores(), nil
+}
+
 // NodeInfo retrieves all the information we know about the host node at the
 // protocol granularity.
 func (api *adminAPI) NodeInfo() (*p2p.NodeInfo, error) {
@@ -25,6 +25,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/stretchr/testify/assert"
 )
@@ -353,3 +355,42 @@ func not(ok bool) string {
 	}
 	return "not "
 }
+
+// This test checks the parsing of admin_banPeer targets.
+func TestParseBanTarget(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	node := enode.NewV4(&key.PublicKey, net.IP{127, 0, 0, 1}, 30303, 30303)
+	tests := []struct {
+		target  string
+		id      enode.ID
+		network string
+		err     bool
+	}{
+		{target: node.ID().String(), id: node.ID()},
+		{target: node.URLv4(), id: node.ID()},
+		{target: node.String(), id: node.ID()},
+		{target: "10.1.2.3", network: "10.1.2.3/32"},
+		{target: "10.1.2.3/16", network: "10.1.0.0/16"},
+		{target: "2001:db8::1/32", network: "2001:db8::/32"},
+		{target: "::ffff:10.1.2.3", network: "10.1.2.3/32"},
+		{target: "enode://invalid", err: true},
+		{target: "not a target", err: true},
+	}
+	for _, test := range tests {
+		id, network, err := parseBanTarget(test.target)
+		if (err != nil) != test.err {
+			t.Errorf("%q: unexpected error %v", test.target, err)
+			continue
+		}
+		if test.err {
+			continue
+		}
+		if test.network != "" {
+			if network.String() != test.network {
+				t.Errorf("%q: wrong network %v, want %s", test.target, network, test.network)
+			}
+		} else if network.IsValid() || id != test.id {
+			t.Errorf("%q: wrong node %v (network %v), want %v", test.target, id, network, test.id)
+		}
+	}
+}
@@ -211,6 +211,18 @@ type Config struct {
 	EnablePersonal bool `toml:"-"`
 
 	DBEngine string `toml:",omitempty"`
+
+	// DBEncryption is the source of the key to encrypt the databases with, either
+	// a file containing the hex-encoded key or the unix socket of a key server.
+	// Encryption is disabled if it's empty.
+	DBEncryption string `toml:",omitempty"`
+
+	// DBEncryptKeys enables the encryption of the database keys besides the values.
+	DBEncryptKeys bool `toml:",omitempty"`
+
+	// DBStats enables the incremental maintenance of the database statistics. It
+	// costs a database read for every written key.
+	DBStats bool `toml:",omitempty"`
 }
 
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"hash/crc32"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -34,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/encryptdb"
 	"github.com/ethereum/go-ethereum/ethdb/memorydb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
@@ -723,14 +725,20 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string, r
 	if n.config.DataDir == "" {
 		db = rawdb.NewMemoryDatabase()
 	} else {
-		db, err = rawdb.Open(rawdb.OpenOptions{
-			Type:      n.config.DBEngine,
-			Directory: n.ResolvePath(name),
-			Namespace: namespace,
-			Cache:     cache,
-			Handles:   handles,
-			ReadOnly:  readonly,
-		})
+		var key *encryptdb.Key
+		if key, err = n.databaseKey(name); err == nil {
+			db, err = rawdb.Open(rawdb.OpenOptions{
+				Type:        n.config.DBEngine,
+				Directory:   n.ResolvePath(name),
+				Namespace:   namespace,
+				Cache:       cache,
+				Handles:     handles,
+				ReadOnly:    readonly,
+				Encryption:  key,
+				EncryptKeys: n.config.DBEncryptKeys,
+				Stats:       n.config.DBStats,
+			})
+		}
 	}
 
 	if err == nil {
@@ -755,15 +763,21 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	if n.config.DataDir == "" {
 		db, err = rawdb.NewDatabaseWithFreezer(memorydb.New(), "", namespace, readonly)
 	} else {
-		db, err = rawdb.Open(rawdb.OpenOptions{
-			Type:              n.config.DBEngine,
-			Directory:         n.ResolvePath(name),
-			AncientsDirectory: n.ResolveAncient(name, ancient),
-			Namespace:         namespace,
-			Cache:             cache,
-			Handles:           handles,
-			ReadOnly:          readonly,
-		})
+		var key *encryptdb.Key
+		if key, err = n.databaseKey(name); err == nil {
+			db, err = rawdb.Open(rawdb.OpenOptions{
+				Type:              n.config.DBEngine,
+				Directory:         n.ResolvePath(name),
+				AncientsDirectory: n.ResolveAncient(name, ancient),
+				Namespace:         namespace,
+				Cache:             cache,
+				Handles:           handles,
+				ReadOnly:          readonly,
+				Encryption:        key,
+				EncryptKeys:       n.config.DBEncryptKeys,
+				Stats:             n.config.DBStats,
+			})
+		}
 	}
 
 	if err == nil {
@@ -772,6 +786,62 @@ func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, ancient
 	return db, err
 }
 
+// OpenDatabaseWithAncients opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// serving the ancient chain segment from the given read-only ancient store. The
+// chain segments following it are moved to a chain freezer in the given ancient
+// directory. If the node is an ephemeral one, a memory database is used. The
+// ancient store is closed along with the database, or if opening it fails.
+func (n *Node) OpenDatabaseWithAncients(name string, cache, handles int, ancient string, ancients ethdb.AncientReader, namespace string, readonly bool) (ethdb.Database, error) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	if n.state == closedState {
+		return nil, ErrNodeStopped
+	}
+	var db ethdb.Database
+	var err error
+	if n.config.DataDir == "" {
+		db, err = rawdb.NewDatabaseWithAncients(memorydb.New(), ancients, "", namespace, readonly)
+	} else {
+		var key *encryptdb.Key
+		if key, err = n.databaseKey(name); err == nil {
+			db, err = rawdb.Open(rawdb.OpenOptions{
+				Type:              n.config.DBEngine,
+				Directory:         n.ResolvePath(name),
+				AncientsDirectory: n.ResolveAncient(name, ancient),
+				Ancients:          ancients,
+				Namespace:         namespace,
+				Cache:             cache,
+				Handles:           handles,
+				ReadOnly:          readonly,
+				Encryption:        key,
+				EncryptKeys:       n.config.DBEncryptKeys,
+				Stats:             n.config.DBStats,
+			})
+		}
+	}
+	if err != nil {
+		if closer, ok := ancients.(io.Closer); ok {
+			closer.Close()
+		}
+		return nil, err
+	}
+	return n.wrapDatabase(db), nil
+}
+
+// databaseKey loads the encryption key of the named database, or returns nil if
+// database encryption is disabled.
+func (n *Node) databaseKey(name string) (*encryptdb.Key, error) {
+	if n.config.DBEncryption == "" {
+		return nil, nil
+	}
+	key, err := encryptdb.LoadKey(n.config.DBEncryption, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key of database %s: %w", name, err)
+	}
+	return key, nil
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.ResolvePath(x)
@@ -803,6 +873,20 @@ func (db *closeTrackingDB) Close() error {
 	return db.Database.Close()
 }
 
+// AncientCipher returns the cipher encrypting the freezer items of the database.
+func (db *closeTrackingDB) AncientCipher() *encryptdb.FileCipher {
+	return rawdb.AncientCipher(db.Database)
+}
+
+// Checkpoint creates a consistent copy of the database, if it supports them.
+func (db *closeTrackingDB) Checkpoint(dir string) error {
+	cp, ok := db.Database.(ethdb.Checkpointer)
+	if !ok {
+		return fmt.Errorf("checkpoints of %T not supported", db.Database)
+	}
+	return cp.Checkpoint(dir)
+}
+
 // wrapDatabase ensures the database will be auto-closed when Node is closed.
 func (n *Node) wrapDatabase(db ethdb.Database) ethdb.Database {
 	wrapper := &closeTrackingDB{db, n}
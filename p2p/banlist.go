@@ -0,0 +1,194 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// BanInfo describes a node or IP network ban as reported by admin_listBans.
+type BanInfo struct {
+	Target string     `json:"target"`          // node ID or IP network in CIDR notation
+	Until  *time.Time `json:"until,omitempty"` // expiry time, nil for permanent bans
+}
+
+// banList tracks the banned nodes and IP networks. The bans are kept in memory
+// for fast lookups during dialing and discovery, and are persisted in the node
+// database so they survive restarts.
+//
+// Expiry times use the zero time to denote permanent bans.
+type banList struct {
+	lock  sync.RWMutex
+	db    *enode.DB
+	nodes map[enode.ID]time.Time
+	nets  map[netip.Prefix]time.Time
+}
+
+// newBanList creates a ban list, loading the active bans from the database.
+func newBanList(db *enode.DB) *banList {
+	return &banList{
+		db:    db,
+		nodes: db.NodeBans(),
+		nets:  db.NetworkBans(),
+	}
+}
+
+// banNode bans a node until the given time.
+func (bl *banList) banNode(id enode.ID, until time.Time) error {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+
+	if err := bl.db.BanNode(id, until); err != nil {
+		return err
+	}
+	bl.nodes[id] = until
+	return nil
+}
+
+// banNetwork bans all nodes within an IP network until the given time.
+func (bl *banList) banNetwork(network netip.Prefix, until time.Time) error {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+
+	network = network.Masked()
+	if err := bl.db.BanNetwork(network, until); err != nil {
+		return err
+	}
+	bl.nets[network] = until
+	return nil
+}
+
+// unbanNode lifts the ban of a node, reporting whether it was banned.
+func (bl *banList) unbanNode(id enode.ID) (bool, error) {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+
+	until, ok := bl.nodes[id]
+	if !ok || banExpired(until, time.Now()) {
+		return false, nil
+	}
+	if err := bl.db.UnbanNode(id); err != nil {
+		return false, err
+	}
+	delete(bl.nodes, id)
+	return true, nil
+}
+
+// unbanNetwork lifts the ban of an IP network, reporting whether it was banned.
+func (bl *banList) unbanNetwork(network netip.Prefix) (bool, error) {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+
+	network = network.Masked()
+	until, ok := bl.nets[network]
+	if !ok || banExpired(until, time.Now()) {
+		return false, nil
+	}
+	if err := bl.db.UnbanNetwork(network); err != nil {
+		return false, err
+	}
+	delete(bl.nets, network)
+	return true, nil
+}
+
+// isBanned reports whether the given node ID or IP address is banned. The IP
+// is ignored if invalid.
+func (bl *banList) isBanned(id enode.ID, ip netip.Addr) bool {
+	if bl == nil {
+		return false
+	}
+	bl.lock.RLock()
+	defer bl.lock.RUnlock()
+
+	now := time.Now()
+	if until, ok := bl.nodes[id]; ok && !banExpired(until, now) {
+		return true
+	}
+	return bl.addrBanned(ip.Unmap(), now)
+}
+
+// isAddrBanned reports whether the given IP address is within a banned network.
+func (bl *banList) isAddrBanned(ip netip.Addr) bool {
+	if bl == nil {
+		return false
+	}
+	bl.lock.RLock()
+	defer bl.lock.RUnlock()
+
+	return bl.addrBanned(ip.Unmap(), time.Now())
+}
+
+func (bl *banList) addrBanned(ip netip.Addr, now time.Time) bool {
+	if !ip.IsValid() {
+		return false
+	}
+	for network, until := range bl.nets {
+		if network.Contains(ip) && !banExpired(until, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsNode reports whether the given node is banned, either by its ID or by
+// the IP address in its record.
+func (bl *banList) containsNode(n *enode.Node) bool {
+	return bl.isBanned(n.ID(), n.IPAddr())
+}
+
+// list returns all active bans, dropping the expired ones.
+func (bl *banList) list() []*BanInfo {
+	bl.lock.Lock()
+	defer bl.lock.Unlock()
+
+	var (
+		now  = time.Now()
+		bans = make([]*BanInfo, 0, len(bl.nodes)+len(bl.nets))
+	)
+	for id, until := range bl.nodes {
+		if banExpired(until, now) {
+			delete(bl.nodes, id)
+			continue
+		}
+		bans = append(bans, newBanInfo(id.String(), until))
+	}
+	for network, until := range bl.nets {
+		if banExpired(until, now) {
+			delete(bl.nets, network)
+			continue
+		}
+		bans = append(bans, newBanInfo(network.String(), until))
+	}
+	return bans
+}
+
+func newBanInfo(target string, until time.Time) *BanInfo {
+	info := &BanInfo{Target: target}
+	if !until.IsZero() {
+		info.Until = &until
+	}
+	return info
+}
+
+// banExpired reports whether a ban with the given expiry time has run out.
+func banExpired(until time.Time, now time.Time) bool {
+	return !until.IsZero() && !now.Before(until)
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests ban lookups by node ID and IP network, and ban expiry.
+func TestBanList(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		bl      = newBanList(db)
+		id      = randomID()
+		other   = randomID()
+		network = netip.MustParsePrefix("10.0.0.0/8")
+	)
+	bl.banNode(id, time.Time{})
+	bl.banNetwork(network, time.Now().Add(time.Hour))
+	bl.banNode(other, time.Now().Add(-time.Second))
+
+	if !bl.isBanned(id, netip.Addr{}) {
+		t.Error("banned node not reported")
+	}
+	if bl.isBanned(other, netip.MustParseAddr("192.168.0.1")) {
+		t.Error("expired ban reported")
+	}
+	if !bl.isBanned(other, netip.MustParseAddr("10.1.2.3")) {
+		t.Error("node within banned network not reported")
+	}
+	if !bl.isAddrBanned(netip.MustParseAddr("::ffff:10.1.2.3")) {
+		t.Error("IPv4-mapped address within banned network not reported")
+	}
+	if bans := bl.list(); len(bans) != 2 {
+		t.Fatalf("wrong number of bans listed: %d", len(bans))
+	}
+	if ok, _ := bl.unbanNetwork(netip.MustParsePrefix("10.1.0.0/16")); ok {
+		t.Error("unbanned a network that was not banned")
+	}
+	if ok, _ := bl.unbanNetwork(network); !ok {
+		t.Error("failed to unban network")
+	}
+	if bl.isAddrBanned(netip.MustParseAddr("10.1.2.3")) {
+		t.Error("address still banned after unban")
+	}
+}
+
+// Tests that banning disconnects matching peers, and that bans are persisted
+// across server restarts.
+func TestServerBans(t *testing.T) {
+	dbpath := filepath.Join(t.TempDir(), "nodes")
+	newServer := func() *Server {
+		srv := &Server{Config: Config{
+			PrivateKey:   newkey(),
+			MaxPeers:     1,
+			NoDiscovery:  true,
+			NoDial:       true,
+			ListenAddr:   "127.0.0.1:0",
+			NodeDatabase: dbpath,
+			Logger:       testlog.Logger(t, log.LvlTrace).New("server", "1"),
+		}}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("can't start server: %v", err)
+		}
+		return srv
+	}
+	srv1 := newServer()
+	srv2 := &Server{Config: Config{
+		PrivateKey:  newkey(),
+		MaxPeers:    1,
+		NoDiscovery: true,
+		Logger:      testlog.Logger(t, log.LvlTrace).New("server", "2"),
+	}}
+	srv2.Start()
+	defer srv2.Stop()
+
+	events := make(chan *PeerEvent, 4)
+	sub := srv1.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+
+	if !syncAddPeer(srv2, srv1.Self()) {
+		t.Fatal("peer not connected")
+	}
+	id := srv2.Self().ID()
+	waitPeerEvent(t, events, id, PeerEventTypeAdd)
+
+	// Banning the network of the peer disconnects it
+	network := netip.MustParsePrefix("127.0.0.0/8")
+	if err := srv1.BanNetwork(network, 0); err != nil {
+		t.Fatalf("failed to ban network: %v", err)
+	}
+	waitPeerEvent(t, events, id, PeerEventTypeDrop)
+	if err := srv1.BanNode(id, time.Hour); err != nil {
+		t.Fatalf("failed to ban node: %v", err)
+	}
+	bans := srv1.Bans()
+	if len(bans) != 2 {
+		t.Fatalf("wrong number of bans: %d", len(bans))
+	}
+	if err := srv1.checkInboundConn(netip.MustParseAddr("127.0.0.1")); err == nil {
+		t.Fatal("inbound connection from banned network accepted")
+	}
+	// Restart the server on the same database, the bans must be retained
+	sub.Unsubscribe()
+	srv1.Stop()
+	srv1 = newServer()
+	defer srv1.Stop()
+
+	restored := srv1.Bans()
+	if len(restored) != len(bans) {
+		t.Fatalf("bans not restored: have %d, want %d", len(restored), len(bans))
+	}
+	for i := range bans {
+		if restored[i].Target != bans[i].Target {
+			t.Errorf("ban %d target mismatch: have %s, want %s", i, restored[i].Target, bans[i].Target)
+		}
+		if (restored[i].Until == nil) != (bans[i].Until == nil) {
+			t.Errorf("ban %d expiry mismatch: have %v, want %v", i, restored[i].Until, bans[i].Until)
+		}
+	}
+	if ok, err := srv1.UnbanNetwork(network); !ok || err != nil {
+		t.Fatalf("failed to unban network: %v", err)
+	}
+	if ok, err := srv1.UnbanNode(id); !ok || err != nil {
+		t.Fatalf("failed to unban node: %v", err)
+	}
+	if bans := srv1.Bans(); len(bans) != 0 {
+		t.Fatalf("bans left after unban: %v", bans)
+	}
+}
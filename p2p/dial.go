@@ -76,6 +76,7 @@ var (
 	errAlreadyConnected = errors.New("already connected")
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNetRestrict      = errors.New("not contained in netrestrict list")
+	errBanned           = errors.New("banned")
 	errNoPort           = errors.New("node does not provide TCP port")
 )
 
@@ -130,6 +131,8 @@ type dialConfig struct {
 	maxDialPeers   int              // maximum number of dialed peers
 	maxActiveDials int              // maximum number of active dials
 	netRestrict    *netutil.Netlist // IP netrestrict list, disabled if nil
+	bans           *banList         // banned nodes and networks, disabled if nil
+	quic           bool             // whether nodes can be dialed over QUIC
 	resolver       nodeResolver
 	dialer         NodeDialer
 	log            log.Logger
@@ -373,7 +376,7 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	if n.ID() == d.self {
 		return errSelf
 	}
-	if n.IPAddr().IsValid() && n.TCP() == 0 {
+	if n.IPAddr().IsValid() && n.TCP() == 0 && (!d.quic || n.QUIC() == 0) {
 		// This check can trigger if a non-TCP node is found
 		// by discovery. If there is no IP, the node is a static
 		// node and the actual endpoint will be resolved later in dialTask.
@@ -388,6 +391,10 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	if d.netRestrict != nil && !d.netRestrict.ContainsAddr(n.IPAddr()) {
 		return errNetRestrict
 	}
+	if d.bans.containsNode(n) {
+		banDialMeter.Mark(1)
+		return errBanned
+	}
 	if d.history.contains(string(n.ID().Bytes())) {
 		return errRecentlyDialed
 	}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"math/rand"
 	"net"
+	"net/netip"
 	"reflect"
 	"sync"
 	"testing"
@@ -152,6 +153,41 @@ func TestDialSchedNetRestrict(t *testing.T) {
 	})
 }
 
+// This test checks that banned nodes and nodes within banned networks are not dialed.
+func TestDialSchedBanned(t *testing.T) {
+	t.Parallel()
+
+	nodes := []*enode.Node{
+		newNode(uintID(0x01), "127.0.0.1:30303"),
+		newNode(uintID(0x02), "127.0.0.2:30303"),
+		newNode(uintID(0x03), "127.0.0.3:30303"),
+		newNode(uintID(0x04), "127.0.2.4:30303"),
+		newNode(uintID(0x05), "127.0.2.5:30303"),
+	}
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	config := dialConfig{
+		bans:           newBanList(db),
+		maxActiveDials: 10,
+		maxDialPeers:   10,
+	}
+	config.bans.banNode(nodes[1].ID(), time.Time{})
+	config.bans.banNetwork(netip.MustParsePrefix("127.0.2.0/24"), time.Now().Add(time.Hour))
+	runDialTest(t, config, []dialTestRound{
+		{
+			discovered:   nodes,
+			wantNewDials: []*enode.Node{nodes[0], nodes[2]},
+		},
+		{
+			succeeded: []enode.ID{
+				nodes[0].ID(),
+				nodes[2].ID(),
+			},
+		},
+	})
+}
+
 // This test checks that static dials work and obey the limits.
 func TestDialSchedStaticDial(t *testing.T) {
 	t.Parallel()
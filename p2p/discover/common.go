@@ -53,9 +53,10 @@ type Config struct {
 	Unhandled   chan<- ReadPacket // unhandled packets are sent on this channel
 
 	// Node table configuration:
-	Bootnodes       []*enode.Node // list of bootstrap nodes
-	PingInterval    time.Duration // speed of node liveness check
-	RefreshInterval time.Duration // used in bucket refresh
+	Banned          func(*enode.Node) bool // reports nodes to keep out of the table
+	Bootnodes       []*enode.Node          // list of bootstrap nodes
+	PingInterval    time.Duration          // speed of node liveness check
+	RefreshInterval time.Duration          // used in bucket refresh
 
 	// The options below are useful in very specific cases, like in unit tests.
 	V5ProtocolID *[6]byte
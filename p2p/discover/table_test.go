@@ -305,6 +305,29 @@ func TestTable_addInboundNode(t *testing.T) {
 	checkBucketContent(t, tab, []*enode.Node{n1, n2v3})
 }
 
+// This test checks that banned nodes are kept out of the table.
+func TestTable_addBannedNode(t *testing.T) {
+	var banned enode.ID
+	tab, db := newTestTable(newPingRecorder(), Config{
+		Banned: func(n *enode.Node) bool { return n.ID() == banned },
+	})
+	<-tab.initDone
+	defer db.Close()
+	defer tab.close()
+
+	n1 := nodeAtDistance(tab.self().ID(), 256, net.IP{88, 77, 66, 1})
+	n2 := nodeAtDistance(tab.self().ID(), 256, net.IP{88, 77, 66, 2})
+	banned = n1.ID()
+	if tab.addFoundNode(n1, false) {
+		t.Fatal("banned node added as found node")
+	}
+	if tab.addInboundNode(n1) {
+		t.Fatal("banned node added as inbound node")
+	}
+	tab.addFoundNode(n2, false)
+	checkBucketContent(t, tab, []*enode.Node{n2})
+}
+
 func TestTable_addFoundNode(t *testing.T) {
 	tab, db := newTestTable(newPingRecorder(), Config{})
 	<-tab.initDone
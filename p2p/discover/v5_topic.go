@@ -0,0 +1,552 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/rand"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// This file implements topic advertisement and search for discovery v5.
+//
+// Every node acts as a registrar, storing the registrations of other nodes in its topic
+// table. Nodes advertise a topic by registering with the registrars closest to the topic
+// identifier in the DHT, and search for it by sending TOPICQUERY to the same nodes.
+//
+// Registrations expire after topicAdLifetime. When the topic table has no room for a new
+// registration, the registrar answers REGTOPIC with a ticket and the time until room is
+// expected. The registrant presents the ticket in its next attempt after the waiting time
+// has passed. Attempts made too early are answered with the remaining waiting time.
+
+const (
+	topicAdLifetime       = 15 * time.Minute // time a registration stays in the topic table
+	topicQueueLimit       = 100              // maximum number of registrations per topic
+	topicTableLimit       = 5000             // maximum number of registrations in total
+	topicMaxWaitTime      = topicAdLifetime  // registrars with longer waiting times are skipped
+	topicQueryResultLimit = 16               // maximum number of nodes in a TOPICQUERY response
+	topicRegistrarCount   = 8                // number of registrars a topic is advertised on
+	topicRegistrarRefresh = 5 * time.Minute  // interval of registrar lookups
+	topicRetryInterval    = time.Minute      // delay after failed registration attempts
+	topicMaxFails         = 3                // failed attempts before a registrar is dropped
+	topicSearchInterval   = 10 * time.Second // delay between topic search rounds
+)
+
+var (
+	errInvalidTicket = errors.New("invalid ticket")
+	errTicketNode    = errors.New("ticket issued for different node or topic")
+)
+
+// Topic identifies a service advertised through discovery v5.
+type Topic [32]byte
+
+// NewTopic creates the topic identifier of a service name.
+func NewTopic(name string) Topic {
+	return sha256.Sum256([]byte(name))
+}
+
+// String returns the topic identifier as a hex string.
+func (t Topic) String() string {
+	return hexutil.Encode(t[:])
+}
+
+// TerminalString returns a shortened hex string for terminal logging.
+func (t Topic) TerminalString() string {
+	return hexutil.Encode(t[:8])
+}
+
+// topicTable stores the topic registrations of other nodes.
+type topicTable struct {
+	clock  mclock.Clock
+	key    []byte                // ticket authentication key
+	queues map[Topic][]*topicReg // registrations by topic, oldest first
+	count  int
+}
+
+type topicReg struct {
+	node *enode.Node
+	time mclock.AbsTime
+}
+
+// topicTicket is the content of a ticket issued by the registrar.
+type topicTicket struct {
+	Node   enode.ID
+	IP     []byte
+	Topic  Topic
+	Issued uint64 // mclock.AbsTime of the registrar
+	Wait   uint64 // waiting time in nanoseconds
+}
+
+func newTopicTable(clock mclock.Clock) *topicTable {
+	key := make([]byte, 32)
+	crand.Read(key)
+	return &topicTable{
+		clock:  clock,
+		key:    key,
+		queues: make(map[Topic][]*topicReg),
+	}
+}
+
+// register attempts to register a node for a topic. It returns a nil ticket if the
+// registration was placed. Otherwise, the returned ticket must be presented again
+// after the waiting time.
+func (tab *topicTable) register(n *enode.Node, topic Topic, ticket []byte, ip netip.Addr) (time.Duration, []byte, error) {
+	now := tab.clock.Now()
+	tab.expire(now)
+
+	// Registering again refreshes an existing registration.
+	if tab.remove(topic, n.ID()) {
+		tab.add(topic, n, now)
+		return 0, nil, nil
+	}
+	if len(ticket) > 0 {
+		t, err := tab.decodeTicket(ticket)
+		if err != nil {
+			return 0, nil, err
+		}
+		if t.Node != n.ID() || t.Topic != topic || !bytes.Equal(t.IP, ip.AsSlice()) {
+			return 0, nil, errTicketNode
+		}
+		ready := mclock.AbsTime(t.Issued).Add(time.Duration(t.Wait))
+		if now < ready {
+			// Too early, the ticket stays valid.
+			return time.Duration(ready - now), ticket, nil
+		}
+	}
+	wait := tab.waitTime(topic, now)
+	if wait <= 0 {
+		tab.add(topic, n, now)
+		return 0, nil, nil
+	}
+	return wait, tab.issueTicket(n.ID(), topic, ip, now, wait), nil
+}
+
+// waitTime returns the time until a registration for the topic can be placed.
+func (tab *topicTable) waitTime(topic Topic, now mclock.AbsTime) time.Duration {
+	var wait time.Duration
+	if q := tab.queues[topic]; len(q) >= topicQueueLimit {
+		wait = time.Duration(q[0].time.Add(topicAdLifetime) - now)
+	}
+	if tab.count >= topicTableLimit {
+		oldest := now
+		for _, q := range tab.queues {
+			if len(q) > 0 && q[0].time < oldest {
+				oldest = q[0].time
+			}
+		}
+		wait = max(wait, time.Duration(oldest.Add(topicAdLifetime)-now))
+	}
+	return wait
+}
+
+func (tab *topicTable) add(topic Topic, n *enode.Node, now mclock.AbsTime) {
+	tab.queues[topic] = append(tab.queues[topic], &topicReg{node: n, time: now})
+	tab.count++
+}
+
+// remove deletes the registration of a node, reporting whether it existed.
+func (tab *topicTable) remove(topic Topic, id enode.ID) bool {
+	q := tab.queues[topic]
+	for i, reg := range q {
+		if reg.node.ID() == id {
+			tab.queues[topic] = append(q[:i:i], q[i+1:]...)
+			tab.count--
+			return true
+		}
+	}
+	return false
+}
+
+// expire removes expired registrations.
+func (tab *topicTable) expire(now mclock.AbsTime) {
+	for topic, q := range tab.queues {
+		i := 0
+		for i < len(q) && q[i].time.Add(topicAdLifetime) <= now {
+			i++
+		}
+		tab.count -= i
+		if i == len(q) {
+			delete(tab.queues, topic)
+		} else if i > 0 {
+			tab.queues[topic] = q[i:]
+		}
+	}
+}
+
+// nodes returns up to limit random nodes registered for the topic.
+func (tab *topicTable) nodes(topic Topic, limit int) []*enode.Node {
+	tab.expire(tab.clock.Now())
+
+	q := tab.queues[topic]
+	nodes := make([]*enode.Node, 0, min(len(q), limit))
+	for _, i := range rand.Perm(len(q)) {
+		if len(nodes) == limit {
+			break
+		}
+		nodes = append(nodes, q[i].node)
+	}
+	return nodes
+}
+
+func (tab *topicTable) issueTicket(id enode.ID, topic Topic, ip netip.Addr, now mclock.AbsTime, wait time.Duration) []byte {
+	enc, _ := rlp.EncodeToBytes(&topicTicket{
+		Node:   id,
+		IP:     ip.AsSlice(),
+		Topic:  topic,
+		Issued: uint64(now),
+		Wait:   uint64(wait),
+	})
+	mac := hmac.New(sha256.New, tab.key)
+	mac.Write(enc)
+	return mac.Sum(enc)
+}
+
+func (tab *topicTable) decodeTicket(ticket []byte) (*topicTicket, error) {
+	if len(ticket) < sha256.Size {
+		return nil, errInvalidTicket
+	}
+	enc, sum := ticket[:len(ticket)-sha256.Size], ticket[len(ticket)-sha256.Size:]
+	mac := hmac.New(sha256.New, tab.key)
+	mac.Write(enc)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return nil, errInvalidTicket
+	}
+	var t topicTicket
+	if err := rlp.DecodeBytes(enc, &t); err != nil {
+		return nil, errInvalidTicket
+	}
+	return &t, nil
+}
+
+// topicSystem serves topic registrations and runs the advertisements of the
+// local node.
+type topicSystem struct {
+	transport *UDPv5
+
+	mutex sync.Mutex
+	table *topicTable
+	ads   map[Topic]context.CancelFunc // running advertisements
+	wg    sync.WaitGroup
+}
+
+// topicRegistration is the state of the registration with a single registrar.
+type topicRegistration struct {
+	node   *enode.Node
+	next   mclock.AbsTime // time of the next attempt
+	ticket []byte
+	fails  int
+}
+
+func newTopicSystem(transport *UDPv5) *topicSystem {
+	return &topicSystem{
+		transport: transport,
+		table:     newTopicTable(transport.clock),
+		ads:       make(map[Topic]context.CancelFunc),
+	}
+}
+
+// handleRegtopic handles a REGTOPIC request, placing the registration or
+// issuing a ticket.
+func (ts *topicSystem) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr netip.AddrPort) {
+	t := ts.transport
+	if p.ENR == nil {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", "missing ENR")
+		return
+	}
+	n, err := enode.New(t.validSchemes, p.ENR)
+	if err == nil && (n.ID() != fromID || n.IPAddr() != fromAddr.Addr()) {
+		err = errors.New("record does not match sender")
+	}
+	if err != nil {
+		t.log.Debug("Invalid "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		return
+	}
+
+	ts.mutex.Lock()
+	wait, ticket, err := ts.table.register(n, p.Topic, p.Ticket, fromAddr.Addr())
+	ts.mutex.Unlock()
+	switch {
+	case err != nil:
+		t.log.Debug("Rejected "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+	case ticket == nil:
+		t.sendResponse(fromID, fromAddr, &v5wire.Regconfirmation{ReqID: p.ReqID, Topic: p.Topic})
+	default:
+		// The waiting time is rounded up to full seconds.
+		waitTime := uint((wait + time.Second - 1) / time.Second)
+		t.sendResponse(fromID, fromAddr, &v5wire.Ticket{ReqID: p.ReqID, Ticket: ticket, WaitTime: waitTime})
+	}
+}
+
+// handleTopicQuery returns the nodes registered for a topic.
+func (ts *topicSystem) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr netip.AddrPort) {
+	ts.mutex.Lock()
+	registered := ts.table.nodes(p.Topic, topicQueryResultLimit)
+	ts.mutex.Unlock()
+
+	nodes := registered[:0]
+	for _, n := range registered {
+		if netutil.CheckRelayAddr(fromAddr.Addr(), n.IPAddr()) == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		ts.transport.sendResponse(fromID, fromAddr, resp)
+	}
+}
+
+// localNodes returns the nodes registered for a topic in the local table.
+func (ts *topicSystem) localNodes(topic Topic) []*enode.Node {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	return ts.table.nodes(topic, topicQueryResultLimit)
+}
+
+// advertise starts advertising the local node for a topic.
+func (ts *topicSystem) advertise(topic Topic) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if _, ok := ts.ads[topic]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(ts.transport.closeCtx)
+	ts.ads[topic] = cancel
+	ts.wg.Add(1)
+	go ts.runAdvertisement(ctx, topic)
+}
+
+// stopAdvertise stops advertising the local node for a topic.
+func (ts *topicSystem) stopAdvertise(topic Topic) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if cancel, ok := ts.ads[topic]; ok {
+		cancel()
+		delete(ts.ads, topic)
+	}
+}
+
+// runAdvertisement keeps the local node registered for a topic with the
+// registrars closest to the topic.
+func (ts *topicSystem) runAdvertisement(ctx context.Context, topic Topic) {
+	defer ts.wg.Done()
+
+	var (
+		t           = ts.transport
+		regs        = make(map[enode.ID]*topicRegistration)
+		nextRefresh mclock.AbsTime
+	)
+	for {
+		now := t.clock.Now()
+		if now >= nextRefresh {
+			ts.updateRegistrars(ctx, topic, regs)
+			if ctx.Err() != nil {
+				return
+			}
+			nextRefresh = now.Add(topicRegistrarRefresh)
+			if len(regs) == 0 {
+				nextRefresh = now.Add(topicRetryInterval)
+			}
+		}
+		// Wait for the next registration attempt or registrar refresh.
+		var next *topicRegistration
+		for _, r := range regs {
+			if next == nil || r.next < next.next {
+				next = r
+			}
+		}
+		deadline := nextRefresh
+		if next != nil && next.next < deadline {
+			deadline = next.next
+		}
+		timer := t.clock.NewTimer(time.Duration(deadline - t.clock.Now()))
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+		if next != nil && t.clock.Now() >= next.next {
+			ts.attemptRegistration(topic, next, regs)
+		}
+	}
+}
+
+// updateRegistrars looks up the nodes closest to the topic. Registrars which are
+// no longer among them are dropped.
+func (ts *topicSystem) updateRegistrars(ctx context.Context, topic Topic, regs map[enode.ID]*topicRegistration) {
+	var (
+		t       = ts.transport
+		closest = t.newLookup(ctx, enode.ID(topic)).run()
+		keep    = make(map[enode.ID]bool)
+		now     = t.clock.Now()
+	)
+	for i := 0; i < len(closest) && i < topicRegistrarCount; i++ {
+		n := closest[i]
+		keep[n.ID()] = true
+		if regs[n.ID()] == nil {
+			regs[n.ID()] = &topicRegistration{node: n, next: now}
+		}
+	}
+	for id := range regs {
+		if !keep[id] {
+			delete(regs, id)
+		}
+	}
+}
+
+// attemptRegistration sends REGTOPIC to a registrar and schedules the next
+// attempt according to the response.
+func (ts *topicSystem) attemptRegistration(topic Topic, r *topicRegistration, regs map[enode.ID]*topicRegistration) {
+	t := ts.transport
+	wait, ticket, err := t.regtopic(r.node, topic, r.ticket)
+	now := t.clock.Now()
+	switch {
+	case err != nil:
+		t.log.Trace("Topic registration failed", "topic", topic.TerminalString(), "id", r.node.ID(), "err", err)
+		r.ticket = nil
+		if r.fails++; r.fails >= topicMaxFails {
+			delete(regs, r.node.ID())
+			return
+		}
+		r.next = now.Add(topicRetryInterval)
+	case ticket == nil:
+		t.log.Trace("Topic registration placed", "topic", topic.TerminalString(), "id", r.node.ID())
+		r.ticket, r.fails = nil, 0
+		r.next = now.Add(topicAdLifetime)
+	case wait > topicMaxWaitTime:
+		// The registrar is too busy, try others after the next refresh.
+		delete(regs, r.node.ID())
+	default:
+		r.ticket, r.fails = ticket, 0
+		r.next = now.Add(wait)
+	}
+}
+
+// wait blocks until all advertisements have stopped.
+func (ts *topicSystem) wait() {
+	ts.wg.Wait()
+}
+
+// topicIterator searches for nodes advertising a topic. Each search round looks
+// up the registrars of the topic and queries them for registered nodes.
+type topicIterator struct {
+	transport *UDPv5
+	topic     Topic
+	ctx       context.Context
+	cancel    func()
+	seen      map[enode.ID]bool
+	buffer    []*enode.Node
+	rounds    int
+}
+
+func newTopicIterator(t *UDPv5, topic Topic) *topicIterator {
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	return &topicIterator{
+		transport: t,
+		topic:     topic,
+		ctx:       ctx,
+		cancel:    cancel,
+		seen:      map[enode.ID]bool{t.Self().ID(): true},
+	}
+}
+
+// Node returns the current node.
+func (it *topicIterator) Node() *enode.Node {
+	if len(it.buffer) == 0 {
+		return nil
+	}
+	return it.buffer[0]
+}
+
+// Next moves to the next node.
+func (it *topicIterator) Next() bool {
+	// Consume next node in buffer.
+	if len(it.buffer) > 0 {
+		it.buffer = it.buffer[1:]
+	}
+	// Run search rounds to refill the buffer.
+	for len(it.buffer) == 0 {
+		if it.rounds > 0 {
+			it.sleep(topicSearchInterval)
+		}
+		if it.ctx.Err() != nil {
+			it.buffer = nil
+			return false
+		}
+		it.rounds++
+		it.search()
+	}
+	return true
+}
+
+// search runs a single search round, adding newly found nodes to the buffer.
+func (it *topicIterator) search() {
+	var (
+		t          = it.transport
+		registrars = t.newLookup(it.ctx, enode.ID(it.topic)).run()
+		results    = make(chan []*enode.Node, len(registrars))
+	)
+	for _, n := range registrars {
+		go func(n *enode.Node) {
+			nodes, err := t.topicQuery(n, it.topic)
+			if err != nil && !errors.Is(err, errClosed) {
+				t.log.Trace("TOPICQUERY failed", "id", n.ID(), "err", err)
+			}
+			results <- nodes
+		}(n)
+	}
+	found := t.topics.localNodes(it.topic)
+	for range registrars {
+		found = append(found, <-results...)
+	}
+	for _, n := range found {
+		if !it.seen[n.ID()] {
+			it.seen[n.ID()] = true
+			it.buffer = append(it.buffer, n)
+		}
+	}
+}
+
+func (it *topicIterator) sleep(d time.Duration) {
+	timer := it.transport.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+	case <-it.ctx.Done():
+	}
+}
+
+// Close ends the iterator.
+func (it *topicIterator) Close() {
+	it.cancel()
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// This test checks registration, expiry and tickets in the topic table.
+func TestTopicTable(t *testing.T) {
+	var (
+		clock = new(mclock.Simulated)
+		tab   = newTopicTable(clock)
+		topic = NewTopic("test")
+		ip    = netip.MustParseAddr("10.0.0.1")
+	)
+	// Fill the queue of the topic.
+	for i := 0; i < topicQueueLimit; i++ {
+		n := enode.NewV4(&newkey().PublicKey, net.IP{10, 0, 0, 1}, 30303, 30303)
+		if _, ticket, err := tab.register(n, topic, nil, ip); ticket != nil || err != nil {
+			t.Fatalf("registration %d not placed: %v", i, err)
+		}
+		clock.Run(time.Second)
+	}
+	if n := len(tab.nodes(topic, topicQueryResultLimit)); n != topicQueryResultLimit {
+		t.Fatalf("wrong number of nodes returned: %d", n)
+	}
+
+	// The next registration gets a ticket, waiting for the oldest one to expire.
+	n := enode.NewV4(&newkey().PublicKey, net.IP{10, 0, 0, 1}, 30303, 30303)
+	wait, ticket, err := tab.register(n, topic, nil, ip)
+	if err != nil || ticket == nil {
+		t.Fatalf("expected ticket, got err %v", err)
+	}
+	if want := topicAdLifetime - topicQueueLimit*time.Second; wait != want {
+		t.Fatalf("wrong waiting time: have %v, want %v", wait, want)
+	}
+
+	// Tickets can't be used by other nodes or for other topics.
+	other := enode.NewV4(&newkey().PublicKey, net.IP{10, 0, 0, 1}, 30303, 30303)
+	if _, _, err := tab.register(other, topic, ticket, ip); err != errTicketNode {
+		t.Fatalf("ticket of other node accepted: %v", err)
+	}
+	if _, _, err := tab.register(n, NewTopic("other"), ticket, ip); err != errTicketNode {
+		t.Fatalf("ticket for other topic accepted: %v", err)
+	}
+	tampered := bytes.Clone(ticket)
+	tampered[0]++
+	if _, _, err := tab.register(n, topic, tampered, ip); err != errInvalidTicket {
+		t.Fatalf("tampered ticket accepted: %v", err)
+	}
+
+	// Attempts before the waiting time return the remaining time.
+	clock.Run(wait / 2)
+	remaining, ticket2, err := tab.register(n, topic, ticket, ip)
+	if err != nil || !bytes.Equal(ticket2, ticket) {
+		t.Fatalf("early attempt failed: %v", err)
+	}
+	if remaining != wait-wait/2 {
+		t.Fatalf("wrong remaining waiting time: have %v, want %v", remaining, wait-wait/2)
+	}
+
+	// After the waiting time, the oldest registration has expired.
+	clock.Run(remaining)
+	if _, ticket, err := tab.register(n, topic, ticket, ip); ticket != nil || err != nil {
+		t.Fatalf("registration not placed after waiting time: %v", err)
+	}
+	if len(tab.queues[topic]) != topicQueueLimit || tab.count != topicQueueLimit {
+		t.Fatalf("wrong table size: queue %d, count %d", len(tab.queues[topic]), tab.count)
+	}
+
+	// All registrations expire eventually.
+	clock.Run(topicAdLifetime)
+	if nodes := tab.nodes(topic, topicQueryResultLimit); len(nodes) != 0 || tab.count != 0 {
+		t.Fatalf("registrations not expired: %d nodes, count %d", len(nodes), tab.count)
+	}
+}
+
+// This test checks that REGTOPIC and TOPICQUERY are handled.
+func TestUDPv5_topicHandling(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		topic  = NewTopic("test")
+		remote = test.getNode(test.remotekey, test.remoteaddr).Node()
+	)
+	// Records of other nodes are rejected.
+	var (
+		otherKey  = newkey()
+		otherAddr = netip.MustParseAddrPort("10.0.1.100:30303")
+		other     = test.getNode(otherKey, otherAddr).Node()
+	)
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte("0"), Topic: topic, ENR: other.Record()})
+
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte("1"), Topic: topic, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr netip.AddrPort, _ v5wire.Nonce) {
+		if !bytes.Equal(p.ReqID, []byte("1")) {
+			t.Error("wrong request ID in response:", p.ReqID)
+		}
+		if p.Topic != topic {
+			t.Error("wrong topic in response:", p.Topic)
+		}
+	})
+
+	test.packetInFrom(otherKey, otherAddr, &v5wire.TopicQuery{ReqID: []byte("2"), Topic: topic})
+	test.waitPacketOut(func(p *v5wire.Nodes, addr netip.AddrPort, _ v5wire.Nonce) {
+		if !bytes.Equal(p.ReqID, []byte("2")) {
+			t.Error("wrong request ID in response:", p.ReqID)
+		}
+		if len(p.Nodes) != 1 {
+			t.Fatalf("wrong number of nodes in response: %d", len(p.Nodes))
+		}
+		if n, err := enode.New(enode.ValidSchemesForTesting, p.Nodes[0]); err != nil || n.ID() != remote.ID() {
+			t.Errorf("wrong node in response: %v", p.Nodes[0])
+		}
+	})
+}
+
+// Real sockets, real crypto: this test checks that advertised topics can be found.
+func TestUDPv5_topicE2E(t *testing.T) {
+	t.Parallel()
+
+	const N = 5
+	var nodes []*UDPv5
+	for i := 0; i < N; i++ {
+		var cfg Config
+		if len(nodes) > 0 {
+			cfg.Bootnodes = []*enode.Node{nodes[0].Self()}
+		}
+		node := startLocalhostV5(t, cfg)
+		nodes = append(nodes, node)
+		defer node.Close()
+	}
+	topic := NewTopic("test")
+	nodes[1].RegisterTopic(topic)
+	nodes[2].RegisterTopic(topic)
+
+	// Wait for the registrations to be placed.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		registered := make(map[enode.ID]bool)
+		for _, n := range nodes {
+			for _, rn := range n.topics.localNodes(topic) {
+				registered[rn.ID()] = true
+			}
+		}
+		if len(registered) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("topic not registered, %d registered nodes", len(registered))
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// Search for the topic.
+	it := nodes[N-1].TopicSearch(topic)
+	defer it.Close()
+	found := make(chan enode.ID, N)
+	go func() {
+		for it.Next() {
+			found <- it.Node().ID()
+		}
+		close(found)
+	}()
+	want := map[enode.ID]bool{nodes[1].Self().ID(): true, nodes[2].Self().ID(): true}
+	timeout := time.After(5 * time.Second)
+	for len(want) > 0 {
+		select {
+		case id, ok := <-found:
+			if !ok {
+				t.Fatal("iterator ended")
+			}
+			if !want[id] {
+				t.Fatalf("unexpected node %v found", id)
+			}
+			delete(want, id)
+		case <-timeout:
+			t.Fatalf("timeout, %d nodes not found", len(want))
+		}
+	}
+}
@@ -78,6 +78,9 @@ type UDPv5 struct {
 	// talkreq handler registry
 	talk *talkSystem
 
+	// topic table and advertisements
+	topics *topicSystem
+
 	// channels into dispatch
 	packetInCh    chan ReadPacket
 	readNextCh    chan struct{}
@@ -176,6 +179,7 @@ func newUDPv5(conn UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv5, error) {
 		cancelCloseCtx: cancelCloseCtx,
 	}
 	t.talk = newTalkSystem(t)
+	t.topics = newTopicSystem(t)
 	tab, err := newTable(t, t.db, cfg)
 	if err != nil {
 		return nil, err
@@ -195,6 +199,7 @@ func (t *UDPv5) Close() {
 		t.cancelCloseCtx()
 		t.conn.Close()
 		t.talk.wait()
+		t.topics.wait()
 		t.wg.Wait()
 		t.tab.close()
 	})
@@ -279,6 +284,25 @@ func (t *UDPv5) TalkRequestToID(id enode.ID, addr netip.AddrPort, protocol strin
 	}
 }
 
+// RegisterTopic starts advertising the local node for the given topic. The node
+// registers with the nodes closest to the topic and keeps its registrations alive
+// until UnregisterTopic is called or the transport is closed.
+func (t *UDPv5) RegisterTopic(topic Topic) {
+	t.topics.advertise(topic)
+}
+
+// UnregisterTopic stops advertising the local node for the given topic. Existing
+// registrations stay with the registrars until they expire.
+func (t *UDPv5) UnregisterTopic(topic Topic) {
+	t.topics.stopAdvertise(topic)
+}
+
+// TopicSearch returns an iterator over the nodes advertising the given topic.
+// Every node is returned at most once.
+func (t *UDPv5) TopicSearch(topic Topic) enode.Iterator {
+	return newTopicIterator(t, topic)
+}
+
 // RandomNodes returns an iterator that finds random nodes in the DHT.
 func (t *UDPv5) RandomNodes() enode.Iterator {
 	if t.tab.len() == 0 {
@@ -389,6 +413,38 @@ func (t *UDPv5) findnode(n *enode.Node, distances []uint) ([]*enode.Node, error)
 	return t.waitForNodes(resp, distances)
 }
 
+// regtopic calls REGTOPIC on a node. It returns a nil ticket if the registration
+// was placed, or the ticket to present again after the waiting time.
+func (t *UDPv5) regtopic(n *enode.Node, topic Topic, ticket []byte) (time.Duration, []byte, error) {
+	req := &v5wire.Regtopic{Topic: topic, ENR: t.localNode.Node().Record(), Ticket: ticket}
+	resp := t.callToNode(n, v5wire.TicketMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case respMsg := <-resp.ch:
+		switch respMsg := respMsg.(type) {
+		case *v5wire.Ticket:
+			if len(respMsg.Ticket) == 0 {
+				return 0, nil, errInvalidTicket
+			}
+			return time.Duration(respMsg.WaitTime) * time.Second, respMsg.Ticket, nil
+		case *v5wire.Regconfirmation:
+			if respMsg.Topic != topic {
+				return 0, nil, errors.New("confirmation for wrong topic")
+			}
+		}
+		return 0, nil, nil
+	case err := <-resp.err:
+		return 0, nil, err
+	}
+}
+
+// topicQuery calls TOPICQUERY on a node and waits for responses.
+func (t *UDPv5) topicQuery(n *enode.Node, topic Topic) ([]*enode.Node, error) {
+	resp := t.callToNode(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic})
+	return t.waitForNodes(resp, nil)
+}
+
 // waitForNodes waits for NODES responses to the given call.
 func (t *UDPv5) waitForNodes(c *callV5, distances []uint) ([]*enode.Node, error) {
 	defer t.callDone(c)
@@ -727,7 +783,7 @@ func (t *UDPv5) handleCallResponse(fromID enode.ID, fromAddr netip.AddrPort, p v
 		t.log.Debug(fmt.Sprintf("%s from wrong endpoint", p.Name()), "id", fromID, "addr", fromAddr)
 		return false
 	}
-	if p.Kind() != ac.responseType {
+	if !isResponseType(ac.responseType, p.Kind()) {
 		t.log.Debug(fmt.Sprintf("Wrong discv5 response type %s", p.Name()), "id", fromID, "addr", fromAddr)
 		return false
 	}
@@ -736,6 +792,15 @@ func (t *UDPv5) handleCallResponse(fromID enode.ID, fromAddr netip.AddrPort, p v
 	return true
 }
 
+// isResponseType reports whether a packet of the given kind answers calls expecting
+// the response type. REGTOPIC calls are answered by either TICKET or REGCONFIRMATION.
+func isResponseType(responseType, kind byte) bool {
+	if responseType == v5wire.TicketMsg && kind == v5wire.RegconfirmationMsg {
+		return true
+	}
+	return kind == responseType
+}
+
 // getNode looks for a node record in table and database.
 func (t *UDPv5) getNode(id enode.ID) *enode.Node {
 	if n := t.tab.getNode(id); n != nil {
@@ -769,6 +834,14 @@ func (t *UDPv5) handle(p v5wire.Packet, fromID enode.ID, fromAddr netip.AddrPort
 		t.talk.handleRequest(fromID, fromAddr, p)
 	case *v5wire.TalkResponse:
 		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.Regtopic:
+		t.topics.handleRegtopic(p, fromID, fromAddr)
+	case *v5wire.Ticket:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.Regconfirmation:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.TopicQuery:
+		t.topics.handleTopicQuery(p, fromID, fromAddr)
 	}
 }
 
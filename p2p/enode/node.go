@@ -38,9 +38,10 @@ type Node struct {
 	r  enr.Record
 	id ID
 	// endpoint information
-	ip  netip.Addr
-	udp uint16
-	tcp uint16
+	ip   netip.Addr
+	udp  uint16
+	tcp  uint16
+	quic uint16
 }
 
 // New wraps a node record. The record must be valid according to the given
@@ -105,6 +106,7 @@ func (n *Node) setIP4(ip netip.Addr) {
 	n.ip = ip
 	n.Load((*enr.UDP)(&n.udp))
 	n.Load((*enr.TCP)(&n.tcp))
+	n.Load((*enr.QUIC)(&n.quic))
 }
 
 func (n *Node) setIP6(ip netip.Addr) {
@@ -119,6 +121,9 @@ func (n *Node) setIP6(ip netip.Addr) {
 	if err := n.Load((*enr.TCP6)(&n.tcp)); err != nil {
 		n.Load((*enr.TCP)(&n.tcp))
 	}
+	if err := n.Load((*enr.QUIC6)(&n.quic)); err != nil {
+		n.Load((*enr.QUIC)(&n.quic))
+	}
 }
 
 // MustParse parses a node record or enode:// URL. It panics if the input is invalid.
@@ -184,6 +189,12 @@ func (n *Node) TCP() int {
 	return int(n.tcp)
 }
 
+// QUIC returns the QUIC port of the node, or zero if the node does not accept
+// devp2p connections over QUIC.
+func (n *Node) QUIC() int {
+	return int(n.quic)
+}
+
 // UDPEndpoint returns the announced UDP endpoint.
 func (n *Node) UDPEndpoint() (netip.AddrPort, bool) {
 	if !n.ip.IsValid() || n.ip.IsUnspecified() || n.udp == 0 {
@@ -200,6 +211,14 @@ func (n *Node) TCPEndpoint() (netip.AddrPort, bool) {
 	return netip.AddrPortFrom(n.ip, n.tcp), true
 }
 
+// QUICEndpoint returns the announced QUIC endpoint.
+func (n *Node) QUICEndpoint() (netip.AddrPort, bool) {
+	if !n.ip.IsValid() || n.ip.IsUnspecified() || n.quic == 0 {
+		return netip.AddrPort{}, false
+	}
+	return netip.AddrPortFrom(n.ip, n.quic), true
+}
+
 // Pubkey returns the secp256k1 public key of the node, if present.
 func (n *Node) Pubkey() *ecdsa.PublicKey {
 	var key ecdsa.PublicKey
@@ -21,6 +21,7 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"net/netip"
 	"os"
 	"sync"
@@ -41,6 +42,8 @@ const (
 	dbVersionKey   = "version" // Version of the database to flush if changes
 	dbNodePrefix   = "n:"      // Identifier to prefix node entries with
 	dbLocalPrefix  = "local:"
+	dbBanPrefix    = "ban:"    // Identifier to prefix node bans with
+	dbBanNetPrefix = "bannet:" // Identifier to prefix IP network bans with
 	dbDiscoverRoot = "v4"
 	dbDiscv5Root   = "v5"
 
@@ -194,6 +197,16 @@ func localItemKey(id ID, field string) []byte {
 	return key
 }
 
+// banKey returns the database key of a node ban.
+func banKey(id ID) []byte {
+	return append([]byte(dbBanPrefix), id[:]...)
+}
+
+// banNetKey returns the database key of an IP network ban.
+func banNetKey(network netip.Prefix) []byte {
+	return append([]byte(dbBanNetPrefix), network.Masked().String()...)
+}
+
 // fetchInt64 retrieves an integer associated with a particular key.
 func (db *DB) fetchInt64(key []byte) int64 {
 	blob, err := db.lvl.Get(key, nil)
@@ -315,6 +328,7 @@ func (db *DB) expirer() {
 		select {
 		case <-tick.C:
 			db.expireNodes()
+			db.expireBans()
 		case <-db.quit:
 			return
 		}
@@ -360,6 +374,117 @@ func (db *DB) expireNodes() {
 	}
 }
 
+// expireBans deletes all node and network bans that have run out.
+func (db *DB) expireBans() {
+	now := time.Now().Unix()
+	for _, prefix := range []string{dbBanPrefix, dbBanNetPrefix} {
+		it := db.lvl.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+		for it.Next() {
+			if until, _ := binary.Varint(it.Value()); until <= now {
+				db.lvl.Delete(it.Key(), nil)
+			}
+		}
+		it.Release()
+	}
+}
+
+// storeBan stores the expiry time of a ban. The zero time denotes a permanent ban.
+func (db *DB) storeBan(key []byte, until time.Time) error {
+	if until.IsZero() {
+		return db.storeInt64(key, math.MaxInt64)
+	}
+	return db.storeInt64(key, until.Unix())
+}
+
+// decodeBan decodes the stored expiry time of a ban, reporting whether the ban
+// is still in effect. Permanent bans are returned as the zero time.
+func decodeBan(until int64, now time.Time) (time.Time, bool) {
+	switch {
+	case until == math.MaxInt64:
+		return time.Time{}, true
+	case until == 0 || now.Unix() >= until:
+		return time.Time{}, false
+	default:
+		return time.Unix(until, 0), true
+	}
+}
+
+// BanNode bans a node from connecting until the given time, or permanently if
+// the time is zero. Bans are kept separately from the discovery data, so they
+// survive node expiration.
+func (db *DB) BanNode(id ID, until time.Time) error {
+	return db.storeBan(banKey(id), until)
+}
+
+// UnbanNode lifts the ban of a node.
+func (db *DB) UnbanNode(id ID) error {
+	return db.lvl.Delete(banKey(id), nil)
+}
+
+// BannedUntil returns the time until which a node is banned and whether it is
+// banned at all. Permanent bans are reported with the zero time.
+func (db *DB) BannedUntil(id ID) (time.Time, bool) {
+	return decodeBan(db.fetchInt64(banKey(id)), time.Now())
+}
+
+// BanNetwork bans all nodes within an IP network until the given time, or
+// permanently if the time is zero.
+func (db *DB) BanNetwork(network netip.Prefix, until time.Time) error {
+	return db.storeBan(banNetKey(network), until)
+}
+
+// UnbanNetwork lifts the ban of an IP network.
+func (db *DB) UnbanNetwork(network netip.Prefix) error {
+	return db.lvl.Delete(banNetKey(network), nil)
+}
+
+// NodeBans returns all active node bans with their expiry times. Permanent bans
+// are reported with the zero time.
+func (db *DB) NodeBans() map[ID]time.Time {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbBanPrefix)), nil)
+	defer it.Release()
+
+	var (
+		now  = time.Now()
+		bans = make(map[ID]time.Time)
+	)
+	for it.Next() {
+		var id ID
+		if len(it.Key()) != len(dbBanPrefix)+len(id) {
+			continue
+		}
+		copy(id[:], it.Key()[len(dbBanPrefix):])
+		value, _ := binary.Varint(it.Value())
+		if until, ok := decodeBan(value, now); ok {
+			bans[id] = until
+		}
+	}
+	return bans
+}
+
+// NetworkBans returns all active IP network bans with their expiry times.
+// Permanent bans are reported with the zero time.
+func (db *DB) NetworkBans() map[netip.Prefix]time.Time {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbBanNetPrefix)), nil)
+	defer it.Release()
+
+	var (
+		now  = time.Now()
+		bans = make(map[netip.Prefix]time.Time)
+	)
+	for it.Next() {
+		network, err := netip.ParsePrefix(string(it.Key()[len(dbBanNetPrefix):]))
+		if err != nil {
+			continue
+		}
+		value, _ := binary.Varint(it.Value())
+		if until, ok := decodeBan(value, now); ok {
+			bans[network] = until
+		}
+	}
+	return bans
+}
+
 // LastPingReceived retrieves the time of the last ping packet received from
 // a remote node.
 func (db *DB) LastPingReceived(id ID, ip netip.Addr) time.Time {
@@ -470,3 +470,74 @@ func TestDBExpireV5(t *testing.T) {
 	db.UpdateFindFailsV5(ID{}, ip, 4)
 	db.expireNodes()
 }
+
+// Tests that node bans are stored, run out and survive node expiration.
+func TestDBBans(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		active    = ID{0x01}
+		expired   = ID{0x02}
+		permanent = ID{0x03}
+		until     = time.Now().Add(time.Hour)
+	)
+	if _, banned := db.BannedUntil(active); banned {
+		t.Fatal("unknown node reported banned")
+	}
+	db.BanNode(active, until)
+	db.BanNode(expired, time.Now().Add(-time.Second))
+	db.BanNode(permanent, time.Time{})
+
+	if have, banned := db.BannedUntil(active); !banned || have.Unix() != until.Unix() {
+		t.Fatalf("ban mismatch: have %v (banned %v), want %v", have, banned, until)
+	}
+	if _, banned := db.BannedUntil(expired); banned {
+		t.Fatal("expired ban still active")
+	}
+	if have, banned := db.BannedUntil(permanent); !banned || !have.IsZero() {
+		t.Fatalf("permanent ban mismatch: have %v (banned %v)", have, banned)
+	}
+	db.DeleteNode(active)
+	db.expireNodes()
+	db.expireBans()
+	if _, banned := db.BannedUntil(active); !banned {
+		t.Fatal("ban lost on node deletion")
+	}
+	if db.fetchInt64(banKey(expired)) != 0 {
+		t.Fatal("expired ban not deleted")
+	}
+	if bans := db.NodeBans(); len(bans) != 2 || !bans[permanent].IsZero() || bans[active].Unix() != until.Unix() {
+		t.Fatalf("wrong node bans: %v", bans)
+	}
+	db.UnbanNode(active)
+	if _, banned := db.BannedUntil(active); banned {
+		t.Fatal("node still banned after unban")
+	}
+}
+
+func TestDBNetworkBans(t *testing.T) {
+	db, _ := OpenDB("")
+	defer db.Close()
+
+	var (
+		active  = netip.MustParsePrefix("10.1.2.3/16")
+		expired = netip.MustParsePrefix("2001:db8::/32")
+		until   = time.Now().Add(time.Hour)
+	)
+	db.BanNetwork(active, until)
+	db.BanNetwork(expired, time.Now().Add(-time.Second))
+
+	bans := db.NetworkBans()
+	if len(bans) != 1 || bans[active.Masked()].Unix() != until.Unix() {
+		t.Fatalf("wrong network bans: %v", bans)
+	}
+	db.expireBans()
+	if db.fetchInt64(banNetKey(expired)) != 0 {
+		t.Fatal("expired network ban not deleted")
+	}
+	db.UnbanNetwork(active)
+	if bans := db.NetworkBans(); len(bans) != 0 {
+		t.Fatalf("network bans left after unban: %v", bans)
+	}
+}
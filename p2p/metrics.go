@@ -59,6 +59,15 @@ var (
 	dialUnexpectedIdentity  = metrics.NewRegisteredMeter("p2p/dials/error/id/unexpected", nil)
 	dialEncHandshakeError   = metrics.NewRegisteredMeter("p2p/dials/error/rlpx/enc", nil)
 	dialProtoHandshakeError = metrics.NewRegisteredMeter("p2p/dials/error/rlpx/proto", nil)
+
+	// peer scoring and ban meters
+	scoreDropMeter = metrics.NewRegisteredMeter("p2p/score/drop", nil) // Peers disconnected due to low score
+	banRejectMeter = metrics.NewRegisteredMeter("p2p/ban/reject", nil) // Connections rejected due to a ban
+	banDialMeter   = metrics.NewRegisteredMeter("p2p/ban/dial", nil)   // Dial candidates skipped due to a ban
+	banDropMeter   = metrics.NewRegisteredMeter("p2p/ban/drop", nil)   // Peers disconnected due to a new ban
+
+	// QUIC transport meters
+	quicFallbackMeter = metrics.NewRegisteredMeter("p2p/dials/quic/fallback", nil) // QUIC dials retried over TCP
 )
 
 func init() {
@@ -116,6 +125,9 @@ func newMeteredConn(conn net.Conn) net.Conn {
 	if !metrics.Enabled {
 		return conn
 	}
+	if _, ok := conn.(*quicConn); ok {
+		return conn // QUIC transports meter the traffic of all streams themselves
+	}
 	return &meteredConn{Conn: conn}
 }
 
@@ -453,6 +453,27 @@ func (t *Trackers) Capacity(id string, kind uint64, targetRTT time.Duration) int
 	return tracker.Capacity(kind, targetRTT)
 }
 
+// Throughputs returns the measured throughput (items per second) of a specific
+// tracker for all the message kinds it has seen, along with its roundtrip. Nil
+// is returned if the tracker is unknown.
+func (t *Trackers) Throughputs(id string) (map[uint64]float64, time.Duration) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	tracker := t.trackers[id]
+	if tracker == nil {
+		return nil, 0
+	}
+	tracker.lock.RLock()
+	defer tracker.lock.RUnlock()
+
+	throughputs := make(map[uint64]float64, len(tracker.capacity))
+	for kind, capacity := range tracker.capacity {
+		throughputs[kind] = capacity
+	}
+	return throughputs, tracker.roundtrip
+}
+
 // Update is a helper function to access a specific tracker without having to
 // track it explicitly outside.
 func (t *Trackers) Update(id string, kind uint64, elapsed time.Duration, items int) {
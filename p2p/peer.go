@@ -115,6 +115,9 @@ type Peer struct {
 	pingRecv chan struct{}
 	disc     chan DiscReason
 
+	// report records a behaviour of the peer if set
+	report func(enode.ID, Behaviour)
+
 	// events receives message send / receive events if set
 	events   *event.Feed
 	testPipe *MsgPipeRW // for testing
@@ -191,6 +194,14 @@ func (p *Peer) RunningCap(protocol string, versions []uint) bool {
 	return false
 }
 
+// Report records a behaviour of the peer, adjusting its score. Peers whose score
+// drops too low are disconnected and temporarily banned.
+func (p *Peer) Report(b Behaviour) {
+	if p.report != nil {
+		p.report(p.ID(), b)
+	}
+}
+
 // RemoteAddr returns the remote address of the network connection.
 func (p *Peer) RemoteAddr() net.Addr {
 	return p.rw.fd.RemoteAddr()
@@ -227,6 +238,9 @@ func (p *Peer) Inbound() bool {
 
 func newPeer(log log.Logger, conn *conn, protocols []Protocol) *Peer {
 	protomap := matchProtocols(protocols, conn.caps, conn)
+	if st, ok := conn.transport.(streamTransport); ok {
+		st.setProtocols(protomap)
+	}
 	p := &Peer{
 		rw:       conn,
 		running:  protomap,
@@ -60,6 +60,11 @@ type Protocol struct {
 	// attempts to create connections to them.
 	DialCandidates enode.Iterator
 
+	// DiscoveryTopic, if non-empty, is the name of a discovery v5 topic for the
+	// protocol. When discovery v5 is enabled, the server advertises the local node
+	// under the topic and dials the nodes found by searching for it.
+	DiscoveryTopic string
+
 	// Attributes contains protocol specific information for the node record.
 	Attributes []enr.Entry
 }
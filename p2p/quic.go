@@ -0,0 +1,517 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/snappy"
+	"github.com/quic-go/quic-go"
+)
+
+// This file implements the experimental QUIC transport of devp2p.
+//
+// QUIC connections are encrypted by TLS 1.3 using ephemeral self-signed certificates.
+// The node identities are authenticated on top of TLS: right after the connection is
+// established, both sides send a signature over keying material exported from the TLS
+// session on the control stream, which is the first stream opened by the dialer.
+//
+// The control stream carries the protocol handshake and base protocol messages. Each
+// subprotocol is carried on its own stream, opened by the sending side when the first
+// message of the protocol is written, so loss on one stream does not hold up the
+// messages of the other protocols. All streams use the same framing:
+//
+//	frame = size || uvarint(code) || snappy(payload)
+//
+// where size is the big endian 32-bit length of the rest of the frame. Message codes
+// are the same as on RLPx, i.e. include the protocol offsets.
+
+const (
+	quicALPN       = "devp2p"
+	quicAuthLabel  = "devp2p quic identity"
+	quicMaxStreams = 64        // maximum number of concurrent streams opened by the remote side
+	quicMaxFrame   = 1<<24 - 1 // maximum size of the (uncompressed) message payload
+)
+
+var errQUICIdentity = errors.New("remote identity mismatch")
+
+// quicConn is a devp2p connection over QUIC. It implements net.Conn on top of the
+// control stream, the other streams are handled by quicTransport.
+type quicConn struct {
+	quic.Stream
+	conn      quic.Connection
+	initiator bool
+}
+
+func (c *quicConn) LocalAddr() net.Addr  { return c.conn.LocalAddr() }
+func (c *quicConn) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
+
+// Close closes the whole connection, not just the control stream.
+func (c *quicConn) Close() error {
+	return c.conn.CloseWithError(0, "")
+}
+
+// quicEndpoint is the local UDP endpoint accepting and dialing QUIC connections.
+type quicEndpoint struct {
+	transport *quic.Transport
+	listener  *quic.Listener
+	tlsConfig *tls.Config
+	config    *quic.Config
+}
+
+// listenQUIC creates a QUIC endpoint listening on the given UDP address.
+func listenQUIC(addr string) (*quicEndpoint, error) {
+	laddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := quicTLSConfig()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	e := &quicEndpoint{
+		transport: &quic.Transport{Conn: conn},
+		tlsConfig: tlsConfig,
+		config: &quic.Config{
+			HandshakeIdleTimeout:  handshakeTimeout,
+			MaxIdleTimeout:        frameReadTimeout,
+			MaxIncomingStreams:    quicMaxStreams,
+			MaxIncomingUniStreams: -1,
+		},
+	}
+	if e.listener, err = e.transport.Listen(e.tlsConfig, e.config); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// addr returns the local UDP address of the endpoint.
+func (e *quicEndpoint) addr() *net.UDPAddr {
+	return e.transport.Conn.LocalAddr().(*net.UDPAddr)
+}
+
+// accept waits for the next inbound connection.
+func (e *quicEndpoint) accept() (quic.Connection, error) {
+	return e.listener.Accept(context.Background())
+}
+
+// dial connects to a node and opens the control stream.
+func (e *quicEndpoint) dial(ctx context.Context, addr netip.AddrPort) (*quicConn, error) {
+	ctx, cancel := context.WithTimeout(ctx, defaultDialTimeout)
+	defer cancel()
+
+	conn, err := e.transport.Dial(ctx, net.UDPAddrFromAddrPort(addr), e.tlsConfig, e.config)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := conn.OpenStreamSync(ctx)
+	if err != nil {
+		conn.CloseWithError(0, "")
+		return nil, err
+	}
+	return &quicConn{Stream: stream, conn: conn, initiator: true}, nil
+}
+
+// acceptControl waits for the remote side to open the control stream of an
+// inbound connection.
+func acceptControl(conn quic.Connection) (*quicConn, error) {
+	ctx, cancel := context.WithTimeout(conn.Context(), handshakeTimeout)
+	defer cancel()
+
+	stream, err := conn.AcceptStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &quicConn{Stream: stream, conn: conn}, nil
+}
+
+// close shuts down the listener and the UDP socket.
+func (e *quicEndpoint) close() {
+	e.listener.Close()
+	e.transport.Close()
+	e.transport.Conn.Close()
+}
+
+// quicTLSConfig creates the TLS configuration of QUIC connections. Certificates
+// are ephemeral and not verified, the remote identity is authenticated by the
+// devp2p identity handshake instead.
+func quicTLSConfig() (*tls.Config, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(100 * 365 * 24 * time.Hour),
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		MinVersion:         tls.VersionTLS13,
+		Certificates:       []tls.Certificate{{Certificate: [][]byte{cert}, PrivateKey: key}},
+		NextProtos:         []string{quicALPN},
+		InsecureSkipVerify: true,
+	}, nil
+}
+
+// quicDialer dials nodes advertising a QUIC endpoint over QUIC, and all other
+// nodes using the fallback dialer. The fallback is also used if the QUIC dial
+// fails.
+type quicDialer struct {
+	endpoint *quicEndpoint
+	fallback NodeDialer
+	log      log.Logger
+}
+
+func (d *quicDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	if addr, ok := dest.QUICEndpoint(); ok {
+		fd, err := d.endpoint.dial(ctx, addr)
+		if err == nil {
+			return fd, nil
+		}
+		d.log.Trace("QUIC dial failed, falling back", "id", dest.ID(), "addr", addr, "err", err)
+		quicFallbackMeter.Mark(1)
+	}
+	return d.fallback.Dial(ctx, dest)
+}
+
+// quicTransport is the transport used by connections over QUIC.
+type quicTransport struct {
+	conn     *quicConn
+	dialDest *ecdsa.PublicKey
+
+	in        chan Msg      // messages read from all streams
+	closed    chan struct{} // closed when the connection fails or is closed
+	closeOnce sync.Once
+	err       error // the error that closed the connection
+
+	smu     sync.Mutex
+	control *quicSendStream
+	streams map[uint64]*quicSendStream // subprotocol streams by protocol offset
+	ranges  []quicProtoRange
+}
+
+// quicSendStream is the sending side of a stream.
+type quicSendStream struct {
+	mu     sync.Mutex
+	stream quic.SendStream
+	buf    []byte
+}
+
+// writeFrame encodes a message frame into the write buffer and writes it to the
+// stream. The caller must hold s.mu.
+func (s *quicSendStream) writeFrame(code uint64, payload []byte, deadline time.Time) (int, error) {
+	s.buf = append(s.buf[:0], 0, 0, 0, 0)
+	s.buf = binary.AppendUvarint(s.buf, code)
+	s.buf = append(s.buf, snappy.Encode(nil, payload)...)
+	binary.BigEndian.PutUint32(s.buf, uint32(len(s.buf)-4))
+
+	s.stream.SetWriteDeadline(deadline)
+	return s.stream.Write(s.buf)
+}
+
+// quicProtoRange is the message code range of a running subprotocol.
+type quicProtoRange struct {
+	offset, length uint64
+}
+
+func newQUICTransport(conn *quicConn, dialDest *ecdsa.PublicKey) transport {
+	return &quicTransport{
+		conn:     conn,
+		dialDest: dialDest,
+		in:       make(chan Msg),
+		closed:   make(chan struct{}),
+		control:  &quicSendStream{stream: conn.Stream},
+		streams:  make(map[uint64]*quicSendStream),
+	}
+}
+
+// newConnTransport creates the transport of a connection, depending on whether
+// it was established over QUIC or a stream-oriented network connection.
+func newConnTransport(fd net.Conn, dialDest *ecdsa.PublicKey) transport {
+	if qc, ok := fd.(*quicConn); ok {
+		return newQUICTransport(qc, dialDest)
+	}
+	return newRLPX(fd, dialDest)
+}
+
+// quicAuthHash returns the hash signed by either side of a connection to prove
+// its identity.
+func quicAuthHash(ekm []byte, initiator bool) []byte {
+	role := []byte("recipient")
+	if initiator {
+		role = []byte("initiator")
+	}
+	return crypto.Keccak256([]byte(quicAuthLabel), role, ekm)
+}
+
+func (t *quicTransport) doEncHandshake(prv *ecdsa.PrivateKey) (*ecdsa.PublicKey, error) {
+	t.conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	defer t.conn.SetDeadline(time.Time{})
+
+	tlsState := t.conn.conn.ConnectionState().TLS
+	ekm, err := tlsState.ExportKeyingMaterial(quicAuthLabel, nil, 32)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(quicAuthHash(ekm, t.conn.initiator), prv)
+	if err != nil {
+		return nil, err
+	}
+	werr := make(chan error, 1)
+	go func() {
+		_, err := t.conn.Write(sig)
+		werr <- err
+	}()
+	remoteSig := make([]byte, crypto.SignatureLength)
+	if _, err := io.ReadFull(t.conn, remoteSig); err != nil {
+		<-werr
+		return nil, err
+	}
+	if err := <-werr; err != nil {
+		return nil, fmt.Errorf("write error: %v", err)
+	}
+	remote, err := crypto.SigToPub(quicAuthHash(ekm, !t.conn.initiator), remoteSig)
+	if err != nil {
+		return nil, err
+	}
+	if t.dialDest != nil && !t.dialDest.Equal(remote) {
+		return nil, errQUICIdentity
+	}
+	// The remote identity is known, start reading messages.
+	go t.readLoop(t.conn.Stream)
+	go t.acceptLoop()
+	return remote, nil
+}
+
+func (t *quicTransport) doProtoHandshake(our *protoHandshake) (their *protoHandshake, err error) {
+	// Writing our handshake happens concurrently, we prefer
+	// returning the handshake read error.
+	werr := make(chan error, 1)
+	go func() { werr <- Send(t, handshakeMsg, our) }()
+	if their, err = readProtocolHandshake(t); err != nil {
+		<-werr // make sure the write terminates too
+		return nil, err
+	}
+	if err := <-werr; err != nil {
+		return nil, fmt.Errorf("write error: %v", err)
+	}
+	return their, nil
+}
+
+// setProtocols sets the message code ranges of the running subprotocols, which
+// are carried on separate streams.
+func (t *quicTransport) setProtocols(protocols map[string]*protoRW) {
+	t.smu.Lock()
+	defer t.smu.Unlock()
+
+	for _, proto := range protocols {
+		t.ranges = append(t.ranges, quicProtoRange{offset: proto.offset, length: proto.Length})
+	}
+}
+
+// sendStream returns the stream a message is sent on, opening it if needed.
+func (t *quicTransport) sendStream(code uint64) (*quicSendStream, error) {
+	t.smu.Lock()
+	defer t.smu.Unlock()
+
+	for _, r := range t.ranges {
+		if code < r.offset || code >= r.offset+r.length {
+			continue
+		}
+		if s := t.streams[r.offset]; s != nil {
+			return s, nil
+		}
+		ctx, cancel := context.WithTimeout(t.conn.conn.Context(), frameWriteTimeout)
+		defer cancel()
+		stream, err := t.conn.conn.OpenStreamSync(ctx)
+		if err != nil {
+			return nil, err
+		}
+		s := &quicSendStream{stream: stream}
+		t.streams[r.offset] = s
+		return s, nil
+	}
+	return t.control, nil
+}
+
+func (t *quicTransport) WriteMsg(msg Msg) error {
+	if msg.Size > quicMaxFrame {
+		return errors.New("message too big")
+	}
+	s, err := t.sendStream(msg.Code)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payload := make([]byte, msg.Size)
+	if _, err := io.ReadFull(msg.Payload, payload); err != nil {
+		return err
+	}
+	size, err := s.writeFrame(msg.Code, payload, time.Now().Add(frameWriteTimeout))
+	if err != nil {
+		return err
+	}
+
+	// Set metrics.
+	msg.meterSize = uint32(size)
+	egressTrafficMeter.Mark(int64(msg.meterSize))
+	if metrics.Enabled && msg.meterCap.Name != "" { // don't meter non-subprotocol messages
+		m := fmt.Sprintf("%s/%s/%d/%#02x", egressMeterName, msg.meterCap.Name, msg.meterCap.Version, msg.meterCode)
+		metrics.GetOrRegisterMeter(m, nil).Mark(int64(msg.meterSize))
+		metrics.GetOrRegisterMeter(m+"/packets", nil).Mark(1)
+	}
+	return nil
+}
+
+func (t *quicTransport) ReadMsg() (Msg, error) {
+	select {
+	case msg := <-t.in:
+		return msg, nil
+	case <-t.closed:
+		return Msg{}, t.err
+	}
+}
+
+// acceptLoop accepts the subprotocol streams opened by the remote side.
+func (t *quicTransport) acceptLoop() {
+	for {
+		stream, err := t.conn.conn.AcceptStream(t.conn.conn.Context())
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		go t.readLoop(stream)
+	}
+}
+
+// readLoop reads messages from a stream, feeding them to ReadMsg.
+func (t *quicTransport) readLoop(stream quic.ReceiveStream) {
+	r := bufio.NewReader(stream)
+	for {
+		msg, err := readQUICFrame(r)
+		if err != nil {
+			t.fail(err)
+			return
+		}
+		ingressTrafficMeter.Mark(int64(msg.meterSize))
+		select {
+		case t.in <- msg:
+		case <-t.closed:
+			return
+		}
+	}
+}
+
+// readQUICFrame reads and decodes a single message frame.
+func readQUICFrame(r *bufio.Reader) (Msg, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return Msg{}, err
+	}
+	size := binary.BigEndian.Uint32(header[:])
+	if size > quicMaxFrame+binary.MaxVarintLen64 {
+		return Msg{}, errors.New("oversized frame")
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return Msg{}, err
+	}
+	code, n := binary.Uvarint(frame)
+	if n <= 0 {
+		return Msg{}, errors.New("invalid message code")
+	}
+	if length, err := snappy.DecodedLen(frame[n:]); err != nil {
+		return Msg{}, err
+	} else if length > quicMaxFrame {
+		return Msg{}, errors.New("message too big")
+	}
+	data, err := snappy.Decode(nil, frame[n:])
+	if err != nil {
+		return Msg{}, err
+	}
+	return Msg{
+		ReceivedAt: time.Now(),
+		Code:       code,
+		Size:       uint32(len(data)),
+		meterSize:  4 + size,
+		Payload:    bytes.NewReader(data),
+	}, nil
+}
+
+// fail terminates the transport with the given error. If the remote side closed
+// the connection with a disconnect reason, the reason is returned instead.
+func (t *quicTransport) fail(err error) {
+	t.closeOnce.Do(func() {
+		var appErr *quic.ApplicationError
+		if errors.As(err, &appErr) && appErr.Remote && appErr.ErrorCode > 0 && appErr.ErrorCode <= math.MaxUint8+1 {
+			err = DiscReason(appErr.ErrorCode - 1)
+		}
+		t.err = err
+		close(t.closed)
+	})
+}
+
+func (t *quicTransport) close(err error) {
+	// Tell the remote end why we're disconnecting. The reason is sent both as
+	// a message and as the QUIC application error code (offset by one, as zero
+	// denotes no reason), in case the message is lost when closing.
+	var code quic.ApplicationErrorCode
+	if r, ok := err.(DiscReason); ok && r != DiscNetworkError {
+		code = quic.ApplicationErrorCode(r) + 1
+		if reason, err := rlp.EncodeToBytes([]DiscReason{r}); err == nil {
+			t.control.mu.Lock()
+			t.control.writeFrame(discMsg, reason, time.Now().Add(discWriteTimeout))
+			t.control.mu.Unlock()
+		}
+	}
+	t.conn.conn.CloseWithError(code, fmt.Sprint(err))
+	t.fail(err)
+}
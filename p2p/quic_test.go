@@ -0,0 +1,216 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func TestQUICTransport(t *testing.T) {
+	var (
+		prv0 = newkey()
+		prv1 = newkey()
+	)
+	e1, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e1.close()
+	e2, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e2.close()
+
+	protocols := map[string]*protoRW{
+		"a": {Protocol: Protocol{Name: "a", Length: 4}, offset: baseProtocolLength},
+		"b": {Protocol: Protocol{Name: "b", Length: 4}, offset: baseProtocolLength + 4},
+	}
+	listenErr := make(chan error, 1)
+	go func() {
+		listenErr <- func() error {
+			conn, err := e2.accept()
+			if err != nil {
+				return err
+			}
+			fd, err := acceptControl(conn)
+			if err != nil {
+				return err
+			}
+			tr := newQUICTransport(fd, nil).(*quicTransport)
+			remote, err := tr.doEncHandshake(prv1)
+			if err != nil {
+				return err
+			}
+			if !reflect.DeepEqual(remote, &prv0.PublicKey) {
+				return errors.New("listen side remote pubkey mismatch")
+			}
+			tr.setProtocols(protocols)
+			// Echo the messages of both protocols.
+			for i := 0; i < 2; i++ {
+				msg, err := tr.ReadMsg()
+				if err != nil {
+					return err
+				}
+				if err := tr.WriteMsg(msg); err != nil {
+					return err
+				}
+			}
+			// The disconnect reason arrives either as a message, or as the
+			// error closing the connection if that is processed first.
+			err = ExpectMsg(tr, discMsg, []DiscReason{DiscQuitting})
+			if errors.Is(err, DiscQuitting) {
+				return nil
+			}
+			return err
+		}()
+	}()
+
+	fd, err := e1.dial(context.Background(), e2.addr().AddrPort())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := newQUICTransport(fd, &prv1.PublicKey).(*quicTransport)
+	remote, err := tr.doEncHandshake(prv0)
+	if err != nil {
+		t.Fatalf("dial side enc handshake failed: %v", err)
+	}
+	if !reflect.DeepEqual(remote, &prv1.PublicKey) {
+		t.Fatalf("dial side remote pubkey mismatch: got %v, want %v", remote, &prv1.PublicKey)
+	}
+	tr.setProtocols(protocols)
+	if err := Send(tr, baseProtocolLength+1, []uint{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Send(tr, baseProtocolLength+6, []uint{2}); err != nil {
+		t.Fatal(err)
+	}
+	// Messages of different protocols are on separate streams, so their order
+	// is not guaranteed.
+	want := map[uint64][]uint{baseProtocolLength + 1: {1}, baseProtocolLength + 6: {2}}
+	for i := 0; i < 2; i++ {
+		msg, err := tr.ReadMsg()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var content []uint
+		if err := msg.Decode(&content); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(content, want[msg.Code]) {
+			t.Errorf("wrong echo for code %d: got %v, want %v", msg.Code, content, want[msg.Code])
+		}
+		delete(want, msg.Code)
+	}
+	if len(tr.streams) != 2 {
+		t.Errorf("wrong number of protocol streams: got %d, want 2", len(tr.streams))
+	}
+	tr.close(DiscQuitting)
+	if err := <-listenErr; err != nil {
+		t.Fatalf("listen side error: %v", err)
+	}
+}
+
+// Tests that dialing a QUIC endpoint fails if the remote identity does not
+// match the dialed node.
+func TestQUICIdentityMismatch(t *testing.T) {
+	e1, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e1.close()
+	e2, err := listenQUIC("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e2.close()
+
+	go func() {
+		conn, err := e2.accept()
+		if err != nil {
+			return
+		}
+		if fd, err := acceptControl(conn); err == nil {
+			newQUICTransport(fd, nil).doEncHandshake(newkey())
+		}
+	}()
+	fd, err := e1.dial(context.Background(), e2.addr().AddrPort())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	tr := newQUICTransport(fd, &newkey().PublicKey)
+	if _, err := tr.doEncHandshake(newkey()); !errors.Is(err, errQUICIdentity) {
+		t.Fatalf("wrong error: got %v, want %v", err, errQUICIdentity)
+	}
+}
+
+// Tests that servers with QUIC enabled connect over QUIC, and fall back to TCP
+// when the remote node doesn't support it.
+func TestServerQUIC(t *testing.T) {
+	newServer := func(name, quicAddr string) *Server {
+		srv := &Server{Config: Config{
+			PrivateKey:     newkey(),
+			MaxPeers:       10,
+			NoDiscovery:    true,
+			ListenAddr:     "127.0.0.1:0",
+			QUICListenAddr: quicAddr,
+			Logger:         testlog.Logger(t, log.LvlTrace).New("server", name),
+		}}
+		if err := srv.Start(); err != nil {
+			t.Fatalf("can't start server %s: %v", name, err)
+		}
+		t.Cleanup(srv.Stop)
+		return srv
+	}
+	var (
+		srv1 = newServer("1", "127.0.0.1:0")
+		srv2 = newServer("2", "127.0.0.1:0")
+		srv3 = newServer("3", "")
+	)
+	if srv1.Self().QUIC() == 0 {
+		t.Fatal("QUIC port not announced in node record")
+	}
+	if info := srv1.NodeInfo(); info.Ports.QUIC != srv1.Self().QUIC() {
+		t.Errorf("wrong QUIC port in node info: got %d, want %d", info.Ports.QUIC, srv1.Self().QUIC())
+	}
+	if !syncAddPeer(srv2, srv1.Self()) {
+		t.Fatal("QUIC peer not connected")
+	}
+	if !syncAddPeer(srv2, srv3.Self()) {
+		t.Fatal("TCP peer not connected")
+	}
+	for _, p := range srv2.Peers() {
+		switch p.ID() {
+		case srv1.Self().ID():
+			if _, ok := p.RemoteAddr().(*net.UDPAddr); !ok {
+				t.Errorf("peer 1 not connected over QUIC: %v", p.RemoteAddr())
+			}
+		case srv3.Self().ID():
+			if _, ok := p.RemoteAddr().(*net.TCPAddr); !ok {
+				t.Errorf("peer 3 not connected over TCP: %v", p.RemoteAddr())
+			}
+		}
+	}
+}
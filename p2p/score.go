@@ -0,0 +1,234 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// scoreHalfLife is the time it takes for a peer's score to decay to half of
+	// its value, so that old (mis)behaviour is gradually forgotten.
+	scoreHalfLife = 10 * time.Minute
+
+	// scoreMax caps the positive score of a peer, preventing long-lived peers
+	// from accumulating enough credit to misbehave freely.
+	scoreMax = 100
+
+	// scoreDropThreshold is the score at or below which a peer is disconnected
+	// and temporarily banned.
+	scoreDropThreshold = -100
+
+	// scoreBanDuration is the time a peer is banned for after its score has
+	// dropped below the threshold.
+	scoreBanDuration = time.Hour
+
+	// scoreForgetThreshold is the absolute score below which the tracked state
+	// of a disconnected peer is discarded.
+	scoreForgetThreshold = 1
+
+	// scoreExpireCycle is the time period for discarding the scores of
+	// disconnected peers.
+	scoreExpireCycle = time.Minute
+)
+
+// Behaviour is a kind of peer conduct reported by the protocols running on top
+// of the server. Each behaviour has a fixed impact on the peer's score.
+type Behaviour uint8
+
+const (
+	BehaviourUsefulResponse      Behaviour = iota // Peer delivered requested data
+	BehaviourSlowResponse                         // Peer failed to deliver requested data in time
+	BehaviourUselessAnnouncement                  // Peer announced data it could not deliver
+	BehaviourInvalidResponse                      // Peer delivered malformed or invalid data
+	BehaviourInvalidBlock                         // Peer served or propagated an invalid block
+	numBehaviours
+)
+
+var behaviourInfo = [numBehaviours]struct {
+	name   string
+	metric string
+	score  float64
+}{
+	BehaviourUsefulResponse:      {"useful response", "useful", 1},
+	BehaviourSlowResponse:        {"slow response", "slow", -5},
+	BehaviourUselessAnnouncement: {"useless announcement", "useless", -2},
+	BehaviourInvalidResponse:     {"invalid response", "invalid/response", -25},
+	BehaviourInvalidBlock:        {"invalid block", "invalid/block", scoreDropThreshold - scoreMax}, // drops any peer
+}
+
+// String implements fmt.Stringer.
+func (b Behaviour) String() string {
+	if b < numBehaviours {
+		return behaviourInfo[b].name
+	}
+	return fmt.Sprintf("unknown behaviour %d", b)
+}
+
+// Score returns the score impact of the behaviour.
+func (b Behaviour) Score() float64 {
+	if b < numBehaviours {
+		return behaviourInfo[b].score
+	}
+	return 0
+}
+
+// behaviourMeters count the reported behaviours per kind.
+var behaviourMeters [numBehaviours]metrics.Meter
+
+func init() {
+	for b := Behaviour(0); b < numBehaviours; b++ {
+		behaviourMeters[b] = metrics.NewRegisteredMeter("p2p/score/report/"+behaviourInfo[b].metric, nil)
+	}
+}
+
+// PeerScore is the reputation of a remote node as reported by admin_peerScores.
+type PeerScore struct {
+	ID         string            `json:"id"`
+	Score      float64           `json:"score"`
+	Connected  bool              `json:"connected"`
+	Behaviours map[string]uint64 `json:"behaviours"`
+}
+
+// peerScore is the tracked reputation of a single remote node.
+type peerScore struct {
+	value     float64
+	updated   mclock.AbsTime
+	connected bool
+	counts    [numBehaviours]uint64
+}
+
+// decay applies the exponential decay of the score up to the given time.
+func (s *peerScore) decay(now mclock.AbsTime) {
+	if elapsed := time.Duration(now - s.updated); elapsed > 0 {
+		s.value *= math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+	}
+	s.updated = now
+}
+
+// scoreboard tracks the scores of the connected and recently seen peers.
+type scoreboard struct {
+	lock   sync.Mutex
+	clock  mclock.Clock
+	scores map[enode.ID]*peerScore
+}
+
+func newScoreboard(clock mclock.Clock) *scoreboard {
+	return &scoreboard{
+		clock:  clock,
+		scores: make(map[enode.ID]*peerScore),
+	}
+}
+
+// report applies a behaviour to a peer's score, returning the updated score.
+func (sb *scoreboard) report(id enode.ID, b Behaviour) float64 {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	now := sb.clock.Now()
+	s := sb.scores[id]
+	if s == nil {
+		s = &peerScore{updated: now}
+		sb.scores[id] = s
+	}
+	s.decay(now)
+	s.value = math.Min(s.value+b.Score(), scoreMax)
+	if b < numBehaviours {
+		s.counts[b]++
+		behaviourMeters[b].Mark(1)
+	}
+	return s.value
+}
+
+// score returns the current score of a peer.
+func (sb *scoreboard) score(id enode.ID) float64 {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	s := sb.scores[id]
+	if s == nil {
+		return 0
+	}
+	s.decay(sb.clock.Now())
+	return s.value
+}
+
+// setConnected marks a peer as connected or disconnected. Disconnected peers
+// are remembered until their score decays, so reconnecting does not reset it.
+func (sb *scoreboard) setConnected(id enode.ID, connected bool) {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	s := sb.scores[id]
+	if s == nil {
+		if !connected {
+			return
+		}
+		s = &peerScore{updated: sb.clock.Now()}
+		sb.scores[id] = s
+	}
+	s.connected = connected
+}
+
+// expire discards the state of disconnected peers whose score decayed to
+// insignificance.
+func (sb *scoreboard) expire() {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	now := sb.clock.Now()
+	for id, s := range sb.scores {
+		if s.connected {
+			continue
+		}
+		if s.decay(now); math.Abs(s.value) < scoreForgetThreshold {
+			delete(sb.scores, id)
+		}
+	}
+}
+
+// list returns the scores of all tracked peers.
+func (sb *scoreboard) list() []*PeerScore {
+	sb.lock.Lock()
+	defer sb.lock.Unlock()
+
+	now := sb.clock.Now()
+	list := make([]*PeerScore, 0, len(sb.scores))
+	for id, s := range sb.scores {
+		s.decay(now)
+		info := &PeerScore{
+			ID:         id.String(),
+			Score:      s.value,
+			Connected:  s.connected,
+			Behaviours: make(map[string]uint64),
+		}
+		for b, count := range s.counts {
+			if count > 0 {
+				info.Behaviours[Behaviour(b).String()] = count
+			}
+		}
+		list = append(list, info)
+	}
+	return list
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Tests that scores are capped, decay over time and are forgotten once
+// insignificant for disconnected peers.
+func TestScoreboardDecay(t *testing.T) {
+	var (
+		clock = new(mclock.Simulated)
+		sb    = newScoreboard(clock)
+		id    = randomID()
+	)
+	sb.setConnected(id, true)
+	if score := sb.report(id, BehaviourInvalidResponse); score != -25 {
+		t.Fatalf("wrong score after report: have %v, want %v", score, -25)
+	}
+	clock.Run(scoreHalfLife)
+	if score := sb.score(id); math.Abs(score+12.5) > 1e-9 {
+		t.Fatalf("wrong score after half-life: have %v, want %v", score, -12.5)
+	}
+	for i := 0; i < 2*scoreMax; i++ {
+		sb.report(id, BehaviourUsefulResponse)
+	}
+	if score := sb.score(id); score != scoreMax {
+		t.Fatalf("score not capped: have %v, want %v", score, scoreMax)
+	}
+	// A single invalid block drops even the best scored peers
+	if score := sb.report(id, BehaviourInvalidBlock); score > scoreDropThreshold {
+		t.Fatalf("invalid block didn't cross the drop threshold: score %v", score)
+	}
+	// Connected peers are never forgotten, disconnected ones only once decayed
+	clock.Run(10 * scoreHalfLife)
+	sb.expire()
+	if len(sb.list()) != 1 {
+		t.Fatal("connected peer forgotten")
+	}
+	sb.setConnected(id, false)
+	sb.expire()
+	if len(sb.list()) != 0 {
+		t.Fatal("decayed disconnected peer not forgotten")
+	}
+}
+
+// Tests that reporting misbehaviour disconnects and bans a peer once its score
+// drops below the threshold, and that banned peers can't connect again.
+func TestServerReportPeer(t *testing.T) {
+	srv1 := &Server{Config: Config{
+		PrivateKey:  newkey(),
+		MaxPeers:    1,
+		NoDiscovery: true,
+		NoDial:      true,
+		ListenAddr:  "127.0.0.1:0",
+		Logger:      testlog.Logger(t, log.LvlTrace).New("server", "1"),
+	}}
+	srv2key := newkey()
+	srv2 := &Server{Config: Config{
+		PrivateKey:  srv2key,
+		MaxPeers:    1,
+		NoDiscovery: true,
+		Logger:      testlog.Logger(t, log.LvlTrace).New("server", "2"),
+	}}
+	srv1.Start()
+	defer srv1.Stop()
+	srv2.Start()
+	defer srv2.Stop()
+
+	// Connect the servers, srv2 being an inbound (non-static) peer of srv1
+	events := make(chan *PeerEvent, 4)
+	sub := srv1.SubscribeEvents(events)
+	defer sub.Unsubscribe()
+
+	if !syncAddPeer(srv2, srv1.Self()) {
+		t.Fatal("peer not connected")
+	}
+	id := srv2.Self().ID()
+	waitPeerEvent(t, events, id, PeerEventTypeAdd)
+
+	// Minor misbehaviour only lowers the score
+	srv1.ReportPeer(id, BehaviourInvalidResponse)
+	if srv1.PeerCount() != 1 {
+		t.Fatal("peer dropped above the score threshold")
+	}
+	// Crossing the threshold disconnects and bans the peer
+	srv1.ReportPeer(id, BehaviourInvalidBlock)
+	waitPeerEvent(t, events, id, PeerEventTypeDrop)
+	if srv1.PeerCount() != 0 {
+		t.Fatal("peer still connected after drop")
+	}
+	if _, banned := srv1.nodedb.BannedUntil(id); !banned {
+		t.Fatal("dropped peer not banned")
+	}
+	scores := srv1.PeerScores()
+	if len(scores) != 1 || scores[0].ID != id.String() || scores[0].Connected || scores[0].Score > scoreDropThreshold {
+		t.Fatalf("wrong peer scores: %+v", scores)
+	}
+	if scores[0].Behaviours[BehaviourInvalidBlock.String()] != 1 || scores[0].Behaviours[BehaviourInvalidResponse.String()] != 1 {
+		t.Fatalf("wrong behaviour counts: %v", scores[0].Behaviours)
+	}
+	// Reconnection attempts of the banned peer are rejected after the handshake
+	tt := &setupTransport{pubkey: &srv2key.PublicKey, phs: protoHandshake{ID: crypto.FromECDSAPub(&srv2key.PublicKey)[1:]}}
+	srv1.newTransport = func(fd net.Conn, dialDest *ecdsa.PublicKey) transport { return tt }
+	p1, _ := net.Pipe()
+	srv1.SetupConn(p1, inboundConn, nil)
+	if !errors.Is(tt.closeErr, DiscUselessPeer) {
+		t.Fatalf("banned peer not rejected: close error %v", tt.closeErr)
+	}
+	// Static and trusted connections are exempt from the ban
+	for _, flags := range []connFlag{staticDialedConn, trustedConn | inboundConn} {
+		c := &conn{fd: p1, flags: flags, node: srv2.Self()}
+		if err := srv1.postHandshakeChecks(nil, 0, c); err != nil {
+			t.Fatalf("banned %v peer rejected: %v", flags, err)
+		}
+	}
+}
+
+func waitPeerEvent(t *testing.T, events chan *PeerEvent, id enode.ID, typ PeerEventType) {
+	t.Helper()
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Peer == id && ev.Type == typ {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timeout waiting for %s event", typ)
+		}
+	}
+}
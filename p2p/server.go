@@ -148,6 +148,12 @@ type Config struct {
 	// for TCP and DiscAddr for the UDP discovery protocol.
 	DiscAddr string
 
+	// If QUICListenAddr is set to a non-empty UDP address, the server also
+	// accepts connections over QUIC (experimental). The QUIC port is announced
+	// in the node record, and nodes announcing one are dialed over QUIC, with
+	// fallback to TCP.
+	QUICListenAddr string
+
 	// If set to a non-nil value, the given NAT port mapper
 	// is used to make the listening port available to the
 	// Internet.
@@ -185,6 +191,7 @@ type Server struct {
 	running bool
 
 	listener     net.Listener
+	quic         *quicEndpoint
 	ourHandshake *protoHandshake
 	loopWG       sync.WaitGroup // loop, listenLoop
 	peerFeed     event.Feed
@@ -196,6 +203,8 @@ type Server struct {
 	discv5    *discover.UDPv5
 	discmix   *enode.FairMix
 	dialsched *dialScheduler
+	scores    *scoreboard
+	bans      *banList
 
 	// This is read by the NAT port mapping loop.
 	portMappingRegister chan *portMapping
@@ -211,6 +220,7 @@ type Server struct {
 	checkpointAddPeer       chan *conn
 
 	// State of run loop and listenLoop.
+	inboundLock    sync.Mutex // protects inboundHistory, shared by the TCP and QUIC listeners
 	inboundHistory expHeap
 }
 
@@ -257,6 +267,13 @@ type transport interface {
 	close(err error)
 }
 
+// streamTransport is implemented by transports carrying each subprotocol on a
+// separate stream. They are told about the message code ranges of the running
+// subprotocols after the protocol handshake.
+type streamTransport interface {
+	setProtocols(protocols map[string]*protoRW)
+}
+
 func (c *conn) String() string {
 	s := c.flags.String()
 	if (c.node.ID() != enode.ID{}) {
@@ -385,6 +402,144 @@ func (srv *Server) RemoveTrustedPeer(node *enode.Node) {
 	}
 }
 
+// ReportPeer records a behaviour of the given peer, adjusting its score. Peers
+// whose score drops too low are disconnected and banned for a while, unless
+// they are trusted or static.
+func (srv *Server) ReportPeer(id enode.ID, b Behaviour) {
+	srv.lock.Lock()
+	scores := srv.scores
+	srv.lock.Unlock()
+	if scores == nil {
+		return // Server not running
+	}
+	score := scores.report(id, b)
+	if score > scoreDropThreshold {
+		return
+	}
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		p := peers[id]
+		if p != nil && p.rw.is(trustedConn|staticDialedConn) {
+			return
+		}
+		srv.log.Debug("Banning low score peer", "id", id, "score", score, "behaviour", b)
+		if err := srv.bans.banNode(id, time.Now().Add(scoreBanDuration)); err != nil {
+			srv.log.Warn("Failed to store peer ban", "id", id, "err", err)
+		}
+		if p != nil {
+			scoreDropMeter.Mark(1)
+			p.Disconnect(DiscUselessPeer)
+		}
+	})
+}
+
+// BanNode bans the given node for the given duration, disconnecting it if it is
+// connected. A zero duration bans the node permanently. Bans are persisted in
+// the node database, so they survive restarts. Trusted and static peers are
+// exempt.
+func (srv *Server) BanNode(id enode.ID, duration time.Duration) error {
+	bans := srv.banList()
+	if bans == nil {
+		return errServerStopped
+	}
+	if err := bans.banNode(id, banExpiry(duration)); err != nil {
+		return err
+	}
+	srv.dropBanned()
+	return nil
+}
+
+// BanNetwork bans all nodes within the given IP network for the given duration,
+// disconnecting the connected ones. A zero duration bans the network permanently.
+// Bans are persisted in the node database, so they survive restarts. Trusted
+// and static peers are exempt.
+func (srv *Server) BanNetwork(network netip.Prefix, duration time.Duration) error {
+	bans := srv.banList()
+	if bans == nil {
+		return errServerStopped
+	}
+	if err := bans.banNetwork(network, banExpiry(duration)); err != nil {
+		return err
+	}
+	srv.dropBanned()
+	return nil
+}
+
+// UnbanNode lifts the ban of the given node, reporting whether it was banned.
+func (srv *Server) UnbanNode(id enode.ID) (bool, error) {
+	bans := srv.banList()
+	if bans == nil {
+		return false, errServerStopped
+	}
+	return bans.unbanNode(id)
+}
+
+// UnbanNetwork lifts the ban of the given IP network, reporting whether it was
+// banned. Only exact matches of a previously banned network are lifted.
+func (srv *Server) UnbanNetwork(network netip.Prefix) (bool, error) {
+	bans := srv.banList()
+	if bans == nil {
+		return false, errServerStopped
+	}
+	return bans.unbanNetwork(network)
+}
+
+// Bans returns the active node and IP network bans.
+func (srv *Server) Bans() []*BanInfo {
+	bans := srv.banList()
+	if bans == nil {
+		return nil
+	}
+	list := bans.list()
+	slices.SortFunc(list, func(a, b *BanInfo) int {
+		return cmp.Compare(a.Target, b.Target)
+	})
+	return list
+}
+
+func (srv *Server) banList() *banList {
+	srv.lock.Lock()
+	defer srv.lock.Unlock()
+	return srv.bans
+}
+
+// dropBanned disconnects all peers covered by a ban.
+func (srv *Server) dropBanned() {
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		for id, p := range peers {
+			if p.rw.is(trustedConn|staticDialedConn) || !srv.bans.isBanned(id, netutil.AddrAddr(p.RemoteAddr())) {
+				continue
+			}
+			srv.log.Debug("Disconnecting banned peer", "id", id, "addr", p.RemoteAddr())
+			banDropMeter.Mark(1)
+			p.Disconnect(DiscUselessPeer)
+		}
+	})
+}
+
+// banExpiry returns the expiry time of a ban lasting for the given duration,
+// or the zero time for permanent bans.
+func banExpiry(duration time.Duration) time.Time {
+	if duration <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(duration)
+}
+
+// PeerScores returns the scores of the connected and recently seen peers.
+func (srv *Server) PeerScores() []*PeerScore {
+	srv.lock.Lock()
+	scores := srv.scores
+	srv.lock.Unlock()
+	if scores == nil {
+		return nil
+	}
+	list := scores.list()
+	slices.SortFunc(list, func(a, b *PeerScore) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return list
+}
+
 // SubscribeEvents subscribes the given channel to peer events
 func (srv *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return srv.peerFeed.Subscribe(ch)
@@ -425,6 +580,9 @@ func (srv *Server) Stop() {
 		// this unblocks listener Accept
 		srv.listener.Close()
 	}
+	if srv.quic != nil {
+		srv.quic.close()
+	}
 	close(srv.quit)
 	srv.lock.Unlock()
 	srv.loopWG.Wait()
@@ -481,7 +639,7 @@ func (srv *Server) Start() (err error) {
 		return errors.New("Server.PrivateKey must be set to a non-nil key")
 	}
 	if srv.newTransport == nil {
-		srv.newTransport = newRLPX
+		srv.newTransport = newConnTransport
 	}
 	if srv.listenFunc == nil {
 		srv.listenFunc = net.Listen
@@ -494,10 +652,12 @@ func (srv *Server) Start() (err error) {
 	srv.removetrusted = make(chan *enode.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
+	srv.scores = newScoreboard(srv.clock)
 
 	if err := srv.setupLocalNode(); err != nil {
 		return err
 	}
+	srv.bans = newBanList(srv.nodedb)
 	srv.setupPortMapping()
 
 	if srv.ListenAddr != "" {
@@ -505,6 +665,11 @@ func (srv *Server) Start() (err error) {
 			return err
 		}
 	}
+	if srv.QUICListenAddr != "" {
+		if err := srv.setupQUIC(); err != nil {
+			return err
+		}
+	}
 	if err := srv.setupDiscovery(); err != nil {
 		return err
 	}
@@ -569,6 +734,7 @@ func (srv *Server) setupDiscovery() error {
 		cfg := discover.Config{
 			PrivateKey:  srv.PrivateKey,
 			NetRestrict: srv.NetRestrict,
+			Banned:      srv.bans.containsNode,
 			Bootnodes:   srv.BootstrapNodes,
 			Unhandled:   unhandled,
 			Log:         srv.log,
@@ -584,6 +750,7 @@ func (srv *Server) setupDiscovery() error {
 		cfg := discover.Config{
 			PrivateKey:  srv.PrivateKey,
 			NetRestrict: srv.NetRestrict,
+			Banned:      srv.bans.containsNode,
 			Bootnodes:   srv.BootstrapNodesV5,
 			Log:         srv.log,
 		}
@@ -601,6 +768,18 @@ func (srv *Server) setupDiscovery() error {
 			added[proto.Name] = true
 		}
 	}
+	// Advertise and search the discovery topics of the protocols.
+	if srv.discv5 != nil {
+		topics := make(map[string]bool)
+		for _, proto := range srv.Protocols {
+			if proto.DiscoveryTopic != "" && !topics[proto.DiscoveryTopic] {
+				topic := discover.NewTopic(proto.DiscoveryTopic)
+				srv.discv5.RegisterTopic(topic)
+				srv.discmix.AddSource(srv.discv5.TopicSearch(topic))
+				topics[proto.DiscoveryTopic] = true
+			}
+		}
+	}
 	return nil
 }
 
@@ -611,6 +790,7 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
+		bans:           srv.bans,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
 	}
@@ -620,6 +800,10 @@ func (srv *Server) setupDialScheduler() {
 	if config.dialer == nil {
 		config.dialer = tcpDialer{&net.Dialer{Timeout: defaultDialTimeout}}
 	}
+	if srv.quic != nil {
+		config.dialer = &quicDialer{endpoint: srv.quic, fallback: config.dialer, log: srv.log}
+		config.quic = true
+	}
 	srv.dialsched = newDialScheduler(config, srv.discmix, srv.SetupConn)
 	for _, n := range srv.StaticNodes {
 		srv.dialsched.addStatic(n)
@@ -723,7 +907,10 @@ func (srv *Server) run() {
 		peers        = make(map[enode.ID]*Peer)
 		inboundCount = 0
 		trusted      = make(map[enode.ID]bool, len(srv.TrustedNodes))
+		expireScores = time.NewTicker(scoreExpireCycle)
 	)
+	defer expireScores.Stop()
+
 	// Put trusted nodes into a map to speed up checks.
 	// Trusted peers are loaded on startup or added via AddTrustedPeer RPC.
 	for _, n := range srv.TrustedNodes {
@@ -760,6 +947,10 @@ running:
 			op(peers)
 			srv.peerOpDone <- struct{}{}
 
+		case <-expireScores.C:
+			// Forget the scores of disconnected peers once they decayed.
+			srv.scores.expire()
+
 		case c := <-srv.checkpointPostHandshake:
 			// A connection has passed the encryption handshake so
 			// the remote identity is known (but hasn't been verified yet).
@@ -778,6 +969,7 @@ running:
 				// The handshakes are done and it passed all checks.
 				p := srv.launchPeer(c)
 				peers[c.node.ID()] = p
+				srv.scores.setConnected(c.node.ID(), true)
 				srv.log.Debug("Adding p2p peer", "peercount", len(peers), "id", p.ID(), "conn", c.flags, "addr", p.RemoteAddr(), "name", p.Name())
 				srv.dialsched.peerAdded(c)
 				if p.Inbound() {
@@ -796,6 +988,7 @@ running:
 			// A peer disconnected.
 			d := common.PrettyDuration(mclock.Now() - pd.created)
 			delete(peers, pd.ID())
+			srv.scores.setConnected(pd.ID(), false)
 			srv.log.Debug("Removing p2p peer", "peercount", len(peers), "id", pd.ID(), "duration", d, "req", pd.requested, "err", pd.err)
 			srv.dialsched.peerRemoved(pd.rw)
 			if pd.Inbound() {
@@ -841,6 +1034,9 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case !c.is(trustedConn|staticDialedConn) && srv.bans.isBanned(c.node.ID(), netutil.AddrAddr(c.fd.RemoteAddr())):
+		banRejectMeter.Mark(1)
+		return DiscUselessPeer
 	default:
 		return nil
 	}
@@ -925,6 +1121,73 @@ func (srv *Server) listenLoop() {
 	}
 }
 
+func (srv *Server) setupQUIC() error {
+	endpoint, err := listenQUIC(srv.QUICListenAddr)
+	if err != nil {
+		return err
+	}
+	srv.quic = endpoint
+	srv.QUICListenAddr = endpoint.addr().String()
+
+	// Announce the QUIC port in the local node record. Note the port is not
+	// mapped through NAT, as the mapping of the UDP port belongs to discovery.
+	srv.localnode.Set(enr.QUIC(endpoint.addr().Port))
+
+	srv.loopWG.Add(1)
+	go srv.quicListenLoop()
+	return nil
+}
+
+// quicListenLoop accepts inbound connections over QUIC.
+func (srv *Server) quicListenLoop() {
+	srv.log.Debug("QUIC listener up", "addr", srv.quic.addr())
+
+	// The slots channel limits the number of connections in the handshake.
+	tokens := defaultMaxPendingPeers
+	if srv.MaxPendingPeers > 0 {
+		tokens = srv.MaxPendingPeers
+	}
+	slots := make(chan struct{}, tokens)
+	for i := 0; i < tokens; i++ {
+		slots <- struct{}{}
+	}
+	defer srv.loopWG.Done()
+	defer func() {
+		for i := 0; i < cap(slots); i++ {
+			<-slots
+		}
+	}()
+
+	for {
+		<-slots
+		qconn, err := srv.quic.accept()
+		if err != nil {
+			srv.log.Debug("QUIC accept error", "err", err)
+			slots <- struct{}{}
+			return
+		}
+		if err := srv.checkInboundConn(netutil.AddrAddr(qconn.RemoteAddr())); err != nil {
+			srv.log.Debug("Rejected inbound QUIC connection", "addr", qconn.RemoteAddr(), "err", err)
+			qconn.CloseWithError(0, "")
+			slots <- struct{}{}
+			continue
+		}
+		serveMeter.Mark(1)
+		srv.log.Trace("Accepted QUIC connection", "addr", qconn.RemoteAddr())
+		go func() {
+			defer func() { slots <- struct{}{} }()
+
+			fd, err := acceptControl(qconn)
+			if err != nil {
+				srv.log.Trace("QUIC control stream not opened", "addr", qconn.RemoteAddr(), "err", err)
+				qconn.CloseWithError(0, "")
+				return
+			}
+			srv.SetupConn(fd, inboundConn, nil)
+		}()
+	}
+}
+
 func (srv *Server) checkInboundConn(remoteIP netip.Addr) error {
 	if !remoteIP.IsValid() {
 		// This case happens for internal test connections without remote address.
@@ -934,7 +1197,15 @@ func (srv *Server) checkInboundConn(remoteIP netip.Addr) error {
 	if srv.NetRestrict != nil && !srv.NetRestrict.ContainsAddr(remoteIP) {
 		return errors.New("not in netrestrict list")
 	}
+	// Reject connections from banned networks.
+	if srv.bans.isAddrBanned(remoteIP) {
+		banRejectMeter.Mark(1)
+		return errors.New("banned")
+	}
 	// Reject Internet peers that try too often.
+	srv.inboundLock.Lock()
+	defer srv.inboundLock.Unlock()
+
 	now := srv.clock.Now()
 	srv.inboundHistory.expire(now, nil)
 	if !netutil.AddrIsLAN(remoteIP) && srv.inboundHistory.contains(remoteIP.String()) {
@@ -1025,9 +1296,13 @@ func (srv *Server) setupConn(c *conn, dialDest *enode.Node) error {
 func nodeFromConn(pubkey *ecdsa.PublicKey, conn net.Conn) *enode.Node {
 	var ip net.IP
 	var port int
-	if tcp, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
-		ip = tcp.IP
-		port = tcp.Port
+	switch addr := conn.RemoteAddr().(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+		port = addr.Port
+	case *net.UDPAddr:
+		ip = addr.IP
+		port = addr.Port
 	}
 	return enode.NewV4(pubkey, ip, port, port)
 }
@@ -1045,6 +1320,7 @@ func (srv *Server) checkpoint(c *conn, stage chan<- *conn) error {
 
 func (srv *Server) launchPeer(c *conn) *Peer {
 	p := newPeer(srv.log, c, srv.Protocols)
+	p.report = srv.ReportPeer
 	if srv.EnableMsgEvents {
 		// If message events are enabled, pass the peerFeed
 		// to the peer.
@@ -1095,8 +1371,9 @@ type NodeInfo struct {
 	ENR   string `json:"enr"`   // Ethereum Node Record
 	IP    string `json:"ip"`    // IP address of the node
 	Ports struct {
-		Discovery int `json:"discovery"` // UDP listening port for discovery protocol
-		Listener  int `json:"listener"`  // TCP listening port for RLPx
+		Discovery int `json:"discovery"`      // UDP listening port for discovery protocol
+		Listener  int `json:"listener"`       // TCP listening port for RLPx
+		QUIC      int `json:"quic,omitempty"` // UDP listening port for QUIC
 	} `json:"ports"`
 	ListenAddr string                 `json:"listenAddr"`
 	Protocols  map[string]interface{} `json:"protocols"`
@@ -1116,6 +1393,7 @@ func (srv *Server) NodeInfo() *NodeInfo {
 	}
 	info.Ports.Discovery = node.UDP()
 	info.Ports.Listener = node.TCP()
+	info.Ports.QUIC = node.QUIC()
 	info.ENR = node.String()
 
 	// Gather all the running protocol infos (only once per protocol type)
@@ -120,6 +120,9 @@ func (e *ExecAdapter) NewNode(config *NodeConfig) (Node, error) {
 	// Listen on a localhost port, which we set when we
 	// initialise NodeConfig (usually a random port)
 	conf.Stack.P2P.ListenAddr = fmt.Sprintf(":%d", config.Port)
+	if config.QUIC {
+		conf.Stack.P2P.QUICListenAddr = fmt.Sprintf(":%d", config.Port)
+	}
 
 	node := &ExecNode{
 		ID:      config.ID,
@@ -92,14 +92,20 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 		return nil, err
 	}
 
+	p2pConfig := p2p.Config{
+		PrivateKey:      config.PrivateKey,
+		MaxPeers:        math.MaxInt32,
+		NoDiscovery:     true,
+		Dialer:          s,
+		EnableMsgEvents: config.EnableMsgEvents,
+	}
+	if config.QUIC {
+		// QUIC nodes are connected over real UDP sockets on localhost, other
+		// nodes are still dialed through the in-memory pipes.
+		p2pConfig.QUICListenAddr = fmt.Sprintf("127.0.0.1:%d", config.Port)
+	}
 	n, err := node.New(&node.Config{
-		P2P: p2p.Config{
-			PrivateKey:      config.PrivateKey,
-			MaxPeers:        math.MaxInt32,
-			NoDiscovery:     true,
-			Dialer:          s,
-			EnableMsgEvents: config.EnableMsgEvents,
-		},
+		P2P:            p2pConfig,
 		ExternalSigner: config.ExternalSigner,
 		Logger:         log.New("node.id", id.String()),
 	})
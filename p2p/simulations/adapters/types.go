@@ -120,6 +120,10 @@ type NodeConfig struct {
 
 	Port uint16
 
+	// QUIC enables the experimental QUIC transport of the node, listening on
+	// the UDP port of the same number as Port.
+	QUIC bool
+
 	// LogFile is the log file name of the p2p node at runtime.
 	//
 	// The default value is empty so that the default log writer
@@ -142,6 +146,7 @@ type nodeConfigJSON struct {
 	Properties      []string `json:"properties"`
 	EnableMsgEvents bool     `json:"enable_msg_events"`
 	Port            uint16   `json:"port"`
+	QUIC            bool     `json:"quic,omitempty"`
 	LogFile         string   `json:"logfile"`
 	LogVerbosity    int      `json:"log_verbosity"`
 }
@@ -155,6 +160,7 @@ func (n *NodeConfig) MarshalJSON() ([]byte, error) {
 		Lifecycles:      n.Lifecycles,
 		Properties:      n.Properties,
 		Port:            n.Port,
+		QUIC:            n.QUIC,
 		EnableMsgEvents: n.EnableMsgEvents,
 		LogFile:         n.LogFile,
 		LogVerbosity:    int(n.LogVerbosity),
@@ -195,6 +201,7 @@ func (n *NodeConfig) UnmarshalJSON(data []byte) error {
 	n.Lifecycles = confJSON.Lifecycles
 	n.Properties = confJSON.Properties
 	n.Port = confJSON.Port
+	n.QUIC = confJSON.QUIC
 	n.EnableMsgEvents = confJSON.EnableMsgEvents
 	n.LogFile = confJSON.LogFile
 	n.LogVerbosity = slog.Level(confJSON.LogVerbosity)
@@ -306,6 +313,10 @@ func (n *NodeConfig) initEnode(ip net.IP, tcpport int, udpport int) error {
 	n.Record.Set(&enrTcpPort)
 	enrUdpPort := enr.UDP(udpport)
 	n.Record.Set(&enrUdpPort)
+	if n.QUIC {
+		enrQuicPort := enr.QUIC(tcpport)
+		n.Record.Set(&enrQuicPort)
+	}
 
 	err := enode.SignV4(&n.Record, n.PrivateKey)
 	if err != nil {
@@ -17,7 +17,9 @@
 package simulations
 
 import (
+	"net"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -170,3 +172,56 @@ func TestConnectNodesStar(t *testing.T) {
 
 	VerifyStar(t, net, ids, pivotIndex)
 }
+
+// Tests a network mixing nodes running the QUIC transport with nodes which are
+// only reachable through the in-memory pipes of the simulation adapter.
+func TestConnectNodesFullMixedQUIC(t *testing.T) {
+	adapter := adapters.NewSimAdapter(adapters.LifecycleConstructors{
+		"noopwoop": func(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+			return NewNoopService(nil), nil
+		},
+	})
+	network := NewNetwork(adapter, &NetworkConfig{DefaultService: "noopwoop"})
+	defer network.Shutdown()
+
+	var (
+		ids    = make([]enode.ID, 6)
+		isQUIC = make(map[enode.ID]bool)
+	)
+	for i := range ids {
+		conf := adapters.RandomNodeConfig()
+		conf.QUIC = i%2 == 0
+		node, err := network.NewNodeWithConfig(conf)
+		if err != nil {
+			t.Fatalf("error creating node: %s", err)
+		}
+		if err := network.Start(node.ID()); err != nil {
+			t.Fatalf("error starting node: %s", err)
+		}
+		ids[i] = node.ID()
+		isQUIC[node.ID()] = conf.QUIC
+	}
+	if err := network.ConnectNodesFull(ids); err != nil {
+		t.Fatal(err)
+	}
+	VerifyFull(t, network, ids)
+
+	// Wait for all peer connections to be established, then check that QUIC
+	// is used between the QUIC nodes only.
+	deadline := time.Now().Add(5 * time.Second)
+	for _, id := range ids {
+		srv := network.GetNode(id).Node.(*adapters.SimNode).Server()
+		for srv.PeerCount() < len(ids)-1 {
+			if time.Now().After(deadline) {
+				t.Fatalf("node %s: timeout waiting for peers, have %d", id.TerminalString(), srv.PeerCount())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		for _, p := range srv.Peers() {
+			_, overQUIC := p.RemoteAddr().(*net.UDPAddr)
+			if want := isQUIC[id] && isQUIC[p.ID()]; overQUIC != want {
+				t.Errorf("node %s: peer %s connected over QUIC: %t, want %t", id.TerminalString(), p.ID().TerminalString(), overQUIC, want)
+			}
+		}
+	}
+}
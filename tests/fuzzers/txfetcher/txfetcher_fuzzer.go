@@ -84,6 +84,7 @@ func fuzz(input []byte) int {
 		},
 		func(string, []common.Hash) error { return nil },
 		nil,
+		nil,
 		clock, rand,
 	)
 	f.Start()
@@ -107,9 +107,9 @@ func execStateTest(t *testing.T, st *testMatcher, test *StateTest) {
 		subtest := subtest
 		key := fmt.Sprintf("%s/%d", subtest.Fork, subtest.Index)
 
-		// If -short flag is used, we don't execute all four permutations, only
+		// If -short flag is used, we don't execute all five permutations, only
 		// one.
-		executionMask := 0xf
+		executionMask := 0x1f
 		if testing.Short() {
 			executionMask = (1 << (rand.Int63() & 4))
 		}
@@ -173,6 +173,19 @@ func execStateTest(t *testing.T, st *testMatcher, test *StateTest) {
 				return result
 			})
 		})
+		t.Run(key+"/hash/trie/optimized", func(t *testing.T) {
+			if executionMask&0x10 == 0 {
+				t.Skip("test (randomly) skipped due to short-tag")
+			}
+			withTrace(t, test.gasLimit(subtest), func(vmconfig vm.Config) error {
+				var result error
+				vmconfig.OptimizedInterpreter = true
+				test.Run(subtest, vmconfig, false, rawdb.HashScheme, func(err error, state *StateTestState) {
+					result = st.checkFailure(t, err)
+				})
+				return result
+			})
+		})
 	}
 }
 
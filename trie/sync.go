@@ -500,6 +500,15 @@ func (s *Sync) Pending() int {
 	return len(s.nodeReqs) + len(s.codeReqs)
 }
 
+// PendingPaths invokes the callback with the path of every trie node request
+// currently tracked by the scheduler, both queued and in flight. The callback
+// must not retain or modify the path.
+func (s *Sync) PendingPaths(fn func(path []byte)) {
+	for _, req := range s.nodeReqs {
+		fn(req.path)
+	}
+}
+
 // scheduleNodeRequest inserts a new state retrieval request into the fetch queue. If there
 // is already a pending request for this node, the new request will be discarded
 // and only a parent reference added to the old one.